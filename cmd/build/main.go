@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,11 +10,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/dnfjson"
+	"github.com/osbuild/images/internal/progress"
 	"github.com/osbuild/images/pkg/blueprint"
-	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/build"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distroregistry"
 	"github.com/osbuild/images/pkg/manifest"
@@ -65,6 +68,7 @@ type crBlueprint struct {
 	Modules        []blueprint.Package       `json:"modules,omitempty"`
 	Groups         []blueprint.Group         `json:"groups,omitempty"`
 	Containers     []blueprint.Container     `json:"containers,omitempty"`
+	Flatpaks       []blueprint.Flatpak       `json:"flatpaks,omitempty"`
 	Customizations *blueprint.Customizations `json:"customizations,omitempty"`
 	Distro         string                    `json:"distro,omitempty"`
 	Minimal        bool                      `json:"minimal,omitempty"`
@@ -93,7 +97,7 @@ func loadConfig(path string) BuildConfig {
 	return conf
 }
 
-func makeManifest(imgType distro.ImageType, config BuildConfig, distribution distro.Distro, repos []rpmmd.RepoConfig, archName string, seedArg int64, cacheRoot string) (manifest.OSBuildManifest, error) {
+func makeManifest(imgType distro.ImageType, config BuildConfig, distribution distro.Distro, repos []rpmmd.RepoConfig, archName string, seedArg int64, cacheRoot string, resolveCache *build.Cache) (manifest.OSBuildManifest, manifest.Secrets, error) {
 	cacheDir := filepath.Join(cacheRoot, archName+distribution.Name())
 
 	options := distro.ImageOptions{Size: 0}
@@ -118,40 +122,25 @@ func makeManifest(imgType distro.ImageType, config BuildConfig, distribution dis
 
 	manifest, warnings, err := imgType.Manifest(&bp, options, repos, seedArg)
 	if err != nil {
-		return nil, fmt.Errorf("[ERROR] manifest generation failed: %s", err.Error())
+		return nil, nil, fmt.Errorf("[ERROR] manifest generation failed: %s", err.Error())
 	}
 	if len(warnings) > 0 {
 		fmt.Fprintf(os.Stderr, "[WARNING]\n%s", strings.Join(warnings, "\n"))
 	}
 
-	packageSpecs, err := depsolve(cacheDir, manifest.GetPackageSetChains(), distribution, archName)
-	if err != nil {
-		return nil, fmt.Errorf("[ERROR] depsolve failed: %s", err.Error())
-	}
-	if packageSpecs == nil {
-		return nil, fmt.Errorf("[ERROR] depsolve did not return any packages")
-	}
-
-	if config.Blueprint != nil {
-		bp = blueprint.Blueprint(*config.Blueprint)
-	}
-
-	containerSpecs, err := resolvePipelineContainers(manifest.GetContainerSourceSpecs(), archName)
-	if err != nil {
-		return nil, fmt.Errorf("[ERROR] container resolution failed: %s", err.Error())
-	}
-
-	commitSpecs, err := resolvePipelineCommits(manifest.GetOSTreeSourceSpecs())
-	if err != nil {
-		return nil, fmt.Errorf("[ERROR] ostree commit resolution failed: %s\n", err.Error())
-	}
+	solver := dnfjson.NewSolver(distribution.ModulePlatformID(), distribution.Releasever(), archName, distribution.Name(), cacheDir)
+	solver.SetDNFJSONPath("./dnf-json")
 
-	mf, err := manifest.Serialize(packageSpecs, containerSpecs, commitSpecs)
+	opts := resolveCache.Wrap(build.Options{
+		Depsolver:         solver,
+		ContainerResolver: &build.RegistryResolver{Arch: archName},
+	}, distribution.Name(), archName)
+	res, err := build.Resolve(context.Background(), manifest, opts)
 	if err != nil {
-		return nil, fmt.Errorf("[ERROR] manifest serialization failed: %s", err.Error())
+		return nil, nil, fmt.Errorf("[ERROR] %s", err.Error())
 	}
 
-	return mf, nil
+	return res.Manifest, res.Secrets, nil
 }
 
 type DistroArchRepoMap map[string]map[string][]repository
@@ -216,58 +205,6 @@ func readRepos() DistroArchRepoMap {
 	return darm
 }
 
-func resolveContainers(containers []container.SourceSpec, archName string) ([]container.Spec, error) {
-	resolver := container.NewResolver(archName)
-
-	for _, c := range containers {
-		resolver.Add(c)
-	}
-
-	return resolver.Finish()
-}
-
-func resolvePipelineContainers(containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
-	containerSpecs := make(map[string][]container.Spec, len(containerSources))
-	for plName, sourceSpecs := range containerSources {
-		specs, err := resolveContainers(sourceSpecs, archName)
-		if err != nil {
-			return nil, err
-		}
-		containerSpecs[plName] = specs
-	}
-	return containerSpecs, nil
-}
-
-func resolvePipelineCommits(commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
-	commits := make(map[string][]ostree.CommitSpec, len(commitSources))
-	for name, commitSources := range commitSources {
-		commitSpecs := make([]ostree.CommitSpec, len(commitSources))
-		for idx, commitSource := range commitSources {
-			var err error
-			commitSpecs[idx], err = ostree.Resolve(commitSource)
-			if err != nil {
-				return nil, err
-			}
-		}
-		commits[name] = commitSpecs
-	}
-	return commits, nil
-}
-
-func depsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error) {
-	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), arch, d.Name(), cacheDir)
-	solver.SetDNFJSONPath("./dnf-json")
-	depsolvedSets := make(map[string][]rpmmd.PackageSpec)
-	for name, pkgSet := range packageSets {
-		res, err := solver.Depsolve(pkgSet)
-		if err != nil {
-			return nil, err
-		}
-		depsolvedSets[name] = res
-	}
-	return depsolvedSets, nil
-}
-
 func save(ms manifest.OSBuildManifest, fpath string) error {
 	b, err := json.MarshalIndent(ms, "", "  ")
 	if err != nil {
@@ -285,6 +222,30 @@ func save(ms manifest.OSBuildManifest, fpath string) error {
 	return nil
 }
 
+// reportFailure prints the diagnosis of a failed build and saves it as JSON
+func reportFailure(res *osbuild.Result, mf manifest.OSBuildManifest, fpath string) {
+	// the stage IDs of the inspected manifest locate the failed stage, the
+	// diagnosis is less precise without them
+	inspected, err := osbuild.InspectManifest(mf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] %s\n", err)
+	}
+	diagnosis, err := osbuild.DiagnoseResult(res, inspected)
+	if err != nil || diagnosis == nil {
+		return
+	}
+	if err := diagnosis.Write(os.Stderr); err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(diagnosis, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(fpath, append(b, '\n'), 0644); err == nil {
+		fmt.Fprintf(os.Stderr, "Failure report saved in %s\n", fpath)
+	}
+}
+
 func u(s string) string {
 	return strings.Replace(s, "-", "_", -1)
 }
@@ -312,6 +273,15 @@ func main() {
 	flag.StringVar(&outputDir, "output", ".", "artifact output directory")
 	flag.StringVar(&osbuildStore, "store", ".osbuild", "osbuild store for intermediate pipeline trees")
 	flag.StringVar(&rpmCacheRoot, "rpmmd", "/tmp/rpmmd", "rpm metadata cache directory")
+	var showProgress bool
+	flag.BoolVar(&showProgress, "progress", progress.IsTerminal(os.Stdout), "show a progress bar while building")
+	var resolveCacheDir string
+	var resolveCacheTTL time.Duration
+	var offline, refresh bool
+	flag.StringVar(&resolveCacheDir, "resolve-cache", build.DefaultCacheDir(), "cache directory for depsolve and resolve results (empty to disable)")
+	flag.DurationVar(&resolveCacheTTL, "resolve-cache-ttl", 24*time.Hour, "time after which cached results expire")
+	flag.BoolVar(&offline, "offline", false, "use cached depsolve results without checking the repository metadata")
+	flag.BoolVar(&refresh, "refresh", false, "invalidate the cached results")
 
 	// image selection args
 	var distroName, imgTypeName, configFile string
@@ -366,7 +336,11 @@ func main() {
 	}
 
 	fmt.Printf("Generating manifest for %s: ", config.Name)
-	mf, err := makeManifest(imgType, config, distribution, rpmmdRepos, archName, seedArg, rpmCacheRoot)
+	resolveCache, err := build.OpenCache(resolveCacheDir, resolveCacheTTL, offline, refresh)
+	if err != nil {
+		fail(fmt.Sprintf("failed to open the resolve cache: %s", err.Error()))
+	}
+	mf, secrets, err := makeManifest(imgType, config, distribution, rpmmdRepos, archName, seedArg, rpmCacheRoot, resolveCache)
 	if err != nil {
 		check(err)
 	}
@@ -379,9 +353,30 @@ func main() {
 
 	fmt.Printf("Building manifest: %s\n", manifestPath)
 
+	// the saved manifest has the secrets redacted, they are only put back
+	// for osbuild
+	buildManifest, err := manifest.MaterializeSecrets(mf, secrets)
+	check(err)
+
 	jobOutput := filepath.Join(outputDir, buildName)
-	if _, err := osbuild.RunOSBuild(mf, osbuildStore, jobOutput, imgType.Exports(), nil, nil, false, os.Stderr); err != nil {
-		check(err)
+	var res *osbuild.Result
+	if showProgress {
+		events := make(chan osbuild.MonitorEvent)
+		bar := progress.NewBar(os.Stdout, progress.IsTerminal(os.Stdout))
+		done := make(chan struct{})
+		go func() {
+			bar.Render(events)
+			close(done)
+		}()
+		res, err = osbuild.RunOSBuildWithProgress(buildManifest, osbuildStore, jobOutput, imgType.Exports(), nil, nil, os.Stderr, events)
+		<-done
+	} else {
+		res, err = osbuild.RunOSBuild(buildManifest, osbuildStore, jobOutput, imgType.Exports(), nil, nil, true, os.Stderr)
+	}
+	check(err)
+	if !res.Success {
+		reportFailure(res, mf, filepath.Join(buildDir, "failure.json"))
+		fail("osbuild failed")
 	}
 
 	fmt.Printf("Jobs done. Results saved in\n%s\n", outputDir)
@@ -6,6 +6,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"flag"
@@ -14,11 +15,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gobwas/glob"
 
 	"github.com/osbuild/images/internal/dnfjson"
 	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/build"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distroregistry"
@@ -71,6 +74,7 @@ type crBlueprint struct {
 	Modules        []blueprint.Package       `json:"modules,omitempty"`
 	Groups         []blueprint.Group         `json:"groups,omitempty"`
 	Containers     []blueprint.Container     `json:"containers,omitempty"`
+	Flatpaks       []blueprint.Flatpak       `json:"flatpaks,omitempty"`
 	Customizations *blueprint.Customizations `json:"customizations,omitempty"`
 	Distro         string                    `json:"distro,omitempty"`
 	Minimal        bool                      `json:"minimal,omitempty"`
@@ -237,6 +241,7 @@ func makeManifestJob(
 	seedArg int64,
 	path string,
 	cacheRoot string,
+	resolveCache *build.Cache,
 	content map[string]bool,
 	metadata bool,
 ) manifestJob {
@@ -274,54 +279,39 @@ func makeManifestJob(
 			bp = blueprint.Blueprint(*bc.Blueprint)
 		}
 
-		manifest, _, err := imgType.Manifest(&bp, options, rpmrepos, seedArg)
+		m, _, err := imgType.Manifest(&bp, options, rpmrepos, seedArg)
 		if err != nil {
 			err = fmt.Errorf("[%s] failed: %s", filename, err)
 			return
 		}
 
-		var packageSpecs map[string][]rpmmd.PackageSpec
+		opts := build.Options{
+			Depsolver:         mockDepsolver{},
+			ContainerResolver: mockContainerResolver{},
+			CommitResolver:    mockCommitResolver{},
+		}
+		// only real results are cached, the mocks are cheap
 		if content["packages"] {
-			packageSpecs, err = depsolve(cacheDir, manifest.GetPackageSetChains(), distribution, archName)
-			if err != nil {
-				err = fmt.Errorf("[%s] depsolve failed: %s", filename, err.Error())
-				return
-			}
-			if packageSpecs == nil {
-				err = fmt.Errorf("[%s] nil package specs", filename)
-				return
-			}
-
-			if bc.Blueprint != nil {
-				bp = blueprint.Blueprint(*bc.Blueprint)
-			}
-		} else {
-			packageSpecs = mockDepsolve(manifest.GetPackageSetChains())
+			solver := dnfjson.NewSolver(distribution.ModulePlatformID(), distribution.Releasever(), archName, distribution.Name(), cacheDir)
+			solver.SetDNFJSONPath("./dnf-json")
+			opts.Depsolver = resolveCache.Depsolver(solver, distribution.Name(), archName)
 		}
-
-		var containerSpecs map[string][]container.Spec
 		if content["containers"] {
-			containerSpecs, err = resolvePipelineContainers(manifest.GetContainerSourceSpecs(), archName)
-			if err != nil {
-				return fmt.Errorf("[%s] container resolution failed: %s", filename, err.Error())
-			}
-		} else {
-			containerSpecs = mockResolveContainers(manifest.GetContainerSourceSpecs())
+			opts.ContainerResolver = resolveCache.ContainerResolver(&build.RegistryResolver{Arch: archName}, archName)
 		}
-
-		var commitSpecs map[string][]ostree.CommitSpec
 		if content["commits"] {
-			commitSpecs, err = resolvePipelineCommits(manifest.GetOSTreeSourceSpecs())
-			if err != nil {
-				return fmt.Errorf("[%s] ostree commit resolution failed: %s", filename, err.Error())
-			}
-		} else {
-			commitSpecs = mockResolveCommits(manifest.GetOSTreeSourceSpecs())
+			opts.CommitResolver = resolveCache.CommitResolver(build.OSTreeResolver{})
 		}
 
-		mf, err := manifest.Serialize(packageSpecs, containerSpecs, commitSpecs)
+		res, err := build.Resolve(context.Background(), m, opts)
 		if err != nil {
-			return fmt.Errorf("[%s] manifest serialization failed: %s", filename, err.Error())
+			return fmt.Errorf("[%s] %s", filename, err.Error())
+		}
+		// the test manifests are built and compared as they are, they
+		// keep their secrets
+		mf, err := manifest.MaterializeSecrets(res.Manifest, res.Secrets)
+		if err != nil {
+			return fmt.Errorf("[%s] %s", filename, err.Error())
 		}
 
 		request := buildRequest{
@@ -331,7 +321,7 @@ func makeManifestJob(
 			Repositories: repos,
 			Config:       &bc,
 		}
-		err = save(mf, packageSpecs, containerSpecs, commitSpecs, request, path, filename, metadata)
+		err = save(mf, res.Packages, res.Containers, res.Commits, request, path, filename, metadata)
 		return
 	}
 	return job
@@ -407,128 +397,76 @@ func readRepos() DistroArchRepoMap {
 	return darm
 }
 
-func resolveContainers(containers []container.SourceSpec, archName string) ([]container.Spec, error) {
-	resolver := container.NewResolver(archName)
-
-	for _, c := range containers {
-		resolver.Add(c)
-	}
-
-	return resolver.Finish()
-}
-
-func resolvePipelineContainers(containerSources map[string][]container.SourceSpec, archName string) (map[string][]container.Spec, error) {
-	containerSpecs := make(map[string][]container.Spec, len(containerSources))
-	for plName, sourceSpecs := range containerSources {
-		specs, err := resolveContainers(sourceSpecs, archName)
-		if err != nil {
-			return nil, err
+// mockContainerResolver resolves containers to digests derived from their
+// names
+type mockContainerResolver struct{}
+
+func (mockContainerResolver) ResolveContainers(ctx context.Context, sourceSpecs []container.SourceSpec) ([]container.Spec, error) {
+	specs := make([]container.Spec, len(sourceSpecs))
+	for idx, src := range sourceSpecs {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(src.Name+src.Source+"digest")))
+		id := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(src.Name+src.Source+"imageid")))
+		listDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(src.Name+src.Source+"list-digest")))
+		name := src.Name
+		if name == "" {
+			name = src.Source
 		}
-		containerSpecs[plName] = specs
-	}
-	return containerSpecs, nil
-}
-
-func mockResolveContainers(containerSources map[string][]container.SourceSpec) map[string][]container.Spec {
-	containerSpecs := make(map[string][]container.Spec, len(containerSources))
-	for plName, sourceSpecs := range containerSources {
-		specs := make([]container.Spec, len(sourceSpecs))
-		for idx, src := range sourceSpecs {
-			digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(src.Name+src.Source+"digest")))
-			id := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(src.Name+src.Source+"imageid")))
-			listDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(src.Name+src.Source+"list-digest")))
-			name := src.Name
-			if name == "" {
-				name = src.Source
-			}
-			spec := container.Spec{
-				Source:     src.Source,
-				Digest:     digest,
-				TLSVerify:  src.TLSVerify,
-				ImageID:    id,
-				LocalName:  name,
-				ListDigest: listDigest,
-			}
-			specs[idx] = spec
-		}
-		containerSpecs[plName] = specs
-	}
-	return containerSpecs
-}
-
-func resolvePipelineCommits(commitSources map[string][]ostree.SourceSpec) (map[string][]ostree.CommitSpec, error) {
-	commits := make(map[string][]ostree.CommitSpec, len(commitSources))
-	for name, commitSources := range commitSources {
-		commitSpecs := make([]ostree.CommitSpec, len(commitSources))
-		for idx, commitSource := range commitSources {
-			var err error
-			commitSpecs[idx], err = ostree.Resolve(commitSource)
-			if err != nil {
-				return nil, err
-			}
+		spec := container.Spec{
+			Source:     src.Source,
+			Digest:     digest,
+			TLSVerify:  src.TLSVerify,
+			ImageID:    id,
+			LocalName:  name,
+			ListDigest: listDigest,
 		}
-		commits[name] = commitSpecs
+		specs[idx] = spec
 	}
-	return commits, nil
+	return specs, nil
 }
 
-func mockResolveCommits(commitSources map[string][]ostree.SourceSpec) map[string][]ostree.CommitSpec {
-	commits := make(map[string][]ostree.CommitSpec, len(commitSources))
-	for name, commitSources := range commitSources {
-		commitSpecs := make([]ostree.CommitSpec, len(commitSources))
-		for idx, commitSource := range commitSources {
-			checksum := fmt.Sprintf("%x", sha256.Sum256([]byte(commitSource.URL+commitSource.Ref)))
-			spec := ostree.CommitSpec{
-				Ref:      commitSource.Ref,
-				URL:      commitSource.URL,
-				Checksum: checksum,
-			}
-			if commitSource.RHSM {
-				spec.Secrets = "org.osbuild.rhsm.consumer"
-			}
-			commitSpecs[idx] = spec
+// mockCommitResolver resolves commits to checksums derived from their URLs
+// and refs
+type mockCommitResolver struct{}
+
+func (mockCommitResolver) ResolveCommits(ctx context.Context, commitSources []ostree.SourceSpec) ([]ostree.CommitSpec, error) {
+	commitSpecs := make([]ostree.CommitSpec, len(commitSources))
+	for idx, commitSource := range commitSources {
+		checksum := fmt.Sprintf("%x", sha256.Sum256([]byte(commitSource.URL+commitSource.Ref)))
+		spec := ostree.CommitSpec{
+			Ref:      commitSource.Ref,
+			URL:      commitSource.URL,
+			Checksum: checksum,
 		}
-		commits[name] = commitSpecs
-	}
-	return commits
-}
-
-func depsolve(cacheDir string, packageSets map[string][]rpmmd.PackageSet, d distro.Distro, arch string) (map[string][]rpmmd.PackageSpec, error) {
-	solver := dnfjson.NewSolver(d.ModulePlatformID(), d.Releasever(), arch, d.Name(), cacheDir)
-	solver.SetDNFJSONPath("./dnf-json")
-	depsolvedSets := make(map[string][]rpmmd.PackageSpec)
-	for name, pkgSet := range packageSets {
-		res, err := solver.Depsolve(pkgSet)
-		if err != nil {
-			return nil, err
+		if commitSource.RHSM {
+			spec.Secrets = "org.osbuild.rhsm.consumer"
 		}
-		depsolvedSets[name] = res
+		commitSpecs[idx] = spec
 	}
-	return depsolvedSets, nil
+	return commitSpecs, nil
 }
 
-func mockDepsolve(packageSets map[string][]rpmmd.PackageSet) map[string][]rpmmd.PackageSpec {
-	depsolvedSets := make(map[string][]rpmmd.PackageSpec)
-	for name, pkgSetChain := range packageSets {
-		specSet := make([]rpmmd.PackageSpec, 0)
-		for _, pkgSet := range pkgSetChain {
-			for _, pkgName := range pkgSet.Include {
-				checksum := fmt.Sprintf("%x", sha256.Sum256([]byte(pkgName)))
-				spec := rpmmd.PackageSpec{
-					Name:           pkgName,
-					Epoch:          0,
-					Version:        "0",
-					Release:        "0",
-					Arch:           "noarch",
-					RemoteLocation: fmt.Sprintf("https://example.com/repo/packages/%s", pkgName),
-					Checksum:       "sha256:" + checksum,
-				}
-				specSet = append(specSet, spec)
+// mockDepsolver resolves every package to a single noarch package with a
+// checksum derived from its name
+type mockDepsolver struct{}
+
+func (mockDepsolver) Depsolve(ctx context.Context, pkgSetChain []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
+	specSet := make([]rpmmd.PackageSpec, 0)
+	for _, pkgSet := range pkgSetChain {
+		for _, pkgName := range pkgSet.Include {
+			checksum := fmt.Sprintf("%x", sha256.Sum256([]byte(pkgName)))
+			spec := rpmmd.PackageSpec{
+				Name:           pkgName,
+				Epoch:          0,
+				Version:        "0",
+				Release:        "0",
+				Arch:           "noarch",
+				RemoteLocation: fmt.Sprintf("https://example.com/repo/packages/%s", pkgName),
+				Checksum:       "sha256:" + checksum,
 			}
+			specSet = append(specSet, spec)
 		}
-		depsolvedSets[name] = specSet
 	}
-	return depsolvedSets
+	return specSet, nil
 }
 
 func save(ms manifest.OSBuildManifest, pkgs map[string][]rpmmd.PackageSpec, containers map[string][]container.Spec, commits map[string][]ostree.CommitSpec, cr buildRequest, path, filename string, metadata bool) error {
@@ -630,6 +568,15 @@ func main() {
 	flag.BoolVar(&containers, "containers", true, "resolve container checksums")
 	flag.BoolVar(&commits, "commits", false, "resolve ostree commit IDs")
 
+	// resolve cache args
+	var resolveCacheDir string
+	var resolveCacheTTL time.Duration
+	var offline, refresh bool
+	flag.StringVar(&resolveCacheDir, "resolve-cache", "", "cache directory to reuse depsolve and resolve results between runs (disabled if empty)")
+	flag.DurationVar(&resolveCacheTTL, "resolve-cache-ttl", 24*time.Hour, "time after which cached results expire")
+	flag.BoolVar(&offline, "offline", false, "use cached depsolve results without checking the repository metadata")
+	flag.BoolVar(&refresh, "refresh", false, "invalidate the cached results")
+
 	// manifest selection args
 	var arches, distros, imgTypes multiValue
 	flag.Var(&arches, "arches", "comma-separated list of architectures (globs supported)")
@@ -643,6 +590,11 @@ func main() {
 	distroReg := distroregistry.NewDefault()
 	jobs := make([]manifestJob, 0)
 
+	resolveCache, err := build.OpenCache(resolveCacheDir, resolveCacheTTL, offline, refresh)
+	if err != nil {
+		panic(fmt.Sprintf("failed to open the resolve cache: %s", err.Error()))
+	}
+
 	contentResolve := map[string]bool{
 		"packages":   packages,
 		"containers": containers,
@@ -712,7 +664,7 @@ func main() {
 				}
 
 				for _, itConfig := range imgTypeConfigs {
-					job := makeManifestJob(itConfig.Name, imgType, itConfig, distribution, repos, archName, seedArg, outputDir, cacheRoot, contentResolve, metadata)
+					job := makeManifestJob(itConfig.Name, imgType, itConfig, distribution, repos, archName, seedArg, outputDir, cacheRoot, resolveCache, contentResolve, metadata)
 					jobs = append(jobs, job)
 				}
 			}
@@ -0,0 +1,182 @@
+// Standalone executable for uploading an image to any of the destinations
+// described by internal/target.
+//
+// The target is read as JSON in the same format that is used by
+// target.Target.UnmarshalJSON and the outcome of the upload is written as a
+// target.TargetResult JSON document.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/osbuild/images/internal/target"
+	"github.com/osbuild/images/internal/worker/clienterrors"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distroregistry"
+)
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(1)
+}
+
+func check(err error) {
+	if err != nil {
+		fail(err.Error())
+	}
+}
+
+func readTarget(path string) (*target.Target, error) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		fp, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer fp.Close()
+		r = fp
+	}
+
+	var t target.Target
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to parse target: %v", err)
+	}
+	return &t, nil
+}
+
+// readBlueprint reads the blueprint JSON file at path, an empty blueprint is
+// returned if the path is empty
+func readBlueprint(path string) (*blueprint.Blueprint, error) {
+	var bp blueprint.Blueprint
+	if path == "" {
+		return &bp, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &bp); err != nil {
+		return nil, fmt.Errorf("failed to parse blueprint: %v", err)
+	}
+	return &bp, nil
+}
+
+// imagePath returns the absolute path of the image to upload, which is the
+// export filename of the target unless the path is given
+func imagePath(path string, t *target.Target) (string, error) {
+	if path == "" {
+		path = t.OsbuildArtifact.ExportFilename
+	}
+	if path == "" {
+		return "", fmt.Errorf("no image to upload: use -image or set the export filename of the target")
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("cannot access the image: %v", err)
+	}
+	return path, nil
+}
+
+// getImageType returns the image type of the distribution for the
+// architecture
+func getImageType(distroName, arch, imageTypeName string) (distro.ImageType, error) {
+	d := distroregistry.NewDefault().GetDistro(distroName)
+	if d == nil {
+		return nil, fmt.Errorf("invalid or unsupported distribution: %q", distroName)
+	}
+	a, err := d.GetArch(arch)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetImageType(imageTypeName)
+}
+
+func writeResult(path string, result *target.TargetResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func main() {
+	var targetPath string
+	var outputPath string
+	var distroName string
+	var imageTypeName string
+	var blueprintPath string
+	var cfg uploadConfig
+
+	flag.StringVar(&targetPath, "target", "-", "path to the target JSON file, '-' reads it from standard input")
+	flag.StringVar(&cfg.Image, "image", "", "path to the image file to upload (default: the export filename of the target)")
+	flag.StringVar(&cfg.Arch, "arch", "x86_64", "architecture of the image, used when registering AWS images and to look up the image type")
+	flag.StringVar(&cfg.AzureCredentials, "azure-credentials", "", "path to the Azure credentials file, required for the org.osbuild.azure.image target")
+	flag.IntVar(&cfg.Threads, "threads", 16, "number of threads for parallel uploads, where supported")
+	flag.StringVar(&cfg.StateFile, "state", "", "path to a file to record the upload progress in, an interrupted upload is resumed from it")
+	flag.StringVar(&distroName, "distro", "", "distribution the image was built for, required to boot the image with the org.osbuild.libvirt target")
+	flag.StringVar(&imageTypeName, "image-type", "", "image type of the image, required to boot the image with the org.osbuild.libvirt target")
+	flag.StringVar(&blueprintPath, "blueprint", "", "path to the blueprint JSON file the image was built from, its users are created with cloud-init by the org.osbuild.libvirt target")
+	flag.StringVar(&outputPath, "output", "-", "path to write the target result JSON to, '-' writes it to standard output")
+	flag.Parse()
+
+	t, err := readTarget(targetPath)
+	check(err)
+
+	cfg.Image, err = imagePath(cfg.Image, t)
+	check(err)
+
+	if distroName != "" || imageTypeName != "" {
+		cfg.ImageType, err = getImageType(distroName, cfg.Arch, imageTypeName)
+		check(err)
+	}
+	cfg.Blueprint, err = readBlueprint(blueprintPath)
+	check(err)
+
+	result := upload(context.Background(), t, cfg)
+	check(writeResult(outputPath, result))
+
+	if result.TargetError != nil {
+		os.Exit(1)
+	}
+}
+
+// upload dispatches the target to the uploader registered for its name and
+// returns the target result. Failures are reported as a target error in the
+// result rather than as a Go error, the same way the worker reports them.
+func upload(ctx context.Context, t *target.Target, cfg uploadConfig) *target.TargetResult {
+	uploader, ok := uploaders[t.Name]
+	if !ok {
+		return &target.TargetResult{
+			Name:        t.Name,
+			TargetError: clienterrors.WorkerClientError(clienterrors.ErrorInvalidTarget, fmt.Sprintf("unsupported target %q", t.Name), nil),
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Uploading %s to %s\n", cfg.Image, t.Name)
+	result, err := uploader(ctx, t, cfg)
+	if err != nil {
+		if result == nil {
+			result = &target.TargetResult{Name: t.Name}
+		}
+		result.TargetError = err
+	}
+	return result
+}
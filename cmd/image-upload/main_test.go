@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/target"
+	"github.com/osbuild/images/internal/worker/clienterrors"
+)
+
+func TestUpload(t *testing.T) {
+	const testTargetName target.TargetName = "org.osbuild.test"
+
+	testCases := []struct {
+		name     string
+		target   target.TargetName
+		uploader uploadFunc
+		result   *target.TargetResult
+	}{
+		{
+			name:   "unknown-target",
+			target: target.TargetNameKoji,
+			result: &target.TargetResult{
+				Name:        target.TargetNameKoji,
+				TargetError: clienterrors.WorkerClientError(clienterrors.ErrorInvalidTarget, `unsupported target "org.osbuild.koji"`, nil),
+			},
+		},
+		{
+			name:   "success",
+			target: testTargetName,
+			uploader: func(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+				return target.NewAWSS3TargetResult(&target.AWSS3TargetResultOptions{URL: "https://example.com/" + cfg.Image}), nil
+			},
+			result: target.NewAWSS3TargetResult(&target.AWSS3TargetResultOptions{URL: "https://example.com/image.raw"}),
+		},
+		{
+			name:   "error-without-result",
+			target: testTargetName,
+			uploader: func(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+				return nil, clienterrors.WorkerClientError(clienterrors.ErrorUploadingImage, "upload failed", nil)
+			},
+			result: &target.TargetResult{
+				Name:        testTargetName,
+				TargetError: clienterrors.WorkerClientError(clienterrors.ErrorUploadingImage, "upload failed", nil),
+			},
+		},
+		{
+			name:   "error-with-result",
+			target: testTargetName,
+			uploader: func(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+				return target.NewAWSS3TargetResult(&target.AWSS3TargetResultOptions{URL: "https://example.com/partial"}), clienterrors.WorkerClientError(clienterrors.ErrorUploadingImage, "upload failed", nil)
+			},
+			result: &target.TargetResult{
+				Name:        target.TargetNameAWSS3,
+				Options:     &target.AWSS3TargetResultOptions{URL: "https://example.com/partial"},
+				TargetError: clienterrors.WorkerClientError(clienterrors.ErrorUploadingImage, "upload failed", nil),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.uploader != nil {
+				uploaders[tc.target] = tc.uploader
+				defer delete(uploaders, tc.target)
+			}
+			result := upload(context.Background(), &target.Target{Name: tc.target}, uploadConfig{Image: "image.raw"})
+			assert.Equal(t, tc.result, result)
+		})
+	}
+}
+
+func TestReadTarget(t *testing.T) {
+	dir := t.TempDir()
+	validPath := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(validPath, []byte(`{"name": "org.osbuild.aws.s3", "osbuild_artifact": {"export_filename": "image.raw"}, "options": {"region": "eu-central-1", "bucket": "images"}}`), 0600))
+	invalidPath := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalidPath, []byte(`{"name": "org.osbuild.aws.s3",`), 0600))
+
+	tgt, err := readTarget(validPath)
+	require.NoError(t, err)
+	assert.Equal(t, target.TargetNameAWSS3, tgt.Name)
+	assert.Equal(t, "image.raw", tgt.OsbuildArtifact.ExportFilename)
+	assert.Equal(t, &target.AWSS3TargetOptions{Region: "eu-central-1", Bucket: "images"}, tgt.Options)
+
+	_, err = readTarget(invalidPath)
+	assert.ErrorContains(t, err, "failed to parse target: ")
+
+	_, err = readTarget(filepath.Join(dir, "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadBlueprint(t *testing.T) {
+	dir := t.TempDir()
+
+	bp, err := readBlueprint("")
+	require.NoError(t, err)
+	assert.Empty(t, bp.Name)
+
+	validPath := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(validPath, []byte(`{"name": "test", "customizations": {"user": [{"name": "admin"}]}}`), 0600))
+	bp, err = readBlueprint(validPath)
+	require.NoError(t, err)
+	assert.Equal(t, "test", bp.Name)
+	assert.Equal(t, "admin", bp.Customizations.GetUsers()[0].Name)
+
+	invalidPath := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalidPath, []byte(`{"name": 1}`), 0600))
+	_, err = readBlueprint(invalidPath)
+	assert.ErrorContains(t, err, "failed to parse blueprint: ")
+
+	_, err = readBlueprint(filepath.Join(dir, "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestImagePath(t *testing.T) {
+	dir := t.TempDir()
+	exported := filepath.Join(dir, "disk.qcow2")
+	require.NoError(t, os.WriteFile(exported, nil, 0600))
+	given := filepath.Join(dir, "other.qcow2")
+	require.NoError(t, os.WriteFile(given, nil, 0600))
+
+	withExport := &target.Target{OsbuildArtifact: target.OsbuildArtifact{ExportFilename: exported}}
+
+	path, err := imagePath("", withExport)
+	require.NoError(t, err)
+	assert.Equal(t, exported, path)
+
+	path, err = imagePath(given, withExport)
+	require.NoError(t, err)
+	assert.Equal(t, given, path)
+
+	// relative paths are made absolute
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() { require.NoError(t, os.Chdir(wd)) }()
+	path, err = imagePath("", &target.Target{OsbuildArtifact: target.OsbuildArtifact{ExportFilename: "disk.qcow2"}})
+	require.NoError(t, err)
+	assert.Equal(t, exported, path)
+
+	_, err = imagePath("", &target.Target{})
+	assert.EqualError(t, err, "no image to upload: use -image or set the export filename of the target")
+
+	_, err = imagePath(filepath.Join(dir, "missing.qcow2"), withExport)
+	assert.ErrorContains(t, err, "cannot access the image: ")
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/google/uuid"
+	"github.com/gophercloud/gophercloud"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/images/internal/cloud/awscloud"
+	"github.com/osbuild/images/internal/cloud/gcp"
+	"github.com/osbuild/images/internal/target"
+	"github.com/osbuild/images/internal/upload/azure"
+	"github.com/osbuild/images/internal/upload/libvirt"
+	"github.com/osbuild/images/internal/upload/oci"
+	"github.com/osbuild/images/internal/upload/openstack"
+	"github.com/osbuild/images/internal/upload/transfer"
+	"github.com/osbuild/images/internal/upload/vmware"
+	"github.com/osbuild/images/internal/worker/clienterrors"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/distro"
+)
+
+// uploadConfig holds the settings of an upload that are not part of the
+// target options, either because they describe the local image or because
+// they are configured on the worker side for the corresponding worker jobs.
+type uploadConfig struct {
+	// Absolute path to the image file
+	Image string
+	// Architecture of the image
+	Arch string
+	// Path to the Azure credentials file
+	AzureCredentials string
+	// Number of threads for uploaders that support parallel transfers
+	Threads int
+	// Path to the file the progress of resumable uploads is recorded in
+	StateFile string
+	// Image type and blueprint the image was built from, required for
+	// targets that boot the image
+	ImageType distro.ImageType
+	Blueprint *blueprint.Blueprint
+}
+
+// transferOptions returns the options for uploaders based on the
+// internal/upload/transfer engine
+func (cfg uploadConfig) transferOptions() transfer.Options {
+	return transfer.Options{
+		Concurrency: cfg.Threads,
+		StateFile:   cfg.StateFile,
+		Progress: func(p transfer.Progress) {
+			done := p.Transferred + p.Skipped
+			fmt.Fprintf(os.Stderr, "\rUploaded %d/%d MiB (%d%%)", done>>20, p.Size>>20, done*100/p.Size)
+			if done == p.Size {
+				fmt.Fprintln(os.Stderr)
+			}
+		},
+	}
+}
+
+// uploadFunc uploads the image to the destination described by the target
+// and returns the target result.
+type uploadFunc func(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error)
+
+var uploaders = map[target.TargetName]uploadFunc{
+	target.TargetNameAWS:        uploadAWS,
+	target.TargetNameAWSS3:      uploadAWSS3,
+	target.TargetNameGCP:        uploadGCP,
+	target.TargetNameAzure:      uploadAzure,
+	target.TargetNameAzureImage: uploadAzureImage,
+	target.TargetNameOCI:        uploadOCI,
+	target.TargetNameContainer:  uploadContainer,
+	target.TargetNameVMWare:     uploadVMWare,
+	target.TargetNameOpenStack:  uploadOpenStack,
+	target.TargetNameLibvirt:    uploadLibvirt,
+}
+
+func invalidTargetConfig(t *target.Target) *clienterrors.Error {
+	return clienterrors.WorkerClientError(clienterrors.ErrorInvalidTargetConfig,
+		fmt.Sprintf("invalid options for target %q: %T", t.Name, t.Options), nil)
+}
+
+func uploadError(err error) *clienterrors.Error {
+	return clienterrors.WorkerClientError(clienterrors.ErrorUploadingImage, err.Error(), nil)
+}
+
+func importError(err error) *clienterrors.Error {
+	return clienterrors.WorkerClientError(clienterrors.ErrorImportingImage, err.Error(), nil)
+}
+
+// objectKey returns the given key or a random one if it is empty
+func objectKey(key string) string {
+	if key == "" {
+		return uuid.New().String()
+	}
+	return key
+}
+
+func uploadAWS(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.AWSTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	a, err := awscloud.New(options.Region, options.AccessKeyID, options.SecretAccessKey, options.SessionToken)
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	key := objectKey(options.Key)
+	if _, err := a.UploadWithOptions(ctx, cfg.Image, options.Bucket, key, cfg.transferOptions()); err != nil {
+		return nil, uploadError(err)
+	}
+
+	ami, _, err := a.Register(t.ImageName, options.Bucket, key, options.ShareWithAccounts, cfg.Arch, options.BootMode)
+	if err != nil {
+		return nil, importError(err)
+	}
+	if ami == nil {
+		return nil, importError(fmt.Errorf("no AMI returned after registering the image"))
+	}
+
+	return target.NewAWSTargetResult(&target.AWSTargetResultOptions{
+		Ami:    *ami,
+		Region: options.Region,
+	}), nil
+}
+
+func uploadAWSS3(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.AWSS3TargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	a, err := awscloud.NewForEndpoint(options.Endpoint, options.Region, options.AccessKeyID, options.SecretAccessKey, options.SessionToken, options.CABundle, options.SkipSSLVerification)
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	key := objectKey(options.Key)
+	uploadOutput, err := a.UploadWithOptions(ctx, cfg.Image, options.Bucket, key, cfg.transferOptions())
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	var url string
+	if options.Public {
+		if err := a.MarkS3ObjectAsPublic(options.Bucket, key); err != nil {
+			return nil, uploadError(err)
+		}
+		url = aws.StringValue(&uploadOutput.Location)
+	} else {
+		url, err = a.S3ObjectPresignedURL(options.Bucket, key)
+		if err != nil {
+			return nil, uploadError(err)
+		}
+	}
+
+	return target.NewAWSS3TargetResult(&target.AWSS3TargetResultOptions{URL: url}), nil
+}
+
+func uploadGCP(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.GCPTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	g, err := gcp.New(options.Credentials)
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	object := objectKey(options.Object)
+	logrus.Infof("[GCP] 🚀 Uploading image to: %s/%s", options.Bucket, object)
+	_, err = g.StorageObjectUploadWithOptions(ctx, cfg.Image, options.Bucket, object,
+		map[string]string{gcp.MetadataKeyImageName: t.ImageName}, cfg.transferOptions())
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	var regions []string
+	if options.Region != "" {
+		regions = []string{options.Region}
+	}
+
+	logrus.Infof("[GCP] 📥 Importing image into Compute Engine as '%s'", t.ImageName)
+	_, importErr := g.ComputeImageInsert(ctx, options.Bucket, object, t.ImageName, regions, gcp.GuestOsFeaturesByDistro(options.Os))
+
+	// Cleanup storage before checking for errors
+	logrus.Infof("[GCP] 🧹 Deleting uploaded image file: %s/%s", options.Bucket, object)
+	if err = g.StorageObjectDelete(ctx, options.Bucket, object); err != nil {
+		logrus.Warnf("[GCP] Encountered error while deleting object: %v", err)
+	}
+
+	if importErr != nil {
+		return nil, importError(importErr)
+	}
+
+	if len(options.ShareWithAccounts) > 0 {
+		logrus.Infof("[GCP] 🔗 Sharing the image with: %+v", options.ShareWithAccounts)
+		if err := g.ComputeImageShare(ctx, t.ImageName, options.ShareWithAccounts); err != nil {
+			return nil, clienterrors.WorkerClientError(clienterrors.ErrorSharingTarget, err.Error(), nil)
+		}
+	}
+
+	return target.NewGCPTargetResult(&target.GCPTargetResultOptions{
+		ImageName: t.ImageName,
+		ProjectID: g.GetProjectID(),
+	}), nil
+}
+
+func uploadAzure(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.AzureTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	c, err := azure.NewStorageClient(options.StorageAccount, options.StorageAccessKey)
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	blobName := t.ImageName
+	if blobName == "" {
+		blobName = filepath.Base(cfg.Image)
+	}
+	metadata := azure.BlobMetadata{
+		StorageAccount: options.StorageAccount,
+		ContainerName:  options.Container,
+		BlobName:       azure.EnsureVHDExtension(blobName),
+	}
+	if _, err := c.UploadPageBlobWithOptions(ctx, metadata, cfg.Image, cfg.transferOptions()); err != nil {
+		return nil, uploadError(err)
+	}
+
+	return target.NewAzureTargetResult(), nil
+}
+
+const azureStorageContainer = "imagebuilder"
+
+func uploadAzureImage(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.AzureImageTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	if cfg.AzureCredentials == "" {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, "the org.osbuild.azure.image target requires -azure-credentials", nil)
+	}
+	creds, err := azure.ParseAzureCredentialsFile(cfg.AzureCredentials)
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	c, err := azure.NewClient(*creds, options.TenantID)
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	location := options.Location
+	if location == "" {
+		location, err = c.GetResourceGroupLocation(ctx, options.SubscriptionID, options.ResourceGroup)
+		if err != nil {
+			return nil, uploadError(err)
+		}
+	}
+
+	// reuse a storage account created by a previous upload to the same
+	// location, otherwise create a new one
+	storageAccountTag := azure.Tag{
+		Name:  "imageBuilderStorageAccount",
+		Value: fmt.Sprintf("location=%s", location),
+	}
+	storageAccount, err := c.GetResourceNameByTag(ctx, options.SubscriptionID, options.ResourceGroup, storageAccountTag)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	if storageAccount == "" {
+		storageAccount = azure.RandomStorageAccountName("ib")
+		logrus.Infof("[Azure] 📦 Creating storage account %s", storageAccount)
+		err = c.CreateStorageAccount(ctx, options.SubscriptionID, options.ResourceGroup, storageAccount, location, storageAccountTag)
+		if err != nil {
+			return nil, uploadError(err)
+		}
+	}
+
+	storageAccessKey, err := c.GetStorageAccountKey(ctx, options.SubscriptionID, options.ResourceGroup, storageAccount)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	storageClient, err := azure.NewStorageClient(storageAccount, storageAccessKey)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	if err := storageClient.CreateStorageContainerIfNotExist(ctx, storageAccount, azureStorageContainer); err != nil {
+		return nil, uploadError(err)
+	}
+
+	blobName := azure.EnsureVHDExtension(t.ImageName)
+	metadata := azure.BlobMetadata{
+		StorageAccount: storageAccount,
+		ContainerName:  azureStorageContainer,
+		BlobName:       blobName,
+	}
+	logrus.Infof("[Azure] ⬆ Uploading image to %s/%s", storageAccount, blobName)
+	if _, err := storageClient.UploadPageBlobWithOptions(ctx, metadata, cfg.Image, cfg.transferOptions()); err != nil {
+		return nil, uploadError(err)
+	}
+
+	logrus.Infof("[Azure] 📝 Registering image %s", t.ImageName)
+	err = c.RegisterImage(ctx, options.SubscriptionID, options.ResourceGroup, storageAccount, azureStorageContainer, blobName, t.ImageName, location)
+	if err != nil {
+		return nil, importError(err)
+	}
+
+	return target.NewAzureImageTargetResult(&target.AzureImageTargetResultOptions{
+		ImageName: t.ImageName,
+	}), nil
+}
+
+func uploadOCI(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.OCITargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	uploader, err := oci.NewClient(&oci.ClientParams{
+		Tenancy:     options.Tenancy,
+		User:        options.User,
+		Region:      options.Region,
+		PrivateKey:  options.PrivateKey,
+		Fingerprint: options.Fingerprint,
+	})
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	file, err := os.Open(cfg.Image)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	defer file.Close()
+
+	objectName := fmt.Sprintf("osbuild-upload-%s", uuid.New().String())
+	imageID, err := uploader.Upload(objectName, options.Bucket, options.Namespace, file, options.Compartment, t.ImageName)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	return target.NewOCITargetResult(&target.OCITargetResultOptions{
+		Region:  options.Region,
+		ImageID: imageID,
+	}), nil
+}
+
+func uploadContainer(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.ContainerTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	client, err := container.NewClient(options.Reference)
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+	if options.Username != "" || options.Password != "" {
+		client.SetCredentials(options.Username, options.Password)
+	}
+	client.SetTLSVerify(options.TlsVerify)
+	// keep standard output for the target result
+	client.ReportWriter = os.Stderr
+
+	digest, err := client.UploadImage(ctx, fmt.Sprintf("oci-archive://%s", cfg.Image), "")
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	return target.NewContainerTargetResult(&target.ContainerTargetResultOptions{
+		URL:    client.Target.String(),
+		Digest: digest.String(),
+	}), nil
+}
+
+func uploadVMWare(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.VMWareTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	creds := vmware.Credentials{
+		Username:   options.Username,
+		Password:   options.Password,
+		Host:       options.Host,
+		Cluster:    options.Cluster,
+		Datacenter: options.Datacenter,
+		Datastore:  options.Datastore,
+		Folder:     options.Folder,
+	}
+
+	var err error
+	switch {
+	case strings.HasSuffix(cfg.Image, ".vmdk"):
+		err = vmware.ImportVmdk(creds, cfg.Image)
+	case strings.HasSuffix(cfg.Image, ".ova"):
+		err = vmware.ImportOva(creds, cfg.Image, t.ImageName)
+	default:
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidTargetConfig,
+			fmt.Sprintf("unsupported image format for VMware: %s", filepath.Base(cfg.Image)), nil)
+	}
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	return target.NewVMWareTargetResult(), nil
+}
+
+func uploadOpenStack(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.OpenStackTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	var c *openstack.Client
+	var err error
+	if options.AuthURL != "" {
+		c, err = openstack.NewClient(gophercloud.AuthOptions{
+			IdentityEndpoint:            options.AuthURL,
+			Username:                    options.Username,
+			Password:                    options.Password,
+			DomainName:                  options.DomainName,
+			TenantID:                    options.TenantID,
+			TenantName:                  options.TenantName,
+			ApplicationCredentialID:     options.ApplicationCredentialID,
+			ApplicationCredentialSecret: options.ApplicationCredentialSecret,
+		}, options.Region)
+	} else {
+		c, err = openstack.NewClientFromEnv(options.Region)
+	}
+	if err != nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidConfig, err.Error(), nil)
+	}
+
+	logrus.Infof("[OpenStack] 🚀 Uploading image %s", t.ImageName)
+	image, err := c.UploadImage(ctx, cfg.Image, openstack.ImageOptions{
+		Name:            t.ImageName,
+		DiskFormat:      options.DiskFormat,
+		ContainerFormat: options.ContainerFormat,
+		Visibility:      options.Visibility,
+		UEFI:            options.UEFI,
+		OSDistro:        options.OSDistro,
+		Properties:      options.Properties,
+		Tags:            options.Tags,
+	})
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	return target.NewOpenStackTargetResult(&target.OpenStackTargetResultOptions{
+		ImageID: image.ID,
+		Region:  options.Region,
+	}), nil
+}
+
+func uploadLibvirt(ctx context.Context, t *target.Target, cfg uploadConfig) (*target.TargetResult, *clienterrors.Error) {
+	options, ok := t.Options.(*target.LibvirtTargetOptions)
+	if !ok {
+		return nil, invalidTargetConfig(t)
+	}
+
+	if cfg.ImageType == nil {
+		return nil, clienterrors.WorkerClientError(clienterrors.ErrorInvalidTargetConfig, "the image type of the image is required, use -distro and -image-type", nil)
+	}
+	name := t.ImageName
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(cfg.Image), filepath.Ext(cfg.Image))
+	}
+
+	vmOptions := libvirt.NewVMOptions(name, cfg.Image, cfg.ImageType, cfg.Blueprint)
+	vmOptions.Pool = options.Pool
+	if options.MemoryMiB != 0 {
+		vmOptions.MemoryMiB = options.MemoryMiB
+	}
+	if options.VCPUs != 0 {
+		vmOptions.VCPUs = options.VCPUs
+	}
+
+	c := libvirt.NewClient(options.URI)
+	logrus.Infof("[libvirt] 🖥 Defining domain %s", name)
+	vm, err := c.CreateVM(ctx, vmOptions)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	return target.NewLibvirtTargetResult(&target.LibvirtTargetResultOptions{
+		Domain:     vm.Domain,
+		DomainUUID: vm.UUID,
+		Volume:     vm.Volume,
+	}), nil
+}
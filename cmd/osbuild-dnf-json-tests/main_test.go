@@ -5,15 +5,16 @@
 package main
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/osbuild/images/internal/dnfjson"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/distro"
 	rhel "github.com/osbuild/images/pkg/distro/rhel8"
-	"github.com/osbuild/images/internal/dnfjson"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
@@ -65,7 +66,7 @@ func TestCrossArchDepsolve(t *testing.T) {
 					assert.NoError(t, err)
 
 					for _, set := range manifest.GetPackageSetChains() {
-						_, err = solver.Depsolve(set)
+						_, err = solver.Depsolve(context.Background(), set)
 						assert.NoError(t, err)
 					}
 				})
@@ -105,7 +106,7 @@ func TestDepsolvePackageSets(t *testing.T) {
 
 	gotPackageSpecsSets := make(map[string][]rpmmd.PackageSpec, len(imagePkgSets))
 	for name, pkgSet := range imagePkgSets {
-		res, err := solver.Depsolve(pkgSet)
+		res, err := solver.Depsolve(context.Background(), pkgSet)
 		if err != nil {
 			require.Nil(t, err)
 		}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distroregistry"
+	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/ostree"
 
 	"github.com/osbuild/images/pkg/blueprint"
@@ -84,6 +86,8 @@ func main() {
 	flag.BoolVar(&rpmmdArg, "rpmmd", false, "output rpmmd struct instead of pipeline manifest")
 	var seedArg int64
 	flag.Int64Var(&seedArg, "seed", 0, "seed for generating manifests (default: 0)")
+	var withSecretsArg bool
+	flag.BoolVar(&withSecretsArg, "with-secrets", false, "include the secrets in the manifest instead of references, e.g. to pass it to osbuild")
 	flag.Parse()
 
 	// Path to composeRequet or '-' for stdin
@@ -196,21 +200,21 @@ func main() {
 	// let the cache grow to fit much more repository metadata than we usually allow
 	solver.SetMaxCacheSize(3 * 1024 * 1024 * 1024)
 
-	manifest, _, err := imageType.Manifest(&composeRequest.Blueprint, options, repos, seedArg)
+	mf, _, err := imageType.Manifest(&composeRequest.Blueprint, options, repos, seedArg)
 	if err != nil {
 		panic(err.Error())
 	}
 
 	depsolvedSets := make(map[string][]rpmmd.PackageSpec)
-	for name, pkgSet := range manifest.GetPackageSetChains() {
-		res, err := solver.Depsolve(pkgSet)
+	for name, pkgSet := range mf.GetPackageSetChains() {
+		res, err := solver.Depsolve(context.Background(), pkgSet)
 		if err != nil {
 			panic("Could not depsolve: " + err.Error())
 		}
 		depsolvedSets[name] = res
 	}
 
-	containerSources := manifest.GetContainerSourceSpecs()
+	containerSources := mf.GetContainerSourceSpecs()
 	containers := make(map[string][]container.Spec, len(containerSources))
 	for name, sourceSpecs := range containerSources {
 		containerSpecs, err := resolveContainers(sourceSpecs, arch.Name())
@@ -220,7 +224,7 @@ func main() {
 		containers[name] = containerSpecs
 	}
 
-	commitSources := manifest.GetOSTreeSourceSpecs()
+	commitSources := mf.GetOSTreeSourceSpecs()
 	commits := make(map[string][]ostree.CommitSpec, len(commitSources))
 	for name, commitSources := range commitSources {
 		commitSpecs := make([]ostree.CommitSpec, len(commitSources))
@@ -241,10 +245,16 @@ func main() {
 			panic(err)
 		}
 	} else {
-		ms, err := manifest.Serialize(depsolvedSets, containers, commits)
+		ms, secrets, err := mf.SerializeWithSecrets(depsolvedSets, containers, commits)
 		if err != nil {
 			panic(err.Error())
 		}
+		if withSecretsArg {
+			ms, err = manifest.MaterializeSecrets(ms, secrets)
+			if err != nil {
+				panic(err)
+			}
+		}
 
 		bytes, err = json.Marshal(ms)
 		if err != nil {
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
 	"path"
+	"time"
 
 	"github.com/osbuild/images/internal/dnfjson"
+	"github.com/osbuild/images/internal/progress"
+	"github.com/osbuild/images/pkg/build"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
@@ -34,30 +38,40 @@ func RunPlayground(img image.ImageKind, d distro.Distro, arch distro.Arch, repos
 		panic("InstantiateManifest() failed: " + err.Error())
 	}
 
-	packageSpecs := make(map[string][]rpmmd.PackageSpec)
-	for name, chain := range manifest.GetPackageSetChains() {
-		packages, err := solver.Depsolve(chain)
-		if err != nil {
-			panic(fmt.Sprintf("failed to depsolve for pipeline %s: %s\n", name, err.Error()))
-		}
-		packageSpecs[name] = packages
+	events := make(chan osbuild.MonitorEvent)
+	bar := progress.NewBar(os.Stdout, progress.IsTerminal(os.Stdout))
+	done := make(chan struct{})
+	go func() {
+		bar.Render(events)
+		close(done)
+	}()
+	opts := build.Options{
+		Depsolver:         solver,
+		ContainerResolver: &build.RegistryResolver{Arch: arch.Name()},
+		Runner: &build.OSBuild{
+			Store:           path.Join(state_dir, "osbuild-store"),
+			OutputDirectory: "./",
+			ErrorWriter:     os.Stdout,
+		},
+		Events: events,
 	}
+	if resolveCache, err := build.NewCache(path.Join(state_dir, "resolve"), 24*time.Hour); err == nil {
+		opts = resolveCache.Wrap(opts, d.Name(), arch.Name())
+	} else {
+		fmt.Fprintf(os.Stderr, "could not open the resolve cache: %s\n", err.Error())
+	}
+	res, err := build.Build(context.Background(), &manifest, opts)
+	<-done
 
 	if err := solver.CleanCache(); err != nil {
 		// print to stderr but don't exit with error
 		fmt.Fprintf(os.Stderr, "could not clean dnf cache: %s", err.Error())
 	}
 
-	bytes, err := manifest.Serialize(packageSpecs, nil, nil)
-	if err != nil {
-		panic("failed to serialize manifest: " + err.Error())
-	}
-
-	store := path.Join(state_dir, "osbuild-store")
-
-	_, err = osbuild.RunOSBuild(bytes, store, "./", manifest.GetExports(), manifest.GetCheckpoints(), nil, false, os.Stdout)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "could not run osbuild: %s", err.Error())
+		fmt.Fprintf(os.Stderr, "could not build: %s", err.Error())
+	} else if !res.OSBuild.Success {
+		fmt.Fprintln(os.Stderr, "osbuild failed")
 	}
 
 	fmt.Fprintf(os.Stderr, "built ./%s/%s (%s)\n", artifact.Export(), artifact.Filename(), artifact.MIMEType())
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/osbuild/images/internal/upload/openstack"
+)
+
+type strArrayFlag []string
+
+func (a *strArrayFlag) String() string {
+	return fmt.Sprintf("%+v", []string(*a))
+}
+
+func (a *strArrayFlag) Set(value string) error {
+	*a = append(*a, value)
+	return nil
+}
+
+func main() {
+	var region string
+	var filename string
+	var options openstack.ImageOptions
+	var properties strArrayFlag
+	var tags strArrayFlag
+	flag.StringVar(&region, "region", "", "target region (default: $OS_REGION_NAME)")
+	flag.StringVar(&filename, "image", "", "image file to upload")
+	flag.StringVar(&options.Name, "name", "", "name of the Glance image")
+	flag.StringVar(&options.DiskFormat, "disk-format", "", "disk format of the image (default: guessed from the file extension)")
+	flag.StringVar(&options.ContainerFormat, "container-format", "bare", "container format of the image")
+	flag.StringVar(&options.Visibility, "visibility", "", "visibility of the image: public, private, shared or community")
+	flag.BoolVar(&options.UEFI, "uefi", false, "boot the image with UEFI")
+	flag.StringVar(&options.OSDistro, "os-distro", "", "value of the os_distro property, e.g. fedora or rhel")
+	flag.Var(&properties, "property", "additional image property in the key=value format, can be set multiple times")
+	flag.Var(&tags, "tag", "image tag, can be set multiple times")
+	flag.Parse()
+
+	if filename == "" || options.Name == "" {
+		fmt.Fprintln(os.Stderr, "the -image and -name flags are required, the credentials are read from the OS_* environment variables")
+		os.Exit(2)
+	}
+
+	options.Properties = make(map[string]string)
+	for _, property := range properties {
+		key, value, ok := strings.Cut(property, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "invalid property %q, expected key=value\n", property)
+			os.Exit(2)
+		}
+		options.Properties[key] = value
+	}
+	options.Tags = tags
+
+	c, err := openstack.NewClientFromEnv(region)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	image, err := c.UploadImage(context.Background(), filename, options)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Printf("image %s uploaded as %s\n", image.Name, image.ID)
+}
@@ -1,78 +1,46 @@
 package openstacktest
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/gophercloud/gophercloud"
-	"github.com/gophercloud/gophercloud/openstack"
+	gopherstack "github.com/gophercloud/gophercloud/openstack"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
-	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/imagedata"
 	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+
+	"github.com/osbuild/images/internal/upload/openstack"
 )
 
 const WaitTimeout = 30 * 60 // 30 minutes in seconds
 
 func UploadImageToOpenStack(p *gophercloud.ProviderClient, imagePath string, imageName string) (*images.Image, error) {
-	client, err := openstack.NewImageServiceV2(p, gophercloud.EndpointOpts{
-		Region: os.Getenv("OS_REGION_NAME"),
-	})
+	client, err := openstack.NewClientFromProvider(p, os.Getenv("OS_REGION_NAME"))
 	if err != nil {
-		return nil, fmt.Errorf("Error creating ImageService client: %v", err)
+		return nil, err
 	}
 
-	// create a new image which gives us the ID
-	image, err := images.Create(client, images.CreateOpts{
+	return client.UploadImage(context.Background(), imagePath, openstack.ImageOptions{
 		Name:            imageName,
 		DiskFormat:      "qcow2",
 		ContainerFormat: "bare",
-	}).Extract()
-	if err != nil {
-		return image, fmt.Errorf("Creating image failed: %v", err)
-	}
-
-	// then upload the actual binary data
-	imageData, err := os.Open(imagePath)
-	if err != nil {
-		return image, fmt.Errorf("Error opening %s: %v", imagePath, err)
-	}
-	defer imageData.Close()
-
-	err = imagedata.Upload(client, image.ID, imageData).ExtractErr()
-	if err != nil {
-		return image, fmt.Errorf("Upload to OpenStack failed: %v", err)
-	}
-
-	// wait for the status to change from Queued to Active
-	err = gophercloud.WaitFor(WaitTimeout, func() (bool, error) {
-		actual, err := images.Get(client, image.ID).Extract()
-		return actual.Status == images.ImageStatusActive, err
+		Timeout:         WaitTimeout * time.Second,
 	})
-	if err != nil {
-		return image, fmt.Errorf("Waiting for image to become Active failed: %v", err)
-	}
-
-	return image, nil
 }
 
 func DeleteImageFromOpenStack(p *gophercloud.ProviderClient, imageUUID string) error {
-	client, err := openstack.NewImageServiceV2(p, gophercloud.EndpointOpts{
-		Region: os.Getenv("OS_REGION_NAME"),
-	})
-	if err != nil {
-		return fmt.Errorf("Error creating ImageService client: %v", err)
-	}
-
-	err = images.Delete(client, imageUUID).ExtractErr()
+	client, err := openstack.NewClientFromProvider(p, os.Getenv("OS_REGION_NAME"))
 	if err != nil {
-		return fmt.Errorf("cannot delete the image: %v", err)
+		return err
 	}
 
-	return nil
+	return client.DeleteImage(imageUUID)
 }
 
 func WithBootedImageInOpenStack(p *gophercloud.ProviderClient, imageID, userData string, f func(address string) error) (retErr error) {
-	client, err := openstack.NewComputeV2(p, gophercloud.EndpointOpts{
+	client, err := gopherstack.NewComputeV2(p, gophercloud.EndpointOpts{
 		Region: os.Getenv("OS_REGION_NAME"),
 	})
 	if err != nil {
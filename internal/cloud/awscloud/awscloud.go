@@ -14,15 +14,13 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/exp/slices"
 )
 
 type AWS struct {
-	uploader *s3manager.Uploader
-	ec2      *ec2.EC2
-	s3       *s3.S3
+	ec2 *ec2.EC2
+	s3  *s3.S3
 }
 
 // Create a new session from the credentials and the region and returns an *AWS object initialized with it.
@@ -37,9 +35,8 @@ func newAwsFromCreds(creds *credentials.Credentials, region string) (*AWS, error
 	}
 
 	return &AWS{
-		uploader: s3manager.NewUploader(sess),
-		ec2:      ec2.New(sess),
-		s3:       s3.New(sess),
+		ec2: ec2.New(sess),
+		s3:  s3.New(sess),
 	}, nil
 }
 
@@ -103,9 +100,8 @@ func newAwsFromCredsWithEndpoint(creds *credentials.Credentials, region, endpoin
 	}
 
 	return &AWS{
-		uploader: s3manager.NewUploader(sess),
-		ec2:      ec2.New(sess),
-		s3:       s3.New(sess),
+		ec2: ec2.New(sess),
+		s3:  s3.New(sess),
 	}, nil
 }
 
@@ -127,29 +123,6 @@ func NewForEndpointFromFile(filename, endpoint, region, caBundle string, skipSSL
 	return newAwsFromCredsWithEndpoint(credentials.NewSharedCredentials(filename, "default"), region, endpoint, caBundle, skipSSLVerification)
 }
 
-func (a *AWS) Upload(filename, bucket, key string) (*s3manager.UploadOutput, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		err := file.Close()
-		if err != nil {
-			logrus.Warnf("[AWS] ‼ Failed to close the file uploaded to S3️: %v", err)
-		}
-	}()
-
-	logrus.Infof("[AWS] 🚀 Uploading image to S3: %s/%s", bucket, key)
-	return a.uploader.Upload(
-		&s3manager.UploadInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(key),
-			Body:   file,
-		},
-	)
-}
-
 // WaitUntilImportSnapshotCompleted uses the Amazon EC2 API operation
 // DescribeImportSnapshots to wait for a condition to be met before returning.
 // If the condition is not met within the max attempt window, an error will
@@ -0,0 +1,325 @@
+package awscloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/sirupsen/logrus"
+
+	"github.com/osbuild/images/internal/upload/transfer"
+)
+
+// Limits of S3 multipart uploads, see
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/qfacts.html
+const (
+	s3MinPartSize int64 = 5 * 1024 * 1024
+	s3MaxParts    int64 = 10000
+)
+
+func (a *AWS) Upload(filename, bucket, key string) (*s3manager.UploadOutput, error) {
+	return a.UploadWithOptions(context.Background(), filename, bucket, key, transfer.Options{})
+}
+
+// UploadWithOptions is Upload with control over the transfer. The file is
+// uploaded as an S3 multipart upload with SHA-256 checksums: S3 validates
+// every part by its MD5 digest and its checksum, and the checksum of the
+// object is verified against the file once the upload is completed. Unlike
+// the ETag, the checksum doesn't depend on the encryption of the bucket.
+// S3 compatible services that don't return checksums are verified by the
+// Content-MD5 of the parts and by the ETag of the object where possible.
+// The chunk size is raised if needed to stay within the limits of S3.
+func (a *AWS) UploadWithOptions(ctx context.Context, filename, bucket, key string, opts transfer.Options) (*s3manager.UploadOutput, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	opts.ChunkSize = s3PartSize(stat.Size(), opts.ChunkSize)
+	// S3 objects are not sparse
+	opts.SkipZeroChunks = false
+
+	logrus.Infof("[AWS] 🚀 Uploading image to S3: %s/%s", bucket, key)
+	dst := &s3Destination{
+		s3:     a.s3,
+		bucket: bucket,
+		key:    key,
+	}
+	if _, err := transfer.UploadFile(ctx, filename, dst, opts); err != nil {
+		return nil, err
+	}
+	return dst.output, nil
+}
+
+// s3PartSize returns the part size to use for an object of the given size
+func s3PartSize(size, chunkSize int64) int64 {
+	if chunkSize <= 0 {
+		chunkSize = transfer.DefaultChunkSize
+	}
+	if min := (size + s3MaxParts - 1) / s3MaxParts; chunkSize < min {
+		chunkSize = min
+	}
+	if chunkSize < s3MinPartSize {
+		chunkSize = s3MinPartSize
+	}
+	return chunkSize
+}
+
+// s3PartChecksum returns the base64 encoded SHA-256 checksum S3 reports for
+// the part
+func s3PartChecksum(part transfer.Part) (string, error) {
+	sum, err := hex.DecodeString(part.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// s3MultipartChecksum returns the SHA-256 checksum S3 assigns to an object
+// uploaded in the given parts, the checksum of the part checksums followed
+// by the number of parts
+func s3MultipartChecksum(parts []transfer.Part) (string, error) {
+	h := sha256.New()
+	for _, part := range parts {
+		sum, err := hex.DecodeString(part.SHA256)
+		if err != nil {
+			return "", err
+		}
+		h.Write(sum)
+	}
+	return fmt.Sprintf("%s-%d", base64.StdEncoding.EncodeToString(h.Sum(nil)), len(parts)), nil
+}
+
+// s3MultipartETag returns the ETag S3 assigns to an object uploaded in the
+// given parts to a bucket without SSE-KMS or SSE-C encryption, the MD5 digest
+// of the part digests followed by the number of parts
+func s3MultipartETag(parts []transfer.Part) (string, error) {
+	/* #nosec G401 */
+	h := md5.New()
+	for _, part := range parts {
+		sum, err := hex.DecodeString(part.MD5)
+		if err != nil {
+			return "", err
+		}
+		h.Write(sum)
+	}
+	return fmt.Sprintf(`"%x-%d"`, h.Sum(nil), len(parts)), nil
+}
+
+// s3Destination uploads to an S3 object using a multipart upload, the
+// session is the ID of the multipart upload
+type s3Destination struct {
+	s3     *s3.S3
+	bucket string
+	key    string
+
+	output *s3manager.UploadOutput
+}
+
+func (d *s3Destination) Begin(ctx context.Context, size int64, session string) (string, error) {
+	// resume only if the multipart upload was not aborted or expired and
+	// its parts have SHA-256 checksums, or no checksums at all on services
+	// that don't support them
+	if session != "" {
+		out, err := d.s3.ListPartsWithContext(ctx, &s3.ListPartsInput{
+			Bucket:   aws.String(d.bucket),
+			Key:      aws.String(d.key),
+			UploadId: aws.String(session),
+		})
+		if err == nil {
+			algorithm := aws.StringValue(out.ChecksumAlgorithm)
+			if algorithm == s3.ChecksumAlgorithmSha256 || algorithm == "" {
+				return session, nil
+			}
+			err = fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+		}
+		logrus.Warnf("[AWS] Cannot resume the multipart upload %s, starting over: %v", session, err)
+	}
+
+	out, err := d.s3.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:            aws.String(d.bucket),
+		Key:               aws.String(d.key),
+		ChecksumAlgorithm: aws.String(s3.ChecksumAlgorithmSha256),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.UploadId), nil
+}
+
+// CheckParts keeps the parts that S3 stores with the same size and checksum.
+// Parts stored without a checksum are kept if their ETag is the MD5 digest
+// of the part, parts that can't be verified are uploaded again.
+func (d *s3Destination) CheckParts(ctx context.Context, session string, parts []transfer.Part) ([]transfer.Part, error) {
+	stored := make(map[int64]*s3.Part)
+	err := d.s3.ListPartsPagesWithContext(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(d.bucket),
+		Key:      aws.String(d.key),
+		UploadId: aws.String(session),
+	}, func(page *s3.ListPartsOutput, lastPage bool) bool {
+		for _, part := range page.Parts {
+			stored[aws.Int64Value(part.PartNumber)] = part
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []transfer.Part
+	for _, part := range parts {
+		checksum, err := s3PartChecksum(part)
+		if err != nil {
+			return nil, err
+		}
+		s := stored[int64(part.Index+1)]
+		if s == nil || aws.Int64Value(s.Size) != part.Size || !s3PartMatches(s, part, checksum) {
+			logrus.Warnf("[AWS] Part %d of the multipart upload %s is missing or changed, uploading it again", part.Index+1, session)
+			continue
+		}
+		part.ID = aws.StringValue(s.ETag)
+		kept = append(kept, part)
+	}
+	return kept, nil
+}
+
+func (d *s3Destination) WriteChunk(ctx context.Context, session string, chunk transfer.Chunk, data []byte, md5sum []byte) (string, error) {
+	sha256sum := sha256.Sum256(data)
+	checksum := base64.StdEncoding.EncodeToString(sha256sum[:])
+	out, err := d.s3.UploadPartWithContext(ctx, &s3.UploadPartInput{
+		Bucket:         aws.String(d.bucket),
+		Key:            aws.String(d.key),
+		UploadId:       aws.String(session),
+		PartNumber:     aws.Int64(int64(chunk.Index + 1)),
+		Body:           bytes.NewReader(data),
+		ContentMD5:     aws.String(base64.StdEncoding.EncodeToString(md5sum)),
+		ChecksumSHA256: aws.String(checksum),
+	})
+	if err != nil {
+		return "", err
+	}
+	// S3 rejects a part that doesn't match its checksum or Content-MD5, the
+	// checksum it returns is only compared to catch a service that ignores
+	// it. Services without checksum support don't return one and are left
+	// to the Content-MD5 check.
+	if c := aws.StringValue(out.ChecksumSHA256); c != "" && c != checksum {
+		return "", fmt.Errorf("unexpected checksum %q of part %d", c, chunk.Index+1)
+	}
+	return aws.StringValue(out.ETag), nil
+}
+
+func (d *s3Destination) Complete(ctx context.Context, session string, parts []transfer.Part, digest transfer.Digest) error {
+	// S3 refuses to complete a multipart upload without parts
+	if len(parts) == 0 {
+		_, err := d.s3.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(d.bucket),
+			Key:      aws.String(d.key),
+			UploadId: aws.String(session),
+		})
+		if err != nil {
+			return err
+		}
+		out, err := d.s3.PutObjectWithContext(ctx, &s3.PutObjectInput{
+			Bucket:         aws.String(d.bucket),
+			Key:            aws.String(d.key),
+			Body:           bytes.NewReader(nil),
+			ContentMD5:     aws.String(base64.StdEncoding.EncodeToString(digest.MD5)),
+			ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(digest.SHA256)),
+		})
+		if err != nil {
+			return err
+		}
+		d.output = &s3manager.UploadOutput{
+			Location: d.s3.Endpoint + "/" + d.bucket + "/" + d.key,
+			ETag:     out.ETag,
+		}
+		return nil
+	}
+
+	completed := make([]*s3.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		checksum, err := s3PartChecksum(part)
+		if err != nil {
+			return err
+		}
+		completed = append(completed, &s3.CompletedPart{
+			ETag:           aws.String(part.ID),
+			PartNumber:     aws.Int64(int64(part.Index + 1)),
+			ChecksumSHA256: aws.String(checksum),
+		})
+	}
+	out, err := d.s3.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(d.bucket),
+		Key:             aws.String(d.key),
+		UploadId:        aws.String(session),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s3VerifyObject(out, parts); err != nil {
+		return err
+	}
+
+	d.output = &s3manager.UploadOutput{
+		Location:  aws.StringValue(out.Location),
+		VersionID: out.VersionId,
+		UploadID:  session,
+		ETag:      out.ETag,
+	}
+	return nil
+}
+
+// Abort aborts the multipart upload, S3 then deletes its parts
+func (d *s3Destination) Abort(ctx context.Context, session string) error {
+	_, err := d.s3.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(d.bucket),
+		Key:      aws.String(d.key),
+		UploadId: aws.String(session),
+	})
+	return err
+}
+
+// s3PartMatches reports whether the stored part has the given checksum or,
+// if S3 stores it without a checksum, the MD5 digest of the part as its ETag
+func s3PartMatches(stored *s3.Part, part transfer.Part, checksum string) bool {
+	if c := aws.StringValue(stored.ChecksumSHA256); c != "" {
+		return c == checksum
+	}
+	return strings.Trim(aws.StringValue(stored.ETag), `"`) == part.MD5
+}
+
+// s3VerifyObject compares the checksum of the completed multipart upload to
+// the parts of the image. Services without checksum support are verified by
+// the multipart ETag instead, which can't be compared for encrypted buckets:
+// the object is then only verified by the Content-MD5 of its parts.
+func s3VerifyObject(out *s3.CompleteMultipartUploadOutput, parts []transfer.Part) error {
+	if checksum := aws.StringValue(out.ChecksumSHA256); checksum != "" {
+		expected, err := s3MultipartChecksum(parts)
+		if err != nil {
+			return err
+		}
+		if checksum != expected {
+			return fmt.Errorf("the checksum of the uploaded object %q does not match the image %q", checksum, expected)
+		}
+		return nil
+	}
+
+	expected, err := s3MultipartETag(parts)
+	if err != nil {
+		return err
+	}
+	if etag := aws.StringValue(out.ETag); etag != expected {
+		logrus.Warnf("[AWS] The uploaded object has no checksum and its ETag %s is not the digest of the image %s, it is only verified by the digests of its parts", etag, expected)
+	}
+	return nil
+}
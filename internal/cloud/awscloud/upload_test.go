@@ -0,0 +1,323 @@
+package awscloud
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/upload/transfer"
+)
+
+// fakeS3 is a minimal stand-in for the S3 multipart upload API. Like a
+// bucket encrypted with SSE-KMS, it returns ETags that are not the MD5 of
+// the data. With noChecksums set, it behaves like an S3 compatible service
+// without checksum support and an unencrypted bucket: it returns no
+// checksums and the ETags are MD5 digests.
+type fakeS3 struct {
+	mu          sync.Mutex
+	objects     map[string][]byte
+	uploads     map[string]map[int][]byte
+	parts       int
+	failAt      int
+	noChecksums bool
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects: make(map[string][]byte),
+		uploads: make(map[string]map[int][]byte),
+		failAt:  -1,
+	}
+}
+
+func sha256Base64(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q := r.URL.Query()
+	uploadID := q.Get("uploadId")
+	body, _ := io.ReadAll(r.Body)
+	if md5header := r.Header.Get("Content-MD5"); md5header != "" {
+		sum := md5.Sum(body)
+		if md5header != base64.StdEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<Error><Code>BadDigest</Code></Error>`)
+			return
+		}
+	}
+	if checksum := r.Header.Get("x-amz-checksum-sha256"); checksum != "" && checksum != sha256Base64(body) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `<Error><Code>BadDigest</Code></Error>`)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		if !f.noChecksums && r.Header.Get("x-amz-checksum-algorithm") != "SHA256" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		uploadID = fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, uploadID)
+	case r.Method == http.MethodGet && uploadID != "":
+		upload, ok := f.uploads[uploadID]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchUpload</Code></Error>`)
+			return
+		}
+		if f.noChecksums {
+			fmt.Fprint(w, `<ListPartsResult>`)
+			for n, data := range upload {
+				fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"%x"</ETag><Size>%d</Size></Part>`, n, md5.Sum(data), len(data))
+			}
+			fmt.Fprint(w, `</ListPartsResult>`)
+			return
+		}
+		fmt.Fprint(w, `<ListPartsResult><ChecksumAlgorithm>SHA256</ChecksumAlgorithm>`)
+		for n, data := range upload {
+			fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"kms-%d"</ETag><Size>%d</Size><ChecksumSHA256>%s</ChecksumSHA256></Part>`, n, n, len(data), sha256Base64(data))
+		}
+		fmt.Fprint(w, `</ListPartsResult>`)
+	case r.Method == http.MethodPut && uploadID != "":
+		var n int
+		fmt.Sscanf(q.Get("partNumber"), "%d", &n)
+		if n == f.failAt {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<Error><Code>AccessDenied</Code></Error>`)
+			return
+		}
+		f.uploads[uploadID][n] = body
+		f.parts++
+		if f.noChecksums {
+			w.Header().Set("ETag", fmt.Sprintf(`"%x"`, md5.Sum(body)))
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"kms-%d"`, n))
+		w.Header().Set("x-amz-checksum-sha256", sha256Base64(body))
+	case r.Method == http.MethodPost && uploadID != "":
+		var req struct {
+			Parts []struct {
+				PartNumber     int
+				ChecksumSHA256 string
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sort.Slice(req.Parts, func(i, j int) bool { return req.Parts[i].PartNumber < req.Parts[j].PartNumber })
+		var data []byte
+		h := sha256.New()
+		etag := md5.New()
+		for _, p := range req.Parts {
+			part, ok := f.uploads[uploadID][p.PartNumber]
+			if !ok || (!f.noChecksums && p.ChecksumSHA256 != sha256Base64(part)) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `<Error><Code>InvalidPart</Code></Error>`)
+				return
+			}
+			data = append(data, part...)
+			sum := sha256.Sum256(part)
+			h.Write(sum[:])
+			md5sum := md5.Sum(part)
+			etag.Write(md5sum[:])
+		}
+		f.objects[r.URL.Path] = data
+		delete(f.uploads, uploadID)
+		if f.noChecksums {
+			fmt.Fprintf(w, `<CompleteMultipartUploadResult><Location>http://%s%s</Location><ETag>"%x-%d"</ETag></CompleteMultipartUploadResult>`, r.Host, r.URL.Path, etag.Sum(nil), len(req.Parts))
+			return
+		}
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Location>http://%s%s</Location><ETag>"kms-object"</ETag><ChecksumSHA256>%s-%d</ChecksumSHA256></CompleteMultipartUploadResult>`, r.Host, r.URL.Path, base64.StdEncoding.EncodeToString(h.Sum(nil)), len(req.Parts))
+	case r.Method == http.MethodDelete && uploadID != "":
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"kms-object"`)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestS3PartSize(t *testing.T) {
+	assert.Equal(t, transfer.DefaultChunkSize, s3PartSize(1, 0))
+	assert.Equal(t, s3MinPartSize, s3PartSize(1, 1024))
+	assert.Equal(t, transfer.DefaultChunkSize, s3PartSize(1024*1024*1024, 0))
+	assert.Equal(t, int64(64*1024*1024), s3PartSize(1024*1024*1024, 64*1024*1024))
+	assert.Equal(t, int64(10737419), s3PartSize(100*1024*1024*1024, 0))
+}
+
+func TestUpload(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	a, err := NewForEndpoint(srv.URL, "us-east-1", "access-key", "secret-key", "", "", false)
+	require.NoError(t, err)
+
+	image := make([]byte, 2*s3MinPartSize+1024)
+	for i := range image {
+		image[i] = byte(i % 251)
+	}
+	filename := filepath.Join(t.TempDir(), "image.raw")
+	require.NoError(t, os.WriteFile(filename, image, 0600))
+
+	out, err := a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", transfer.Options{ChunkSize: s3MinPartSize})
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/bucket/image.raw", out.Location)
+	assert.True(t, bytes.Equal(image, fake.objects["/bucket/image.raw"]))
+	assert.Equal(t, 3, fake.parts)
+
+	empty := filepath.Join(t.TempDir(), "empty.raw")
+	require.NoError(t, os.WriteFile(empty, nil, 0600))
+	_, err = a.Upload(empty, "bucket", "empty.raw")
+	require.NoError(t, err)
+	assert.Contains(t, fake.objects, "/bucket/empty.raw")
+	assert.Empty(t, fake.uploads)
+}
+
+func TestUploadResume(t *testing.T) {
+	fake := newFakeS3()
+	fake.failAt = 2
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	a, err := NewForEndpoint(srv.URL, "us-east-1", "access-key", "secret-key", "", "", false)
+	require.NoError(t, err)
+
+	image := make([]byte, 3*s3MinPartSize)
+	for i := range image {
+		image[i] = byte(i % 251)
+	}
+	filename := filepath.Join(t.TempDir(), "image.raw")
+	require.NoError(t, os.WriteFile(filename, image, 0600))
+	opts := transfer.Options{
+		ChunkSize:   s3MinPartSize,
+		Concurrency: 1,
+		StateFile:   filepath.Join(t.TempDir(), "upload.state"),
+	}
+
+	_, err = a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", opts)
+	require.ErrorContains(t, err, "AccessDenied")
+
+	fake.failAt = -1
+	_, err = a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", opts)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(image, fake.objects["/bucket/image.raw"]))
+	// the first part is not uploaded again
+	assert.Equal(t, 3, fake.parts)
+}
+
+func TestUploadResumeLostPart(t *testing.T) {
+	fake := newFakeS3()
+	fake.failAt = 3
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	a, err := NewForEndpoint(srv.URL, "us-east-1", "access-key", "secret-key", "", "", false)
+	require.NoError(t, err)
+
+	image := make([]byte, 3*s3MinPartSize)
+	for i := range image {
+		image[i] = byte(i % 251)
+	}
+	filename := filepath.Join(t.TempDir(), "image.raw")
+	require.NoError(t, os.WriteFile(filename, image, 0600))
+	opts := transfer.Options{
+		ChunkSize:   s3MinPartSize,
+		Concurrency: 1,
+		StateFile:   filepath.Join(t.TempDir(), "upload.state"),
+	}
+
+	_, err = a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", opts)
+	require.ErrorContains(t, err, "AccessDenied")
+
+	// the recorded first part is gone from the resumed multipart upload
+	fake.failAt = -1
+	delete(fake.uploads["upload-1"], 1)
+	_, err = a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", opts)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(image, fake.objects["/bucket/image.raw"]))
+	assert.Equal(t, 4, fake.parts)
+}
+
+func TestUploadWithoutChecksums(t *testing.T) {
+	fake := newFakeS3()
+	fake.noChecksums = true
+	fake.failAt = 3
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	a, err := NewForEndpoint(srv.URL, "us-east-1", "access-key", "secret-key", "", "", false)
+	require.NoError(t, err)
+
+	image := make([]byte, 3*s3MinPartSize)
+	for i := range image {
+		image[i] = byte(i % 251)
+	}
+	filename := filepath.Join(t.TempDir(), "image.raw")
+	require.NoError(t, os.WriteFile(filename, image, 0600))
+	opts := transfer.Options{
+		ChunkSize:   s3MinPartSize,
+		Concurrency: 1,
+		StateFile:   filepath.Join(t.TempDir(), "upload.state"),
+	}
+
+	_, err = a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", opts)
+	require.ErrorContains(t, err, "AccessDenied")
+
+	// the parts stored without checksums are checked by their ETags, the
+	// changed second part is uploaded again
+	fake.failAt = -1
+	fake.uploads["upload-1"][2] = []byte("changed")
+	out, err := a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", opts)
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/bucket/image.raw", out.Location)
+	assert.True(t, bytes.Equal(image, fake.objects["/bucket/image.raw"]))
+	assert.Equal(t, 4, fake.parts)
+}
+
+func TestUploadAbort(t *testing.T) {
+	fake := newFakeS3()
+	fake.failAt = 2
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	a, err := NewForEndpoint(srv.URL, "us-east-1", "access-key", "secret-key", "", "", false)
+	require.NoError(t, err)
+
+	image := make([]byte, 3*s3MinPartSize)
+	filename := filepath.Join(t.TempDir(), "image.raw")
+	require.NoError(t, os.WriteFile(filename, image, 0600))
+
+	// without a state file the upload can't be resumed, the multipart
+	// upload is aborted
+	_, err = a.UploadWithOptions(context.Background(), filename, "bucket", "image.raw", transfer.Options{ChunkSize: s3MinPartSize, Concurrency: 1})
+	require.ErrorContains(t, err, "AccessDenied")
+	assert.Equal(t, 1, fake.parts)
+	assert.Empty(t, fake.uploads)
+	assert.Empty(t, fake.objects)
+}
@@ -36,12 +36,16 @@ var GuestOsFeaturesRHEL9 []*computepb.GuestOsFeature = []*computepb.GuestOsFeatu
 // Features list defined, nil is returned.
 func GuestOsFeaturesByDistro(distroName string) []*computepb.GuestOsFeature {
 	switch {
-	case strings.HasPrefix(distroName, "centos-8"):
+	case strings.HasPrefix(distroName, "centos-8"),
+		strings.HasPrefix(distroName, "almalinux-8"),
+		strings.HasPrefix(distroName, "rocky-8"):
 		fallthrough
 	case strings.HasPrefix(distroName, "rhel-8"):
 		return GuestOsFeaturesRHEL8
 
-	case strings.HasPrefix(distroName, "centos-9"):
+	case strings.HasPrefix(distroName, "centos-9"),
+		strings.HasPrefix(distroName, "almalinux-9"),
+		strings.HasPrefix(distroName, "rocky-9"):
 		fallthrough
 	case strings.HasPrefix(distroName, "rhel-9"):
 		return GuestOsFeaturesRHEL9
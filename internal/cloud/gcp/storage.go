@@ -2,15 +2,15 @@ package gcp
 
 import (
 	"context"
-	// gcp uses MD5 hashes
-	/* #nosec G501 */
-	"crypto/md5"
 	"fmt"
-	"io"
-	"os"
 
 	"cloud.google.com/go/storage"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+
+	"github.com/osbuild/images/internal/upload/transfer"
 )
 
 const (
@@ -22,61 +22,181 @@ const (
 )
 
 // StorageObjectUpload uploads an OS image to specified Cloud Storage bucket and object.
-// The bucket must exist. MD5 sum of the image file and uploaded object is
-// compared after the upload to verify the integrity of the uploaded image.
+// The bucket must exist. The image is uploaded in parts which are composed
+// into the object, the MD5 sum of every part and the CRC32C of the whole
+// image are verified by Cloud Storage to verify the integrity of the
+// uploaded image.
 //
 // The ObjectAttrs is returned if the object has been created.
 //
 // Uses:
 //   - Storage API
 func (g *GCP) StorageObjectUpload(ctx context.Context, filename, bucket, object string, metadata map[string]string) (*storage.ObjectAttrs, error) {
+	return g.StorageObjectUploadWithOptions(ctx, filename, bucket, object, metadata, transfer.Options{})
+}
+
+// StorageObjectUploadWithOptions is StorageObjectUpload with control over
+// the transfer.
+//
+// Uses:
+//   - Storage API
+func (g *GCP) StorageObjectUploadWithOptions(ctx context.Context, filename, bucket, object string, metadata map[string]string, opts transfer.Options) (*storage.ObjectAttrs, error) {
 	storageClient, err := storage.NewClient(ctx, option.WithCredentials(g.creds))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Storage client: %v", err)
 	}
 	defer storageClient.Close()
 
-	// Open the image file
-	imageFile, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open the image: %v", err)
+	// The Bucket MUST exist and be of a STANDARD storage class
+	dst := &storageDestination{
+		bucket:   storageClient.Bucket(bucket),
+		object:   object,
+		metadata: metadata,
 	}
-	defer imageFile.Close()
-
-	// Compute MD5 checksum of the image file for later verification
-	// gcp uses MD5 hashes
-	/* #nosec G401 */
-	imageFileHash := md5.New()
-	if _, err := io.Copy(imageFileHash, imageFile); err != nil {
-		return nil, fmt.Errorf("cannot create md5 of the image: %v", err)
+	// Cloud Storage objects are not sparse
+	opts.SkipZeroChunks = false
+	if _, err := transfer.UploadFile(ctx, filename, dst, opts); err != nil {
+		return nil, err
 	}
-	// Move the cursor of opened file back to the start
-	if _, err := imageFile.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("cannot seek the image: %v", err)
+	return dst.attrs, nil
+}
+
+// storageMaxComposeSources is the maximum number of objects that can be
+// composed in a single request, see
+// https://cloud.google.com/storage/docs/composite-objects
+const storageMaxComposeSources = 32
+
+// storageDestination uploads the chunks of an image as temporary objects
+// and composes them into the final object. The session is the prefix of the
+// temporary objects.
+type storageDestination struct {
+	bucket   *storage.BucketHandle
+	object   string
+	metadata map[string]string
+
+	attrs *storage.ObjectAttrs
+}
+
+func (d *storageDestination) Begin(ctx context.Context, size int64, session string) (string, error) {
+	// resume only if the parts of the interrupted upload still exist
+	if session != "" {
+		_, err := d.bucket.Objects(ctx, &storage.Query{Prefix: session + "/"}).Next()
+		if err == nil {
+			return session, nil
+		}
 	}
+	return fmt.Sprintf("%s.parts-%s", d.object, uuid.New().String()), nil
+}
 
-	// Upload the image
-	// The Bucket MUST exist and be of a STANDARD storage class
-	obj := storageClient.Bucket(bucket).Object(object)
-	wc := obj.NewWriter(ctx)
+func (d *storageDestination) partName(session string, index int) string {
+	return fmt.Sprintf("%s/%06d", session, index)
+}
 
+func (d *storageDestination) WriteChunk(ctx context.Context, session string, chunk transfer.Chunk, data []byte, md5sum []byte) (string, error) {
+	wc := d.bucket.Object(d.partName(session, chunk.Index)).NewWriter(ctx)
 	// Uploaded data is rejected if its MD5 hash does not match the set value.
-	wc.MD5 = imageFileHash.Sum(nil)
+	wc.MD5 = md5sum
+	// upload the part in a single request
+	wc.ChunkSize = 0
 
-	if metadata != nil {
-		wc.ObjectAttrs.Metadata = metadata
+	if _, err := wc.Write(data); err != nil {
+		return "", fmt.Errorf("uploading the image failed: %v", err)
 	}
+	// The object will not be available until Close has been called.
+	if err := wc.Close(); err != nil {
+		return "", fmt.Errorf("Writer.Close: %v", err)
+	}
+	return wc.Attrs().Name, nil
+}
+
+func (d *storageDestination) Complete(ctx context.Context, session string, parts []transfer.Part, digest transfer.Digest) error {
+	final := d.bucket.Object(d.object)
 
-	if _, err = io.Copy(wc, imageFile); err != nil {
-		return nil, fmt.Errorf("uploading the image failed: %v", err)
+	if len(parts) == 0 {
+		wc := final.NewWriter(ctx)
+		wc.MD5 = digest.MD5
+		wc.ObjectAttrs.Metadata = d.metadata
+		if err := wc.Close(); err != nil {
+			return fmt.Errorf("Writer.Close: %v", err)
+		}
+		d.attrs = wc.Attrs()
+		return nil
 	}
 
-	// The object will not be available until Close has been called.
-	if err := wc.Close(); err != nil {
-		return nil, fmt.Errorf("Writer.Close: %v", err)
+	sources := make([]string, 0, len(parts))
+	for _, part := range parts {
+		sources = append(sources, part.ID)
+	}
+	temporary := append([]string{}, sources...)
+	defer func() {
+		for _, name := range temporary {
+			if err := d.bucket.Object(name).Delete(context.Background()); err != nil {
+				logrus.Warnf("[GCP] Failed to delete the temporary object %s: %v", name, err)
+			}
+		}
+	}()
+
+	// compose the parts in several rounds if there are more than can be
+	// composed at once
+	for round := 0; len(sources) > storageMaxComposeSources; round++ {
+		var composed []string
+		for i := 0; i < len(sources); i += storageMaxComposeSources {
+			end := i + storageMaxComposeSources
+			if end > len(sources) {
+				end = len(sources)
+			}
+			name := fmt.Sprintf("%s/compose-%d-%06d", session, round, len(composed))
+			if _, err := d.compose(d.bucket.Object(name), sources[i:end]).Run(ctx); err != nil {
+				return fmt.Errorf("composing the image failed: %v", err)
+			}
+			composed = append(composed, name)
+			temporary = append(temporary, name)
+		}
+		sources = composed
+	}
+
+	// The object is rejected if its CRC32C does not match the image
+	composer := d.compose(final, sources)
+	composer.ObjectAttrs.Metadata = d.metadata
+	composer.CRC32C = digest.CRC32C
+	composer.SendCRC32C = true
+	attrs, err := composer.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("composing the image failed: %v", err)
 	}
+	d.attrs = attrs
+	return nil
+}
 
-	return wc.Attrs(), nil
+// Abort deletes the temporary objects of the upload
+func (d *storageDestination) Abort(ctx context.Context, session string) error {
+	it := d.bucket.Objects(ctx, &storage.Query{Prefix: session + "/"})
+	var failed int
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("listing the temporary objects failed: %v", err)
+		}
+		if err := d.bucket.Object(attrs.Name).Delete(ctx); err != nil {
+			logrus.Warnf("[GCP] Failed to delete the temporary object %s: %v", attrs.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d temporary objects of %s", failed, session)
+	}
+	return nil
+}
+
+func (d *storageDestination) compose(dst *storage.ObjectHandle, sources []string) *storage.Composer {
+	handles := make([]*storage.ObjectHandle, 0, len(sources))
+	for _, name := range sources {
+		handles = append(handles, d.bucket.Object(name))
+	}
+	return dst.ComposerFrom(handles...)
 }
 
 // StorageObjectDelete deletes the given object from a bucket.
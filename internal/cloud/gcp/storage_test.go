@@ -0,0 +1,149 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+
+	"github.com/osbuild/images/internal/upload/transfer"
+)
+
+// fakeStorage is a minimal stand-in for the Cloud Storage JSON API of a
+// single bucket, it supports multipart uploads, listing and deleting
+// objects. Uploads of objects whose name ends with failSuffix are rejected.
+type fakeStorage struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	failSuffix string
+}
+
+func (f *fakeStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const objectsPath = "/storage/v1/b/bucket/o"
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload"+objectsPath:
+		name, data, err := readMultipartUpload(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if f.failSuffix != "" && strings.HasSuffix(name, f.failSuffix) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"error": {"code": 403, "message": "access denied"}}`)
+			return
+		}
+		f.objects[name] = data
+		writeJSON(w, map[string]interface{}{"bucket": "bucket", "name": name, "size": fmt.Sprint(len(data))})
+	case r.Method == http.MethodGet && r.URL.Path == objectsPath:
+		prefix := r.URL.Query().Get("prefix")
+		items := []map[string]interface{}{}
+		for _, name := range f.names() {
+			if strings.HasPrefix(name, prefix) {
+				items = append(items, map[string]interface{}{"bucket": "bucket", "name": name})
+			}
+		}
+		writeJSON(w, map[string]interface{}{"kind": "storage#objects", "items": items})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, objectsPath+"/"):
+		name := strings.TrimPrefix(r.URL.Path, objectsPath+"/")
+		if _, ok := f.objects[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// names returns the sorted names of the objects, must be called with mu held
+func (f *fakeStorage) names() []string {
+	names := make([]string, 0, len(f.objects))
+	for name := range f.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readMultipartUpload(r *http.Request) (string, []byte, error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, err
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	metadata, err := mr.NextPart()
+	if err != nil {
+		return "", nil, err
+	}
+	var attrs struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(metadata).Decode(&attrs); err != nil {
+		return "", nil, err
+	}
+	media, err := mr.NextPart()
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := io.ReadAll(media)
+	return attrs.Name, data, err
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestStorageUploadAbort(t *testing.T) {
+	fake := &fakeStorage{objects: make(map[string][]byte), failSuffix: "/000002"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx, option.WithEndpoint(srv.URL+"/storage/v1/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	defer client.Close()
+
+	image := bytes.Repeat([]byte{1}, 4*1024)
+	upload := func(opts transfer.Options) error {
+		dst := &storageDestination{bucket: client.Bucket("bucket"), object: "image.raw"}
+		opts.ChunkSize = 1024
+		opts.Concurrency = 1
+		_, err := transfer.Upload(ctx, bytes.NewReader(image), int64(len(image)), dst, opts)
+		return err
+	}
+
+	// the parts written before the failure are kept to resume the upload
+	err = upload(transfer.Options{StateFile: filepath.Join(t.TempDir(), "upload.state")})
+	require.ErrorContains(t, err, "access denied")
+	fake.mu.Lock()
+	assert.Len(t, fake.objects, 2)
+	fake.objects = make(map[string][]byte)
+	fake.mu.Unlock()
+
+	// without a state file the upload is aborted and its parts are deleted
+	err = upload(transfer.Options{})
+	require.ErrorContains(t, err, "access denied")
+	fake.mu.Lock()
+	assert.Empty(t, fake.objects)
+	fake.mu.Unlock()
+}
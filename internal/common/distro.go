@@ -22,15 +22,36 @@ func GetHostDistroName() (string, bool, bool, error) {
 	}
 
 	isStream := osrelease["NAME"] == "CentOS Stream"
-
-	version := strings.Split(osrelease["VERSION_ID"], ".")
-	name := osrelease["ID"] + "-" + strings.Join(version, "")
+	name := distroName(osrelease["ID"], osrelease["VERSION_ID"])
 
 	// TODO: We should probably index these things by the full CPE
 	beta := strings.Contains(osrelease["CPE_NAME"], "beta")
 	return name, beta, isStream, nil
 }
 
+// rebuildDistros are the distributions that are only known by their major
+// version, as they don't have minor release specific repositories
+var rebuildDistros = map[string]bool{
+	"almalinux": true,
+	"rocky":     true,
+}
+
+// distroName returns the name of a distribution release from its os-release
+// ID and VERSION_ID. The version is joined without the dot ("rhel-94")
+// unless the major version has two digits ("rhel-10.0"), which would be
+// ambiguous otherwise. Rebuilds of RHEL are named by their major version
+// only ("almalinux-9").
+func distroName(id, versionID string) string {
+	major, _, _ := strings.Cut(versionID, ".")
+	if rebuildDistros[id] {
+		return id + "-" + major
+	}
+	if len(major) > 1 {
+		return id + "-" + versionID
+	}
+	return id + "-" + strings.ReplaceAll(versionID, ".", "")
+}
+
 func readOSRelease(r io.Reader) (map[string]string, error) {
 	osrelease := make(map[string]string)
 	scanner := bufio.NewScanner(r)
@@ -138,3 +138,30 @@ func TestVersionLessThan(t *testing.T) {
 		})
 	}
 }
+
+func TestDistroName(t *testing.T) {
+	cases := []struct {
+		ID        string
+		VersionID string
+		Expected  string
+	}{
+		{"fedora", "39", "fedora-39"},
+		{"centos", "9", "centos-9"},
+		{"rhel", "8.10", "rhel-810"},
+		{"rhel", "9.4", "rhel-94"},
+		{"rhel", "10.0", "rhel-10.0"},
+		{"centos", "10", "centos-10"},
+		{"almalinux", "9.4", "almalinux-9"},
+		{"almalinux", "8.10", "almalinux-8"},
+		{"rocky", "9.4", "rocky-9"},
+		{"rocky", "8.9", "rocky-8"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Expected, func(t *testing.T) {
+			if name := distroName(c.ID, c.VersionID); name != c.Expected {
+				t.Fatalf("distroName(%q, %q) returned %q", c.ID, c.VersionID, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"os"
+	"strings"
+)
+
+const FIPSEnabledImageWarning = `The host building this image is not ` +
+	`running in FIPS mode. The image will still be FIPS compliant. ` +
+	`If you have custom steps that generate keys or perform ` +
+	`cryptographic operations, those must be considered non-compliant.`
+
+// fipsEnabledFilePath is the procfs file that reports if the running kernel
+// is in FIPS mode, it can be overridden in tests.
+var fipsEnabledFilePath = "/proc/sys/crypto/fips_enabled"
+
+// IsBuildHostFIPSEnabled returns true if the host the image is built on is
+// running in FIPS mode.
+func IsBuildHostFIPSEnabled() bool {
+	content, err := os.ReadFile(fipsEnabledFilePath)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(content)) == "1"
+}
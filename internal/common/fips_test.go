@@ -0,0 +1,25 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBuildHostFIPSEnabled(t *testing.T) {
+	tmpdir := t.TempDir()
+	restore := fipsEnabledFilePath
+	defer func() { fipsEnabledFilePath = restore }()
+
+	fipsEnabledFilePath = filepath.Join(tmpdir, "missing")
+	assert.False(t, IsBuildHostFIPSEnabled())
+
+	fipsEnabledFilePath = filepath.Join(tmpdir, "fips_enabled")
+	assert.NoError(t, os.WriteFile(fipsEnabledFilePath, []byte("0\n"), 0644))
+	assert.False(t, IsBuildHostFIPSEnabled())
+
+	assert.NoError(t, os.WriteFile(fipsEnabledFilePath, []byte("1\n"), 0644))
+	assert.True(t, IsBuildHostFIPSEnabled())
+}
@@ -15,6 +15,7 @@ package dnfjson
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -132,8 +133,9 @@ func (s *Solver) GetCacheDir() string {
 // Depsolve the list of required package sets with explicit excludes using
 // their associated repositories.  Each package set is depsolved as a separate
 // transactions in a chain.  It returns a list of all packages (with solved
-// dependencies) that will be installed into the system.
-func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
+// dependencies) that will be installed into the system. dnf-json is killed
+// when the context is cancelled.
+func (s *Solver) Depsolve(ctx context.Context, pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
 	req, repoMap, err := s.makeDepsolveRequest(pkgSets)
 	if err != nil {
 		return nil, err
@@ -143,7 +145,7 @@ func (s *Solver) Depsolve(pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, erro
 	s.cache.locker.RLock()
 	defer s.cache.locker.RUnlock()
 
-	output, err := run(s.dnfJsonCmd, req)
+	output, err := run(ctx, s.dnfJsonCmd, req)
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +182,7 @@ func (s *Solver) FetchMetadata(repos []rpmmd.RepoConfig) (rpmmd.PackageList, err
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req)
+	result, err := run(context.Background(), s.dnfJsonCmd, req)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +228,7 @@ func (s *Solver) SearchMetadata(repos []rpmmd.RepoConfig, packages []string) (rp
 		return pkgs, nil
 	}
 
-	result, err := run(s.dnfJsonCmd, req)
+	result, err := run(context.Background(), s.dnfJsonCmd, req)
 	if err != nil {
 		return nil, err
 	}
@@ -615,7 +617,7 @@ func ParseError(data []byte) Error {
 	return e
 }
 
-func run(dnfJsonCmd []string, req *Request) ([]byte, error) {
+func run(ctx context.Context, dnfJsonCmd []string, req *Request) ([]byte, error) {
 	if len(dnfJsonCmd) == 0 {
 		return nil, fmt.Errorf("dnf-json command undefined")
 	}
@@ -624,7 +626,7 @@ func run(dnfJsonCmd []string, req *Request) ([]byte, error) {
 	if len(dnfJsonCmd) > 1 {
 		args = dnfJsonCmd[1:]
 	}
-	cmd := exec.Command(ex, args...)
+	cmd := exec.CommandContext(ctx, ex, args...)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -646,6 +648,9 @@ func run(dnfJsonCmd []string, req *Request) ([]byte, error) {
 	stdin.Close()
 
 	err = cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
 	output := stdout.Bytes()
 	if runError, ok := err.(*exec.ExitError); ok && runError.ExitCode() != 0 {
 		return nil, parseError(output, req.Arguments.Repos)
@@ -0,0 +1,181 @@
+package firstboot
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/pkg/blueprint"
+)
+
+const (
+	// ServiceName is the systemd unit that runs the first boot scripts
+	ServiceName = "osbuild-custom-first-boot.service"
+
+	// ScriptsDir is where scripts without an explicit path are installed
+	ScriptsDir = "/etc/osbuild/first-boot.d"
+
+	// MarkerPath is created once all scripts ran successfully, which
+	// prevents the unit from running again
+	MarkerPath = "/var/lib/osbuild-custom-first-boot.done"
+
+	runnerPath = "/etc/osbuild/run-first-boot"
+	unitPath   = "/etc/systemd/system/" + ServiceName
+
+	defaultRetryDelay = 10
+)
+
+type Script struct {
+	Path string
+	Data string
+}
+
+type Options struct {
+	// Scripts to run, in order
+	Scripts []Script
+
+	WaitForNetwork bool
+
+	// Number of times a failing script is retried and the delay between the
+	// attempts in seconds
+	Retries    uint
+	RetryDelay uint
+}
+
+// OptionsFromBP converts a validated blueprint customization to Options.
+// Scripts given as a command are wrapped in a shell script.
+func OptionsFromBP(bpFirstBoot blueprint.FirstBootCustomization) *Options {
+	options := &Options{
+		WaitForNetwork: bpFirstBoot.WaitForNetwork,
+		Retries:        uint(bpFirstBoot.Retries),
+		RetryDelay:     uint(bpFirstBoot.RetryDelay),
+	}
+	if options.RetryDelay == 0 {
+		options.RetryDelay = defaultRetryDelay
+	}
+
+	for idx, bpScript := range bpFirstBoot.Scripts {
+		script := Script{
+			Path: bpScript.Path,
+			Data: bpScript.Contents,
+		}
+		if script.Path == "" {
+			script.Path = path.Join(ScriptsDir, fmt.Sprintf("%02d-%s", idx, bpScript.Name))
+		}
+		if bpScript.Command != "" {
+			script.Data = fmt.Sprintf("#!/bin/sh\n%s\n", bpScript.Command)
+		}
+		options.Scripts = append(options.Scripts, script)
+	}
+
+	return options
+}
+
+// Directories returns the directories containing the scripts. Existing
+// directories are left untouched.
+func (o *Options) Directories() []*fsnode.Directory {
+	seen := make(map[string]bool)
+	var dirs []*fsnode.Directory
+	for _, dirPath := range append([]string{path.Dir(runnerPath)}, o.scriptDirs()...) {
+		if seen[dirPath] {
+			continue
+		}
+		seen[dirPath] = true
+
+		dir, err := fsnode.NewDirectory(dirPath, nil, nil, nil, true)
+		if err != nil {
+			panic(err)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+func (o *Options) scriptDirs() []string {
+	var dirs []string
+	for _, script := range o.Scripts {
+		dirs = append(dirs, path.Dir(script.Path))
+	}
+	return dirs
+}
+
+// Files returns the scripts, the script running them and the systemd unit.
+// The paths of the scripts must have been validated.
+func (o *Options) Files() []*fsnode.File {
+	var files []*fsnode.File
+
+	for _, script := range o.Scripts {
+		files = append(files, newFile(script.Path, 0755, script.Data))
+	}
+	files = append(files, newFile(runnerPath, 0755, o.runner()))
+	files = append(files, newFile(unitPath, 0644, o.unit()))
+
+	return files
+}
+
+// runner returns a script that runs each first boot script in order and
+// retries the ones that fail.
+func (o *Options) runner() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `#!/bin/sh
+# Generated by osbuild, runs the first boot scripts in order
+
+run() {
+	attempt=0
+	until "$1"; do
+		attempt=$((attempt + 1))
+		if [ "$attempt" -gt %[1]d ]; then
+			echo "$1 failed, giving up" >&2
+			return 1
+		fi
+		echo "$1 failed, retrying in %[2]d seconds ($attempt/%[1]d)" >&2
+		sleep %[2]d
+	done
+}
+
+`, o.Retries, o.RetryDelay)
+
+	for _, script := range o.Scripts {
+		fmt.Fprintf(&b, "run %s || exit 1\n", shellQuote(script.Path))
+	}
+
+	return b.String()
+}
+
+func (o *Options) unit() string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=Custom first boot scripts\n")
+	fmt.Fprintf(&b, "ConditionPathExists=!%s\n", MarkerPath)
+	if o.WaitForNetwork {
+		b.WriteString("Wants=network-online.target\n")
+		b.WriteString("After=network-online.target\n")
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=oneshot\n")
+	b.WriteString("RemainAfterExit=yes\n")
+	// retries may take longer than the default timeout
+	b.WriteString("TimeoutStartSec=0\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", runnerPath)
+	fmt.Fprintf(&b, "ExecStartPost=/usr/bin/touch %s\n", MarkerPath)
+	b.WriteString("StandardOutput=journal+console\n")
+	b.WriteString("StandardError=journal+console\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func newFile(filePath string, mode os.FileMode, data string) *fsnode.File {
+	file, err := fsnode.NewFile(filePath, common.ToPtr(mode), "root", "root", []byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return file
+}
@@ -0,0 +1,68 @@
+package firstboot
+
+import (
+	"testing"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsFromBP(t *testing.T) {
+	options := OptionsFromBP(blueprint.FirstBootCustomization{
+		Scripts: []blueprint.FirstBootScriptCustomization{
+			{Name: "register", Command: "/usr/bin/register --now"},
+			{Name: "setup", Path: "/etc/myapp/setup", Contents: "#!/bin/bash\necho setup\n"},
+		},
+		Retries: 2,
+	})
+
+	assert.Equal(t, &Options{
+		Scripts: []Script{
+			{Path: "/etc/osbuild/first-boot.d/00-register", Data: "#!/bin/sh\n/usr/bin/register --now\n"},
+			{Path: "/etc/myapp/setup", Data: "#!/bin/bash\necho setup\n"},
+		},
+		Retries:    2,
+		RetryDelay: defaultRetryDelay,
+	}, options)
+}
+
+func TestOptionsNodes(t *testing.T) {
+	options := &Options{
+		Scripts: []Script{
+			{Path: "/etc/osbuild/first-boot.d/00-a", Data: "#!/bin/sh\ntrue\n"},
+			{Path: "/etc/myapp/it's", Data: "#!/bin/sh\nfalse\n"},
+		},
+		WaitForNetwork: true,
+		Retries:        3,
+		RetryDelay:     5,
+	}
+
+	var dirs []string
+	for _, dir := range options.Directories() {
+		dirs = append(dirs, dir.Path())
+		assert.True(t, dir.EnsureParentDirs())
+	}
+	assert.Equal(t, []string{"/etc/osbuild", "/etc/osbuild/first-boot.d", "/etc/myapp"}, dirs)
+
+	files := make(map[string]string)
+	for _, file := range options.Files() {
+		files[file.Path()] = string(file.Data())
+	}
+	require.Len(t, files, 4)
+	assert.Equal(t, "#!/bin/sh\ntrue\n", files["/etc/osbuild/first-boot.d/00-a"])
+
+	runner := files["/etc/osbuild/run-first-boot"]
+	assert.Contains(t, runner, `if [ "$attempt" -gt 3 ]; then`)
+	assert.Contains(t, runner, "sleep 5")
+	assert.Contains(t, runner, "run '/etc/osbuild/first-boot.d/00-a' || exit 1\nrun '/etc/myapp/it'\\''s' || exit 1\n")
+
+	unit := files["/etc/systemd/system/osbuild-custom-first-boot.service"]
+	assert.Contains(t, unit, "ConditionPathExists=!/var/lib/osbuild-custom-first-boot.done\n")
+	assert.Contains(t, unit, "After=network-online.target\n")
+	assert.Contains(t, unit, "ExecStart=/etc/osbuild/run-first-boot\n")
+	assert.Contains(t, unit, "ExecStartPost=/usr/bin/touch /var/lib/osbuild-custom-first-boot.done\n")
+
+	options.WaitForNetwork = false
+	assert.NotContains(t, options.unit(), "network-online.target")
+}
@@ -1,6 +1,9 @@
 package fsnode
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"os"
 )
 
@@ -34,3 +37,66 @@ func NewFile(path string, mode *os.FileMode, user interface{}, group interface{}
 		data:       data,
 	}, nil
 }
+
+type fileJSON struct {
+	Path  string       `json:"path"`
+	Mode  *os.FileMode `json:"mode,omitempty"`
+	User  interface{}  `json:"user,omitempty"`
+	Group interface{}  `json:"group,omitempty"`
+	Data  string       `json:"data,omitempty"`
+}
+
+// MarshalJSON encodes the file in the format UnmarshalJSON decodes.
+func (f *File) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fileJSON{
+		Path:  f.path,
+		Mode:  f.mode,
+		User:  f.user,
+		Group: f.group,
+		Data:  string(f.data),
+	})
+}
+
+// UnmarshalJSON decodes a file from an object with its path, mode, user,
+// group and data. The user and group are either a name or an ID, the data is
+// the content of the file as a string.
+func (f *File) UnmarshalJSON(data []byte) error {
+	var fj fileJSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fj); err != nil {
+		return err
+	}
+	user, err := ownerFromJSON(fj.User)
+	if err != nil {
+		return fmt.Errorf("invalid user of file %q: %w", fj.Path, err)
+	}
+	group, err := ownerFromJSON(fj.Group)
+	if err != nil {
+		return fmt.Errorf("invalid group of file %q: %w", fj.Path, err)
+	}
+	var content []byte
+	if fj.Data != "" {
+		content = []byte(fj.Data)
+	}
+	file, err := NewFile(fj.Path, fj.Mode, user, group, content)
+	if err != nil {
+		return err
+	}
+	*f = *file
+	return nil
+}
+
+// ownerFromJSON converts a decoded user or group to the string or int64 that
+// file system nodes expect.
+func ownerFromJSON(owner interface{}) (interface{}, error) {
+	switch owner := owner.(type) {
+	case nil, string:
+		return owner, nil
+	case json.Number:
+		return owner.Int64()
+	default:
+		return nil, fmt.Errorf("must be a name or an ID, got %T", owner)
+	}
+}
@@ -1,6 +1,7 @@
 package fsnode
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
 
@@ -79,3 +80,68 @@ func TestNewFile(t *testing.T) {
 		})
 	}
 }
+
+func TestFileUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		json     string
+		expected *File
+		err      string
+	}{
+		{
+			name:     "path-only",
+			json:     `{"path": "/etc/file"}`,
+			expected: &File{baseFsNode: baseFsNode{path: "/etc/file"}},
+		},
+		{
+			name:     "names",
+			json:     `{"path": "/etc/file", "mode": 420, "user": "root", "group": "root", "data": "data\n"}`,
+			expected: &File{baseFsNode: baseFsNode{path: "/etc/file", mode: common.ToPtr(os.FileMode(0644)), user: "root", group: "root"}, data: []byte("data\n")},
+		},
+		{
+			name:     "ids",
+			json:     `{"path": "/etc/file", "user": 1000, "group": 1000}`,
+			expected: &File{baseFsNode: baseFsNode{path: "/etc/file", user: int64(1000), group: int64(1000)}},
+		},
+		{
+			name: "relative-path",
+			json: `{"path": "etc/file"}`,
+			err:  "path must be absolute",
+		},
+		{
+			name: "invalid-user",
+			json: `{"path": "/etc/file", "user": true}`,
+			err:  `invalid user of file "/etc/file": must be a name or an ID, got bool`,
+		},
+		{
+			name: "unknown-field",
+			json: `{"path": "/etc/file", "owner": "root"}`,
+			err:  `unknown field "owner"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var file File
+			err := json.Unmarshal([]byte(tc.json), &file)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, &file)
+		})
+	}
+}
+
+func TestFileMarshalJSON(t *testing.T) {
+	file, err := NewFile("/etc/file", common.ToPtr(os.FileMode(0600)), "root", int64(0), []byte("data"))
+	assert.NoError(t, err)
+	data, err := json.Marshal(file)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"path": "/etc/file", "mode": 384, "user": "root", "group": 0, "data": "data"}`, string(data))
+
+	var decoded File
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, file, &decoded)
+}
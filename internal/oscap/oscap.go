@@ -10,6 +10,9 @@ import (
 
 type Profile string
 
+// TailoringFormat is the format of a user supplied tailoring file
+type TailoringFormat string
+
 func (p Profile) String() string {
 	return string(p)
 }
@@ -34,32 +37,41 @@ const (
 	StigGui               Profile = "xccdf_org.ssgproject.content_profile_stig_gui"
 
 	// datastream fallbacks
-	defaultFedoraDatastream  string = "/usr/share/xml/scap/ssg/content/ssg-fedora-ds.xml"
-	defaultCentos8Datastream string = "/usr/share/xml/scap/ssg/content/ssg-centos8-ds.xml"
-	defaultCentos9Datastream string = "/usr/share/xml/scap/ssg/content/ssg-cs9-ds.xml"
-	defaultRHEL8Datastream   string = "/usr/share/xml/scap/ssg/content/ssg-rhel8-ds.xml"
-	defaultRHEL9Datastream   string = "/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml"
+	defaultFedoraDatastream string = "/usr/share/xml/scap/ssg/content/ssg-fedora-ds.xml"
+	defaultDatastreamTmpl   string = "/usr/share/xml/scap/ssg/content/ssg-%s-ds.xml"
 
 	// tailoring directory path
 	tailoringDirPath string = "/usr/share/xml/osbuild-openscap-data"
+
+	// a complete XCCDF tailoring file
+	TailoringFormatXCCDF TailoringFormat = "xccdf"
+	// the JSON tailoring format understood by autotailor
+	TailoringFormatJSON TailoringFormat = "json"
 )
 
 func DefaultFedoraDatastream() string {
 	return defaultFedoraDatastream
 }
 
-func DefaultRHEL8Datastream(isRHEL bool) string {
-	if isRHEL {
-		return defaultRHEL8Datastream
-	}
-	return defaultCentos8Datastream
-}
-
-func DefaultRHEL9Datastream(isRHEL bool) string {
-	if isRHEL {
-		return defaultRHEL9Datastream
+// DefaultDatastream returns the datastream fallback for a distribution of the
+// RHEL family, identified by its os-release ID (e.g. "rhel", "centos",
+// "almalinux" or "rocky") and its major version.
+func DefaultDatastream(id string, major uint64) string {
+	var product string
+	switch id {
+	case "centos":
+		product = fmt.Sprintf("cs%d", major)
+		if major < 9 {
+			product = fmt.Sprintf("centos%d", major)
+		}
+	case "almalinux":
+		product = fmt.Sprintf("almalinux%d", major)
+	default:
+		// Rocky Linux has no product of its own in the SCAP Security Guide
+		// and uses the RHEL content
+		product = fmt.Sprintf("rhel%d", major)
 	}
-	return defaultCentos9Datastream
+	return fmt.Sprintf(defaultDatastreamTmpl, product)
 }
 
 func IsProfileAllowed(profile string, allowlist []Profile) bool {
@@ -89,3 +101,25 @@ func GetTailoringFile(profile string) (string, string, *fsnode.Directory, error)
 
 	return newProfile, path, tailoringDir, nil
 }
+
+// GetCustomTailoringFile returns the path at which a user supplied tailoring
+// file of the given format is embedded in the image, along with the node for
+// its parent directory. An empty format defaults to XCCDF.
+func GetCustomTailoringFile(format TailoringFormat) (string, *fsnode.Directory, error) {
+	var filename string
+	switch format {
+	case "", TailoringFormatXCCDF:
+		filename = "tailoring.xml"
+	case TailoringFormatJSON:
+		filename = "tailoring.json"
+	default:
+		return "", nil, fmt.Errorf("unknown tailoring file format %q", format)
+	}
+
+	tailoringDir, err := fsnode.NewDirectory(tailoringDirPath, nil, nil, nil, true)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return filepath.Join(tailoringDirPath, filename), tailoringDir, nil
+}
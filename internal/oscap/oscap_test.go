@@ -0,0 +1,51 @@
+package oscap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCustomTailoringFile(t *testing.T) {
+	tests := []struct {
+		format   TailoringFormat
+		expected string
+		err      bool
+	}{
+		{"", "/usr/share/xml/osbuild-openscap-data/tailoring.xml", false},
+		{TailoringFormatXCCDF, "/usr/share/xml/osbuild-openscap-data/tailoring.xml", false},
+		{TailoringFormatJSON, "/usr/share/xml/osbuild-openscap-data/tailoring.json", false},
+		{"yaml", "", true},
+	}
+
+	for _, tt := range tests {
+		path, dir, err := GetCustomTailoringFile(tt.format)
+		if tt.err {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, path)
+		assert.Equal(t, "/usr/share/xml/osbuild-openscap-data", dir.Path())
+	}
+}
+
+func TestDefaultDatastream(t *testing.T) {
+	tests := []struct {
+		id       string
+		major    uint64
+		expected string
+	}{
+		{"rhel", 8, "/usr/share/xml/scap/ssg/content/ssg-rhel8-ds.xml"},
+		{"rhel", 9, "/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml"},
+		{"rhel", 10, "/usr/share/xml/scap/ssg/content/ssg-rhel10-ds.xml"},
+		{"centos", 8, "/usr/share/xml/scap/ssg/content/ssg-centos8-ds.xml"},
+		{"centos", 9, "/usr/share/xml/scap/ssg/content/ssg-cs9-ds.xml"},
+		{"centos", 10, "/usr/share/xml/scap/ssg/content/ssg-cs10-ds.xml"},
+		{"almalinux", 9, "/usr/share/xml/scap/ssg/content/ssg-almalinux9-ds.xml"},
+		{"rocky", 9, "/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, DefaultDatastream(tt.id, tt.major))
+	}
+}
@@ -46,3 +46,16 @@ var CustomFilesPolicies = NewPathPolicies(map[string]PathPolicy{
 	"/etc/passwd": {Deny: true},
 	"/etc/group":  {Deny: true},
 })
+
+// FirstBootScriptsPolicies is a set of default policies for first boot scripts
+var FirstBootScriptsPolicies = NewPathPolicies(map[string]PathPolicy{
+	"/":                         {Deny: true},
+	"/etc":                      {},
+	"/etc/fstab":                {Deny: true},
+	"/etc/shadow":               {Deny: true},
+	"/etc/passwd":               {Deny: true},
+	"/etc/group":                {Deny: true},
+	"/etc/systemd":              {Deny: true},
+	"/etc/osbuild":              {Deny: true},
+	"/etc/osbuild/first-boot.d": {},
+})
@@ -78,3 +78,36 @@ func TestMountpointPolicies(t *testing.T) {
 		})
 	}
 }
+
+func TestFirstBootScriptsPolicies(t *testing.T) {
+	type testCase struct {
+		path    string
+		allowed bool
+	}
+
+	testCases := []testCase{
+		{"/", false},
+		{"/usr/bin/script", false},
+		{"/var/lib/script", false},
+
+		{"/etc/script", true},
+		{"/etc/myapp/setup.sh", true},
+		{"/etc/fstab", false},
+		{"/etc/passwd", false},
+		{"/etc/systemd/system/script", false},
+
+		{"/etc/osbuild/run-first-boot", false},
+		{"/etc/osbuild/first-boot.d/script", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			err := FirstBootScriptsPolicies.Check(tc.path)
+			if err != nil && tc.allowed {
+				t.Errorf("expected %s to be allowed, but got error: %v", tc.path, err)
+			} else if err == nil && !tc.allowed {
+				t.Errorf("expected %s to be denied, but got no error", tc.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package progress renders the progress of osbuild runs for the command line
+// tools.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+const (
+	barWidth = 30
+
+	// number of log lines of a failed stage that are shown
+	logTailLines = 20
+)
+
+// A Bar renders osbuild monitor events. On a terminal it redraws a single
+// progress bar line, otherwise it prints a line for every finished stage
+// and pipeline. The last lines of the log of a failed stage are printed in
+// both cases.
+type Bar struct {
+	w           io.Writer
+	interactive bool
+
+	status   string
+	progress *osbuild.MonitorProgress
+	logTail  []string
+	drawn    bool
+}
+
+func NewBar(w io.Writer, interactive bool) *Bar {
+	return &Bar{
+		w:           w,
+		interactive: interactive,
+	}
+}
+
+// IsTerminal returns true if the file is a terminal, to decide whether the
+// bar is interactive.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Render updates the bar with the events until the channel is closed.
+func (b *Bar) Render(events <-chan osbuild.MonitorEvent) {
+	for event := range events {
+		b.Update(event)
+	}
+	b.Finish()
+}
+
+// Update renders a single event.
+func (b *Bar) Update(event osbuild.MonitorEvent) {
+	if event.Progress != nil {
+		b.progress = event.Progress
+	}
+
+	switch event.Type {
+	case osbuild.MonitorLog:
+		b.logTail = append(b.logTail, strings.TrimRight(event.Message, "\n"))
+		if len(b.logTail) > logTailLines {
+			b.logTail = b.logTail[len(b.logTail)-logTailLines:]
+		}
+	case osbuild.MonitorPipelineStarted:
+		b.status = fmt.Sprintf("pipeline %s", event.Pipeline)
+	case osbuild.MonitorStageStarted:
+		b.status = fmt.Sprintf("pipeline %s: %s", event.Pipeline, event.Stage)
+		b.logTail = nil
+	case osbuild.MonitorStageFinished:
+		if !event.Success {
+			b.println(fmt.Sprintf("stage %s of pipeline %s failed after %s", event.Stage, event.Pipeline, formatDuration(event.Duration)))
+			for _, line := range b.logTail {
+				b.println("  " + line)
+			}
+		} else if !b.interactive {
+			b.println(fmt.Sprintf("pipeline %s: %s finished in %s", event.Pipeline, event.Stage, formatDuration(event.Duration)))
+		}
+		b.logTail = nil
+	case osbuild.MonitorPipelineFinished:
+		result := "finished"
+		if !event.Success {
+			result = "failed"
+		}
+		if !b.interactive || !event.Success {
+			b.println(fmt.Sprintf("pipeline %s %s in %s", event.Pipeline, result, formatDuration(event.Duration)))
+		}
+	}
+
+	if b.interactive {
+		b.draw()
+	}
+}
+
+// Finish ends the line of the bar.
+func (b *Bar) Finish() {
+	if b.drawn {
+		fmt.Fprintln(b.w)
+		b.drawn = false
+	}
+}
+
+// println prints a line above the bar
+func (b *Bar) println(line string) {
+	if b.drawn {
+		// clear the bar, it's redrawn with the next update
+		fmt.Fprint(b.w, "\r\033[K")
+		b.drawn = false
+	}
+	fmt.Fprintln(b.w, line)
+}
+
+func (b *Bar) draw() {
+	fraction := b.progress.Fraction()
+	filled := int(fraction * barWidth)
+	fmt.Fprintf(b.w, "\r\033[K[%s%s] %3d%% %s", strings.Repeat("#", filled), strings.Repeat(" ", barWidth-filled), int(fraction*100), b.status)
+	b.drawn = true
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,53 @@
+package progress
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+func testEvents() []osbuild.MonitorEvent {
+	progress := &osbuild.MonitorProgress{Total: 2, Done: 1, SubProgress: &osbuild.MonitorProgress{Total: 2, Done: 0}}
+	return []osbuild.MonitorEvent{
+		{Type: osbuild.MonitorPipelineStarted, Pipeline: "os", Progress: progress},
+		{Type: osbuild.MonitorStageStarted, Pipeline: "os", Stage: "org.osbuild.rpm", Progress: progress},
+		{Type: osbuild.MonitorLog, Pipeline: "os", Stage: "org.osbuild.rpm", Message: "installing pkg1\n", Progress: progress},
+		{Type: osbuild.MonitorStageFinished, Pipeline: "os", Stage: "org.osbuild.rpm", Duration: 1234 * time.Millisecond, Success: true},
+		{Type: osbuild.MonitorStageStarted, Pipeline: "os", Stage: "org.osbuild.selinux", Progress: progress},
+		{Type: osbuild.MonitorLog, Pipeline: "os", Stage: "org.osbuild.selinux", Message: "no space left on device", Progress: progress},
+		{Type: osbuild.MonitorStageFinished, Pipeline: "os", Stage: "org.osbuild.selinux", Duration: 2 * time.Second},
+		{Type: osbuild.MonitorPipelineFinished, Pipeline: "os", Duration: 3 * time.Second},
+	}
+}
+
+func renderTestEvents(interactive bool) string {
+	var buf bytes.Buffer
+	bar := NewBar(&buf, interactive)
+	events := make(chan osbuild.MonitorEvent, 10)
+	for _, event := range testEvents() {
+		events <- event
+	}
+	close(events)
+	bar.Render(events)
+	return buf.String()
+}
+
+func TestBarPlain(t *testing.T) {
+	assert.Equal(t, `pipeline os: org.osbuild.rpm finished in 1.2s
+stage org.osbuild.selinux of pipeline os failed after 2s
+  no space left on device
+pipeline os failed in 3s
+`, renderTestEvents(false))
+}
+
+func TestBarInteractive(t *testing.T) {
+	output := renderTestEvents(true)
+	assert.Contains(t, output, "\r\033[K[###############               ]  50% pipeline os: org.osbuild.rpm")
+	assert.Contains(t, output, "\r\033[Kstage org.osbuild.selinux of pipeline os failed after 2s\n  no space left on device\n")
+	assert.NotContains(t, output, "org.osbuild.rpm finished")
+	assert.Contains(t, output, "pipeline os failed in 3s\n")
+}
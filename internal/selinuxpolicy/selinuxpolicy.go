@@ -0,0 +1,93 @@
+// Package selinuxpolicy customizes the local SELinux policy of an image.
+//
+// osbuild has no stage to change the SELinux policy, the changes are made by
+// a script that is copied into the tree and run with the org.osbuild.script
+// stage before the tree is labelled. semanage and semodule only work on the
+// policy store of the tree, they don't need a loaded policy, so the script
+// works while building the image.
+package selinuxpolicy
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+// ScriptPath is the script changing the policy. It removes itself once it
+// ran successfully.
+const ScriptPath = "/usr/local/sbin/osbuild-selinux-policy"
+
+// Options describe changes to the local SELinux policy of the tree, for the
+// policy type that is configured in the tree.
+type Options struct {
+	// SELinux booleans to set persistently
+	Booleans map[string]bool
+
+	// Additional file context rules, used when the tree is labelled
+	FileContexts []FileContext
+
+	// Paths in the tree of policy modules (.pp, .pp.bz2 or .cil) to install
+	Modules []string
+}
+
+// FileContext is a file context rule, as added by
+// `semanage fcontext --add --type <Type> <Target>`.
+type FileContext struct {
+	// Regular expression of the paths the rule applies to
+	Target string
+	// SELinux type of the matching paths
+	Type string
+}
+
+// Files returns the script changing the policy.
+func (o *Options) Files() []*fsnode.File {
+	file, err := fsnode.NewFile(ScriptPath, common.ToPtr(os.FileMode(0700)), "root", "root", []byte(o.script()))
+	if err != nil {
+		panic(err)
+	}
+	return []*fsnode.File{file}
+}
+
+// script returns the commands applying the changes. Modules are installed
+// first, so that booleans and file contexts can refer to what they define.
+func (o *Options) script() string {
+	var commands []string
+	for _, module := range o.Modules {
+		commands = append(commands, "/usr/sbin/semodule --noreload --install="+shellQuote(module))
+	}
+
+	names := make([]string, 0, len(o.Booleans))
+	for name := range o.Booleans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		state := "--off"
+		if o.Booleans[name] {
+			state = "--on"
+		}
+		commands = append(commands, fmt.Sprintf("/usr/sbin/semanage boolean --noreload --modify %s %s", state, shellQuote(name)))
+	}
+
+	for _, fc := range o.FileContexts {
+		commands = append(commands, fmt.Sprintf("/usr/sbin/semanage fcontext --noreload --add --type %s %s", shellQuote(fc.Type), shellQuote(fc.Target)))
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Generated by osbuild, customizes the local SELinux policy\n")
+	b.WriteString("set -e\n\n")
+	for _, command := range commands {
+		b.WriteString(command + "\n")
+	}
+	fmt.Fprintf(&b, "rm -f %s\n", shellQuote(ScriptPath))
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
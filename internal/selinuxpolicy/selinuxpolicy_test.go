@@ -0,0 +1,39 @@
+package selinuxpolicy
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	options := &Options{
+		Booleans: map[string]bool{
+			"httpd_can_network_connect": true,
+			"ftpd_anon_write":           false,
+		},
+		FileContexts: []FileContext{
+			{Target: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+			{Target: "/srv/it's", Type: "public_content_t"},
+		},
+		Modules: []string{"/usr/share/selinux/packages/custom.pp"},
+	}
+
+	files := options.Files()
+	require.Len(t, files, 1)
+	assert.Equal(t, ScriptPath, files[0].Path())
+	assert.Equal(t, os.FileMode(0700), *files[0].Mode())
+	assert.Equal(t, `#!/bin/sh
+# Generated by osbuild, customizes the local SELinux policy
+set -e
+
+/usr/sbin/semodule --noreload --install='/usr/share/selinux/packages/custom.pp'
+/usr/sbin/semanage boolean --noreload --modify --off 'ftpd_anon_write'
+/usr/sbin/semanage boolean --noreload --modify --on 'httpd_can_network_connect'
+/usr/sbin/semanage fcontext --noreload --add --type 'httpd_sys_content_t' '/srv/www(/.*)?'
+/usr/sbin/semanage fcontext --noreload --add --type 'public_content_t' '/srv/it'\''s'
+rm -f '/usr/local/sbin/osbuild-selinux-policy'
+`, string(files[0].Data()))
+}
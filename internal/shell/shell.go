@@ -1,11 +1,11 @@
 package shell
 
 type EnvironmentVariable struct {
-	Key   string
-	Value string
+	Key   string `json:"key"`
+	Value string `json:"value"`
 }
 
 type InitFile struct {
-	Filename  string
-	Variables []EnvironmentVariable
+	Filename  string                `json:"filename"`
+	Variables []EnvironmentVariable `json:"variables"`
 }
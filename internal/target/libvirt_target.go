@@ -0,0 +1,33 @@
+package target
+
+const TargetNameLibvirt TargetName = "org.osbuild.libvirt"
+
+type LibvirtTargetOptions struct {
+	// Connection URI of the hypervisor, defaults to qemu:///session
+	URI string `json:"uri,omitempty"`
+	// Storage pool the image is copied into, defaults to "default"
+	Pool string `json:"pool,omitempty"`
+
+	// Resources of the domain, default to the recommended resources of
+	// the image type
+	MemoryMiB uint64 `json:"memory_mib,omitempty"`
+	VCPUs     uint   `json:"vcpus,omitempty"`
+}
+
+func (LibvirtTargetOptions) isTargetOptions() {}
+
+func NewLibvirtTarget(options *LibvirtTargetOptions) *Target {
+	return newTarget(TargetNameLibvirt, options)
+}
+
+type LibvirtTargetResultOptions struct {
+	Domain     string `json:"domain"`
+	DomainUUID string `json:"domain_uuid"`
+	Volume     string `json:"volume"`
+}
+
+func (LibvirtTargetResultOptions) isTargetResultOptions() {}
+
+func NewLibvirtTargetResult(options *LibvirtTargetResultOptions) *TargetResult {
+	return newTargetResult(TargetNameLibvirt, options)
+}
@@ -0,0 +1,57 @@
+package target
+
+const TargetNameOpenStack TargetName = "org.osbuild.openstack"
+
+type OpenStackTargetOptions struct {
+	// If provided, these credentials are used by the worker to authenticate
+	// with Keystone. If not provided, the worker will try to authenticate
+	// using the OS_* environment variables.
+	AuthURL    string `json:"auth_url,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	DomainName string `json:"domain_name,omitempty"`
+	TenantID   string `json:"tenant_id,omitempty"`
+	TenantName string `json:"tenant_name,omitempty"`
+
+	// Application credentials can be used instead of the username and
+	// password
+	ApplicationCredentialID     string `json:"application_credential_id,omitempty"`
+	ApplicationCredentialSecret string `json:"application_credential_secret,omitempty"`
+
+	Region string `json:"region,omitempty"`
+
+	// Disk and container format of the Glance image, the disk format is
+	// guessed from the file extension of the image if empty and the
+	// container format defaults to "bare"
+	DiskFormat      string `json:"disk_format,omitempty"`
+	ContainerFormat string `json:"container_format,omitempty"`
+	// One of "public", "private", "shared" or "community", Glance defaults
+	// to "shared" if empty
+	Visibility string `json:"visibility,omitempty"`
+
+	// Sets the hw_firmware_type property to "uefi" to boot the image with
+	// UEFI
+	UEFI bool `json:"uefi,omitempty"`
+	// Value of the os_distro property, e.g. "fedora" or "rhel"
+	OSDistro string `json:"os_distro,omitempty"`
+	// Additional properties of the image
+	Properties map[string]string `json:"properties,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+}
+
+func (OpenStackTargetOptions) isTargetOptions() {}
+
+func NewOpenStackTarget(options *OpenStackTargetOptions) *Target {
+	return newTarget(TargetNameOpenStack, options)
+}
+
+type OpenStackTargetResultOptions struct {
+	ImageID string `json:"image_id"`
+	Region  string `json:"region,omitempty"`
+}
+
+func (OpenStackTargetResultOptions) isTargetResultOptions() {}
+
+func NewOpenStackTargetResult(options *OpenStackTargetResultOptions) *TargetResult {
+	return newTargetResult(TargetNameOpenStack, options)
+}
@@ -89,6 +89,10 @@ func (target *Target) UnmarshalJSON(data []byte) error {
 		options = new(OCITargetOptions)
 	case TargetNameContainer:
 		options = new(ContainerTargetOptions)
+	case TargetNameOpenStack:
+		options = new(OpenStackTargetOptions)
+	case TargetNameLibvirt:
+		options = new(LibvirtTargetOptions)
 	case TargetNameWorkerServer:
 		options = new(WorkerServerTargetOptions)
 	default:
@@ -258,6 +262,12 @@ func (target Target) MarshalJSON() ([]byte, error) {
 			}
 			rawOptions, err = json.Marshal(compat)
 
+		case *OpenStackTargetOptions, *LibvirtTargetOptions:
+			// These targets do not handle the backward compatibility
+			// for the Filename in target options, because it was added after
+			// the incompatible change.
+			rawOptions, err = json.Marshal(target.Options)
+
 		case *WorkerServerTargetOptions:
 			// WorkerServer target does not handle the backward compatibility
 			// for the Filename in target options, because it was added after
@@ -166,6 +166,39 @@ func TestTargetOptionsFilenameCompatibilityUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		{
+			targetJSON: []byte(`{"image_name":"my-image","name":"org.osbuild.openstack","osbuild_artifact":{"export_filename":"disk.qcow2"},"options":{"region":"RegionOne","disk_format":"qcow2","visibility":"private","uefi":true,"os_distro":"fedora","properties":{"hw_disk_bus":"scsi"}}}`),
+			expectedTarget: &Target{
+				ImageName: "my-image",
+				OsbuildArtifact: OsbuildArtifact{
+					ExportFilename: "disk.qcow2",
+				},
+				Name: TargetNameOpenStack,
+				Options: &OpenStackTargetOptions{
+					Region:     "RegionOne",
+					DiskFormat: "qcow2",
+					Visibility: "private",
+					UEFI:       true,
+					OSDistro:   "fedora",
+					Properties: map[string]string{"hw_disk_bus": "scsi"},
+				},
+			},
+		},
+		{
+			targetJSON: []byte(`{"image_name":"my-vm","name":"org.osbuild.libvirt","osbuild_artifact":{"export_filename":"disk.qcow2"},"options":{"uri":"qemu:///session","pool":"images","memory_mib":4096}}`),
+			expectedTarget: &Target{
+				ImageName: "my-vm",
+				OsbuildArtifact: OsbuildArtifact{
+					ExportFilename: "disk.qcow2",
+				},
+				Name: TargetNameLibvirt,
+				Options: &LibvirtTargetOptions{
+					URI:       "qemu:///session",
+					Pool:      "images",
+					MemoryMiB: 4096,
+				},
+			},
+		},
 		// Test that the job as Marshalled by the current compatibility code is also acceptable.
 		// Such job has Filename set in the Target options, as well in the ExportFilename.
 		{
@@ -230,6 +263,20 @@ func TestTargetOptionsFilenameCompatibilityMarshal(t *testing.T) {
 				},
 			},
 		},
+		{
+			targetJSON: []byte(`{"uuid":"00000000-0000-0000-0000-000000000000","image_name":"my-image","name":"org.osbuild.openstack","created":"0001-01-01T00:00:00Z","status":"WAITING","options":{"region":"RegionOne","uefi":true},"osbuild_artifact":{"export_filename":"disk.qcow2","export_name":""}}`),
+			target: &Target{
+				ImageName: "my-image",
+				OsbuildArtifact: OsbuildArtifact{
+					ExportFilename: "disk.qcow2",
+				},
+				Name: TargetNameOpenStack,
+				Options: &OpenStackTargetOptions{
+					Region: "RegionOne",
+					UEFI:   true,
+				},
+			},
+		},
 	}
 
 	for idx, testCase := range testCases {
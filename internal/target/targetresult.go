@@ -69,6 +69,10 @@ func UnmarshalTargetResultOptions(trName TargetName, rawOptions json.RawMessage)
 		options = new(OCITargetResultOptions)
 	case TargetNameContainer:
 		options = new(ContainerTargetResultOptions)
+	case TargetNameOpenStack:
+		options = new(OpenStackTargetResultOptions)
+	case TargetNameLibvirt:
+		options = new(LibvirtTargetResultOptions)
 	default:
 		return nil, fmt.Errorf("unexpected target result name: %s", trName)
 	}
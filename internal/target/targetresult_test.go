@@ -77,6 +77,27 @@ func TestTargetResultUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		{
+			resultJSON: []byte(`{"name":"org.osbuild.openstack","options":{"image_id":"6f1e7ae4-0f2c-4b3d-9bd2-1d4a3c3b0d7e","region":"RegionOne"}}`),
+			expectedResult: &TargetResult{
+				Name: TargetNameOpenStack,
+				Options: &OpenStackTargetResultOptions{
+					ImageID: "6f1e7ae4-0f2c-4b3d-9bd2-1d4a3c3b0d7e",
+					Region:  "RegionOne",
+				},
+			},
+		},
+		{
+			resultJSON: []byte(`{"name":"org.osbuild.libvirt","options":{"domain":"my-vm","domain_uuid":"3a8f1e2c-5b9d-4c6e-8f7a-1b2c3d4e5f60","volume":"/var/lib/libvirt/images/my-vm.qcow2"}}`),
+			expectedResult: &TargetResult{
+				Name: TargetNameLibvirt,
+				Options: &LibvirtTargetResultOptions{
+					Domain:     "my-vm",
+					DomainUUID: "3a8f1e2c-5b9d-4c6e-8f7a-1b2c3d4e5f60",
+					Volume:     "/var/lib/libvirt/images/my-vm.qcow2",
+				},
+			},
+		},
 		{
 			resultJSON: []byte(`{"name":"org.osbuild.vmware"}`),
 			expectedResult: &TargetResult{
@@ -1,20 +1,14 @@
 package azure
 
 import (
-	"bufio"
 	"bytes"
 	"context"
-	// azure uses MD5 hashes
-	/* #nosec G501 */
-	"crypto/md5"
 	"errors"
 	"fmt"
-	"io"
-	"net/url"
 	"os"
 	"regexp"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
@@ -24,12 +18,14 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/upload/transfer"
 )
 
 // StorageClient is a client for the Azure Storage API,
 // see the docs: https://docs.microsoft.com/en-us/rest/api/storageservices/
 type StorageClient struct {
 	credential *azblob.SharedKeyCredential
+	endpoint   string
 }
 
 // NewStorageClient creates a new client for Azure Storage API.
@@ -44,9 +40,34 @@ func NewStorageClient(storageAccount, storageAccessKey string) (*StorageClient,
 
 	return &StorageClient{
 		credential: credential,
+		endpoint:   fmt.Sprintf("https://%s.blob.core.windows.net", storageAccount),
 	}, nil
 }
 
+// NewStorageClientWithEndpoint creates a new client for an Azure Storage API
+// compatible service served at the given endpoint, for example Azurite's
+// http://127.0.0.1:10000/devstoreaccount1.
+func NewStorageClientWithEndpoint(endpoint, storageAccount, storageAccessKey string) (*StorageClient, error) {
+	c, err := NewStorageClient(storageAccount, storageAccessKey)
+	if err != nil {
+		return nil, err
+	}
+	c.endpoint = strings.TrimSuffix(endpoint, "/")
+	return c, nil
+}
+
+// accountURL returns the URL of the blob service of the storage account
+func (c StorageClient) accountURL(storageAccount string) string {
+	if storageAccount == c.credential.AccountName() {
+		return c.endpoint
+	}
+	return fmt.Sprintf("https://%s.blob.core.windows.net", storageAccount)
+}
+
+func (c StorageClient) blobURL(metadata BlobMetadata) string {
+	return fmt.Sprintf("%s/%s/%s", c.accountURL(metadata.StorageAccount), metadata.ContainerName, metadata.BlobName)
+}
+
 // BlobMetadata contains information needed to store the image in a proper place.
 // In case of Azure cloud storage this includes container name and blob name.
 type BlobMetadata struct {
@@ -62,17 +83,6 @@ const DefaultUploadThreads = 16
 // See https://learn.microsoft.com/en-us/rest/api/storageservices/put-page
 const PageBlobMaxUploadPagesBytes = 4 * 1024 * 1024
 
-// allZerosSlice returns true if all values in the slice are equal to 0
-func allZerosSlice(slice []byte) bool {
-	for i := 0; i < len(slice); i++ {
-		if slice[i] != 0 {
-			return false
-		}
-	}
-
-	return true
-}
-
 // UploadPageBlob takes the metadata and credentials required to upload the image specified by `fileName`
 // It can speed up the upload by using goroutines. The number of parallel goroutines is bounded by
 // the `threads` argument.
@@ -80,119 +90,149 @@ func allZerosSlice(slice []byte) bool {
 // Note that if you want to create an image out of the page blob, make sure that metadata.BlobName
 // has a .vhd extension, see EnsureVHDExtension.
 func (c StorageClient) UploadPageBlob(metadata BlobMetadata, fileName string, threads int) error {
-	// Create a page blob client.
-	URL, _ := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", metadata.StorageAccount, metadata.ContainerName, metadata.BlobName))
-	client, err := pageblob.NewClientWithSharedKeyCredential(URL.String(), c.credential, nil)
-	if err != nil {
-		return fmt.Errorf("cannot create a pageblob client: %w", err)
-	}
-
-	// Create the container, use a never-expiring context
-	ctx := context.Background()
+	_, err := c.UploadPageBlobWithOptions(context.Background(), metadata, fileName, transfer.Options{
+		Concurrency: threads,
+	})
+	return err
+}
 
-	// Open the image file for reading
-	imageFile, err := os.Open(fileName)
+// UploadPageBlobWithOptions is UploadPageBlob with control over the transfer.
+// Regions of the image that only contain zeros are never uploaded and
+// the chunk size is always PageBlobMaxUploadPagesBytes. The service
+// validates every write by its MD5 digest and the MD5 it returns is compared
+// to the one of the image. Before the upload is completed, and when it is
+// resumed, the page ranges of the blob are checked to cover every uploaded
+// chunk. The MD5 of the whole image is then set as the Content-MD5 of the
+// blob for its consumers, the service doesn't verify it.
+func (c StorageClient) UploadPageBlobWithOptions(ctx context.Context, metadata BlobMetadata, fileName string, opts transfer.Options) (*transfer.Result, error) {
+	client, err := pageblob.NewClientWithSharedKeyCredential(c.blobURL(metadata), c.credential, nil)
 	if err != nil {
-		return fmt.Errorf("cannot open the image: %v", err)
+		return nil, fmt.Errorf("cannot create a pageblob client: %w", err)
 	}
-	defer imageFile.Close()
 
-	// Stat image to get the file size
-	stat, err := imageFile.Stat()
+	stat, err := os.Stat(fileName)
 	if err != nil {
-		return fmt.Errorf("cannot stat the image: %v", err)
+		return nil, fmt.Errorf("cannot stat the image: %v", err)
 	}
-
 	if stat.Size()%512 != 0 {
-		return errors.New("size for azure image must be aligned to 512 bytes")
+		return nil, errors.New("size for azure image must be aligned to 512 bytes")
+	}
+
+	// We define the size of the blob when it's created and the blob is
+	// zero-initialized, so pushing zeros would actually be a no-op.
+	opts.ChunkSize = PageBlobMaxUploadPagesBytes
+	opts.SkipZeroChunks = true
+	return transfer.UploadFile(ctx, fileName, &pageBlobDestination{client: client}, opts)
+}
+
+// pageBlobDestination uploads to a page blob. Page blob is required for VM
+// images.
+type pageBlobDestination struct {
+	client *pageblob.Client
+}
+
+func (d *pageBlobDestination) Begin(ctx context.Context, size int64, session string) (string, error) {
+	// resume only if the blob created by the interrupted upload still
+	// exists, the session is its creation time
+	if session != "" {
+		props, err := d.client.GetProperties(ctx, nil)
+		if err == nil && props.CreationTime != nil && props.CreationTime.UTC().Format(time.RFC3339) == session {
+			return session, nil
+		}
 	}
 
-	// Hash the imageFile
-	// azure uses MD5 hashes
-	/* #nosec G401 */
-	imageFileHash := md5.New()
-	if _, err := io.Copy(imageFileHash, imageFile); err != nil {
-		return fmt.Errorf("cannot create md5 of the image: %v", err)
+	resp, err := d.client.Create(ctx, size, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot create a new page blob: %w", err)
 	}
-	// Move the cursor back to the start of the imageFile
-	if _, err := imageFile.Seek(0, 0); err != nil {
-		return fmt.Errorf("cannot seek the image: %v", err)
+	if resp.LastModified == nil {
+		return "", errors.New("cannot create a new page blob: no creation time returned")
 	}
+	return resp.LastModified.UTC().Format(time.RFC3339), nil
+}
 
-	// Create page blob. Page blob is required for VM images
-	_, err = client.Create(ctx, stat.Size(), &pageblob.CreateOptions{
-		HTTPHeaders: &blob.HTTPHeaders{
-			BlobContentMD5: imageFileHash.Sum(nil),
-		},
+func (d *pageBlobDestination) WriteChunk(ctx context.Context, session string, chunk transfer.Chunk, data []byte, md5sum []byte) (string, error) {
+	uploadRange := blob.HTTPRange{
+		Offset: chunk.Offset,
+		Count:  chunk.Size,
+	}
+	resp, err := d.client.UploadPages(ctx, common.NopSeekCloser(bytes.NewReader(data)), uploadRange, &pageblob.UploadPagesOptions{
+		TransactionalValidation: blob.TransferValidationTypeMD5(md5sum),
 	})
 	if err != nil {
-		return fmt.Errorf("cannot create a new page blob: %w", err)
+		return "", fmt.Errorf("uploading a page failed: %v", err)
+	}
+	if !bytes.Equal(resp.ContentMD5, md5sum) {
+		return "", fmt.Errorf("the service computed the MD5 %x for the page at offset %d, expected %x", resp.ContentMD5, chunk.Offset, md5sum)
 	}
+	return "", nil
+}
 
-	// Create control variables
-	// This channel simulates behavior of a semaphore and bounds the number of parallel threads
-	var semaphore = make(chan int, threads)
-	// Forward error from goroutine to the caller
-	var errorInGoroutine = make(chan error, 1)
-	var counter int64 = 0
-
-	// Create buffered reader to speed up the upload
-	reader := bufio.NewReader(imageFile)
-	// Run the upload
-	run := true
-	var wg sync.WaitGroup
-	for run {
-		buffer := make([]byte, PageBlobMaxUploadPagesBytes)
-		n, err := reader.Read(buffer)
+// pageRanges returns the ranges of the blob that have been written
+func (d *pageBlobDestination) pageRanges(ctx context.Context) ([]blob.HTTPRange, error) {
+	var ranges []blob.HTTPRange
+	pager := d.client.NewGetPageRangesPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
 		if err != nil {
-			if err == io.EOF {
-				run = false
-			} else {
-				return fmt.Errorf("reading the image failed: %v", err)
-			}
+			return nil, fmt.Errorf("cannot get the page ranges of the page blob: %w", err)
 		}
-		if n == 0 {
-			break
+		for _, r := range page.PageRange {
+			if r.Start == nil || r.End == nil {
+				continue
+			}
+			ranges = append(ranges, blob.HTTPRange{Offset: *r.Start, Count: *r.End - *r.Start + 1})
 		}
+	}
+	return ranges, nil
+}
 
-		// Skip the uploading part if there are only zeros in the buffer.
-		// We already defined the size of the blob in the initial call and the blob is zero-initialized,
-		// so this pushing zeros would actually be a no-op.
-		if allZerosSlice(buffer) {
-			counter++
-			continue
+// storedParts splits the parts into the ones that are stored in the ranges
+// and the ones that are not. Skipped parts are always stored since the blob
+// is zero-initialized.
+func storedParts(parts []transfer.Part, ranges []blob.HTTPRange) (stored, missing []transfer.Part) {
+	for _, part := range parts {
+		covered := part.Skipped
+		for _, r := range ranges {
+			if r.Offset <= part.Offset && part.Offset+part.Size <= r.Offset+r.Count {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			stored = append(stored, part)
+		} else {
+			missing = append(missing, part)
 		}
+	}
+	return stored, missing
+}
 
-		wg.Add(1)
-		semaphore <- 1
-		go func(counter int64, buffer []byte, n int) {
-			defer wg.Done()
-			uploadRange := blob.HTTPRange{
-				Offset: counter * PageBlobMaxUploadPagesBytes,
-				Count:  int64(n),
-			}
-			_, err := client.UploadPages(ctx, common.NopSeekCloser(bytes.NewReader(buffer[:n])), uploadRange, nil)
-			if err != nil {
-				err = fmt.Errorf("uploading a page failed: %v", err)
-				// Send the error to the error channel in a non-blocking way. If there is already an error, just discard this one
-				select {
-				case errorInGoroutine <- err:
-				default:
-				}
-			}
-			<-semaphore
-		}(counter, buffer, n)
-		counter++
+// CheckParts keeps the parts whose pages have been written to the blob
+func (d *pageBlobDestination) CheckParts(ctx context.Context, session string, parts []transfer.Part) ([]transfer.Part, error) {
+	ranges, err := d.pageRanges(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// Wait for all goroutines to finish
-	wg.Wait()
-	// Check any errors during the transmission using a nonblocking read from the channel
-	select {
-	case err := <-errorInGoroutine:
+	stored, _ := storedParts(parts, ranges)
+	return stored, nil
+}
+
+func (d *pageBlobDestination) Complete(ctx context.Context, session string, parts []transfer.Part, digest transfer.Digest) error {
+	ranges, err := d.pageRanges(ctx)
+	if err != nil {
 		return err
-	default:
+	}
+	if _, missing := storedParts(parts, ranges); len(missing) > 0 {
+		return fmt.Errorf("the page blob is missing the page at offset %d", missing[0].Offset)
 	}
 
+	_, err = d.client.SetHTTPHeaders(ctx, blob.HTTPHeaders{
+		BlobContentMD5: digest.MD5,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("cannot set the md5 of the page blob: %w", err)
+	}
 	return nil
 }
 
@@ -200,9 +240,9 @@ func (c StorageClient) UploadPageBlob(metadata BlobMetadata, fileName string, th
 // a storage account. If a container with the same name already exists,
 // this method is no-op.
 func (c StorageClient) CreateStorageContainerIfNotExist(ctx context.Context, storageAccount, name string) error {
-	URL, _ := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", storageAccount, name))
+	URL := fmt.Sprintf("%s/%s", c.accountURL(storageAccount), name)
 
-	cl, err := container.NewClientWithSharedKeyCredential(URL.String(), c.credential, nil)
+	cl, err := container.NewClientWithSharedKeyCredential(URL, c.credential, nil)
 	if err != nil {
 		return fmt.Errorf("cannot create a storage container client: %w", err)
 	}
@@ -233,9 +273,7 @@ func (c StorageClient) TagBlob(ctx context.Context, metadata BlobMetadata, tags
 		}
 	}
 
-	URL, _ := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", metadata.StorageAccount, metadata.ContainerName, metadata.BlobName))
-
-	client, err := blob.NewClientWithSharedKeyCredential(URL.String(), c.credential, nil)
+	client, err := blob.NewClientWithSharedKeyCredential(c.blobURL(metadata), c.credential, nil)
 	if err != nil {
 		return fmt.Errorf("cannot create a blob client: %w", err)
 	}
@@ -1,11 +1,27 @@
 package azure
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/pageblob"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/upload/transfer"
 )
 
 func TestRandomStorageAccountName(t *testing.T) {
@@ -31,3 +47,191 @@ func TestEnsureVHDExtension(t *testing.T) {
 		})
 	}
 }
+
+// fakePageBlobService is a minimal Azurite-style stand-in for the page blob
+// API
+type fakePageBlobService struct {
+	mu      sync.Mutex
+	blobs   map[string][]byte
+	created map[string]time.Time
+	ranges  map[string][][2]int64
+	md5     map[string][]byte
+	puts    int
+	corrupt bool
+	// failAt fails the write of the page at the offset
+	failAt int64
+}
+
+func newFakePageBlobService() *fakePageBlobService {
+	return &fakePageBlobService{
+		blobs:   make(map[string][]byte),
+		created: make(map[string]time.Time),
+		ranges:  make(map[string][][2]int64),
+		md5:     make(map[string][]byte),
+		failAt:  -1,
+	}
+}
+
+// lose forgets the pages written at the offset, as if the write had never
+// been committed
+func (f *fakePageBlobService) lose(path string, offset int64) {
+	var ranges [][2]int64
+	for _, r := range f.ranges[path] {
+		if r[0] != offset {
+			ranges = append(ranges, r)
+		}
+	}
+	f.ranges[path] = ranges
+	copy(f.blobs[path][offset:], make([]byte, PageBlobMaxUploadPagesBytes))
+}
+
+func (f *fakePageBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "page":
+		var start, end int64
+		if _, err := fmt.Sscanf(r.Header.Get("x-ms-range"), "bytes=%d-%d", &start, &end); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if start == f.failAt {
+			// not a server error, the client would retry it
+			w.Header().Set("x-ms-error-code", "InvalidInput")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		sum := md5.Sum(data)
+		if f.corrupt || r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			w.Header().Set("x-ms-error-code", "Md5Mismatch")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		copy(f.blobs[r.URL.Path][start:end+1], data)
+		f.ranges[r.URL.Path] = append(f.ranges[r.URL.Path], [2]int64{start, end})
+		f.puts++
+		w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && r.URL.Query().Get("comp") == "pagelist":
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0" encoding="utf-8"?><PageList>`)
+		for _, r := range f.ranges[r.URL.Path] {
+			fmt.Fprintf(&b, "<PageRange><Start>%d</Start><End>%d</End></PageRange>", r[0], r[1])
+		}
+		b.WriteString("</PageList>")
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(b.String()))
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "properties":
+		f.md5[r.URL.Path], _ = base64.StdEncoding.DecodeString(r.Header.Get("x-ms-blob-content-md5"))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-blob-type") == "PageBlob":
+		size, _ := strconv.ParseInt(r.Header.Get("x-ms-blob-content-length"), 10, 64)
+		f.blobs[r.URL.Path] = make([]byte, size)
+		f.created[r.URL.Path] = time.Now().UTC()
+		f.ranges[r.URL.Path] = nil
+		w.Header().Set("Last-Modified", f.created[r.URL.Path].Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodHead:
+		blob, ok := f.blobs[r.URL.Path]
+		if !ok {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+		w.Header().Set("x-ms-creation-time", f.created[r.URL.Path].Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestUploadPageBlob(t *testing.T) {
+	fake := newFakePageBlobService()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client, err := NewStorageClientWithEndpoint(srv.URL+"/devstoreaccount1", "devstoreaccount1", base64.StdEncoding.EncodeToString([]byte("key")))
+	require.NoError(t, err)
+
+	// a sparse image with data in the first and the last page
+	image := make([]byte, 3*PageBlobMaxUploadPagesBytes+512)
+	copy(image, "first page")
+	copy(image[len(image)-512:], "last page")
+	fileName := filepath.Join(t.TempDir(), "image.vhd")
+	require.NoError(t, os.WriteFile(fileName, image, 0600))
+
+	metadata := BlobMetadata{
+		StorageAccount: "devstoreaccount1",
+		ContainerName:  "imagebuilder",
+		BlobName:       "image.vhd",
+	}
+	require.NoError(t, client.UploadPageBlob(metadata, fileName, 4))
+
+	blobPath := "/devstoreaccount1/imagebuilder/image.vhd"
+	sum := md5.Sum(image)
+	assert.Equal(t, image, fake.blobs[blobPath])
+	assert.Equal(t, sum[:], fake.md5[blobPath])
+	assert.Equal(t, 2, fake.puts)
+
+	fake.corrupt = true
+	assert.ErrorContains(t, client.UploadPageBlob(metadata, fileName, 4), "Md5Mismatch")
+
+	// the upload is not completed if the blob misses a written page, the
+	// failed upload above created a blob without pages
+	dst := &pageBlobDestination{client: pageBlobClient(t, client, metadata)}
+	err = dst.Complete(context.Background(), "", []transfer.Part{{Chunk: transfer.Chunk{Offset: int64(len(image) - 512), Size: 512}}}, transfer.Digest{})
+	assert.EqualError(t, err, fmt.Sprintf("the page blob is missing the page at offset %d", len(image)-512))
+
+	require.NoError(t, os.WriteFile(fileName, image[:100], 0600))
+	assert.EqualError(t, client.UploadPageBlob(metadata, fileName, 4), "size for azure image must be aligned to 512 bytes")
+}
+
+func pageBlobClient(t *testing.T, client *StorageClient, metadata BlobMetadata) *pageblob.Client {
+	c, err := pageblob.NewClientWithSharedKeyCredential(client.blobURL(metadata), client.credential, nil)
+	require.NoError(t, err)
+	return c
+}
+
+func TestUploadPageBlobResumeLostPage(t *testing.T) {
+	fake := newFakePageBlobService()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	client, err := NewStorageClientWithEndpoint(srv.URL+"/devstoreaccount1", "devstoreaccount1", base64.StdEncoding.EncodeToString([]byte("key")))
+	require.NoError(t, err)
+
+	image := make([]byte, 3*PageBlobMaxUploadPagesBytes)
+	for i := range image {
+		image[i] = byte(i % 251)
+	}
+	fileName := filepath.Join(t.TempDir(), "image.vhd")
+	require.NoError(t, os.WriteFile(fileName, image, 0600))
+
+	metadata := BlobMetadata{
+		StorageAccount: "devstoreaccount1",
+		ContainerName:  "imagebuilder",
+		BlobName:       "image.vhd",
+	}
+	opts := transfer.Options{
+		Concurrency: 1,
+		StateFile:   filepath.Join(t.TempDir(), "upload.state"),
+	}
+	fake.failAt = 2 * PageBlobMaxUploadPagesBytes
+	_, err = client.UploadPageBlobWithOptions(context.Background(), metadata, fileName, opts)
+	require.ErrorContains(t, err, "uploading a page failed")
+
+	// the page ranges of the resumed blob show that the first page was
+	// lost, so it's uploaded again along with the failed one
+	blobPath := "/devstoreaccount1/imagebuilder/image.vhd"
+	fake.failAt = -1
+	fake.lose(blobPath, 0)
+	fake.puts = 0
+	_, err = client.UploadPageBlobWithOptions(context.Background(), metadata, fileName, opts)
+	require.NoError(t, err)
+	assert.Equal(t, image, fake.blobs[blobPath])
+	assert.Equal(t, 2, fake.puts)
+}
@@ -3,9 +3,6 @@ package koji
 import (
 	"bytes"
 	"context"
-	// koji uses MD5 hashes
-	/* #nosec G501 */
-	"crypto/md5"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -17,6 +14,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"strings"
 	"time"
 
@@ -25,6 +23,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/ubccr/kerby/khttp"
 
+	"github.com/osbuild/images/internal/upload/transfer"
 	"github.com/osbuild/images/pkg/rpmmd"
 )
 
@@ -316,7 +315,7 @@ func (k *Koji) CGImport(build ImageBuild, buildRoots []BuildRoot, images []Image
 }
 
 // uploadChunk uploads a byte slice to a given filepath/filname at a given offset
-func (k *Koji) uploadChunk(chunk []byte, filepath, filename string, offset uint64) error {
+func (k *Koji) uploadChunk(ctx context.Context, chunk []byte, filepath, filename string, offset uint64) error {
 	// We have to open-code a bastardized version of XML-RPC: We send an octet-stream, as
 	// if it was an RPC call, and get a regular XML-RPC reply back. In addition to the
 	// standard URL parameters, we also have to pass any other parameters as part of the
@@ -339,8 +338,13 @@ func (k *Koji) uploadChunk(chunk []byte, filepath, filename string, offset uint6
 		Transport: k.transport,
 	}
 
-	respData, err := client.Post(u.String(), "application/octet-stream", bytes.NewBuffer(chunk))
+	req, err := rh.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewBuffer(chunk))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
 
+	respData, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -382,36 +386,61 @@ func (k *Koji) uploadChunk(chunk []byte, filepath, filename string, offset uint6
 
 // Upload uploads file to the temporary filepath on the kojiserver under the name filename
 // The md5sum and size of the file is returned on success.
-func (k *Koji) Upload(file io.Reader, filepath, filename string) (string, uint64, error) {
-	chunk := make([]byte, 1024*1024) // upload a megabyte at a time
-	offset := uint64(0)
-	// Koji uses MD5 hashes
-	/* #nosec G401 */
-	hash := md5.New()
-	for {
-		n, err := file.Read(chunk)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return "", 0, err
-		}
-		err = k.uploadChunk(chunk[:n], filepath, filename, offset)
-		if err != nil {
-			return "", 0, err
-		}
+//
+// Koji does not accept concurrent writes to the same file, so the chunks
+// are always uploaded one after another. The md5sum of the uploaded file is
+// verified by the kojiserver once all chunks have been written.
+func (k *Koji) Upload(ctx context.Context, file io.ReaderAt, size int64, filepath, filename string, opts transfer.Options) (string, uint64, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 1024 * 1024 // upload a megabyte at a time
+	}
+	opts.Concurrency = 1
+	opts.SkipZeroChunks = false
 
-		offset += uint64(n)
+	dst := &kojiDestination{
+		koji:     k,
+		filepath: filepath,
+		filename: filename,
+	}
+	result, err := transfer.Upload(ctx, file, size, dst, opts)
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%x", result.Digest.MD5), uint64(result.Size), nil
+}
 
-		m, err := hash.Write(chunk[:n])
-		if err != nil {
-			return "", 0, err
-		}
-		if m != n {
-			return "", 0, fmt.Errorf("sent %d bytes, but hashed %d", n, m)
-		}
+// kojiDestination uploads to a temporary file on the kojiserver
+type kojiDestination struct {
+	koji     *Koji
+	filepath string
+	filename string
+}
+
+func (d *kojiDestination) Begin(ctx context.Context, size int64, session string) (string, error) {
+	// the file is truncated when the first chunk is written, so
+	// there is nothing to prepare
+	if session != "" {
+		return session, nil
+	}
+	return path.Join(d.filepath, d.filename), nil
+}
+
+func (d *kojiDestination) WriteChunk(ctx context.Context, session string, chunk transfer.Chunk, data []byte, md5sum []byte) (string, error) {
+	return "", d.koji.uploadChunk(ctx, data, d.filepath, d.filename, uint64(chunk.Offset))
+}
+
+func (d *kojiDestination) Complete(ctx context.Context, session string, parts []transfer.Part, digest transfer.Digest) error {
+	var reply struct {
+		HexDigest string `xmlrpc:"hexdigest"`
 	}
-	return fmt.Sprintf("%x", hash.Sum(nil)), offset, nil
+	err := d.koji.xmlrpc.Call("checkUpload", []interface{}{d.filepath, d.filename, "md5"}, &reply)
+	if err != nil {
+		return fmt.Errorf("cannot verify the uploaded file: %v", err)
+	}
+	if expected := fmt.Sprintf("%x", digest.MD5); reply.HexDigest != expected {
+		return fmt.Errorf("Uploaded a file with MD5 digest %s, but server computed digest %s", expected, reply.HexDigest)
+	}
+	return nil
 }
 
 type Transport struct {
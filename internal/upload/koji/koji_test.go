@@ -0,0 +1,240 @@
+package koji
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/upload/transfer"
+)
+
+// fakeKojiHub is a minimal stand-in for the upload and checkUpload calls of
+// the koji hub
+type fakeKojiHub struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	// offsets of the written chunks
+	writes []int64
+
+	// failAt is the offset of the chunk the hub refuses to write
+	failAt int64
+	// the hub reports a wrong size or digest of the written chunks
+	badChunkSize   bool
+	badChunkDigest bool
+	// the hub reports a wrong digest of the uploaded file
+	badFileDigest bool
+}
+
+func newFakeKojiHub() *fakeKojiHub {
+	return &fakeKojiHub{files: make(map[string][]byte), failAt: -1}
+}
+
+var methodNameRe = regexp.MustCompile(`<methodName>([^<]*)</methodName>`)
+var stringParamRe = regexp.MustCompile(`<string>([^<]*)</string>`)
+
+func xmlrpcStruct(w io.Writer, members map[string]string) {
+	fmt.Fprint(w, `<?xml version="1.0"?><methodResponse><params><param><value><struct>`)
+	for name, value := range members {
+		fmt.Fprintf(w, `<member><name>%s</name><value>%s</value></member>`, name, value)
+	}
+	fmt.Fprint(w, `</struct></value></param></params></methodResponse>`)
+}
+
+func xmlrpcFault(w io.Writer, msg string) {
+	fmt.Fprintf(w, `<?xml version="1.0"?><methodResponse><fault><value><struct>`+
+		`<member><name>faultCode</name><value><int>1000</int></value></member>`+
+		`<member><name>faultString</name><value><string>%s</string></value></member>`+
+		`</struct></value></fault></methodResponse>`, msg)
+}
+
+func (h *fakeKojiHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	q := r.URL.Query()
+	if q.Get("session-id") != "1" || q.Get("session-key") != "key" || q.Get("callnum") == "" {
+		xmlrpcFault(w, "not logged in")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	// chunk upload
+	if q.Has("offset") {
+		offset, err := strconv.ParseInt(q.Get("offset"), 10, 64)
+		if err != nil || q.Get("fileverify") != "adler32" {
+			xmlrpcFault(w, "invalid upload")
+			return
+		}
+		if offset == h.failAt {
+			xmlrpcFault(w, "connection to the storage lost")
+			return
+		}
+		name := filepath.Join(q.Get("filepath"), q.Get("filename"))
+		// koji truncates the file when the first chunk is written
+		file := h.files[name]
+		if offset == 0 {
+			file = nil
+		}
+		if int64(len(file)) < offset+int64(len(body)) {
+			file = append(file, make([]byte, offset+int64(len(body))-int64(len(file)))...)
+		}
+		copy(file[offset:], body)
+		h.files[name] = file
+		h.writes = append(h.writes, offset)
+
+		size := len(body)
+		if h.badChunkSize {
+			size--
+		}
+		digest := adler32.Checksum(body)
+		if h.badChunkDigest {
+			digest++
+		}
+		xmlrpcStruct(w, map[string]string{
+			"size":      fmt.Sprintf("<int>%d</int>", size),
+			"hexdigest": fmt.Sprintf("<string>%08x</string>", digest),
+		})
+		return
+	}
+
+	method := methodNameRe.FindSubmatch(body)
+	if method == nil || string(method[1]) != "checkUpload" {
+		xmlrpcFault(w, "unknown method")
+		return
+	}
+	params := stringParamRe.FindAllSubmatch(body, -1)
+	if len(params) != 3 || string(params[2][1]) != "md5" {
+		xmlrpcFault(w, "invalid checkUpload parameters")
+		return
+	}
+	file, ok := h.files[filepath.Join(string(params[0][1]), string(params[1][1]))]
+	if !ok {
+		xmlrpcFault(w, "no such upload")
+		return
+	}
+	digest := fmt.Sprintf("%x", md5.Sum(file))
+	if h.badFileDigest {
+		digest = fmt.Sprintf("%x", md5.Sum(nil))
+	}
+	xmlrpcStruct(w, map[string]string{
+		"size":      fmt.Sprintf("<int>%d</int>", len(file)),
+		"hexdigest": fmt.Sprintf("<string>%s</string>", digest),
+	})
+}
+
+func newTestKoji(t *testing.T, hub *fakeKojiHub) *Koji {
+	srv := httptest.NewServer(hub)
+	t.Cleanup(srv.Close)
+
+	k, err := newKoji(srv.URL, http.DefaultTransport, loginReply{SessionID: 1, SessionKey: "key"})
+	require.NoError(t, err)
+	return k
+}
+
+func testImage(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestUpload(t *testing.T) {
+	hub := newFakeKojiHub()
+	k := newTestKoji(t, hub)
+
+	image := testImage(2*1024 + 512)
+	md5sum, size, err := k.Upload(context.Background(), bytes.NewReader(image), int64(len(image)), "koji-dir", "image.raw", transfer.Options{ChunkSize: 1024})
+	require.NoError(t, err)
+
+	assert.Equal(t, fmt.Sprintf("%x", md5.Sum(image)), md5sum)
+	assert.Equal(t, uint64(len(image)), size)
+	assert.Equal(t, image, hub.files["koji-dir/image.raw"])
+	assert.Equal(t, []int64{0, 1024, 2048}, hub.writes)
+}
+
+func TestUploadResume(t *testing.T) {
+	hub := newFakeKojiHub()
+	hub.failAt = 2048
+	k := newTestKoji(t, hub)
+
+	image := testImage(4 * 1024)
+	opts := transfer.Options{
+		ChunkSize: 1024,
+		StateFile: filepath.Join(t.TempDir(), "upload.state"),
+	}
+	_, _, err := k.Upload(context.Background(), bytes.NewReader(image), int64(len(image)), "koji-dir", "image.raw", opts)
+	require.ErrorContains(t, err, "connection to the storage lost")
+	assert.FileExists(t, opts.StateFile)
+
+	// the chunks written before the failure are not written again
+	hub.failAt = -1
+	hub.writes = nil
+	md5sum, _, err := k.Upload(context.Background(), bytes.NewReader(image), int64(len(image)), "koji-dir", "image.raw", opts)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x", md5.Sum(image)), md5sum)
+	assert.Equal(t, image, hub.files["koji-dir/image.raw"])
+	assert.Equal(t, []int64{2048, 3072}, hub.writes)
+	assert.NoFileExists(t, opts.StateFile)
+}
+
+func TestUploadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(hub *fakeKojiHub)
+		err   string
+	}{
+		{
+			name:  "chunk-size",
+			setup: func(hub *fakeKojiHub) { hub.badChunkSize = true },
+			err:   "Sent a chunk of 1024 bytes, but server got 1023 bytes",
+		},
+		{
+			name:  "chunk-digest",
+			setup: func(hub *fakeKojiHub) { hub.badChunkDigest = true },
+			err:   "Sent a chunk with Adler32 digest",
+		},
+		{
+			name:  "check-upload-mismatch",
+			setup: func(hub *fakeKojiHub) { hub.badFileDigest = true },
+			err:   fmt.Sprintf("but server computed digest %x", md5.Sum(nil)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := newFakeKojiHub()
+			tt.setup(hub)
+			k := newTestKoji(t, hub)
+
+			image := testImage(2 * 1024)
+			_, _, err := k.Upload(context.Background(), bytes.NewReader(image), int64(len(image)), "koji-dir", "image.raw", transfer.Options{ChunkSize: 1024})
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestUploadCancelled(t *testing.T) {
+	hub := newFakeKojiHub()
+	k := newTestKoji(t, hub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	image := testImage(1024)
+	_, _, err := k.Upload(ctx, bytes.NewReader(image), int64(len(image)), "koji-dir", "image.raw", transfer.Options{ChunkSize: 1024})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, hub.writes)
+}
@@ -0,0 +1,125 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/crypt"
+)
+
+// User is created by cloud-init on the first boot of a domain
+type User struct {
+	Name   string
+	Groups []string
+	Keys   []string
+	// Plain text or crypted password
+	Password string
+}
+
+// UsersFromBlueprint returns the users of the blueprint customizations
+func UsersFromBlueprint(users []blueprint.UserCustomization) []User {
+	var result []User
+	for _, u := range users {
+		user := User{
+			Name:   u.Name,
+			Groups: u.Groups,
+		}
+		if u.Key != nil {
+			user.Keys = []string{*u.Key}
+		}
+		if u.Password != nil {
+			user.Password = *u.Password
+		}
+		result = append(result, user)
+	}
+	return result
+}
+
+type cloudConfigUser struct {
+	Name              string   `json:"name"`
+	Groups            []string `json:"groups,omitempty"`
+	SSHAuthorizedKeys []string `json:"ssh_authorized_keys,omitempty"`
+	Passwd            string   `json:"passwd,omitempty"`
+	PlainTextPasswd   string   `json:"plain_text_passwd,omitempty"`
+	LockPasswd        bool     `json:"lock_passwd"`
+}
+
+// UserData returns the cloud-config user data creating the users. JSON is
+// a subset of YAML, so the document is encoded as JSON.
+func UserData(users []User) ([]byte, error) {
+	config := struct {
+		Users []cloudConfigUser `json:"users"`
+	}{}
+
+	for _, u := range users {
+		user := cloudConfigUser{
+			Name:              u.Name,
+			Groups:            u.Groups,
+			SSHAuthorizedKeys: u.Keys,
+			LockPasswd:        u.Password == "",
+		}
+		if crypt.PasswordIsCrypted(u.Password) {
+			user.Passwd = u.Password
+		} else {
+			user.PlainTextPasswd = u.Password
+		}
+		config.Users = append(config.Users, user)
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("#cloud-config\n"), data...), nil
+}
+
+// MetaData returns the NoCloud meta data of an instance
+func MetaData(instance string) ([]byte, error) {
+	return json.MarshalIndent(map[string]string{
+		"instance-id":    instance,
+		"local-hostname": instance,
+	}, "", "  ")
+}
+
+// WriteSeedISO writes a cloud-init NoCloud seed ISO to path using mkisofs
+func (c *Client) WriteSeedISO(ctx context.Context, path, instance string, users []User) error {
+	dir, err := os.MkdirTemp("", "libvirt-seed-")
+	if err != nil {
+		return fmt.Errorf("cannot create the temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	userData, err := UserData(users)
+	if err != nil {
+		return err
+	}
+	metaData, err := MetaData(instance)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user-data"), userData, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meta-data"), metaData, 0600); err != nil {
+		return err
+	}
+
+	_, err = c.run(ctx, "mkisofs",
+		"-quiet",
+		"-input-charset", "utf-8",
+		"-volid", "cidata",
+		"-joliet",
+		"-rock",
+		"-output", path,
+		filepath.Join(dir, "user-data"),
+		filepath.Join(dir, "meta-data"),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot create cloud-init iso: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package libvirt
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/distro"
+)
+
+// DomainOptions describe a domain booting a disk image
+type DomainOptions struct {
+	Name string
+	// Defaults to the host architecture
+	Arch     string
+	BootMode distro.BootMode
+
+	// Resources of the domain, see distro.ImageType.RecommendedResources
+	MemoryMiB uint64
+	VCPUs     uint
+
+	// Path and format of the disk image
+	Disk       string
+	DiskFormat string
+	// Path to the cloud-init NoCloud seed ISO, optional
+	Seed string
+
+	// Use user mode networking instead of the "default" network, required
+	// for unprivileged qemu:///session connections
+	UserNetwork bool
+}
+
+// the machine type used for each architecture
+var machines = map[string]string{
+	"x86_64":  "q35",
+	"aarch64": "virt",
+	"ppc64le": "pseries",
+	"s390x":   "s390-ccw-virtio",
+}
+
+type domainXML struct {
+	XMLName  xml.Name        `xml:"domain"`
+	Type     string          `xml:"type,attr"`
+	Name     string          `xml:"name"`
+	Memory   domainMemory    `xml:"memory"`
+	VCPU     uint            `xml:"vcpu"`
+	OS       domainOS        `xml:"os"`
+	Features *domainFeatures `xml:"features,omitempty"`
+	CPU      *domainCPU      `xml:"cpu,omitempty"`
+	Devices  domainDevices   `xml:"devices"`
+}
+
+type domainMemory struct {
+	Unit  string `xml:"unit,attr"`
+	Value uint64 `xml:",chardata"`
+}
+
+type domainOS struct {
+	Firmware string       `xml:"firmware,attr,omitempty"`
+	Type     domainOSType `xml:"type"`
+	Boot     domainBoot   `xml:"boot"`
+}
+
+type domainOSType struct {
+	Arch    string `xml:"arch,attr"`
+	Machine string `xml:"machine,attr"`
+	Value   string `xml:",chardata"`
+}
+
+type domainBoot struct {
+	Dev string `xml:"dev,attr"`
+}
+
+type domainFeatures struct {
+	ACPI *struct{} `xml:"acpi"`
+	APIC *struct{} `xml:"apic,omitempty"`
+}
+
+type domainCPU struct {
+	Mode string `xml:"mode,attr"`
+}
+
+type domainDevices struct {
+	Disks       []domainDisk       `xml:"disk"`
+	Controllers []domainController `xml:"controller,omitempty"`
+	Interface   domainInterface    `xml:"interface"`
+	Console     domainConsole      `xml:"console"`
+	RNG         domainRNG          `xml:"rng"`
+}
+
+type domainDisk struct {
+	Type     string           `xml:"type,attr"`
+	Device   string           `xml:"device,attr"`
+	Driver   domainDiskDriver `xml:"driver"`
+	Source   domainDiskSource `xml:"source"`
+	Target   domainDiskTarget `xml:"target"`
+	ReadOnly *struct{}        `xml:"readonly,omitempty"`
+}
+
+type domainDiskDriver struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type domainDiskSource struct {
+	File string `xml:"file,attr"`
+}
+
+type domainDiskTarget struct {
+	Dev string `xml:"dev,attr"`
+	Bus string `xml:"bus,attr"`
+}
+
+type domainController struct {
+	Type  string `xml:"type,attr"`
+	Model string `xml:"model,attr"`
+}
+
+type domainInterface struct {
+	Type   string                 `xml:"type,attr"`
+	Source *domainInterfaceSource `xml:"source,omitempty"`
+	Model  domainModel            `xml:"model"`
+}
+
+type domainInterfaceSource struct {
+	Network string `xml:"network,attr"`
+}
+
+type domainModel struct {
+	Type string `xml:"type,attr"`
+}
+
+type domainConsole struct {
+	Type string `xml:"type,attr"`
+}
+
+type domainRNG struct {
+	Model   string           `xml:"model,attr"`
+	Backend domainRNGBackend `xml:"backend"`
+}
+
+type domainRNGBackend struct {
+	Model string `xml:"model,attr"`
+	Value string `xml:",chardata"`
+}
+
+// firmware returns the value of the firmware attribute of the domain's os
+// element for the given architecture and boot mode
+func firmware(arch string, bootMode distro.BootMode) (string, error) {
+	switch arch {
+	case "x86_64":
+		switch bootMode {
+		case distro.BOOT_LEGACY:
+			return "", nil
+		case distro.BOOT_UEFI, distro.BOOT_HYBRID:
+			return "efi", nil
+		}
+	case "aarch64":
+		if bootMode == distro.BOOT_UEFI || bootMode == distro.BOOT_HYBRID {
+			return "efi", nil
+		}
+	case "ppc64le", "s390x":
+		if bootMode == distro.BOOT_LEGACY {
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("unsupported boot mode %q for %s", bootMode, arch)
+}
+
+// DomainXML returns the libvirt domain XML of a domain booting a disk image
+func DomainXML(options DomainOptions) ([]byte, error) {
+	arch := options.Arch
+	if arch == "" {
+		arch = common.CurrentArch()
+	}
+	machine, ok := machines[arch]
+	if !ok {
+		return nil, fmt.Errorf("unsupported architecture %q", arch)
+	}
+	if options.MemoryMiB == 0 || options.VCPUs == 0 {
+		return nil, fmt.Errorf("the memory and the vCPUs of the domain must be set")
+	}
+	fw, err := firmware(arch, options.BootMode)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := domainXML{
+		Type:   "qemu",
+		Name:   options.Name,
+		Memory: domainMemory{Unit: "MiB", Value: options.MemoryMiB},
+		VCPU:   options.VCPUs,
+		OS: domainOS{
+			Firmware: fw,
+			Type:     domainOSType{Arch: arch, Machine: machine, Value: "hvm"},
+			Boot:     domainBoot{Dev: "hd"},
+		},
+		Devices: domainDevices{
+			Disks: []domainDisk{
+				{
+					Type:   "file",
+					Device: "disk",
+					Driver: domainDiskDriver{Name: "qemu", Type: options.DiskFormat},
+					Source: domainDiskSource{File: options.Disk},
+					Target: domainDiskTarget{Dev: "vda", Bus: "virtio"},
+				},
+			},
+			Interface: domainInterface{
+				Type:  "network",
+				Model: domainModel{Type: "virtio"},
+			},
+			Console: domainConsole{Type: "pty"},
+			RNG: domainRNG{
+				Model:   "virtio",
+				Backend: domainRNGBackend{Model: "random", Value: "/dev/urandom"},
+			},
+		},
+	}
+	if domain.Devices.Disks[0].Driver.Type == "" {
+		domain.Devices.Disks[0].Driver.Type = "qcow2"
+	}
+
+	// use hardware acceleration for images of the host architecture
+	if arch == common.CurrentArch() {
+		domain.Type = "kvm"
+		domain.CPU = &domainCPU{Mode: "host-passthrough"}
+	}
+
+	switch arch {
+	case "x86_64":
+		domain.Features = &domainFeatures{ACPI: &struct{}{}, APIC: &struct{}{}}
+	case "aarch64":
+		domain.Features = &domainFeatures{ACPI: &struct{}{}}
+	}
+
+	if options.UserNetwork {
+		domain.Devices.Interface.Type = "user"
+	} else {
+		domain.Devices.Interface.Source = &domainInterfaceSource{Network: "default"}
+	}
+
+	if options.Seed != "" {
+		domain.Devices.Disks = append(domain.Devices.Disks, domainDisk{
+			Type:     "file",
+			Device:   "cdrom",
+			Driver:   domainDiskDriver{Name: "qemu", Type: "raw"},
+			Source:   domainDiskSource{File: options.Seed},
+			Target:   domainDiskTarget{Dev: "sda", Bus: "scsi"},
+			ReadOnly: &struct{}{},
+		})
+		domain.Devices.Controllers = append(domain.Devices.Controllers, domainController{Type: "scsi", Model: "virtio-scsi"})
+	}
+
+	return xml.MarshalIndent(domain, "", "  ")
+}
@@ -0,0 +1,123 @@
+package libvirt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/distro"
+)
+
+func TestDomainXML(t *testing.T) {
+	origRuntimeGOARCH := common.RuntimeGOARCH
+	defer func() { common.RuntimeGOARCH = origRuntimeGOARCH }()
+	common.RuntimeGOARCH = "amd64"
+
+	domain, err := DomainXML(DomainOptions{
+		Name:      "my-vm",
+		Arch:      "x86_64",
+		BootMode:  distro.BOOT_HYBRID,
+		MemoryMiB: 2048,
+		VCPUs:     2,
+		Disk:      "/var/lib/libvirt/images/my-vm.qcow2",
+		Seed:      "/var/lib/libvirt/images/my-vm-seed.iso",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `<domain type="kvm">
+  <name>my-vm</name>
+  <memory unit="MiB">2048</memory>
+  <vcpu>2</vcpu>
+  <os firmware="efi">
+    <type arch="x86_64" machine="q35">hvm</type>
+    <boot dev="hd"></boot>
+  </os>
+  <features>
+    <acpi></acpi>
+    <apic></apic>
+  </features>
+  <cpu mode="host-passthrough"></cpu>
+  <devices>
+    <disk type="file" device="disk">
+      <driver name="qemu" type="qcow2"></driver>
+      <source file="/var/lib/libvirt/images/my-vm.qcow2"></source>
+      <target dev="vda" bus="virtio"></target>
+    </disk>
+    <disk type="file" device="cdrom">
+      <driver name="qemu" type="raw"></driver>
+      <source file="/var/lib/libvirt/images/my-vm-seed.iso"></source>
+      <target dev="sda" bus="scsi"></target>
+      <readonly></readonly>
+    </disk>
+    <controller type="scsi" model="virtio-scsi"></controller>
+    <interface type="network">
+      <source network="default"></source>
+      <model type="virtio"></model>
+    </interface>
+    <console type="pty"></console>
+    <rng model="virtio">
+      <backend model="random">/dev/urandom</backend>
+    </rng>
+  </devices>
+</domain>`, string(domain))
+}
+
+func TestDomainXMLArches(t *testing.T) {
+	origRuntimeGOARCH := common.RuntimeGOARCH
+	defer func() { common.RuntimeGOARCH = origRuntimeGOARCH }()
+	common.RuntimeGOARCH = "amd64"
+
+	tests := []struct {
+		arch     string
+		bootMode distro.BootMode
+		contains []string
+		err      string
+	}{
+		{arch: "x86_64", bootMode: distro.BOOT_LEGACY, contains: []string{`<domain type="kvm">`, `<os>`, `machine="q35"`}},
+		{arch: "x86_64", bootMode: distro.BOOT_UEFI, contains: []string{`<os firmware="efi">`}},
+		{arch: "aarch64", bootMode: distro.BOOT_UEFI, contains: []string{`<domain type="qemu">`, `<os firmware="efi">`, `machine="virt"`}},
+		{arch: "aarch64", bootMode: distro.BOOT_LEGACY, err: `unsupported boot mode "legacy" for aarch64`},
+		{arch: "ppc64le", bootMode: distro.BOOT_LEGACY, contains: []string{`<os>`, `machine="pseries"`}},
+		{arch: "s390x", bootMode: distro.BOOT_LEGACY, contains: []string{`<os>`, `machine="s390-ccw-virtio"`}},
+		{arch: "s390x", bootMode: distro.BOOT_UEFI, err: `unsupported boot mode "uefi" for s390x`},
+		{arch: "riscv64", bootMode: distro.BOOT_UEFI, err: `unsupported architecture "riscv64"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arch+"-"+tt.bootMode.String(), func(t *testing.T) {
+			domain, err := DomainXML(DomainOptions{
+				Name:        "my-vm",
+				Arch:        tt.arch,
+				BootMode:    tt.bootMode,
+				MemoryMiB:   1024,
+				VCPUs:       1,
+				Disk:        "/tmp/disk.qcow2",
+				UserNetwork: true,
+			})
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, string(domain), `<interface type="user">`)
+			for _, s := range tt.contains {
+				assert.Contains(t, string(domain), s)
+			}
+		})
+	}
+}
+
+func TestDomainXMLResources(t *testing.T) {
+	origRuntimeGOARCH := common.RuntimeGOARCH
+	defer func() { common.RuntimeGOARCH = origRuntimeGOARCH }()
+	common.RuntimeGOARCH = "amd64"
+
+	_, err := DomainXML(DomainOptions{
+		Name:     "my-vm",
+		Arch:     "x86_64",
+		BootMode: distro.BOOT_UEFI,
+		VCPUs:    2,
+		Disk:     "/tmp/disk.qcow2",
+	})
+	assert.EqualError(t, err, "the memory and the vCPUs of the domain must be set")
+}
@@ -0,0 +1,265 @@
+// Package libvirt defines ready-to-run libvirt domains from disk images
+// using the virsh command line tool.
+package libvirt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+)
+
+// DefaultURI is the connection URI used if none is given, it does not
+// require any privileges
+const DefaultURI = "qemu:///session"
+
+// DefaultPool is the storage pool used if none is given
+const DefaultPool = "default"
+
+type runFunc func(ctx context.Context, name string, args ...string) (string, error)
+
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s failed: %v: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Client manages volumes and domains of a libvirt connection
+type Client struct {
+	uri string
+	run runFunc
+}
+
+// NewClient returns a client for the connection URI, DefaultURI is used if
+// empty
+func NewClient(uri string) *Client {
+	if uri == "" {
+		uri = DefaultURI
+	}
+	return &Client{uri: uri, run: run}
+}
+
+func (c *Client) virsh(ctx context.Context, args ...string) (string, error) {
+	return c.run(ctx, "virsh", append([]string{"--connect", c.uri}, args...)...)
+}
+
+// isSession returns true for unprivileged connections
+func (c *Client) isSession() bool {
+	return strings.HasSuffix(c.uri, "/session")
+}
+
+// UploadVolume creates the volume name in the storage pool with the
+// content of the file and returns its path. It fails if the volume already
+// exists, the volume is deleted again if the upload fails.
+func (c *Client) UploadVolume(ctx context.Context, pool, name, filename string) (path string, err error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return "", fmt.Errorf("cannot stat the image: %v", err)
+	}
+
+	// the volume is created as raw with the exact size of the file, the
+	// format of the uploaded data is detected when the pool is refreshed
+	if _, err := c.virsh(ctx, "vol-create-as", "--pool", pool, name, fmt.Sprintf("%d", stat.Size()), "--format", "raw"); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			err = c.deleteVolumeOnError(err, pool, name)
+		}
+	}()
+	if _, err := c.virsh(ctx, "vol-upload", "--pool", pool, name, filename); err != nil {
+		return "", err
+	}
+	if _, err := c.virsh(ctx, "pool-refresh", pool); err != nil {
+		return "", err
+	}
+	return c.virsh(ctx, "vol-path", "--pool", pool, name)
+}
+
+// DeleteVolume deletes the volume name from the storage pool
+func (c *Client) DeleteVolume(ctx context.Context, pool, name string) error {
+	_, err := c.virsh(ctx, "vol-delete", "--pool", pool, name)
+	return err
+}
+
+// deleteVolumeOnError deletes a volume created before the error err and
+// returns err, amended if the volume cannot be deleted
+func (c *Client) deleteVolumeOnError(err error, pool, name string) error {
+	// the context of the failed operation may be cancelled already
+	if deleteErr := c.DeleteVolume(context.Background(), pool, name); deleteErr != nil {
+		return fmt.Errorf("%w (cannot delete the volume %s: %v)", err, name, deleteErr)
+	}
+	return err
+}
+
+// DefineDomain defines (but does not start) a domain and returns its UUID.
+// It fails if a domain of the same name already exists.
+func (c *Client) DefineDomain(ctx context.Context, name string, domainXML []byte) (string, error) {
+	if _, err := c.virsh(ctx, "domuuid", name); err == nil {
+		return "", fmt.Errorf("domain %q already exists", name)
+	}
+
+	f, err := os.CreateTemp("", "libvirt-domain-*.xml")
+	if err != nil {
+		return "", fmt.Errorf("cannot write the domain XML: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(domainXML); err != nil {
+		f.Close()
+		return "", fmt.Errorf("cannot write the domain XML: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("cannot write the domain XML: %v", err)
+	}
+
+	if _, err := c.virsh(ctx, "define", f.Name()); err != nil {
+		return "", err
+	}
+	return c.virsh(ctx, "domuuid", name)
+}
+
+// VMOptions describe a virtual machine created by CreateVM
+type VMOptions struct {
+	// Name of the domain and the prefix of its volumes
+	Name string
+	// Storage pool for the volumes, defaults to DefaultPool
+	Pool string
+
+	// Path and format of the disk image, the format defaults to raw for
+	// .raw and .img files and to qcow2 otherwise
+	Image       string
+	ImageFormat string
+
+	Arch      string
+	BootMode  distro.BootMode
+	MemoryMiB uint64
+	VCPUs     uint
+
+	// If not empty, a cloud-init NoCloud seed ISO creating the users is
+	// attached to the domain
+	Users []User
+}
+
+// NewVMOptions returns the options of a VM booting the image of the image
+// type: the architecture, boot mode and resources are the ones of the image
+// type and cloud-init creates the users of the blueprint, including their
+// ssh keys.
+func NewVMOptions(name, image string, imgType distro.ImageType, bp *blueprint.Blueprint) VMOptions {
+	var customizations *blueprint.Customizations
+	if bp != nil {
+		customizations = bp.Customizations
+	}
+	resources := imgType.RecommendedResources()
+	return VMOptions{
+		Name:      name,
+		Image:     image,
+		Arch:      imgType.Arch().Name(),
+		BootMode:  imgType.BootMode(),
+		MemoryMiB: resources.MemoryMiB,
+		VCPUs:     resources.VCPUs,
+		Users:     UsersFromBlueprint(customizations.GetUsers()),
+	}
+}
+
+// VM is a defined domain
+type VM struct {
+	Domain string
+	UUID   string
+	// Path of the disk volume
+	Volume string
+}
+
+// CreateVM copies the image into a storage pool and defines a domain
+// booting it, optionally with a cloud-init seed ISO. The volumes are deleted
+// again if the domain cannot be defined.
+func (c *Client) CreateVM(ctx context.Context, options VMOptions) (vm *VM, err error) {
+	pool := options.Pool
+	if pool == "" {
+		pool = DefaultPool
+	}
+	format := options.ImageFormat
+	if format == "" {
+		switch filepath.Ext(options.Image) {
+		case ".raw", ".img":
+			format = "raw"
+		default:
+			format = "qcow2"
+		}
+	}
+
+	domain := DomainOptions{
+		Name:        options.Name,
+		Arch:        options.Arch,
+		BootMode:    options.BootMode,
+		MemoryMiB:   options.MemoryMiB,
+		VCPUs:       options.VCPUs,
+		DiskFormat:  format,
+		UserNetwork: c.isSession(),
+	}
+	// validate the domain before any volume is created
+	if _, err := DomainXML(domain); err != nil {
+		return nil, err
+	}
+
+	// the volumes are only kept once the domain referring to them is defined
+	var volumes []string
+	defer func() {
+		if err != nil {
+			for _, volume := range volumes {
+				err = c.deleteVolumeOnError(err, pool, volume)
+			}
+		}
+	}()
+
+	diskVolume := options.Name + filepath.Ext(options.Image)
+	domain.Disk, err = c.UploadVolume(ctx, pool, diskVolume, options.Image)
+	if err != nil {
+		return nil, err
+	}
+	volumes = append(volumes, diskVolume)
+
+	if len(options.Users) > 0 {
+		dir, err := os.MkdirTemp("", "libvirt-seed-")
+		if err != nil {
+			return nil, fmt.Errorf("cannot create the temporary directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		seed := filepath.Join(dir, "seed.iso")
+		if err := c.WriteSeedISO(ctx, seed, options.Name, options.Users); err != nil {
+			return nil, err
+		}
+		seedVolume := options.Name + "-seed.iso"
+		domain.Seed, err = c.UploadVolume(ctx, pool, seedVolume, seed)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, seedVolume)
+	}
+
+	domainXML, err := DomainXML(domain)
+	if err != nil {
+		return nil, err
+	}
+	uuid, err := c.DefineDomain(ctx, options.Name, domainXML)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VM{
+		Domain: options.Name,
+		UUID:   uuid,
+		Volume: domain.Disk,
+	}, nil
+}
@@ -0,0 +1,210 @@
+package libvirt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/fedora"
+)
+
+// fakeVirsh records the commands run by a client
+type fakeVirsh struct {
+	commands []string
+	domain   string
+	domains  map[string]bool
+	// if set, defining a domain fails
+	defineErr error
+}
+
+func (f *fakeVirsh) run(ctx context.Context, name string, args ...string) (string, error) {
+	f.commands = append(f.commands, strings.Join(append([]string{name}, args...), " "))
+	if name == "mkisofs" {
+		for i, arg := range args {
+			if arg == "-output" {
+				return "", os.WriteFile(args[i+1], []byte("iso"), 0600)
+			}
+		}
+	}
+
+	switch args[2] {
+	case "vol-path":
+		return "/pool/" + args[5], nil
+	case "domuuid":
+		if !f.domains[args[3]] {
+			return "", errors.New("domain not found")
+		}
+		return "0b1c8bb0-4b1e-4c6b-8a3c-1b2c3d4e5f60", nil
+	case "define":
+		if f.defineErr != nil {
+			return "", f.defineErr
+		}
+		data, err := os.ReadFile(args[3])
+		if err != nil {
+			return "", err
+		}
+		f.domain = string(data)
+		f.domains["my-vm"] = true
+	}
+	return "", nil
+}
+
+func TestCreateVM(t *testing.T) {
+	origRuntimeGOARCH := common.RuntimeGOARCH
+	defer func() { common.RuntimeGOARCH = origRuntimeGOARCH }()
+	common.RuntimeGOARCH = "amd64"
+
+	image := filepath.Join(t.TempDir(), "disk.qcow2")
+	require.NoError(t, os.WriteFile(image, []byte("qcow2"), 0600))
+
+	fake := &fakeVirsh{domains: make(map[string]bool)}
+	c := NewClient("")
+	c.run = fake.run
+
+	vm, err := c.CreateVM(context.Background(), VMOptions{
+		Name:      "my-vm",
+		Image:     image,
+		Arch:      "x86_64",
+		BootMode:  distro.BOOT_UEFI,
+		MemoryMiB: 2048,
+		VCPUs:     2,
+		Users:     []User{{Name: "admin", Keys: []string{"ssh-ed25519 AAAA"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &VM{
+		Domain: "my-vm",
+		UUID:   "0b1c8bb0-4b1e-4c6b-8a3c-1b2c3d4e5f60",
+		Volume: "/pool/my-vm.qcow2",
+	}, vm)
+
+	require.Len(t, fake.commands, 12)
+	assert.Equal(t, []string{
+		"virsh --connect qemu:///session vol-create-as --pool default my-vm.qcow2 5 --format raw",
+		"virsh --connect qemu:///session vol-upload --pool default my-vm.qcow2 " + image,
+		"virsh --connect qemu:///session pool-refresh default",
+		"virsh --connect qemu:///session vol-path --pool default my-vm.qcow2",
+	}, fake.commands[:4])
+	assert.True(t, strings.HasPrefix(fake.commands[4], "mkisofs -quiet -input-charset utf-8 -volid cidata -joliet -rock -output "))
+	assert.Equal(t, "virsh --connect qemu:///session vol-path --pool default my-vm-seed.iso", fake.commands[8])
+	assert.Equal(t, "virsh --connect qemu:///session domuuid my-vm", fake.commands[9])
+	assert.True(t, strings.HasPrefix(fake.commands[10], "virsh --connect qemu:///session define "))
+
+	assert.Contains(t, fake.domain, `<os firmware="efi">`)
+	assert.Contains(t, fake.domain, `<source file="/pool/my-vm.qcow2"></source>`)
+	assert.Contains(t, fake.domain, `<source file="/pool/my-vm-seed.iso"></source>`)
+	assert.Contains(t, fake.domain, `<interface type="user">`)
+
+	// the domain is not redefined and the new volume is deleted again
+	fake.commands = nil
+	_, err = c.CreateVM(context.Background(), VMOptions{
+		Name:      "my-vm",
+		Image:     image,
+		Arch:      "x86_64",
+		BootMode:  distro.BOOT_UEFI,
+		MemoryMiB: 2048,
+		VCPUs:     2,
+	})
+	assert.EqualError(t, err, `domain "my-vm" already exists`)
+	assert.Equal(t, "virsh --connect qemu:///session vol-delete --pool default my-vm.qcow2", fake.commands[len(fake.commands)-1])
+}
+
+func TestCreateVMDeletesVolumes(t *testing.T) {
+	origRuntimeGOARCH := common.RuntimeGOARCH
+	defer func() { common.RuntimeGOARCH = origRuntimeGOARCH }()
+	common.RuntimeGOARCH = "amd64"
+
+	image := filepath.Join(t.TempDir(), "disk.qcow2")
+	require.NoError(t, os.WriteFile(image, []byte("qcow2"), 0600))
+
+	fake := &fakeVirsh{domains: make(map[string]bool), defineErr: errors.New("virsh define failed")}
+	c := NewClient("")
+	c.run = fake.run
+
+	_, err := c.CreateVM(context.Background(), VMOptions{
+		Name:      "my-vm",
+		Image:     image,
+		Arch:      "x86_64",
+		BootMode:  distro.BOOT_UEFI,
+		MemoryMiB: 2048,
+		VCPUs:     2,
+		Users:     []User{{Name: "admin", Keys: []string{"ssh-ed25519 AAAA"}}},
+	})
+	assert.EqualError(t, err, "virsh define failed")
+	assert.Equal(t, []string{
+		"virsh --connect qemu:///session vol-delete --pool default my-vm.qcow2",
+		"virsh --connect qemu:///session vol-delete --pool default my-vm-seed.iso",
+	}, fake.commands[len(fake.commands)-2:])
+}
+
+func TestNewVMOptions(t *testing.T) {
+	arch, err := fedora.NewF39().GetArch("x86_64")
+	require.NoError(t, err)
+	imgType, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+
+	key := "ssh-ed25519 AAAA"
+	bp := &blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			User: []blueprint.UserCustomization{{Name: "admin", Key: &key, Groups: []string{"wheel"}}},
+		},
+	}
+	assert.Equal(t, VMOptions{
+		Name:      "my-vm",
+		Image:     "/tmp/disk.qcow2",
+		Arch:      "x86_64",
+		BootMode:  imgType.BootMode(),
+		MemoryMiB: imgType.RecommendedResources().MemoryMiB,
+		VCPUs:     imgType.RecommendedResources().VCPUs,
+		Users:     []User{{Name: "admin", Groups: []string{"wheel"}, Keys: []string{key}}},
+	}, NewVMOptions("my-vm", "/tmp/disk.qcow2", imgType, bp))
+
+	// without a blueprint no seed is attached
+	assert.Empty(t, NewVMOptions("my-vm", "/tmp/disk.qcow2", imgType, nil).Users)
+}
+
+func TestUserData(t *testing.T) {
+	key := "ssh-ed25519 AAAA"
+	password := "$6$salt$hash"
+	users := UsersFromBlueprint([]blueprint.UserCustomization{
+		{Name: "admin", Key: &key, Groups: []string{"wheel"}},
+		{Name: "user", Password: &password},
+		{Name: "guest", Password: common.ToPtr("guest")},
+	})
+
+	userData, err := UserData(users)
+	require.NoError(t, err)
+	assert.Equal(t, `#cloud-config
+{
+  "users": [
+    {
+      "name": "admin",
+      "groups": [
+        "wheel"
+      ],
+      "ssh_authorized_keys": [
+        "ssh-ed25519 AAAA"
+      ],
+      "lock_passwd": true
+    },
+    {
+      "name": "user",
+      "passwd": "$6$salt$hash",
+      "lock_passwd": false
+    },
+    {
+      "name": "guest",
+      "plain_text_passwd": "guest",
+      "lock_passwd": false
+    }
+  ]
+}`, string(userData))
+}
@@ -0,0 +1,241 @@
+package openstack
+
+import (
+	"context"
+	// Glance uses MD5 hashes for the image checksum
+	/* #nosec G501 */
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/imagedata"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTimeout is how long UploadImage waits for an image to become
+// active after the upload
+const DefaultTimeout = 30 * time.Minute
+
+// Client is a client for the OpenStack Image service (Glance)
+type Client struct {
+	image *gophercloud.ServiceClient
+
+	// how often the status of an image is checked while waiting for it
+	// to become active
+	pollInterval time.Duration
+}
+
+// NewClient authenticates with Keystone and returns a client for the Image
+// service in the given region.
+func NewClient(auth gophercloud.AuthOptions, region string) (*Client, error) {
+	provider, err := openstack.AuthenticatedClient(auth)
+	if err != nil {
+		return nil, fmt.Errorf("cannot authenticate with OpenStack: %v", err)
+	}
+	return NewClientFromProvider(provider, region)
+}
+
+// NewClientFromProvider returns a client for the Image service in the
+// given region using an already authenticated provider.
+func NewClientFromProvider(provider *gophercloud.ProviderClient, region string) (*Client, error) {
+	image, err := openstack.NewImageServiceV2(provider, gophercloud.EndpointOpts{
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create an Image service client: %v", err)
+	}
+
+	return newClient(image), nil
+}
+
+// NewClientFromEnv is NewClient with the credentials read from the OS_*
+// environment variables. The region is read from OS_REGION_NAME if empty.
+func NewClientFromEnv(region string) (*Client, error) {
+	auth, err := openstack.AuthOptionsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the OpenStack credentials from the environment: %v", err)
+	}
+	if region == "" {
+		region = os.Getenv("OS_REGION_NAME")
+	}
+	return NewClient(auth, region)
+}
+
+func newClient(image *gophercloud.ServiceClient) *Client {
+	return &Client{
+		image:        image,
+		pollInterval: 10 * time.Second,
+	}
+}
+
+// ImageOptions describe the Glance image created by UploadImage
+type ImageOptions struct {
+	Name string
+	// Guessed from the file extension of the image if empty, see
+	// DiskFormatFromFilename
+	DiskFormat string
+	// Defaults to "bare"
+	ContainerFormat string
+	// One of "public", "private", "shared" or "community", Glance defaults
+	// to "shared" if empty
+	Visibility string
+
+	// Sets the hw_firmware_type property to "uefi"
+	UEFI bool
+	// Value of the os_distro property
+	OSDistro   string
+	Properties map[string]string
+	Tags       []string
+
+	// How long to wait for the image to become active, defaults to
+	// DefaultTimeout
+	Timeout time.Duration
+}
+
+var diskFormats = map[string]string{
+	".qcow2": "qcow2",
+	".raw":   "raw",
+	".img":   "raw",
+	".vmdk":  "vmdk",
+	".vhd":   "vhd",
+	".vhdx":  "vhdx",
+	".vdi":   "vdi",
+	".iso":   "iso",
+}
+
+// DiskFormatFromFilename returns the Glance disk format of an image file
+// based on its extension
+func DiskFormatFromFilename(filename string) (string, error) {
+	format, ok := diskFormats[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return "", fmt.Errorf("cannot determine the disk format of %q", filepath.Base(filename))
+	}
+	return format, nil
+}
+
+func (o ImageOptions) createOpts(filename string) (images.CreateOpts, error) {
+	opts := images.CreateOpts{
+		Name:            o.Name,
+		DiskFormat:      o.DiskFormat,
+		ContainerFormat: o.ContainerFormat,
+		Tags:            o.Tags,
+	}
+
+	if opts.DiskFormat == "" {
+		format, err := DiskFormatFromFilename(filename)
+		if err != nil {
+			return opts, err
+		}
+		opts.DiskFormat = format
+	}
+	if opts.ContainerFormat == "" {
+		opts.ContainerFormat = "bare"
+	}
+
+	switch visibility := images.ImageVisibility(o.Visibility); visibility {
+	case "":
+	case images.ImageVisibilityPublic, images.ImageVisibilityPrivate, images.ImageVisibilityShared, images.ImageVisibilityCommunity:
+		opts.Visibility = &visibility
+	default:
+		return opts, fmt.Errorf("invalid image visibility %q", o.Visibility)
+	}
+
+	if len(o.Properties) > 0 || o.UEFI || o.OSDistro != "" {
+		opts.Properties = make(map[string]string, len(o.Properties)+2)
+		for k, v := range o.Properties {
+			opts.Properties[k] = v
+		}
+		if o.UEFI {
+			opts.Properties["hw_firmware_type"] = "uefi"
+		}
+		if o.OSDistro != "" {
+			opts.Properties["os_distro"] = o.OSDistro
+		}
+	}
+
+	return opts, nil
+}
+
+// UploadImage creates a Glance image, uploads the image file into it and
+// waits for the image to become active. The checksum computed by Glance is
+// compared to the MD5 sum of the file to verify the integrity of the
+// uploaded image. The image is deleted if any of the steps fails.
+func (c *Client) UploadImage(ctx context.Context, filename string, options ImageOptions) (*images.Image, error) {
+	createOpts, err := options.createOpts(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	imageFile, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the image: %v", err)
+	}
+	defer imageFile.Close()
+
+	image, err := images.Create(c.image, createOpts).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the image: %v", err)
+	}
+
+	image, err = c.uploadImageData(ctx, image, imageFile, options.Timeout)
+	if err != nil {
+		if deleteErr := c.DeleteImage(image.ID); deleteErr != nil {
+			logrus.Warnf("[OpenStack] Cannot delete the image %s: %v", image.ID, deleteErr)
+		}
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func (c *Client) uploadImageData(ctx context.Context, image *images.Image, data io.Reader, timeout time.Duration) (*images.Image, error) {
+	/* #nosec G401 */
+	hash := md5.New()
+	if err := imagedata.Upload(c.image, image.ID, io.TeeReader(data, hash)).ExtractErr(); err != nil {
+		return image, fmt.Errorf("uploading the image failed: %v", err)
+	}
+
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		current, err := images.Get(c.image, image.ID).Extract()
+		if err != nil {
+			return image, fmt.Errorf("cannot get the status of the image: %v", err)
+		}
+
+		switch current.Status {
+		case images.ImageStatusActive:
+			if checksum := fmt.Sprintf("%x", hash.Sum(nil)); current.Checksum != "" && current.Checksum != checksum {
+				return image, fmt.Errorf("the checksum of the uploaded image %s does not match the image %s", current.Checksum, checksum)
+			}
+			return current, nil
+		case images.ImageStatusKilled, images.ImageStatusDeleted, images.ImageStatusPendingDelete:
+			return image, fmt.Errorf("the image became %s while waiting for it to become active", current.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return image, fmt.Errorf("waiting for the image to become active failed: %v", ctx.Err())
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// DeleteImage deletes the Glance image with the given ID
+func (c *Client) DeleteImage(id string) error {
+	if err := images.Delete(c.image, id).ExtractErr(); err != nil {
+		return fmt.Errorf("cannot delete the image: %v", err)
+	}
+	return nil
+}
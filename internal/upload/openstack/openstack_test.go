@@ -0,0 +1,153 @@
+package openstack
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGlance is a minimal stand-in for the Glance v2 API
+type fakeGlance struct {
+	mu       sync.Mutex
+	images   map[string]map[string]interface{}
+	data     map[string][]byte
+	status   string
+	checksum string
+}
+
+func (f *fakeGlance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 2:
+		var image map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&image); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		image["id"] = fmt.Sprintf("image-%d", len(f.images)+1)
+		image["status"] = "queued"
+		f.images[image["id"].(string)] = image
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(image)
+	case r.Method == http.MethodPut && len(parts) == 4 && parts[3] == "file":
+		data, _ := io.ReadAll(r.Body)
+		f.data[parts[2]] = data
+		f.images[parts[2]]["status"] = f.status
+		f.images[parts[2]]["checksum"] = f.checksum
+		if f.checksum == "" {
+			f.images[parts[2]]["checksum"] = fmt.Sprintf("%x", md5.Sum(data))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && len(parts) == 3:
+		_ = json.NewEncoder(w).Encode(f.images[parts[2]])
+	case r.Method == http.MethodDelete && len(parts) == 3:
+		delete(f.images, parts[2])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeGlance) {
+	fake := &fakeGlance{
+		images: make(map[string]map[string]interface{}),
+		data:   make(map[string][]byte),
+		status: "active",
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	c := newClient(&gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{},
+		Endpoint:       srv.URL + "/",
+		ResourceBase:   srv.URL + "/v2/",
+	})
+	c.pollInterval = time.Millisecond
+	return c, fake
+}
+
+func TestDiskFormatFromFilename(t *testing.T) {
+	for filename, format := range map[string]string{
+		"disk.qcow2":    "qcow2",
+		"disk.raw":      "raw",
+		"disk.IMG":      "raw",
+		"image.vhd":     "vhd",
+		"installer.iso": "iso",
+	} {
+		got, err := DiskFormatFromFilename(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, format, got, filename)
+	}
+	_, err := DiskFormatFromFilename("image.tar.xz")
+	assert.EqualError(t, err, `cannot determine the disk format of "image.tar.xz"`)
+}
+
+func TestUploadImage(t *testing.T) {
+	c, fake := newTestClient(t)
+	filename := filepath.Join(t.TempDir(), "disk.qcow2")
+	require.NoError(t, os.WriteFile(filename, []byte("qcow2 data"), 0600))
+
+	image, err := c.UploadImage(context.Background(), filename, ImageOptions{
+		Name:       "my-image",
+		Visibility: "private",
+		UEFI:       true,
+		OSDistro:   "fedora",
+		Properties: map[string]string{"hw_disk_bus": "scsi"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "image-1", image.ID)
+	assert.Equal(t, []byte("qcow2 data"), fake.data["image-1"])
+
+	created := fake.images["image-1"]
+	assert.Equal(t, "my-image", created["name"])
+	assert.Equal(t, "qcow2", created["disk_format"])
+	assert.Equal(t, "bare", created["container_format"])
+	assert.Equal(t, "private", created["visibility"])
+	assert.Equal(t, "uefi", created["hw_firmware_type"])
+	assert.Equal(t, "fedora", created["os_distro"])
+	assert.Equal(t, "scsi", created["hw_disk_bus"])
+}
+
+func TestUploadImageErrors(t *testing.T) {
+	c, fake := newTestClient(t)
+	filename := filepath.Join(t.TempDir(), "disk.raw")
+	require.NoError(t, os.WriteFile(filename, []byte("raw data"), 0600))
+
+	_, err := c.UploadImage(context.Background(), filename, ImageOptions{Name: "my-image", Visibility: "everyone"})
+	assert.EqualError(t, err, `invalid image visibility "everyone"`)
+
+	// the image is deleted if it does not become active
+	fake.status = "killed"
+	_, err = c.UploadImage(context.Background(), filename, ImageOptions{Name: "my-image"})
+	assert.EqualError(t, err, "the image became killed while waiting for it to become active")
+	assert.Empty(t, fake.images)
+
+	fake.status = "saving"
+	_, err = c.UploadImage(context.Background(), filename, ImageOptions{Name: "my-image", Timeout: 10 * time.Millisecond})
+	assert.ErrorContains(t, err, "waiting for the image to become active failed")
+	assert.Empty(t, fake.images)
+
+	fake.status = "active"
+	fake.checksum = "0123456789abcdef0123456789abcdef"
+	_, err = c.UploadImage(context.Background(), filename, ImageOptions{Name: "my-image"})
+	assert.ErrorContains(t, err, "the checksum of the uploaded image 0123456789abcdef0123456789abcdef does not match the image")
+	assert.Empty(t, fake.images)
+}
@@ -0,0 +1,79 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// state is the on-disk record of an upload in progress
+type state struct {
+	Size      int64        `json:"size"`
+	ChunkSize int64        `json:"chunk_size"`
+	Session   string       `json:"session,omitempty"`
+	Parts     map[int]Part `json:"parts"`
+}
+
+// loadState reads the state from path. A fresh state is returned if path is
+// empty, does not exist or records an upload of a different source.
+func loadState(path string, size, chunkSize int64) (*state, error) {
+	fresh := &state{
+		Size:      size,
+		ChunkSize: chunkSize,
+		Parts:     make(map[int]Part),
+	}
+	if path == "" {
+		return fresh, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fresh, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read the upload state: %v", err)
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("cannot parse the upload state %q: %v", path, err)
+	}
+	if st.Size != size || st.ChunkSize != chunkSize {
+		return fresh, nil
+	}
+	if st.Parts == nil {
+		st.Parts = make(map[int]Part)
+	}
+	return &st, nil
+}
+
+// save writes the state to path atomically, nothing is written if path is
+// empty
+func (s *state) save(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot write the upload state: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write the upload state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write the upload state: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot write the upload state: %v", err)
+	}
+	return nil
+}
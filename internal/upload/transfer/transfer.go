@@ -0,0 +1,430 @@
+// Package transfer implements a generic engine for uploading large image
+// files to cloud storage in chunks.
+//
+// The engine splits the source into fixed size chunks, transfers them in
+// parallel to a Destination and computes the checksums of the whole source
+// on the way, so that the Destination can verify the upload end-to-end once
+// all chunks have been written. Chunks that only contain zeros can be
+// skipped for destinations that are zero-initialized (e.g. Azure page
+// blobs). If a state file is configured, the progress of the upload is
+// recorded on disk and an interrupted upload is resumed from the chunks that
+// were already transferred. Otherwise a failed upload is aborted, so that the
+// destination can remove the chunks that were already written.
+package transfer
+
+import (
+	"bytes"
+	"context"
+	// the md5 digests are required by the storage APIs
+	/* #nosec G501 */
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	DefaultChunkSize   int64 = 8 * 1024 * 1024
+	DefaultConcurrency int   = 4
+)
+
+// Chunk is a contiguous region of the source
+type Chunk struct {
+	Index  int   `json:"index"`
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// Part is a chunk that has been processed by the engine
+type Part struct {
+	Chunk
+	// Hex encoded MD5 digest of the chunk data
+	MD5 string `json:"md5"`
+	// Hex encoded SHA-256 digest of the chunk data
+	SHA256 string `json:"sha256"`
+	// Identifier of the part returned by the destination, for example
+	// the ETag of an S3 multipart upload part
+	ID string `json:"id,omitempty"`
+	// Skipped is set if the chunk only contained zeros and was not
+	// written to the destination
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Digest holds the checksums of the whole source
+type Digest struct {
+	MD5    []byte
+	SHA256 []byte
+	CRC32C uint32
+}
+
+// Destination is the receiving end of an upload.
+type Destination interface {
+	// Begin prepares the destination for an upload of the given size. The
+	// session is empty for a new upload or the value returned by a
+	// previous call to Begin when an interrupted upload is resumed. The
+	// returned session identifies the upload and is stored in the state
+	// file. If the session cannot be resumed, a new one must be returned
+	// and all chunks are written again.
+	Begin(ctx context.Context, size int64, session string) (string, error)
+
+	// WriteChunk writes the data of a chunk along with its MD5 digest,
+	// which should be passed on to the storage service for transactional
+	// validation where supported. It may be called concurrently. The
+	// returned identifier is recorded in the Part of the chunk.
+	WriteChunk(ctx context.Context, session string, chunk Chunk, data []byte, md5sum []byte) (string, error)
+
+	// Complete finalizes the upload once all chunks have been written. The
+	// parts are ordered by index and the digest covers the whole source,
+	// destinations should use it to verify the result where possible.
+	Complete(ctx context.Context, session string, parts []Part, digest Digest) error
+}
+
+// A PartChecker is a Destination that can tell which parts of a resumed
+// session it still stores. When Upload resumes a session, it passes the
+// parts recorded in the state file to CheckParts and uploads the chunks of
+// the parts that are not returned again.
+type PartChecker interface {
+	CheckParts(ctx context.Context, session string, parts []Part) ([]Part, error)
+}
+
+// An Aborter is a Destination that can discard an upload. When an upload
+// fails and can't be resumed because no state file is set, Upload passes
+// the session to Abort, so that the chunks written so far don't keep using
+// storage.
+type Aborter interface {
+	Abort(ctx context.Context, session string) error
+}
+
+// Progress is reported after every chunk that has been processed
+type Progress struct {
+	// Size of the source in bytes
+	Size int64
+	// Bytes written to the destination
+	Transferred int64
+	// Bytes that did not need to be written because they only contained
+	// zeros or were already uploaded before the upload was resumed
+	Skipped int64
+}
+
+type Options struct {
+	// Size of the chunks the source is split into. Defaults to
+	// DefaultChunkSize.
+	ChunkSize int64
+
+	// Maximum number of chunks written in parallel. Defaults to
+	// DefaultConcurrency.
+	Concurrency int
+
+	// Path to a file to record the state of the upload in. If the file
+	// exists, the upload is resumed from it. The file is removed once
+	// the upload completes successfully. No state is recorded if empty.
+	StateFile string
+
+	// Do not write chunks that only contain zeros. Only set this for
+	// destinations that are initialized with zeros.
+	SkipZeroChunks bool
+
+	// Called after every processed chunk. Calls are serialized.
+	Progress func(Progress)
+}
+
+// Result of a successful upload
+type Result struct {
+	Size   int64
+	Digest Digest
+	Parts  []Part
+}
+
+// UploadFile uploads the file at the given path to the destination.
+func UploadFile(ctx context.Context, filename string, dst Destination, opts Options) (*Result, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the image: %v", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat the image: %v", err)
+	}
+
+	return Upload(ctx, file, stat.Size(), dst, opts)
+}
+
+// Chunks returns the list of chunks a source of the given size is split into
+func Chunks(size, chunkSize int64) []Chunk {
+	var chunks []Chunk
+	for offset := int64(0); offset < size; offset += chunkSize {
+		n := chunkSize
+		if offset+n > size {
+			n = size - offset
+		}
+		chunks = append(chunks, Chunk{Index: len(chunks), Offset: offset, Size: n})
+	}
+	return chunks
+}
+
+type job struct {
+	chunk     Chunk
+	buffer    []byte
+	md5sum    []byte
+	sha256sum []byte
+}
+
+// Upload reads size bytes from src and uploads them to the destination. A
+// failed upload is aborted if no state file is set and the destination is
+// an Aborter.
+func Upload(ctx context.Context, src io.ReaderAt, size int64, dst Destination, opts Options) (result *Result, err error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+
+	st, err := loadState(opts.StateFile, size, opts.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := dst.Begin(ctx, size, st.Session)
+	if err != nil {
+		return nil, fmt.Errorf("cannot begin the upload: %w", err)
+	}
+	// the parts of an interrupted upload are lost if the destination could
+	// not resume its session
+	if session != st.Session {
+		st.Session = session
+		st.Parts = make(map[int]Part)
+	} else if checker, ok := dst.(PartChecker); ok && len(st.Parts) > 0 {
+		parts, err := checker.CheckParts(ctx, session, st.sortedParts())
+		if err != nil {
+			return nil, fmt.Errorf("cannot check the parts of the interrupted upload: %w", err)
+		}
+		st.Parts = make(map[int]Part)
+		for _, part := range parts {
+			st.Parts[part.Index] = part
+		}
+	}
+	if aborter, ok := dst.(Aborter); ok && opts.StateFile == "" {
+		defer func() {
+			if err == nil {
+				return
+			}
+			// the context of the upload may already be cancelled
+			if abortErr := aborter.Abort(context.Background(), session); abortErr != nil {
+				err = fmt.Errorf("%w (aborting the upload failed: %v)", err, abortErr)
+			}
+		}()
+	}
+	if err := st.save(opts.StateFile); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	progress := Progress{Size: size}
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	// done records a processed part and must be called with mu held
+	done := func(part Part, transferred bool) error {
+		st.Parts[part.Index] = part
+		if transferred {
+			progress.Transferred += part.Size
+		} else {
+			progress.Skipped += part.Size
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		return st.save(opts.StateFile)
+	}
+
+	// the buffers are recycled between the reader and the workers, this
+	// bounds the memory used by the upload
+	buffers := make(chan []byte, opts.Concurrency+1)
+	for i := 0; i < cap(buffers); i++ {
+		buffers <- make([]byte, opts.ChunkSize)
+	}
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					buffers <- j.buffer
+					continue
+				}
+				data := j.buffer[:j.chunk.Size]
+				id, err := dst.WriteChunk(ctx, st.Session, j.chunk, data, j.md5sum)
+				buffers <- j.buffer
+				if err != nil {
+					setErr(fmt.Errorf("uploading chunk %d at offset %d failed: %w", j.chunk.Index, j.chunk.Offset, err))
+					continue
+				}
+				mu.Lock()
+				err = done(Part{Chunk: j.chunk, MD5: fmt.Sprintf("%x", j.md5sum), SHA256: fmt.Sprintf("%x", j.sha256sum), ID: id}, true)
+				mu.Unlock()
+				if err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+
+	// the source is read sequentially to compute the digests of the whole
+	// source, including chunks that are not written
+	/* #nosec G401 */
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	crc32cHash := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	hashes := io.MultiWriter(md5Hash, sha256Hash, crc32cHash)
+
+	chunks := Chunks(size, opts.ChunkSize)
+	readErr := func() error {
+		defer close(jobs)
+		for _, chunk := range chunks {
+			var buffer []byte
+			select {
+			case buffer = <-buffers:
+			case <-ctx.Done():
+				return nil
+			}
+
+			data := buffer[:chunk.Size]
+			// io.ReaderAt may return io.EOF together with a full chunk at the
+			// end of the source, anything shorter means the source is
+			// smaller than its announced size
+			n, err := src.ReadAt(data, chunk.Offset)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("reading the image failed: %v", err)
+			}
+			if int64(n) != chunk.Size {
+				return fmt.Errorf("reading the image failed: short read of chunk %d: got %d of %d bytes", chunk.Index, n, chunk.Size)
+			}
+			if _, err := hashes.Write(data); err != nil {
+				return err
+			}
+			/* #nosec G401 */
+			chunkSum := md5.Sum(data)
+			md5sum := chunkSum[:]
+			chunkSHA256 := sha256.Sum256(data)
+			sha256sum := chunkSHA256[:]
+
+			mu.Lock()
+			prev, uploaded := st.Parts[chunk.Index]
+			mu.Unlock()
+			// resume from the state if the chunk did not change
+			if uploaded && prev.Chunk == chunk && prev.MD5 == fmt.Sprintf("%x", md5sum) && prev.SHA256 == fmt.Sprintf("%x", sha256sum) {
+				buffers <- buffer
+				mu.Lock()
+				err := done(prev, false)
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			if opts.SkipZeroChunks && allZeros(data) {
+				buffers <- buffer
+				mu.Lock()
+				err := done(Part{Chunk: chunk, MD5: fmt.Sprintf("%x", md5sum), SHA256: fmt.Sprintf("%x", sha256sum), Skipped: true}, false)
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			select {
+			case jobs <- job{chunk: chunk, buffer: buffer, md5sum: md5sum, sha256sum: sha256sum}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	}()
+	if readErr != nil {
+		setErr(readErr)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// the reader and the workers stop without an error when the upload is
+	// cancelled, the destination must not complete a partial upload
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("upload interrupted: %w", err)
+	}
+	for _, chunk := range chunks {
+		if _, ok := st.Parts[chunk.Index]; !ok {
+			return nil, fmt.Errorf("chunk %d at offset %d was not uploaded", chunk.Index, chunk.Offset)
+		}
+	}
+
+	result = &Result{
+		Size: size,
+		Digest: Digest{
+			MD5:    md5Hash.Sum(nil),
+			SHA256: sha256Hash.Sum(nil),
+			CRC32C: crc32cHash.Sum32(),
+		},
+		Parts: st.sortedParts(),
+	}
+
+	if err := dst.Complete(ctx, st.Session, result.Parts, result.Digest); err != nil {
+		return nil, fmt.Errorf("cannot complete the upload: %w", err)
+	}
+
+	if opts.StateFile != "" {
+		if err := os.Remove(opts.StateFile); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot remove the upload state: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+var zeros = make([]byte, 64*1024)
+
+func allZeros(data []byte) bool {
+	for len(data) > 0 {
+		n := len(zeros)
+		if n > len(data) {
+			n = len(data)
+		}
+		if !bytes.Equal(data[:n], zeros[:n]) {
+			return false
+		}
+		data = data[n:]
+	}
+	return true
+}
+
+func (s *state) sortedParts() []Part {
+	parts := make([]Part, 0, len(s.Parts))
+	for _, part := range s.Parts {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Index < parts[j].Index })
+	return parts
+}
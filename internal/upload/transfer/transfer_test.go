@@ -0,0 +1,363 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memDestination is an in-memory stand-in for a storage service
+type memDestination struct {
+	mu       sync.Mutex
+	data     []byte
+	sessions int
+	writes   map[int]int
+	failAt   int
+	expired  bool
+	lost     map[int]bool
+	digest   Digest
+	parts    []Part
+
+	// cancel is called when the chunk cancelAt is written, the
+	// destination itself ignores the context
+	cancelAt  int
+	cancel    context.CancelFunc
+	completes int
+
+	aborted  []string
+	abortErr error
+}
+
+func newMemDestination() *memDestination {
+	return &memDestination{writes: make(map[int]int), failAt: -1, cancelAt: -1}
+}
+
+func (d *memDestination) Begin(ctx context.Context, size int64, session string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if session != "" && !d.expired {
+		return session, nil
+	}
+	d.sessions++
+	d.data = make([]byte, size)
+	return fmt.Sprintf("session-%d", d.sessions), nil
+}
+
+func (d *memDestination) WriteChunk(ctx context.Context, session string, chunk Chunk, data []byte, md5sum []byte) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if chunk.Index == d.failAt {
+		return "", errors.New("connection reset")
+	}
+	if sum := md5.Sum(data); !bytes.Equal(sum[:], md5sum) {
+		return "", errors.New("md5 mismatch")
+	}
+	copy(d.data[chunk.Offset:], data)
+	d.writes[chunk.Index]++
+	if chunk.Index == d.cancelAt {
+		d.cancel()
+	}
+	return fmt.Sprintf("etag-%d", chunk.Index), nil
+}
+
+func (d *memDestination) CheckParts(ctx context.Context, session string, parts []Part) ([]Part, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var kept []Part
+	for _, part := range parts {
+		if !d.lost[part.Index] {
+			kept = append(kept, part)
+		}
+	}
+	return kept, nil
+}
+
+func (d *memDestination) Complete(ctx context.Context, session string, parts []Part, digest Digest) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.completes++
+	if sum := md5.Sum(d.data); !bytes.Equal(sum[:], digest.MD5) {
+		return errors.New("md5 mismatch")
+	}
+	d.parts = parts
+	d.digest = digest
+	return nil
+}
+
+func (d *memDestination) Abort(ctx context.Context, session string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.aborted = append(d.aborted, session)
+	return d.abortErr
+}
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestChunks(t *testing.T) {
+	assert.Nil(t, Chunks(0, 10))
+	assert.Equal(t, []Chunk{{0, 0, 10}}, Chunks(10, 10))
+	assert.Equal(t, []Chunk{{0, 0, 10}, {1, 10, 10}, {2, 20, 5}}, Chunks(25, 10))
+}
+
+func TestUpload(t *testing.T) {
+	for _, size := range []int{0, 1, 1024, 1000, 4097} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			data := testData(size)
+			dst := newMemDestination()
+
+			var last Progress
+			res, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, Options{
+				ChunkSize:   256,
+				Concurrency: 3,
+				Progress:    func(p Progress) { last = p },
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, data, dst.data)
+			assert.Equal(t, int64(size), res.Size)
+			assert.Len(t, res.Parts, (size+255)/256)
+			for i, part := range res.Parts {
+				assert.Equal(t, i, part.Index)
+				assert.Equal(t, fmt.Sprintf("etag-%d", i), part.ID)
+				assert.Equal(t, fmt.Sprintf("%x", md5.Sum(data[part.Offset:part.Offset+part.Size])), part.MD5)
+			}
+
+			md5sum := md5.Sum(data)
+			sha256sum := sha256.Sum256(data)
+			assert.Equal(t, md5sum[:], res.Digest.MD5)
+			assert.Equal(t, sha256sum[:], res.Digest.SHA256)
+			assert.Equal(t, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)), res.Digest.CRC32C)
+			assert.Equal(t, res.Digest, dst.digest)
+
+			if size > 0 {
+				assert.Equal(t, Progress{Size: int64(size), Transferred: int64(size)}, last)
+			}
+		})
+	}
+}
+
+func TestUploadSkipZeroChunks(t *testing.T) {
+	data := testData(1024)
+	copy(data[256:768], make([]byte, 512))
+	dst := newMemDestination()
+
+	var last Progress
+	res, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, Options{
+		ChunkSize:      256,
+		SkipZeroChunks: true,
+		Progress:       func(p Progress) { last = p },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, data, dst.data)
+	assert.Equal(t, map[int]int{0: 1, 3: 1}, dst.writes)
+	assert.True(t, res.Parts[1].Skipped)
+	assert.True(t, res.Parts[2].Skipped)
+	assert.Equal(t, Progress{Size: 1024, Transferred: 512, Skipped: 512}, last)
+}
+
+func TestUploadResume(t *testing.T) {
+	data := testData(2048)
+	stateFile := filepath.Join(t.TempDir(), "upload.state")
+	dst := newMemDestination()
+	dst.failAt = 5
+
+	opts := Options{
+		ChunkSize:   256,
+		Concurrency: 1,
+		StateFile:   stateFile,
+	}
+	_, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.ErrorContains(t, err, "uploading chunk 5 at offset 1280 failed: connection reset")
+	require.FileExists(t, stateFile)
+	// the upload can be resumed, it's not aborted
+	assert.Empty(t, dst.aborted)
+
+	// the chunks that were transferred before the failure are not
+	// uploaded again and the session is reused
+	dst.failAt = -1
+	var last Progress
+	opts.Progress = func(p Progress) { last = p }
+	_, err = Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, data, dst.data)
+	assert.Equal(t, 1, dst.sessions)
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, 1, dst.writes[i], "chunk %d", i)
+	}
+	assert.Equal(t, Progress{Size: 2048, Transferred: 768, Skipped: 1280}, last)
+	assert.NoFileExists(t, stateFile)
+}
+
+func TestUploadAbort(t *testing.T) {
+	data := testData(2048)
+	dst := newMemDestination()
+	dst.failAt = 5
+
+	_, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, Options{ChunkSize: 256})
+	require.ErrorContains(t, err, "uploading chunk 5 at offset 1280 failed: connection reset")
+	assert.Equal(t, []string{"session-1"}, dst.aborted)
+
+	// a failure to abort is reported along with the error of the upload
+	dst.abortErr = errors.New("access denied")
+	_, err = Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, Options{ChunkSize: 256})
+	assert.EqualError(t, err, "uploading chunk 5 at offset 1280 failed: connection reset (aborting the upload failed: access denied)")
+	assert.Equal(t, []string{"session-1", "session-2"}, dst.aborted)
+
+	// a successful upload is not aborted
+	dst.failAt = -1
+	_, err = Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, Options{ChunkSize: 256})
+	require.NoError(t, err)
+	assert.Len(t, dst.aborted, 2)
+}
+
+func TestUploadCancelled(t *testing.T) {
+	data := testData(4096)
+	stateFile := filepath.Join(t.TempDir(), "upload.state")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dst := newMemDestination()
+	dst.cancelAt = 2
+	dst.cancel = cancel
+
+	_, err := Upload(ctx, bytes.NewReader(data), int64(len(data)), dst, Options{
+		ChunkSize:   256,
+		Concurrency: 1,
+		StateFile:   stateFile,
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, dst.completes)
+	assert.Less(t, len(dst.writes), 16)
+	// the upload can be resumed
+	assert.FileExists(t, stateFile)
+}
+
+func TestUploadShortSource(t *testing.T) {
+	data := testData(1000)
+	dst := newMemDestination()
+
+	// the source is smaller than the announced size
+	_, err := Upload(context.Background(), bytes.NewReader(data), 1024, dst, Options{
+		ChunkSize:   256,
+		Concurrency: 1,
+	})
+	require.ErrorContains(t, err, "short read of chunk 3: got 232 of 256 bytes")
+	assert.Equal(t, 0, dst.completes)
+}
+
+func TestUploadResumeChangedSource(t *testing.T) {
+	data := testData(1024)
+	stateFile := filepath.Join(t.TempDir(), "upload.state")
+	dst := newMemDestination()
+	dst.failAt = 3
+
+	opts := Options{
+		ChunkSize:   256,
+		Concurrency: 1,
+		StateFile:   stateFile,
+	}
+	_, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.Error(t, err)
+
+	// chunks whose content changed since the interrupted upload are
+	// uploaded again
+	data[0] = 0xff
+	dst.failAt = -1
+	_, err = Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.NoError(t, err)
+	assert.Equal(t, data, dst.data)
+	assert.Equal(t, map[int]int{0: 2, 1: 1, 2: 1, 3: 1}, dst.writes)
+
+	// a state for a source of a different size is discarded
+	require.NoError(t, os.WriteFile(stateFile, []byte(`{"size":1,"chunk_size":256,"session":"stale"}`), 0600))
+	_, err = Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, dst.sessions)
+}
+
+func TestUploadFile(t *testing.T) {
+	data := testData(1000)
+	filename := filepath.Join(t.TempDir(), "disk.raw")
+	require.NoError(t, os.WriteFile(filename, data, 0600))
+
+	dst := newMemDestination()
+	res, err := UploadFile(context.Background(), filename, dst, Options{ChunkSize: 100})
+	require.NoError(t, err)
+	assert.Equal(t, data, dst.data)
+	assert.Len(t, res.Parts, 10)
+
+	_, err = UploadFile(context.Background(), filepath.Join(t.TempDir(), "missing"), dst, Options{})
+	assert.ErrorContains(t, err, "cannot open the image")
+}
+
+func TestUploadResumeExpiredSession(t *testing.T) {
+	data := testData(1024)
+	stateFile := filepath.Join(t.TempDir(), "upload.state")
+	dst := newMemDestination()
+	dst.failAt = 2
+
+	opts := Options{
+		ChunkSize:   256,
+		Concurrency: 1,
+		StateFile:   stateFile,
+	}
+	_, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.Error(t, err)
+
+	// the destination lost the interrupted upload, so everything is
+	// uploaded again
+	dst.failAt = -1
+	dst.expired = true
+	_, err = Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.NoError(t, err)
+	assert.Equal(t, data, dst.data)
+	assert.Equal(t, 2, dst.sessions)
+	assert.Equal(t, map[int]int{0: 2, 1: 2, 2: 1, 3: 1}, dst.writes)
+}
+
+func TestUploadResumeLostParts(t *testing.T) {
+	data := testData(1024)
+	stateFile := filepath.Join(t.TempDir(), "upload.state")
+	dst := newMemDestination()
+	dst.failAt = 3
+
+	opts := Options{
+		ChunkSize:   256,
+		Concurrency: 1,
+		StateFile:   stateFile,
+	}
+	_, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.Error(t, err)
+
+	// the session is resumed, but the destination no longer has a part
+	// that was recorded, so it's uploaded again
+	dst.failAt = -1
+	dst.lost = map[int]bool{1: true}
+	copy(dst.data[256:512], make([]byte, 256))
+	res, err := Upload(context.Background(), bytes.NewReader(data), int64(len(data)), dst, opts)
+	require.NoError(t, err)
+	assert.Equal(t, data, dst.data)
+	assert.Equal(t, 1, dst.sessions)
+	assert.Equal(t, map[int]int{0: 1, 1: 2, 2: 1, 3: 1}, dst.writes)
+
+	sum := sha256.Sum256(data[256:512])
+	assert.Equal(t, fmt.Sprintf("%x", sum), res.Parts[1].SHA256)
+}
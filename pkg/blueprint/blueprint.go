@@ -10,6 +10,7 @@ type Blueprint struct {
 	Modules        []Package       `json:"modules" toml:"modules"`
 	Groups         []Group         `json:"groups" toml:"groups"`
 	Containers     []Container     `json:"containers,omitempty" toml:"containers,omitempty"`
+	Flatpaks       []Flatpak       `json:"flatpaks,omitempty" toml:"flatpaks,omitempty"`
 	Customizations *Customizations `json:"customizations,omitempty" toml:"customizations"`
 	Distro         string          `json:"distro" toml:"distro"`
 
@@ -0,0 +1,140 @@
+package blueprint
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// validContainerTrustDefaults holds the values of the default trust policy
+var validContainerTrustDefaults = []string{"accept", "reject"}
+
+// validContainerTrustTypes holds the supported types of trust rules
+var validContainerTrustTypes = []string{"accept", "reject", "gpg", "sigstore"}
+
+// ContainersCustomization configures how the containers tools of the image
+// (podman, skopeo, ...) pull container images.
+type ContainersCustomization struct {
+	// Registries that are tried in order for short names
+	UnqualifiedSearchRegistries []string `json:"unqualified_search_registries,omitempty" toml:"unqualified_search_registries,omitempty"`
+
+	// Fully qualified image names for short names, e.g.
+	// "ubi9" = "registry.access.redhat.com/ubi9"
+	ShortNameAliases map[string]string `json:"short_name_aliases,omitempty" toml:"short_name_aliases,omitempty"`
+
+	Registries []ContainerRegistryCustomization `json:"registries,omitempty" toml:"registries,omitempty"`
+
+	// Signature policy, replaces the default policy of the image
+	Policy *ContainerPolicyCustomization `json:"policy,omitempty" toml:"policy,omitempty"`
+}
+
+// ContainerRegistryCustomization configures the registry, namespace or
+// repository matching the Prefix.
+type ContainerRegistryCustomization struct {
+	Prefix string `json:"prefix" toml:"prefix"`
+
+	// Location to pull from instead of Prefix
+	Location string `json:"location,omitempty" toml:"location,omitempty"`
+	Insecure bool   `json:"insecure,omitempty" toml:"insecure,omitempty"`
+	Blocked  bool   `json:"blocked,omitempty" toml:"blocked,omitempty"`
+
+	// Mirrors that are tried in order before the registry itself
+	Mirrors []ContainerRegistryMirrorCustomization `json:"mirrors,omitempty" toml:"mirrors,omitempty"`
+}
+
+type ContainerRegistryMirrorCustomization struct {
+	Location string `json:"location" toml:"location"`
+	Insecure bool   `json:"insecure,omitempty" toml:"insecure,omitempty"`
+}
+
+// ContainerPolicyCustomization is the signature policy for images pulled
+// from registries. It replaces the policy of the distribution, including its
+// rules for the vendor registries (e.g. registry.redhat.io on RHEL), which
+// need to be repeated in Rules to keep them.
+type ContainerPolicyCustomization struct {
+	// Policy for images that don't match any rule: accept or reject.
+	// Required, since the policy of the distribution is not merged.
+	Default string `json:"default,omitempty" toml:"default,omitempty"`
+
+	Rules []ContainerTrustRuleCustomization `json:"rules,omitempty" toml:"rules,omitempty"`
+}
+
+// ContainerTrustRuleCustomization sets the policy for the images matching
+// the Scope (a registry, namespace or repository, e.g. "quay.io/fedora").
+// The Type is one of:
+//   - accept: accept images without checking signatures
+//   - reject: reject all images
+//   - gpg: require a simple signing signature by the GPG key at KeyPath
+//   - sigstore: require a sigstore signature by the key at KeyPath, the
+//     signatures are looked up as sigstore attachments in the registry
+//
+// The key needs to be added to the image, e.g. with a file customization.
+type ContainerTrustRuleCustomization struct {
+	Scope   string `json:"scope" toml:"scope"`
+	Type    string `json:"type" toml:"type"`
+	KeyPath string `json:"key_path,omitempty" toml:"key_path,omitempty"`
+}
+
+// GetContainers returns the containers customization after validating it.
+func (c *Customizations) GetContainers() (*ContainersCustomization, error) {
+	if c == nil || c.Containers == nil {
+		return nil, nil
+	}
+
+	cc := c.Containers
+	for short, long := range cc.ShortNameAliases {
+		// short names must not include a registry
+		if short == "" || strings.ContainsAny(strings.Split(short, "/")[0], ".:") {
+			return nil, fmt.Errorf("invalid short name %q", short)
+		}
+		if !strings.Contains(long, "/") {
+			return nil, fmt.Errorf("short name %q must be an alias for a fully qualified image name, not %q", short, long)
+		}
+	}
+	for _, registry := range cc.Registries {
+		if registry.Prefix == "" {
+			return nil, fmt.Errorf("container registries require a prefix")
+		}
+		for _, mirror := range registry.Mirrors {
+			if mirror.Location == "" {
+				return nil, fmt.Errorf("mirrors of container registry %q require a location", registry.Prefix)
+			}
+		}
+	}
+
+	if policy := cc.Policy; policy != nil {
+		if policy.Default == "" {
+			return nil, fmt.Errorf("the container trust policy requires a default (%s), it replaces the policy of the distribution", strings.Join(validContainerTrustDefaults, " or "))
+		}
+		if !slices.Contains(validContainerTrustDefaults, policy.Default) {
+			return nil, fmt.Errorf("invalid default container trust policy %q: must be one of %s", policy.Default, strings.Join(validContainerTrustDefaults, ", "))
+		}
+		scopes := make(map[string]bool)
+		for _, rule := range policy.Rules {
+			if rule.Scope == "" {
+				return nil, fmt.Errorf("container trust rules require a scope")
+			}
+			if scopes[rule.Scope] {
+				return nil, fmt.Errorf("container trust rule scope %q is used more than once", rule.Scope)
+			}
+			scopes[rule.Scope] = true
+
+			switch rule.Type {
+			case "accept", "reject":
+				if rule.KeyPath != "" {
+					return nil, fmt.Errorf("container trust rule for %q of type %q does not take a key", rule.Scope, rule.Type)
+				}
+			case "gpg", "sigstore":
+				if !filepath.IsAbs(rule.KeyPath) {
+					return nil, fmt.Errorf("container trust rule for %q of type %q requires an absolute key path", rule.Scope, rule.Type)
+				}
+			default:
+				return nil, fmt.Errorf("invalid type %q of container trust rule for %q: must be one of %s", rule.Type, rule.Scope, strings.Join(validContainerTrustTypes, ", "))
+			}
+		}
+	}
+
+	return cc, nil
+}
@@ -0,0 +1,112 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContainers(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers *ContainersCustomization
+		wantErr    string
+	}{
+		{
+			name: "valid",
+			containers: &ContainersCustomization{
+				UnqualifiedSearchRegistries: []string{"registry.access.redhat.com"},
+				ShortNameAliases:            map[string]string{"ubi9": "registry.access.redhat.com/ubi9"},
+				Registries: []ContainerRegistryCustomization{
+					{Prefix: "docker.io", Mirrors: []ContainerRegistryMirrorCustomization{{Location: "mirror.example.com"}}},
+					{Prefix: "evil.example.com", Blocked: true},
+				},
+				Policy: &ContainerPolicyCustomization{
+					Default: "reject",
+					Rules: []ContainerTrustRuleCustomization{
+						{Scope: "registry.access.redhat.com", Type: "gpg", KeyPath: "/etc/pki/rpm-gpg/RPM-GPG-KEY-redhat-release"},
+						{Scope: "quay.io/fedora", Type: "sigstore", KeyPath: "/etc/pki/containers/fedora.pub"},
+						{Scope: "localhost:5000", Type: "accept"},
+					},
+				},
+			},
+		},
+		{
+			name:       "alias-with-registry",
+			containers: &ContainersCustomization{ShortNameAliases: map[string]string{"quay.io/ubi9": "registry.access.redhat.com/ubi9"}},
+			wantErr:    `invalid short name "quay.io/ubi9"`,
+		},
+		{
+			name:       "alias-not-qualified",
+			containers: &ContainersCustomization{ShortNameAliases: map[string]string{"ubi9": "ubi9"}},
+			wantErr:    `short name "ubi9" must be an alias for a fully qualified image name, not "ubi9"`,
+		},
+		{
+			name:       "registry-no-prefix",
+			containers: &ContainersCustomization{Registries: []ContainerRegistryCustomization{{Blocked: true}}},
+			wantErr:    "container registries require a prefix",
+		},
+		{
+			name: "mirror-no-location",
+			containers: &ContainersCustomization{Registries: []ContainerRegistryCustomization{
+				{Prefix: "docker.io", Mirrors: []ContainerRegistryMirrorCustomization{{Insecure: true}}},
+			}},
+			wantErr: `mirrors of container registry "docker.io" require a location`,
+		},
+		{
+			name:       "invalid-default",
+			containers: &ContainersCustomization{Policy: &ContainerPolicyCustomization{Default: "gpg"}},
+			wantErr:    `invalid default container trust policy "gpg": must be one of accept, reject`,
+		},
+		{
+			name: "no-default",
+			containers: &ContainersCustomization{Policy: &ContainerPolicyCustomization{Rules: []ContainerTrustRuleCustomization{
+				{Scope: "quay.io", Type: "reject"},
+			}}},
+			wantErr: "the container trust policy requires a default (accept or reject), it replaces the policy of the distribution",
+		},
+		{
+			name: "duplicate-scope",
+			containers: &ContainersCustomization{Policy: &ContainerPolicyCustomization{Default: "accept", Rules: []ContainerTrustRuleCustomization{
+				{Scope: "quay.io", Type: "accept"},
+				{Scope: "quay.io", Type: "reject"},
+			}}},
+			wantErr: `container trust rule scope "quay.io" is used more than once`,
+		},
+		{
+			name: "key-for-accept",
+			containers: &ContainersCustomization{Policy: &ContainerPolicyCustomization{Default: "accept", Rules: []ContainerTrustRuleCustomization{
+				{Scope: "quay.io", Type: "accept", KeyPath: "/etc/pki/key.pub"},
+			}}},
+			wantErr: `container trust rule for "quay.io" of type "accept" does not take a key`,
+		},
+		{
+			name: "no-key-for-sigstore",
+			containers: &ContainersCustomization{Policy: &ContainerPolicyCustomization{Default: "accept", Rules: []ContainerTrustRuleCustomization{
+				{Scope: "quay.io", Type: "sigstore"},
+			}}},
+			wantErr: `container trust rule for "quay.io" of type "sigstore" requires an absolute key path`,
+		},
+		{
+			name: "invalid-type",
+			containers: &ContainersCustomization{Policy: &ContainerPolicyCustomization{Default: "accept", Rules: []ContainerTrustRuleCustomization{
+				{Scope: "quay.io", Type: "trust"},
+			}}},
+			wantErr: `invalid type "trust" of container trust rule for "quay.io": must be one of accept, reject, gpg, sigstore`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{Containers: tt.containers}
+			containers, err := c.GetContainers()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, containers)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.containers, containers)
+			}
+		})
+	}
+}
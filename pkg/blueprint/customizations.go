@@ -7,23 +7,34 @@ import (
 )
 
 type Customizations struct {
-	Hostname           *string                   `json:"hostname,omitempty" toml:"hostname,omitempty"`
-	Kernel             *KernelCustomization      `json:"kernel,omitempty" toml:"kernel,omitempty"`
-	SSHKey             []SSHKeyCustomization     `json:"sshkey,omitempty" toml:"sshkey,omitempty"`
-	User               []UserCustomization       `json:"user,omitempty" toml:"user,omitempty"`
-	Group              []GroupCustomization      `json:"group,omitempty" toml:"group,omitempty"`
-	Timezone           *TimezoneCustomization    `json:"timezone,omitempty" toml:"timezone,omitempty"`
-	Locale             *LocaleCustomization      `json:"locale,omitempty" toml:"locale,omitempty"`
-	Firewall           *FirewallCustomization    `json:"firewall,omitempty" toml:"firewall,omitempty"`
-	Services           *ServicesCustomization    `json:"services,omitempty" toml:"services,omitempty"`
-	Filesystem         []FilesystemCustomization `json:"filesystem,omitempty" toml:"filesystem,omitempty"`
-	InstallationDevice string                    `json:"installation_device,omitempty" toml:"installation_device,omitempty"`
-	FDO                *FDOCustomization         `json:"fdo,omitempty" toml:"fdo,omitempty"`
-	OpenSCAP           *OpenSCAPCustomization    `json:"openscap,omitempty" toml:"openscap,omitempty"`
-	Ignition           *IgnitionCustomization    `json:"ignition,omitempty" toml:"ignition,omitempty"`
-	Directories        []DirectoryCustomization  `json:"directories,omitempty" toml:"directories,omitempty"`
-	Files              []FileCustomization       `json:"files,omitempty" toml:"files,omitempty"`
-	Repositories       []RepositoryCustomization `json:"repositories,omitempty" toml:"repositories,omitempty"`
+	Hostname           *string                     `json:"hostname,omitempty" toml:"hostname,omitempty"`
+	Kernel             *KernelCustomization        `json:"kernel,omitempty" toml:"kernel,omitempty"`
+	SSHKey             []SSHKeyCustomization       `json:"sshkey,omitempty" toml:"sshkey,omitempty"`
+	User               []UserCustomization         `json:"user,omitempty" toml:"user,omitempty"`
+	Group              []GroupCustomization        `json:"group,omitempty" toml:"group,omitempty"`
+	Timezone           *TimezoneCustomization      `json:"timezone,omitempty" toml:"timezone,omitempty"`
+	Locale             *LocaleCustomization        `json:"locale,omitempty" toml:"locale,omitempty"`
+	Firewall           *FirewallCustomization      `json:"firewall,omitempty" toml:"firewall,omitempty"`
+	Services           *ServicesCustomization      `json:"services,omitempty" toml:"services,omitempty"`
+	Filesystem         []FilesystemCustomization   `json:"filesystem,omitempty" toml:"filesystem,omitempty"`
+	InstallationDevice string                      `json:"installation_device,omitempty" toml:"installation_device,omitempty"`
+	FDO                *FDOCustomization           `json:"fdo,omitempty" toml:"fdo,omitempty"`
+	OpenSCAP           *OpenSCAPCustomization      `json:"openscap,omitempty" toml:"openscap,omitempty"`
+	Ignition           *IgnitionCustomization      `json:"ignition,omitempty" toml:"ignition,omitempty"`
+	Directories        []DirectoryCustomization    `json:"directories,omitempty" toml:"directories,omitempty"`
+	Files              []FileCustomization         `json:"files,omitempty" toml:"files,omitempty"`
+	Repositories       []RepositoryCustomization   `json:"repositories,omitempty" toml:"repositories,omitempty"`
+	Installer          *InstallerCustomization     `json:"installer,omitempty" toml:"installer,omitempty"`
+	FirstBoot          *FirstBootCustomization     `json:"firstboot,omitempty" toml:"firstboot,omitempty"`
+	FIPS               *bool                       `json:"fips,omitempty" toml:"fips,omitempty"`
+	Sysctl             []SysctlCustomization       `json:"sysctl,omitempty" toml:"sysctl,omitempty"`
+	KernelModules      *KernelModulesCustomization `json:"kernel_modules,omitempty" toml:"kernel_modules,omitempty"`
+	Tuned              *TunedCustomization         `json:"tuned,omitempty" toml:"tuned,omitempty"`
+	Journald           *JournaldCustomization      `json:"journald,omitempty" toml:"journald,omitempty"`
+	Logind             *LogindCustomization        `json:"logind,omitempty" toml:"logind,omitempty"`
+	Dracut             *DracutCustomization        `json:"dracut,omitempty" toml:"dracut,omitempty"`
+	SELinux            *SELinuxCustomization       `json:"selinux,omitempty" toml:"selinux,omitempty"`
+	Containers         *ContainersCustomization    `json:"containers,omitempty" toml:"containers,omitempty"`
 }
 
 type IgnitionCustomization struct {
@@ -107,14 +118,37 @@ type ServicesCustomization struct {
 }
 
 type OpenSCAPCustomization struct {
-	DataStream string                           `json:"datastream,omitempty" toml:"datastream,omitempty"`
-	ProfileID  string                           `json:"profile_id,omitempty" toml:"profile_id,omitempty"`
-	Tailoring  *OpenSCAPTailoringCustomizations `json:"tailoring,omitempty" toml:"tailoring,omitempty"`
+	DataStream    string                              `json:"datastream,omitempty" toml:"datastream,omitempty"`
+	ProfileID     string                              `json:"profile_id,omitempty" toml:"profile_id,omitempty"`
+	Tailoring     *OpenSCAPTailoringCustomizations    `json:"tailoring,omitempty" toml:"tailoring,omitempty"`
+	TailoringFile *OpenSCAPTailoringFileCustomization `json:"tailoring_file,omitempty" toml:"tailoring_file,omitempty"`
 }
 
 type OpenSCAPTailoringCustomizations struct {
-	Selected   []string `json:"selected,omitempty" toml:"selected,omitempty"`
-	Unselected []string `json:"unselected,omitempty" toml:"unselected,omitempty"`
+	Selected   []string                    `json:"selected,omitempty" toml:"selected,omitempty"`
+	Unselected []string                    `json:"unselected,omitempty" toml:"unselected,omitempty"`
+	Overrides  []OpenSCAPTailoringOverride `json:"overrides,omitempty" toml:"overrides,omitempty"`
+}
+
+// OpenSCAPTailoringOverride refines the value of an XCCDF variable
+type OpenSCAPTailoringOverride struct {
+	Var   string `json:"var" toml:"var"`
+	Value string `json:"value" toml:"value"`
+}
+
+// OpenSCAPTailoringFileCustomization is a complete tailoring file, either an
+// XCCDF tailoring document or a JSON tailoring, provided inline or downloaded
+// from a URL at build time.
+type OpenSCAPTailoringFileCustomization struct {
+	// Format of the file, either "xccdf" (default) or "json"
+	Format string `json:"format,omitempty" toml:"format,omitempty"`
+	// ID of the tailored profile defined in the file
+	ProfileID string `json:"profile_id" toml:"profile_id"`
+	// Inline content of the file
+	Data string `json:"data,omitempty" toml:"data,omitempty"`
+	// URL to download the file from, requires Checksum
+	URL      string `json:"url,omitempty" toml:"url,omitempty"`
+	Checksum string `json:"checksum,omitempty" toml:"checksum,omitempty"`
 }
 
 type CustomizationError struct {
@@ -261,6 +295,14 @@ func (c *Customizations) GetKernel() *KernelCustomization {
 	}
 }
 
+// GetFIPS returns true if the image should be set up for FIPS mode.
+func (c *Customizations) GetFIPS() bool {
+	if c == nil || c.FIPS == nil {
+		return false
+	}
+	return *c.FIPS
+}
+
 func (c *Customizations) GetFirewall() *FirewallCustomization {
 	if c == nil {
 		return nil
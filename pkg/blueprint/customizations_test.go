@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/internal/common"
 )
 
 func TestCheckAllowed(t *testing.T) {
@@ -77,6 +79,14 @@ func TestGetKernel(t *testing.T) {
 	assert.Equal(t, &expectedKernel, retKernel)
 }
 
+func TestGetFIPS(t *testing.T) {
+	var nilCustomizations *Customizations
+	assert.False(t, nilCustomizations.GetFIPS())
+	assert.False(t, (&Customizations{}).GetFIPS())
+	assert.False(t, (&Customizations{FIPS: common.ToPtr(false)}).GetFIPS())
+	assert.True(t, (&Customizations{FIPS: common.ToPtr(true)}).GetFIPS())
+}
+
 func TestSSHKey(t *testing.T) {
 
 	expectedSSHKeys := []SSHKeyCustomization{
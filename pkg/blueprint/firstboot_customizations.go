@@ -0,0 +1,116 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/images/internal/pathpolicy"
+)
+
+// FirstBootCustomization defines scripts that are run once, in order, on the
+// first boot of the image.
+type FirstBootCustomization struct {
+	Scripts []FirstBootScriptCustomization `json:"scripts,omitempty" toml:"scripts,omitempty"`
+
+	// Wait for the network to be online before running the scripts
+	WaitForNetwork bool `json:"wait_for_network,omitempty" toml:"wait_for_network,omitempty"`
+
+	// Number of times a failing script is retried before giving up, and the
+	// delay between the attempts in seconds
+	Retries    int `json:"retries,omitempty" toml:"retries,omitempty"`
+	RetryDelay int `json:"retry_delay,omitempty" toml:"retry_delay,omitempty"`
+}
+
+// FirstBootScriptCustomization is a single first boot script, given either
+// as a command line or as the complete contents of a script.
+type FirstBootScriptCustomization struct {
+	Name string `json:"name" toml:"name"`
+
+	// Path to install the script to, defaults to a path derived from the
+	// name in a directory reserved for first boot scripts
+	Path string `json:"path,omitempty" toml:"path,omitempty"`
+
+	Command  string `json:"command,omitempty" toml:"command,omitempty"`
+	Contents string `json:"contents,omitempty" toml:"contents,omitempty"`
+}
+
+var firstBootScriptNameRE = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+func (c *Customizations) GetFirstBoot() *FirstBootCustomization {
+	if c == nil {
+		return nil
+	}
+	return c.FirstBoot
+}
+
+// ValidateFirstBootCustomization checks that the scripts have valid and
+// unique names and paths and that each of them specifies exactly one of a
+// command or contents.
+func ValidateFirstBootCustomization(fb *FirstBootCustomization) error {
+	if fb == nil {
+		return nil
+	}
+
+	if len(fb.Scripts) == 0 {
+		return fmt.Errorf("first boot customization requires at least one script")
+	}
+	if fb.Retries < 0 {
+		return fmt.Errorf("first boot retries must not be negative")
+	}
+	if fb.RetryDelay < 0 {
+		return fmt.Errorf("first boot retry delay must not be negative")
+	}
+
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for _, script := range fb.Scripts {
+		if !firstBootScriptNameRE.MatchString(script.Name) {
+			return fmt.Errorf("invalid first boot script name %q", script.Name)
+		}
+		if names[script.Name] {
+			return fmt.Errorf("duplicate first boot script name %q", script.Name)
+		}
+		names[script.Name] = true
+
+		if script.Path != "" {
+			if paths[script.Path] {
+				return fmt.Errorf("duplicate first boot script path %q", script.Path)
+			}
+			paths[script.Path] = true
+		}
+
+		if (script.Command == "") == (script.Contents == "") {
+			return fmt.Errorf("first boot script %q must specify exactly one of command or contents", script.Name)
+		}
+		if script.Contents != "" && !strings.HasPrefix(script.Contents, "#!") {
+			return fmt.Errorf("contents of first boot script %q must start with an interpreter line (#!)", script.Name)
+		}
+	}
+
+	return nil
+}
+
+// CheckFirstBootCustomizationPolicy checks if the paths of the first boot
+// scripts are allowed by the path policy.
+func CheckFirstBootCustomizationPolicy(fb *FirstBootCustomization, pathPolicy *pathpolicy.PathPolicies) error {
+	if fb == nil {
+		return nil
+	}
+
+	var invalidPaths []string
+	for _, script := range fb.Scripts {
+		if script.Path == "" {
+			continue
+		}
+		if err := pathPolicy.Check(script.Path); err != nil {
+			invalidPaths = append(invalidPaths, script.Path)
+		}
+	}
+
+	if len(invalidPaths) > 0 {
+		return fmt.Errorf("the following first boot script paths are not allowed: %+q", invalidPaths)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/osbuild/images/internal/pathpolicy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFirstBootCustomization(t *testing.T) {
+	tests := []struct {
+		name      string
+		firstBoot *FirstBootCustomization
+		wantErr   string
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "valid",
+			firstBoot: &FirstBootCustomization{
+				Scripts: []FirstBootScriptCustomization{
+					{Name: "register", Command: "/usr/bin/register --now"},
+					{Name: "setup.sh", Path: "/etc/myapp/setup.sh", Contents: "#!/bin/bash\necho setup\n"},
+				},
+				Retries:    3,
+				RetryDelay: 30,
+			},
+		},
+		{
+			name:      "no-scripts",
+			firstBoot: &FirstBootCustomization{},
+			wantErr:   "first boot customization requires at least one script",
+		},
+		{
+			name: "negative-retries",
+			firstBoot: &FirstBootCustomization{
+				Scripts: []FirstBootScriptCustomization{{Name: "a", Command: "true"}},
+				Retries: -1,
+			},
+			wantErr: "first boot retries must not be negative",
+		},
+		{
+			name: "invalid-name",
+			firstBoot: &FirstBootCustomization{
+				Scripts: []FirstBootScriptCustomization{{Name: "../a", Command: "true"}},
+			},
+			wantErr: `invalid first boot script name "../a"`,
+		},
+		{
+			name: "duplicate-name",
+			firstBoot: &FirstBootCustomization{
+				Scripts: []FirstBootScriptCustomization{{Name: "a", Command: "true"}, {Name: "a", Command: "false"}},
+			},
+			wantErr: `duplicate first boot script name "a"`,
+		},
+		{
+			name: "duplicate-path",
+			firstBoot: &FirstBootCustomization{
+				Scripts: []FirstBootScriptCustomization{
+					{Name: "a", Path: "/etc/a", Command: "true"},
+					{Name: "b", Path: "/etc/a", Command: "false"},
+				},
+			},
+			wantErr: `duplicate first boot script path "/etc/a"`,
+		},
+		{
+			name: "command-and-contents",
+			firstBoot: &FirstBootCustomization{
+				Scripts: []FirstBootScriptCustomization{{Name: "a", Command: "true", Contents: "#!/bin/sh\n"}},
+			},
+			wantErr: `first boot script "a" must specify exactly one of command or contents`,
+		},
+		{
+			name: "no-interpreter",
+			firstBoot: &FirstBootCustomization{
+				Scripts: []FirstBootScriptCustomization{{Name: "a", Contents: "echo a\n"}},
+			},
+			wantErr: `contents of first boot script "a" must start with an interpreter line (#!)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFirstBootCustomization(tt.firstBoot)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckFirstBootCustomizationPolicy(t *testing.T) {
+	firstBoot := &FirstBootCustomization{
+		Scripts: []FirstBootScriptCustomization{
+			{Name: "default", Command: "true"},
+			{Name: "etc", Path: "/etc/myapp/setup", Command: "true"},
+			{Name: "usr", Path: "/usr/bin/setup", Command: "true"},
+			{Name: "unit", Path: "/etc/systemd/system/setup", Command: "true"},
+		},
+	}
+	err := CheckFirstBootCustomizationPolicy(firstBoot, pathpolicy.FirstBootScriptsPolicies)
+	assert.EqualError(t, err, `the following first boot script paths are not allowed: ["/usr/bin/setup" "/etc/systemd/system/setup"]`)
+
+	firstBoot.Scripts = firstBoot.Scripts[:2]
+	assert.NoError(t, CheckFirstBootCustomizationPolicy(firstBoot, pathpolicy.FirstBootScriptsPolicies))
+	assert.NoError(t, CheckFirstBootCustomizationPolicy(nil, pathpolicy.FirstBootScriptsPolicies))
+}
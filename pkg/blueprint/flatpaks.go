@@ -0,0 +1,77 @@
+package blueprint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// A Flatpak specifies a flatpak application or runtime to preinstall in the
+// system installation of the image. Runtimes required by an application are
+// not resolved and must be listed explicitly.
+type Flatpak struct {
+	// Name of the remote the flatpak is installed from, the remote is
+	// configured in the image
+	Remote string `json:"remote" toml:"remote"`
+
+	// URL of the flatpak repository of the remote
+	URL string `json:"url" toml:"url"`
+
+	// ASCII-armored public GPG key of the remote (optional)
+	GPGKey string `json:"gpgkey,omitempty" toml:"gpgkey,omitempty"`
+
+	// Kind and ID of the flatpak, e.g. "app/org.gnome.Calculator" or
+	// "runtime/org.gnome.Platform"
+	Ref string `json:"ref" toml:"ref"`
+
+	// Branch of the flatpak, defaults to "stable"
+	Branch string `json:"branch,omitempty" toml:"branch,omitempty"`
+}
+
+var (
+	flatpakRemoteRE = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+	flatpakRefRE    = regexp.MustCompile(`^(app|runtime)/[a-zA-Z_][a-zA-Z0-9_-]*(\.[a-zA-Z_][a-zA-Z0-9_-]*)+$`)
+	flatpakBranchRE = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+// ValidateFlatpaks checks that the refs, branches and remotes of the
+// flatpaks are valid and that remotes with the same name are defined
+// consistently.
+func ValidateFlatpaks(flatpaks []Flatpak) error {
+	remotes := make(map[string]Flatpak)
+	refs := make(map[string]bool)
+	for _, fp := range flatpaks {
+		if !flatpakRemoteRE.MatchString(fp.Remote) {
+			return fmt.Errorf("invalid flatpak remote name %q", fp.Remote)
+		}
+		if _, err := url.ParseRequestURI(fp.URL); err != nil {
+			return fmt.Errorf("flatpak remote %q has an invalid URL %q", fp.Remote, fp.URL)
+		}
+		if remote, ok := remotes[fp.Remote]; ok && (remote.URL != fp.URL || remote.GPGKey != fp.GPGKey) {
+			return fmt.Errorf("flatpak remote %q is defined more than once with different URLs or GPG keys", fp.Remote)
+		}
+		remotes[fp.Remote] = fp
+
+		if !flatpakRefRE.MatchString(fp.Ref) {
+			return fmt.Errorf("invalid flatpak ref %q: must be of the form app/<id> or runtime/<id>", fp.Ref)
+		}
+		if fp.Branch != "" && !flatpakBranchRE.MatchString(fp.Branch) {
+			return fmt.Errorf("invalid branch %q for flatpak %q", fp.Branch, fp.Ref)
+		}
+		key := fp.Ref + "/" + fp.GetBranch()
+		if refs[key] {
+			return fmt.Errorf("duplicate flatpak %q", key)
+		}
+		refs[key] = true
+	}
+	return nil
+}
+
+// GetBranch returns the branch of the flatpak or the default branch if none
+// is set.
+func (f Flatpak) GetBranch() string {
+	if f.Branch == "" {
+		return "stable"
+	}
+	return f.Branch
+}
@@ -0,0 +1,87 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFlatpaks(t *testing.T) {
+	calculator := Flatpak{
+		Remote: "flathub",
+		URL:    "https://dl.flathub.org/repo/",
+		Ref:    "app/org.gnome.Calculator",
+	}
+	platform := Flatpak{
+		Remote: "flathub",
+		URL:    "https://dl.flathub.org/repo/",
+		Ref:    "runtime/org.gnome.Platform",
+		Branch: "45",
+	}
+
+	tests := []struct {
+		name     string
+		flatpaks []Flatpak
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			flatpaks: []Flatpak{calculator, platform},
+		},
+		{
+			name: "invalid-remote",
+			flatpaks: []Flatpak{
+				{Remote: "flat hub", URL: calculator.URL, Ref: calculator.Ref},
+			},
+			wantErr: `invalid flatpak remote name "flat hub"`,
+		},
+		{
+			name: "invalid-url",
+			flatpaks: []Flatpak{
+				{Remote: "flathub", URL: "dl.flathub.org", Ref: calculator.Ref},
+			},
+			wantErr: `flatpak remote "flathub" has an invalid URL "dl.flathub.org"`,
+		},
+		{
+			name: "conflicting-remote",
+			flatpaks: []Flatpak{
+				calculator,
+				{Remote: "flathub", URL: "https://example.com/repo/", Ref: platform.Ref},
+			},
+			wantErr: `flatpak remote "flathub" is defined more than once with different URLs or GPG keys`,
+		},
+		{
+			name: "invalid-ref",
+			flatpaks: []Flatpak{
+				{Remote: "flathub", URL: calculator.URL, Ref: "app/org.gnome.Calculator/x86_64/stable"},
+			},
+			wantErr: `invalid flatpak ref "app/org.gnome.Calculator/x86_64/stable": must be of the form app/<id> or runtime/<id>`,
+		},
+		{
+			name: "invalid-branch",
+			flatpaks: []Flatpak{
+				{Remote: "flathub", URL: calculator.URL, Ref: calculator.Ref, Branch: "a/b"},
+			},
+			wantErr: `invalid branch "a/b" for flatpak "app/org.gnome.Calculator"`,
+		},
+		{
+			name: "duplicate",
+			flatpaks: []Flatpak{
+				calculator,
+				{Remote: "flathub", URL: calculator.URL, Ref: calculator.Ref, Branch: "stable"},
+			},
+			wantErr: `duplicate flatpak "app/org.gnome.Calculator/stable"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFlatpaks(tt.flatpaks)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package blueprint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// anacondaModulePrefix is the D-Bus name prefix shared by all anaconda
+// modules
+const anacondaModulePrefix = "org.fedoraproject.Anaconda.Modules."
+
+// InstallerCustomization configures the installer environment of installer
+// image types, not the installed system.
+type InstallerCustomization struct {
+	// Kernel command line arguments appended when booting the installer
+	KernelAppend string `json:"kernel_append,omitempty" toml:"kernel_append,omitempty"`
+
+	// Extra drivers and dracut modules to include in the installer initramfs
+	Drivers       []string `json:"drivers,omitempty" toml:"drivers,omitempty"`
+	DracutModules []string `json:"dracut_modules,omitempty" toml:"dracut_modules,omitempty"`
+
+	Modules *AnacondaModulesCustomization `json:"modules,omitempty" toml:"modules,omitempty"`
+
+	// Anaconda screens (spokes) to hide from the user, e.g. "UserSpoke" or
+	// "NetworkSpoke". The values from the interactive defaults of the
+	// installer are used for the hidden screens and can't be changed.
+	LockedScreens []string `json:"locked_screens,omitempty" toml:"locked_screens,omitempty"`
+}
+
+// AnacondaModulesCustomization enables or disables anaconda modules, which
+// are referred to by their full D-Bus name, e.g.
+// "org.fedoraproject.Anaconda.Modules.Users".
+type AnacondaModulesCustomization struct {
+	Enable  []string `json:"enable,omitempty" toml:"enable,omitempty"`
+	Disable []string `json:"disable,omitempty" toml:"disable,omitempty"`
+}
+
+// GetInstaller returns the installer customization after validating the
+// anaconda module names.
+func (c *Customizations) GetInstaller() (*InstallerCustomization, error) {
+	if c == nil || c.Installer == nil {
+		return nil, nil
+	}
+
+	if modules := c.Installer.Modules; modules != nil {
+		enabled := make(map[string]bool)
+		for _, module := range modules.Enable {
+			if !strings.HasPrefix(module, anacondaModulePrefix) {
+				return nil, fmt.Errorf("invalid anaconda module %q: must start with %q", module, anacondaModulePrefix)
+			}
+			enabled[module] = true
+		}
+		for _, module := range modules.Disable {
+			if !strings.HasPrefix(module, anacondaModulePrefix) {
+				return nil, fmt.Errorf("invalid anaconda module %q: must start with %q", module, anacondaModulePrefix)
+			}
+			if enabled[module] {
+				return nil, fmt.Errorf("anaconda module %q cannot be both enabled and disabled", module)
+			}
+		}
+	}
+
+	return c.Installer, nil
+}
+
+// GetKernelOptions returns the installer kernel arguments as a list
+func (i *InstallerCustomization) GetKernelOptions() []string {
+	if i == nil {
+		return nil
+	}
+	return strings.Fields(i.KernelAppend)
+}
+
+// GetEnabledModules returns the anaconda modules to enable
+func (i *InstallerCustomization) GetEnabledModules() []string {
+	if i == nil || i.Modules == nil {
+		return nil
+	}
+	return i.Modules.Enable
+}
+
+// GetDisabledModules returns the anaconda modules to disable
+func (i *InstallerCustomization) GetDisabledModules() []string {
+	if i == nil || i.Modules == nil {
+		return nil
+	}
+	return i.Modules.Disable
+}
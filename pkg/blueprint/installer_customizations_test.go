@@ -0,0 +1,87 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInstaller(t *testing.T) {
+	tests := []struct {
+		name      string
+		installer *InstallerCustomization
+		wantErr   string
+	}{
+		{
+			name: "valid",
+			installer: &InstallerCustomization{
+				KernelAppend: "console=ttyS0 inst.text",
+				Modules: &AnacondaModulesCustomization{
+					Enable:  []string{"org.fedoraproject.Anaconda.Modules.Localization"},
+					Disable: []string{"org.fedoraproject.Anaconda.Modules.Users"},
+				},
+				LockedScreens: []string{"UserSpoke"},
+			},
+		},
+		{
+			name: "invalid-enabled",
+			installer: &InstallerCustomization{
+				Modules: &AnacondaModulesCustomization{
+					Enable: []string{"Localization"},
+				},
+			},
+			wantErr: `invalid anaconda module "Localization": must start with "org.fedoraproject.Anaconda.Modules."`,
+		},
+		{
+			name: "invalid-disabled",
+			installer: &InstallerCustomization{
+				Modules: &AnacondaModulesCustomization{
+					Disable: []string{"Users"},
+				},
+			},
+			wantErr: `invalid anaconda module "Users": must start with "org.fedoraproject.Anaconda.Modules."`,
+		},
+		{
+			name: "conflict",
+			installer: &InstallerCustomization{
+				Modules: &AnacondaModulesCustomization{
+					Enable:  []string{"org.fedoraproject.Anaconda.Modules.Users"},
+					Disable: []string{"org.fedoraproject.Anaconda.Modules.Users"},
+				},
+			},
+			wantErr: `anaconda module "org.fedoraproject.Anaconda.Modules.Users" cannot be both enabled and disabled`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{Installer: tt.installer}
+			installer, err := c.GetInstaller()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, installer)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.installer, installer)
+		})
+	}
+}
+
+func TestInstallerCustomizationGetters(t *testing.T) {
+	var empty *InstallerCustomization
+	assert.Nil(t, empty.GetKernelOptions())
+	assert.Nil(t, empty.GetEnabledModules())
+	assert.Nil(t, empty.GetDisabledModules())
+
+	installer := &InstallerCustomization{
+		KernelAppend: " console=ttyS0  inst.text ",
+		Modules: &AnacondaModulesCustomization{
+			Enable:  []string{"org.fedoraproject.Anaconda.Modules.Localization"},
+			Disable: []string{"org.fedoraproject.Anaconda.Modules.Users"},
+		},
+	}
+	assert.Equal(t, []string{"console=ttyS0", "inst.text"}, installer.GetKernelOptions())
+	assert.Equal(t, []string{"org.fedoraproject.Anaconda.Modules.Localization"}, installer.GetEnabledModules())
+	assert.Equal(t, []string{"org.fedoraproject.Anaconda.Modules.Users"}, installer.GetDisabledModules())
+}
@@ -0,0 +1,72 @@
+package blueprint
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/osbuild/images/internal/oscap"
+)
+
+var tailoringFileChecksumRegex = regexp.MustCompile(`^(sha256|sha384|sha512):([0-9a-f]+)$`)
+
+// tailoringFileChecksumLength is the length of the hex digest of every
+// supported checksum algorithm
+var tailoringFileChecksumLength = map[string]int{
+	"sha256": 64,
+	"sha384": 96,
+	"sha512": 128,
+}
+
+// ValidateOpenSCAPCustomization checks that the tailoring options of an
+// OpenSCAP customization are consistent. It does not check the profile
+// against the allowed profiles of a distribution.
+func ValidateOpenSCAPCustomization(c *OpenSCAPCustomization) error {
+	if c == nil {
+		return nil
+	}
+
+	if c.Tailoring != nil {
+		if c.TailoringFile != nil {
+			return fmt.Errorf("OpenSCAP tailoring and tailoring file cannot be used together")
+		}
+		for _, override := range c.Tailoring.Overrides {
+			if override.Var == "" {
+				return fmt.Errorf("OpenSCAP tailoring override requires a variable name")
+			}
+		}
+	}
+
+	tf := c.TailoringFile
+	if tf == nil {
+		return nil
+	}
+
+	switch oscap.TailoringFormat(tf.Format) {
+	case "", oscap.TailoringFormatXCCDF, oscap.TailoringFormatJSON:
+	default:
+		return fmt.Errorf("OpenSCAP tailoring file format %q is not supported", tf.Format)
+	}
+
+	if tf.ProfileID == "" {
+		return fmt.Errorf("OpenSCAP tailoring file requires the ID of the tailored profile")
+	}
+
+	if (tf.Data == "") == (tf.URL == "") {
+		return fmt.Errorf("OpenSCAP tailoring file requires exactly one of data or url")
+	}
+
+	if tf.URL != "" {
+		if _, err := url.ParseRequestURI(tf.URL); err != nil {
+			return fmt.Errorf("OpenSCAP tailoring file url is invalid: %v", err)
+		}
+		match := tailoringFileChecksumRegex.FindStringSubmatch(tf.Checksum)
+		if match == nil || len(match[2]) != tailoringFileChecksumLength[match[1]] {
+			return fmt.Errorf("OpenSCAP tailoring file url requires a checksum in the form <algorithm>:<hexdigest>, got %q", tf.Checksum)
+		}
+	} else if tf.Checksum != "" {
+		return fmt.Errorf("OpenSCAP tailoring file checksum can only be set with a url")
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package blueprint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOpenSCAPCustomization(t *testing.T) {
+	checksum := "sha256:4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3"
+
+	tests := []struct {
+		name  string
+		oscap *OpenSCAPCustomization
+		err   string
+	}{
+		{
+			name:  "nil",
+			oscap: nil,
+		},
+		{
+			name: "no-tailoring",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+			},
+		},
+		{
+			name: "overrides",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				Tailoring: &OpenSCAPTailoringCustomizations{
+					Overrides: []OpenSCAPTailoringOverride{{Var: "var_password_pam_minlen", Value: "16"}},
+				},
+			},
+		},
+		{
+			name: "override-without-var",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				Tailoring: &OpenSCAPTailoringCustomizations{
+					Overrides: []OpenSCAPTailoringOverride{{Value: "16"}},
+				},
+			},
+			err: "OpenSCAP tailoring override requires a variable name",
+		},
+		{
+			name: "tailoring-and-file",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				Tailoring: &OpenSCAPTailoringCustomizations{
+					Selected: []string{"quick_rule"},
+				},
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					Data:      "<xml/>",
+				},
+			},
+			err: "OpenSCAP tailoring and tailoring file cannot be used together",
+		},
+		{
+			name: "inline-xccdf",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					Data:      "<xml/>",
+				},
+			},
+		},
+		{
+			name: "json-url",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					Format:    "json",
+					ProfileID: "cis_tailored",
+					URL:       "https://example.com/tailoring.json",
+					Checksum:  checksum,
+				},
+			},
+		},
+		{
+			name: "bad-format",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					Format:    "yaml",
+					ProfileID: "cis_tailored",
+					Data:      "{}",
+				},
+			},
+			err: `OpenSCAP tailoring file format "yaml" is not supported`,
+		},
+		{
+			name: "no-profile",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					Data: "<xml/>",
+				},
+			},
+			err: "OpenSCAP tailoring file requires the ID of the tailored profile",
+		},
+		{
+			name: "data-and-url",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					Data:      "<xml/>",
+					URL:       "https://example.com/tailoring.xml",
+					Checksum:  checksum,
+				},
+			},
+			err: "OpenSCAP tailoring file requires exactly one of data or url",
+		},
+		{
+			name: "neither-data-nor-url",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+				},
+			},
+			err: "OpenSCAP tailoring file requires exactly one of data or url",
+		},
+		{
+			name: "url-without-checksum",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					URL:       "https://example.com/tailoring.xml",
+				},
+			},
+			err: `OpenSCAP tailoring file url requires a checksum in the form <algorithm>:<hexdigest>, got ""`,
+		},
+		{
+			name: "sha512-url",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					URL:       "https://example.com/tailoring.xml",
+					Checksum:  "sha512:" + strings.Repeat("a", 128),
+				},
+			},
+		},
+		{
+			name: "checksum-length-of-other-algorithm",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					URL:       "https://example.com/tailoring.xml",
+					Checksum:  "sha256:" + strings.Repeat("a", 128),
+				},
+			},
+			err: `OpenSCAP tailoring file url requires a checksum in the form <algorithm>:<hexdigest>, got "sha256:` + strings.Repeat("a", 128) + `"`,
+		},
+		{
+			name: "checksum-too-short",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					URL:       "https://example.com/tailoring.xml",
+					Checksum:  "sha384:" + strings.Repeat("a", 64),
+				},
+			},
+			err: `OpenSCAP tailoring file url requires a checksum in the form <algorithm>:<hexdigest>, got "sha384:` + strings.Repeat("a", 64) + `"`,
+		},
+		{
+			name: "checksum-without-url",
+			oscap: &OpenSCAPCustomization{
+				ProfileID: "xccdf_org.ssgproject.content_profile_cis",
+				TailoringFile: &OpenSCAPTailoringFileCustomization{
+					ProfileID: "cis_tailored",
+					Data:      "<xml/>",
+					Checksum:  checksum,
+				},
+			},
+			err: "OpenSCAP tailoring file checksum can only be set with a url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOpenSCAPCustomization(tt.oscap)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package blueprint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// validSELinuxModes holds the SELinux modes that can be selected for the
+// installed system
+var validSELinuxModes = []string{"enforcing", "permissive"}
+
+// selinuxNameRegex matches SELinux boolean and type names
+var selinuxNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// validSELinuxModuleExts holds the supported file name extensions of
+// SELinux policy modules
+var validSELinuxModuleExts = []string{".pp", ".pp.bz2", ".cil"}
+
+// SELinuxCustomization configures the SELinux policy of the image. All
+// changes are applied before the image is labelled.
+type SELinuxCustomization struct {
+	// SELinux mode of the installed system: enforcing or permissive
+	Mode string `json:"mode,omitempty" toml:"mode,omitempty"`
+
+	// SELinux booleans to set, e.g. "httpd_can_network_connect"
+	Booleans map[string]bool `json:"booleans,omitempty" toml:"booleans,omitempty"`
+
+	// Additional file context rules
+	FileContexts []SELinuxFileContextCustomization `json:"fcontexts,omitempty" toml:"fcontexts,omitempty"`
+
+	// Paths of policy modules in the image to install. The modules need to
+	// be added to the image, e.g. with a package or a file customization.
+	Modules []string `json:"modules,omitempty" toml:"modules,omitempty"`
+}
+
+// SELinuxFileContextCustomization labels the paths matching the Path regular
+// expression with the SELinux Type.
+type SELinuxFileContextCustomization struct {
+	Path string `json:"path" toml:"path"`
+	Type string `json:"type" toml:"type"`
+}
+
+// GetSELinux returns the SELinux customization after validating it.
+func (c *Customizations) GetSELinux() (*SELinuxCustomization, error) {
+	if c == nil || c.SELinux == nil {
+		return nil, nil
+	}
+
+	s := c.SELinux
+	if s.Mode != "" && !slices.Contains(validSELinuxModes, s.Mode) {
+		return nil, fmt.Errorf("invalid SELinux mode %q: must be one of %s", s.Mode, strings.Join(validSELinuxModes, ", "))
+	}
+	for name := range s.Booleans {
+		if !selinuxNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid SELinux boolean name %q", name)
+		}
+	}
+	for _, fc := range s.FileContexts {
+		if !strings.HasPrefix(fc.Path, "/") {
+			return nil, fmt.Errorf("invalid SELinux file context path %q: must be absolute", fc.Path)
+		}
+		if _, err := regexp.Compile(fc.Path); err != nil {
+			return nil, fmt.Errorf("invalid SELinux file context path %q: %v", fc.Path, err)
+		}
+		if !selinuxNameRegex.MatchString(fc.Type) {
+			return nil, fmt.Errorf("invalid SELinux type %q for file context path %q", fc.Type, fc.Path)
+		}
+	}
+	for _, module := range s.Modules {
+		if !filepath.IsAbs(module) || filepath.Clean(module) != module {
+			return nil, fmt.Errorf("invalid SELinux module path %q: must be absolute and canonical", module)
+		}
+		if !slices.ContainsFunc(validSELinuxModuleExts, func(ext string) bool { return strings.HasSuffix(module, ext) }) {
+			return nil, fmt.Errorf("invalid SELinux module path %q: must end with one of %s", module, strings.Join(validSELinuxModuleExts, ", "))
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,87 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSELinux(t *testing.T) {
+	tests := []struct {
+		name    string
+		selinux *SELinuxCustomization
+		wantErr string
+	}{
+		{
+			name: "valid",
+			selinux: &SELinuxCustomization{
+				Mode:     "permissive",
+				Booleans: map[string]bool{"httpd_can_network_connect": true},
+				FileContexts: []SELinuxFileContextCustomization{
+					{Path: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+				},
+				Modules: []string{"/usr/share/selinux/packages/custom.pp", "/usr/share/selinux/packages/other.cil"},
+			},
+		},
+		{
+			name:    "invalid-mode",
+			selinux: &SELinuxCustomization{Mode: "disabled"},
+			wantErr: `invalid SELinux mode "disabled": must be one of enforcing, permissive`,
+		},
+		{
+			name:    "invalid-boolean",
+			selinux: &SELinuxCustomization{Booleans: map[string]bool{"httpd can": true}},
+			wantErr: `invalid SELinux boolean name "httpd can"`,
+		},
+		{
+			name: "relative-fcontext",
+			selinux: &SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{
+				{Path: "srv/www", Type: "httpd_sys_content_t"},
+			}},
+			wantErr: `invalid SELinux file context path "srv/www": must be absolute`,
+		},
+		{
+			name: "invalid-fcontext-regexp",
+			selinux: &SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{
+				{Path: "/srv/www(/.*?", Type: "httpd_sys_content_t"},
+			}},
+			wantErr: "invalid SELinux file context path \"/srv/www(/.*?\": error parsing regexp: missing closing ): `/srv/www(/.*?`",
+		},
+		{
+			name: "invalid-fcontext-type",
+			selinux: &SELinuxCustomization{FileContexts: []SELinuxFileContextCustomization{
+				{Path: "/srv/www", Type: "system_u:object_r:httpd_sys_content_t:s0"},
+			}},
+			wantErr: `invalid SELinux type "system_u:object_r:httpd_sys_content_t:s0" for file context path "/srv/www"`,
+		},
+		{
+			name:    "relative-module",
+			selinux: &SELinuxCustomization{Modules: []string{"custom.pp"}},
+			wantErr: `invalid SELinux module path "custom.pp": must be absolute and canonical`,
+		},
+		{
+			name:    "invalid-module-ext",
+			selinux: &SELinuxCustomization{Modules: []string{"/usr/share/selinux/packages/custom.te"}},
+			wantErr: `invalid SELinux module path "/usr/share/selinux/packages/custom.te": must end with one of .pp, .pp.bz2, .cil`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Customizations{SELinux: tt.selinux}
+			selinux, err := c.GetSELinux()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, selinux)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.selinux, selinux)
+			}
+		})
+	}
+
+	var c *Customizations
+	selinux, err := c.GetSELinux()
+	assert.NoError(t, err)
+	assert.Nil(t, selinux)
+}
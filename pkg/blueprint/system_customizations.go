@@ -0,0 +1,184 @@
+package blueprint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// validJournaldStorage holds the values accepted by the journald Storage
+// option
+var validJournaldStorage = []string{"volatile", "persistent", "auto", "none"}
+
+// kernelModuleNameRegex matches valid kernel module names
+var kernelModuleNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// SysctlCustomization sets a kernel parameter when the system boots.
+type SysctlCustomization struct {
+	Key   string `json:"key" toml:"key"`
+	Value string `json:"value" toml:"value"`
+}
+
+// KernelModulesCustomization configures the loading of kernel modules.
+type KernelModulesCustomization struct {
+	// Modules that are not loaded automatically
+	Blacklist []string `json:"blacklist,omitempty" toml:"blacklist,omitempty"`
+
+	// Parameters for modules, set whenever the module is loaded
+	Options []KernelModuleOptionsCustomization `json:"options,omitempty" toml:"options,omitempty"`
+}
+
+type KernelModuleOptionsCustomization struct {
+	Name    string `json:"name" toml:"name"`
+	Options string `json:"options" toml:"options"`
+}
+
+// TunedCustomization selects the TuneD profiles of the image.
+type TunedCustomization struct {
+	Profiles []string `json:"profiles" toml:"profiles"`
+}
+
+// JournaldCustomization configures the systemd journal.
+type JournaldCustomization struct {
+	// Where to store journal data: volatile, persistent, auto or none
+	Storage string `json:"storage,omitempty" toml:"storage,omitempty"`
+
+	// Maximum time to keep journal entries (e.g. "1month")
+	MaxRetentionSec string `json:"max_retention_sec,omitempty" toml:"max_retention_sec,omitempty"`
+
+	// Maximum time to store entries in a single journal file
+	MaxFileSec string `json:"max_file_sec,omitempty" toml:"max_file_sec,omitempty"`
+}
+
+// LogindCustomization configures systemd-logind.
+type LogindCustomization struct {
+	// Number of virtual terminals to allocate by default
+	NAutoVTs *int `json:"nautovts,omitempty" toml:"nautovts,omitempty"`
+}
+
+// DracutCustomization configures how the initramfs of the image is built.
+type DracutCustomization struct {
+	AddModules  []string `json:"add_modules,omitempty" toml:"add_modules,omitempty"`
+	OmitModules []string `json:"omit_modules,omitempty" toml:"omit_modules,omitempty"`
+	AddDrivers  []string `json:"add_drivers,omitempty" toml:"add_drivers,omitempty"`
+	Install     []string `json:"install_items,omitempty" toml:"install_items,omitempty"`
+}
+
+// GetSysctl returns the sysctl customizations after checking that every
+// entry has a key and a value.
+func (c *Customizations) GetSysctl() ([]SysctlCustomization, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	keys := make(map[string]bool)
+	for _, sysctl := range c.Sysctl {
+		if sysctl.Key == "" || strings.ContainsAny(sysctl.Key, " \t=") {
+			return nil, fmt.Errorf("invalid sysctl key %q", sysctl.Key)
+		}
+		if sysctl.Value == "" {
+			return nil, fmt.Errorf("sysctl key %q requires a value", sysctl.Key)
+		}
+		if keys[sysctl.Key] {
+			return nil, fmt.Errorf("sysctl key %q is set more than once", sysctl.Key)
+		}
+		keys[sysctl.Key] = true
+	}
+	return c.Sysctl, nil
+}
+
+// GetKernelModules returns the kernel modules customization after checking
+// the module names.
+func (c *Customizations) GetKernelModules() (*KernelModulesCustomization, error) {
+	if c == nil || c.KernelModules == nil {
+		return nil, nil
+	}
+
+	for _, name := range c.KernelModules.Blacklist {
+		if !kernelModuleNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid kernel module name %q", name)
+		}
+	}
+	for _, options := range c.KernelModules.Options {
+		if !kernelModuleNameRegex.MatchString(options.Name) {
+			return nil, fmt.Errorf("invalid kernel module name %q", options.Name)
+		}
+		if strings.TrimSpace(options.Options) == "" || strings.Contains(options.Options, "\n") {
+			return nil, fmt.Errorf("invalid options %q for kernel module %q", options.Options, options.Name)
+		}
+	}
+	return c.KernelModules, nil
+}
+
+// GetTuned returns the TuneD customization, which must select at least one
+// profile.
+func (c *Customizations) GetTuned() (*TunedCustomization, error) {
+	if c == nil || c.Tuned == nil {
+		return nil, nil
+	}
+
+	if len(c.Tuned.Profiles) == 0 {
+		return nil, fmt.Errorf("tuned customization requires at least one profile")
+	}
+	for _, profile := range c.Tuned.Profiles {
+		if profile == "" || strings.ContainsAny(profile, " \t/") {
+			return nil, fmt.Errorf("invalid tuned profile name %q", profile)
+		}
+	}
+	return c.Tuned, nil
+}
+
+// GetJournald returns the journald customization, which must set at least
+// one option.
+func (c *Customizations) GetJournald() (*JournaldCustomization, error) {
+	if c == nil || c.Journald == nil {
+		return nil, nil
+	}
+
+	if *c.Journald == (JournaldCustomization{}) {
+		return nil, fmt.Errorf("journald customization requires at least one option")
+	}
+	if storage := c.Journald.Storage; storage != "" {
+		if !slices.Contains(validJournaldStorage, storage) {
+			return nil, fmt.Errorf("invalid journald storage %q: must be one of %s", storage, strings.Join(validJournaldStorage, ", "))
+		}
+	}
+	return c.Journald, nil
+}
+
+// GetLogind returns the logind customization, which must set at least one
+// option.
+func (c *Customizations) GetLogind() (*LogindCustomization, error) {
+	if c == nil || c.Logind == nil {
+		return nil, nil
+	}
+
+	if c.Logind.NAutoVTs == nil {
+		return nil, fmt.Errorf("logind customization requires at least one option")
+	}
+	if *c.Logind.NAutoVTs < 0 {
+		return nil, fmt.Errorf("invalid logind nautovts value %d", *c.Logind.NAutoVTs)
+	}
+	return c.Logind, nil
+}
+
+// GetDracut returns the dracut customization, which must set at least one
+// option.
+func (c *Customizations) GetDracut() (*DracutCustomization, error) {
+	if c == nil || c.Dracut == nil {
+		return nil, nil
+	}
+
+	d := c.Dracut
+	if len(d.AddModules) == 0 && len(d.OmitModules) == 0 && len(d.AddDrivers) == 0 && len(d.Install) == 0 {
+		return nil, fmt.Errorf("dracut customization requires at least one option")
+	}
+	for _, module := range d.AddModules {
+		if slices.Contains(d.OmitModules, module) {
+			return nil, fmt.Errorf("dracut module %q cannot be both added and omitted", module)
+		}
+	}
+	return c.Dracut, nil
+}
@@ -0,0 +1,150 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestSystemCustomizationsNil(t *testing.T) {
+	var c *Customizations
+
+	sysctl, err := c.GetSysctl()
+	assert.NoError(t, err)
+	assert.Nil(t, sysctl)
+	modules, err := c.GetKernelModules()
+	assert.NoError(t, err)
+	assert.Nil(t, modules)
+	tuned, err := c.GetTuned()
+	assert.NoError(t, err)
+	assert.Nil(t, tuned)
+	journald, err := c.GetJournald()
+	assert.NoError(t, err)
+	assert.Nil(t, journald)
+	logind, err := c.GetLogind()
+	assert.NoError(t, err)
+	assert.Nil(t, logind)
+	dracut, err := c.GetDracut()
+	assert.NoError(t, err)
+	assert.Nil(t, dracut)
+}
+
+func TestSystemCustomizationsValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		customizations *Customizations
+		wantErr        string
+	}{
+		{
+			name: "valid",
+			customizations: &Customizations{
+				Sysctl: []SysctlCustomization{
+					{Key: "net.ipv4.ip_forward", Value: "1"},
+					{Key: "vm.swappiness", Value: "10"},
+				},
+				KernelModules: &KernelModulesCustomization{
+					Blacklist: []string{"floppy"},
+					Options:   []KernelModuleOptionsCustomization{{Name: "kvm_intel", Options: "nested=1"}},
+				},
+				Tuned:    &TunedCustomization{Profiles: []string{"virtual-guest"}},
+				Journald: &JournaldCustomization{Storage: "persistent", MaxRetentionSec: "1month"},
+				Logind:   &LogindCustomization{NAutoVTs: common.ToPtr(0)},
+				Dracut:   &DracutCustomization{AddModules: []string{"nfs"}, OmitModules: []string{"plymouth"}},
+			},
+		},
+		{
+			name:           "sysctl-no-key",
+			customizations: &Customizations{Sysctl: []SysctlCustomization{{Value: "1"}}},
+			wantErr:        `invalid sysctl key ""`,
+		},
+		{
+			name:           "sysctl-no-value",
+			customizations: &Customizations{Sysctl: []SysctlCustomization{{Key: "vm.swappiness"}}},
+			wantErr:        `sysctl key "vm.swappiness" requires a value`,
+		},
+		{
+			name: "sysctl-duplicate",
+			customizations: &Customizations{Sysctl: []SysctlCustomization{
+				{Key: "vm.swappiness", Value: "10"},
+				{Key: "vm.swappiness", Value: "20"},
+			}},
+			wantErr: `sysctl key "vm.swappiness" is set more than once`,
+		},
+		{
+			name:           "module-blacklist",
+			customizations: &Customizations{KernelModules: &KernelModulesCustomization{Blacklist: []string{"../floppy"}}},
+			wantErr:        `invalid kernel module name "../floppy"`,
+		},
+		{
+			name: "module-options",
+			customizations: &Customizations{KernelModules: &KernelModulesCustomization{
+				Options: []KernelModuleOptionsCustomization{{Name: "kvm_intel"}},
+			}},
+			wantErr: `invalid options "" for kernel module "kvm_intel"`,
+		},
+		{
+			name:           "tuned-no-profiles",
+			customizations: &Customizations{Tuned: &TunedCustomization{}},
+			wantErr:        "tuned customization requires at least one profile",
+		},
+		{
+			name:           "journald-empty",
+			customizations: &Customizations{Journald: &JournaldCustomization{}},
+			wantErr:        "journald customization requires at least one option",
+		},
+		{
+			name:           "journald-storage",
+			customizations: &Customizations{Journald: &JournaldCustomization{Storage: "disk"}},
+			wantErr:        `invalid journald storage "disk": must be one of volatile, persistent, auto, none`,
+		},
+		{
+			name:           "logind-empty",
+			customizations: &Customizations{Logind: &LogindCustomization{}},
+			wantErr:        "logind customization requires at least one option",
+		},
+		{
+			name:           "dracut-empty",
+			customizations: &Customizations{Dracut: &DracutCustomization{}},
+			wantErr:        "dracut customization requires at least one option",
+		},
+		{
+			name:           "dracut-conflict",
+			customizations: &Customizations{Dracut: &DracutCustomization{AddModules: []string{"nfs"}, OmitModules: []string{"nfs"}}},
+			wantErr:        `dracut module "nfs" cannot be both added and omitted`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.customizations
+			var errs []error
+			_, err := c.GetSysctl()
+			errs = append(errs, err)
+			_, err = c.GetKernelModules()
+			errs = append(errs, err)
+			_, err = c.GetTuned()
+			errs = append(errs, err)
+			_, err = c.GetJournald()
+			errs = append(errs, err)
+			_, err = c.GetLogind()
+			errs = append(errs, err)
+			_, err = c.GetDracut()
+			errs = append(errs, err)
+
+			var gotErr error
+			for _, err := range errs {
+				if err != nil {
+					assert.Nil(t, gotErr, "more than one error")
+					gotErr = err
+				}
+			}
+			if tt.wantErr != "" {
+				assert.EqualError(t, gotErr, tt.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+// Package build turns a manifest into an image: it depsolves the package
+// sets, resolves the containers and ostree commits, serializes the manifest
+// and runs osbuild. The steps are done through interfaces so that the
+// tools and the tests can replace them.
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// A Depsolver resolves a package set chain to the packages to install.
+// *dnfjson.Solver implements it.
+type Depsolver interface {
+	Depsolve(ctx context.Context, pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error)
+}
+
+// A ContainerResolver resolves the containers embedded in a pipeline.
+type ContainerResolver interface {
+	ResolveContainers(ctx context.Context, sources []container.SourceSpec) ([]container.Spec, error)
+}
+
+// A CommitResolver resolves the ostree commits of a pipeline.
+type CommitResolver interface {
+	ResolveCommits(ctx context.Context, sources []ostree.SourceSpec) ([]ostree.CommitSpec, error)
+}
+
+// A Runner builds a serialized manifest. If the events channel isn't nil the
+// progress is sent to it and it is closed when the build is done.
+type Runner interface {
+	Run(ctx context.Context, mf manifest.OSBuildManifest, exports, checkpoints []string, events chan<- osbuild.MonitorEvent) (*osbuild.Result, error)
+}
+
+// RegistryResolver resolves containers from their registries for an
+// architecture.
+type RegistryResolver struct {
+	Arch         string
+	AuthFilePath string
+}
+
+func (r *RegistryResolver) ResolveContainers(ctx context.Context, sources []container.SourceSpec) ([]container.Spec, error) {
+	resolver := container.NewResolverWithContext(ctx, r.Arch)
+	resolver.AuthFilePath = r.AuthFilePath
+	for _, source := range sources {
+		resolver.Add(source)
+	}
+	return resolver.Finish()
+}
+
+// OSTreeResolver resolves the ostree refs from their repositories.
+type OSTreeResolver struct{}
+
+func (OSTreeResolver) ResolveCommits(ctx context.Context, sources []ostree.SourceSpec) ([]ostree.CommitSpec, error) {
+	commits := make([]ostree.CommitSpec, len(sources))
+	for idx, source := range sources {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		commit, err := ostree.Resolve(source)
+		if err != nil {
+			return nil, err
+		}
+		commits[idx] = commit
+	}
+	return commits, nil
+}
+
+// OSBuild runs osbuild with a store and an output directory.
+type OSBuild struct {
+	Store           string
+	OutputDirectory string
+	ExtraEnv        []string
+
+	// Writer for the error output of osbuild, os.Stderr if nil
+	ErrorWriter io.Writer
+}
+
+func (o *OSBuild) Run(ctx context.Context, mf manifest.OSBuildManifest, exports, checkpoints []string, events chan<- osbuild.MonitorEvent) (*osbuild.Result, error) {
+	errorWriter := o.ErrorWriter
+	if errorWriter == nil {
+		errorWriter = os.Stderr
+	}
+	return osbuild.RunOSBuildContext(ctx, mf, o.Store, o.OutputDirectory, exports, checkpoints, o.ExtraEnv, errorWriter, events)
+}
+
+// Options configure the steps of a build. The Depsolver is only required
+// for manifests with package sets, the ContainerResolver only for manifests
+// with containers and the Runner only for Build. The manifest doesn't know
+// its architecture, so there is no default ContainerResolver, use a
+// RegistryResolver for the architecture of the image.
+type Options struct {
+	Depsolver         Depsolver
+	ContainerResolver ContainerResolver
+	CommitResolver    CommitResolver
+	Runner            Runner
+
+	// Pipelines to export, the exports of the manifest if empty
+	Exports []string
+
+	// Progress of osbuild, see Runner
+	Events chan<- osbuild.MonitorEvent
+}
+
+// Result collects everything that was resolved and built.
+type Result struct {
+	// Manifest with the secrets replaced by references, it can be stored
+	// or logged. Build materializes it from the Secrets only to run it.
+	Manifest   manifest.OSBuildManifest
+	Secrets    manifest.Secrets
+	Packages   map[string][]rpmmd.PackageSpec
+	Containers map[string][]container.Spec
+	Commits    map[string][]ostree.CommitSpec
+
+	// Result of osbuild, nil if it didn't run
+	OSBuild *osbuild.Result
+
+	// Files of every export in the output directory of an OSBuild runner
+	Artifacts map[string][]string
+}
+
+// Resolve depsolves the package sets of the manifest and resolves its
+// containers and ostree commits concurrently, then serializes it with the
+// secrets redacted. The first error cancels the other resolvers.
+func Resolve(ctx context.Context, m *manifest.Manifest, opts Options) (*Result, error) {
+	packageSets := m.GetPackageSetChains()
+	containerSources := m.GetContainerSourceSpecs()
+	commitSources := m.GetOSTreeSourceSpecs()
+
+	if len(packageSets) > 0 && opts.Depsolver == nil {
+		return nil, fmt.Errorf("manifest has package sets but no depsolver was given")
+	}
+	if len(containerSources) > 0 && opts.ContainerResolver == nil {
+		return nil, fmt.Errorf("manifest has containers but no container resolver was given")
+	}
+	commitResolver := opts.CommitResolver
+	if commitResolver == nil {
+		commitResolver = OSTreeResolver{}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	res := &Result{
+		Packages:   make(map[string][]rpmmd.PackageSpec, len(packageSets)),
+		Containers: make(map[string][]container.Spec, len(containerSources)),
+		Commits:    make(map[string][]ostree.CommitSpec, len(commitSources)),
+	}
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// the depsolver is not safe for concurrent use, the pipelines are
+	// depsolved one after the other
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, name := range sortedKeys(packageSets) {
+			if err := ctx.Err(); err != nil {
+				fail(err)
+				return
+			}
+			packages, err := opts.Depsolver.Depsolve(ctx, packageSets[name])
+			if err != nil {
+				fail(fmt.Errorf("depsolving pipeline %s failed: %w", name, err))
+				return
+			}
+			res.Packages[name] = packages
+		}
+	}()
+
+	var mu sync.Mutex
+	for name, sources := range containerSources {
+		wg.Add(1)
+		go func(name string, sources []container.SourceSpec) {
+			defer wg.Done()
+			specs, err := opts.ContainerResolver.ResolveContainers(ctx, sources)
+			if err != nil {
+				fail(fmt.Errorf("resolving containers of pipeline %s failed: %w", name, err))
+				return
+			}
+			mu.Lock()
+			res.Containers[name] = specs
+			mu.Unlock()
+		}(name, sources)
+	}
+
+	for name, sources := range commitSources {
+		wg.Add(1)
+		go func(name string, sources []ostree.SourceSpec) {
+			defer wg.Done()
+			commits, err := commitResolver.ResolveCommits(ctx, sources)
+			if err != nil {
+				fail(fmt.Errorf("resolving ostree commits of pipeline %s failed: %w", name, err))
+				return
+			}
+			mu.Lock()
+			res.Commits[name] = commits
+			mu.Unlock()
+		}(name, sources)
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	mf, secrets, err := m.SerializeWithSecrets(res.Packages, res.Containers, res.Commits)
+	if err != nil {
+		return nil, fmt.Errorf("manifest serialization failed: %w", err)
+	}
+	res.Manifest = mf
+	res.Secrets = secrets
+
+	return res, nil
+}
+
+// Build resolves the manifest like Resolve and builds it with the runner.
+// A failed osbuild run is not an error, it's reported by the osbuild result.
+func Build(ctx context.Context, m *manifest.Manifest, opts Options) (*Result, error) {
+	if opts.Runner == nil {
+		if opts.Events != nil {
+			close(opts.Events)
+		}
+		return nil, fmt.Errorf("no runner was given")
+	}
+
+	res, err := Resolve(ctx, m, opts)
+	if err != nil {
+		if opts.Events != nil {
+			close(opts.Events)
+		}
+		return nil, err
+	}
+
+	exports := opts.Exports
+	if len(exports) == 0 {
+		exports = m.GetExports()
+	}
+	mf, err := manifest.MaterializeSecrets(res.Manifest, res.Secrets)
+	if err != nil {
+		if opts.Events != nil {
+			close(opts.Events)
+		}
+		return nil, err
+	}
+	res.OSBuild, err = opts.Runner.Run(ctx, mf, exports, m.GetCheckpoints(), opts.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	if runner, ok := opts.Runner.(*OSBuild); ok && res.OSBuild.Success {
+		res.Artifacts, err = listArtifacts(runner.OutputDirectory, exports)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// listArtifacts lists the files of the exports, osbuild writes every export
+// to a directory of the same name
+func listArtifacts(outputDirectory string, exports []string) (map[string][]string, error) {
+	artifacts := make(map[string][]string, len(exports))
+	for _, export := range exports {
+		dir := filepath.Join(outputDirectory, export)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list the artifacts of export %s: %w", export, err)
+		}
+		for _, entry := range entries {
+			artifacts[export] = append(artifacts[export], filepath.Join(dir, entry.Name()))
+		}
+	}
+	return artifacts, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,251 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+)
+
+type fakeDepsolver struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+
+	// wait for the context to be cancelled instead of depsolving
+	block bool
+}
+
+func (d *fakeDepsolver) Depsolve(ctx context.Context, pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	var specs []rpmmd.PackageSpec
+	for _, pkgSet := range pkgSets {
+		for _, name := range pkgSet.Include {
+			specs = append(specs, rpmmd.PackageSpec{
+				Name:     name,
+				Checksum: "sha256:" + strings.Repeat("0", 64),
+			})
+		}
+	}
+	return specs, nil
+}
+
+type fakeContainerResolver struct {
+	err error
+
+	// wait for the context to be cancelled instead of resolving
+	block bool
+}
+
+func (r *fakeContainerResolver) ResolveContainers(ctx context.Context, sources []container.SourceSpec) ([]container.Spec, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	specs := make([]container.Spec, len(sources))
+	for idx, source := range sources {
+		specs[idx] = container.Spec{
+			Source:    source.Source,
+			Digest:    "sha256:" + strings.Repeat("1", 64),
+			ImageID:   "sha256:" + strings.Repeat("2", 64),
+			LocalName: source.Name,
+		}
+	}
+	return specs, nil
+}
+
+type fakeRunner struct {
+	manifest manifest.OSBuildManifest
+	exports  []string
+	result   *osbuild.Result
+}
+
+func (r *fakeRunner) Run(ctx context.Context, mf manifest.OSBuildManifest, exports, checkpoints []string, events chan<- osbuild.MonitorEvent) (*osbuild.Result, error) {
+	if events != nil {
+		defer close(events)
+	}
+	r.manifest = mf
+	r.exports = exports
+	return r.result, nil
+}
+
+func newTestManifest() *manifest.Manifest {
+	m := manifest.New()
+	build := manifest.NewBuild(&m, &runner.Fedora{Version: 38}, nil)
+	os := manifest.NewOS(&m, build, &platform.X86{BIOS: true}, nil)
+	os.OSCustomizations.Containers = []container.SourceSpec{
+		{Source: "registry.example.com/app:latest", Name: "app"},
+	}
+	tar := manifest.NewTar(build, os, "archive")
+	tar.SetFilename("archive.tar")
+	tar.Export()
+	return &m
+}
+
+func TestResolve(t *testing.T) {
+	depsolver := &fakeDepsolver{}
+	res, err := Resolve(context.Background(), newTestManifest(), Options{
+		Depsolver:         depsolver,
+		ContainerResolver: &fakeContainerResolver{},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, depsolver.calls)
+	assert.Contains(t, res.Packages, "build")
+	assert.Contains(t, res.Packages, "os")
+	require.Len(t, res.Containers["os"], 1)
+	assert.Equal(t, "app", res.Containers["os"][0].LocalName)
+	assert.Empty(t, res.Commits)
+	assert.Contains(t, string(res.Manifest), strings.Repeat("1", 64))
+	assert.Nil(t, res.OSBuild)
+}
+
+func TestResolveNoDepsolver(t *testing.T) {
+	_, err := Resolve(context.Background(), newTestManifest(), Options{})
+	assert.EqualError(t, err, "manifest has package sets but no depsolver was given")
+}
+
+func TestResolveErrorCancelsResolvers(t *testing.T) {
+	_, err := Resolve(context.Background(), newTestManifest(), Options{
+		Depsolver:         &fakeDepsolver{err: fmt.Errorf("nothing provides pkg1")},
+		ContainerResolver: &fakeContainerResolver{block: true},
+	})
+	assert.ErrorContains(t, err, "nothing provides pkg1")
+	assert.ErrorContains(t, err, "depsolving pipeline build failed")
+
+	// the context of the depsolver is cancelled as well
+	_, err = Resolve(context.Background(), newTestManifest(), Options{
+		Depsolver:         &fakeDepsolver{block: true},
+		ContainerResolver: &fakeContainerResolver{err: fmt.Errorf("manifest unknown")},
+	})
+	assert.EqualError(t, err, "resolving containers of pipeline os failed: manifest unknown")
+}
+
+func TestResolveNoContainerResolver(t *testing.T) {
+	_, err := Resolve(context.Background(), newTestManifest(), Options{
+		Depsolver: &fakeDepsolver{},
+	})
+	assert.EqualError(t, err, "manifest has containers but no container resolver was given")
+}
+
+func TestResolveCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	depsolver := &fakeDepsolver{}
+	_, err := Resolve(ctx, newTestManifest(), Options{
+		Depsolver:         depsolver,
+		ContainerResolver: &fakeContainerResolver{},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, depsolver.calls)
+}
+
+func TestOSTreeResolver(t *testing.T) {
+	checksum := strings.Repeat("a", 64)
+	commits, err := OSTreeResolver{}.ResolveCommits(context.Background(), []ostree.SourceSpec{{Ref: checksum}})
+	require.NoError(t, err)
+	assert.Equal(t, []ostree.CommitSpec{{Ref: checksum, Checksum: checksum}}, commits)
+
+	_, err = OSTreeResolver{}.ResolveCommits(context.Background(), []ostree.SourceSpec{{Ref: "invalid ref"}})
+	assert.Error(t, err)
+}
+
+func TestBuild(t *testing.T) {
+	runner := &fakeRunner{result: &osbuild.Result{Success: true}}
+	events := make(chan osbuild.MonitorEvent)
+	res, err := Build(context.Background(), newTestManifest(), Options{
+		Depsolver:         &fakeDepsolver{},
+		ContainerResolver: &fakeContainerResolver{},
+		Runner:            runner,
+		Events:            events,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"archive"}, runner.exports)
+	assert.True(t, res.OSBuild.Success)
+	assert.Nil(t, res.Artifacts)
+	_, open := <-events
+	assert.False(t, open)
+}
+
+func TestBuildSecrets(t *testing.T) {
+	m := manifest.New()
+	build := manifest.NewBuild(&m, &runner.Fedora{Version: 38}, nil)
+	osPipeline := manifest.NewOS(&m, build, &platform.X86{BIOS: true}, nil)
+	osPipeline.Users = []users.User{{Name: "admin", Password: common.ToPtr("my-secret-password")}}
+	tar := manifest.NewTar(build, osPipeline, "archive")
+	tar.SetFilename("archive.tar")
+	tar.Export()
+
+	r := &fakeRunner{result: &osbuild.Result{Success: true}}
+	res, err := Build(context.Background(), &m, Options{
+		Depsolver: &fakeDepsolver{},
+		Runner:    r,
+	})
+	require.NoError(t, err)
+
+	// only the runner gets the secrets
+	passwordHash := res.Secrets["os.user-password-admin"]
+	require.NotEmpty(t, passwordHash)
+	assert.NotContains(t, string(res.Manifest), passwordHash)
+	assert.Contains(t, string(res.Manifest), "${secret:os.user-password-admin}")
+	assert.Contains(t, string(r.manifest), passwordHash)
+	assert.NotContains(t, string(r.manifest), "${secret:")
+}
+
+func TestBuildResolveErrorClosesEvents(t *testing.T) {
+	events := make(chan osbuild.MonitorEvent)
+	_, err := Build(context.Background(), newTestManifest(), Options{
+		Depsolver: &fakeDepsolver{err: fmt.Errorf("depsolve failed")},
+		Runner:    &fakeRunner{},
+		Events:    events,
+	})
+	assert.Error(t, err)
+	_, open := <-events
+	assert.False(t, open)
+
+	_, err = Build(context.Background(), newTestManifest(), Options{})
+	assert.EqualError(t, err, "no runner was given")
+}
+
+func TestListArtifacts(t *testing.T) {
+	outputDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(outputDir, "archive"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "archive", "archive.tar"), nil, 0644))
+
+	artifacts, err := listArtifacts(outputDir, []string{"archive"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"archive": {filepath.Join(outputDir, "archive", "archive.tar")}}, artifacts)
+
+	_, err = listArtifacts(outputDir, []string{"qcow2"})
+	assert.ErrorContains(t, err, "cannot list the artifacts of export qcow2")
+}
@@ -0,0 +1,611 @@
+package build
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// Kinds of cache entries, every kind is stored in a directory of the cache
+const (
+	CacheDepsolve   = "depsolve"
+	CacheContainers = "containers"
+	CacheCommits    = "commits"
+)
+
+// A Cache persists the results of the depsolvers and resolvers on disk so
+// that they are shared between runs and processes. Entries are addressed by
+// the hash of everything that determines the result, e.g. the package sets
+// and repositories of a depsolve, and expire after the TTL. Depsolve entries
+// are also invalidated when the metadata of a repository changes.
+type Cache struct {
+	dir string
+	ttl time.Duration
+
+	// RepoChecksum returns the checksum of the current metadata of a
+	// repository, or an empty string if it can't be determined. Depsolves
+	// with repositories that can't be checked aren't cached. If it's nil,
+	// e.g. to work offline, depsolve results only expire after the TTL.
+	RepoChecksum func(repo rpmmd.RepoConfig) (string, error)
+
+	mu        sync.Mutex
+	checksums map[string]string
+
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	Created time.Time `json:"created"`
+
+	// Entries of immutable content, e.g. a container referenced by its
+	// digest, don't expire
+	Immutable bool `json:"immutable,omitempty"`
+
+	// Checksums of the repository metadata of depsolve results
+	Checksums []string `json:"checksums,omitempty"`
+
+	Value json.RawMessage `json:"value"`
+}
+
+// DefaultCacheDir returns the directory of the cache in the cache directory
+// of the user, or an empty string if the user has none.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "osbuild-images", "resolve")
+}
+
+// NewCache returns a cache in the directory whose entries expire after the
+// TTL. The repository metadata is checked with RepomdChecksum.
+//
+// The cached results end up in manifests, so the directory is created only
+// accessible by the current user and a directory owned by another user is
+// refused.
+func NewCache(dir string, ttl time.Duration) (*Cache, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("invalid cache TTL %s", ttl)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create cache directory: %w", err)
+	}
+	if err := checkCacheDir(dir, os.Getuid()); err != nil {
+		return nil, err
+	}
+	return &Cache{
+		dir:          dir,
+		ttl:          ttl,
+		RepoChecksum: RepomdChecksum,
+		checksums:    make(map[string]string),
+		now:          time.Now,
+	}, nil
+}
+
+// checkCacheDir checks that the directory is a directory owned by the user
+func checkCacheDir(dir string, uid int) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("cannot open cache directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("cache directory %s is not a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != uid {
+		return fmt.Errorf("cache directory %s is owned by uid %d, not by the current user", dir, stat.Uid)
+	}
+	return nil
+}
+
+// OpenCache returns the cache in the directory, or nil if the directory is
+// empty, and prepares it for a run: with refresh all entries are removed,
+// otherwise the expired ones. Offline the repository metadata isn't checked.
+func OpenCache(dir string, ttl time.Duration, offline, refresh bool) (*Cache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	cache, err := NewCache(dir, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if offline {
+		cache.RepoChecksum = nil
+	}
+	if refresh {
+		err = cache.Invalidate()
+	} else {
+		err = cache.Prune()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func cacheKey(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+func (c *Cache) path(kind, key string) string {
+	return filepath.Join(c.dir, kind, key[:2], key+".json")
+}
+
+func (c *Cache) expired(entry *cacheEntry) bool {
+	return !entry.Immutable && c.now().Sub(entry.Created) > c.ttl
+}
+
+// get returns the entry of the key if it exists and hasn't expired
+func (c *Cache) get(kind, key string) *cacheEntry {
+	data, err := os.ReadFile(c.path(kind, key))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || c.expired(&entry) {
+		return nil
+	}
+	return &entry
+}
+
+// put stores the value, the entry is written to a temporary file first so
+// that concurrent readers never see a partial entry
+func (c *Cache) put(kind, key string, value interface{}, immutable bool, checksums []string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	entry, err := json.Marshal(cacheEntry{
+		Created:   c.now(),
+		Immutable: immutable,
+		Checksums: checksums,
+		Value:     data,
+	})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(entry); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Invalidate removes the entries of the kinds, all entries if no kind is
+// given.
+func (c *Cache) Invalidate(kinds ...string) error {
+	if len(kinds) == 0 {
+		kinds = []string{CacheDepsolve, CacheContainers, CacheCommits}
+	}
+	for _, kind := range kinds {
+		if err := os.RemoveAll(filepath.Join(c.dir, kind)); err != nil {
+			return fmt.Errorf("cannot invalidate %s cache: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// Prune removes the expired entries.
+func (c *Cache) Prune() error {
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil || c.expired(&entry) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// repoChecksums returns the metadata checksums of the repositories of the
+// package sets, an empty string for the ones that can't be checked
+func (c *Cache) repoChecksums(pkgSets []rpmmd.PackageSet) []string {
+	if c.RepoChecksum == nil {
+		return nil
+	}
+	var checksums []string
+	for _, pkgSet := range pkgSets {
+		for _, repo := range pkgSet.Repositories {
+			checksums = append(checksums, c.repoChecksum(repo))
+		}
+	}
+	return checksums
+}
+
+// repoChecksum returns the metadata checksum of a repository, it's only
+// determined once per cache since all depsolves of a run share the
+// repositories
+func (c *Cache) repoChecksum(repo rpmmd.RepoConfig) string {
+	key, err := cacheKey(repo)
+	if err != nil {
+		return ""
+	}
+
+	c.mu.Lock()
+	checksum, ok := c.checksums[key]
+	c.mu.Unlock()
+	if ok {
+		return checksum
+	}
+
+	checksum, err = c.RepoChecksum(repo)
+	if err != nil {
+		checksum = ""
+	}
+	c.mu.Lock()
+	c.checksums[key] = checksum
+	c.mu.Unlock()
+	return checksum
+}
+
+// checkable returns whether the metadata of all repositories can be checked,
+// results that can't be checked are neither used nor stored unless the cache
+// works offline
+func checkable(checksums []string) bool {
+	for _, checksum := range checksums {
+		if checksum == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// checksumsMatch returns false if the metadata of a repository changed or the
+// stored result can't be checked. Without current checksums the cache works
+// offline and everything matches.
+func checksumsMatch(stored, current []string) bool {
+	if current == nil {
+		return true
+	}
+	if len(stored) != len(current) {
+		return false
+	}
+	for idx := range stored {
+		if stored[idx] == "" || stored[idx] != current[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// RepomdChecksum returns the checksum of the repomd.xml of the repository.
+// For a metalink it's the checksum the metalink lists, for a mirrorlist the
+// repomd.xml of the first mirror is fetched. Repositories that require a
+// subscription can't be checked.
+func RepomdChecksum(repo rpmmd.RepoConfig) (string, error) {
+	if repo.RHSM {
+		return "", nil
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	if repo.IgnoreSSL != nil && *repo.IgnoreSSL {
+		client.Transport = &http.Transport{
+			/* #nosec G402 */
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	switch {
+	case len(repo.BaseURLs) > 0:
+		return repomdChecksum(client, repo.BaseURLs[0])
+	case repo.Metalink != "":
+		return metalinkChecksum(client, repo.Metalink)
+	case repo.MirrorList != "":
+		baseURL, err := firstMirror(client, repo.MirrorList)
+		if err != nil {
+			return "", err
+		}
+		return repomdChecksum(client, baseURL)
+	}
+	return "", nil
+}
+
+func fetch(client *http.Client, url string) (io.ReadCloser, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cannot fetch %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func repomdChecksum(client *http.Client, baseURL string) (string, error) {
+	body, err := fetch(client, strings.TrimSuffix(baseURL, "/")+"/repodata/repomd.xml")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+type metalink struct {
+	Files []struct {
+		Name   string `xml:"name,attr"`
+		Hashes []struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"verification>hash"`
+	} `xml:"files>file"`
+}
+
+// metalinkChecksum returns the sha256 checksum of the repomd.xml listed in
+// the metalink
+func metalinkChecksum(client *http.Client, url string) (string, error) {
+	body, err := fetch(client, url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	var ml metalink
+	if err := xml.NewDecoder(body).Decode(&ml); err != nil {
+		return "", fmt.Errorf("cannot parse metalink %s: %w", url, err)
+	}
+	for _, file := range ml.Files {
+		if file.Name != "repomd.xml" {
+			continue
+		}
+		for _, hash := range file.Hashes {
+			if hash.Type == "sha256" {
+				return "sha256:" + strings.TrimSpace(hash.Value), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("metalink %s has no sha256 checksum of repomd.xml", url)
+}
+
+// firstMirror returns the first base URL of the mirrorlist
+func firstMirror(client *http.Client, url string) (string, error) {
+	body, err := fetch(client, url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("mirrorlist %s has no mirrors", url)
+}
+
+type cachedDepsolver struct {
+	cache     *Cache
+	depsolver Depsolver
+	namespace string
+}
+
+// Depsolver returns a depsolver that uses the cache. The results of
+// depsolvers of different distributions or architectures must not be
+// shared, the namespace keeps them apart. A nil cache returns the depsolver.
+func (c *Cache) Depsolver(depsolver Depsolver, distro, arch string) Depsolver {
+	if c == nil {
+		return depsolver
+	}
+	return &cachedDepsolver{
+		cache:     c,
+		depsolver: depsolver,
+		namespace: distro + "/" + arch,
+	}
+}
+
+func (d *cachedDepsolver) Depsolve(ctx context.Context, pkgSets []rpmmd.PackageSet) ([]rpmmd.PackageSpec, error) {
+	key, err := cacheKey(struct {
+		Namespace string
+		PkgSets   []rpmmd.PackageSet
+	}{d.namespace, pkgSets})
+	if err != nil {
+		return d.depsolver.Depsolve(ctx, pkgSets)
+	}
+
+	checksums := d.cache.repoChecksums(pkgSets)
+	if !checkable(checksums) {
+		return d.depsolver.Depsolve(ctx, pkgSets)
+	}
+	if entry := d.cache.get(CacheDepsolve, key); entry != nil && checksumsMatch(entry.Checksums, checksums) {
+		var specs []rpmmd.PackageSpec
+		if err := json.Unmarshal(entry.Value, &specs); err == nil {
+			return specs, nil
+		}
+	}
+
+	specs, err := d.depsolver.Depsolve(ctx, pkgSets)
+	if err != nil {
+		return nil, err
+	}
+	// failing to store the result only makes the next run slower
+	_ = d.cache.put(CacheDepsolve, key, specs, false, checksums)
+	return specs, nil
+}
+
+type cachedContainerResolver struct {
+	cache    *Cache
+	resolver ContainerResolver
+	arch     string
+}
+
+// ContainerResolver returns a container resolver that uses the cache.
+// Containers referenced by a digest don't expire. A nil cache returns the
+// resolver.
+func (c *Cache) ContainerResolver(resolver ContainerResolver, arch string) ContainerResolver {
+	if c == nil {
+		return resolver
+	}
+	return &cachedContainerResolver{
+		cache:    c,
+		resolver: resolver,
+		arch:     arch,
+	}
+}
+
+// ResolveContainers resolves the containers that aren't cached concurrently,
+// one per call of the resolver so that the results match the sources.
+func (r *cachedContainerResolver) ResolveContainers(ctx context.Context, sources []container.SourceSpec) ([]container.Spec, error) {
+	specs := make([]container.Spec, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for idx, source := range sources {
+		key, err := cacheKey(struct {
+			Arch   string
+			Source container.SourceSpec
+		}{r.arch, source})
+		if err == nil {
+			if entry := r.cache.get(CacheContainers, key); entry != nil {
+				if err := json.Unmarshal(entry.Value, &specs[idx]); err == nil {
+					continue
+				}
+			}
+		}
+
+		wg.Add(1)
+		go func(idx int, source container.SourceSpec, key string) {
+			defer wg.Done()
+			resolved, err := r.resolver.ResolveContainers(ctx, []container.SourceSpec{source})
+			if err != nil {
+				errs[idx] = err
+				return
+			}
+			if len(resolved) != 1 {
+				errs[idx] = fmt.Errorf("resolving container %s returned %d results", source.Source, len(resolved))
+				return
+			}
+			specs[idx] = resolved[0]
+			if key != "" {
+				_ = r.cache.put(CacheContainers, key, resolved[0], strings.Contains(source.Source, "@sha256:"), nil)
+			}
+		}(idx, source, key)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return specs, nil
+}
+
+type cachedCommitResolver struct {
+	cache    *Cache
+	resolver CommitResolver
+}
+
+// CommitResolver returns an ostree commit resolver that uses the cache. A
+// nil cache returns the resolver.
+func (c *Cache) CommitResolver(resolver CommitResolver) CommitResolver {
+	if c == nil {
+		return resolver
+	}
+	return &cachedCommitResolver{
+		cache:    c,
+		resolver: resolver,
+	}
+}
+
+func (r *cachedCommitResolver) ResolveCommits(ctx context.Context, sources []ostree.SourceSpec) ([]ostree.CommitSpec, error) {
+	commits := make([]ostree.CommitSpec, len(sources))
+	for idx, source := range sources {
+		key, err := cacheKey(source)
+		if err == nil {
+			if entry := r.cache.get(CacheCommits, key); entry != nil {
+				if err := json.Unmarshal(entry.Value, &commits[idx]); err == nil {
+					continue
+				}
+			}
+		}
+
+		resolved, err := r.resolver.ResolveCommits(ctx, []ostree.SourceSpec{source})
+		if err != nil {
+			return nil, err
+		}
+		if len(resolved) != 1 {
+			return nil, fmt.Errorf("resolving ostree ref %s returned %d results", source.Ref, len(resolved))
+		}
+		commits[idx] = resolved[0]
+		if key != "" {
+			_ = r.cache.put(CacheCommits, key, resolved[0], false, nil)
+		}
+	}
+	return commits, nil
+}
+
+// Wrap returns the options with the depsolver and the resolvers using the
+// cache, the default resolvers are used if none are set. A nil cache returns
+// the options unchanged.
+func (c *Cache) Wrap(opts Options, distro, arch string) Options {
+	if c == nil {
+		return opts
+	}
+	if opts.Depsolver != nil {
+		opts.Depsolver = c.Depsolver(opts.Depsolver, distro, arch)
+	}
+	if opts.ContainerResolver == nil {
+		opts.ContainerResolver = &RegistryResolver{Arch: arch}
+	}
+	opts.ContainerResolver = c.ContainerResolver(opts.ContainerResolver, arch)
+	if opts.CommitResolver == nil {
+		opts.CommitResolver = OSTreeResolver{}
+	}
+	opts.CommitResolver = c.CommitResolver(opts.CommitResolver)
+	return opts
+}
@@ -0,0 +1,425 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+func newTestCache(t *testing.T) (*Cache, *time.Time) {
+	cache, err := NewCache(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	now := time.Date(2023, 11, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	cache.RepoChecksum = nil
+	return cache, &now
+}
+
+var testPkgSets = []rpmmd.PackageSet{
+	{
+		Include:      []string{"pkg1", "pkg2"},
+		Repositories: []rpmmd.RepoConfig{{Id: "repo1", BaseURLs: []string{"https://example.com/repo1"}}},
+	},
+}
+
+func TestNewCacheInvalidTTL(t *testing.T) {
+	_, err := NewCache(t.TempDir(), 0)
+	assert.EqualError(t, err, "invalid cache TTL 0s")
+}
+
+func TestNewCacheDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "resolve")
+	_, err := NewCache(dir, time.Hour)
+	require.NoError(t, err)
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	assert.NoError(t, checkCacheDir(dir, os.Getuid()))
+	assert.EqualError(t, checkCacheDir(dir, os.Getuid()+1), fmt.Sprintf("cache directory %s is owned by uid %d, not by the current user", dir, os.Getuid()))
+
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0600))
+	assert.EqualError(t, checkCacheDir(file, os.Getuid()), fmt.Sprintf("cache directory %s is not a directory", file))
+}
+
+func TestOpenCache(t *testing.T) {
+	cache, err := OpenCache("", time.Hour, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, cache)
+
+	dir := t.TempDir()
+	cache, err = OpenCache(dir, time.Hour, true, false)
+	require.NoError(t, err)
+	assert.Nil(t, cache.RepoChecksum)
+	_, err = cache.Depsolver(&fakeDepsolver{}, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+
+	_, err = OpenCache(dir, time.Hour, false, true)
+	require.NoError(t, err)
+	entries, err := filepath.Glob(filepath.Join(dir, CacheDepsolve, "*", "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCacheDepsolver(t *testing.T) {
+	cache, now := newTestCache(t)
+	depsolver := &fakeDepsolver{}
+	cached := cache.Depsolver(depsolver, "fedora-39", "x86_64")
+
+	specs, err := cached.Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Len(t, specs, 2)
+	assert.Equal(t, 1, depsolver.calls)
+
+	// cached, also for another process using the same directory
+	again, err := cached.Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, specs, again)
+	other := &Cache{dir: cache.dir, ttl: cache.ttl, now: cache.now}
+	_, err = other.Depsolver(depsolver, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 1, depsolver.calls)
+
+	// other architectures and package sets are separate entries
+	_, err = cache.Depsolver(depsolver, "fedora-39", "aarch64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 2, depsolver.calls)
+
+	// expired
+	*now = now.Add(2 * time.Hour)
+	_, err = cached.Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 3, depsolver.calls)
+}
+
+func TestCacheDepsolverErrorsNotCached(t *testing.T) {
+	cache, _ := newTestCache(t)
+	depsolver := &fakeDepsolver{err: fmt.Errorf("nothing provides pkg2")}
+	cached := cache.Depsolver(depsolver, "fedora-39", "x86_64")
+
+	for i := 0; i < 2; i++ {
+		_, err := cached.Depsolve(context.Background(), testPkgSets)
+		assert.EqualError(t, err, "nothing provides pkg2")
+	}
+	assert.Equal(t, 2, depsolver.calls)
+}
+
+func TestCacheDepsolverRepoChecksum(t *testing.T) {
+	cache, _ := newTestCache(t)
+	checksum := "sha256:1"
+	checksumCalls := 0
+	cache.RepoChecksum = func(repo rpmmd.RepoConfig) (string, error) {
+		checksumCalls++
+		return checksum, nil
+	}
+	depsolver := &fakeDepsolver{}
+
+	_, err := cache.Depsolver(depsolver, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	_, err = cache.Depsolver(depsolver, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 1, depsolver.calls)
+	// the checksums are only determined once per cache
+	assert.Equal(t, 1, checksumCalls)
+
+	// the metadata of the repository changed in a new run
+	cache.checksums = make(map[string]string)
+	checksum = "sha256:2"
+	_, err = cache.Depsolver(depsolver, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 2, depsolver.calls)
+
+	// a result that can't be checked is not used
+	cache.checksums = make(map[string]string)
+	cache.RepoChecksum = func(repo rpmmd.RepoConfig) (string, error) {
+		return "", fmt.Errorf("network is unreachable")
+	}
+	_, err = cache.Depsolver(depsolver, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 3, depsolver.calls)
+
+	// offline the cached result is used
+	cache.RepoChecksum = nil
+	_, err = cache.Depsolver(depsolver, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 3, depsolver.calls)
+}
+
+func TestCacheDepsolverUncheckableRepo(t *testing.T) {
+	cache, _ := newTestCache(t)
+	cache.RepoChecksum = func(repo rpmmd.RepoConfig) (string, error) {
+		return "", nil
+	}
+	depsolver := &fakeDepsolver{}
+	cached := cache.Depsolver(depsolver, "fedora-39", "x86_64")
+
+	// the result is neither used nor stored
+	for i := 0; i < 2; i++ {
+		_, err := cached.Depsolve(context.Background(), testPkgSets)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, depsolver.calls)
+	entries, err := filepath.Glob(filepath.Join(cache.dir, CacheDepsolve, "*", "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// a result stored offline can't be checked online
+	cache.RepoChecksum = nil
+	_, err = cached.Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	_, err = cached.Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 3, depsolver.calls)
+	cache.RepoChecksum = func(repo rpmmd.RepoConfig) (string, error) {
+		return "sha256:1", nil
+	}
+	_, err = cached.Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	assert.Equal(t, 4, depsolver.calls)
+}
+
+func TestChecksumsMatch(t *testing.T) {
+	tests := []struct {
+		stored  []string
+		current []string
+		match   bool
+	}{
+		{nil, nil, true},
+		{[]string{"sha256:1"}, nil, true},
+		{[]string{"sha256:1"}, []string{"sha256:1"}, true},
+		{[]string{"sha256:1"}, []string{"sha256:2"}, false},
+		{nil, []string{"sha256:1"}, false},
+		{[]string{""}, []string{"sha256:1"}, false},
+		{[]string{"sha256:1"}, []string{"sha256:1", "sha256:2"}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, checksumsMatch(tt.stored, tt.current), "stored %v, current %v", tt.stored, tt.current)
+	}
+}
+
+type countingContainerResolver struct {
+	fakeContainerResolver
+	calls chan string
+}
+
+func (r *countingContainerResolver) ResolveContainers(ctx context.Context, sources []container.SourceSpec) ([]container.Spec, error) {
+	for _, source := range sources {
+		r.calls <- source.Source
+	}
+	return r.fakeContainerResolver.ResolveContainers(ctx, sources)
+}
+
+func TestCacheContainerResolver(t *testing.T) {
+	cache, now := newTestCache(t)
+	resolver := &countingContainerResolver{calls: make(chan string, 10)}
+	cached := cache.ContainerResolver(resolver, "x86_64")
+
+	sources := []container.SourceSpec{
+		{Source: "registry.example.com/app:latest", Name: "app"},
+		{Source: "registry.example.com/db@sha256:" + fmt.Sprintf("%064d", 0), Name: "db"},
+	}
+	specs, err := cached.ResolveContainers(context.Background(), sources)
+	require.NoError(t, err)
+	require.Len(t, specs, 2)
+	assert.Equal(t, "app", specs[0].LocalName)
+	assert.Equal(t, "db", specs[1].LocalName)
+	assert.Len(t, resolver.calls, 2)
+
+	again, err := cached.ResolveContainers(context.Background(), sources)
+	require.NoError(t, err)
+	assert.Equal(t, specs, again)
+	assert.Len(t, resolver.calls, 2)
+
+	// the tag expires, the digest doesn't
+	*now = now.Add(2 * time.Hour)
+	_, err = cached.ResolveContainers(context.Background(), sources)
+	require.NoError(t, err)
+	require.Len(t, resolver.calls, 3)
+	for i := 0; i < 2; i++ {
+		<-resolver.calls
+	}
+	assert.Equal(t, "registry.example.com/app:latest", <-resolver.calls)
+}
+
+type countingCommitResolver struct {
+	calls int
+}
+
+func (r *countingCommitResolver) ResolveCommits(ctx context.Context, sources []ostree.SourceSpec) ([]ostree.CommitSpec, error) {
+	r.calls++
+	commits := make([]ostree.CommitSpec, len(sources))
+	for idx, source := range sources {
+		commits[idx] = ostree.CommitSpec{Ref: source.Ref, URL: source.URL, Checksum: fmt.Sprintf("%064d", r.calls)}
+	}
+	return commits, nil
+}
+
+func TestCacheCommitResolverInvalidate(t *testing.T) {
+	cache, _ := newTestCache(t)
+	resolver := &countingCommitResolver{}
+	cached := cache.CommitResolver(resolver)
+	sources := []ostree.SourceSpec{{URL: "https://example.com/repo", Ref: "fedora/39/x86_64/iot"}}
+
+	commits, err := cached.ResolveCommits(context.Background(), sources)
+	require.NoError(t, err)
+	_, err = cached.ResolveCommits(context.Background(), sources)
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolver.calls)
+
+	// invalidating other kinds keeps the commits
+	require.NoError(t, cache.Invalidate(CacheDepsolve, CacheContainers))
+	_, err = cached.ResolveCommits(context.Background(), sources)
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolver.calls)
+
+	require.NoError(t, cache.Invalidate())
+	again, err := cached.ResolveCommits(context.Background(), sources)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resolver.calls)
+	assert.NotEqual(t, commits, again)
+}
+
+func TestCachePrune(t *testing.T) {
+	cache, now := newTestCache(t)
+	depsolver := &fakeDepsolver{}
+	_, err := cache.Depsolver(depsolver, "fedora-39", "x86_64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+	*now = now.Add(30 * time.Minute)
+	_, err = cache.Depsolver(depsolver, "fedora-39", "aarch64").Depsolve(context.Background(), testPkgSets)
+	require.NoError(t, err)
+
+	*now = now.Add(45 * time.Minute)
+	require.NoError(t, cache.Prune())
+
+	entries, err := filepath.Glob(filepath.Join(cache.dir, CacheDepsolve, "*", "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// corrupted entries are removed too
+	require.NoError(t, os.WriteFile(entries[0], []byte("{"), 0644))
+	require.NoError(t, cache.Prune())
+	entries, err = filepath.Glob(filepath.Join(cache.dir, CacheDepsolve, "*", "*.json"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRepomdChecksum(t *testing.T) {
+	repomd := "<repomd>1</repomd>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repo/repodata/repomd.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, repomd)
+	}))
+	defer server.Close()
+
+	repo := rpmmd.RepoConfig{BaseURLs: []string{server.URL + "/repo/"}}
+	first, err := RepomdChecksum(repo)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:86c44cced9476ed4f8889ed288d487252ab499a6780ddff3b2eb181d371e9660", first)
+
+	repomd = "<repomd>2</repomd>"
+	second, err := RepomdChecksum(repo)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	_, err = RepomdChecksum(rpmmd.RepoConfig{BaseURLs: []string{server.URL + "/missing"}})
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	checksum, err := RepomdChecksum(rpmmd.RepoConfig{Metalink: "https://example.com/metalink", RHSM: true})
+	require.NoError(t, err)
+	assert.Equal(t, "", checksum)
+}
+
+func TestRepomdChecksumMetalinkMirrorlist(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metalink":
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<metalink version="3.0" xmlns="http://www.metalinker.org/" xmlns:mm0="http://fedorahosted.org/mirrormanager">
+ <files>
+  <file name="repomd.xml">
+   <mm0:timestamp>1698825600</mm0:timestamp>
+   <size>4411</size>
+   <verification>
+    <hash type="md5">0123456789abcdef0123456789abcdef</hash>
+    <hash type="sha256">86c44cced9476ed4f8889ed288d487252ab499a6780ddff3b2eb181d371e9660</hash>
+   </verification>
+   <mm0:alternates>
+    <mm0:alternate>
+     <mm0:timestamp>1698739200</mm0:timestamp>
+     <verification>
+      <hash type="sha256">0000000000000000000000000000000000000000000000000000000000000000</hash>
+     </verification>
+    </mm0:alternate>
+   </mm0:alternates>
+   <resources maxconnections="1">
+    <url protocol="https" type="https" location="US" preference="100">https://mirror.example.com/repo/repodata/repomd.xml</url>
+   </resources>
+  </file>
+ </files>
+</metalink>`)
+		case "/metalink-md5":
+			fmt.Fprint(w, `<metalink><files><file name="repomd.xml"><verification><hash type="md5">0123456789abcdef0123456789abcdef</hash></verification></file></files></metalink>`)
+		case "/mirrorlist":
+			fmt.Fprintf(w, "# mirrors of the repository\n\n%s/repo/\n%s/other/\n", server.URL, server.URL)
+		case "/repo/repodata/repomd.xml":
+			fmt.Fprint(w, "<repomd>1</repomd>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	// both are the checksum of the same repomd.xml
+	expected := "sha256:86c44cced9476ed4f8889ed288d487252ab499a6780ddff3b2eb181d371e9660"
+	checksum, err := RepomdChecksum(rpmmd.RepoConfig{Metalink: server.URL + "/metalink"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, checksum)
+
+	checksum, err = RepomdChecksum(rpmmd.RepoConfig{MirrorList: server.URL + "/mirrorlist"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, checksum)
+
+	_, err = RepomdChecksum(rpmmd.RepoConfig{Metalink: server.URL + "/metalink-md5"})
+	assert.ErrorContains(t, err, "has no sha256 checksum of repomd.xml")
+
+	_, err = RepomdChecksum(rpmmd.RepoConfig{MirrorList: server.URL + "/missing"})
+	assert.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestCacheWrap(t *testing.T) {
+	var noCache *Cache
+	opts := Options{Depsolver: &fakeDepsolver{}}
+	assert.Equal(t, opts, noCache.Wrap(opts, "fedora-39", "x86_64"))
+
+	cache, _ := newTestCache(t)
+	depsolver := &fakeDepsolver{}
+	wrapped := cache.Wrap(Options{Depsolver: depsolver, ContainerResolver: &fakeContainerResolver{}}, "fedora-39", "x86_64")
+	assert.IsType(t, &cachedDepsolver{}, wrapped.Depsolver)
+	assert.IsType(t, &cachedContainerResolver{}, wrapped.ContainerResolver)
+	assert.IsType(t, &cachedCommitResolver{}, wrapped.CommitResolver)
+
+	for i := 0; i < 2; i++ {
+		res, err := Resolve(context.Background(), newTestManifest(), wrapped)
+		require.NoError(t, err)
+		assert.Len(t, res.Containers["os"], 1)
+	}
+	assert.Equal(t, 2, depsolver.calls)
+}
@@ -0,0 +1,148 @@
+package container
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/signature"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RegistriesConfDropinDir holds drop-in files that are merged with
+	// /etc/containers/registries.conf
+	RegistriesConfDropinDir = "/etc/containers/registries.conf.d"
+
+	// RegistriesDDir holds the configuration of signature storage
+	RegistriesDDir = "/etc/containers/registries.d"
+)
+
+// RegistriesConf is a drop-in configuration file for
+// containers-registries.conf(5). Unlike sysregistriesv2.V2RegistriesConf,
+// unset options are omitted, so that they don't override the values of the
+// other configuration files.
+type RegistriesConf struct {
+	UnqualifiedSearchRegistries []string                   `toml:"unqualified-search-registries,omitempty"`
+	Registries                  []sysregistriesv2.Registry `toml:"registry,omitempty"`
+	Aliases                     map[string]string          `toml:"aliases,omitempty"`
+}
+
+// Marshal returns the TOML representation of the configuration
+func (c *RegistriesConf) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// IsBlocked returns true if the configuration blocks pulling the image with
+// the given name. Like in containers-registries.conf(5), the registry with the
+// longest matching prefix applies.
+func (c *RegistriesConf) IsBlocked(name string) (bool, error) {
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return false, err
+	}
+	ref := named.String()
+
+	var match *sysregistriesv2.Registry
+	for idx := range c.Registries {
+		registry := &c.Registries[idx]
+		if !registryPrefixMatches(registry.Prefix, ref) {
+			continue
+		}
+		if match == nil || len(registry.Prefix) > len(match.Prefix) {
+			match = registry
+		}
+	}
+	return match != nil && match.Blocked, nil
+}
+
+// registryPrefixMatches returns true if the registries.conf prefix, which
+// may start with a "*." wildcard for subdomains, matches the reference.
+func registryPrefixMatches(prefix, ref string) bool {
+	if strings.HasPrefix(prefix, "*.") {
+		host := strings.SplitN(ref, "/", 2)[0]
+		return strings.HasSuffix(host, prefix[1:])
+	}
+	if !strings.HasPrefix(ref, prefix) {
+		return false
+	}
+	if len(ref) == len(prefix) {
+		return true
+	}
+	switch ref[len(prefix)] {
+	case '/', ':', '@':
+		return true
+	}
+	return false
+}
+
+// RegistriesDConfig is a configuration file for containers-registries.d(5)
+type RegistriesDConfig struct {
+	Docker map[string]RegistriesDNamespace `yaml:"docker,omitempty"`
+}
+
+// RegistriesDNamespace configures the signature storage of a registry,
+// namespace or repository
+type RegistriesDNamespace struct {
+	UseSigstoreAttachments *bool `yaml:"use-sigstore-attachments,omitempty"`
+}
+
+// Marshal returns the YAML representation of the configuration
+func (c *RegistriesDConfig) Marshal() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// PolicyRejects returns true if the policy rejects all images with the
+// given name, independent of their signatures.
+func PolicyRejects(policy *signature.Policy, name string) (bool, error) {
+	ref, err := docker.ParseReference("//" + name)
+	if err != nil {
+		return false, err
+	}
+
+	requirements := policy.Default
+	if scopes, ok := policy.Transports[docker.Transport.Name()]; ok {
+		// the most specific scope applies, as in containers-policy.json(5)
+		candidates := append([]string{ref.PolicyConfigurationIdentity()}, ref.PolicyConfigurationNamespaces()...)
+		candidates = append(candidates, "")
+		for _, scope := range candidates {
+			if reqs, ok := scopes[scope]; ok {
+				requirements = reqs
+				break
+			}
+		}
+	}
+
+	for _, req := range requirements {
+		if reflect.DeepEqual(req, signature.NewPRReject()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NewTrustRequirement returns the policy requirement for a trust rule type:
+// "accept", "reject", "gpg" or "sigstore". The last two require the path of
+// the public key.
+func NewTrustRequirement(ruleType, keyPath string) (signature.PolicyRequirement, error) {
+	switch ruleType {
+	case "accept":
+		return signature.NewPRInsecureAcceptAnything(), nil
+	case "reject":
+		return signature.NewPRReject(), nil
+	case "gpg":
+		return signature.NewPRSignedByKeyPath(signature.SBKeyTypeGPGKeys, keyPath, signature.NewPRMMatchRepoDigestOrExact())
+	case "sigstore":
+		return signature.NewPRSigstoreSignedKeyPath(keyPath, signature.NewPRMMatchRepoDigestOrExact())
+	}
+	return nil, fmt.Errorf("unknown trust rule type %q", ruleType)
+}
@@ -0,0 +1,139 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+)
+
+func TestRegistriesConfMarshal(t *testing.T) {
+	conf := &RegistriesConf{
+		UnqualifiedSearchRegistries: []string{"registry.example.com"},
+		Registries: []sysregistriesv2.Registry{
+			{
+				Prefix: "docker.io",
+				Mirrors: []sysregistriesv2.Endpoint{
+					{Location: "mirror.example.com/docker", Insecure: true},
+				},
+			},
+			{
+				Prefix:  "evil.example.com",
+				Blocked: true,
+			},
+		},
+		Aliases: map[string]string{
+			"ubi9": "registry.access.redhat.com/ubi9",
+		},
+	}
+	data, err := conf.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, `unqualified-search-registries = ["registry.example.com"]
+
+[[registry]]
+  prefix = "docker.io"
+
+  [[registry.mirror]]
+    location = "mirror.example.com/docker"
+    insecure = true
+
+[[registry]]
+  prefix = "evil.example.com"
+  blocked = true
+
+[aliases]
+  ubi9 = "registry.access.redhat.com/ubi9"
+`, string(data))
+
+	// empty options are not written, so they don't override other files
+	data, err = (&RegistriesConf{}).Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, "", string(data))
+}
+
+func TestRegistriesConfIsBlocked(t *testing.T) {
+	conf := &RegistriesConf{
+		Registries: []sysregistriesv2.Registry{
+			{Prefix: "quay.io", Blocked: true},
+			{Prefix: "quay.io/fedora"},
+			{Prefix: "*.example.com", Blocked: true},
+			{Prefix: "docker.io/library/busybox", Blocked: true},
+		},
+	}
+	for name, blocked := range map[string]bool{
+		"quay.io/centos/centos:stream9":          true,
+		"quay.io/fedora/fedora:39":               false,
+		"quay.io/fedora-other/fedora:39":         true,
+		"registry.example.com/app":               true,
+		"example.com/app":                        false,
+		"busybox":                                true,
+		"busybox:latest":                         true,
+		"docker.io/library/busyboxplus":          false,
+		"registry.access.redhat.com/ubi9:latest": false,
+	} {
+		got, err := conf.IsBlocked(name)
+		require.NoError(t, err)
+		assert.Equal(t, blocked, got, name)
+	}
+
+	_, err := conf.IsBlocked("Invalid Name")
+	assert.Error(t, err)
+}
+
+func TestRegistriesDConfigMarshal(t *testing.T) {
+	conf := &RegistriesDConfig{
+		Docker: map[string]RegistriesDNamespace{
+			"quay.io/fedora": {UseSigstoreAttachments: common.ToPtr(true)},
+		},
+	}
+	data, err := conf.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, "docker:\n    quay.io/fedora:\n        use-sigstore-attachments: true\n", string(data))
+}
+
+func TestPolicyRejects(t *testing.T) {
+	sigstore, err := NewTrustRequirement("sigstore", "/etc/pki/containers/fedora.pub")
+	require.NoError(t, err)
+	policy := &signature.Policy{
+		Default: signature.PolicyRequirements{signature.NewPRReject()},
+		Transports: map[string]signature.PolicyTransportScopes{
+			"docker": {
+				"quay.io/fedora":                  {sigstore},
+				"registry.access.redhat.com":      {signature.NewPRInsecureAcceptAnything()},
+				"registry.access.redhat.com/ubi8": {signature.NewPRReject()},
+				"*.example.com":                   {signature.NewPRInsecureAcceptAnything()},
+			},
+		},
+	}
+	for name, rejected := range map[string]bool{
+		"quay.io/fedora/fedora:39":                    false,
+		"quay.io/centos/centos:stream9":               true,
+		"registry.access.redhat.com/ubi9":             false,
+		"registry.access.redhat.com/ubi8/ubi-minimal": true,
+		"registry.example.com/app":                    false,
+	} {
+		got, err := PolicyRejects(policy, name)
+		require.NoError(t, err)
+		assert.Equal(t, rejected, got, name)
+	}
+}
+
+func TestNewTrustRequirement(t *testing.T) {
+	req, err := NewTrustRequirement("accept", "")
+	require.NoError(t, err)
+	assert.Equal(t, signature.NewPRInsecureAcceptAnything(), req)
+
+	req, err = NewTrustRequirement("gpg", "/etc/pki/rpm-gpg/RPM-GPG-KEY-redhat-release")
+	require.NoError(t, err)
+	assert.NotNil(t, req)
+
+	_, err = NewTrustRequirement("sigstore", "")
+	assert.Error(t, err)
+
+	_, err = NewTrustRequirement("trust-me", "")
+	assert.EqualError(t, err, `unknown trust rule type "trust-me"`)
+}
@@ -29,8 +29,14 @@ type SourceSpec struct {
 }
 
 func NewResolver(arch string) Resolver {
+	return NewResolverWithContext(context.Background(), arch)
+}
+
+// NewResolverWithContext returns a resolver whose requests to the
+// registries are cancelled with the context.
+func NewResolverWithContext(ctx context.Context, arch string) Resolver {
 	return Resolver{
-		ctx:   context.Background(),
+		ctx:   ctx,
 		queue: make(chan resolveResult, 2),
 		Arch:  arch,
 	}
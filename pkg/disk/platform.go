@@ -0,0 +1,159 @@
+package disk
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/platform"
+)
+
+const (
+	// Size limits of the PReP partition, outside of which the partition is
+	// not reliably found by Open Firmware
+	PRePMinSize = 4 * common.MebiByte
+	PRePMaxSize = 8 * common.MebiByte
+
+	// Partition tables of s390x images for ECKD DASDs must fit the
+	// compatible disk layout, which is stricter than the partition tables
+	// used on FBA DASDs, SCSI and virtio disks
+	S390XECKDMaxPartitions = 3
+)
+
+// ValidateForPlatform checks that the partition table can be booted by the
+// firmware and boot loader of the platform. It is meant to be run on the final
+// partition table, after customizations were applied, as these can add,
+// resize or move partitions.
+func (pt *PartitionTable) ValidateForPlatform(pf platform.Platform) error {
+	if pf == nil {
+		return nil
+	}
+
+	switch pf.GetArch() {
+	case platform.ARCH_PPC64LE:
+		if pf.GetBIOSPlatform() != "" {
+			return pt.validatePReP()
+		}
+	case platform.ARCH_S390X:
+		if pf.GetZiplSupport() {
+			diskType := platform.S390X_DISK_FBA
+			if s390x, ok := pf.(*platform.S390X); ok {
+				diskType = s390x.DiskType
+			}
+			return pt.validateZipl(diskType)
+		}
+	case platform.ARCH_AARCH64:
+		if sbc, ok := pf.(*platform.Aarch64_SBC); ok {
+			return pt.validateUBoot(sbc.Board)
+		}
+	}
+
+	return nil
+}
+
+// validatePReP checks that the partition table has a single, empty PReP
+// partition at the start of the disk that Open Firmware can load the boot
+// loader from.
+func (pt *PartitionTable) validatePReP() error {
+	prepIdx := -1
+	for idx := range pt.Partitions {
+		if !pt.Partitions[idx].IsPReP() {
+			continue
+		}
+		if prepIdx != -1 {
+			return fmt.Errorf("partition table must have exactly one PReP partition")
+		}
+		prepIdx = idx
+	}
+	if prepIdx == -1 {
+		return fmt.Errorf("partition table must have a PReP partition to boot on ppc64le")
+	}
+
+	prep := pt.Partitions[prepIdx]
+	if prepIdx != 0 {
+		return fmt.Errorf("PReP partition must be the first partition, found at position %d", prepIdx+1)
+	}
+	if prep.Size < PRePMinSize || prep.Size > PRePMaxSize {
+		return fmt.Errorf("PReP partition size must be between %d and %d bytes, got %d", PRePMinSize, PRePMaxSize, prep.Size)
+	}
+	if prep.Payload != nil {
+		return fmt.Errorf("PReP partition must not contain a payload")
+	}
+	if pt.Type == "dos" && !prep.Bootable {
+		return fmt.Errorf("PReP partition must be bootable in a dos partition table")
+	}
+
+	return nil
+}
+
+// validateZipl checks that zipl can install the boot record on the partition
+// table and read the kernel and initrd from /boot. The partition table of an
+// ECKD DASD must also fit the compatible disk layout, other disks take any
+// dos or gpt partition table.
+func (pt *PartitionTable) validateZipl(diskType platform.S390XDiskType) error {
+	if diskType == platform.S390X_DISK_ECKD {
+		if pt.Type != "dos" {
+			return fmt.Errorf("s390x images for ECKD DASDs require a dos partition table, got %q", pt.Type)
+		}
+		if len(pt.Partitions) > S390XECKDMaxPartitions {
+			return fmt.Errorf("s390x images for ECKD DASDs support at most %d partitions, got %d", S390XECKDMaxPartitions, len(pt.Partitions))
+		}
+	}
+
+	// zipl maps the blocks of the boot files directly, so /boot (or / if
+	// there is no separate /boot) must be a plain filesystem on a partition
+	// and not inside a LUKS container, an LVM volume group or btrfs.
+	bootMountpoint := "/boot"
+	if pt.FindMountable(bootMountpoint) == nil {
+		bootMountpoint = "/"
+	}
+	for idx := range pt.Partitions {
+		fs, ok := pt.Partitions[idx].Payload.(*Filesystem)
+		if !ok || fs.Mountpoint != bootMountpoint {
+			continue
+		}
+		if fs.Type != "xfs" && fs.Type != "ext4" {
+			return fmt.Errorf("zipl does not support %s filesystems for %s, use xfs or ext4", fs.Type, bootMountpoint)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("zipl requires %s to be a filesystem on a partition", bootMountpoint)
+}
+
+// validateUBoot checks that U-Boot can read the extlinux configuration and
+// the kernel from /boot and, on the Raspberry Pi, that the firmware can find
+// its files on the first partition.
+func (pt *PartitionTable) validateUBoot(board platform.SBCBoard) error {
+	if board == platform.SBC_RPI {
+		// the firmware of the Raspberry Pi 3 and earlier can't read GPT
+		if pt.Type != "dos" {
+			return fmt.Errorf("images for the Raspberry Pi require a dos partition table, got %q", pt.Type)
+		}
+		if len(pt.Partitions) == 0 {
+			return fmt.Errorf("images for the Raspberry Pi require a firmware partition")
+		}
+		fs, ok := pt.Partitions[0].Payload.(*Filesystem)
+		if !ok || fs.Mountpoint != "/boot/efi" || fs.Type != "vfat" {
+			return fmt.Errorf("the first partition of images for the Raspberry Pi must be a vfat filesystem mounted at /boot/efi")
+		}
+	}
+
+	// U-Boot has no support for LUKS, LVM or xfs, so /boot (or / if there
+	// is no separate /boot) must be a plain ext4 or vfat filesystem.
+	bootMountpoint := "/boot"
+	if pt.FindMountable(bootMountpoint) == nil {
+		bootMountpoint = "/"
+	}
+	for idx := range pt.Partitions {
+		fs, ok := pt.Partitions[idx].Payload.(*Filesystem)
+		if !ok || fs.Mountpoint != bootMountpoint {
+			continue
+		}
+		if fs.Type != "ext4" && fs.Type != "vfat" {
+			return fmt.Errorf("%s must be an ext4 or vfat filesystem to boot with U-Boot, got %s", bootMountpoint, fs.Type)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s must be a filesystem on a partition to boot with U-Boot", bootMountpoint)
+}
@@ -0,0 +1,353 @@
+package disk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/platform"
+)
+
+func ppc64lePartitionTable() *PartitionTable {
+	return &PartitionTable{
+		Type: "dos",
+		Partitions: []Partition{
+			{
+				Size:     4 * common.MebiByte,
+				Type:     "41",
+				Bootable: true,
+			},
+			{
+				Size: 1 * common.GibiByte,
+				Payload: &Filesystem{
+					Type:       "xfs",
+					Mountpoint: "/boot",
+				},
+			},
+			{
+				Size: 2 * common.GibiByte,
+				Payload: &Filesystem{
+					Type:       "xfs",
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+}
+
+func s390xPartitionTable() *PartitionTable {
+	return &PartitionTable{
+		Type: "dos",
+		Partitions: []Partition{
+			{
+				Size: 1 * common.GibiByte,
+				Payload: &Filesystem{
+					Type:       "xfs",
+					Mountpoint: "/boot",
+				},
+			},
+			{
+				Size:     2 * common.GibiByte,
+				Bootable: true,
+				Payload: &Filesystem{
+					Type:       "xfs",
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+}
+
+func TestValidateForPlatformPReP(t *testing.T) {
+	pf := &platform.PPC64LE{BIOS: true}
+
+	tests := []struct {
+		name    string
+		modify  func(pt *PartitionTable)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(pt *PartitionTable) {},
+		},
+		{
+			name: "missing",
+			modify: func(pt *PartitionTable) {
+				pt.Partitions = pt.Partitions[1:]
+			},
+			wantErr: "partition table must have a PReP partition to boot on ppc64le",
+		},
+		{
+			name: "duplicate",
+			modify: func(pt *PartitionTable) {
+				pt.Partitions = append([]Partition{pt.Partitions[0]}, pt.Partitions...)
+			},
+			wantErr: "partition table must have exactly one PReP partition",
+		},
+		{
+			name: "not-first",
+			modify: func(pt *PartitionTable) {
+				pt.Partitions[0], pt.Partitions[1] = pt.Partitions[1], pt.Partitions[0]
+			},
+			wantErr: "PReP partition must be the first partition, found at position 2",
+		},
+		{
+			name: "too-large",
+			modify: func(pt *PartitionTable) {
+				pt.Partitions[0].Size = 16 * common.MebiByte
+			},
+			wantErr: "PReP partition size must be between 4194304 and 8388608 bytes, got 16777216",
+		},
+		{
+			name: "payload",
+			modify: func(pt *PartitionTable) {
+				pt.Partitions[0].Payload = &Filesystem{Type: "xfs", Mountpoint: "/prep"}
+			},
+			wantErr: "PReP partition must not contain a payload",
+		},
+		{
+			name: "not-bootable",
+			modify: func(pt *PartitionTable) {
+				pt.Partitions[0].Bootable = false
+			},
+			wantErr: "PReP partition must be bootable in a dos partition table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := ppc64lePartitionTable()
+			tt.modify(pt)
+			err := pt.ValidateForPlatform(pf)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// PReP is only required for Open Firmware
+	pt := ppc64lePartitionTable()
+	pt.Partitions = pt.Partitions[1:]
+	assert.NoError(t, pt.ValidateForPlatform(&platform.PPC64LE{}))
+}
+
+func TestValidateForPlatformZipl(t *testing.T) {
+	fba := &platform.S390X{Zipl: true}
+	eckd := &platform.S390X{Zipl: true, DiskType: platform.S390X_DISK_ECKD}
+
+	addPartitions := func(pt *PartitionTable) {
+		for _, mnt := range []string{"/var", "/home"} {
+			pt.Partitions = append(pt.Partitions, Partition{
+				Size:    1 * common.GibiByte,
+				Payload: &Filesystem{Type: "xfs", Mountpoint: mnt},
+			})
+		}
+	}
+
+	tests := []struct {
+		name     string
+		platform platform.Platform
+		modify   func(pt *PartitionTable)
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			platform: eckd,
+			modify:   func(pt *PartitionTable) {},
+		},
+		{
+			name:     "eckd-gpt",
+			platform: eckd,
+			modify: func(pt *PartitionTable) {
+				pt.Type = "gpt"
+			},
+			wantErr: `s390x images for ECKD DASDs require a dos partition table, got "gpt"`,
+		},
+		{
+			name:     "eckd-too-many-partitions",
+			platform: eckd,
+			modify:   addPartitions,
+			wantErr:  "s390x images for ECKD DASDs support at most 3 partitions, got 4",
+		},
+		{
+			name:     "fba-many-partitions",
+			platform: fba,
+			modify:   addPartitions,
+		},
+		{
+			name:     "fba-gpt-many-partitions",
+			platform: fba,
+			modify: func(pt *PartitionTable) {
+				pt.Type = "gpt"
+				addPartitions(pt)
+			},
+		},
+		{
+			name:     "boot-on-root",
+			platform: fba,
+			modify: func(pt *PartitionTable) {
+				pt.Partitions = pt.Partitions[1:]
+			},
+		},
+		{
+			name:     "boot-btrfs",
+			platform: fba,
+			modify: func(pt *PartitionTable) {
+				pt.Partitions[0].Payload = &Filesystem{Type: "btrfs", Mountpoint: "/boot"}
+			},
+			wantErr: "zipl does not support btrfs filesystems for /boot, use xfs or ext4",
+		},
+		{
+			name:     "boot-on-lvm",
+			platform: eckd,
+			modify: func(pt *PartitionTable) {
+				pt.Partitions = pt.Partitions[1:]
+				pt.Partitions[0].Payload = &LVMVolumeGroup{
+					Name: "rootvg",
+					LogicalVolumes: []LVMLogicalVolume{
+						{
+							Name:    "rootlv",
+							Payload: &Filesystem{Type: "xfs", Mountpoint: "/"},
+						},
+					},
+				}
+			},
+			wantErr: "zipl requires / to be a filesystem on a partition",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := s390xPartitionTable()
+			tt.modify(pt)
+			err := pt.ValidateForPlatform(tt.platform)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func rpiPartitionTable() *PartitionTable {
+	return &PartitionTable{
+		Type: "dos",
+		Partitions: []Partition{
+			{
+				Size:     200 * common.MebiByte,
+				Type:     "06",
+				Bootable: true,
+				Payload: &Filesystem{
+					Type:       "vfat",
+					Mountpoint: "/boot/efi",
+				},
+			},
+			{
+				Size: 500 * common.MebiByte,
+				Payload: &Filesystem{
+					Type:       "ext4",
+					Mountpoint: "/boot",
+				},
+			},
+			{
+				Size: 2 * common.GibiByte,
+				Payload: &Filesystem{
+					Type:       "ext4",
+					Mountpoint: "/",
+				},
+			},
+		},
+	}
+}
+
+func TestValidateForPlatformUBoot(t *testing.T) {
+	tests := []struct {
+		name    string
+		board   platform.SBCBoard
+		modify  func(pt *PartitionTable)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			board:  platform.SBC_RPI,
+			modify: func(pt *PartitionTable) {},
+		},
+		{
+			name:  "rpi-gpt",
+			board: platform.SBC_RPI,
+			modify: func(pt *PartitionTable) {
+				pt.Type = "gpt"
+			},
+			wantErr: `images for the Raspberry Pi require a dos partition table, got "gpt"`,
+		},
+		{
+			name:  "generic-gpt",
+			board: platform.SBC_GENERIC,
+			modify: func(pt *PartitionTable) {
+				pt.Type = "gpt"
+			},
+		},
+		{
+			name:  "rpi-firmware-not-first",
+			board: platform.SBC_RPI,
+			modify: func(pt *PartitionTable) {
+				pt.Partitions[0], pt.Partitions[1] = pt.Partitions[1], pt.Partitions[0]
+			},
+			wantErr: "the first partition of images for the Raspberry Pi must be a vfat filesystem mounted at /boot/efi",
+		},
+		{
+			name:  "boot-on-root",
+			board: platform.SBC_RPI,
+			modify: func(pt *PartitionTable) {
+				pt.Partitions = append(pt.Partitions[:1], pt.Partitions[2:]...)
+			},
+		},
+		{
+			name:  "boot-xfs",
+			board: platform.SBC_GENERIC,
+			modify: func(pt *PartitionTable) {
+				pt.Partitions[1].Payload = &Filesystem{Type: "xfs", Mountpoint: "/boot"}
+			},
+			wantErr: "/boot must be an ext4 or vfat filesystem to boot with U-Boot, got xfs",
+		},
+		{
+			name:  "boot-on-lvm",
+			board: platform.SBC_GENERIC,
+			modify: func(pt *PartitionTable) {
+				pt.Partitions = append(pt.Partitions[:1], pt.Partitions[2:]...)
+				pt.Partitions[1].Payload = &LVMVolumeGroup{
+					Name: "rootvg",
+					LogicalVolumes: []LVMLogicalVolume{
+						{
+							Name:    "rootlv",
+							Payload: &Filesystem{Type: "ext4", Mountpoint: "/"},
+						},
+					},
+				}
+			},
+			wantErr: "/ must be a filesystem on a partition to boot with U-Boot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := rpiPartitionTable()
+			tt.modify(pt)
+			err := pt.ValidateForPlatform(&platform.Aarch64_SBC{Board: tt.board})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// UEFI boards don't depend on the filesystem of /boot
+	pt := rpiPartitionTable()
+	pt.Partitions[1].Payload = &Filesystem{Type: "xfs", Mountpoint: "/boot"}
+	assert.NoError(t, pt.ValidateForPlatform(&platform.Aarch64{UEFIVendor: "fedora"}))
+}
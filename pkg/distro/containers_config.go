@@ -0,0 +1,192 @@
+package distro
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/signature"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/container"
+)
+
+// containersConfigFiles returns the configuration files of the containers
+// tools in the image for the containers customization:
+//   - a registries.conf drop-in for the search registries, short name
+//     aliases and registries
+//   - the signature policy, which replaces /etc/containers/policy.json of
+//     the distribution
+//   - a registries.d file that enables sigstore attachments for the scopes
+//     of sigstore trust rules
+func containersConfigFiles(cc *blueprint.ContainersCustomization) ([]*fsnode.File, error) {
+	var files []*fsnode.File
+
+	registriesConf := newRegistriesConf(cc)
+	if len(registriesConf.UnqualifiedSearchRegistries) > 0 || len(registriesConf.Registries) > 0 || len(registriesConf.Aliases) > 0 {
+		data, err := registriesConf.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		file, err := fsnode.NewFile(filepath.Join(container.RegistriesConfDropinDir, BlueprintDropinFilename), nil, nil, nil, data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if cc.Policy != nil {
+		policy, err := newContainersPolicy(cc.Policy)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(policy, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		file, err := fsnode.NewFile(container.DefaultPolicyPath, nil, nil, nil, append(data, '\n'))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+
+		registriesD := container.RegistriesDConfig{}
+		for _, rule := range cc.Policy.Rules {
+			if rule.Type != "sigstore" {
+				continue
+			}
+			if registriesD.Docker == nil {
+				registriesD.Docker = make(map[string]container.RegistriesDNamespace)
+			}
+			registriesD.Docker[rule.Scope] = container.RegistriesDNamespace{
+				UseSigstoreAttachments: common.ToPtr(true),
+			}
+		}
+		if len(registriesD.Docker) > 0 {
+			data, err := registriesD.Marshal()
+			if err != nil {
+				return nil, err
+			}
+			file, err := fsnode.NewFile(filepath.Join(container.RegistriesDDir, "zz-blueprint.yaml"), nil, nil, nil, data)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+func newRegistriesConf(cc *blueprint.ContainersCustomization) *container.RegistriesConf {
+	conf := &container.RegistriesConf{
+		UnqualifiedSearchRegistries: cc.UnqualifiedSearchRegistries,
+		Aliases:                     cc.ShortNameAliases,
+	}
+	for _, r := range cc.Registries {
+		registry := sysregistriesv2.Registry{
+			Prefix: r.Prefix,
+			Endpoint: sysregistriesv2.Endpoint{
+				Location: r.Location,
+				Insecure: r.Insecure,
+			},
+			Blocked: r.Blocked,
+		}
+		for _, m := range r.Mirrors {
+			registry.Mirrors = append(registry.Mirrors, sysregistriesv2.Endpoint{
+				Location: m.Location,
+				Insecure: m.Insecure,
+			})
+		}
+		conf.Registries = append(conf.Registries, registry)
+	}
+	return conf
+}
+
+// localContainerTransports are the transports of images that are not pulled
+// from a registry and don't have signatures
+var localContainerTransports = []string{
+	"containers-storage",
+	"dir",
+	"docker-archive",
+	"docker-daemon",
+	"oci",
+	"oci-archive",
+}
+
+// newContainersPolicy returns the signature policy for the validated policy
+// customization. Images from the local transports, e.g. archives, are always
+// accepted, the default only applies to images pulled from registries.
+func newContainersPolicy(p *blueprint.ContainerPolicyCustomization) (*signature.Policy, error) {
+	defaultReq, err := container.NewTrustRequirement(p.Default, "")
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &signature.Policy{
+		Default:    signature.PolicyRequirements{defaultReq},
+		Transports: make(map[string]signature.PolicyTransportScopes),
+	}
+	for _, transport := range localContainerTransports {
+		policy.Transports[transport] = signature.PolicyTransportScopes{
+			"": signature.PolicyRequirements{signature.NewPRInsecureAcceptAnything()},
+		}
+	}
+
+	if len(p.Rules) > 0 {
+		scopes := make(signature.PolicyTransportScopes)
+		for _, rule := range p.Rules {
+			req, err := container.NewTrustRequirement(rule.Type, rule.KeyPath)
+			if err != nil {
+				return nil, err
+			}
+			scopes[rule.Scope] = signature.PolicyRequirements{req}
+		}
+		policy.Transports[docker.Transport.Name()] = scopes
+	}
+	return policy, nil
+}
+
+// CheckEmbeddedContainers returns an error if the containers configuration of
+// the customizations doesn't allow the containers to be pulled, so that the
+// containers embedded in the image follow the same rules as the ones pulled
+// on the running system.
+func CheckEmbeddedContainers(customizations *blueprint.Customizations, containers []blueprint.Container) error {
+	cc, err := customizations.GetContainers()
+	if err != nil || cc == nil {
+		return err
+	}
+
+	registriesConf := newRegistriesConf(cc)
+	var policy *signature.Policy
+	if cc.Policy != nil {
+		if policy, err = newContainersPolicy(cc.Policy); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range containers {
+		blocked, err := registriesConf.IsBlocked(c.Source)
+		if err != nil {
+			return fmt.Errorf("invalid container source %q: %v", c.Source, err)
+		}
+		if blocked {
+			return fmt.Errorf("embedded container %q is from a blocked registry", c.Source)
+		}
+		if policy == nil {
+			continue
+		}
+		rejected, err := container.PolicyRejects(policy, c.Source)
+		if err != nil {
+			return fmt.Errorf("invalid container source %q: %v", c.Source, err)
+		}
+		if rejected {
+			return fmt.Errorf("embedded container %q is rejected by the container signature policy", c.Source)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/containers/image/v5/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+)
+
+func TestContainersConfigFiles(t *testing.T) {
+	cc := &blueprint.ContainersCustomization{
+		Registries: []blueprint.ContainerRegistryCustomization{
+			{Prefix: "evil.example.com", Blocked: true},
+		},
+		Policy: &blueprint.ContainerPolicyCustomization{
+			Default: "reject",
+			Rules: []blueprint.ContainerTrustRuleCustomization{
+				{Scope: "quay.io/fedora", Type: "sigstore", KeyPath: "/etc/pki/containers/fedora.pub"},
+			},
+		},
+	}
+	files, err := containersConfigFiles(cc)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	assert.Equal(t, "/etc/containers/registries.conf.d/zz-blueprint.conf", files[0].Path())
+	assert.Equal(t, "[[registry]]\n  prefix = \"evil.example.com\"\n  blocked = true\n", string(files[0].Data()))
+
+	assert.Equal(t, "/etc/containers/policy.json", files[1].Path())
+	assert.JSONEq(t, `{
+		"default": [{"type": "reject"}],
+		"transports": {
+			"docker": {
+				"quay.io/fedora": [{
+					"type": "sigstoreSigned",
+					"keyPath": "/etc/pki/containers/fedora.pub",
+					"signedIdentity": {"type": "matchRepoDigestOrExact"}
+				}]
+			},
+			"containers-storage": {"": [{"type": "insecureAcceptAnything"}]},
+			"dir": {"": [{"type": "insecureAcceptAnything"}]},
+			"docker-archive": {"": [{"type": "insecureAcceptAnything"}]},
+			"docker-daemon": {"": [{"type": "insecureAcceptAnything"}]},
+			"oci": {"": [{"type": "insecureAcceptAnything"}]},
+			"oci-archive": {"": [{"type": "insecureAcceptAnything"}]}
+		}
+	}`, string(files[1].Data()))
+	_, err = signature.NewPolicyFromBytes(files[1].Data())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "/etc/containers/registries.d/zz-blueprint.yaml", files[2].Path())
+	assert.Equal(t, "docker:\n    quay.io/fedora:\n        use-sigstore-attachments: true\n", string(files[2].Data()))
+}
+
+func TestCheckEmbeddedContainers(t *testing.T) {
+	customizations := &blueprint.Customizations{
+		Containers: &blueprint.ContainersCustomization{
+			Registries: []blueprint.ContainerRegistryCustomization{
+				{Prefix: "evil.example.com", Blocked: true},
+			},
+			Policy: &blueprint.ContainerPolicyCustomization{
+				Default: "reject",
+				Rules: []blueprint.ContainerTrustRuleCustomization{
+					{Scope: "registry.access.redhat.com", Type: "accept"},
+					{Scope: "evil.example.com", Type: "accept"},
+				},
+			},
+		},
+	}
+
+	assert.NoError(t, CheckEmbeddedContainers(nil, []blueprint.Container{{Source: "quay.io/fedora/fedora"}}))
+	assert.NoError(t, CheckEmbeddedContainers(customizations, []blueprint.Container{{Source: "registry.access.redhat.com/ubi9:latest"}}))
+	assert.EqualError(t, CheckEmbeddedContainers(customizations, []blueprint.Container{{Source: "evil.example.com/app"}}),
+		`embedded container "evil.example.com/app" is from a blocked registry`)
+	assert.EqualError(t, CheckEmbeddedContainers(customizations, []blueprint.Container{{Source: "quay.io/fedora/fedora"}}),
+		`embedded container "quay.io/fedora/fedora" is rejected by the container signature policy`)
+}
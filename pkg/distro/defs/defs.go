@@ -0,0 +1,616 @@
+// Package defs loads image type definitions from YAML files.
+//
+// The definitions describe the declarative parts of an image type: its
+// package sets, default image configuration, base partition tables, boot
+// mode, exports and output file. The code that turns an image type into an
+// image kind is still provided by the distribution and referred to by name,
+// as are the environments and workloads of image types; ImageTypes resolves
+// these names for the architectures of a distribution. The package doesn't
+// depend on the image type of any distribution, each distribution converts
+// the resolved definitions into its own image types.
+//
+// All image types of the rhel8 package, which implements RHEL 8 and its
+// rebuilds, and of the rhel9 package, which implements RHEL 9, RHEL 10 and
+// their rebuilds, are defined here, in rhel-8.yaml and rhel-9.yaml. The
+// fedora and rhel7 distributions still define their image types in Go.
+//
+// Most parts of a definition can be restricted to some distributions,
+// versions and architectures with conditions, see Conditions. Strings in
+// package names and image configurations can refer to the release version
+// of the distribution as ${releasever} and, in image configurations, to the
+// OS version as ${os_version}.
+package defs
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+//go:embed *.yaml
+var data embed.FS
+
+// Distro is the distribution release and architecture that conditions in the
+// definitions are evaluated for.
+type Distro struct {
+	// Name of the distribution, e.g. "rhel-94" or "centos-9"
+	Name string
+	// Version of the operating system, e.g. "9.4" or "9-stream"
+	OSVersion string
+	// Release version of the package repositories, e.g. "9"
+	Releasever string
+	// Name of the architecture, e.g. "x86_64"
+	Arch string
+}
+
+// ID returns the name of the distribution without the version, e.g. "rhel".
+func (d Distro) ID() string {
+	id, _, _ := strings.Cut(d.Name, "-")
+	return id
+}
+
+// Definitions of the image types of a distribution.
+type Definitions struct {
+	// Package sets shared between image types, that can be inherited by
+	// name
+	PackageSets map[string]PackageSet `yaml:"package_sets"`
+
+	// Image configurations shared between image types, that can be
+	// inherited by name
+	ImageConfigs map[string]ImageConfig `yaml:"image_configs"`
+
+	// Partition tables shared between image types, by name and architecture
+	PartitionTables map[string]map[string]PartitionTable `yaml:"partition_tables"`
+
+	ImageTypes map[string]ImageType `yaml:"image_types"`
+}
+
+// Conditions restrict a part of a definition to some distributions and
+// architectures. All set conditions must match.
+type Conditions struct {
+	// Distribution IDs, e.g. "rhel" or "centos"
+	Distro []string `yaml:"distro"`
+
+	// Architecture names
+	Arch []string `yaml:"arch"`
+
+	// Release versions, e.g. "9"
+	Releasever []string `yaml:"releasever"`
+
+	// OS versions, e.g. "8.4"
+	Version []string `yaml:"version"`
+
+	// The OS version must be lower than this version
+	VersionLessThan string `yaml:"version_less_than"`
+
+	// The OS version must be at least this version
+	VersionGreaterOrEqual string `yaml:"version_greater_or_equal"`
+
+	// The conditions must not all match
+	Not *Conditions `yaml:"not"`
+}
+
+// Match returns true if the conditions hold for the distribution.
+func (c *Conditions) Match(d Distro) bool {
+	if c == nil {
+		return true
+	}
+	if len(c.Distro) > 0 && !slices.Contains(c.Distro, d.ID()) {
+		return false
+	}
+	if len(c.Arch) > 0 && !slices.Contains(c.Arch, d.Arch) {
+		return false
+	}
+	if len(c.Releasever) > 0 && !slices.Contains(c.Releasever, d.Releasever) {
+		return false
+	}
+	if len(c.Version) > 0 && !slices.Contains(c.Version, d.OSVersion) {
+		return false
+	}
+	if c.VersionLessThan != "" && !common.VersionLessThan(d.OSVersion, c.VersionLessThan) {
+		return false
+	}
+	if c.VersionGreaterOrEqual != "" && common.VersionLessThan(d.OSVersion, c.VersionGreaterOrEqual) {
+		return false
+	}
+	if c.Not != nil && c.Not.Match(d) {
+		return false
+	}
+	return true
+}
+
+func (c *Conditions) validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, arch := range c.Arch {
+		if err := validateArch(arch); err != nil {
+			return err
+		}
+	}
+	for _, v := range []string{c.VersionLessThan, c.VersionGreaterOrEqual} {
+		if v == "" {
+			continue
+		}
+		if _, err := version.NewVersion(v); err != nil {
+			return fmt.Errorf("invalid version %q: %w", v, err)
+		}
+	}
+	return c.Not.validate()
+}
+
+// PackageSet is a list of chunks that are appended in order to build the
+// package set.
+type PackageSet []PackageSetChunk
+
+type PackageSetChunk struct {
+	// The chunk is only used if the conditions match
+	When *Conditions `yaml:"when"`
+
+	// Names of shared package sets to append before the packages of the
+	// chunk
+	Inherit []string `yaml:"inherit"`
+
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+
+	// Remove the excluded packages from the included ones, after the
+	// packages of the chunk are appended
+	ResolveConflicts bool `yaml:"resolve_conflicts"`
+
+	// Packages to remove from the included ones, after the packages of the
+	// chunk are appended and conflicts are resolved
+	Remove []string `yaml:"remove"`
+}
+
+// BootMode of an image type, which determines the boot loader set up on each
+// architecture.
+type BootMode string
+
+const (
+	// The image is not bootable
+	BOOT_NONE BootMode = "none"
+	// BIOS on x86_64, Open Firmware on ppc64le and zipl on s390x
+	BOOT_LEGACY BootMode = "legacy"
+	// UEFI on x86_64 and aarch64
+	BOOT_UEFI BootMode = "uefi"
+	// Both legacy and UEFI boot where the architecture supports them
+	BOOT_HYBRID BootMode = "hybrid"
+)
+
+type ImageType struct {
+	// The image type is only available if the conditions match, on the
+	// architectures in Arches
+	When *Conditions `yaml:"when"`
+
+	// Other names the image type can be requested by
+	NameAliases []string `yaml:"name_aliases"`
+
+	Filename    string `yaml:"filename"`
+	Compression string `yaml:"compression"`
+	MimeType    string `yaml:"mime_type"`
+
+	// Name of the function of the distribution that creates the image kind
+	Image string `yaml:"image"`
+
+	// Names of the environment and the workload of the distribution the
+	// image is built for, if any
+	Environment string `yaml:"environment"`
+	Workload    string `yaml:"workload"`
+
+	// Architectures the image type is available on
+	Arches []string `yaml:"arches"`
+
+	BootMode    BootMode `yaml:"boot_mode"`
+	ImageFormat string   `yaml:"image_format"`
+	QCOW2Compat string   `yaml:"qcow2_compat"`
+	// Firmware packages of the platform
+	FirmwarePackages []string `yaml:"firmware_packages"`
+
+	Bootable bool `yaml:"bootable"`
+	BootISO  bool `yaml:"boot_iso"`
+	// The image contains an OSTree commit or deployment
+	RPMOSTree bool `yaml:"rpm_ostree"`
+	// Default size with a unit, e.g. "4 GiB"
+	DefaultSize   string `yaml:"default_size"`
+	KernelOptions string `yaml:"kernel_options"`
+
+	BuildPipelines   []string `yaml:"build_pipelines"`
+	PayloadPipelines []string `yaml:"payload_pipelines"`
+	Exports          []string `yaml:"exports"`
+
+	PackageSets map[string]PackageSet `yaml:"package_sets"`
+	ImageConfig ImageConfig           `yaml:"image_config"`
+
+	// Name of the base partition table in the shared partition tables
+	PartitionTable string `yaml:"partition_table"`
+
+	// Overrides of the image type, applied in order if their conditions
+	// match
+	Overrides []ImageTypeOverride `yaml:"overrides"`
+}
+
+// ImageTypeOverride replaces the set values of an image type for some
+// distributions or architectures.
+type ImageTypeOverride struct {
+	When *Conditions `yaml:"when"`
+
+	BootMode         BootMode `yaml:"boot_mode"`
+	ImageFormat      string   `yaml:"image_format"`
+	FirmwarePackages []string `yaml:"firmware_packages"`
+	KernelOptions    string   `yaml:"kernel_options"`
+	PartitionTable   string   `yaml:"partition_table"`
+}
+
+var (
+	loadMutex sync.Mutex
+	loaded    = map[string]*Definitions{}
+)
+
+// Load the embedded definitions of a distribution, e.g. "rhel-9". The
+// definitions are loaded once and shared by all callers, they must not be
+// modified.
+func Load(name string) (*Definitions, error) {
+	loadMutex.Lock()
+	defer loadMutex.Unlock()
+
+	if defs, ok := loaded[name]; ok {
+		return defs, nil
+	}
+	buf, err := data.ReadFile(name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("no image type definitions for %s: %w", name, err)
+	}
+	defs, err := Parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image type definitions for %s: %w", name, err)
+	}
+	loaded[name] = defs
+	return defs, nil
+}
+
+// Parse and validate definitions. Unknown keys are an error.
+func Parse(r io.Reader) (*Definitions, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var defs Definitions
+	if err := dec.Decode(&defs); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := defs.Validate(); err != nil {
+		return nil, err
+	}
+	return &defs, nil
+}
+
+// Validate checks that the definitions are complete and consistent.
+func (d *Definitions) Validate() error {
+	for _, name := range sortedKeys(d.PackageSets) {
+		if err := d.validatePackageSet(d.PackageSets[name], []string{name}); err != nil {
+			return fmt.Errorf("package set %q: %w", name, err)
+		}
+	}
+
+	for _, name := range sortedKeys(d.ImageConfigs) {
+		if err := d.validateImageConfig(d.ImageConfigs[name], []string{name}); err != nil {
+			return fmt.Errorf("image config %q: %w", name, err)
+		}
+	}
+
+	for _, name := range sortedKeys(d.PartitionTables) {
+		for _, arch := range sortedKeys(d.PartitionTables[name]) {
+			if err := validateArch(arch); err != nil {
+				return fmt.Errorf("partition table %q: %w", name, err)
+			}
+			if err := d.PartitionTables[name][arch].validate(); err != nil {
+				return fmt.Errorf("partition table %q for %s: %w", name, arch, err)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(d.ImageTypes) {
+		if err := d.validateImageType(d.ImageTypes[name]); err != nil {
+			return fmt.Errorf("image type %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Definitions) validatePackageSet(ps PackageSet, path []string) error {
+	for _, chunk := range ps {
+		if err := chunk.When.validate(); err != nil {
+			return err
+		}
+		for _, name := range chunk.Inherit {
+			inherited, ok := d.PackageSets[name]
+			if !ok {
+				return fmt.Errorf("inherits unknown package set %q", name)
+			}
+			if slices.Contains(path, name) {
+				return fmt.Errorf("package set %q inherits itself", name)
+			}
+			if err := d.validatePackageSet(inherited, append(append([]string{}, path...), name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Definitions) validateImageType(it ImageType) error {
+	if it.Filename == "" {
+		return fmt.Errorf("filename is required")
+	}
+	if it.Image == "" {
+		return fmt.Errorf("image is required")
+	}
+	if len(it.Arches) == 0 {
+		return fmt.Errorf("at least one architecture is required")
+	}
+	if len(it.Exports) == 0 {
+		return fmt.Errorf("at least one export is required")
+	}
+	for _, export := range it.Exports {
+		if !slices.Contains(it.PayloadPipelines, export) {
+			return fmt.Errorf("export %q is not a payload pipeline", export)
+		}
+	}
+	if it.DefaultSize != "" {
+		if _, err := common.DataSizeToUint64(it.DefaultSize); err != nil {
+			return fmt.Errorf("invalid default size: %w", err)
+		}
+	}
+	if err := it.When.validate(); err != nil {
+		return err
+	}
+
+	// validate the image type with each override on its own, on the
+	// architectures the override applies to
+	if err := d.validateImageTypeVariant(it, it.Arches); err != nil {
+		return err
+	}
+	for _, o := range it.Overrides {
+		if err := o.When.validate(); err != nil {
+			return err
+		}
+		arches := it.Arches
+		if o.When != nil && len(o.When.Arch) > 0 {
+			arches = o.When.Arch
+		}
+		if err := d.validateImageTypeVariant(it.override(o), arches); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedKeys(it.PackageSets) {
+		if err := d.validatePackageSet(it.PackageSets[name], nil); err != nil {
+			return fmt.Errorf("package set %q: %w", name, err)
+		}
+	}
+	if err := d.validateImageConfig(it.ImageConfig, nil); err != nil {
+		return fmt.Errorf("image config: %w", err)
+	}
+	return nil
+}
+
+func (d *Definitions) validateImageTypeVariant(variant ImageType, arches []string) error {
+	if variant.ImageFormat != "" {
+		if _, err := parseImageFormat(variant.ImageFormat); err != nil {
+			return err
+		}
+	}
+	if variant.Bootable && (variant.BootMode == "" || variant.BootMode == BOOT_NONE) {
+		return fmt.Errorf("bootable image types require a boot mode")
+	}
+	for _, arch := range arches {
+		if _, err := variant.Platform(arch, ""); err != nil {
+			return err
+		}
+		if variant.PartitionTable == "" {
+			continue
+		}
+		tables, ok := d.PartitionTables[variant.PartitionTable]
+		if !ok {
+			return fmt.Errorf("unknown partition table %q", variant.PartitionTable)
+		}
+		if _, ok := tables[arch]; !ok {
+			return fmt.Errorf("partition table %q has no table for %s", variant.PartitionTable, arch)
+		}
+	}
+	return nil
+}
+
+// ForDistro returns the image type with the overrides that match the
+// distribution applied.
+func (it ImageType) ForDistro(target Distro) ImageType {
+	result := it
+	for _, o := range it.Overrides {
+		if o.When.Match(target) {
+			result = result.override(o)
+		}
+	}
+	result.Overrides = nil
+	return result
+}
+
+func (it ImageType) override(o ImageTypeOverride) ImageType {
+	if o.BootMode != "" {
+		it.BootMode = o.BootMode
+	}
+	if o.ImageFormat != "" {
+		it.ImageFormat = o.ImageFormat
+	}
+	if o.FirmwarePackages != nil {
+		it.FirmwarePackages = o.FirmwarePackages
+	}
+	if o.KernelOptions != "" {
+		it.KernelOptions = o.KernelOptions
+	}
+	if o.PartitionTable != "" {
+		it.PartitionTable = o.PartitionTable
+	}
+	return it
+}
+
+// Size returns the default size of the image type in bytes.
+func (it *ImageType) Size() uint64 {
+	if it.DefaultSize == "" {
+		return 0
+	}
+	size, err := common.DataSizeToUint64(it.DefaultSize)
+	if err != nil {
+		// the definitions are validated when they are loaded
+		panic(err)
+	}
+	return size
+}
+
+// Platform returns the platform of the image type on the architecture.
+// uefiVendor is the vendor of the UEFI boot loader of the distribution.
+func (it *ImageType) Platform(arch, uefiVendor string) (platform.Platform, error) {
+	var format platform.ImageFormat
+	if it.ImageFormat != "" {
+		var err error
+		if format, err = parseImageFormat(it.ImageFormat); err != nil {
+			return nil, err
+		}
+	}
+	base := platform.BasePlatform{
+		ImageFormat:      format,
+		QCOW2Compat:      it.QCOW2Compat,
+		FirmwarePackages: it.FirmwarePackages,
+	}
+
+	legacy := it.BootMode == BOOT_LEGACY || it.BootMode == BOOT_HYBRID
+	uefi := it.BootMode == BOOT_UEFI || it.BootMode == BOOT_HYBRID
+	if !uefi {
+		uefiVendor = ""
+	}
+
+	switch it.BootMode {
+	case "", BOOT_NONE, BOOT_LEGACY, BOOT_UEFI, BOOT_HYBRID:
+	default:
+		return nil, fmt.Errorf("unknown boot mode %q", it.BootMode)
+	}
+
+	switch arch {
+	case platform.ARCH_X86_64.String():
+		return &platform.X86{BasePlatform: base, BIOS: legacy, UEFIVendor: uefiVendor}, nil
+	case platform.ARCH_AARCH64.String():
+		if it.BootMode == BOOT_LEGACY {
+			return nil, fmt.Errorf("boot mode %q is not supported on %s", it.BootMode, arch)
+		}
+		return &platform.Aarch64{BasePlatform: base, UEFIVendor: uefiVendor}, nil
+	case platform.ARCH_PPC64LE.String():
+		if it.BootMode == BOOT_UEFI {
+			return nil, fmt.Errorf("boot mode %q is not supported on %s", it.BootMode, arch)
+		}
+		return &platform.PPC64LE{BasePlatform: base, BIOS: legacy}, nil
+	case platform.ARCH_S390X.String():
+		if it.BootMode == BOOT_UEFI {
+			return nil, fmt.Errorf("boot mode %q is not supported on %s", it.BootMode, arch)
+		}
+		return &platform.S390X{BasePlatform: base, Zipl: legacy}, nil
+	default:
+		return nil, validateArch(arch)
+	}
+}
+
+// PackageSet evaluates the chunks of a package set for the distribution.
+func (d *Definitions) PackageSet(ps PackageSet, target Distro) rpmmd.PackageSet {
+	var result rpmmd.PackageSet
+	for _, chunk := range ps {
+		if !chunk.When.Match(target) {
+			continue
+		}
+		for _, name := range chunk.Inherit {
+			result = result.Append(d.PackageSet(d.PackageSets[name], target))
+		}
+		include := make([]string, 0, len(chunk.Include))
+		for _, pkg := range chunk.Include {
+			include = append(include, strings.ReplaceAll(pkg, "${releasever}", target.Releasever))
+		}
+		result = result.Append(rpmmd.PackageSet{
+			Include: include,
+			Exclude: chunk.Exclude,
+		})
+		if chunk.ResolveConflicts {
+			result = result.ResolveConflictsExclude()
+		}
+		if len(chunk.Remove) > 0 {
+			kept := make([]string, 0, len(result.Include))
+			for _, pkg := range result.Include {
+				if !slices.Contains(chunk.Remove, pkg) {
+					kept = append(kept, pkg)
+				}
+			}
+			result.Include = kept
+		}
+	}
+	return result
+}
+
+// SharedPackageSet evaluates the shared package set with the given name for
+// the distribution.
+func (d *Definitions) SharedPackageSet(name string, target Distro) rpmmd.PackageSet {
+	ps, ok := d.PackageSets[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown shared package set %q", name))
+	}
+	return d.PackageSet(ps, target)
+}
+
+func parseImageFormat(name string) (platform.ImageFormat, error) {
+	for _, format := range []platform.ImageFormat{
+		platform.FORMAT_RAW,
+		platform.FORMAT_ISO,
+		platform.FORMAT_QCOW2,
+		platform.FORMAT_VMDK,
+		platform.FORMAT_VHD,
+		platform.FORMAT_GCE,
+		platform.FORMAT_OVA,
+	} {
+		if format.String() == name {
+			return format, nil
+		}
+	}
+	return platform.FORMAT_UNSET, fmt.Errorf("unknown image format %q", name)
+}
+
+func validateArch(name string) error {
+	for _, arch := range []platform.Arch{
+		platform.ARCH_AARCH64,
+		platform.ARCH_PPC64LE,
+		platform.ARCH_S390X,
+		platform.ARCH_X86_64,
+	} {
+		if arch.String() == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown architecture %q", name)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,331 @@
+package defs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/platform"
+)
+
+const testDefinitions = `
+package_sets:
+  base:
+    - include: [bash, systemd]
+    - when:
+        distro: [rhel]
+      include: [subscription-manager]
+  cloud:
+    - inherit: [base]
+      include: [cloud-init]
+
+partition_tables:
+  plain:
+    x86_64:
+      uuid: D209C89E-EA5E-4FBD-B161-B461CCE297E0
+      type: gpt
+      partitions:
+        - size: 1 MiB
+          bootable: true
+          type: 21686148-6449-6E6F-744E-656564454649
+        - size: 2 GiB
+          filesystem:
+            type: xfs
+            label: root
+            mountpoint: /
+            fstab_options: defaults
+    aarch64:
+      when:
+        distro: [rhel]
+      type: gpt
+      partitions:
+        - size: 2 GiB
+          filesystem:
+            type: xfs
+            mountpoint: /
+
+image_configs:
+  base:
+    - locale: en_US.UTF-8
+      timezone: UTC
+    - when:
+        distro: [rhel]
+      gpg_key_files: [/etc/pki/rpm-gpg/RPM-GPG-KEY-redhat-release]
+
+image_types:
+  cloud-qcow2:
+    filename: disk.qcow2
+    mime_type: application/x-qemu-disk
+    image: disk
+    arches: [x86_64, aarch64]
+    boot_mode: hybrid
+    image_format: qcow2
+    bootable: true
+    default_size: 4 GiB
+    build_pipelines: [build]
+    payload_pipelines: [os, image, qcow2]
+    exports: [qcow2]
+    partition_table: plain
+    image_config:
+      - inherit: [base]
+        timezone: America/New_York
+      - when:
+          version_less_than: "9.2"
+        default_target: multi-user.target
+        dnf_config:
+          - variables:
+              - name: releasever
+                value: ${os_version}
+    overrides:
+      - when:
+          arch: [aarch64]
+        boot_mode: uefi
+        kernel_options: console=ttyAMA0
+    package_sets:
+      os:
+        - inherit: [cloud]
+          exclude: [rng-tools]
+        - when:
+            arch: [x86_64]
+            version_greater_or_equal: "9.2"
+          include: [microcode_ctl]
+`
+
+func TestParse(t *testing.T) {
+	defs, err := Parse(strings.NewReader(testDefinitions))
+	require.NoError(t, err)
+
+	it := defs.ImageTypes["cloud-qcow2"]
+	assert.Equal(t, uint64(4*common.GibiByte), it.Size())
+
+	rhel := Distro{Name: "rhel-94", OSVersion: "9.4", Releasever: "9", Arch: "x86_64"}
+	ps := defs.PackageSet(it.PackageSets["os"], rhel)
+	assert.Equal(t, []string{"bash", "systemd", "subscription-manager", "cloud-init", "microcode_ctl"}, ps.Include)
+	assert.Equal(t, []string{"rng-tools"}, ps.Exclude)
+
+	centos := Distro{Name: "centos-9", OSVersion: "9-stream", Arch: "aarch64"}
+	ps = defs.PackageSet(it.PackageSets["os"], centos)
+	assert.Equal(t, []string{"bash", "systemd", "cloud-init"}, ps.Include)
+
+	rhel90 := Distro{Name: "rhel-90", OSVersion: "9.0", Arch: "x86_64"}
+	ps = defs.PackageSet(it.PackageSets["os"], rhel90)
+	assert.NotContains(t, ps.Include, "microcode_ctl")
+
+	pt, ok := defs.BasePartitionTable("plain", rhel)
+	require.True(t, ok)
+	assert.Equal(t, "gpt", pt.Type)
+	require.Len(t, pt.Partitions, 2)
+	assert.Equal(t, uint64(1*common.MebiByte), pt.Partitions[0].Size)
+	assert.Nil(t, pt.Partitions[0].Payload)
+	assert.NotNil(t, pt.FindMountable("/"))
+	// only defined for RHEL on aarch64
+	_, ok = defs.BasePartitionTable("plain", centos)
+	assert.False(t, ok)
+	_, ok = defs.BasePartitionTable("plain", Distro{Name: "rhel-94", OSVersion: "9.4", Arch: "aarch64"})
+	assert.True(t, ok)
+}
+
+func TestPlatform(t *testing.T) {
+	it := ImageType{BootMode: BOOT_HYBRID, ImageFormat: "qcow2", QCOW2Compat: "1.1"}
+
+	pf, err := it.Platform("x86_64", "redhat")
+	require.NoError(t, err)
+	assert.Equal(t, &platform.X86{
+		BasePlatform: platform.BasePlatform{ImageFormat: platform.FORMAT_QCOW2, QCOW2Compat: "1.1"},
+		BIOS:         true,
+		UEFIVendor:   "redhat",
+	}, pf)
+
+	pf, err = it.Platform("aarch64", "redhat")
+	require.NoError(t, err)
+	assert.Equal(t, "redhat", pf.GetUEFIVendor())
+
+	pf, err = it.Platform("s390x", "redhat")
+	require.NoError(t, err)
+	assert.True(t, pf.GetZiplSupport())
+
+	it.BootMode = BOOT_UEFI
+	pf, err = it.Platform("x86_64", "redhat")
+	require.NoError(t, err)
+	assert.Equal(t, "", pf.GetBIOSPlatform())
+	_, err = it.Platform("ppc64le", "redhat")
+	assert.EqualError(t, err, `boot mode "uefi" is not supported on ppc64le`)
+
+	it.BootMode = BOOT_NONE
+	pf, err = it.Platform("x86_64", "redhat")
+	require.NoError(t, err)
+	assert.Equal(t, "", pf.GetUEFIVendor())
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace [2]string
+		wantErr string
+	}{
+		{
+			name:    "unknown-key",
+			replace: [2]string{"mime_type:", "mimetype:"},
+			wantErr: "field mimetype not found",
+		},
+		{
+			name:    "unknown-inherit",
+			replace: [2]string{"inherit: [base]", "inherit: [core]"},
+			wantErr: `package set "cloud": inherits unknown package set "core"`,
+		},
+		{
+			name:    "inherit-loop",
+			replace: [2]string{"- include: [bash, systemd]", "- inherit: [cloud]"},
+			wantErr: `package set "base": package set "base" inherits itself`,
+		},
+		{
+			name:    "export",
+			replace: [2]string{"exports: [qcow2]", "exports: [vmdk]"},
+			wantErr: `image type "cloud-qcow2": export "vmdk" is not a payload pipeline`,
+		},
+		{
+			name:    "image-format",
+			replace: [2]string{"image_format: qcow2", "image_format: qcow3"},
+			wantErr: `image type "cloud-qcow2": unknown image format "qcow3"`,
+		},
+		{
+			name:    "arch",
+			replace: [2]string{"arches: [x86_64, aarch64]", "arches: [x86_64, riscv64]"},
+			wantErr: `image type "cloud-qcow2": unknown architecture "riscv64"`,
+		},
+		{
+			name:    "boot-mode",
+			replace: [2]string{"boot_mode: hybrid", "boot_mode: legacy"},
+			wantErr: `image type "cloud-qcow2": boot mode "legacy" is not supported on aarch64`,
+		},
+		{
+			name:    "bootable-without-boot-mode",
+			replace: [2]string{"boot_mode: hybrid", "boot_mode: none"},
+			wantErr: `image type "cloud-qcow2": bootable image types require a boot mode`,
+		},
+		{
+			name:    "image-config-option",
+			replace: [2]string{"timezone: UTC", "time_zone: UTC"},
+			wantErr: `image config "base": json: unknown field "time_zone"`,
+		},
+		{
+			name:    "image-config-inherit",
+			replace: [2]string{"- inherit: [base]\n        timezone", "- inherit: [cloud]\n        timezone"},
+			wantErr: `image type "cloud-qcow2": image config: inherits unknown image config "cloud"`,
+		},
+		{
+			name:    "override-partition-table",
+			replace: [2]string{"kernel_options: console=ttyAMA0", "partition_table: missing"},
+			wantErr: `image type "cloud-qcow2": unknown partition table "missing"`,
+		},
+		{
+			name:    "partition-table-arch",
+			replace: [2]string{"    x86_64:\n      uuid", "    s390x:\n      uuid"},
+			wantErr: `image type "cloud-qcow2": partition table "plain" has no table for x86_64`,
+		},
+		{
+			name:    "partition-size",
+			replace: [2]string{"size: 2 GiB", "size: large"},
+			wantErr: `partition table "plain" for x86_64: invalid size of partition 2: the size string doesn't contain any number: large`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(testDefinitions, tt.replace[0], tt.replace[1], 1)
+			require.NotEqual(t, testDefinitions, data)
+			_, err := Parse(strings.NewReader(data))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestImageConfig(t *testing.T) {
+	defs, err := Parse(strings.NewReader(testDefinitions))
+	require.NoError(t, err)
+	ic := defs.ImageTypes["cloud-qcow2"].ImageConfig
+
+	rhel90 := Distro{Name: "rhel-90", OSVersion: "9.0", Releasever: "9", Arch: "x86_64"}
+	config := defs.ImageConfig(ic, rhel90)
+	require.NotNil(t, config)
+	assert.Equal(t, "en_US.UTF-8", *config.Locale)
+	// the options of the image type replace the inherited ones
+	assert.Equal(t, "America/New_York", *config.Timezone)
+	assert.Equal(t, []string{"/etc/pki/rpm-gpg/RPM-GPG-KEY-redhat-release"}, config.GPGKeyFiles)
+	assert.Equal(t, "multi-user.target", *config.DefaultTarget)
+	require.Len(t, config.DNFConfig, 1)
+	assert.Equal(t, "9.0", config.DNFConfig[0].Variables[0].Value)
+
+	rhel94 := Distro{Name: "rhel-94", OSVersion: "9.4", Releasever: "9", Arch: "x86_64"}
+	config = defs.ImageConfig(ic, rhel94)
+	require.NotNil(t, config)
+	assert.Nil(t, config.DefaultTarget)
+	assert.Nil(t, config.DNFConfig)
+
+	centos := Distro{Name: "centos-9", OSVersion: "9-stream", Releasever: "9", Arch: "x86_64"}
+	config = defs.ImageConfig(ic, centos)
+	require.NotNil(t, config)
+	assert.Equal(t, "en_US.UTF-8", *config.Locale)
+	assert.Nil(t, config.GPGKeyFiles)
+
+	assert.Nil(t, defs.ImageConfig(nil, centos))
+}
+
+func TestForDistro(t *testing.T) {
+	defs, err := Parse(strings.NewReader(testDefinitions))
+	require.NoError(t, err)
+	it := defs.ImageTypes["cloud-qcow2"]
+
+	x86 := it.ForDistro(Distro{Name: "rhel-94", OSVersion: "9.4", Arch: "x86_64"})
+	assert.Equal(t, BOOT_HYBRID, x86.BootMode)
+	assert.Equal(t, "", x86.KernelOptions)
+
+	arm := it.ForDistro(Distro{Name: "rhel-94", OSVersion: "9.4", Arch: "aarch64"})
+	assert.Equal(t, BOOT_UEFI, arm.BootMode)
+	assert.Equal(t, "console=ttyAMA0", arm.KernelOptions)
+	assert.Nil(t, arm.Overrides)
+	// the definition itself is not modified
+	assert.Len(t, it.Overrides, 1)
+}
+
+func TestConditions(t *testing.T) {
+	rhel86 := Distro{Name: "rhel-86", OSVersion: "8.6", Releasever: "8", Arch: "aarch64"}
+	tests := []struct {
+		name  string
+		when  *Conditions
+		match bool
+	}{
+		{"none", nil, true},
+		{"distro", &Conditions{Distro: []string{"rhel"}}, true},
+		{"other-distro", &Conditions{Distro: []string{"centos"}}, false},
+		{"arch", &Conditions{Arch: []string{"x86_64"}}, false},
+		{"releasever", &Conditions{Releasever: []string{"8"}}, true},
+		{"version", &Conditions{Version: []string{"8.5", "8.6"}}, true},
+		{"version-less-than", &Conditions{VersionLessThan: "8.6"}, false},
+		{"version-greater-or-equal", &Conditions{VersionGreaterOrEqual: "8.6"}, true},
+		{"all", &Conditions{Distro: []string{"rhel"}, Arch: []string{"aarch64"}, VersionLessThan: "8.10"}, true},
+		{"not", &Conditions{Not: &Conditions{Arch: []string{"aarch64"}, VersionLessThan: "8.6"}}, true},
+		{"not-matching", &Conditions{Not: &Conditions{Arch: []string{"aarch64"}, VersionLessThan: "8.7"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.when.Match(rhel86))
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for _, name := range []string{"rhel-8", "rhel-9"} {
+		defs, err := Load(name)
+		require.NoError(t, err)
+		assert.Contains(t, defs.ImageTypes, "openstack")
+		assert.Contains(t, defs.PartitionTables, "default")
+	}
+
+	_, err := Load("rhel-1")
+	assert.ErrorContains(t, err, "no image type definitions for rhel-1")
+}
@@ -0,0 +1,131 @@
+package defs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/osbuild/images/pkg/distro"
+)
+
+// ImageConfig is a list of chunks that are applied in order to build the
+// image configuration. Each option set by a chunk replaces the option of the
+// chunks before it.
+type ImageConfig []ImageConfigChunk
+
+type ImageConfigChunk struct {
+	// The chunk is only used if the conditions match
+	When *Conditions `yaml:"when"`
+
+	// Names of shared image configurations to apply before the options of
+	// the chunk, in order
+	Inherit []string `yaml:"inherit"`
+
+	// Options of distro.ImageConfig by their JSON names, e.g. "locale" or
+	// "enabled_services"
+	Options map[string]interface{} `yaml:",inline"`
+}
+
+// placeholder distribution the image configurations are validated for
+var validationDistro = Distro{
+	Name:       "validation-1",
+	OSVersion:  "1.0",
+	Releasever: "1",
+}
+
+func (d *Definitions) validateImageConfig(ic ImageConfig, path []string) error {
+	for _, chunk := range ic {
+		if err := chunk.When.validate(); err != nil {
+			return err
+		}
+		for _, name := range chunk.Inherit {
+			inherited, ok := d.ImageConfigs[name]
+			if !ok {
+				return fmt.Errorf("inherits unknown image config %q", name)
+			}
+			if slices.Contains(path, name) {
+				return fmt.Errorf("image config %q inherits itself", name)
+			}
+			if err := d.validateImageConfig(inherited, append(append([]string{}, path...), name)); err != nil {
+				return err
+			}
+		}
+		if _, err := chunk.options(validationDistro); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImageConfig evaluates the chunks of an image configuration for the
+// distribution. It returns nil if no chunk applies.
+func (d *Definitions) ImageConfig(ic ImageConfig, target Distro) *distro.ImageConfig {
+	var result *distro.ImageConfig
+	for _, chunk := range ic {
+		if !chunk.When.Match(target) {
+			continue
+		}
+		for _, name := range chunk.Inherit {
+			if inherited := d.ImageConfig(d.ImageConfigs[name], target); inherited != nil {
+				result = inherited.InheritFrom(result)
+			}
+		}
+		if len(chunk.Options) == 0 {
+			continue
+		}
+		options, err := chunk.options(target)
+		if err != nil {
+			// the definitions are validated when they are loaded
+			panic(err)
+		}
+		result = options.InheritFrom(result)
+	}
+	return result
+}
+
+// options decodes the options of the chunk into a distro.ImageConfig. Values
+// are decoded from JSON, so that the options are named like in the JSON
+// encoding of distro.ImageConfig and its stage options.
+func (chunk ImageConfigChunk) options(target Distro) (*distro.ImageConfig, error) {
+	replacer := strings.NewReplacer(
+		"${releasever}", target.Releasever,
+		"${os_version}", target.OSVersion,
+	)
+	buf, err := json.Marshal(substitute(chunk.Options, replacer))
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+	var ic distro.ImageConfig
+	if err := dec.Decode(&ic); err != nil {
+		return nil, err
+	}
+	return &ic, nil
+}
+
+// substitute returns a copy of a decoded YAML value with the variables in all
+// strings replaced.
+func substitute(value interface{}, replacer *strings.Replacer) interface{} {
+	switch v := value.(type) {
+	case string:
+		return replacer.Replace(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for idx, item := range v {
+			result[idx] = substitute(item, replacer)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = substitute(item, replacer)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,125 @@
+package defs
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slices"
+
+	"github.com/osbuild/images/internal/environment"
+	"github.com/osbuild/images/internal/workload"
+	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// Funcs are the functions and values of a distribution that image type
+// definitions refer to by name. I is the type of the functions creating the
+// image kinds.
+type Funcs[I any] struct {
+	// Functions creating the image kind of an image type
+	Images map[string]I
+
+	Environments map[string]environment.Environment
+	Workloads    map[string]workload.Workload
+}
+
+// ArchImageType is an image type of the definitions on one architecture of a
+// distribution, with its overrides applied and all names it refers to
+// resolved.
+type ArchImageType[I any] struct {
+	Name       string
+	Arch       string
+	Definition ImageType
+	Platform   platform.Platform
+
+	// Function creating the image kind
+	Image I
+
+	Environment environment.Environment
+	Workload    workload.Workload
+
+	// Package sets by name
+	PackageSets map[string]func() rpmmd.PackageSet
+
+	// Default image configuration, nil if the image type has none
+	ImageConfig func() *distro.ImageConfig
+
+	// Base partition table, nil if the image type has none
+	PartitionTable func() (disk.PartitionTable, bool)
+}
+
+// ImageTypes returns the image types of the definitions that are available
+// for the distribution on the given architectures, ordered by name and then
+// by the order of the architectures. The architecture of target is ignored.
+// uefiVendor is the vendor of the UEFI boot loader of the distribution.
+func ImageTypes[I any](d *Definitions, funcs Funcs[I], target Distro, uefiVendor string, arches ...string) ([]ArchImageType[I], error) {
+	var imageTypes []ArchImageType[I]
+	for _, name := range sortedKeys(d.ImageTypes) {
+		for _, arch := range arches {
+			archTarget := target
+			archTarget.Arch = arch
+
+			def := d.ImageTypes[name]
+			if !slices.Contains(def.Arches, arch) || !def.When.Match(archTarget) {
+				continue
+			}
+			ait, err := archImageType(d, name, def.ForDistro(archTarget), funcs, archTarget, uefiVendor)
+			if err != nil {
+				return nil, fmt.Errorf("image type %q: %w", name, err)
+			}
+			imageTypes = append(imageTypes, ait)
+		}
+	}
+	return imageTypes, nil
+}
+
+func archImageType[I any](d *Definitions, name string, def ImageType, funcs Funcs[I], target Distro, uefiVendor string) (ArchImageType[I], error) {
+	ait := ArchImageType[I]{
+		Name:        name,
+		Arch:        target.Arch,
+		Definition:  def,
+		PackageSets: make(map[string]func() rpmmd.PackageSet, len(def.PackageSets)),
+	}
+
+	var ok bool
+	if ait.Image, ok = funcs.Images[def.Image]; !ok {
+		return ait, fmt.Errorf("refers to unknown image function %q", def.Image)
+	}
+	if def.Environment != "" {
+		if ait.Environment, ok = funcs.Environments[def.Environment]; !ok {
+			return ait, fmt.Errorf("refers to unknown environment %q", def.Environment)
+		}
+	}
+	if def.Workload != "" {
+		if ait.Workload, ok = funcs.Workloads[def.Workload]; !ok {
+			return ait, fmt.Errorf("refers to unknown workload %q", def.Workload)
+		}
+	}
+
+	var err error
+	if ait.Platform, err = def.Platform(target.Arch, uefiVendor); err != nil {
+		return ait, err
+	}
+
+	for psName, ps := range def.PackageSets {
+		ps := ps
+		ait.PackageSets[psName] = func() rpmmd.PackageSet {
+			return d.PackageSet(ps, target)
+		}
+	}
+
+	if len(def.ImageConfig) > 0 {
+		ait.ImageConfig = func() *distro.ImageConfig {
+			return d.ImageConfig(def.ImageConfig, target)
+		}
+	}
+
+	if def.PartitionTable != "" {
+		ait.PartitionTable = func() (disk.PartitionTable, bool) {
+			return d.BasePartitionTable(def.PartitionTable, target)
+		}
+	}
+
+	return ait, nil
+}
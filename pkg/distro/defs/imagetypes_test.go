@@ -0,0 +1,92 @@
+package defs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageTypes(t *testing.T) {
+	defs, err := Parse(strings.NewReader(testDefinitions))
+	require.NoError(t, err)
+
+	funcs := Funcs[string]{
+		Images: map[string]string{"disk": "disk-image"},
+	}
+	rhel := Distro{Name: "rhel-94", OSVersion: "9.4", Releasever: "9"}
+	imageTypes, err := ImageTypes(defs, funcs, rhel, "redhat", "aarch64", "x86_64", "s390x")
+	require.NoError(t, err)
+	require.Len(t, imageTypes, 2)
+	// ordered by name and then by the order of the architectures
+	assert.Equal(t, "aarch64", imageTypes[0].Arch)
+	assert.Equal(t, "x86_64", imageTypes[1].Arch)
+
+	// the overrides for the architecture are applied
+	assert.Equal(t, BOOT_UEFI, imageTypes[0].Definition.BootMode)
+	assert.Equal(t, "", imageTypes[0].Platform.GetBIOSPlatform())
+
+	it := imageTypes[1]
+	assert.Equal(t, "cloud-qcow2", it.Name)
+	assert.Equal(t, "disk-image", it.Image)
+	assert.Equal(t, BOOT_HYBRID, it.Definition.BootMode)
+	assert.Equal(t, "x86_64", it.Platform.GetArch().String())
+	assert.Equal(t, "redhat", it.Platform.GetUEFIVendor())
+	assert.Nil(t, it.Workload)
+
+	rhel.Arch = "x86_64"
+	assert.Equal(t, defs.PackageSet(defs.ImageTypes["cloud-qcow2"].PackageSets["os"], rhel), it.PackageSets["os"]())
+	require.NotNil(t, it.ImageConfig)
+	assert.Equal(t, defs.ImageConfig(defs.ImageTypes["cloud-qcow2"].ImageConfig, rhel), it.ImageConfig())
+	require.NotNil(t, it.PartitionTable)
+	pt, ok := it.PartitionTable()
+	require.True(t, ok)
+	assert.Equal(t, "D209C89E-EA5E-4FBD-B161-B461CCE297E0", pt.UUID)
+
+	// the partition table on aarch64 is only defined for RHEL
+	centos := Distro{Name: "centos-9", OSVersion: "9-stream", Releasever: "9"}
+	imageTypes, err = ImageTypes(defs, funcs, centos, "centos", "aarch64")
+	require.NoError(t, err)
+	require.Len(t, imageTypes, 1)
+	_, ok = imageTypes[0].PartitionTable()
+	assert.False(t, ok)
+}
+
+func TestImageTypesConditions(t *testing.T) {
+	data := strings.Replace(testDefinitions, "    image: disk\n", "    image: disk\n    when:\n      not:\n        arch: [aarch64]\n        distro: [centos]\n", 1)
+	defs, err := Parse(strings.NewReader(data))
+	require.NoError(t, err)
+
+	funcs := Funcs[string]{
+		Images: map[string]string{"disk": "disk-image"},
+	}
+	centos := Distro{Name: "centos-9", OSVersion: "9-stream", Releasever: "9"}
+	imageTypes, err := ImageTypes(defs, funcs, centos, "centos", "aarch64", "x86_64")
+	require.NoError(t, err)
+	require.Len(t, imageTypes, 1)
+	assert.Equal(t, "x86_64", imageTypes[0].Arch)
+
+	rhel := Distro{Name: "rhel-94", OSVersion: "9.4", Releasever: "9"}
+	imageTypes, err = ImageTypes(defs, funcs, rhel, "redhat", "aarch64", "x86_64")
+	require.NoError(t, err)
+	assert.Len(t, imageTypes, 2)
+}
+
+func TestImageTypesUnknownNames(t *testing.T) {
+	defs, err := Parse(strings.NewReader(testDefinitions))
+	require.NoError(t, err)
+	rhel := Distro{Name: "rhel-94", OSVersion: "9.4", Releasever: "9"}
+
+	_, err = ImageTypes(defs, Funcs[string]{}, rhel, "redhat", "x86_64")
+	assert.EqualError(t, err, `image type "cloud-qcow2": refers to unknown image function "disk"`)
+
+	data := strings.Replace(testDefinitions, "    image: disk\n", "    image: disk\n    workload: eap\n", 1)
+	defs, err = Parse(strings.NewReader(data))
+	require.NoError(t, err)
+	funcs := Funcs[string]{
+		Images: map[string]string{"disk": "disk-image"},
+	}
+	_, err = ImageTypes(defs, funcs, rhel, "redhat", "x86_64")
+	assert.EqualError(t, err, `image type "cloud-qcow2": refers to unknown workload "eap"`)
+}
@@ -0,0 +1,256 @@
+package defs
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+// PartitionTable is a base partition table.
+type PartitionTable struct {
+	When *Conditions `yaml:"when"`
+	UUID string      `yaml:"uuid"`
+	Type string      `yaml:"type"`
+	// Minimum size of the disk with a unit, e.g. "64 GiB"
+	Size string `yaml:"size"`
+	// Offset of the first partition with a unit, e.g. "8 MiB"
+	StartOffset string      `yaml:"start_offset"`
+	Partitions  []Partition `yaml:"partitions"`
+}
+
+// Partition of a base partition table. At most one of the payloads can be
+// set.
+type Partition struct {
+	// The partition is only part of the table if the conditions match
+	When *Conditions `yaml:"when"`
+	// Size with a unit, e.g. "200 MiB". The last partition can have no
+	// size, it then takes up the rest of the disk.
+	Size     string `yaml:"size"`
+	Type     string `yaml:"type"`
+	UUID     string `yaml:"uuid"`
+	Bootable bool   `yaml:"bootable"`
+
+	Filesystem *Filesystem `yaml:"filesystem"`
+	LUKS       *LUKS       `yaml:"luks"`
+	LVM        *LVM        `yaml:"lvm"`
+}
+
+type Filesystem struct {
+	Type         string `yaml:"type"`
+	UUID         string `yaml:"uuid"`
+	Label        string `yaml:"label"`
+	Mountpoint   string `yaml:"mountpoint"`
+	FSTabOptions string `yaml:"fstab_options"`
+	FSTabFreq    uint64 `yaml:"fstab_freq"`
+	FSTabPassNo  uint64 `yaml:"fstab_passno"`
+}
+
+// LUKS is an encrypted container with either a filesystem or an LVM volume
+// group.
+type LUKS struct {
+	Label      string `yaml:"label"`
+	Cipher     string `yaml:"cipher"`
+	Passphrase string `yaml:"passphrase"`
+
+	// Parameters of the argon2id password-based key derivation function
+	PBKDF struct {
+		Memory      uint `yaml:"memory"`
+		Iterations  uint `yaml:"iterations"`
+		Parallelism uint `yaml:"parallelism"`
+	} `yaml:"pbkdf"`
+
+	Clevis *struct {
+		Pin              string `yaml:"pin"`
+		Policy           string `yaml:"policy"`
+		RemovePassphrase bool   `yaml:"remove_passphrase"`
+	} `yaml:"clevis"`
+
+	Filesystem *Filesystem `yaml:"filesystem"`
+	LVM        *LVM        `yaml:"lvm"`
+}
+
+type LVM struct {
+	Name           string          `yaml:"name"`
+	Description    string          `yaml:"description"`
+	LogicalVolumes []LogicalVolume `yaml:"logical_volumes"`
+}
+
+type LogicalVolume struct {
+	Name string `yaml:"name"`
+	// Size with a unit, e.g. "2 GiB"
+	Size       string      `yaml:"size"`
+	Filesystem *Filesystem `yaml:"filesystem"`
+}
+
+// BasePartitionTable returns the shared partition table with the given name
+// for the distribution. The second return value is false if there is no
+// partition table with that name for the architecture or its conditions
+// don't match.
+func (d *Definitions) BasePartitionTable(name string, target Distro) (disk.PartitionTable, bool) {
+	pt, ok := d.PartitionTables[name][target.Arch]
+	if !ok || !pt.When.Match(target) {
+		return disk.PartitionTable{}, false
+	}
+	table, err := pt.PartitionTable(target)
+	if err != nil {
+		// the definitions are validated when they are loaded
+		panic(err)
+	}
+	return *table, true
+}
+
+// PartitionTable converts the definition to a disk.PartitionTable for the
+// distribution.
+func (pt PartitionTable) PartitionTable(target Distro) (*disk.PartitionTable, error) {
+	return pt.convert(func(c *Conditions) bool { return c.Match(target) })
+}
+
+// validate converts the partition table with all its partitions
+func (pt PartitionTable) validate() error {
+	if err := pt.When.validate(); err != nil {
+		return err
+	}
+	for _, part := range pt.Partitions {
+		if err := part.When.validate(); err != nil {
+			return err
+		}
+	}
+	_, err := pt.convert(func(*Conditions) bool { return true })
+	return err
+}
+
+func (pt PartitionTable) convert(match func(*Conditions) bool) (*disk.PartitionTable, error) {
+	if pt.Type != "gpt" && pt.Type != "dos" {
+		return nil, fmt.Errorf("unknown partition table type %q", pt.Type)
+	}
+	size, err := parseSize(pt.Size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size: %w", err)
+	}
+	startOffset, err := parseSize(pt.StartOffset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start offset: %w", err)
+	}
+	table := &disk.PartitionTable{
+		UUID:        pt.UUID,
+		Type:        pt.Type,
+		Size:        size,
+		StartOffset: startOffset,
+	}
+	for idx, part := range pt.Partitions {
+		if !match(part.When) {
+			continue
+		}
+		size, err := parseSize(part.Size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size of partition %d: %w", idx+1, err)
+		}
+		payloads := 0
+		for _, set := range []bool{part.Filesystem != nil, part.LUKS != nil, part.LVM != nil} {
+			if set {
+				payloads++
+			}
+		}
+		if payloads > 1 {
+			return nil, fmt.Errorf("partition %d has more than one payload", idx+1)
+		}
+		partition := disk.Partition{
+			Size:     size,
+			Type:     part.Type,
+			UUID:     part.UUID,
+			Bootable: part.Bootable,
+		}
+		switch {
+		case part.Filesystem != nil:
+			partition.Payload = part.Filesystem.filesystem()
+		case part.LUKS != nil:
+			if partition.Payload, err = part.LUKS.container(); err != nil {
+				return nil, fmt.Errorf("partition %d: %w", idx+1, err)
+			}
+		case part.LVM != nil:
+			if partition.Payload, err = part.LVM.volumeGroup(); err != nil {
+				return nil, fmt.Errorf("partition %d: %w", idx+1, err)
+			}
+		}
+		table.Partitions = append(table.Partitions, partition)
+	}
+	return table, nil
+}
+
+func (fs *Filesystem) filesystem() *disk.Filesystem {
+	return &disk.Filesystem{
+		Type:         fs.Type,
+		UUID:         fs.UUID,
+		Label:        fs.Label,
+		Mountpoint:   fs.Mountpoint,
+		FSTabOptions: fs.FSTabOptions,
+		FSTabFreq:    fs.FSTabFreq,
+		FSTabPassNo:  fs.FSTabPassNo,
+	}
+}
+
+func (luks *LUKS) container() (*disk.LUKSContainer, error) {
+	container := &disk.LUKSContainer{
+		Label:      luks.Label,
+		Cipher:     luks.Cipher,
+		Passphrase: luks.Passphrase,
+		PBKDF: disk.Argon2id{
+			Memory:      luks.PBKDF.Memory,
+			Iterations:  luks.PBKDF.Iterations,
+			Parallelism: luks.PBKDF.Parallelism,
+		},
+	}
+	if luks.Clevis != nil {
+		container.Clevis = &disk.ClevisBind{
+			Pin:              luks.Clevis.Pin,
+			Policy:           luks.Clevis.Policy,
+			RemovePassphrase: luks.Clevis.RemovePassphrase,
+		}
+	}
+	switch {
+	case luks.Filesystem != nil && luks.LVM != nil:
+		return nil, fmt.Errorf("LUKS container has more than one payload")
+	case luks.Filesystem != nil:
+		container.Payload = luks.Filesystem.filesystem()
+	case luks.LVM != nil:
+		vg, err := luks.LVM.volumeGroup()
+		if err != nil {
+			return nil, err
+		}
+		container.Payload = vg
+	default:
+		return nil, fmt.Errorf("LUKS container has no payload")
+	}
+	return container, nil
+}
+
+func (lvm *LVM) volumeGroup() (*disk.LVMVolumeGroup, error) {
+	vg := &disk.LVMVolumeGroup{
+		Name:        lvm.Name,
+		Description: lvm.Description,
+	}
+	for _, lv := range lvm.LogicalVolumes {
+		size, err := parseSize(lv.Size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size of logical volume %q: %w", lv.Name, err)
+		}
+		volume := disk.LVMLogicalVolume{
+			Name: lv.Name,
+			Size: size,
+		}
+		if lv.Filesystem != nil {
+			volume.Payload = lv.Filesystem.filesystem()
+		}
+		vg.LogicalVolumes = append(vg.LogicalVolumes, volume)
+	}
+	return vg, nil
+}
+
+// parseSize parses a size with a unit, an empty size is 0.
+func parseSize(size string) (uint64, error) {
+	if size == "" {
+		return 0, nil
+	}
+	return common.DataSizeToUint64(size)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rhsm/facts"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/subscription"
@@ -120,6 +121,10 @@ type ImageType interface {
 	// Returns the names of the stages that will produce the build output.
 	Exports() []string
 
+	// Returns the recommended resources of a virtual machine booting the
+	// image.
+	RecommendedResources() VMResources
+
 	// Returns an osbuild manifest, containing the sources and pipeline necessary
 	// to build an image, given output format with all packages and customizations
 	// specified in the given blueprint; it also returns any warnings (e.g.
@@ -135,6 +140,17 @@ type ImageOptions struct {
 	Subscription     *subscription.ImageOptions
 	Facts            *facts.ImageOptions
 	PartitioningMode disk.PartitioningMode
+	SecureExecution  *platform.SecureExecutionOptions
+
+	// URL the tree of a PXE image type is served from. The boot
+	// configurations of the tree contain a placeholder if it's not set.
+	PXEBaseURL string
+}
+
+// VMResources are the resources of a virtual machine
+type VMResources struct {
+	MemoryMiB uint64
+	VCPUs     uint
 }
 
 type BasePartitionTableMap map[string]disk.PartitionTable
@@ -160,3 +176,7 @@ func ExportsFallback() []string {
 func PayloadPackageSets() []string {
 	return []string{}
 }
+
+func RecommendedResourcesFallback() VMResources {
+	return VMResources{MemoryMiB: 2048, VCPUs: 2}
+}
@@ -2,17 +2,22 @@ package distro_test
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/selinuxpolicy"
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distroregistry"
+	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -72,28 +77,114 @@ func TestImageType_PackageSetsChains(t *testing.T) {
 	}
 }
 
+// types for parsing the opaque manifest with just the fields we care about
+type testStage struct {
+	Type    string                 `json:"type"`
+	Options map[string]interface{} `json:"options"`
+	Inputs  map[string]interface{} `json:"inputs"`
+}
+
+type testPipeline struct {
+	Name   string      `json:"name"`
+	Stages []testStage `json:"stages"`
+}
+
+type testManifest struct {
+	Pipelines []testPipeline                    `json:"pipelines"`
+	Sources   map[string]map[string]interface{} `json:"sources"`
+
+	// the serialized manifest
+	Raw string `json:"-"`
+}
+
+// stages returns the stages of the named pipeline
+func (m *testManifest) stages(name string) []testStage {
+	for _, pl := range m.Pipelines {
+		if pl.Name == name {
+			return pl.Stages
+		}
+	}
+	return nil
+}
+
+// inlineFiles returns the decoded contents of the org.osbuild.inline source
+func (m *testManifest) inlineFiles(t *testing.T) []string {
+	items, _ := m.Sources["org.osbuild.inline"]["items"].(map[string]interface{})
+	files := make([]string, 0, len(items))
+	for _, item := range items {
+		data, err := base64.StdEncoding.DecodeString(item.(map[string]interface{})["data"].(string))
+		require.NoError(t, err)
+		files = append(files, string(data))
+	}
+	return files
+}
+
+// copiedPaths returns the destinations of an org.osbuild.copy stage
+func copiedPaths(s testStage) []string {
+	paths, _ := s.Options["paths"].([]interface{})
+	copied := make([]string, 0, len(paths))
+	for _, p := range paths {
+		copied = append(copied, p.(map[string]interface{})["to"].(string))
+	}
+	return copied
+}
+
+// serializeManifestForTest creates the manifest of the image type and
+// serializes it with fake content: every package set resolves to a kernel
+// package and the containers and ostree commits resolve to fake digests.
+func serializeManifestForTest(t *testing.T, imgType distro.ImageType, bp *blueprint.Blueprint, options distro.ImageOptions) (*manifest.Manifest, *testManifest) {
+	m, _, err := imgType.Manifest(bp, options, nil, 0)
+	require.NoError(t, err)
+
+	packageSets := make(map[string][]rpmmd.PackageSpec)
+	for plName := range m.GetPackageSetChains() {
+		packageSets[plName] = []rpmmd.PackageSpec{
+			{
+				Name:     "kernel",
+				Version:  "6.5.6",
+				Release:  "300.fc39",
+				Arch:     imgType.Arch().Name(),
+				Checksum: "sha256:a0c936696eb7d5ee3192bf53b9d281cecbb40ca9db520de72cb95817ad92ac72",
+			},
+		}
+	}
+
+	containers := make(map[string][]container.Spec)
+	for name, sources := range m.GetContainerSourceSpecs() {
+		for _, source := range sources {
+			containers[name] = append(containers[name], container.Spec{
+				Source:    source.Source,
+				Digest:    fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("digest"+source.Source))),
+				ImageID:   fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("id"+source.Source))),
+				LocalName: source.Source,
+			})
+		}
+	}
+
+	commits := make(map[string][]ostree.CommitSpec)
+	for name, sources := range m.GetOSTreeSourceSpecs() {
+		for _, source := range sources {
+			commits[name] = append(commits[name], ostree.CommitSpec{
+				Ref:      source.Ref,
+				URL:      source.URL,
+				Checksum: fmt.Sprintf("%x", sha256.Sum256([]byte(source.URL+source.Ref))),
+			})
+		}
+	}
+
+	mf, err := m.Serialize(packageSets, containers, commits)
+	require.NoError(t, err)
+	pm := &testManifest{Raw: string(mf)}
+	require.NoError(t, json.Unmarshal(mf, pm))
+	return m, pm
+}
+
 // Ensure all image types report the correct names for their pipelines.
 // Each image type contains a list of build and payload pipelines. They are
 // needed for knowing the names of pipelines from the static object without
 // having access to a manifest, which we need when parsing metadata from build
 // results.
 func TestImageTypePipelineNames(t *testing.T) {
-	// types for parsing the opaque manifest with just the fields we care about
-	type rpmStageOptions struct {
-		GPGKeys []string `json:"gpgkeys"`
-	}
-	type stage struct {
-		Type    string          `json:"type"`
-		Options rpmStageOptions `json:"options"`
-	}
-	type pipeline struct {
-		Name   string  `json:"name"`
-		Stages []stage `json:"stages"`
-	}
-	type manifest struct {
-		Pipelines []pipeline `json:"pipelines"`
-	}
-
 	assert := assert.New(t)
 	distros := distroregistry.NewDefault()
 	for _, distroName := range distros.List() {
@@ -170,7 +261,7 @@ func TestImageTypePipelineNames(t *testing.T) {
 					}
 					mf, err := m.Serialize(packageSets, containers, commits)
 					assert.NoError(err)
-					pm := new(manifest)
+					pm := new(testManifest)
 					err = json.Unmarshal(mf, pm)
 					assert.NoError(err)
 
@@ -193,7 +284,7 @@ func TestImageTypePipelineNames(t *testing.T) {
 										// a preset workload, payload packages are ignored
 										// and dropped and so are the payload
 										// repo gpg keys.
-										assert.Equal(repos[0].GPGKeys, s.Options.GPGKeys)
+										assert.Equal([]interface{}{repos[0].GPGKeys[0]}, s.Options["gpgkeys"])
 									}
 								}
 							}
@@ -497,6 +588,691 @@ func TestPipelineRepositories(t *testing.T) {
 	}
 }
 
+// Ensure that ostree disk images can be deployed from an OSTree native
+// container and record the container as the update origin.
+func TestOSTreeContainerDeployment(t *testing.T) {
+	distros := distroregistry.NewDefault()
+	testCases := []struct {
+		distro    string
+		imageType string
+	}{
+		{"fedora-39", "iot-raw-image"},
+		{"fedora-39", "iot-qcow2-image"},
+		{"rhel-94", "edge-raw-image"},
+		{"rhel-94", "edge-ami"},
+	}
+
+	const source = "registry.example.com/os/bootable:latest"
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/%s", tc.distro, tc.imageType), func(t *testing.T) {
+			d := distros.GetDistro(tc.distro)
+			require.NotNil(t, d)
+			arch, err := d.GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType(tc.imageType)
+			require.NoError(t, err)
+
+			options := distro.ImageOptions{
+				Size: imageType.Size(0),
+				OSTree: &ostree.ImageOptions{
+					Container: source,
+				},
+			}
+			m, pm := serializeManifestForTest(t, imageType, &blueprint.Blueprint{}, options)
+
+			assert.Empty(t, m.GetOSTreeSourceSpecs())
+			sources := m.GetContainerSourceSpecs()
+			require.Len(t, sources["ostree-deployment"], 1)
+			assert.Equal(t, source, sources["ostree-deployment"][0].Source)
+
+			var stageTypes []string
+			var deployOptions map[string]interface{}
+			for _, s := range pm.stages("ostree-deployment") {
+				stageTypes = append(stageTypes, s.Type)
+				if s.Type == "org.osbuild.ostree.deploy.container" {
+					deployOptions = s.Options
+				}
+			}
+			assert.NotContains(t, stageTypes, "org.osbuild.ostree.pull")
+			assert.NotContains(t, stageTypes, "org.osbuild.ostree.deploy")
+			assert.NotContains(t, stageTypes, "org.osbuild.ostree.remotes")
+			require.NotNil(t, deployOptions)
+			assert.Equal(t, "ostree-unverified-registry:"+source, deployOptions["target_imgref"])
+		})
+	}
+
+	t.Run("unsupported-image-type", func(t *testing.T) {
+		arch, err := distros.GetDistro("fedora-39").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("iot-commit")
+		require.NoError(t, err)
+		options := distro.ImageOptions{
+			OSTree: &ostree.ImageOptions{
+				Container: source,
+			},
+		}
+		_, _, err = imageType.Manifest(&blueprint.Blueprint{}, options, nil, 0)
+		assert.EqualError(t, err, `image type "iot-commit" does not support deploying an OSTree container`)
+	})
+}
+
+func TestInstallerCustomizations(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Installer: &blueprint.InstallerCustomization{
+				KernelAppend:  "inst.text console=ttyS0",
+				Drivers:       []string{"megaraid_sas"},
+				DracutModules: []string{"nvdimm"},
+				Modules: &blueprint.AnacondaModulesCustomization{
+					Enable:  []string{"org.fedoraproject.Anaconda.Modules.Localization"},
+					Disable: []string{"org.fedoraproject.Anaconda.Modules.Network"},
+				},
+				LockedScreens: []string{"UserSpoke"},
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	testCases := []struct {
+		distro    string
+		imageType string
+	}{
+		{"fedora-39", "image-installer"},
+		{"rhel-810", "image-installer"},
+		{"rhel-94", "image-installer"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/%s", tc.distro, tc.imageType), func(t *testing.T) {
+			d := distros.GetDistro(tc.distro)
+			require.NotNil(t, d)
+			arch, err := d.GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType(tc.imageType)
+			require.NoError(t, err)
+
+			_, pm := serializeManifestForTest(t, imageType, &bp, distro.ImageOptions{})
+
+			stages := make(map[string]map[string]interface{})
+			for _, s := range pm.stages("anaconda-tree") {
+				stages[s.Type] = s.Options
+			}
+
+			require.Contains(t, stages, "org.osbuild.anaconda")
+			modules := stages["org.osbuild.anaconda"]["kickstart-modules"]
+			assert.Contains(t, modules, "org.fedoraproject.Anaconda.Modules.Localization")
+			assert.NotContains(t, modules, "org.fedoraproject.Anaconda.Modules.Network")
+
+			require.Contains(t, stages, "org.osbuild.dracut")
+			assert.Contains(t, stages["org.osbuild.dracut"]["modules"], "nvdimm")
+			assert.Contains(t, stages["org.osbuild.dracut"]["add_drivers"], "megaraid_sas")
+
+			assert.Contains(t, pm.Raw, `"inst.text","console=ttyS0"`)
+			assert.Contains(t, pm.Raw, "/etc/anaconda/conf.d/90-osbuild.conf")
+		})
+	}
+
+	t.Run("live-installer", func(t *testing.T) {
+		arch, err := distros.GetDistro("fedora-39").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("live-installer")
+		require.NoError(t, err)
+		_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.EqualError(t, err, `installer modules and locked screens are not supported for live image type "live-installer"`)
+	})
+
+	t.Run("unsupported-image-type", func(t *testing.T) {
+		arch, err := distros.GetDistro("rhel-94").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+		_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.EqualError(t, err, `installer customizations are not supported for image type "qcow2"`)
+	})
+}
+
+func TestFirstBootCustomization(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			FirstBoot: &blueprint.FirstBootCustomization{
+				Scripts: []blueprint.FirstBootScriptCustomization{
+					{Name: "register", Command: "/usr/bin/register --now"},
+				},
+				WaitForNetwork: true,
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	testCases := []struct {
+		distro    string
+		imageType string
+		pipeline  string
+	}{
+		{"fedora-39", "qcow2", "os"},
+		{"fedora-39", "iot-raw-image", "ostree-deployment"},
+		{"rhel-810", "qcow2", "os"},
+		{"rhel-94", "qcow2", "os"},
+		{"rhel-94", "edge-raw-image", "ostree-deployment"},
+	}
+
+	const source = "registry.example.com/os/bootable:latest"
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s/%s", tc.distro, tc.imageType), func(t *testing.T) {
+			d := distros.GetDistro(tc.distro)
+			require.NotNil(t, d)
+			arch, err := d.GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType(tc.imageType)
+			require.NoError(t, err)
+
+			options := distro.ImageOptions{
+				Size: imageType.Size(0),
+			}
+			if tc.pipeline == "ostree-deployment" {
+				options.OSTree = &ostree.ImageOptions{
+					Container: source,
+				}
+			}
+			_, pm := serializeManifestForTest(t, imageType, &bp, options)
+
+			var enabledServices []interface{}
+			var copied []string
+			for _, s := range pm.stages(tc.pipeline) {
+				switch s.Type {
+				case "org.osbuild.systemd":
+					if services, ok := s.Options["enabled_services"].([]interface{}); ok {
+						enabledServices = append(enabledServices, services...)
+					}
+				case "org.osbuild.copy":
+					copied = append(copied, copiedPaths(s)...)
+				}
+			}
+			assert.Contains(t, enabledServices, "osbuild-custom-first-boot.service")
+			assert.Contains(t, copied, "tree:///etc/osbuild/first-boot.d/00-register")
+			assert.Contains(t, copied, "tree:///etc/systemd/system/osbuild-custom-first-boot.service")
+		})
+	}
+
+	t.Run("path-policy", func(t *testing.T) {
+		arch, err := distros.GetDistro("fedora-39").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+		bp := blueprint.Blueprint{
+			Customizations: &blueprint.Customizations{
+				FirstBoot: &blueprint.FirstBootCustomization{
+					Scripts: []blueprint.FirstBootScriptCustomization{
+						{Name: "register", Path: "/usr/bin/register", Command: "true"},
+					},
+				},
+			},
+		}
+		_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.EqualError(t, err, `the following first boot script paths are not allowed: ["/usr/bin/register"]`)
+	})
+}
+
+func TestFlatpaks(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Flatpaks: []blueprint.Flatpak{
+			{
+				Remote: "flathub",
+				URL:    "https://dl.flathub.org/repo/",
+				Ref:    "app/org.gnome.Calculator",
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	arch, err := distros.GetDistro("fedora-39").GetArch("x86_64")
+	require.NoError(t, err)
+
+	t.Run("qcow2", func(t *testing.T) {
+		imageType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+		m, pm := serializeManifestForTest(t, imageType, &bp, distro.ImageOptions{})
+		assert.Contains(t, m.GetPackageSetChains()["os"][0].Include, "flatpak")
+
+		const url = "https://dl.flathub.org/repo/"
+		const ref = "app/org.gnome.Calculator/x86_64/stable"
+		require.Equal(t, map[string][]ostree.SourceSpec{
+			"os": {
+				{URL: url, Ref: ref},
+			},
+		}, m.GetOSTreeSourceSpecs())
+		checksum := fmt.Sprintf("%x", sha256.Sum256([]byte(url+ref)))
+
+		var pullStage, systemdStage *testStage
+		osStages := pm.stages("os")
+		for idx := range osStages {
+			switch osStages[idx].Type {
+			case "org.osbuild.ostree.pull":
+				pullStage = &osStages[idx]
+			case "org.osbuild.systemd":
+				systemdStage = &osStages[idx]
+			}
+		}
+		require.NotNil(t, systemdStage)
+		assert.Contains(t, systemdStage.Options["enabled_services"], "osbuild-flatpak-install.service")
+		require.NotNil(t, pullStage)
+		assert.Equal(t, "/var/lib/flatpak/repo", pullStage.Options["repo"])
+		assert.Equal(t, "flathub", pullStage.Options["remote"])
+		assert.Contains(t, pullStage.Inputs["commits"].(map[string]interface{})["references"], checksum)
+		assert.Contains(t, pm.Sources["org.osbuild.ostree"]["items"], checksum)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		for _, tc := range []struct {
+			distro    string
+			imageType string
+		}{
+			{"fedora-39", "iot-commit"},
+			{"fedora-39", "live-installer"},
+			{"rhel-94", "qcow2"},
+		} {
+			arch, err := distros.GetDistro(tc.distro).GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType(tc.imageType)
+			require.NoError(t, err)
+			_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+			assert.EqualError(t, err, fmt.Sprintf("embedding flatpaks is not supported for %s on %s", tc.imageType, tc.distro))
+		}
+	})
+}
+
+func TestS390XSecureExecution(t *testing.T) {
+	const hostKey = "-----BEGIN CERTIFICATE-----\nZmFrZSBob3N0IGtleSBkb2N1bWVudA==\n-----END CERTIFICATE-----\n"
+	options := distro.ImageOptions{
+		SecureExecution: &platform.SecureExecutionOptions{
+			HostKeys: []string{hostKey},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, distroName := range []string{"fedora-39", "rhel-810", "rhel-94"} {
+		t.Run(distroName, func(t *testing.T) {
+			d := distros.GetDistro(distroName)
+			require.NotNil(t, d)
+			arch, err := d.GetArch("s390x")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType("qcow2")
+			require.NoError(t, err)
+
+			_, pm := serializeManifestForTest(t, imageType, &blueprint.Blueprint{}, options)
+
+			var genprotimg, ziplInst *testStage
+			for _, pl := range pm.Pipelines {
+				for idx := range pl.Stages {
+					switch pl.Stages[idx].Type {
+					case "org.osbuild.genprotimg":
+						genprotimg = &pl.Stages[idx]
+					case "org.osbuild.zipl.inst":
+						ziplInst = &pl.Stages[idx]
+					}
+				}
+			}
+			require.NotNil(t, genprotimg)
+			assert.Equal(t, "/boot/vmlinuz-6.5.6-300.fc39.s390x", genprotimg.Options["kernel"])
+			assert.Equal(t, []interface{}{"/etc/se-hostkeys/hostkey-1.crt"}, genprotimg.Options["host-keys"])
+			assert.Contains(t, genprotimg.Options["cmdline"], "root=UUID=")
+			require.NotNil(t, ziplInst)
+			assert.Equal(t, "/boot/secure-image", ziplInst.Options["secure-image"])
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		arch, err := distros.GetDistro("rhel-94").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+		_, _, err = imageType.Manifest(&blueprint.Blueprint{}, options, nil, 0)
+		assert.EqualError(t, err, "IBM Secure Execution is not supported for qcow2 on x86_64")
+	})
+}
+
+func TestFIPS(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			FIPS: common.ToPtr(true),
+			Filesystem: []blueprint.FilesystemCustomization{
+				{Mountpoint: "/boot", MinSize: 1024 * 1024 * 1024},
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, distroName := range []string{"fedora-39", "rhel-810", "rhel-94", "centos-10"} {
+		t.Run(distroName, func(t *testing.T) {
+			d := distros.GetDistro(distroName)
+			require.NotNil(t, d)
+			arch, err := d.GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType("qcow2")
+			require.NoError(t, err)
+
+			m, pm := serializeManifestForTest(t, imageType, &bp, distro.ImageOptions{})
+			assert.Contains(t, m.GetPackageSetChains()["os"][0].Include, "crypto-policies-scripts")
+			assert.Contains(t, m.GetPackageSetChains()["build"][0].Include, "crypto-policies-scripts")
+
+			policies := make(map[string]interface{})
+			var grub2, dracut *testStage
+			for _, pl := range pm.Pipelines {
+				for idx := range pl.Stages {
+					switch pl.Stages[idx].Type {
+					case "org.osbuild.update-crypto-policies":
+						policies[pl.Name] = pl.Stages[idx].Options["policy"]
+					case "org.osbuild.grub2":
+						grub2 = &pl.Stages[idx]
+					case "org.osbuild.dracut":
+						dracut = &pl.Stages[idx]
+					}
+				}
+			}
+			assert.Equal(t, map[string]interface{}{"build": "FIPS", "os": "FIPS"}, policies)
+			require.NotNil(t, dracut)
+			assert.Equal(t, []interface{}{"6.5.6-300.fc39.x86_64"}, dracut.Options["kernel"])
+			assert.Equal(t, []interface{}{"fips"}, dracut.Options["add_modules"])
+			require.NotNil(t, grub2)
+			kernelOpts := strings.Fields(grub2.Options["kernel_opts"].(string))
+			assert.Contains(t, kernelOpts, "fips=1")
+			assert.Contains(t, kernelOpts, fmt.Sprintf("boot=UUID=%s", grub2.Options["boot_fs_uuid"]))
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		arch, err := distros.GetDistro("rhel-7").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+		_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.EqualError(t, err, "FIPS mode is not supported for qcow2 on rhel-7")
+	})
+}
+
+func TestSystemConfigCustomizations(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Sysctl:   []blueprint.SysctlCustomization{{Key: "vm.swappiness", Value: "10"}},
+			Tuned:    &blueprint.TunedCustomization{Profiles: []string{"virtual-guest"}},
+			Journald: &blueprint.JournaldCustomization{Storage: "persistent"},
+			Dracut:   &blueprint.DracutCustomization{AddDrivers: []string{"virtio_scsi"}},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, distroName := range []string{"fedora-39", "rhel-810", "rhel-94", "centos-10"} {
+		t.Run(distroName, func(t *testing.T) {
+			arch, err := distros.GetDistro(distroName).GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType("qcow2")
+			require.NoError(t, err)
+
+			_, pm := serializeManifestForTest(t, imageType, &bp, distro.ImageOptions{})
+
+			stages := make(map[string]*testStage)
+			osStages := pm.stages("os")
+			for idx := range osStages {
+				stages[osStages[idx].Type] = &osStages[idx]
+			}
+			for _, stageType := range []string{"org.osbuild.sysctld", "org.osbuild.systemd-journald", "org.osbuild.dracut.conf"} {
+				require.Contains(t, stages, stageType)
+				assert.Equal(t, distro.BlueprintDropinFilename, stages[stageType].Options["filename"])
+			}
+			require.Contains(t, stages, "org.osbuild.tuned")
+			assert.Equal(t, []interface{}{"virtual-guest"}, stages["org.osbuild.tuned"].Options["profiles"])
+			require.Contains(t, stages, "org.osbuild.dracut")
+			assert.Equal(t, []interface{}{"6.5.6-300.fc39.x86_64"}, stages["org.osbuild.dracut"].Options["kernel"])
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		arch, err := distros.GetDistro("rhel-94").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+		invalid := blueprint.Blueprint{
+			Customizations: &blueprint.Customizations{
+				Journald: &blueprint.JournaldCustomization{Storage: "disk"},
+			},
+		}
+		_, _, err = imageType.Manifest(&invalid, distro.ImageOptions{}, nil, 0)
+		assert.EqualError(t, err, `invalid journald storage "disk": must be one of volatile, persistent, auto, none`)
+	})
+}
+
+func TestKernelModulesCustomizations(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			KernelModules: &blueprint.KernelModulesCustomization{
+				Blacklist: []string{"nouveau"},
+				Options:   []blueprint.KernelModuleOptionsCustomization{{Name: "kvm_intel", Options: "nested=1"}},
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, distroName := range []string{"fedora-39", "rhel-810", "rhel-94", "centos-10"} {
+		t.Run(distroName, func(t *testing.T) {
+			arch, err := distros.GetDistro(distroName).GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType("qcow2")
+			require.NoError(t, err)
+
+			_, pm := serializeManifestForTest(t, imageType, &bp, distro.ImageOptions{})
+
+			// replay the stages that write to /etc/modprobe.d, in order, so
+			// that a file written by a later stage replaces an earlier one
+			items, _ := pm.Sources["org.osbuild.inline"]["items"].(map[string]interface{})
+			tree := make(map[string]string)
+			for _, s := range pm.stages("os") {
+				switch s.Type {
+				case "org.osbuild.modprobe":
+					var lines []string
+					for _, cmd := range s.Options["commands"].([]interface{}) {
+						cmd := cmd.(map[string]interface{})
+						lines = append(lines, fmt.Sprintf("%s %s\n", cmd["command"], cmd["modulename"]))
+					}
+					tree["/etc/modprobe.d/"+s.Options["filename"].(string)] = strings.Join(lines, "")
+				case "org.osbuild.copy":
+					for _, p := range s.Options["paths"].([]interface{}) {
+						p := p.(map[string]interface{})
+						to := strings.TrimPrefix(p["to"].(string), "tree://")
+						if !strings.HasPrefix(to, "/etc/modprobe.d/") {
+							continue
+						}
+						from := p["from"].(string)
+						item := items[from[strings.LastIndex(from, "/")+1:]].(map[string]interface{})
+						data, err := base64.StdEncoding.DecodeString(item["data"].(string))
+						require.NoError(t, err)
+						tree[to] = string(data)
+					}
+				}
+			}
+
+			var modprobe []string
+			for _, data := range tree {
+				modprobe = append(modprobe, data)
+			}
+			files := strings.Join(modprobe, "")
+			assert.Contains(t, files, "blacklist nouveau\n")
+			assert.Contains(t, files, "options kvm_intel nested=1\n")
+		})
+	}
+}
+
+func TestSELinuxCustomizations(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			SELinux: &blueprint.SELinuxCustomization{
+				Mode:     "permissive",
+				Booleans: map[string]bool{"httpd_can_network_connect": true},
+				Modules:  []string{"/usr/share/selinux/packages/custom.pp"},
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, distroName := range []string{"fedora-39", "rhel-810", "rhel-94", "centos-10"} {
+		t.Run(distroName, func(t *testing.T) {
+			arch, err := distros.GetDistro(distroName).GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType("qcow2")
+			require.NoError(t, err)
+
+			m, pm := serializeManifestForTest(t, imageType, &bp, distro.ImageOptions{})
+			// semanage runs in the tree
+			assert.Contains(t, m.GetPackageSetChains()["os"][0].Include, "policycoreutils-python-utils")
+
+			osStages := pm.stages("os")
+			var selinuxConfig *testStage
+			scriptIdx, selinuxIdx := -1, -1
+			for idx := range osStages {
+				switch osStages[idx].Type {
+				case "org.osbuild.selinux.config":
+					selinuxConfig = &osStages[idx]
+				case "org.osbuild.script":
+					if osStages[idx].Options["script"] == selinuxpolicy.ScriptPath {
+						scriptIdx = idx
+					}
+				case "org.osbuild.selinux":
+					selinuxIdx = idx
+				}
+			}
+			require.NotNil(t, selinuxConfig)
+			assert.Equal(t, "permissive", selinuxConfig.Options["state"])
+			require.NotEqual(t, -1, scriptIdx)
+			assert.Equal(t, selinuxIdx-1, scriptIdx)
+			assert.Contains(t, pm.Raw, "tree:///usr/local/sbin/osbuild-selinux-policy")
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		arch, err := distros.GetDistro("fedora-39").GetArch("x86_64")
+		require.NoError(t, err)
+		imageType, err := arch.GetImageType("container")
+		require.NoError(t, err)
+		_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+		assert.EqualError(t, err, "SELinux customizations are not supported for images without SELinux labelling")
+	})
+}
+
+func TestContainersCustomizationEmbeddedContainers(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{Source: "evil.example.com/app:latest"},
+		},
+		Customizations: &blueprint.Customizations{
+			Containers: &blueprint.ContainersCustomization{
+				Registries: []blueprint.ContainerRegistryCustomization{
+					{Prefix: "evil.example.com", Blocked: true},
+				},
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, distroName := range []string{"fedora-39", "rhel-810", "rhel-94", "centos-10"} {
+		t.Run(distroName, func(t *testing.T) {
+			arch, err := distros.GetDistro(distroName).GetArch("x86_64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType("qcow2")
+			require.NoError(t, err)
+			_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+			assert.EqualError(t, err, `embedded container "evil.example.com/app:latest" is from a blocked registry`)
+		})
+	}
+}
+
+func TestPartitionTablePlatformValidation(t *testing.T) {
+	bp := blueprint.Blueprint{
+		Customizations: &blueprint.Customizations{
+			Filesystem: []blueprint.FilesystemCustomization{
+				{Mountpoint: "/var", MinSize: 1024 * 1024 * 1024},
+				{Mountpoint: "/home", MinSize: 1024 * 1024 * 1024},
+			},
+		},
+	}
+	options := distro.ImageOptions{
+		PartitioningMode: disk.RawPartitioningMode,
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, distroName := range []string{"fedora-39", "rhel-94"} {
+		t.Run(distroName, func(t *testing.T) {
+			arch, err := distros.GetDistro(distroName).GetArch("s390x")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType("qcow2")
+			require.NoError(t, err)
+			// qcow2 images are booted from virtio disks, the partition
+			// limit of ECKD DASDs doesn't apply
+			_, _, err = imageType.Manifest(&bp, options, nil, 0)
+			assert.NoError(t, err)
+
+			_, _, err = imageType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSingleBoardComputerBoot(t *testing.T) {
+	testCases := []struct {
+		imageType string
+		copied    []string
+		files     []string
+	}{
+		{
+			imageType: "minimal-raw-rpi",
+			copied: []string{
+				"tree:///boot/efi/rpi-u-boot.bin",
+				"tree:///boot/efi/config.txt",
+				"tree:///boot/extlinux/extlinux.conf",
+			},
+			files: []string{"kernel=rpi-u-boot.bin"},
+		},
+		{
+			imageType: "minimal-raw-sbc",
+			copied: []string{
+				"tree:///boot/extlinux/extlinux.conf",
+			},
+		},
+	}
+
+	distros := distroregistry.NewDefault()
+	for _, tc := range testCases {
+		t.Run(tc.imageType, func(t *testing.T) {
+			arch, err := distros.GetDistro("fedora-39").GetArch("aarch64")
+			require.NoError(t, err)
+			imageType, err := arch.GetImageType(tc.imageType)
+			require.NoError(t, err)
+
+			_, pm := serializeManifestForTest(t, imageType, &blueprint.Blueprint{}, distro.ImageOptions{})
+
+			var copied []string
+			for _, s := range pm.stages("os") {
+				switch s.Type {
+				case "org.osbuild.grub2", "org.osbuild.grub2.legacy":
+					t.Errorf("unexpected %s stage in the os pipeline", s.Type)
+				case "org.osbuild.copy":
+					copied = append(copied, copiedPaths(s)...)
+				}
+			}
+			for _, path := range tc.copied {
+				assert.Contains(t, copied, path)
+			}
+
+			files := strings.Join(pm.inlineFiles(t), "\n")
+			// the minimal raw image has a separate /boot partition
+			assert.Contains(t, files, "\tkernel /vmlinuz-6.5.6-300.fc39.aarch64\n")
+			assert.Contains(t, files, "\tfdtdir /dtb-6.5.6-300.fc39.aarch64\n")
+			assert.Contains(t, files, "\tappend root=UUID=")
+			for _, content := range tc.files {
+				assert.Contains(t, files, content)
+			}
+		})
+	}
+}
+
 // a very basic implementation of a Set of strings
 type stringSet struct {
 	elems map[string]bool
@@ -90,8 +90,10 @@ func TestDistro_KernelOption(t *testing.T, d distro.Distro) {
 		// image installer on Fedora doesn't support kernel customizations
 		// on RHEL we support kernel name
 		// TODO: Remove when we unify the allowed options
-		"image-installer": true,
-		"live-installer":  true,
+		"image-installer":         true,
+		"image-installer-pxe-tar": true,
+		"live-installer":          true,
+		"live-pxe-tar":            true,
 	}
 
 	{ // empty blueprint: all image types should just have the default kernel
@@ -108,6 +108,41 @@ var (
 		exports:          []string{"bootiso"},
 	}
 
+	livePXETarImgType = imageType{
+		name:        "live-pxe-tar",
+		nameAliases: []string{},
+		filename:    "live-pxe.tar",
+		mimeType:    "application/x-tar",
+		packageSets: map[string]packageSetFunc{
+			installerPkgsKey: liveInstallerPackageSet,
+		},
+		bootable:         true,
+		bootISO:          true,
+		rpmOstree:        false,
+		image:            livePXETarImage,
+		buildPipelines:   []string{"build"},
+		payloadPipelines: []string{"anaconda-tree", "rootfs-image", "pxe-tree", "pxe-tar"},
+		exports:          []string{"pxe-tar"},
+	}
+
+	imageInstallerPXETarImgType = imageType{
+		name:        "image-installer-pxe-tar",
+		nameAliases: []string{},
+		filename:    "installer-pxe.tar",
+		mimeType:    "application/x-tar",
+		packageSets: map[string]packageSetFunc{
+			osPkgsKey:        minimalrpmPackageSet,
+			installerPkgsKey: imageInstallerPackageSet,
+		},
+		bootable:         true,
+		bootISO:          true,
+		rpmOstree:        false,
+		image:            imageInstallerPXETarImage,
+		buildPipelines:   []string{"build"},
+		payloadPipelines: []string{"anaconda-tree", "rootfs-image", "os", "pxe-tree", "pxe-tar"},
+		exports:          []string{"pxe-tar"},
+	}
+
 	iotCommitImgType = imageType{
 		name:        "iot-commit",
 		nameAliases: []string{"fedora-iot-commit"},
@@ -565,6 +600,12 @@ func newDistro(version int) distro.Distro {
 	openstackImgType := qcow2ImgType
 	openstackImgType.name = "openstack"
 
+	minimalrawRPiImgType := minimalrawImgType
+	minimalrawRPiImgType.name = "minimal-raw-rpi"
+
+	minimalrawSBCImgType := minimalrawImgType
+	minimalrawSBCImgType.name = "minimal-raw-sbc"
+
 	vhdImgType := qcow2ImgType
 	vhdImgType.name = "vhd"
 	vhdImgType.filename = "disk.vhd"
@@ -668,7 +709,9 @@ func newDistro(version int) distro.Distro {
 		iotCommitImgType,
 		iotInstallerImgType,
 		imageInstallerImgType,
+		imageInstallerPXETarImgType,
 		liveInstallerImgType,
+		livePXETarImgType,
 	)
 	x86_64.addImageTypes(
 		&platform.X86{
@@ -739,10 +782,12 @@ func newDistro(version int) distro.Distro {
 			UEFIVendor: "fedora",
 		},
 		imageInstallerImgType,
+		imageInstallerPXETarImgType,
 		iotCommitImgType,
 		iotInstallerImgType,
 		iotOCIImgType,
 		liveInstallerImgType,
+		livePXETarImgType,
 	)
 	aarch64.addImageTypes(
 		&platform.Aarch64_Fedora{
@@ -811,6 +856,24 @@ func newDistro(version int) distro.Distro {
 		},
 		minimalrawImgType,
 	)
+	aarch64.addImageTypes(
+		&platform.Aarch64_SBC{
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_RAW,
+			},
+			Board: platform.SBC_RPI,
+		},
+		minimalrawRPiImgType,
+	)
+	aarch64.addImageTypes(
+		&platform.Aarch64_SBC{
+			BasePlatform: platform.BasePlatform{
+				ImageFormat: platform.FORMAT_RAW,
+			},
+			Board: platform.SBC_GENERIC,
+		},
+		minimalrawSBCImgType,
+	)
 
 	if !common.VersionLessThan(rd.Releasever(), "38") {
 		// iot simplified installer was introduced in F38
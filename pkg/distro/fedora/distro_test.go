@@ -1,6 +1,8 @@
 package fedora_test
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -12,6 +14,8 @@ import (
 	"github.com/osbuild/images/pkg/distro"
 	"github.com/osbuild/images/pkg/distro/distro_test_common"
 	"github.com/osbuild/images/pkg/distro/fedora"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/rpmmd"
 )
 
 type fedoraFamilyDistro struct {
@@ -173,6 +177,22 @@ func TestFilenameFromType(t *testing.T) {
 				mimeType: "application/x-iso9660-image",
 			},
 		},
+		{
+			name: "live-pxe-tar",
+			args: args{"live-pxe-tar"},
+			want: wantResult{
+				filename: "live-pxe.tar",
+				mimeType: "application/x-tar",
+			},
+		},
+		{
+			name: "image-installer-pxe-tar",
+			args: args{"image-installer-pxe-tar"},
+			want: wantResult{
+				filename: "installer-pxe.tar",
+				mimeType: "application/x-tar",
+			},
+		},
 		{
 			name: "image-installer",
 			args: args{"image-installer"},
@@ -327,12 +347,14 @@ func TestImageType_Name(t *testing.T) {
 			imgNames: []string{
 				"ami",
 				"image-installer",
+				"image-installer-pxe-tar",
 				"iot-commit",
 				"iot-container",
 				"iot-installer",
 				"iot-qcow2-image",
 				"iot-raw-image",
 				"live-installer",
+				"live-pxe-tar",
 				"minimal-raw",
 				"oci",
 				"openstack",
@@ -353,12 +375,15 @@ func TestImageType_Name(t *testing.T) {
 			imgNames: []string{
 				"ami",
 				"image-installer",
+				"image-installer-pxe-tar",
 				"iot-commit",
 				"iot-container",
 				"iot-installer",
 				"iot-qcow2-image",
 				"iot-raw-image",
 				"minimal-raw",
+				"minimal-raw-rpi",
+				"minimal-raw-sbc",
 				"oci",
 				"openstack",
 				"qcow2",
@@ -491,12 +516,12 @@ func TestDistro_ManifestError(t *testing.T) {
 					assert.EqualError(t, err, "kernel boot parameter customizations are not supported for ostree types")
 				} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" {
 					assert.EqualError(t, err, fmt.Sprintf("boot ISO image type \"%s\" requires specifying a URL from which to retrieve the OSTree commit", imgTypeName))
-				} else if imgTypeName == "image-installer" {
-					assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: User, Group)", imgTypeName))
-				} else if imgTypeName == "live-installer" {
-					assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
+				} else if imgTypeName == "image-installer" || imgTypeName == "image-installer-pxe-tar" {
+					assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: User, Group, Installer)", imgTypeName))
+				} else if imgTypeName == "live-installer" || imgTypeName == "live-pxe-tar" {
+					assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: Installer)", imgTypeName))
 				} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-					assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services)", imgTypeName))
+					assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services, FirstBoot, FIPS)", imgTypeName))
 				} else {
 					assert.NoError(t, err)
 				}
@@ -517,12 +542,14 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 				"ami",
 				"container",
 				"image-installer",
+				"image-installer-pxe-tar",
 				"iot-commit",
 				"iot-container",
 				"iot-installer",
 				"iot-qcow2-image",
 				"iot-raw-image",
 				"live-installer",
+				"live-pxe-tar",
 				"minimal-raw",
 				"oci",
 				"openstack",
@@ -544,13 +571,17 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 				"ami",
 				"container",
 				"image-installer",
+				"image-installer-pxe-tar",
 				"iot-commit",
 				"iot-container",
 				"iot-installer",
 				"iot-qcow2-image",
 				"iot-raw-image",
 				"live-installer",
+				"live-pxe-tar",
 				"minimal-raw",
+				"minimal-raw-rpi",
+				"minimal-raw-sbc",
 				"oci",
 				"openstack",
 				"qcow2",
@@ -672,11 +703,11 @@ func TestDistro_CustomFileSystemManifestError(t *testing.T) {
 			if imgTypeName == "iot-commit" || imgTypeName == "iot-container" {
 				assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
 			} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services)", imgTypeName))
-			} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services, FirstBoot, FIPS)", imgTypeName))
+			} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" || imgTypeName == "image-installer-pxe-tar" {
 				continue
-			} else if imgTypeName == "live-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
+			} else if imgTypeName == "live-installer" || imgTypeName == "live-pxe-tar" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: Installer)", imgTypeName))
 			} else {
 				assert.EqualError(t, err, "The following custom mountpoints are not supported [\"/etc\"]")
 			}
@@ -704,11 +735,11 @@ func TestDistro_TestRootMountPoint(t *testing.T) {
 			if imgTypeName == "iot-commit" || imgTypeName == "iot-container" {
 				assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
 			} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services)", imgTypeName))
-			} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services, FirstBoot, FIPS)", imgTypeName))
+			} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" || imgTypeName == "image-installer-pxe-tar" {
 				continue
-			} else if imgTypeName == "live-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
+			} else if imgTypeName == "live-installer" || imgTypeName == "live-pxe-tar" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: Installer)", imgTypeName))
 			} else {
 				assert.NoError(t, err)
 			}
@@ -739,8 +770,8 @@ func TestDistro_CustomFileSystemSubDirectories(t *testing.T) {
 			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
 			if strings.HasPrefix(imgTypeName, "iot-") || strings.HasPrefix(imgTypeName, "image-") {
 				continue
-			} else if imgTypeName == "live-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
+			} else if imgTypeName == "live-installer" || imgTypeName == "live-pxe-tar" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: Installer)", imgTypeName))
 			} else {
 				assert.NoError(t, err)
 			}
@@ -779,8 +810,8 @@ func TestDistro_MountpointsWithArbitraryDepthAllowed(t *testing.T) {
 			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
 			if strings.HasPrefix(imgTypeName, "iot-") || strings.HasPrefix(imgTypeName, "image-") {
 				continue
-			} else if imgTypeName == "live-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
+			} else if imgTypeName == "live-installer" || imgTypeName == "live-pxe-tar" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: Installer)", imgTypeName))
 			} else {
 				assert.NoError(t, err)
 			}
@@ -815,8 +846,8 @@ func TestDistro_DirtyMountpointsNotAllowed(t *testing.T) {
 			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
 			if strings.HasPrefix(imgTypeName, "iot-") || strings.HasPrefix(imgTypeName, "image-") {
 				continue
-			} else if imgTypeName == "live-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
+			} else if imgTypeName == "live-installer" || imgTypeName == "live-pxe-tar" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: Installer)", imgTypeName))
 			} else {
 				assert.EqualError(t, err, "The following custom mountpoints are not supported [\"//\" \"/var//\" \"/var//log/audit/\"]")
 			}
@@ -844,14 +875,74 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 			if imgTypeName == "iot-commit" || imgTypeName == "iot-container" {
 				assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
 			} else if imgTypeName == "iot-raw-image" || imgTypeName == "iot-qcow2-image" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services)", imgTypeName))
-			} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for image type %q: (allowed: User, Group, Directories, Files, Services, FirstBoot, FIPS)", imgTypeName))
+			} else if imgTypeName == "iot-installer" || imgTypeName == "iot-simplified-installer" || imgTypeName == "image-installer" || imgTypeName == "image-installer-pxe-tar" {
 				continue
-			} else if imgTypeName == "live-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: None)", imgTypeName))
+			} else if imgTypeName == "live-installer" || imgTypeName == "live-pxe-tar" {
+				assert.EqualError(t, err, fmt.Sprintf("unsupported blueprint customizations found for boot ISO image type \"%s\": (allowed: Installer)", imgTypeName))
 			} else {
 				assert.NoError(t, err)
 			}
 		}
 	}
 }
+
+func TestDistro_PXEBaseURL(t *testing.T) {
+	arch, err := fedora.NewF39().GetArch("x86_64")
+	require.NoError(t, err)
+
+	for _, imgTypeName := range []string{"live-pxe-tar", "image-installer-pxe-tar"} {
+		t.Run(imgTypeName, func(t *testing.T) {
+			imgType, err := arch.GetImageType(imgTypeName)
+			require.NoError(t, err)
+
+			serialize := func(options distro.ImageOptions) string {
+				m, _, err := imgType.Manifest(&blueprint.Blueprint{}, options, nil, 0)
+				require.NoError(t, err)
+				packageSets := make(map[string][]rpmmd.PackageSpec)
+				for plName := range m.GetPackageSetChains() {
+					packageSets[plName] = []rpmmd.PackageSpec{
+						{Name: "kernel", Version: "6.5.6", Release: "300.fc39", Arch: "x86_64", Checksum: "sha256:a0c936696eb7d5ee3192bf53b9d281cecbb40ca9db520de72cb95817ad92ac72"},
+					}
+				}
+				mf, err := m.Serialize(packageSets, nil, nil)
+				require.NoError(t, err)
+
+				// the boot configurations are inline sources
+				var sources struct {
+					Sources struct {
+						Inline struct {
+							Items map[string]struct {
+								Data string `json:"data"`
+							} `json:"items"`
+						} `json:"org.osbuild.inline"`
+					} `json:"sources"`
+				}
+				require.NoError(t, json.Unmarshal(mf, &sources))
+				var inline strings.Builder
+				for _, item := range sources.Sources.Inline.Items {
+					data, err := base64.StdEncoding.DecodeString(item.Data)
+					require.NoError(t, err)
+					inline.Write(data)
+				}
+				return inline.String()
+			}
+
+			assert.Contains(t, serialize(distro.ImageOptions{}), manifest.PXEBaseURLPlaceholder)
+
+			mf := serialize(distro.ImageOptions{PXEBaseURL: "http://pxe.example.com/fedora/"})
+			assert.NotContains(t, mf, manifest.PXEBaseURLPlaceholder)
+			assert.Contains(t, mf, "http://pxe.example.com/fedora")
+		})
+	}
+
+	imgType, err := arch.GetImageType("qcow2")
+	require.NoError(t, err)
+	_, _, err = imgType.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{PXEBaseURL: "http://pxe.example.com"}, nil, 0)
+	assert.EqualError(t, err, `a PXE base URL is not supported for image type "qcow2"`)
+
+	imgType, err = arch.GetImageType("live-pxe-tar")
+	require.NoError(t, err)
+	_, _, err = imgType.Manifest(&blueprint.Blueprint{}, distro.ImageOptions{PXEBaseURL: "pxe/fedora"}, nil, 0)
+	assert.EqualError(t, err, `invalid PXE base URL "pxe/fedora": an absolute URL is required`)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/fdo"
+	"github.com/osbuild/images/internal/firstboot"
 	"github.com/osbuild/images/internal/fsnode"
 	"github.com/osbuild/images/internal/ignition"
 	"github.com/osbuild/images/internal/oscap"
@@ -14,6 +15,7 @@ import (
 	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/flatpak"
 	"github.com/osbuild/images/pkg/image"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/osbuild"
@@ -24,13 +26,35 @@ import (
 
 // HELPERS
 
+// flatpakSources converts the flatpaks of the blueprint to source
+// specifications for the architecture of the image type.
+func flatpakSources(t *imageType, flatpaks []blueprint.Flatpak) []flatpak.SourceSpec {
+	var sources []flatpak.SourceSpec
+	for _, fp := range flatpaks {
+		sources = append(sources, flatpak.SourceSpec{
+			Remote: flatpak.Remote{
+				Name:   fp.Remote,
+				URL:    fp.URL,
+				GPGKey: fp.GPGKey,
+			},
+			Ref: flatpak.NewRef(fp.Ref, t.arch.Name(), fp.GetBranch()),
+		})
+	}
+	return sources
+}
+
 func osCustomizations(
 	t *imageType,
 	osPackageSet rpmmd.PackageSet,
 	containers []container.SourceSpec,
-	c *blueprint.Customizations) manifest.OSCustomizations {
+	bp *blueprint.Blueprint) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	c := bp.Customizations
+	imageConfig, err := t.getDefaultImageConfig().WithCustomizations(c)
+	if err != nil {
+		// The customizations have already been validated in checkOptions()
+		panic(fmt.Sprintf("failed to apply system configuration customizations: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -47,11 +71,14 @@ func osCustomizations(
 		osc.KernelOptionsAppend = kernelOptions
 	}
 
+	osc.FIPS = c.GetFIPS()
+
 	osc.ExtraBasePackages = osPackageSet.Include
 	osc.ExcludeBasePackages = osPackageSet.Exclude
 	osc.ExtraBaseRepos = osPackageSet.Repositories
 
 	osc.Containers = containers
+	osc.Flatpaks = flatpakSources(t, bp.Flatpaks)
 
 	osc.GPGKeyFiles = imageConfig.GPGKeyFiles
 	if imageConfig.ExcludeDocs != nil {
@@ -121,7 +148,6 @@ func osCustomizations(
 		osc.SElinux = "targeted"
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -136,6 +162,10 @@ func osCustomizations(
 		panic(fmt.Sprintf("failed to convert file customizations to fs node files: %v", err))
 	}
 
+	if fb := c.GetFirstBoot(); fb != nil {
+		osc.FirstBoot = firstboot.OptionsFromBP(*fb)
+	}
+
 	customRepos, err := c.GetRepositories()
 	if err != nil {
 		// This shouldn't happen and since the repos
@@ -165,43 +195,9 @@ func osCustomizations(
 		if t.rpmOstree {
 			panic("unexpected oscap options for ostree image type")
 		}
-		var datastream = oscapConfig.DataStream
-		if datastream == "" {
-			datastream = oscap.DefaultFedoraDatastream()
-		}
-
-		oscapStageOptions := osbuild.OscapConfig{
-			Datastream: datastream,
-			ProfileID:  oscapConfig.ProfileID,
-		}
-
-		if oscapConfig.Tailoring != nil {
-			newProfile, tailoringFilepath, tailoringDir, err := oscap.GetTailoringFile(oscapConfig.ProfileID)
-			if err != nil {
-				panic(fmt.Sprintf("unexpected error creating tailoring file options: %v", err))
-			}
-
-			tailoringOptions := osbuild.OscapAutotailorConfig{
-				Selected:   oscapConfig.Tailoring.Selected,
-				Unselected: oscapConfig.Tailoring.Unselected,
-				NewProfile: newProfile,
-			}
-
-			osc.OpenSCAPTailorConfig = osbuild.NewOscapAutotailorStageOptions(
-				tailoringFilepath,
-				oscapStageOptions,
-				tailoringOptions,
-			)
-
-			// overwrite the profile id with the new tailoring id
-			oscapStageOptions.ProfileID = newProfile
-			oscapStageOptions.Tailoring = tailoringFilepath
-
-			// add the parent directory for the tailoring file
-			osc.Directories = append(osc.Directories, tailoringDir)
+		if err := distro.ApplyOpenSCAPCustomizations(&osc, oscapConfig, oscap.DefaultFedoraDatastream()); err != nil {
+			panic(err)
 		}
-
-		osc.OpenSCAPConfig = osbuild.NewOscapRemediationStageOptions(oscapStageOptions)
 	}
 
 	osc.ShellInit = imageConfig.ShellInit
@@ -209,12 +205,17 @@ func osCustomizations(
 	osc.Grub2Config = imageConfig.Grub2Config
 	osc.Sysconfig = imageConfig.Sysconfig
 	osc.SystemdLogind = imageConfig.SystemdLogind
+	osc.SystemdJournald = imageConfig.SystemdJournald
 	osc.CloudInit = imageConfig.CloudInit
 	osc.Modprobe = imageConfig.Modprobe
 	osc.DracutConf = imageConfig.DracutConf
+	if imageConfig.RegenerateInitramfs != nil {
+		osc.RegenerateInitramfs = *imageConfig.RegenerateInitramfs
+	}
 	osc.SystemdUnit = imageConfig.SystemdUnit
 	osc.Authselect = imageConfig.Authselect
 	osc.SELinuxConfig = imageConfig.SELinuxConfig
+	osc.SELinuxPolicy = imageConfig.SELinuxPolicy
 	osc.Tuned = imageConfig.Tuned
 	osc.Tmpfilesd = imageConfig.Tmpfilesd
 	osc.PamLimitsConf = imageConfig.PamLimitsConf
@@ -243,7 +244,8 @@ func diskImage(workload workload.Workload,
 
 	img := image.NewDiskImage()
 	img.Platform = t.platform
-	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp.Customizations)
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp)
+	img.OSCustomizations.SecureExecution = options.SecureExecution
 	img.Environment = t.environment
 	img.Workload = workload
 	img.Compression = t.compression
@@ -273,7 +275,7 @@ func containerImage(workload workload.Workload,
 	img := image.NewBaseContainer()
 
 	img.Platform = t.platform
-	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp.Customizations)
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp)
 	img.Environment = t.environment
 	img.Workload = workload
 
@@ -296,6 +298,10 @@ func liveInstallerImage(workload workload.Workload,
 	img.Workload = workload
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
 
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, bp.Customizations); err != nil {
+		return nil, err
+	}
+
 	d := t.arch.distro
 
 	img.ISOLabelTempl = d.isolabelTmpl
@@ -320,8 +326,7 @@ func imageInstallerImage(workload workload.Workload,
 	img := image.NewAnacondaTarInstaller()
 
 	// Enable anaconda-webui for Fedora > 38
-	distro := t.Arch().Distro()
-	if !common.VersionLessThan(distro.Releasever(), "38") {
+	if !common.VersionLessThan(t.arch.distro.Releasever(), "38") {
 		img.AdditionalAnacondaModules = []string{
 			"org.fedoraproject.Anaconda.Modules.Security",
 			"org.fedoraproject.Anaconda.Modules.Timezone",
@@ -334,11 +339,15 @@ func imageInstallerImage(workload workload.Workload,
 	customizations := bp.Customizations
 	img.Platform = t.platform
 	img.Workload = workload
-	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, customizations)
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp)
 	img.ExtraBasePackages = packageSets[installerPkgsKey]
 	img.Users = users.UsersFromBP(customizations.GetUsers())
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, customizations); err != nil {
+		return nil, err
+	}
+
 	img.SquashfsCompression = "lz4"
 
 	d := t.arch.distro
@@ -354,6 +363,75 @@ func imageInstallerImage(workload workload.Workload,
 	return img, nil
 }
 
+func livePXETarImage(workload workload.Workload,
+	t *imageType,
+	bp *blueprint.Blueprint,
+	options distro.ImageOptions,
+	packageSets map[string]rpmmd.PackageSet,
+	containers []container.SourceSpec,
+	rng *rand.Rand) (image.ImageKind, error) {
+
+	img := image.NewAnacondaPXETar()
+	img.Live = true
+	img.BaseURL = options.PXEBaseURL
+
+	img.Platform = t.platform
+	img.Workload = workload
+	img.ExtraBasePackages = packageSets[installerPkgsKey]
+
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, bp.Customizations); err != nil {
+		return nil, err
+	}
+
+	d := t.arch.distro
+
+	img.Product = d.product
+	img.OSName = "fedora"
+	img.OSVersion = d.osVersion
+
+	img.Filename = t.Filename()
+
+	return img, nil
+}
+
+func imageInstallerPXETarImage(workload workload.Workload,
+	t *imageType,
+	bp *blueprint.Blueprint,
+	options distro.ImageOptions,
+	packageSets map[string]rpmmd.PackageSet,
+	containers []container.SourceSpec,
+	rng *rand.Rand) (image.ImageKind, error) {
+
+	img := image.NewAnacondaPXETar()
+	img.BaseURL = options.PXEBaseURL
+
+	img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
+
+	customizations := bp.Customizations
+	img.Platform = t.platform
+	img.Workload = workload
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp)
+	img.ExtraBasePackages = packageSets[installerPkgsKey]
+	img.Users = users.UsersFromBP(customizations.GetUsers())
+	img.Groups = users.GroupsFromBP(customizations.GetGroups())
+
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, customizations); err != nil {
+		return nil, err
+	}
+
+	img.SquashfsCompression = "lz4"
+
+	d := t.arch.distro
+
+	img.Product = d.product
+	img.OSName = "fedora"
+	img.OSVersion = d.osVersion
+
+	img.Filename = t.Filename()
+
+	return img, nil
+}
+
 func iotCommitImage(workload workload.Workload,
 	t *imageType,
 	bp *blueprint.Blueprint,
@@ -368,7 +446,7 @@ func iotCommitImage(workload workload.Workload,
 	d := t.arch.distro
 
 	img.Platform = t.platform
-	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp.Customizations)
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp)
 	if !common.VersionLessThan(d.Releasever(), "38") {
 		// see https://github.com/ostreedev/ostree/issues/2840
 		img.OSCustomizations.Presets = []osbuild.Preset{
@@ -408,7 +486,7 @@ func iotContainerImage(workload workload.Workload,
 	img := image.NewOSTreeContainer(commitRef)
 	d := t.arch.distro
 	img.Platform = t.platform
-	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp.Customizations)
+	img.OSCustomizations = osCustomizations(t, packageSets[osPkgsKey], containers, bp)
 	if !common.VersionLessThan(d.Releasever(), "38") {
 		// see https://github.com/ostreedev/ostree/issues/2840
 		img.OSCustomizations.Presets = []osbuild.Preset{
@@ -465,6 +543,10 @@ func iotInstallerImage(workload workload.Workload,
 		"org.fedoraproject.Anaconda.Modules.Users",
 	}
 
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, customizations); err != nil {
+		return nil, err
+	}
+
 	img.SquashfsCompression = "lz4"
 
 	img.ISOLabelTempl = d.isolabelTmpl
@@ -487,13 +569,10 @@ func iotImage(workload workload.Workload,
 	containers []container.SourceSpec,
 	rng *rand.Rand) (image.ImageKind, error) {
 
-	commit, err := makeOSTreePayloadCommit(options.OSTree, t.OSTreeRef())
+	img, err := newOSTreeDiskImage(options.OSTree, t.OSTreeRef())
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s", t.Name(), err.Error())
 	}
-	img := image.NewOSTreeDiskImage(commit)
-
-	distro := t.Arch().Distro()
 
 	customizations := bp.Customizations
 	img.Users = users.UsersFromBP(customizations.GetUsers())
@@ -508,7 +587,12 @@ func iotImage(workload workload.Workload,
 		return nil, err
 	}
 
+	if fb := customizations.GetFirstBoot(); fb != nil {
+		img.FirstBoot = firstboot.OptionsFromBP(*fb)
+	}
+
 	img.KernelOptionsAppend = []string{"modprobe.blacklist=vc4"}
+	img.FIPS = customizations.GetFIPS()
 	img.Keyboard = "us"
 	img.Locale = "C.UTF-8"
 
@@ -518,15 +602,18 @@ func iotImage(workload workload.Workload,
 	img.Platform = t.platform
 	img.Workload = workload
 
-	img.Remote = ostree.Remote{
-		Name:        "fedora-iot",
-		URL:         "https://ostree.fedoraproject.org/iot",
-		ContentURL:  "mirrorlist=https://ostree.fedoraproject.org/iot/mirrorlist",
-		GPGKeyPaths: []string{"/etc/pki/rpm-gpg/"},
+	// images deployed from a container are updated from the registry
+	if img.CommitSource != nil {
+		img.Remote = ostree.Remote{
+			Name:        "fedora-iot",
+			URL:         "https://ostree.fedoraproject.org/iot",
+			ContentURL:  "mirrorlist=https://ostree.fedoraproject.org/iot/mirrorlist",
+			GPGKeyPaths: []string{"/etc/pki/rpm-gpg/"},
+		}
 	}
 	img.OSName = "fedora-iot"
 
-	if !common.VersionLessThan(distro.Releasever(), "38") {
+	if !common.VersionLessThan(t.arch.distro.Releasever(), "38") {
 		img.Ignition = true
 		switch img.Platform.GetImageFormat() {
 		case platform.FORMAT_RAW:
@@ -575,6 +662,7 @@ func iotSimplifiedInstallerImage(workload workload.Workload,
 	rawImg.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	rawImg.KernelOptionsAppend = []string{"modprobe.blacklist=vc4"}
+	rawImg.FIPS = customizations.GetFIPS()
 	rawImg.Keyboard = "us"
 	rawImg.Locale = "C.UTF-8"
 	if !common.VersionLessThan(t.arch.distro.osVersion, "38") {
@@ -697,6 +785,24 @@ func makeOSTreePayloadCommit(options *ostree.ImageOptions, defaultRef string) (o
 	}, nil
 }
 
+// newOSTreeDiskImage creates the disk image of an ostree image type. The image
+// deploys the OSTree native container from the options if one is set and the
+// payload commit otherwise.
+func newOSTreeDiskImage(options *ostree.ImageOptions, defaultRef string) (*image.OSTreeDiskImage, error) {
+	if options != nil && options.Container != "" {
+		return image.NewOSTreeDiskImageFromContainer(container.SourceSpec{
+			Source: options.Container,
+			Name:   options.Container,
+		}), nil
+	}
+
+	commit, err := makeOSTreePayloadCommit(options, defaultRef)
+	if err != nil {
+		return nil, err
+	}
+	return image.NewOSTreeDiskImage(commit), nil
+}
+
 // initialSetupKickstart returns the File configuration for a kickstart file
 // that's required to enable initial-setup to run on first boot.
 func initialSetupKickstart() *fsnode.File {
@@ -2,7 +2,9 @@ package fedora
 
 import (
 	"fmt"
+	"log"
 	"math/rand"
+	"net/url"
 	"strings"
 
 	"github.com/osbuild/images/internal/common"
@@ -113,6 +115,15 @@ func (t *imageType) Exports() []string {
 	return []string{"assembler"}
 }
 
+func (t *imageType) RecommendedResources() distro.VMResources {
+	resources := distro.RecommendedResourcesFallback()
+	if t.bootISO {
+		// the installer and its image run from memory
+		resources.MemoryMiB = 4096
+	}
+	return resources
+}
+
 func (t *imageType) BootMode() distro.BootMode {
 	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
 		return distro.BOOT_HYBRID
@@ -147,7 +158,14 @@ func (t *imageType) getPartitionTable(
 		partitioningMode = disk.AutoLVMPartitioningMode
 	}
 
-	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, partitioningMode, t.requiredPartitionSizes, rng)
+	pt, err := disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, partitioningMode, t.requiredPartitionSizes, rng)
+	if err != nil {
+		return nil, err
+	}
+	if err := pt.ValidateForPlatform(t.platform); err != nil {
+		return nil, fmt.Errorf("invalid partition table for %s on %s: %w", t.Name(), t.arch.Name(), err)
+	}
+	return pt, nil
 }
 
 func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
@@ -251,17 +269,49 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 
 	customizations := bp.Customizations
 
+	// holds warnings (e.g. deprecation notices)
+	var warnings []string
+
 	// we do not support embedding containers on ostree-derived images, only on commits themselves
 	if len(bp.Containers) > 0 && t.rpmOstree && (t.name != "iot-commit" && t.name != "iot-container") {
 		return nil, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
 	}
 
+	// flatpaks are installed in /var, which is not part of ostree commits,
+	// and live images have no OS tree to install them to
+	if len(bp.Flatpaks) > 0 {
+		if t.rpmOstree || t.name == "live-installer" || t.name == "live-pxe-tar" {
+			return nil, fmt.Errorf("embedding flatpaks is not supported for %s on %s", t.name, t.arch.distro.name)
+		}
+		if err := blueprint.ValidateFlatpaks(bp.Flatpaks); err != nil {
+			return nil, err
+		}
+	}
+
 	if options.OSTree != nil {
 		if err := options.OSTree.Validate(); err != nil {
 			return nil, err
 		}
 	}
 
+	if se := options.SecureExecution; se != nil {
+		if !t.platform.GetZiplSupport() {
+			return nil, fmt.Errorf("IBM Secure Execution is not supported for %s on %s", t.name, t.arch.Name())
+		}
+		if err := se.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.PXEBaseURL != "" {
+		if t.name != "live-pxe-tar" && t.name != "image-installer-pxe-tar" {
+			return nil, fmt.Errorf("a PXE base URL is not supported for image type %q", t.name)
+		}
+		if u, err := url.Parse(options.PXEBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid PXE base URL %q: an absolute URL is required", options.PXEBaseURL)
+		}
+	}
+
 	if t.bootISO && t.rpmOstree {
 		// ostree-based ISOs require a URL from which to pull a payload commit
 		if options.OSTree == nil || options.OSTree.URL == "" {
@@ -269,19 +319,29 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		}
 	}
 
+	if options.OSTree != nil && options.OSTree.Container != "" && t.name != "iot-raw-image" && t.name != "iot-qcow2-image" {
+		return nil, fmt.Errorf("image type %q does not support deploying an OSTree container", t.name)
+	}
+
 	if t.name == "iot-raw-image" || t.name == "iot-qcow2-image" {
-		allowed := []string{"User", "Group", "Directories", "Files", "Services"}
+		allowed := []string{"User", "Group", "Directories", "Files", "Services", "FirstBoot", "FIPS"}
 		if err := customizations.CheckAllowed(allowed...); err != nil {
 			return nil, fmt.Errorf("unsupported blueprint customizations found for image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 		}
 		// TODO: consider additional checks, such as those in "edge-simplified-installer" in RHEL distros
 	}
 
+	if installer, err := customizations.GetInstaller(); err != nil {
+		return nil, err
+	} else if installer != nil && !t.bootISO {
+		return nil, fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+	}
+
 	// BootISOs have limited support for customizations.
 	// TODO: Support kernel name selection for image-installer
 	if t.bootISO {
 		if t.name == "iot-simplified-installer" {
-			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group"}
+			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group", "FIPS"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return nil, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
@@ -318,15 +378,22 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 					return nil, fmt.Errorf("ignition.firstboot requires a provisioning url")
 				}
 			}
-		} else if t.name == "iot-installer" || t.name == "image-installer" {
-			allowed := []string{"User", "Group"}
+		} else if t.name == "iot-installer" || t.name == "image-installer" || t.name == "image-installer-pxe-tar" {
+			allowed := []string{"User", "Group", "Installer"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return nil, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
-		} else if t.name == "live-installer" {
-			allowed := []string{}
+		} else if t.name == "live-installer" || t.name == "live-pxe-tar" {
+			allowed := []string{"Installer"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
-				return nil, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: None)", t.name)
+				return nil, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
+			}
+			installer, err := customizations.GetInstaller()
+			if err != nil {
+				return nil, err
+			}
+			if installer != nil && (installer.Modules != nil || len(installer.LockedScreens) > 0) {
+				return nil, fmt.Errorf("installer modules and locked screens are not supported for live image type %q", t.name)
 			}
 		}
 	}
@@ -335,6 +402,19 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return nil, fmt.Errorf("kernel boot parameter customizations are not supported for ostree types")
 	}
 
+	if customizations.GetFIPS() && !common.IsBuildHostFIPSEnabled() {
+		w := fmt.Sprintln(common.FIPSEnabledImageWarning)
+		log.Print(w)
+		warnings = append(warnings, w)
+	}
+
+	if _, err := t.getDefaultImageConfig().WithCustomizations(customizations); err != nil {
+		return warnings, err
+	}
+	if err := distro.CheckEmbeddedContainers(customizations, bp.Containers); err != nil {
+		return warnings, err
+	}
+
 	mountpoints := customizations.GetFilesystems()
 
 	if mountpoints != nil && t.rpmOstree {
@@ -357,6 +437,9 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		if osc.ProfileID == "" {
 			return nil, fmt.Errorf("OpenSCAP profile cannot be empty")
 		}
+		if err := blueprint.ValidateOpenSCAPCustomization(osc); err != nil {
+			return nil, err
+		}
 	}
 
 	// Check Directory/File Customizations are valid
@@ -378,11 +461,20 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return nil, err
 	}
 
+	fb := customizations.GetFirstBoot()
+	if err := blueprint.ValidateFirstBootCustomization(fb); err != nil {
+		return nil, err
+	}
+	err = blueprint.CheckFirstBootCustomizationPolicy(fb, pathpolicy.FirstBootScriptsPolicies)
+	if err != nil {
+		return nil, err
+	}
+
 	// check if repository customizations are valid
 	_, err = customizations.GetRepositories()
 	if err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	return warnings, nil
 }
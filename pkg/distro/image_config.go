@@ -3,69 +3,78 @@ package distro
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
+	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/selinuxpolicy"
 	"github.com/osbuild/images/internal/shell"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/subscription"
 )
 
 // ImageConfig represents a (default) configuration applied to the image
 type ImageConfig struct {
-	Timezone            *string
-	TimeSynchronization *osbuild.ChronyStageOptions
-	Locale              *string
-	Keyboard            *osbuild.KeymapStageOptions
-	EnabledServices     []string
-	DisabledServices    []string
-	DefaultTarget       *string
-	Sysconfig           []*osbuild.SysconfigStageOptions
+	Timezone            *string                          `json:"timezone"`
+	TimeSynchronization *osbuild.ChronyStageOptions      `json:"time_synchronization"`
+	Locale              *string                          `json:"locale"`
+	Keyboard            *osbuild.KeymapStageOptions      `json:"keyboard"`
+	EnabledServices     []string                         `json:"enabled_services"`
+	DisabledServices    []string                         `json:"disabled_services"`
+	DefaultTarget       *string                          `json:"default_target"`
+	Sysconfig           []*osbuild.SysconfigStageOptions `json:"sysconfig"`
 
 	// List of files from which to import GPG keys into the RPM database
-	GPGKeyFiles []string
+	GPGKeyFiles []string `json:"gpg_key_files"`
 
 	// Disable SELinux labelling
-	NoSElinux *bool
+	NoSElinux *bool `json:"no_selinux"`
 
 	// Do not use. Forces auto-relabelling on first boot.
 	// See https://github.com/osbuild/osbuild/commit/52cb27631b587c1df177cd17625c5b473e1e85d2
-	SELinuxForceRelabel *bool
+	SELinuxForceRelabel *bool `json:"selinux_force_relabel"`
 
 	// Disable documentation
-	ExcludeDocs *bool
+	ExcludeDocs *bool `json:"exclude_docs"`
 
-	ShellInit []shell.InitFile
+	ShellInit []shell.InitFile `json:"shell_init"`
 
 	// for RHSM configuration, we need to potentially distinguish the case
 	// when the user want the image to be subscribed on first boot and when not
-	RHSMConfig          map[subscription.RHSMStatus]*osbuild.RHSMStageOptions
-	SystemdLogind       []*osbuild.SystemdLogindStageOptions
-	CloudInit           []*osbuild.CloudInitStageOptions
-	Modprobe            []*osbuild.ModprobeStageOptions
-	DracutConf          []*osbuild.DracutConfStageOptions
-	SystemdUnit         []*osbuild.SystemdUnitStageOptions
-	Authselect          *osbuild.AuthselectStageOptions
-	SELinuxConfig       *osbuild.SELinuxConfigStageOptions
-	Tuned               *osbuild.TunedStageOptions
-	Tmpfilesd           []*osbuild.TmpfilesdStageOptions
-	PamLimitsConf       []*osbuild.PamLimitsConfStageOptions
-	Sysctld             []*osbuild.SysctldStageOptions
-	DNFConfig           []*osbuild.DNFConfigStageOptions
-	SshdConfig          *osbuild.SshdConfigStageOptions
-	Authconfig          *osbuild.AuthconfigStageOptions
-	PwQuality           *osbuild.PwqualityConfStageOptions
-	WAAgentConfig       *osbuild.WAAgentConfStageOptions
-	Grub2Config         *osbuild.GRUB2Config
-	DNFAutomaticConfig  *osbuild.DNFAutomaticConfigStageOptions
-	YumConfig           *osbuild.YumConfigStageOptions
-	YUMRepos            []*osbuild.YumReposStageOptions
-	Firewall            *osbuild.FirewallStageOptions
-	UdevRules           *osbuild.UdevRulesStageOptions
-	GCPGuestAgentConfig *osbuild.GcpGuestAgentConfigOptions
-	WSLConfig           *osbuild.WSLConfStageOptions
-
-	Files       []*fsnode.File
-	Directories []*fsnode.Directory
+	RHSMConfig      map[subscription.RHSMStatus]*osbuild.RHSMStageOptions `json:"rhsm_config"`
+	SystemdLogind   []*osbuild.SystemdLogindStageOptions                  `json:"systemd_logind"`
+	SystemdJournald []*osbuild.SystemdJournaldStageOptions                `json:"systemd_journald"`
+	CloudInit       []*osbuild.CloudInitStageOptions                      `json:"cloud_init"`
+	Modprobe        []*osbuild.ModprobeStageOptions                       `json:"modprobe"`
+	DracutConf      []*osbuild.DracutConfStageOptions                     `json:"dracut_conf"`
+	// Recreate the initramfs of the installed kernel after writing the
+	// dracut configuration
+	RegenerateInitramfs *bool                                   `json:"regenerate_initramfs"`
+	SystemdUnit         []*osbuild.SystemdUnitStageOptions      `json:"systemd_unit"`
+	Authselect          *osbuild.AuthselectStageOptions         `json:"authselect"`
+	SELinuxConfig       *osbuild.SELinuxConfigStageOptions      `json:"selinux_config"`
+	SELinuxPolicy       *selinuxpolicy.Options                  `json:"selinux_policy"`
+	Tuned               *osbuild.TunedStageOptions              `json:"tuned"`
+	Tmpfilesd           []*osbuild.TmpfilesdStageOptions        `json:"tmpfilesd"`
+	PamLimitsConf       []*osbuild.PamLimitsConfStageOptions    `json:"pam_limits_conf"`
+	Sysctld             []*osbuild.SysctldStageOptions          `json:"sysctld"`
+	DNFConfig           []*osbuild.DNFConfigStageOptions        `json:"dnf_config"`
+	SshdConfig          *osbuild.SshdConfigStageOptions         `json:"sshd_config"`
+	Authconfig          *osbuild.AuthconfigStageOptions         `json:"authconfig"`
+	PwQuality           *osbuild.PwqualityConfStageOptions      `json:"pwquality"`
+	WAAgentConfig       *osbuild.WAAgentConfStageOptions        `json:"waagent_config"`
+	Grub2Config         *osbuild.GRUB2Config                    `json:"grub2_config"`
+	DNFAutomaticConfig  *osbuild.DNFAutomaticConfigStageOptions `json:"dnf_automatic_config"`
+	YumConfig           *osbuild.YumConfigStageOptions          `json:"yum_config"`
+	YUMRepos            []*osbuild.YumReposStageOptions         `json:"yum_repos"`
+	Firewall            *osbuild.FirewallStageOptions           `json:"firewall"`
+	UdevRules           *osbuild.UdevRulesStageOptions          `json:"udev_rules"`
+	GCPGuestAgentConfig *osbuild.GcpGuestAgentConfigOptions     `json:"gcp_guest_agent_config"`
+	WSLConfig           *osbuild.WSLConfStageOptions            `json:"wsl_config"`
+
+	Files       []*fsnode.File      `json:"files"`
+	Directories []*fsnode.Directory `json:"directories"`
 }
 
 // InheritFrom inherits unset values from the provided parent configuration and
@@ -92,3 +101,210 @@ func (c *ImageConfig) InheritFrom(parentConfig *ImageConfig) *ImageConfig {
 	}
 	return &finalConfig
 }
+
+// BlueprintDropinFilename is the name of the drop-in configuration files
+// created from blueprint customizations. It sorts after the drop-ins of the
+// image type defaults, so that the blueprint settings take precedence.
+const BlueprintDropinFilename = "zz-blueprint.conf"
+
+// BlueprintModprobeOptionsFilename is the name of the modprobe drop-in with
+// the kernel module options of the blueprint. It differs from
+// BlueprintDropinFilename, which the modprobe stage uses for the kernel
+// module blacklist, so that neither file replaces the other.
+const BlueprintModprobeOptionsFilename = "zz-blueprint-options.conf"
+
+// WithCustomizations returns a new image configuration with the system
+// configuration customizations of the blueprint applied on top of c:
+//   - sysctl, kernel module, journald, logind and dracut customizations are
+//     written to an additional drop-in file that is added after the drop-ins
+//     of c, so conflicting settings are taken from the blueprint and all
+//     other settings of c are kept
+//   - the tuned customization replaces the profiles of c
+//   - the SELinux mode replaces the state of the SELinux configuration of c,
+//     the other SELinux customizations change the policy before the image
+//     is labelled
+//   - the containers customization adds the configuration files of the
+//     containers tools, see containersConfigFiles()
+//
+// The initramfs is recreated when the blueprint changes the dracut
+// configuration, so that it applies to the kernel of the image.
+func (c *ImageConfig) WithCustomizations(customizations *blueprint.Customizations) (*ImageConfig, error) {
+	bpConfig := &ImageConfig{}
+
+	sysctl, err := customizations.GetSysctl()
+	if err != nil {
+		return nil, err
+	}
+	if len(sysctl) > 0 {
+		var lines []osbuild.SysctldConfigLine
+		for _, s := range sysctl {
+			lines = append(lines, osbuild.SysctldConfigLine{Key: s.Key, Value: s.Value})
+		}
+		bpConfig.Sysctld = []*osbuild.SysctldStageOptions{
+			osbuild.NewSysctldStageOptions(BlueprintDropinFilename, lines),
+		}
+	}
+
+	modules, err := customizations.GetKernelModules()
+	if err != nil {
+		return nil, err
+	}
+	if modules != nil && len(modules.Blacklist) > 0 {
+		var commands osbuild.ModprobeConfigCmdList
+		for _, name := range modules.Blacklist {
+			commands = append(commands, osbuild.NewModprobeConfigCmdBlacklist(name))
+		}
+		bpConfig.Modprobe = []*osbuild.ModprobeStageOptions{
+			{
+				Filename: BlueprintDropinFilename,
+				Commands: commands,
+			},
+		}
+	}
+	if modules != nil && len(modules.Options) > 0 {
+		// the modprobe stage has no support for module options, so the
+		// configuration file is created directly, next to the blacklist
+		// drop-in of the modprobe stage
+		var lines []string
+		for _, o := range modules.Options {
+			lines = append(lines, fmt.Sprintf("options %s %s\n", o.Name, strings.TrimSpace(o.Options)))
+		}
+		file, err := fsnode.NewFile("/etc/modprobe.d/"+BlueprintModprobeOptionsFilename, nil, nil, nil, []byte(strings.Join(lines, "")))
+		if err != nil {
+			return nil, err
+		}
+		bpConfig.Files = append(bpConfig.Files, file)
+	}
+
+	tuned, err := customizations.GetTuned()
+	if err != nil {
+		return nil, err
+	}
+	if tuned != nil {
+		bpConfig.Tuned = osbuild.NewTunedStageOptions(tuned.Profiles...)
+	}
+
+	journald, err := customizations.GetJournald()
+	if err != nil {
+		return nil, err
+	}
+	if journald != nil {
+		bpConfig.SystemdJournald = []*osbuild.SystemdJournaldStageOptions{
+			{
+				Filename: BlueprintDropinFilename,
+				Config: osbuild.SystemdJournaldConfigDropin{
+					Journal: osbuild.SystemdJournaldConfigJournalSection{
+						Storage:         osbuild.ConfigStorage(journald.Storage),
+						MaxRetentionSec: journald.MaxRetentionSec,
+						MaxFileSec:      journald.MaxFileSec,
+					},
+				},
+			},
+		}
+	}
+
+	logind, err := customizations.GetLogind()
+	if err != nil {
+		return nil, err
+	}
+	if logind != nil {
+		bpConfig.SystemdLogind = []*osbuild.SystemdLogindStageOptions{
+			{
+				Filename: BlueprintDropinFilename,
+				Config: osbuild.SystemdLogindConfigDropin{
+					Login: osbuild.SystemdLogindConfigLoginSection{
+						NAutoVTs: logind.NAutoVTs,
+					},
+				},
+			},
+		}
+	}
+
+	dracut, err := customizations.GetDracut()
+	if err != nil {
+		return nil, err
+	}
+	if dracut != nil {
+		bpConfig.DracutConf = []*osbuild.DracutConfStageOptions{
+			{
+				Filename: BlueprintDropinFilename,
+				Config: osbuild.DracutConfigFile{
+					AddModules:  dracut.AddModules,
+					OmitModules: dracut.OmitModules,
+					AddDrivers:  dracut.AddDrivers,
+					Install:     dracut.Install,
+				},
+			},
+		}
+		bpConfig.RegenerateInitramfs = common.ToPtr(true)
+	}
+
+	selinux, err := customizations.GetSELinux()
+	if err != nil {
+		return nil, err
+	}
+	if selinux != nil {
+		if c.NoSElinux != nil && *c.NoSElinux {
+			return nil, fmt.Errorf("SELinux customizations are not supported for images without SELinux labelling")
+		}
+		if selinux.Mode != "" {
+			selinuxConfig := osbuild.SELinuxConfigStageOptions{}
+			if c.SELinuxConfig != nil {
+				selinuxConfig = *c.SELinuxConfig
+			}
+			selinuxConfig.State = osbuild.SELinuxPolicyState(selinux.Mode)
+			bpConfig.SELinuxConfig = &selinuxConfig
+		}
+		if len(selinux.Booleans) > 0 || len(selinux.FileContexts) > 0 || len(selinux.Modules) > 0 {
+			policy := &selinuxpolicy.Options{
+				Booleans: selinux.Booleans,
+				Modules:  selinux.Modules,
+			}
+			for _, fc := range selinux.FileContexts {
+				policy.FileContexts = append(policy.FileContexts, selinuxpolicy.FileContext{
+					Target: fc.Path,
+					Type:   fc.Type,
+				})
+			}
+			bpConfig.SELinuxPolicy = policy
+		}
+	}
+
+	containers, err := customizations.GetContainers()
+	if err != nil {
+		return nil, err
+	}
+	if containers != nil {
+		files, err := containersConfigFiles(containers)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			for _, custom := range customizations.GetFiles() {
+				if custom.Path == file.Path() {
+					return nil, fmt.Errorf("file %q is created by the containers customization and can't be customized", custom.Path)
+				}
+			}
+		}
+		bpConfig.Files = append(bpConfig.Files, files...)
+	}
+
+	bpConfig.Sysctld = appendDropins(c.Sysctld, bpConfig.Sysctld)
+	bpConfig.Modprobe = appendDropins(c.Modprobe, bpConfig.Modprobe)
+	bpConfig.Files = appendDropins(c.Files, bpConfig.Files)
+	bpConfig.SystemdJournald = appendDropins(c.SystemdJournald, bpConfig.SystemdJournald)
+	bpConfig.SystemdLogind = appendDropins(c.SystemdLogind, bpConfig.SystemdLogind)
+	bpConfig.DracutConf = appendDropins(c.DracutConf, bpConfig.DracutConf)
+
+	return bpConfig.InheritFrom(c), nil
+}
+
+// appendDropins returns a new slice with the drop-ins of the child added
+// after the ones of the parent. A nil child is returned as is, so that
+// InheritFrom() takes the parent value.
+func appendDropins[T any](parent, child []T) []T {
+	if child == nil {
+		return nil
+	}
+	return append(append([]T{}, parent...), child...)
+}
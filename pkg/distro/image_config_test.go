@@ -6,6 +6,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/selinuxpolicy"
+	"github.com/osbuild/images/pkg/blueprint"
 	"github.com/osbuild/images/pkg/osbuild"
 )
 
@@ -216,3 +218,123 @@ func TestImageConfigInheritFrom(t *testing.T) {
 		})
 	}
 }
+
+func TestImageConfigWithCustomizations(t *testing.T) {
+	defaultConfig := &ImageConfig{
+		Timezone: common.ToPtr("UTC"),
+		Sysctld: []*osbuild.SysctldStageOptions{
+			osbuild.NewSysctldStageOptions("sap.conf", []osbuild.SysctldConfigLine{{Key: "vm.swappiness", Value: "60"}}),
+		},
+		Modprobe: []*osbuild.ModprobeStageOptions{
+			{
+				Filename: "blacklist-floppy.conf",
+				Commands: osbuild.ModprobeConfigCmdList{osbuild.NewModprobeConfigCmdBlacklist("floppy")},
+			},
+		},
+		Tuned: osbuild.NewTunedStageOptions("sap-hana"),
+	}
+
+	t.Run("no-customizations", func(t *testing.T) {
+		config, err := defaultConfig.WithCustomizations(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, defaultConfig, config)
+	})
+
+	t.Run("customizations", func(t *testing.T) {
+		customizations := &blueprint.Customizations{
+			Sysctl: []blueprint.SysctlCustomization{{Key: "vm.swappiness", Value: "10"}},
+			KernelModules: &blueprint.KernelModulesCustomization{
+				Blacklist: []string{"nouveau"},
+				Options:   []blueprint.KernelModuleOptionsCustomization{{Name: "kvm_intel", Options: "nested=1"}},
+			},
+			Tuned:    &blueprint.TunedCustomization{Profiles: []string{"virtual-guest"}},
+			Journald: &blueprint.JournaldCustomization{Storage: "volatile"},
+			Logind:   &blueprint.LogindCustomization{NAutoVTs: common.ToPtr(0)},
+			Dracut:   &blueprint.DracutCustomization{AddDrivers: []string{"virtio_scsi"}},
+		}
+		config, err := defaultConfig.WithCustomizations(customizations)
+		assert.NoError(t, err)
+
+		// values that are not customized are inherited
+		assert.Equal(t, common.ToPtr("UTC"), config.Timezone)
+		// drop-ins are added after the defaults
+		assert.Equal(t, []*osbuild.SysctldStageOptions{
+			defaultConfig.Sysctld[0],
+			osbuild.NewSysctldStageOptions(BlueprintDropinFilename, []osbuild.SysctldConfigLine{{Key: "vm.swappiness", Value: "10"}}),
+		}, config.Sysctld)
+		assert.Equal(t, []*osbuild.ModprobeStageOptions{
+			defaultConfig.Modprobe[0],
+			{
+				Filename: BlueprintDropinFilename,
+				Commands: osbuild.ModprobeConfigCmdList{osbuild.NewModprobeConfigCmdBlacklist("nouveau")},
+			},
+		}, config.Modprobe)
+		assert.Len(t, config.Files, 1)
+		assert.Equal(t, "/etc/modprobe.d/"+BlueprintModprobeOptionsFilename, config.Files[0].Path())
+		assert.Equal(t, "options kvm_intel nested=1\n", string(config.Files[0].Data()))
+		assert.Equal(t, osbuild.StorageVolatile, config.SystemdJournald[0].Config.Journal.Storage)
+		assert.Equal(t, common.ToPtr(0), config.SystemdLogind[0].Config.Login.NAutoVTs)
+		assert.Equal(t, []string{"virtio_scsi"}, config.DracutConf[0].Config.AddDrivers)
+		assert.Equal(t, common.ToPtr(true), config.RegenerateInitramfs)
+		// the tuned profiles are replaced
+		assert.Equal(t, osbuild.NewTunedStageOptions("virtual-guest"), config.Tuned)
+		// the defaults are not modified
+		assert.Len(t, defaultConfig.Sysctld, 1)
+		assert.Len(t, defaultConfig.Modprobe, 1)
+	})
+
+	t.Run("selinux", func(t *testing.T) {
+		config := &ImageConfig{
+			SELinuxConfig: &osbuild.SELinuxConfigStageOptions{
+				State: osbuild.SELinuxStateEnforcing,
+				Type:  osbuild.SELinuxTypeTargeted,
+			},
+		}
+		customizations := &blueprint.Customizations{
+			SELinux: &blueprint.SELinuxCustomization{
+				Mode:     "permissive",
+				Booleans: map[string]bool{"httpd_can_network_connect": true},
+				FileContexts: []blueprint.SELinuxFileContextCustomization{
+					{Path: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+				},
+			},
+		}
+		newConfig, err := config.WithCustomizations(customizations)
+		assert.NoError(t, err)
+		assert.Equal(t, &osbuild.SELinuxConfigStageOptions{
+			State: osbuild.SELinuxStatePermissive,
+			Type:  osbuild.SELinuxTypeTargeted,
+		}, newConfig.SELinuxConfig)
+		assert.Equal(t, osbuild.SELinuxStateEnforcing, config.SELinuxConfig.State)
+		assert.Equal(t, &selinuxpolicy.Options{
+			Booleans: map[string]bool{"httpd_can_network_connect": true},
+			FileContexts: []selinuxpolicy.FileContext{
+				{Target: "/srv/www(/.*)?", Type: "httpd_sys_content_t"},
+			},
+		}, newConfig.SELinuxPolicy)
+
+		_, err = (&ImageConfig{NoSElinux: common.ToPtr(true)}).WithCustomizations(customizations)
+		assert.EqualError(t, err, "SELinux customizations are not supported for images without SELinux labelling")
+	})
+
+	t.Run("containers", func(t *testing.T) {
+		customizations := &blueprint.Customizations{
+			Containers: &blueprint.ContainersCustomization{
+				UnqualifiedSearchRegistries: []string{"registry.access.redhat.com"},
+			},
+		}
+		config, err := defaultConfig.WithCustomizations(customizations)
+		assert.NoError(t, err)
+		assert.Len(t, config.Files, 1)
+		assert.Equal(t, "/etc/containers/registries.conf.d/zz-blueprint.conf", config.Files[0].Path())
+
+		customizations.Files = []blueprint.FileCustomization{{Path: "/etc/containers/registries.conf.d/zz-blueprint.conf"}}
+		_, err = defaultConfig.WithCustomizations(customizations)
+		assert.EqualError(t, err, `file "/etc/containers/registries.conf.d/zz-blueprint.conf" is created by the containers customization and can't be customized`)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := defaultConfig.WithCustomizations(&blueprint.Customizations{Tuned: &blueprint.TunedCustomization{}})
+		assert.EqualError(t, err, "tuned customization requires at least one profile")
+	})
+}
@@ -0,0 +1,28 @@
+package distro
+
+import (
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/image"
+)
+
+// ApplyInstallerCustomizations adds the installer customizations of the
+// blueprint to the installer of an image. The kernel options, drivers, dracut
+// modules and enabled anaconda modules are appended to the defaults of the
+// image type.
+func ApplyInstallerCustomizations(ic *image.InstallerCustomizations, customizations *blueprint.Customizations) error {
+	installer, err := customizations.GetInstaller()
+	if err != nil {
+		return err
+	}
+	if installer == nil {
+		return nil
+	}
+
+	ic.AdditionalKernelOpts = append(ic.AdditionalKernelOpts, installer.GetKernelOptions()...)
+	ic.AdditionalDrivers = append(ic.AdditionalDrivers, installer.Drivers...)
+	ic.AdditionalDracutModules = append(ic.AdditionalDracutModules, installer.DracutModules...)
+	ic.AdditionalAnacondaModules = append(ic.AdditionalAnacondaModules, installer.GetEnabledModules()...)
+	ic.DisabledAnacondaModules = installer.GetDisabledModules()
+	ic.HiddenSpokes = installer.LockedScreens
+	return nil
+}
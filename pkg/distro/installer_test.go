@@ -0,0 +1,53 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/image"
+)
+
+func TestApplyInstallerCustomizations(t *testing.T) {
+	customizations := &blueprint.Customizations{
+		Installer: &blueprint.InstallerCustomization{
+			KernelAppend:  "inst.text console=ttyS0",
+			Drivers:       []string{"megaraid_sas"},
+			DracutModules: []string{"nvdimm"},
+			Modules: &blueprint.AnacondaModulesCustomization{
+				Enable:  []string{"org.fedoraproject.Anaconda.Modules.Localization"},
+				Disable: []string{"org.fedoraproject.Anaconda.Modules.Network"},
+			},
+			LockedScreens: []string{"UserSpoke"},
+		},
+	}
+
+	ic := image.InstallerCustomizations{
+		AdditionalKernelOpts:      []string{"inst.webui"},
+		AdditionalAnacondaModules: []string{"org.fedoraproject.Anaconda.Modules.Users"},
+		AdditionalDracutModules:   []string{"prefixdevname"},
+		AdditionalDrivers:         []string{"cuse"},
+	}
+	assert.NoError(t, ApplyInstallerCustomizations(&ic, customizations))
+	assert.Equal(t, image.InstallerCustomizations{
+		AdditionalKernelOpts: []string{"inst.webui", "inst.text", "console=ttyS0"},
+		AdditionalAnacondaModules: []string{
+			"org.fedoraproject.Anaconda.Modules.Users",
+			"org.fedoraproject.Anaconda.Modules.Localization",
+		},
+		AdditionalDracutModules: []string{"prefixdevname", "nvdimm"},
+		AdditionalDrivers:       []string{"cuse", "megaraid_sas"},
+		DisabledAnacondaModules: []string{"org.fedoraproject.Anaconda.Modules.Network"},
+		HiddenSpokes:            []string{"UserSpoke"},
+	}, ic)
+
+	// without installer customizations the defaults are kept
+	defaults := image.InstallerCustomizations{AdditionalDrivers: []string{"cuse"}}
+	ic = defaults
+	assert.NoError(t, ApplyInstallerCustomizations(&ic, nil))
+	assert.Equal(t, defaults, ic)
+
+	customizations.Installer.Modules.Enable = []string{"Users"}
+	assert.EqualError(t, ApplyInstallerCustomizations(&ic, customizations), `invalid anaconda module "Users": must start with "org.fedoraproject.Anaconda.Modules."`)
+}
@@ -0,0 +1,109 @@
+package distro
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/oscap"
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// ApplyOpenSCAPCustomizations configures the OpenSCAP remediation of the OS
+// from the OpenSCAP customization of the blueprint, including the tailoring
+// of the profile and the embedding of a user supplied tailoring file. The
+// default datastream of the distribution is used if the customization
+// doesn't name one.
+func ApplyOpenSCAPCustomizations(osc *manifest.OSCustomizations, oscapConfig *blueprint.OpenSCAPCustomization, defaultDatastream string) error {
+	datastream := oscapConfig.DataStream
+	if datastream == "" {
+		datastream = defaultDatastream
+	}
+
+	oscapStageOptions := osbuild.OscapConfig{
+		Datastream: datastream,
+		ProfileID:  oscapConfig.ProfileID,
+	}
+
+	if oscapConfig.Tailoring != nil {
+		newProfile, tailoringFilepath, tailoringDir, err := oscap.GetTailoringFile(oscapConfig.ProfileID)
+		if err != nil {
+			return fmt.Errorf("unexpected error creating tailoring file options: %v", err)
+		}
+
+		var overrides []osbuild.OscapAutotailorOverride
+		for _, override := range oscapConfig.Tailoring.Overrides {
+			overrides = append(overrides, osbuild.OscapAutotailorOverride{
+				Var:   override.Var,
+				Value: override.Value,
+			})
+		}
+
+		tailoringOptions := osbuild.OscapAutotailorConfig{
+			Selected:   oscapConfig.Tailoring.Selected,
+			Unselected: oscapConfig.Tailoring.Unselected,
+			Overrides:  overrides,
+			NewProfile: newProfile,
+		}
+
+		osc.OpenSCAPTailorConfig = osbuild.NewOscapAutotailorStageOptions(
+			tailoringFilepath,
+			oscapStageOptions,
+			tailoringOptions,
+		)
+
+		// overwrite the profile id with the new tailoring id
+		oscapStageOptions.ProfileID = newProfile
+		oscapStageOptions.Tailoring = tailoringFilepath
+
+		// add the parent directory for the tailoring file
+		osc.Directories = append(osc.Directories, tailoringDir)
+	} else if tailoringFile := oscapConfig.TailoringFile; tailoringFile != nil {
+		format := oscap.TailoringFormat(tailoringFile.Format)
+		customFilepath, tailoringDir, err := oscap.GetCustomTailoringFile(format)
+		if err != nil {
+			return fmt.Errorf("unexpected error creating tailoring file options: %v", err)
+		}
+
+		// embed the user supplied tailoring file in the image
+		osc.Directories = append(osc.Directories, tailoringDir)
+		if tailoringFile.URL != "" {
+			osc.RemoteFiles = append(osc.RemoteFiles, osbuild.RemoteFile{
+				URL:      tailoringFile.URL,
+				Checksum: tailoringFile.Checksum,
+				Path:     customFilepath,
+			})
+		} else {
+			file, err := fsnode.NewFile(customFilepath, nil, nil, nil, []byte(tailoringFile.Data))
+			if err != nil {
+				return fmt.Errorf("unexpected error creating tailoring file: %v", err)
+			}
+			osc.Files = append(osc.Files, file)
+		}
+
+		tailoringFilepath := customFilepath
+		if format == oscap.TailoringFormatJSON {
+			// the JSON tailoring needs to be converted to an XCCDF
+			// tailoring file before it can be used for remediation
+			_, tailoringFilepath, _, err = oscap.GetTailoringFile(oscapConfig.ProfileID)
+			if err != nil {
+				return fmt.Errorf("unexpected error creating tailoring file options: %v", err)
+			}
+			osc.OpenSCAPTailorConfig = osbuild.NewOscapAutotailorStageOptions(
+				tailoringFilepath,
+				oscapStageOptions,
+				osbuild.OscapAutotailorConfig{
+					NewProfile:    tailoringFile.ProfileID,
+					JSONTailoring: customFilepath,
+				},
+			)
+		}
+
+		oscapStageOptions.ProfileID = tailoringFile.ProfileID
+		oscapStageOptions.Tailoring = tailoringFilepath
+	}
+
+	osc.OpenSCAPConfig = osbuild.NewOscapRemediationStageOptions(oscapStageOptions)
+	return nil
+}
@@ -0,0 +1,84 @@
+package distro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+func TestApplyOpenSCAPCustomizations(t *testing.T) {
+	const datastream = "/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml"
+	const profile = "xccdf_org.ssgproject.content_profile_cis"
+
+	t.Run("default-datastream", func(t *testing.T) {
+		var osc manifest.OSCustomizations
+		require.NoError(t, ApplyOpenSCAPCustomizations(&osc, &blueprint.OpenSCAPCustomization{ProfileID: profile}, datastream))
+		assert.Nil(t, osc.OpenSCAPTailorConfig)
+		assert.Equal(t, datastream, osc.OpenSCAPConfig.Config.Datastream)
+		assert.Equal(t, profile, osc.OpenSCAPConfig.Config.ProfileID)
+	})
+
+	t.Run("tailoring", func(t *testing.T) {
+		var osc manifest.OSCustomizations
+		oscapConfig := &blueprint.OpenSCAPCustomization{
+			DataStream: "/custom-ds.xml",
+			ProfileID:  profile,
+			Tailoring: &blueprint.OpenSCAPTailoringCustomizations{
+				Selected:  []string{"fast_rule"},
+				Overrides: []blueprint.OpenSCAPTailoringOverride{{Var: "var_password_pam_minlen", Value: "16"}},
+			},
+		}
+		require.NoError(t, ApplyOpenSCAPCustomizations(&osc, oscapConfig, datastream))
+		require.NotNil(t, osc.OpenSCAPTailorConfig)
+		assert.Equal(t, "/custom-ds.xml", osc.OpenSCAPTailorConfig.Config.Datastream)
+		assert.Equal(t, []string{"fast_rule"}, osc.OpenSCAPTailorConfig.Config.Selected)
+		assert.Equal(t, []osbuild.OscapAutotailorOverride{{Var: "var_password_pam_minlen", Value: "16"}}, osc.OpenSCAPTailorConfig.Config.Overrides)
+		assert.Equal(t, profile+"_osbuild_tailoring", osc.OpenSCAPConfig.Config.ProfileID)
+		assert.Equal(t, osc.OpenSCAPTailorConfig.Filepath, osc.OpenSCAPConfig.Config.Tailoring)
+		assert.Len(t, osc.Directories, 1)
+	})
+
+	t.Run("json-tailoring-file", func(t *testing.T) {
+		var osc manifest.OSCustomizations
+		oscapConfig := &blueprint.OpenSCAPCustomization{
+			ProfileID: profile,
+			TailoringFile: &blueprint.OpenSCAPTailoringFileCustomization{
+				Format:    "json",
+				ProfileID: "cis_tailored",
+				URL:       "https://example.com/tailoring.json",
+				Checksum:  "sha256:4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3",
+			},
+		}
+		require.NoError(t, ApplyOpenSCAPCustomizations(&osc, oscapConfig, datastream))
+		require.Len(t, osc.RemoteFiles, 1)
+		jsonPath := osc.RemoteFiles[0].Path
+		assert.Equal(t, "/usr/share/xml/osbuild-openscap-data/tailoring.json", jsonPath)
+		require.NotNil(t, osc.OpenSCAPTailorConfig)
+		assert.Equal(t, jsonPath, osc.OpenSCAPTailorConfig.Config.JSONTailoring)
+		assert.Equal(t, "cis_tailored", osc.OpenSCAPTailorConfig.Config.NewProfile)
+		// the remediation uses the XCCDF tailoring generated from the JSON
+		assert.Equal(t, "cis_tailored", osc.OpenSCAPConfig.Config.ProfileID)
+		assert.Equal(t, osc.OpenSCAPTailorConfig.Filepath, osc.OpenSCAPConfig.Config.Tailoring)
+	})
+
+	t.Run("xccdf-tailoring-file", func(t *testing.T) {
+		var osc manifest.OSCustomizations
+		oscapConfig := &blueprint.OpenSCAPCustomization{
+			ProfileID: profile,
+			TailoringFile: &blueprint.OpenSCAPTailoringFileCustomization{
+				ProfileID: "cis_tailored",
+				Data:      "<xml/>",
+			},
+		}
+		require.NoError(t, ApplyOpenSCAPCustomizations(&osc, oscapConfig, datastream))
+		assert.Nil(t, osc.OpenSCAPTailorConfig)
+		require.Len(t, osc.Files, 1)
+		assert.Equal(t, osc.Files[0].Path(), osc.OpenSCAPConfig.Config.Tailoring)
+		assert.Equal(t, "cis_tailored", osc.OpenSCAPConfig.Config.ProfileID)
+	})
+}
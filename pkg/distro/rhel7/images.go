@@ -25,7 +25,11 @@ func osCustomizations(
 	c *blueprint.Customizations,
 ) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	imageConfig, err := t.getDefaultImageConfig().WithCustomizations(c)
+	if err != nil {
+		// The customizations have already been validated in checkOptions()
+		panic(fmt.Sprintf("failed to apply system configuration customizations: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -142,7 +146,6 @@ func osCustomizations(
 		osc.FactAPIType = &options.Facts.APIType
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -191,12 +194,17 @@ func osCustomizations(
 	osc.Grub2Config = imageConfig.Grub2Config
 	osc.Sysconfig = imageConfig.Sysconfig
 	osc.SystemdLogind = imageConfig.SystemdLogind
+	osc.SystemdJournald = imageConfig.SystemdJournald
 	osc.CloudInit = imageConfig.CloudInit
 	osc.Modprobe = imageConfig.Modprobe
 	osc.DracutConf = imageConfig.DracutConf
+	if imageConfig.RegenerateInitramfs != nil {
+		osc.RegenerateInitramfs = *imageConfig.RegenerateInitramfs
+	}
 	osc.SystemdUnit = imageConfig.SystemdUnit
 	osc.Authselect = imageConfig.Authselect
 	osc.SELinuxConfig = imageConfig.SELinuxConfig
+	osc.SELinuxPolicy = imageConfig.SELinuxPolicy
 	osc.Tuned = imageConfig.Tuned
 	osc.Tmpfilesd = imageConfig.Tmpfilesd
 	osc.PamLimitsConf = imageConfig.PamLimitsConf
@@ -99,6 +99,10 @@ func (t *imageType) Exports() []string {
 	return t.exports
 }
 
+func (t *imageType) RecommendedResources() distro.VMResources {
+	return distro.RecommendedResourcesFallback()
+}
+
 func (t *imageType) BootMode() distro.BootMode {
 	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
 		return distro.BOOT_HYBRID
@@ -245,6 +249,27 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
 	}
 
+	if len(bp.Flatpaks) > 0 {
+		return warnings, fmt.Errorf("embedding flatpaks is not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	if customizations.GetFIPS() {
+		return warnings, fmt.Errorf("FIPS mode is not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	if _, err := t.getDefaultImageConfig().WithCustomizations(customizations); err != nil {
+		return warnings, err
+	}
+	if options.Subscription != nil {
+		if err := options.Subscription.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
+	if err := distro.CheckEmbeddedContainers(customizations, bp.Containers); err != nil {
+		return warnings, err
+	}
+
 	mountpoints := customizations.GetFilesystems()
 
 	err := blueprint.CheckMountpointsPolicy(mountpoints, pathpolicy.MountpointPolicies)
@@ -0,0 +1,38 @@
+package rhel8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionsAvailability(t *testing.T) {
+	// image types of the definitions that are only available on some
+	// releases
+	for _, tc := range []struct {
+		distro    string
+		minor     int
+		arch      string
+		imageType string
+		available bool
+	}{
+		{"rhel", 5, "aarch64", "vhd", false},
+		{"rhel", 6, "aarch64", "vhd", true},
+		{"rhel", 5, "x86_64", "vhd", true},
+		{"centos", 0, "aarch64", "vhd", true},
+		{"rhel", 5, "x86_64", "azure-eap7-rhui", false},
+		{"rhel", 6, "x86_64", "azure-eap7-rhui", true},
+		{"rhel", 5, "x86_64", "ec2-sap", false},
+		{"rhel", 6, "x86_64", "ec2-sap", true},
+		{"centos", 0, "x86_64", "azure-rhui", false},
+		{"rhel", 4, "s390x", "qcow2", true},
+	} {
+		d := newDistro(tc.distro, tc.minor)
+		require.NoError(t, d.LoadError())
+		arch, err := d.GetArch(tc.arch)
+		require.NoError(t, err)
+		_, err = arch.GetImageType(tc.imageType)
+		assert.Equal(t, tc.available, err == nil, "%s on %s/%s", tc.imageType, d.Name(), tc.arch)
+	}
+}
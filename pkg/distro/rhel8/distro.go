@@ -9,6 +9,7 @@ import (
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/oscap"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/defs"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/runner"
@@ -48,6 +49,10 @@ type distribution struct {
 	runner             runner.Runner
 	arches             map[string]distro.Arch
 	defaultImageConfig *distro.ImageConfig
+	defs               *defs.Definitions
+	// error loading the image type definitions, the distribution has no
+	// usable architectures if set
+	defsErr error
 }
 
 // RHEL-based OS image configuration defaults
@@ -94,6 +99,9 @@ func (d *distribution) ListArches() []string {
 }
 
 func (d *distribution) GetArch(name string) (distro.Arch, error) {
+	if d.defsErr != nil {
+		return nil, d.defsErr
+	}
 	arch, exists := d.arches[name]
 	if !exists {
 		return nil, errors.New("invalid architecture: " + name)
@@ -118,6 +126,17 @@ func (d *distribution) isRHEL() bool {
 	return strings.HasPrefix(d.name, "rhel")
 }
 
+// id returns the os-release ID of the distribution, e.g. "rhel" or "centos".
+func (d *distribution) id() string {
+	return strings.SplitN(d.name, "-", 2)[0]
+}
+
+// LoadError returns the error the image type definitions of the
+// distribution failed to load with, if any.
+func (d *distribution) LoadError() error {
+	return d.defsErr
+}
+
 func (d *distribution) getDefaultImageConfig() *distro.ImageConfig {
 	return d.defaultImageConfig
 }
@@ -163,6 +182,18 @@ func NewCentos() distro.Distro {
 	return newDistro("centos", 0)
 }
 
+// NewAlmaLinux8 creates AlmaLinux 8. Rebuilds of RHEL only track the
+// current minor release, which the image types are defined for.
+func NewAlmaLinux8() distro.Distro {
+	return newDistro("almalinux", 10)
+}
+
+// NewRocky8 creates Rocky Linux 8, which tracks the current minor
+// release like AlmaLinux.
+func NewRocky8() distro.Distro {
+	return newDistro("rocky", 10)
+}
+
 func newDistro(name string, minor int) *distribution {
 	var rd distribution
 	switch name {
@@ -192,9 +223,41 @@ func newDistro(name string, minor int) *distribution {
 			runner:             &runner.CentOS{Version: uint64(8)},
 			defaultImageConfig: defaultDistroImageConfig,
 		}
+	case "almalinux":
+		rd = distribution{
+			name:               "almalinux-8",
+			product:            "AlmaLinux",
+			osVersion:          fmt.Sprintf("8.%d", minor),
+			releaseVersion:     "8",
+			modulePlatformID:   "platform:el8",
+			vendor:             "almalinux",
+			ostreeRefTmpl:      "almalinux/8/%s/edge",
+			isolabelTmpl:       fmt.Sprintf("AlmaLinux-8-%d-%%s-dvd", minor),
+			runner:             &runner.CentOS{Version: uint64(8)},
+			defaultImageConfig: defaultDistroImageConfig,
+		}
+	case "rocky":
+		rd = distribution{
+			name:               "rocky-8",
+			product:            "Rocky Linux",
+			osVersion:          fmt.Sprintf("8.%d", minor),
+			releaseVersion:     "8",
+			modulePlatformID:   "platform:el8",
+			vendor:             "rocky",
+			ostreeRefTmpl:      "rocky/8/%s/edge",
+			isolabelTmpl:       fmt.Sprintf("Rocky-8-%d-%%s-dvd", minor),
+			runner:             &runner.CentOS{Version: uint64(8)},
+			defaultImageConfig: defaultDistroImageConfig,
+		}
 	default:
 		panic(fmt.Sprintf("unknown distro name: %s", name))
 	}
+	definitions, err := loadDefinitions()
+	if err != nil {
+		rd.defsErr = fmt.Errorf("cannot load the image type definitions of %s: %w", rd.name, err)
+		return &rd
+	}
+	rd.defs = definitions
 
 	// Architecture definitions
 	x86_64 := architecture{
@@ -216,303 +279,18 @@ func newDistro(name string, minor int) *distribution {
 		name:   platform.ARCH_S390X.String(),
 	}
 
-	ociImgType := qcow2ImgType(rd)
-	ociImgType.name = "oci"
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "0.10",
-			},
-		},
-		qcow2ImgType(rd),
-		ociImgType,
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-			},
-		},
-		openstackImgType(),
-	)
-
-	ec2X86Platform := &platform.X86{
-		BIOS:       true,
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_RAW,
-		},
-	}
-	x86_64.addImageTypes(
-		ec2X86Platform,
-		amiImgTypeX86_64(rd),
-	)
-
-	bareMetalX86Platform := &platform.X86{
-		BasePlatform: platform.BasePlatform{
-			FirmwarePackages: []string{
-				"microcode_ctl", // ??
-				"iwl1000-firmware",
-				"iwl100-firmware",
-				"iwl105-firmware",
-				"iwl135-firmware",
-				"iwl2000-firmware",
-				"iwl2030-firmware",
-				"iwl3160-firmware",
-				"iwl5000-firmware",
-				"iwl5150-firmware",
-				"iwl6050-firmware",
-			},
-		},
-		BIOS:       true,
-		UEFIVendor: rd.vendor,
-	}
-
-	x86_64.addImageTypes(
-		bareMetalX86Platform,
-		edgeOCIImgType(rd),
-		edgeCommitImgType(rd),
-		edgeInstallerImgType(rd),
-		imageInstaller(),
-	)
-
-	gceX86Platform := &platform.X86{
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_GCE,
-		},
-	}
-
-	x86_64.addImageTypes(
-		gceX86Platform,
-		gceImgType(rd),
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_VMDK,
-			},
-		},
-		vmdkImgType(),
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_OVA,
-			},
-		},
-		ovaImgType(),
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{},
-		tarImgType(),
-		wslImgType(),
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "0.10",
-			},
-		},
-		qcow2ImgType(rd),
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-			},
-		},
-		openstackImgType(),
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{},
-		tarImgType(),
-		wslImgType(),
-	)
-
-	bareMetalAarch64Platform := &platform.Aarch64{
-		BasePlatform: platform.BasePlatform{},
-		UEFIVendor:   rd.vendor,
-	}
-
-	aarch64.addImageTypes(
-		bareMetalAarch64Platform,
-		edgeOCIImgType(rd),
-		edgeCommitImgType(rd),
-		edgeInstallerImgType(rd),
-		imageInstaller(),
-	)
-
-	rawAarch64Platform := &platform.Aarch64{
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_RAW,
-		},
-	}
-
-	aarch64.addImageTypes(
-		rawAarch64Platform,
-		amiImgTypeAarch64(rd),
-		minimalRawImgType(rd),
-	)
-
-	ppc64le.addImageTypes(
-		&platform.PPC64LE{
-			BIOS: true,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "0.10",
-			},
-		},
-		qcow2ImgType(rd),
-	)
-
-	ppc64le.addImageTypes(
-		&platform.PPC64LE{},
-		tarImgType(),
-	)
-
-	s390x.addImageTypes(
-		&platform.S390X{
-			Zipl: true,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "0.10",
-			},
-		},
-		qcow2ImgType(rd),
-	)
-
-	s390x.addImageTypes(
-		&platform.S390X{},
-		tarImgType(),
-	)
-
-	azureX64Platform := &platform.X86{
-		BIOS:       true,
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_VHD,
-		},
-	}
-
-	azureAarch64Platform := &platform.Aarch64{
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_VHD,
-		},
+	arches := []*architecture{&x86_64, &aarch64, &ppc64le}
+	// s390x is only supported on RHEL
+	if rd.isRHEL() {
+		arches = append(arches, &s390x)
 	}
-
-	rawUEFIx86Platform := &platform.X86{
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_RAW,
-		},
-		BIOS:       false,
-		UEFIVendor: rd.vendor,
+	if err := addImageTypesFromDefinitions(&rd, arches...); err != nil {
+		rd.defsErr = fmt.Errorf("invalid image type definitions for %s: %w", rd.name, err)
+		return &rd
 	}
 
-	x86_64.addImageTypes(
-		rawUEFIx86Platform,
-		minimalRawImgType(rd),
-	)
-
-	if rd.isRHEL() {
-		if !common.VersionLessThan(rd.osVersion, "8.6") {
-			// image types only available on 8.6 and later on RHEL
-			// These edge image types require FDO which aren't available on older versions
-			x86_64.addImageTypes(
-				bareMetalX86Platform,
-				edgeRawImgType(),
-			)
-
-			x86_64.addImageTypes(
-				rawUEFIx86Platform,
-				edgeSimplifiedInstallerImgType(rd),
-			)
-
-			azureEap := azureEap7RhuiImgType()
-			x86_64.addImageTypes(azureX64Platform, azureEap)
-
-			aarch64.addImageTypes(
-				rawAarch64Platform,
-				edgeRawImgType(),
-				edgeSimplifiedInstallerImgType(rd),
-			)
-
-			// The Azure image types require hyperv-daemons which isn't available on older versions
-			aarch64.addImageTypes(azureAarch64Platform, azureRhuiImgType(), azureByosImgType())
-		}
-
-		// add azure to RHEL distro only
-		x86_64.addImageTypes(azureX64Platform, azureRhuiImgType(), azureByosImgType(), azureSapRhuiImgType(rd))
-
-		// keep the RHEL EC2 x86_64 images before 8.9 BIOS-only for backward compatibility
-		if common.VersionLessThan(rd.osVersion, "8.9") {
-			ec2X86Platform = &platform.X86{
-				BIOS: true,
-				BasePlatform: platform.BasePlatform{
-					ImageFormat: platform.FORMAT_RAW,
-				},
-			}
-		}
-
-		// add ec2 image types to RHEL distro only
-		x86_64.addImageTypes(ec2X86Platform, ec2ImgTypeX86_64(rd), ec2HaImgTypeX86_64(rd))
-		aarch64.addImageTypes(rawAarch64Platform, ec2ImgTypeAarch64(rd))
-
-		if rd.osVersion != "8.5" {
-			// NOTE: RHEL 8.5 is going away and these image types require some
-			// work to get working, so we just disable them here until the
-			// whole distro gets deleted
-			x86_64.addImageTypes(ec2X86Platform, ec2SapImgTypeX86_64(rd))
-		}
-
-		// add GCE RHUI image to RHEL only
-		x86_64.addImageTypes(gceX86Platform, gceRhuiImgType(rd))
-
-		// add s390x to RHEL distro only
-		rd.addArches(s390x)
-	} else {
-		x86_64.addImageTypes(
-			bareMetalX86Platform,
-			edgeRawImgType(),
-		)
-
-		x86_64.addImageTypes(
-			rawUEFIx86Platform,
-			edgeSimplifiedInstallerImgType(rd),
-		)
-
-		x86_64.addImageTypes(azureX64Platform, azureImgType())
-
-		aarch64.addImageTypes(
-			rawAarch64Platform,
-			edgeRawImgType(),
-			edgeSimplifiedInstallerImgType(rd),
-		)
-
-		aarch64.addImageTypes(azureAarch64Platform, azureImgType())
+	for _, arch := range arches {
+		rd.addArches(*arch)
 	}
-	rd.addArches(x86_64, aarch64, ppc64le)
 	return &rd
 }
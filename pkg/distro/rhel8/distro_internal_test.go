@@ -6,19 +6,38 @@ import (
 	"testing"
 
 	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/defs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// testPartitionTables returns the base partition tables of the definitions
+// with the given name, as used by RHEL 8.10
+func testPartitionTables(name string) basePartitionTableFunc {
+	return func(t *imageType) (disk.PartitionTable, bool) {
+		definitions, err := loadDefinitions()
+		if err != nil {
+			panic(err)
+		}
+		return definitions.BasePartitionTable(name, defs.Distro{
+			Name:       "rhel-810",
+			OSVersion:  "8.10",
+			Releasever: "8",
+			Arch:       t.arch.name,
+		})
+	}
+}
+
 var testBasicImageType = imageType{
 	name:                "test",
-	basePartitionTables: defaultBasePartitionTables,
+	basePartitionTables: testPartitionTables("default"),
 }
 
 var testEc2ImageType = imageType{
 	name:                "test_ec2",
-	basePartitionTables: ec2BasePartitionTables,
+	basePartitionTables: testPartitionTables("ec2"),
 }
 
 var mountpoints = []blueprint.FilesystemCustomization{
@@ -61,7 +80,7 @@ func TestDistro_Ec2PartitionTables(t *testing.T) {
 			name: archName,
 		}
 		pt, err := testEc2ImageType.getPartitionTable(mountpoints, distro.ImageOptions{}, rng)
-		if _, exists := testEc2ImageType.basePartitionTables[archName]; exists {
+		if _, exists := testEc2ImageType.getBasePartitionTable(); exists {
 			require.Nil(t, err)
 			for _, m := range mountpoints {
 				assert.True(t, pt.ContainsMountpoint(m.Mountpoint))
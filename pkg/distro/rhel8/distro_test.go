@@ -641,6 +641,25 @@ func TestRhel8_Name(t *testing.T) {
 	assert.Equal(t, "rhel-8", distro.Name())
 }
 
+func TestRhel8_Rebuilds(t *testing.T) {
+	for _, d := range []distro.Distro{rhel8.NewAlmaLinux8(), rhel8.NewRocky8()} {
+		t.Run(d.Name(), func(t *testing.T) {
+			assert.Equal(t, "8", d.Releasever())
+			assert.Equal(t, "platform:el8", d.ModulePlatformID())
+
+			// image types that need RHUI or RHSM are only available on RHEL
+			arch, err := d.GetArch("x86_64")
+			require.NoError(t, err)
+			for _, name := range []string{"ec2", "azure-rhui", "gce-rhui"} {
+				_, err := arch.GetImageType(name)
+				assert.Error(t, err, name)
+			}
+			_, err = arch.GetImageType("qcow2")
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestRhel8_ModulePlatformID(t *testing.T) {
 	distro := rhel8.New()
 	assert.Equal(t, "platform:el8", distro.ModulePlatformID())
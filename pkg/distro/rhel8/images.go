@@ -5,7 +5,7 @@ import (
 	"math/rand"
 
 	"github.com/osbuild/images/internal/fdo"
-	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/firstboot"
 	"github.com/osbuild/images/internal/ignition"
 	"github.com/osbuild/images/internal/oscap"
 	"github.com/osbuild/images/internal/users"
@@ -29,7 +29,11 @@ func osCustomizations(
 	c *blueprint.Customizations,
 ) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	imageConfig, err := t.getDefaultImageConfig().WithCustomizations(c)
+	if err != nil {
+		// The customizations have already been validated in checkOptions()
+		panic(fmt.Sprintf("failed to apply system configuration customizations: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -49,11 +53,14 @@ func osCustomizations(
 		}
 	}
 
+	osc.FIPS = c.GetFIPS()
+
 	osc.ExtraBasePackages = osPackageSet.Include
 	osc.ExcludeBasePackages = osPackageSet.Exclude
 	osc.ExtraBaseRepos = osPackageSet.Repositories
 
 	osc.Containers = containers
+	osc.SecureExecution = options.SecureExecution
 
 	osc.GPGKeyFiles = imageConfig.GPGKeyFiles
 	if imageConfig.ExcludeDocs != nil {
@@ -138,7 +145,6 @@ func osCustomizations(
 		osc.FactAPIType = &options.Facts.APIType
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -153,6 +159,10 @@ func osCustomizations(
 		panic(fmt.Sprintf("failed to convert file customizations to fs node files: %v", err))
 	}
 
+	if fb := c.GetFirstBoot(); fb != nil {
+		osc.FirstBoot = firstboot.OptionsFromBP(*fb)
+	}
+
 	// set yum repos first, so it doesn't get overridden by
 	// imageConfig.YUMRepos
 	osc.YUMRepos = imageConfig.YUMRepos
@@ -186,43 +196,9 @@ func osCustomizations(
 		if t.rpmOstree {
 			panic("unexpected oscap options for ostree image type")
 		}
-		var datastream = oscapConfig.DataStream
-		if datastream == "" {
-			datastream = oscap.DefaultRHEL8Datastream(t.arch.distro.isRHEL())
-		}
-
-		oscapStageOptions := osbuild.OscapConfig{
-			Datastream: datastream,
-			ProfileID:  oscapConfig.ProfileID,
-		}
-
-		if oscapConfig.Tailoring != nil {
-			newProfile, tailoringFilepath, tailoringDir, err := oscap.GetTailoringFile(oscapConfig.ProfileID)
-			if err != nil {
-				panic(fmt.Sprintf("unexpected error creating tailoring file options: %v", err))
-			}
-
-			tailoringOptions := osbuild.OscapAutotailorConfig{
-				Selected:   oscapConfig.Tailoring.Selected,
-				Unselected: oscapConfig.Tailoring.Unselected,
-				NewProfile: newProfile,
-			}
-
-			osc.OpenSCAPTailorConfig = osbuild.NewOscapAutotailorStageOptions(
-				tailoringFilepath,
-				oscapStageOptions,
-				tailoringOptions,
-			)
-
-			// overwrite the profile id with the new tailoring id
-			oscapStageOptions.ProfileID = newProfile
-			oscapStageOptions.Tailoring = tailoringFilepath
-
-			// add the parent directory for the tailoring file
-			osc.Directories = append(osc.Directories, tailoringDir)
+		if err := distro.ApplyOpenSCAPCustomizations(&osc, oscapConfig, oscap.DefaultDatastream(t.arch.distro.id(), 8)); err != nil {
+			panic(err)
 		}
-
-		osc.OpenSCAPConfig = osbuild.NewOscapRemediationStageOptions(oscapStageOptions)
 	}
 
 	osc.ShellInit = imageConfig.ShellInit
@@ -230,12 +206,17 @@ func osCustomizations(
 	osc.Grub2Config = imageConfig.Grub2Config
 	osc.Sysconfig = imageConfig.Sysconfig
 	osc.SystemdLogind = imageConfig.SystemdLogind
+	osc.SystemdJournald = imageConfig.SystemdJournald
 	osc.CloudInit = imageConfig.CloudInit
 	osc.Modprobe = imageConfig.Modprobe
 	osc.DracutConf = imageConfig.DracutConf
+	if imageConfig.RegenerateInitramfs != nil {
+		osc.RegenerateInitramfs = *imageConfig.RegenerateInitramfs
+	}
 	osc.SystemdUnit = imageConfig.SystemdUnit
 	osc.Authselect = imageConfig.Authselect
 	osc.SELinuxConfig = imageConfig.SELinuxConfig
+	osc.SELinuxPolicy = imageConfig.SELinuxPolicy
 	osc.Tuned = imageConfig.Tuned
 	osc.Tmpfilesd = imageConfig.Tmpfilesd
 	osc.PamLimitsConf = imageConfig.PamLimitsConf
@@ -304,6 +285,10 @@ func imageInstallerImage(workload workload.Workload,
 	img.AdditionalDracutModules = []string{"prefixdevname", "prefixdevname-tools"}
 	img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
 
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, customizations); err != nil {
+		return nil, err
+	}
+
 	img.SquashfsCompression = "xz"
 
 	// put the kickstart file in the root of the iso
@@ -418,6 +403,10 @@ func edgeInstallerImage(workload workload.Workload,
 		img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
 	}
 
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, customizations); err != nil {
+		return nil, err
+	}
+
 	img.ISOLabelTempl = d.isolabelTmpl
 	img.Product = d.product
 	img.Variant = "edge"
@@ -448,7 +437,12 @@ func edgeRawImage(workload workload.Workload,
 	img.Users = users.UsersFromBP(customizations.GetUsers())
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	if fb := customizations.GetFirstBoot(); fb != nil {
+		img.FirstBoot = firstboot.OptionsFromBP(*fb)
+	}
+
 	img.KernelOptionsAppend = []string{"modprobe.blacklist=vc4"}
+	img.FIPS = customizations.GetFIPS()
 	// TODO: move to image config
 	img.Keyboard = "us"
 	img.Locale = "C.UTF-8"
@@ -494,6 +488,7 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 	rawImg.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	rawImg.KernelOptionsAppend = []string{"modprobe.blacklist=vc4"}
+	rawImg.FIPS = customizations.GetFIPS()
 	rawImg.Keyboard = "us"
 	rawImg.Locale = "C.UTF-8"
 
@@ -603,13 +598,3 @@ func makeOSTreePayloadCommit(options *ostree.ImageOptions, defaultRef string) (o
 		RHSM: options.RHSM,
 	}, nil
 }
-
-// initialSetupKickstart returns the File configuration for a kickstart file
-// that's required to enable initial-setup to run on first boot.
-func initialSetupKickstart() *fsnode.File {
-	file, err := fsnode.NewFile("/root/anaconda-ks.cfg", nil, "root", "root", []byte("# Run initial-setup on first boot\n# Created by osbuild\nfirstboot --reconfig\nlang en_US.UTF-8\n"))
-	if err != nil {
-		panic(err)
-	}
-	return file
-}
@@ -43,6 +43,10 @@ type imageFunc func(workload workload.Workload, t *imageType, customizations *bl
 
 type packageSetFunc func(t *imageType) rpmmd.PackageSet
 
+type imageConfigFunc func(t *imageType) *distro.ImageConfig
+
+type basePartitionTableFunc func(t *imageType) (disk.PartitionTable, bool)
+
 type imageType struct {
 	arch               *architecture
 	platform           platform.Platform
@@ -54,7 +58,7 @@ type imageType struct {
 	compression        string // TODO: remove from image definition and make it a transport option
 	mimeType           string
 	packageSets        map[string]packageSetFunc
-	defaultImageConfig *distro.ImageConfig
+	defaultImageConfig imageConfigFunc
 	kernelOptions      string
 	defaultSize        uint64
 	buildPipelines     []string
@@ -69,7 +73,7 @@ type imageType struct {
 	// bootable image
 	bootable bool
 	// List of valid arches for the image type
-	basePartitionTables distro.BasePartitionTableMap
+	basePartitionTables basePartitionTableFunc
 }
 
 func (t *imageType) Name() string {
@@ -130,6 +134,15 @@ func (t *imageType) Exports() []string {
 	return []string{"assembler"}
 }
 
+func (t *imageType) RecommendedResources() distro.VMResources {
+	resources := distro.RecommendedResourcesFallback()
+	if t.bootISO {
+		// the installer and its image run from memory
+		resources.MemoryMiB = 4096
+	}
+	return resources
+}
+
 func (t *imageType) BootMode() distro.BootMode {
 	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
 		return distro.BOOT_HYBRID
@@ -148,8 +161,7 @@ func (t *imageType) getPartitionTable(
 ) (*disk.PartitionTable, error) {
 	archName := t.arch.Name()
 
-	basePartitionTable, exists := t.basePartitionTables[archName]
-
+	basePartitionTable, exists := t.getBasePartitionTable()
 	if !exists {
 		return nil, fmt.Errorf("no partition table defined for architecture %q for image type %q", archName, t.Name())
 	}
@@ -167,12 +179,31 @@ func (t *imageType) getPartitionTable(
 		partitioningMode = disk.RawPartitioningMode
 	}
 
-	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, partitioningMode, nil, rng)
+	pt, err := disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, partitioningMode, nil, rng)
+	if err != nil {
+		return nil, err
+	}
+	if err := pt.ValidateForPlatform(t.platform); err != nil {
+		return nil, fmt.Errorf("invalid partition table for %s on %s: %w", t.Name(), t.arch.Name(), err)
+	}
+	return pt, nil
+}
+
+// getBasePartitionTable returns the base partition table of the image type on
+// its architecture. The second return value is false if there is none.
+func (t *imageType) getBasePartitionTable() (disk.PartitionTable, bool) {
+	if t.basePartitionTables == nil {
+		return disk.PartitionTable{}, false
+	}
+	return t.basePartitionTables(t)
 }
 
 func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
 	// ensure that image always returns non-nil default config
-	imageConfig := t.defaultImageConfig
+	var imageConfig *distro.ImageConfig
+	if t.defaultImageConfig != nil {
+		imageConfig = t.defaultImageConfig(t)
+	}
 	if imageConfig == nil {
 		imageConfig = &distro.ImageConfig{}
 	}
@@ -181,8 +212,7 @@ func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
 }
 
 func (t *imageType) PartitionType() string {
-	archName := t.arch.Name()
-	basePartitionTable, exists := t.basePartitionTables[archName]
+	basePartitionTable, exists := t.getBasePartitionTable()
 	if !exists {
 		return ""
 	}
@@ -287,12 +317,31 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
 	}
 
+	if len(bp.Flatpaks) > 0 {
+		return warnings, fmt.Errorf("embedding flatpaks is not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	if se := options.SecureExecution; se != nil {
+		if !t.platform.GetZiplSupport() {
+			return warnings, fmt.Errorf("IBM Secure Execution is not supported for %s on %s", t.name, t.arch.Name())
+		}
+		if err := se.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
 	if options.OSTree != nil {
 		if err := options.OSTree.Validate(); err != nil {
 			return nil, err
 		}
 	}
 
+	if installer, err := customizations.GetInstaller(); err != nil {
+		return warnings, err
+	} else if installer != nil && !t.bootISO {
+		return warnings, fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+	}
+
 	if t.bootISO && t.rpmOstree {
 		// ostree-based ISOs require a URL from which to pull a payload commit
 		if options.OSTree == nil || options.OSTree.URL == "" {
@@ -300,7 +349,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		}
 
 		if t.name == "edge-simplified-installer" {
-			allowed := []string{"InstallationDevice", "FDO", "User", "Group"}
+			allowed := []string{"InstallationDevice", "FDO", "User", "Group", "FIPS"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
@@ -327,7 +376,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 				}
 			}
 		} else if t.name == "edge-installer" {
-			allowed := []string{"User", "Group"}
+			allowed := []string{"User", "Group", "Installer"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
@@ -340,7 +389,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 			return warnings, fmt.Errorf("%q images require specifying a URL from which to retrieve the OSTree commit", t.name)
 		}
 
-		allowed := []string{"User", "Group"}
+		allowed := []string{"User", "Group", "FirstBoot", "FIPS"}
 		if err := customizations.CheckAllowed(allowed...); err != nil {
 			return warnings, fmt.Errorf("unsupported blueprint customizations found for image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 		}
@@ -366,6 +415,25 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("kernel boot parameter customizations are not supported for ostree types")
 	}
 
+	if customizations.GetFIPS() && !common.IsBuildHostFIPSEnabled() {
+		w := fmt.Sprintln(common.FIPSEnabledImageWarning)
+		log.Print(w)
+		warnings = append(warnings, w)
+	}
+
+	if _, err := t.getDefaultImageConfig().WithCustomizations(customizations); err != nil {
+		return warnings, err
+	}
+	if options.Subscription != nil {
+		if err := options.Subscription.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
+	if err := distro.CheckEmbeddedContainers(customizations, bp.Containers); err != nil {
+		return warnings, err
+	}
+
 	mountpoints := customizations.GetFilesystems()
 
 	if mountpoints != nil && t.rpmOstree {
@@ -390,6 +458,9 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		if osc.ProfileID == "" {
 			return warnings, fmt.Errorf("OpenSCAP profile cannot be empty")
 		}
+		if err := blueprint.ValidateOpenSCAPCustomization(osc); err != nil {
+			return warnings, err
+		}
 	}
 
 	// Check Directory/File Customizations are valid
@@ -410,6 +481,15 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, err
 	}
 
+	fb := customizations.GetFirstBoot()
+	if err := blueprint.ValidateFirstBootCustomization(fb); err != nil {
+		return warnings, err
+	}
+	err = blueprint.CheckFirstBootCustomizationPolicy(fb, pathpolicy.FirstBootScriptsPolicies)
+	if err != nil {
+		return warnings, err
+	}
+
 	// check if repository customizations are valid
 	_, err = customizations.GetRepositories()
 	if err != nil {
@@ -1,317 +0,0 @@
-package rhel9
-
-import (
-	"fmt"
-
-	"github.com/osbuild/images/pkg/platform"
-	"github.com/osbuild/images/pkg/rpmmd"
-)
-
-var (
-	tarImgType = imageType{
-		name:     "tar",
-		filename: "root.tar.xz",
-		mimeType: "application/x-tar",
-		packageSets: map[string]packageSetFunc{
-			osPkgsKey: func(t *imageType) rpmmd.PackageSet {
-				return rpmmd.PackageSet{
-					Include: []string{"policycoreutils", "selinux-policy-targeted"},
-					Exclude: []string{"rng-tools"},
-				}
-			},
-		},
-		image:            tarImage,
-		buildPipelines:   []string{"build"},
-		payloadPipelines: []string{"os", "archive"},
-		exports:          []string{"archive"},
-	}
-
-	imageInstaller = imageType{
-		name:     "image-installer",
-		filename: "installer.iso",
-		mimeType: "application/x-iso9660-image",
-		packageSets: map[string]packageSetFunc{
-			osPkgsKey:        bareMetalPackageSet,
-			installerPkgsKey: anacondaPackageSet,
-		},
-		rpmOstree:        false,
-		bootISO:          true,
-		bootable:         true,
-		image:            imageInstallerImage,
-		buildPipelines:   []string{"build"},
-		payloadPipelines: []string{"anaconda-tree", "rootfs-image", "efiboot-tree", "os", "bootiso-tree", "bootiso"},
-		exports:          []string{"bootiso"},
-	}
-)
-
-func bareMetalPackageSet(t *imageType) rpmmd.PackageSet {
-	ps := rpmmd.PackageSet{
-		Include: []string{
-			"authselect-compat",
-			"chrony",
-			"cockpit-system",
-			"cockpit-ws",
-			"dhcp-client",
-			"dnf-utils",
-			"dosfstools",
-			"firewalld",
-			"iwl1000-firmware",
-			"iwl100-firmware",
-			"iwl105-firmware",
-			"iwl135-firmware",
-			"iwl2000-firmware",
-			"iwl2030-firmware",
-			"iwl3160-firmware",
-			"iwl5000-firmware",
-			"iwl5150-firmware",
-			"iwl6000g2a-firmware",
-			"iwl6000g2b-firmware",
-			"iwl6050-firmware",
-			"iwl7260-firmware",
-			"lvm2",
-			"net-tools",
-			"nfs-utils",
-			"oddjob",
-			"oddjob-mkhomedir",
-			"policycoreutils",
-			"psmisc",
-			"python3-jsonschema",
-			"qemu-guest-agent",
-			"redhat-release",
-			"redhat-release-eula",
-			"rsync",
-			"tar",
-			"tcpdump",
-		},
-	}.Append(coreOsCommonPackageSet(t)).Append(distroBuildPackageSet(t))
-
-	// Ensure to not pull in subscription-manager on non-RHEL distro
-	if t.arch.distro.isRHEL() {
-		ps = ps.Append(rpmmd.PackageSet{
-			Include: []string{
-				"subscription-manager-cockpit",
-			},
-		})
-	}
-
-	return ps
-}
-
-func installerPackageSet(t *imageType) rpmmd.PackageSet {
-	ps := rpmmd.PackageSet{
-		Include: []string{
-			"anaconda-dracut",
-			"curl",
-			"dracut-config-generic",
-			"dracut-network",
-			"hostname",
-			"iwl100-firmware",
-			"iwl1000-firmware",
-			"iwl105-firmware",
-			"iwl135-firmware",
-			"iwl2000-firmware",
-			"iwl2030-firmware",
-			"iwl3160-firmware",
-			"iwl5000-firmware",
-			"iwl5150-firmware",
-			"iwl6050-firmware",
-			"iwl7260-firmware",
-			"kernel",
-			"less",
-			"nfs-utils",
-			"openssh-clients",
-			"ostree",
-			"plymouth",
-			"prefixdevname",
-			"rng-tools",
-			"rpcbind",
-			"selinux-policy-targeted",
-			"systemd",
-			"tar",
-			"xfsprogs",
-			"xz",
-		},
-	}
-
-	switch t.arch.Name() {
-	case platform.ARCH_X86_64.String():
-		ps = ps.Append(rpmmd.PackageSet{
-			Include: []string{
-				"biosdevname",
-			},
-		})
-	}
-
-	return ps
-}
-
-func anacondaPackageSet(t *imageType) rpmmd.PackageSet {
-
-	// common installer packages
-	ps := installerPackageSet(t)
-
-	ps = ps.Append(rpmmd.PackageSet{
-		Include: []string{
-			"aajohan-comfortaa-fonts",
-			"abattis-cantarell-fonts",
-			"alsa-firmware",
-			"alsa-tools-firmware",
-			"anaconda",
-			"anaconda-dracut",
-			"anaconda-install-env-deps",
-			"anaconda-widgets",
-			"audit",
-			"bind-utils",
-			"bitmap-fangsongti-fonts",
-			"bzip2",
-			"cryptsetup",
-			"curl",
-			"dbus-x11",
-			"dejavu-sans-fonts",
-			"dejavu-sans-mono-fonts",
-			"device-mapper-persistent-data",
-			"dmidecode",
-			"dnf",
-			"dracut-config-generic",
-			"dracut-network",
-			"efibootmgr",
-			"ethtool",
-			"fcoe-utils",
-			"ftp",
-			"gdb-gdbserver",
-			"gdisk",
-			"glibc-all-langpacks",
-			"gnome-kiosk",
-			"google-noto-sans-cjk-ttc-fonts",
-			"grub2-tools",
-			"grub2-tools-extra",
-			"grub2-tools-minimal",
-			"grubby",
-			"gsettings-desktop-schemas",
-			"hdparm",
-			"hexedit",
-			"hostname",
-			"initscripts",
-			"ipmitool",
-			"iwl1000-firmware",
-			"iwl100-firmware",
-			"iwl105-firmware",
-			"iwl135-firmware",
-			"iwl2000-firmware",
-			"iwl2030-firmware",
-			"iwl3160-firmware",
-			"iwl5000-firmware",
-			"iwl5150-firmware",
-			"iwl6000g2a-firmware",
-			"iwl6000g2b-firmware",
-			"iwl6050-firmware",
-			"iwl7260-firmware",
-			"jomolhari-fonts",
-			"kacst-farsi-fonts",
-			"kacst-qurn-fonts",
-			"kbd",
-			"kbd-misc",
-			"kdump-anaconda-addon",
-			"kernel",
-			"khmeros-base-fonts",
-			"less",
-			"libblockdev-lvm-dbus",
-			"libibverbs",
-			"libreport-plugin-bugzilla",
-			"libreport-plugin-reportuploader",
-			"librsvg2",
-			"linux-firmware",
-			"lklug-fonts",
-			"lldpad",
-			"lohit-assamese-fonts",
-			"lohit-bengali-fonts",
-			"lohit-devanagari-fonts",
-			"lohit-gujarati-fonts",
-			"lohit-gurmukhi-fonts",
-			"lohit-kannada-fonts",
-			"lohit-odia-fonts",
-			"lohit-tamil-fonts",
-			"lohit-telugu-fonts",
-			"lsof",
-			"madan-fonts",
-			"mtr",
-			"mt-st",
-			"net-tools",
-			"nfs-utils",
-			"nmap-ncat",
-			"nm-connection-editor",
-			"nss-tools",
-			"openssh-clients",
-			"openssh-server",
-			"oscap-anaconda-addon",
-			"ostree",
-			"pciutils",
-			"perl-interpreter",
-			"pigz",
-			"plymouth",
-			"prefixdevname",
-			"python3-pyatspi",
-			"rdma-core",
-			"redhat-release-eula",
-			"rng-tools",
-			"rpcbind",
-			"rpm-ostree",
-			"rsync",
-			"rsyslog",
-			"selinux-policy-targeted",
-			"sg3_utils",
-			"sil-abyssinica-fonts",
-			"sil-padauk-fonts",
-			"sil-scheherazade-fonts",
-			"smartmontools",
-			"smc-meera-fonts",
-			"spice-vdagent",
-			"strace",
-			"systemd",
-			"tar",
-			"thai-scalable-waree-fonts",
-			"tigervnc-server-minimal",
-			"tigervnc-server-module",
-			"udisks2",
-			"udisks2-iscsi",
-			"usbutils",
-			"vim-minimal",
-			"volume_key",
-			"wget",
-			"xfsdump",
-			"xfsprogs",
-			"xorg-x11-drivers",
-			"xorg-x11-fonts-misc",
-			"xorg-x11-server-utils",
-			"xorg-x11-server-Xorg",
-			"xorg-x11-xauth",
-			"xz",
-		},
-	})
-
-	ps = ps.Append(anacondaBootPackageSet(t))
-
-	switch t.arch.Name() {
-	case platform.ARCH_X86_64.String():
-		ps = ps.Append(rpmmd.PackageSet{
-			Include: []string{
-				"biosdevname",
-				"dmidecode",
-				"grub2-tools-efi",
-				"memtest86+",
-			},
-		})
-
-	case platform.ARCH_AARCH64.String():
-		ps = ps.Append(rpmmd.PackageSet{
-			Include: []string{
-				"dmidecode",
-			},
-		})
-
-	default:
-		panic(fmt.Sprintf("unsupported arch: %s", t.arch.Name()))
-	}
-
-	return ps
-}
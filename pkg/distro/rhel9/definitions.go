@@ -0,0 +1,109 @@
+package rhel9
+
+import (
+	"fmt"
+
+	"github.com/osbuild/images/internal/environment"
+	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/defs"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// Image functions, environments and workloads that image type definitions
+// can refer to by name
+var definitionFuncs = defs.Funcs[imageFunc]{
+	Images: map[string]imageFunc{
+		"disk":                      diskImage,
+		"edge-commit":               edgeCommitImage,
+		"edge-container":            edgeContainerImage,
+		"edge-installer":            edgeInstallerImage,
+		"edge-raw":                  edgeRawImage,
+		"edge-simplified-installer": edgeSimplifiedInstallerImage,
+		"image-installer":           imageInstallerImage,
+		"tar":                       tarImage,
+	},
+	Environments: map[string]environment.Environment{
+		"ec2": &environment.EC2{},
+	},
+}
+
+// loadDefinitions loads the image type definitions of a major release. RHEL
+// 10 shares the definitions of RHEL 9.
+func loadDefinitions(major int) (*defs.Definitions, error) {
+	if major == 10 {
+		major = 9
+	}
+	return defs.Load(fmt.Sprintf("rhel-%d", major))
+}
+
+// addImageTypesFromDefinitions adds the image types of the definitions to
+// the architectures they are available on.
+func addImageTypesFromDefinitions(d *distribution, arches ...*architecture) error {
+	archNames := make([]string, 0, len(arches))
+	archByName := make(map[string]*architecture, len(arches))
+	for _, arch := range arches {
+		archNames = append(archNames, arch.name)
+		archByName[arch.name] = arch
+	}
+
+	target := defs.Distro{
+		Name:       d.name,
+		OSVersion:  d.osVersion,
+		Releasever: d.releaseVersion,
+	}
+	imageTypes, err := defs.ImageTypes(d.defs, definitionFuncs, target, d.vendor, archNames...)
+	if err != nil {
+		return err
+	}
+	for _, ait := range imageTypes {
+		archByName[ait.Arch].addImageTypes(ait.Platform, imageTypeFromDefinition(ait))
+	}
+	return nil
+}
+
+// imageTypeFromDefinition returns the image type of a resolved image type
+// definition
+func imageTypeFromDefinition(ait defs.ArchImageType[imageFunc]) imageType {
+	def := ait.Definition
+	it := imageType{
+		name:             ait.Name,
+		nameAliases:      def.NameAliases,
+		filename:         def.Filename,
+		compression:      def.Compression,
+		mimeType:         def.MimeType,
+		environment:      ait.Environment,
+		workload:         ait.Workload,
+		packageSets:      make(map[string]packageSetFunc, len(ait.PackageSets)),
+		kernelOptions:    def.KernelOptions,
+		defaultSize:      def.Size(),
+		buildPipelines:   def.BuildPipelines,
+		payloadPipelines: def.PayloadPipelines,
+		exports:          def.Exports,
+		image:            ait.Image,
+		bootISO:          def.BootISO,
+		rpmOstree:        def.RPMOSTree,
+		bootable:         def.Bootable,
+	}
+
+	for psName, ps := range ait.PackageSets {
+		ps := ps
+		it.packageSets[psName] = func(*imageType) rpmmd.PackageSet {
+			return ps()
+		}
+	}
+
+	if ic := ait.ImageConfig; ic != nil {
+		it.defaultImageConfig = func(*imageType) *distro.ImageConfig {
+			return ic()
+		}
+	}
+
+	if pt := ait.PartitionTable; pt != nil {
+		it.basePartitionTables = func(*imageType) (disk.PartitionTable, bool) {
+			return pt()
+		}
+	}
+
+	return it
+}
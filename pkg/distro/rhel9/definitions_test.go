@@ -0,0 +1,45 @@
+package rhel9
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
+)
+
+func TestDefinitionsLoadError(t *testing.T) {
+	// there are no image type definitions for RHEL 11
+	d := newDistro("rhel", 11, 0)
+	assert.ErrorContains(t, d.LoadError(), "cannot load the image type definitions of rhel-")
+
+	_, err := d.GetArch("x86_64")
+	assert.Equal(t, d.LoadError(), err)
+}
+
+func TestDefinitionsRHEL10(t *testing.T) {
+	// RHEL 10 shares the definitions of RHEL 9, without the packages that
+	// were removed from the distribution
+	for _, tc := range []struct {
+		major    int
+		included bool
+	}{
+		{9, true},
+		{10, false},
+	} {
+		d := newDistro("rhel", tc.major, 0)
+		require.NoError(t, d.LoadError())
+		arch, err := d.GetArch("x86_64")
+		require.NoError(t, err)
+		it, err := arch.GetImageType("qcow2")
+		require.NoError(t, err)
+
+		ps := it.(*imageType).packageSets[osPkgsKey](it.(*imageType))
+		for _, pkg := range []string{"NetworkManager-team", "authselect-compat"} {
+			assert.Equal(t, tc.included, slices.Contains(ps.Include, pkg), "%s in RHEL %d", pkg, tc.major)
+		}
+
+		_, err = arch.GetImageType("edge-commit")
+		assert.Equal(t, tc.major == 9, err == nil, "edge-commit in RHEL %d", tc.major)
+	}
+}
@@ -4,18 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/oscap"
 	"github.com/osbuild/images/pkg/distro"
+	"github.com/osbuild/images/pkg/distro/defs"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/runner"
 )
 
 var (
-	// rhel9 & cs9 share the same list
+	// rhel9, rhel10, cs9 & cs10 share the same list
 	// of allowed profiles so a single
 	// allow list can be used
 	oscapProfileAllowList = []oscap.Profile{
@@ -50,6 +52,10 @@ type distribution struct {
 	runner             runner.Runner
 	arches             map[string]distro.Arch
 	defaultImageConfig *distro.ImageConfig
+	defs               *defs.Definitions
+	// error loading the image type definitions, the distribution has no
+	// usable architectures if set
+	defsErr error
 }
 
 // CentOS- and RHEL-based OS image configuration defaults
@@ -96,6 +102,9 @@ func (d *distribution) ListArches() []string {
 }
 
 func (d *distribution) GetArch(name string) (distro.Arch, error) {
+	if d.defsErr != nil {
+		return nil, d.defsErr
+	}
 	arch, exists := d.arches[name]
 	if !exists {
 		return nil, errors.New("invalid architecture: " + name)
@@ -116,77 +125,168 @@ func (d *distribution) addArches(arches ...architecture) {
 	}
 }
 
+// major returns the major version of the distribution
+func (d *distribution) major() uint64 {
+	major, err := strconv.ParseUint(d.releaseVersion, 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return major
+}
+
 func (d *distribution) isRHEL() bool {
 	return strings.HasPrefix(d.name, "rhel")
 }
 
+// id returns the os-release ID of the distribution, e.g. "rhel" or "centos".
+func (d *distribution) id() string {
+	return strings.SplitN(d.name, "-", 2)[0]
+}
+
+// LoadError returns the error the image type definitions of the
+// distribution failed to load with, if any.
+func (d *distribution) LoadError() error {
+	return d.defsErr
+}
+
 func (d *distribution) getDefaultImageConfig() *distro.ImageConfig {
 	return d.defaultImageConfig
 }
 
 func New() distro.Distro {
 	// default minor: create default minor version (current GA) and rename it
-	d := newDistro("rhel", 4)
+	d := newDistro("rhel", 9, 4)
 	d.name = "rhel-9"
 	return d
 }
 
 func NewCentOS9() distro.Distro {
-	return newDistro("centos", 0)
+	return newDistro("centos", 9, 0)
+}
+
+// NewAlmaLinux9 creates AlmaLinux 9. Rebuilds of RHEL only track the
+// current minor release, which the image types are defined for.
+func NewAlmaLinux9() distro.Distro {
+	return newDistro("almalinux", 9, 4)
+}
+
+// NewRocky9 creates Rocky Linux 9, which tracks the current minor
+// release like AlmaLinux.
+func NewRocky9() distro.Distro {
+	return newDistro("rocky", 9, 4)
 }
 
 func NewRHEL90() distro.Distro {
-	return newDistro("rhel", 0)
+	return newDistro("rhel", 9, 0)
 }
 
 func NewRHEL91() distro.Distro {
-	return newDistro("rhel", 1)
+	return newDistro("rhel", 9, 1)
 }
 
 func NewRHEL92() distro.Distro {
-	return newDistro("rhel", 2)
+	return newDistro("rhel", 9, 2)
 }
 
 func NewRHEL93() distro.Distro {
-	return newDistro("rhel", 3)
+	return newDistro("rhel", 9, 3)
 }
 
 func NewRHEL94() distro.Distro {
-	return newDistro("rhel", 4)
+	return newDistro("rhel", 9, 4)
+}
+
+// RHEL 10 and CentOS Stream 10 share the image types of RHEL 9 except for
+// the edge image types.
+
+func NewRHEL10() distro.Distro {
+	// default minor: create default minor version (current GA) and rename it
+	d := newDistro("rhel", 10, 0)
+	d.name = "rhel-10"
+	return d
+}
+
+func NewRHEL100() distro.Distro {
+	return newDistro("rhel", 10, 0)
 }
 
-func newDistro(name string, minor int) *distribution {
+func NewCentOS10() distro.Distro {
+	return newDistro("centos", 10, 0)
+}
+
+func newDistro(name string, major, minor int) *distribution {
 	var rd distribution
 	switch name {
 	case "rhel":
 		rd = distribution{
-			name:               fmt.Sprintf("rhel-9%d", minor),
+			name:               fmt.Sprintf("rhel-%d%d", major, minor),
 			product:            "Red Hat Enterprise Linux",
-			osVersion:          fmt.Sprintf("9.%d", minor),
-			releaseVersion:     "9",
-			modulePlatformID:   "platform:el9",
+			osVersion:          fmt.Sprintf("%d.%d", major, minor),
+			releaseVersion:     strconv.Itoa(major),
+			modulePlatformID:   fmt.Sprintf("platform:el%d", major),
 			vendor:             "redhat",
-			ostreeRefTmpl:      "rhel/9/%s/edge",
-			isolabelTmpl:       fmt.Sprintf("RHEL-9-%d-0-BaseOS-%%s", minor),
-			runner:             &runner.RHEL{Major: uint64(9), Minor: uint64(minor)},
+			ostreeRefTmpl:      fmt.Sprintf("rhel/%d/%%s/edge", major),
+			isolabelTmpl:       fmt.Sprintf("RHEL-%d-%d-0-BaseOS-%%s", major, minor),
+			runner:             &runner.RHEL{Major: uint64(major), Minor: uint64(minor)},
 			defaultImageConfig: defaultDistroImageConfig,
 		}
+		if major >= 10 {
+			// "rhel-100" would be ambiguous
+			rd.name = fmt.Sprintf("rhel-%d.%d", major, minor)
+			rd.isolabelTmpl = fmt.Sprintf("RHEL-%d-%d-BaseOS-%%s", major, minor)
+		}
 	case "centos":
 		rd = distribution{
-			name:               "centos-9",
+			name:               fmt.Sprintf("centos-%d", major),
 			product:            "CentOS Stream",
-			osVersion:          "9-stream",
-			releaseVersion:     "9",
-			modulePlatformID:   "platform:el9",
+			osVersion:          fmt.Sprintf("%d-stream", major),
+			releaseVersion:     strconv.Itoa(major),
+			modulePlatformID:   fmt.Sprintf("platform:el%d", major),
 			vendor:             "centos",
-			ostreeRefTmpl:      "centos/9/%s/edge",
-			isolabelTmpl:       "CentOS-Stream-9-BaseOS-%s",
-			runner:             &runner.CentOS{Version: uint64(9)},
+			ostreeRefTmpl:      fmt.Sprintf("centos/%d/%%s/edge", major),
+			isolabelTmpl:       fmt.Sprintf("CentOS-Stream-%d-BaseOS-%%s", major),
+			runner:             &runner.CentOS{Version: uint64(major)},
+			defaultImageConfig: defaultDistroImageConfig,
+		}
+	case "almalinux":
+		rd = distribution{
+			name:               fmt.Sprintf("almalinux-%d", major),
+			product:            "AlmaLinux",
+			osVersion:          fmt.Sprintf("%d.%d", major, minor),
+			releaseVersion:     strconv.Itoa(major),
+			modulePlatformID:   fmt.Sprintf("platform:el%d", major),
+			vendor:             "almalinux",
+			ostreeRefTmpl:      fmt.Sprintf("almalinux/%d/%%s/edge", major),
+			isolabelTmpl:       fmt.Sprintf("AlmaLinux-%d-%d-%%s-dvd", major, minor),
+			runner:             &runner.CentOS{Version: uint64(major)},
+			defaultImageConfig: defaultDistroImageConfig,
+		}
+	case "rocky":
+		rd = distribution{
+			name:               fmt.Sprintf("rocky-%d", major),
+			product:            "Rocky Linux",
+			osVersion:          fmt.Sprintf("%d.%d", major, minor),
+			releaseVersion:     strconv.Itoa(major),
+			modulePlatformID:   fmt.Sprintf("platform:el%d", major),
+			vendor:             "rocky",
+			ostreeRefTmpl:      fmt.Sprintf("rocky/%d/%%s/edge", major),
+			isolabelTmpl:       fmt.Sprintf("Rocky-%d-%d-%%s-dvd", major, minor),
+			runner:             &runner.CentOS{Version: uint64(major)},
 			defaultImageConfig: defaultDistroImageConfig,
 		}
 	default:
 		panic(fmt.Sprintf("unknown distro name: %s", name))
 	}
+	if major >= 10 {
+		// there are no edge image types for RHEL 10
+		rd.ostreeRefTmpl = ""
+	}
+	definitions, err := loadDefinitions(major)
+	if err != nil {
+		rd.defsErr = fmt.Errorf("cannot load the image type definitions of %s: %w", rd.name, err)
+		return &rd
+	}
+	rd.defs = definitions
 
 	// Architecture definitions
 	x86_64 := architecture{
@@ -209,285 +309,11 @@ func newDistro(name string, minor int) *distribution {
 		name:   platform.ARCH_S390X.String(),
 	}
 
-	qcow2ImgType := mkQcow2ImgType(rd)
-	ociImgType := qcow2ImgType
-	ociImgType.name = "oci"
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "1.1",
-			},
-		},
-		qcow2ImgType,
-		ociImgType,
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-			},
-		},
-		openstackImgType,
-	)
-
-	azureX64Platform := &platform.X86{
-		BIOS:       true,
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_VHD,
-		},
-	}
-
-	azureAarch64Platform := &platform.Aarch64{
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_VHD,
-		},
+	if err := addImageTypesFromDefinitions(&rd, &x86_64, &aarch64, &ppc64le, &s390x); err != nil {
+		rd.defsErr = fmt.Errorf("invalid image type definitions for %s: %w", rd.name, err)
+		return &rd
 	}
 
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_VMDK,
-			},
-		},
-		vmdkImgType,
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_OVA,
-			},
-		},
-		ovaImgType,
-	)
-
-	ec2X86Platform := &platform.X86{
-		BIOS:       true,
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_RAW,
-		},
-	}
-	x86_64.addImageTypes(
-		ec2X86Platform,
-		mkAMIImgTypeX86_64(rd.osVersion, rd.isRHEL()),
-	)
-
-	gceX86Platform := &platform.X86{
-		UEFIVendor: rd.vendor,
-		BasePlatform: platform.BasePlatform{
-			ImageFormat: platform.FORMAT_GCE,
-		},
-	}
-	x86_64.addImageTypes(
-		gceX86Platform,
-		mkGCEImageType(rd.isRHEL()),
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BasePlatform: platform.BasePlatform{
-				FirmwarePackages: []string{
-					"microcode_ctl", // ??
-					"iwl1000-firmware",
-					"iwl100-firmware",
-					"iwl105-firmware",
-					"iwl135-firmware",
-					"iwl2000-firmware",
-					"iwl2030-firmware",
-					"iwl3160-firmware",
-					"iwl5000-firmware",
-					"iwl5150-firmware",
-					"iwl6050-firmware",
-				},
-			},
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-		},
-		edgeOCIImgType,
-		edgeCommitImgType,
-		edgeInstallerImgType,
-		edgeRawImgType,
-		imageInstaller,
-		edgeAMIImgType,
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_VMDK,
-			},
-			BIOS:       true,
-			UEFIVendor: rd.vendor,
-		},
-		edgeVsphereImgType,
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_RAW,
-			},
-			BIOS:       false,
-			UEFIVendor: rd.vendor,
-		},
-		edgeSimplifiedInstallerImgType,
-		minimalrawImgType,
-	)
-
-	x86_64.addImageTypes(
-		&platform.X86{},
-		tarImgType,
-		wslImgType,
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-			},
-		},
-		openstackImgType,
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{},
-		tarImgType,
-		wslImgType,
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			BasePlatform: platform.BasePlatform{},
-			UEFIVendor:   rd.vendor,
-		},
-		edgeCommitImgType,
-		edgeOCIImgType,
-		edgeInstallerImgType,
-		edgeSimplifiedInstallerImgType,
-		imageInstaller,
-		edgeAMIImgType,
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_VMDK,
-			},
-			UEFIVendor: rd.vendor,
-		},
-		edgeVsphereImgType,
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_RAW,
-			},
-			UEFIVendor: rd.vendor,
-		},
-		edgeRawImgType,
-		minimalrawImgType,
-	)
-
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "1.1",
-			},
-		},
-		qcow2ImgType,
-	)
-	aarch64.addImageTypes(
-		&platform.Aarch64{
-			UEFIVendor: rd.vendor,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_RAW,
-			},
-		},
-		mkAMIImgTypeAarch64(rd.osVersion, rd.isRHEL()),
-	)
-
-	ppc64le.addImageTypes(
-		&platform.PPC64LE{
-			BIOS: true,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "1.1",
-			},
-		},
-		qcow2ImgType,
-	)
-	ppc64le.addImageTypes(
-		&platform.PPC64LE{},
-		tarImgType,
-	)
-
-	s390x.addImageTypes(
-		&platform.S390X{
-			Zipl: true,
-			BasePlatform: platform.BasePlatform{
-				ImageFormat: platform.FORMAT_QCOW2,
-				QCOW2Compat: "1.1",
-			},
-		},
-		qcow2ImgType,
-	)
-	s390x.addImageTypes(
-		&platform.S390X{},
-		tarImgType,
-	)
-
-	if rd.isRHEL() {
-		// add azure to RHEL distro only
-		x86_64.addImageTypes(azureX64Platform, azureRhuiImgType, azureByosImgType)
-		aarch64.addImageTypes(azureAarch64Platform, azureRhuiImgType, azureByosImgType)
-
-		// keep the RHEL EC2 x86_64 images before 9.3 BIOS-only for backward compatibility
-		if common.VersionLessThan(rd.osVersion, "9.3") {
-			ec2X86Platform = &platform.X86{
-				BIOS: true,
-				BasePlatform: platform.BasePlatform{
-					ImageFormat: platform.FORMAT_RAW,
-				},
-			}
-		}
-
-		// add ec2 image types to RHEL distro only
-		x86_64.addImageTypes(ec2X86Platform, mkEc2ImgTypeX86_64(rd.osVersion, rd.isRHEL()), mkEc2HaImgTypeX86_64(rd.osVersion, rd.isRHEL()), mkEC2SapImgTypeX86_64(rd.osVersion, rd.isRHEL()))
-
-		aarch64.addImageTypes(
-			&platform.Aarch64{
-				UEFIVendor: rd.vendor,
-				BasePlatform: platform.BasePlatform{
-					ImageFormat: platform.FORMAT_RAW,
-				},
-			},
-			mkEC2ImgTypeAarch64(rd.osVersion, rd.isRHEL()),
-		)
-
-		// add GCE RHUI image to RHEL only
-		x86_64.addImageTypes(gceX86Platform, mkGCERHUIImageType(rd.isRHEL()))
-	} else {
-		x86_64.addImageTypes(azureX64Platform, azureImgType)
-		aarch64.addImageTypes(azureAarch64Platform, azureImgType)
-	}
 	rd.addArches(x86_64, aarch64, ppc64le, s390x)
 	return &rd
 }
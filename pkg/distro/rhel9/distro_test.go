@@ -18,13 +18,44 @@ import (
 type rhelFamilyDistro struct {
 	name   string
 	distro distro.Distro
+	// edge is set if the distribution has the edge image types
+	edge bool
 }
 
 var rhelFamilyDistros = []rhelFamilyDistro{
 	{
 		name:   "rhel",
 		distro: rhel9.New(),
+		edge:   true,
 	},
+	{
+		name:   "rhel-10",
+		distro: rhel9.NewRHEL10(),
+	},
+	{
+		name:   "centos-10",
+		distro: rhel9.NewCentOS10(),
+	},
+}
+
+// image types that need RHUI or RHSM and are only available on RHEL
+var rhelOnlyImageTypes = []string{"azure-rhui", "ec2", "ec2-ha", "ec2-sap", "gce-rhui"}
+
+// hasImageType returns false for the image types the distribution doesn't
+// have compared to RHEL 9
+func (d rhelFamilyDistro) hasImageType(name string) bool {
+	isEdge := strings.HasPrefix(name, "edge-") || strings.HasPrefix(name, "rhel-edge-")
+	if isEdge && !d.edge {
+		return false
+	}
+	if !strings.HasPrefix(d.name, "rhel") {
+		for _, rhelOnly := range rhelOnlyImageTypes {
+			if name == rhelOnly {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 func TestFilenameFromType(t *testing.T) {
@@ -230,6 +261,9 @@ func TestFilenameFromType(t *testing.T) {
 		t.Run(dist.name, func(t *testing.T) {
 			for _, tt := range tests {
 				t.Run(tt.name, func(t *testing.T) {
+					if !dist.hasImageType(tt.args.outputFormat) {
+						t.Skipf("%s doesn't have the image type %q", dist.name, tt.args.outputFormat)
+					}
 					dist := dist.distro
 					arch, _ := dist.GetArch("x86_64")
 					imgType, err := arch.GetImageType(tt.args.outputFormat)
@@ -374,7 +408,7 @@ func TestImageType_Name(t *testing.T) {
 				arch, err := dist.distro.GetArch(mapping.arch)
 				if assert.NoError(t, err) {
 					for _, imgName := range mapping.imgNames {
-						if imgName == "edge-commit" && dist.name == "centos" {
+						if !dist.hasImageType(imgName) {
 							continue
 						}
 						imgType, err := arch.GetImageType(imgName)
@@ -468,7 +502,6 @@ func TestImageTypeAliases(t *testing.T) {
 func TestDistro_ManifestError(t *testing.T) {
 	// Currently, the only unsupported configuration is OSTree commit types
 	// with Kernel boot options
-	r9distro := rhel9.New()
 	bp := blueprint.Blueprint{
 		Customizations: &blueprint.Customizations{
 			Kernel: &blueprint.KernelCustomization{
@@ -477,22 +510,24 @@ func TestDistro_ManifestError(t *testing.T) {
 		},
 	}
 
-	for _, archName := range r9distro.ListArches() {
-		arch, _ := r9distro.GetArch(archName)
-		for _, imgTypeName := range arch.ListImageTypes() {
-			imgType, _ := arch.GetImageType(imgTypeName)
-			imgOpts := distro.ImageOptions{
-				Size: imgType.Size(0),
-			}
-			_, _, err := imgType.Manifest(&bp, imgOpts, nil, 0)
-			if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
-				assert.EqualError(t, err, "kernel boot parameter customizations are not supported for ostree types")
-			} else if imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
-				assert.EqualError(t, err, fmt.Sprintf("\"%s\" images require specifying a URL from which to retrieve the OSTree commit", imgTypeName))
-			} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" {
-				assert.EqualError(t, err, fmt.Sprintf("boot ISO image type \"%s\" requires specifying a URL from which to retrieve the OSTree commit", imgTypeName))
-			} else {
-				assert.NoError(t, err)
+	for _, dist := range rhelFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, _ := dist.distro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				imgOpts := distro.ImageOptions{
+					Size: imgType.Size(0),
+				}
+				_, _, err := imgType.Manifest(&bp, imgOpts, nil, 0)
+				if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
+					assert.EqualError(t, err, "kernel boot parameter customizations are not supported for ostree types")
+				} else if imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
+					assert.EqualError(t, err, fmt.Sprintf("\"%s\" images require specifying a URL from which to retrieve the OSTree commit or an OSTree container", imgTypeName))
+				} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" {
+					assert.EqualError(t, err, fmt.Sprintf("boot ISO image type \"%s\" requires specifying a URL from which to retrieve the OSTree commit", imgTypeName))
+				} else {
+					assert.NoError(t, err)
+				}
 			}
 		}
 	}
@@ -579,7 +614,11 @@ func TestArchitecture_ListImageTypes(t *testing.T) {
 				imageTypes := arch.ListImageTypes()
 
 				var expectedImageTypes []string
-				expectedImageTypes = append(expectedImageTypes, mapping.imgNames...)
+				for _, name := range mapping.imgNames {
+					if dist.hasImageType(name) {
+						expectedImageTypes = append(expectedImageTypes, name)
+					}
+				}
 				if dist.name == "rhel" {
 					expectedImageTypes = append(expectedImageTypes, mapping.rhelAdditionalImageTypes...)
 				}
@@ -640,13 +679,50 @@ func TestRhel9_Name(t *testing.T) {
 	assert.Equal(t, "rhel-9", distro.Name())
 }
 
+func TestRhel10_Name(t *testing.T) {
+	assert.Equal(t, "rhel-10", rhel9.NewRHEL10().Name())
+	assert.Equal(t, "rhel-10.0", rhel9.NewRHEL100().Name())
+	assert.Equal(t, "rhel-94", rhel9.NewRHEL94().Name())
+}
+
+func TestCentOS10_Name(t *testing.T) {
+	distro := rhel9.NewCentOS10()
+	assert.Equal(t, "centos-10", distro.Name())
+	assert.Equal(t, "10", distro.Releasever())
+}
+
+func TestRhel9_Rebuilds(t *testing.T) {
+	for _, d := range []distro.Distro{rhel9.NewAlmaLinux9(), rhel9.NewRocky9()} {
+		t.Run(d.Name(), func(t *testing.T) {
+			assert.Equal(t, "9", d.Releasever())
+			assert.Equal(t, "platform:el9", d.ModulePlatformID())
+
+			// image types that need RHUI or RHSM are only available on RHEL
+			arch, err := d.GetArch("x86_64")
+			require.NoError(t, err)
+			for _, name := range []string{"ec2", "azure-rhui", "gce-rhui"} {
+				_, err := arch.GetImageType(name)
+				assert.Error(t, err, name)
+			}
+			_, err = arch.GetImageType("qcow2")
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestRhel9_ModulePlatformID(t *testing.T) {
 	distro := rhel9.New()
 	assert.Equal(t, "platform:el9", distro.ModulePlatformID())
+	assert.Equal(t, "platform:el10", rhel9.NewRHEL10().ModulePlatformID())
+	assert.Equal(t, "platform:el10", rhel9.NewCentOS10().ModulePlatformID())
 }
 
 func TestRhel9_KernelOption(t *testing.T) {
-	distro_test_common.TestDistro_KernelOption(t, rhel9.New())
+	for _, dist := range rhelFamilyDistros {
+		t.Run(dist.name, func(t *testing.T) {
+			distro_test_common.TestDistro_KernelOption(t, dist.distro)
+		})
+	}
 }
 
 func TestRhel9_OSTreeOptions(t *testing.T) {
@@ -654,7 +730,6 @@ func TestRhel9_OSTreeOptions(t *testing.T) {
 }
 
 func TestDistro_CustomFileSystemManifestError(t *testing.T) {
-	r9distro := rhel9.New()
 	bp := blueprint.Blueprint{
 		Customizations: &blueprint.Customizations{
 			Filesystem: []blueprint.FilesystemCustomization{
@@ -665,24 +740,25 @@ func TestDistro_CustomFileSystemManifestError(t *testing.T) {
 			},
 		},
 	}
-	for _, archName := range r9distro.ListArches() {
-		arch, _ := r9distro.GetArch(archName)
-		for _, imgTypeName := range arch.ListImageTypes() {
-			imgType, _ := arch.GetImageType(imgTypeName)
-			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
-			if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
-				assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
-			} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" || imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
-				continue
-			} else {
-				assert.EqualError(t, err, "The following custom mountpoints are not supported [\"/etc\"]")
+	for _, dist := range rhelFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, _ := dist.distro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+				if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
+					assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
+				} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" || imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
+					continue
+				} else {
+					assert.EqualError(t, err, "The following custom mountpoints are not supported [\"/etc\"]")
+				}
 			}
 		}
 	}
 }
 
 func TestDistro_TestRootMountPoint(t *testing.T) {
-	r9distro := rhel9.New()
 	bp := blueprint.Blueprint{
 		Customizations: &blueprint.Customizations{
 			Filesystem: []blueprint.FilesystemCustomization{
@@ -693,24 +769,25 @@ func TestDistro_TestRootMountPoint(t *testing.T) {
 			},
 		},
 	}
-	for _, archName := range r9distro.ListArches() {
-		arch, _ := r9distro.GetArch(archName)
-		for _, imgTypeName := range arch.ListImageTypes() {
-			imgType, _ := arch.GetImageType(imgTypeName)
-			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
-			if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
-				assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
-			} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" || imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
-				continue
-			} else {
-				assert.NoError(t, err)
+	for _, dist := range rhelFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, _ := dist.distro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+				if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
+					assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
+				} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" || imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
+					continue
+				} else {
+					assert.NoError(t, err)
+				}
 			}
 		}
 	}
 }
 
 func TestDistro_CustomFileSystemSubDirectories(t *testing.T) {
-	r9distro := rhel9.New()
 	bp := blueprint.Blueprint{
 		Customizations: &blueprint.Customizations{
 			Filesystem: []blueprint.FilesystemCustomization{
@@ -725,22 +802,23 @@ func TestDistro_CustomFileSystemSubDirectories(t *testing.T) {
 			},
 		},
 	}
-	for _, archName := range r9distro.ListArches() {
-		arch, _ := r9distro.GetArch(archName)
-		for _, imgTypeName := range arch.ListImageTypes() {
-			imgType, _ := arch.GetImageType(imgTypeName)
-			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
-			if strings.HasPrefix(imgTypeName, "edge-") {
-				continue
-			} else {
-				assert.NoError(t, err)
+	for _, dist := range rhelFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, _ := dist.distro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+				if strings.HasPrefix(imgTypeName, "edge-") {
+					continue
+				} else {
+					assert.NoError(t, err)
+				}
 			}
 		}
 	}
 }
 
 func TestDistro_MountpointsWithArbitraryDepthAllowed(t *testing.T) {
-	r9distro := rhel9.New()
 	bp := blueprint.Blueprint{
 		Customizations: &blueprint.Customizations{
 			Filesystem: []blueprint.FilesystemCustomization{
@@ -763,22 +841,23 @@ func TestDistro_MountpointsWithArbitraryDepthAllowed(t *testing.T) {
 			},
 		},
 	}
-	for _, archName := range r9distro.ListArches() {
-		arch, _ := r9distro.GetArch(archName)
-		for _, imgTypeName := range arch.ListImageTypes() {
-			imgType, _ := arch.GetImageType(imgTypeName)
-			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
-			if strings.HasPrefix(imgTypeName, "edge-") {
-				continue
-			} else {
-				assert.NoError(t, err)
+	for _, dist := range rhelFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, _ := dist.distro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+				if strings.HasPrefix(imgTypeName, "edge-") {
+					continue
+				} else {
+					assert.NoError(t, err)
+				}
 			}
 		}
 	}
 }
 
 func TestDistro_DirtyMountpointsNotAllowed(t *testing.T) {
-	r9distro := rhel9.New()
 	bp := blueprint.Blueprint{
 		Customizations: &blueprint.Customizations{
 			Filesystem: []blueprint.FilesystemCustomization{
@@ -797,22 +876,23 @@ func TestDistro_DirtyMountpointsNotAllowed(t *testing.T) {
 			},
 		},
 	}
-	for _, archName := range r9distro.ListArches() {
-		arch, _ := r9distro.GetArch(archName)
-		for _, imgTypeName := range arch.ListImageTypes() {
-			imgType, _ := arch.GetImageType(imgTypeName)
-			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
-			if strings.HasPrefix(imgTypeName, "edge-") {
-				continue
-			} else {
-				assert.EqualError(t, err, "The following custom mountpoints are not supported [\"//\" \"/var//\" \"/var//log/audit/\"]")
+	for _, dist := range rhelFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, _ := dist.distro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+				if strings.HasPrefix(imgTypeName, "edge-") {
+					continue
+				} else {
+					assert.EqualError(t, err, "The following custom mountpoints are not supported [\"//\" \"/var//\" \"/var//log/audit/\"]")
+				}
 			}
 		}
 	}
 }
 
 func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
-	r9distro := rhel9.New()
 	bp := blueprint.Blueprint{
 		Customizations: &blueprint.Customizations{
 			Filesystem: []blueprint.FilesystemCustomization{
@@ -823,17 +903,19 @@ func TestDistro_CustomUsrPartitionNotLargeEnough(t *testing.T) {
 			},
 		},
 	}
-	for _, archName := range r9distro.ListArches() {
-		arch, _ := r9distro.GetArch(archName)
-		for _, imgTypeName := range arch.ListImageTypes() {
-			imgType, _ := arch.GetImageType(imgTypeName)
-			_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
-			if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
-				assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
-			} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" || imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
-				continue
-			} else {
-				assert.NoError(t, err)
+	for _, dist := range rhelFamilyDistros {
+		for _, archName := range dist.distro.ListArches() {
+			arch, _ := dist.distro.GetArch(archName)
+			for _, imgTypeName := range arch.ListImageTypes() {
+				imgType, _ := arch.GetImageType(imgTypeName)
+				_, _, err := imgType.Manifest(&bp, distro.ImageOptions{}, nil, 0)
+				if imgTypeName == "edge-commit" || imgTypeName == "edge-container" {
+					assert.EqualError(t, err, "Custom mountpoints are not supported for ostree types")
+				} else if imgTypeName == "edge-installer" || imgTypeName == "edge-simplified-installer" || imgTypeName == "edge-raw-image" || imgTypeName == "edge-ami" || imgTypeName == "edge-vsphere" {
+					continue
+				} else {
+					assert.NoError(t, err)
+				}
 			}
 		}
 	}
@@ -6,7 +6,7 @@ import (
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/fdo"
-	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/firstboot"
 	"github.com/osbuild/images/internal/ignition"
 	"github.com/osbuild/images/internal/oscap"
 	"github.com/osbuild/images/internal/users"
@@ -29,7 +29,11 @@ func osCustomizations(
 	c *blueprint.Customizations,
 ) manifest.OSCustomizations {
 
-	imageConfig := t.getDefaultImageConfig()
+	imageConfig, err := t.getDefaultImageConfig().WithCustomizations(c)
+	if err != nil {
+		// The customizations have already been validated in checkOptions()
+		panic(fmt.Sprintf("failed to apply system configuration customizations: %v", err))
+	}
 
 	osc := manifest.OSCustomizations{}
 
@@ -46,11 +50,14 @@ func osCustomizations(
 		osc.KernelOptionsAppend = kernelOptions
 	}
 
+	osc.FIPS = c.GetFIPS()
+
 	osc.ExtraBasePackages = osPackageSet.Include
 	osc.ExcludeBasePackages = osPackageSet.Exclude
 	osc.ExtraBaseRepos = osPackageSet.Repositories
 
 	osc.Containers = containers
+	osc.SecureExecution = options.SecureExecution
 
 	osc.GPGKeyFiles = imageConfig.GPGKeyFiles
 	if imageConfig.ExcludeDocs != nil {
@@ -135,7 +142,6 @@ func osCustomizations(
 		osc.FactAPIType = &options.Facts.APIType
 	}
 
-	var err error
 	osc.Directories, err = blueprint.DirectoryCustomizationsToFsNodeDirectories(c.GetDirectories())
 	if err != nil {
 		// In theory this should never happen, because the blueprint directory customizations
@@ -150,6 +156,10 @@ func osCustomizations(
 		panic(fmt.Sprintf("failed to convert file customizations to fs node files: %v", err))
 	}
 
+	if fb := c.GetFirstBoot(); fb != nil {
+		osc.FirstBoot = firstboot.OptionsFromBP(*fb)
+	}
+
 	// set yum repos first, so it doesn't get overridden by
 	// imageConfig.YUMRepos
 	osc.YUMRepos = imageConfig.YUMRepos
@@ -183,43 +193,9 @@ func osCustomizations(
 		if t.rpmOstree {
 			panic("unexpected oscap options for ostree image type")
 		}
-		var datastream = oscapConfig.DataStream
-		if datastream == "" {
-			datastream = oscap.DefaultRHEL9Datastream(t.arch.distro.isRHEL())
+		if err := distro.ApplyOpenSCAPCustomizations(&osc, oscapConfig, oscap.DefaultDatastream(t.arch.distro.id(), t.arch.distro.major())); err != nil {
+			panic(err)
 		}
-
-		oscapStageOptions := osbuild.OscapConfig{
-			Datastream: datastream,
-			ProfileID:  oscapConfig.ProfileID,
-		}
-
-		if oscapConfig.Tailoring != nil {
-			newProfile, tailoringFilepath, tailoringDir, err := oscap.GetTailoringFile(oscapConfig.ProfileID)
-			if err != nil {
-				panic(fmt.Sprintf("unexpected error creating tailoring file options: %v", err))
-			}
-
-			tailoringOptions := osbuild.OscapAutotailorConfig{
-				Selected:   oscapConfig.Tailoring.Selected,
-				Unselected: oscapConfig.Tailoring.Unselected,
-				NewProfile: newProfile,
-			}
-
-			osc.OpenSCAPTailorConfig = osbuild.NewOscapAutotailorStageOptions(
-				tailoringFilepath,
-				oscapStageOptions,
-				tailoringOptions,
-			)
-
-			// overwrite the profile id with the new tailoring id
-			oscapStageOptions.ProfileID = newProfile
-			oscapStageOptions.Tailoring = tailoringFilepath
-
-			// add the parent directory for the tailoring file
-			osc.Directories = append(osc.Directories, tailoringDir)
-		}
-
-		osc.OpenSCAPConfig = osbuild.NewOscapRemediationStageOptions(oscapStageOptions)
 	}
 
 	osc.ShellInit = imageConfig.ShellInit
@@ -227,12 +203,17 @@ func osCustomizations(
 	osc.Grub2Config = imageConfig.Grub2Config
 	osc.Sysconfig = imageConfig.Sysconfig
 	osc.SystemdLogind = imageConfig.SystemdLogind
+	osc.SystemdJournald = imageConfig.SystemdJournald
 	osc.CloudInit = imageConfig.CloudInit
 	osc.Modprobe = imageConfig.Modprobe
 	osc.DracutConf = imageConfig.DracutConf
+	if imageConfig.RegenerateInitramfs != nil {
+		osc.RegenerateInitramfs = *imageConfig.RegenerateInitramfs
+	}
 	osc.SystemdUnit = imageConfig.SystemdUnit
 	osc.Authselect = imageConfig.Authselect
 	osc.SELinuxConfig = imageConfig.SELinuxConfig
+	osc.SELinuxPolicy = imageConfig.SELinuxPolicy
 	osc.Tuned = imageConfig.Tuned
 	osc.Tmpfilesd = imageConfig.Tmpfilesd
 	osc.PamLimitsConf = imageConfig.PamLimitsConf
@@ -376,6 +357,10 @@ func edgeInstallerImage(workload workload.Workload,
 		img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
 	}
 
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, customizations); err != nil {
+		return nil, err
+	}
+
 	img.ISOLabelTempl = d.isolabelTmpl
 	img.Product = d.product
 	img.Variant = "edge"
@@ -396,21 +381,25 @@ func edgeRawImage(workload workload.Workload,
 	containers []container.SourceSpec,
 	rng *rand.Rand) (image.ImageKind, error) {
 
-	commit, err := makeOSTreePayloadCommit(options.OSTree, t.OSTreeRef())
+	img, err := newOSTreeDiskImage(options.OSTree, t.OSTreeRef())
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s", t.Name(), err.Error())
 	}
-	img := image.NewOSTreeDiskImage(commit)
 
 	img.Users = users.UsersFromBP(customizations.GetUsers())
 	img.Groups = users.GroupsFromBP(customizations.GetGroups())
 
+	if fb := customizations.GetFirstBoot(); fb != nil {
+		img.FirstBoot = firstboot.OptionsFromBP(*fb)
+	}
+
 	// The kernel options defined on the image type are usually handled in
 	// osCustomiztions() but ostree images don't use OSCustomizations, so we
 	// handle them here separately.
 	if t.kernelOptions != "" {
 		img.KernelOptionsAppend = append(img.KernelOptionsAppend, t.kernelOptions)
 	}
+	img.FIPS = customizations.GetFIPS()
 	img.Keyboard = "us"
 	img.Locale = "C.UTF-8"
 	if !common.VersionLessThan(t.arch.distro.osVersion, "9.2") || !t.arch.distro.isRHEL() {
@@ -428,10 +417,13 @@ func edgeRawImage(workload workload.Workload,
 
 	img.Platform = t.platform
 	img.Workload = workload
-	img.Remote = ostree.Remote{
-		Name:       "rhel-edge",
-		URL:        options.OSTree.URL,
-		ContentURL: options.OSTree.ContentURL,
+	// images deployed from a container are updated from the registry
+	if img.CommitSource != nil {
+		img.Remote = ostree.Remote{
+			Name:       "rhel-edge",
+			URL:        options.OSTree.URL,
+			ContentURL: options.OSTree.ContentURL,
+		}
 	}
 	img.OSName = "redhat"
 
@@ -470,6 +462,7 @@ func edgeSimplifiedInstallerImage(workload workload.Workload,
 	rawImg.Groups = users.GroupsFromBP(customizations.GetGroups())
 
 	rawImg.KernelOptionsAppend = []string{"modprobe.blacklist=vc4"}
+	rawImg.FIPS = customizations.GetFIPS()
 	rawImg.Keyboard = "us"
 	rawImg.Locale = "C.UTF-8"
 	if !common.VersionLessThan(t.arch.distro.osVersion, "9.2") || !t.arch.distro.isRHEL() {
@@ -563,6 +556,10 @@ func imageInstallerImage(workload workload.Workload,
 	img.AdditionalDrivers = []string{"cuse", "ipmi_devintf", "ipmi_msghandler"}
 	img.AdditionalAnacondaModules = []string{"org.fedoraproject.Anaconda.Modules.Users"}
 
+	if err := distro.ApplyInstallerCustomizations(&img.InstallerCustomizations, customizations); err != nil {
+		return nil, err
+	}
+
 	img.SquashfsCompression = "xz"
 
 	// put the kickstart file in the root of the iso
@@ -657,12 +654,20 @@ func makeOSTreePayloadCommit(options *ostree.ImageOptions, defaultRef string) (o
 	}, nil
 }
 
-// initialSetupKickstart returns the File configuration for a kickstart file
-// that's required to enable initial-setup to run on first boot.
-func initialSetupKickstart() *fsnode.File {
-	file, err := fsnode.NewFile("/root/anaconda-ks.cfg", nil, "root", "root", []byte("# Run initial-setup on first boot\n# Created by osbuild\nfirstboot --reconfig\nlang en_US.UTF-8\n"))
+// newOSTreeDiskImage creates the disk image of an ostree image type. The image
+// deploys the OSTree native container from the options if one is set and the
+// payload commit otherwise.
+func newOSTreeDiskImage(options *ostree.ImageOptions, defaultRef string) (*image.OSTreeDiskImage, error) {
+	if options != nil && options.Container != "" {
+		return image.NewOSTreeDiskImageFromContainer(container.SourceSpec{
+			Source: options.Container,
+			Name:   options.Container,
+		}), nil
+	}
+
+	commit, err := makeOSTreePayloadCommit(options, defaultRef)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return file
+	return image.NewOSTreeDiskImage(commit), nil
 }
@@ -46,6 +46,8 @@ type imageFunc func(workload workload.Workload, t *imageType, customizations *bl
 
 type packageSetFunc func(t *imageType) rpmmd.PackageSet
 
+type imageConfigFunc func(t *imageType) *distro.ImageConfig
+
 type basePartitionTableFunc func(t *imageType) (disk.PartitionTable, bool)
 
 type imageType struct {
@@ -59,7 +61,7 @@ type imageType struct {
 	compression        string // TODO: remove from image definition and make it a transport option
 	mimeType           string
 	packageSets        map[string]packageSetFunc
-	defaultImageConfig *distro.ImageConfig
+	defaultImageConfig imageConfigFunc
 	kernelOptions      string
 	defaultSize        uint64
 	buildPipelines     []string
@@ -135,6 +137,15 @@ func (t *imageType) Exports() []string {
 	return []string{"assembler"}
 }
 
+func (t *imageType) RecommendedResources() distro.VMResources {
+	resources := distro.RecommendedResourcesFallback()
+	if t.bootISO {
+		// the installer and its image run from memory
+		resources.MemoryMiB = 4096
+	}
+	return resources
+}
+
 func (t *imageType) BootMode() distro.BootMode {
 	if t.platform.GetUEFIVendor() != "" && t.platform.GetBIOSPlatform() != "" {
 		return distro.BOOT_HYBRID
@@ -173,12 +184,22 @@ func (t *imageType) getPartitionTable(
 		partitioningMode = disk.LVMPartitioningMode
 	}
 
-	return disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, partitioningMode, nil, rng)
+	pt, err := disk.NewPartitionTable(&basePartitionTable, mountpoints, imageSize, partitioningMode, nil, rng)
+	if err != nil {
+		return nil, err
+	}
+	if err := pt.ValidateForPlatform(t.platform); err != nil {
+		return nil, fmt.Errorf("invalid partition table for %s on %s: %w", t.Name(), t.arch.Name(), err)
+	}
+	return pt, nil
 }
 
 func (t *imageType) getDefaultImageConfig() *distro.ImageConfig {
 	// ensure that image always returns non-nil default config
-	imageConfig := t.defaultImageConfig
+	var imageConfig *distro.ImageConfig
+	if t.defaultImageConfig != nil {
+		imageConfig = t.defaultImageConfig(t)
+	}
 	if imageConfig == nil {
 		imageConfig = &distro.ImageConfig{}
 	}
@@ -260,6 +281,9 @@ func (t *imageType) Manifest(bp *blueprint.Blueprint,
 	}
 	mf := manifest.New()
 	mf.Distro = manifest.DISTRO_EL9
+	if t.arch.distro.major() == 10 {
+		mf.Distro = manifest.DISTRO_EL10
+	}
 	_, err = img.InstantiateManifest(&mf, repos, t.arch.distro.runner, rng)
 	if err != nil {
 		return nil, nil, err
@@ -292,12 +316,31 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("embedding containers is not supported for %s on %s", t.name, t.arch.distro.name)
 	}
 
+	if len(bp.Flatpaks) > 0 {
+		return warnings, fmt.Errorf("embedding flatpaks is not supported for %s on %s", t.name, t.arch.distro.name)
+	}
+
+	if se := options.SecureExecution; se != nil {
+		if !t.platform.GetZiplSupport() {
+			return warnings, fmt.Errorf("IBM Secure Execution is not supported for %s on %s", t.name, t.arch.Name())
+		}
+		if err := se.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
 	if options.OSTree != nil {
 		if err := options.OSTree.Validate(); err != nil {
 			return nil, err
 		}
 	}
 
+	if installer, err := customizations.GetInstaller(); err != nil {
+		return warnings, err
+	} else if installer != nil && !t.bootISO {
+		return warnings, fmt.Errorf("installer customizations are not supported for image type %q", t.name)
+	}
+
 	if t.bootISO && t.rpmOstree {
 		// ostree-based ISOs require a URL from which to pull a payload commit
 		if options.OSTree == nil || options.OSTree.URL == "" {
@@ -305,7 +348,7 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		}
 
 		if t.name == "edge-simplified-installer" {
-			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group"}
+			allowed := []string{"InstallationDevice", "FDO", "Ignition", "Kernel", "User", "Group", "FIPS"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
@@ -343,20 +386,25 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 				}
 			}
 		} else if t.name == "edge-installer" {
-			allowed := []string{"User", "Group"}
+			allowed := []string{"User", "Group", "Installer"}
 			if err := customizations.CheckAllowed(allowed...); err != nil {
 				return warnings, fmt.Errorf("unsupported blueprint customizations found for boot ISO image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 			}
 		}
 	}
 
+	if options.OSTree != nil && options.OSTree.Container != "" && t.name != "edge-raw-image" && t.name != "edge-ami" && t.name != "edge-vsphere" {
+		return warnings, fmt.Errorf("image type %q does not support deploying an OSTree container", t.name)
+	}
+
 	if t.name == "edge-raw-image" || t.name == "edge-ami" || t.name == "edge-vsphere" {
-		// ostree-based bootable images require a URL from which to pull a payload commit
-		if options.OSTree == nil || options.OSTree.URL == "" {
-			return warnings, fmt.Errorf("%q images require specifying a URL from which to retrieve the OSTree commit", t.name)
+		// ostree-based bootable images require a URL from which to pull a
+		// payload commit or a container to deploy
+		if options.OSTree == nil || (options.OSTree.URL == "" && options.OSTree.Container == "") {
+			return warnings, fmt.Errorf("%q images require specifying a URL from which to retrieve the OSTree commit or an OSTree container", t.name)
 		}
 
-		allowed := []string{"Ignition", "Kernel", "User", "Group"}
+		allowed := []string{"Ignition", "Kernel", "User", "Group", "FirstBoot", "FIPS"}
 		if err := customizations.CheckAllowed(allowed...); err != nil {
 			return warnings, fmt.Errorf("unsupported blueprint customizations found for image type %q: (allowed: %s)", t.name, strings.Join(allowed, ", "))
 		}
@@ -382,6 +430,25 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, fmt.Errorf("kernel boot parameter customizations are not supported for ostree types")
 	}
 
+	if customizations.GetFIPS() && !common.IsBuildHostFIPSEnabled() {
+		w := fmt.Sprintln(common.FIPSEnabledImageWarning)
+		log.Print(w)
+		warnings = append(warnings, w)
+	}
+
+	if _, err := t.getDefaultImageConfig().WithCustomizations(customizations); err != nil {
+		return warnings, err
+	}
+	if options.Subscription != nil {
+		if err := options.Subscription.Validate(); err != nil {
+			return warnings, err
+		}
+	}
+
+	if err := distro.CheckEmbeddedContainers(customizations, bp.Containers); err != nil {
+		return warnings, err
+	}
+
 	mountpoints := customizations.GetFilesystems()
 
 	if mountpoints != nil && t.rpmOstree {
@@ -406,6 +473,9 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		if osc.ProfileID == "" {
 			return warnings, fmt.Errorf("OpenSCAP profile cannot be empty")
 		}
+		if err := blueprint.ValidateOpenSCAPCustomization(osc); err != nil {
+			return warnings, err
+		}
 	}
 
 	// Check Directory/File Customizations are valid
@@ -426,6 +496,15 @@ func (t *imageType) checkOptions(bp *blueprint.Blueprint, options distro.ImageOp
 		return warnings, err
 	}
 
+	fb := customizations.GetFirstBoot()
+	if err := blueprint.ValidateFirstBootCustomization(fb); err != nil {
+		return warnings, err
+	}
+	err = blueprint.CheckFirstBootCustomizationPolicy(fb, pathpolicy.FirstBootScriptsPolicies)
+	if err != nil {
+		return warnings, err
+	}
+
 	// check if repository customizations are valid
 	_, err = customizations.GetRepositories()
 	if err != nil {
@@ -193,6 +193,10 @@ func (t *TestImageType) PartitionType() string {
 	return ""
 }
 
+func (t *TestImageType) RecommendedResources() distro.VMResources {
+	return distro.RecommendedResourcesFallback()
+}
+
 func (t *TestImageType) BootMode() distro.BootMode {
 	return distro.BOOT_HYBRID
 }
@@ -32,6 +32,8 @@ var supportedDistros = []func() distro.Distro{
 	rhel8.NewRHEL89,
 	rhel8.NewRHEL810,
 	rhel8.NewCentos,
+	rhel8.NewAlmaLinux8,
+	rhel8.NewRocky8,
 
 	rhel9.New,
 	rhel9.NewRHEL90,
@@ -40,6 +42,12 @@ var supportedDistros = []func() distro.Distro{
 	rhel9.NewRHEL93,
 	rhel9.NewRHEL94,
 	rhel9.NewCentOS9,
+	rhel9.NewAlmaLinux9,
+	rhel9.NewRocky9,
+
+	rhel9.NewRHEL10,
+	rhel9.NewRHEL100,
+	rhel9.NewCentOS10,
 }
 
 type Registry struct {
@@ -48,7 +56,25 @@ type Registry struct {
 	hostArchName string
 }
 
+// loader is a distribution that loads its image types from definitions when
+// it is created
+type loader interface {
+	// LoadError returns the error loading the definitions failed with
+	LoadError() error
+}
+
+// New creates a Registry of the given distributions. An error is returned if
+// the image type definitions of a distribution could not be loaded.
 func New(hostDistro distro.Distro, distros ...distro.Distro) (*Registry, error) {
+	for _, d := range distros {
+		if l, ok := d.(loader); ok && l.LoadError() != nil {
+			return nil, l.LoadError()
+		}
+	}
+	return newRegistry(hostDistro, distros...)
+}
+
+func newRegistry(hostDistro distro.Distro, distros ...distro.Distro) (*Registry, error) {
 	reg := &Registry{
 		distros:      make(map[string]distro.Distro),
 		hostDistro:   hostDistro,
@@ -66,7 +92,9 @@ func New(hostDistro distro.Distro, distros ...distro.Distro) (*Registry, error)
 
 // NewDefault creates a Registry with all distributions supported by
 // osbuild-composer. If you need to add a distribution here, see the
-// supportedDistros variable.
+// supportedDistros variable. Distributions whose image type definitions
+// could not be loaded are registered nevertheless, so that the others can be
+// used: their GetArch method returns the error. Use Errors to check for them.
 func NewDefault() *Registry {
 	var distros []distro.Distro
 	var hostDistro distro.Distro
@@ -84,7 +112,7 @@ func NewDefault() *Registry {
 		distros = append(distros, distro)
 	}
 
-	registry, err := New(hostDistro, distros...)
+	registry, err := newRegistry(hostDistro, distros...)
 	if err != nil {
 		panic(fmt.Sprintf("two supported distros have the same name, this is a programming error: %v", err))
 	}
@@ -101,6 +129,18 @@ func (r *Registry) GetDistro(name string) distro.Distro {
 	return d
 }
 
+// Errors returns the errors the distributions of the Registry failed to load
+// their image type definitions with, sorted by the name of the distribution.
+func (r *Registry) Errors() []error {
+	var errs []error
+	for _, name := range r.List() {
+		if l, ok := r.distros[name].(loader); ok && l.LoadError() != nil {
+			errs = append(errs, l.LoadError())
+		}
+	}
+	return errs
+}
+
 // List returns the names of all distros in a Registry, sorted alphabetically.
 func (r *Registry) List() []string {
 	list := []string{}
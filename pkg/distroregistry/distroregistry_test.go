@@ -1,6 +1,7 @@
 package distroregistry
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -74,6 +75,9 @@ func TestRegistry_mangleHostDistroName(t *testing.T) {
 		{"rhel-90 beta", args{"rhel-90", true, false}, "rhel-90-beta"},
 		{"rhel-90 stream", args{"rhel-90", false, true}, "rhel-90"},
 		{"rhel-90 beta stream", args{"rhel-90", true, true}, "rhel-90-beta"},
+
+		{"almalinux-9", args{"almalinux-9", false, false}, "almalinux-9"},
+		{"rocky-8", args{"rocky-8", false, false}, "rocky-8"},
 	}
 
 	for _, tt := range tests {
@@ -120,3 +124,19 @@ func TestRegistry_FromHost(t *testing.T) {
 		require.Equal(t, gotDistro.Name(), hostDistro.Name())
 	})
 }
+
+// brokenDistro is a distribution whose image type definitions failed to load
+type brokenDistro struct {
+	distro.Distro
+}
+
+func (d brokenDistro) LoadError() error {
+	return errors.New("invalid image type definitions")
+}
+
+func TestRegistry_LoadErrors(t *testing.T) {
+	require.Empty(t, NewDefault().Errors())
+
+	_, err := New(nil, rhel8.New(), brokenDistro{rhel8.NewCentos()})
+	require.EqualError(t, err, "invalid image type definitions")
+}
@@ -0,0 +1,144 @@
+// Package flatpak describes flatpaks that are embedded in images.
+//
+// Flatpak repositories are OSTree repositories, so a flatpak ref is resolved
+// to a commit and fetched like any other OSTree commit. See the ostree package
+// for details. The commits are pulled into the repository of the system
+// installation when the image is built and a systemd unit deploys them from
+// there, without network access, when the image boots for the first time.
+package flatpak
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/pkg/ostree"
+)
+
+const (
+	// RepoPath is the OSTree repository of the system installation
+	RepoPath = "/var/lib/flatpak/repo"
+
+	// ServiceName is the systemd unit that deploys the flatpaks
+	ServiceName = "osbuild-flatpak-install.service"
+
+	// markerPath is created once the flatpaks are deployed, which prevents
+	// the unit from running again
+	markerPath = "/var/lib/osbuild-flatpak-install.done"
+	unitPath   = "/etc/systemd/system/" + ServiceName
+)
+
+// Remote is a flatpak repository that is configured in the image.
+type Remote struct {
+	Name string
+	URL  string
+
+	// ASCII-armored public GPG key of the remote (optional)
+	GPGKey string
+}
+
+// SourceSpec specifies a flatpak to resolve and install from a remote.
+type SourceSpec struct {
+	Remote Remote
+
+	// Full flatpak ref, e.g. "app/org.gnome.Calculator/x86_64/stable"
+	Ref string
+}
+
+// Spec is a flatpak resolved to the checksum of the commit of its ref.
+type Spec struct {
+	Remote   Remote
+	Ref      string
+	Checksum string
+}
+
+// NewRef returns the full ref of a flatpak from its kind and ID (e.g.
+// "app/org.gnome.Calculator"), architecture and branch.
+func NewRef(kindAndID, arch, branch string) string {
+	return fmt.Sprintf("%s/%s/%s", kindAndID, arch, branch)
+}
+
+// OSTreeSourceSpec returns the ostree source specification used to resolve
+// the flatpak to a commit.
+func (s SourceSpec) OSTreeSourceSpec() ostree.SourceSpec {
+	return ostree.SourceSpec{
+		URL: s.Remote.URL,
+		Ref: s.Ref,
+	}
+}
+
+// NewSpec creates the Spec of a flatpak from its source specification and
+// the resolved commit.
+func NewSpec(source SourceSpec, commit ostree.CommitSpec) Spec {
+	if commit.Ref != source.Ref {
+		panic(fmt.Sprintf("commit ref %q does not match flatpak ref %q", commit.Ref, source.Ref))
+	}
+	return Spec{
+		Remote:   source.Remote,
+		Ref:      source.Ref,
+		Checksum: commit.Checksum,
+	}
+}
+
+// CommitSpec returns the ostree commit specification used to fetch the
+// flatpak. The commit is verified with the GPG key of the remote, if any.
+func (s Spec) CommitSpec() ostree.CommitSpec {
+	commit := ostree.CommitSpec{
+		Ref:      s.Ref,
+		URL:      s.Remote.URL,
+		Checksum: s.Checksum,
+	}
+	if s.Remote.GPGKey != "" {
+		commit.GPGKeys = []string{s.Remote.GPGKey}
+	}
+	return commit
+}
+
+// Remotes returns the unique remotes of the flatpaks in order of their first
+// appearance.
+func Remotes(specs []Spec) []Remote {
+	seen := make(map[string]bool)
+	var remotes []Remote
+	for _, spec := range specs {
+		if seen[spec.Remote.Name] {
+			continue
+		}
+		seen[spec.Remote.Name] = true
+		remotes = append(remotes, spec.Remote)
+	}
+	return remotes
+}
+
+// InstallFiles returns the systemd unit that deploys the flatpaks from the
+// repository of the system installation. Runtimes are deployed before the
+// applications that depend on them, related refs such as translations are
+// not deployed since they are not in the repository.
+func InstallFiles(specs []Spec) []*fsnode.File {
+	specs = append([]Spec(nil), specs...)
+	sort.SliceStable(specs, func(i, j int) bool {
+		return strings.HasPrefix(specs[i].Ref, "runtime/") && !strings.HasPrefix(specs[j].Ref, "runtime/")
+	})
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=Deploy the flatpaks embedded in the image\n")
+	fmt.Fprintf(&b, "ConditionPathExists=!%s\n", markerPath)
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=oneshot\n")
+	b.WriteString("RemainAfterExit=yes\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "ExecStart=/usr/bin/flatpak install --system --noninteractive --no-pull --no-related %s %s\n", spec.Remote.Name, spec.Ref)
+	}
+	fmt.Fprintf(&b, "ExecStartPost=/usr/bin/touch %s\n", markerPath)
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+
+	unit, err := fsnode.NewFile(unitPath, common.ToPtr(os.FileMode(0644)), "root", "root", []byte(b.String()))
+	if err != nil {
+		panic(err)
+	}
+	return []*fsnode.File{unit}
+}
@@ -0,0 +1,73 @@
+package flatpak
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/ostree"
+)
+
+func TestSpecs(t *testing.T) {
+	flathub := Remote{Name: "flathub", URL: "https://dl.flathub.org/repo/"}
+	fedora := Remote{Name: "fedora", URL: "oci+https://registry.fedoraproject.org"}
+
+	source := SourceSpec{
+		Remote: flathub,
+		Ref:    NewRef("app/org.gnome.Calculator", "x86_64", "stable"),
+	}
+	assert.Equal(t, ostree.SourceSpec{
+		URL: "https://dl.flathub.org/repo/",
+		Ref: "app/org.gnome.Calculator/x86_64/stable",
+	}, source.OSTreeSourceSpec())
+
+	commit := ostree.CommitSpec{
+		URL:      "https://dl.flathub.org/repo/",
+		Ref:      "app/org.gnome.Calculator/x86_64/stable",
+		Checksum: "8b4e5e2b4a1c4d7b5f0a3c6e9d2b1a0f7e6d5c4b3a2918e7d6c5b4a39281706f",
+	}
+	spec := NewSpec(source, commit)
+	assert.Equal(t, commit, spec.CommitSpec())
+
+	assert.Panics(t, func() { NewSpec(source, ostree.CommitSpec{Ref: "app/org.gnome.Maps/x86_64/stable"}) })
+
+	specs := []Spec{spec, {Remote: fedora}, {Remote: flathub}}
+	assert.Equal(t, []Remote{flathub, fedora}, Remotes(specs))
+}
+
+func TestCommitSpecGPGKey(t *testing.T) {
+	spec := Spec{
+		Remote:   Remote{Name: "flathub", URL: "https://dl.flathub.org/repo/", GPGKey: "key"},
+		Ref:      "app/org.gnome.Calculator/x86_64/stable",
+		Checksum: "8b4e5e2b4a1c4d7b5f0a3c6e9d2b1a0f7e6d5c4b3a2918e7d6c5b4a39281706f",
+	}
+	assert.Equal(t, []string{"key"}, spec.CommitSpec().GPGKeys)
+}
+
+func TestInstallFiles(t *testing.T) {
+	flathub := Remote{Name: "flathub", URL: "https://dl.flathub.org/repo/"}
+	specs := []Spec{
+		{Remote: flathub, Ref: "app/org.gnome.Calculator/x86_64/stable"},
+		{Remote: flathub, Ref: "runtime/org.gnome.Platform/x86_64/45"},
+	}
+
+	files := InstallFiles(specs)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "/etc/systemd/system/osbuild-flatpak-install.service", files[0].Path())
+	assert.Equal(t, `[Unit]
+Description=Deploy the flatpaks embedded in the image
+ConditionPathExists=!/var/lib/osbuild-flatpak-install.done
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/usr/bin/flatpak install --system --noninteractive --no-pull --no-related flathub runtime/org.gnome.Platform/x86_64/45
+ExecStart=/usr/bin/flatpak install --system --noninteractive --no-pull --no-related flathub app/org.gnome.Calculator/x86_64/stable
+ExecStartPost=/usr/bin/touch /var/lib/osbuild-flatpak-install.done
+
+[Install]
+WantedBy=multi-user.target
+`, string(files[0].Data()))
+	// the order of the specs is not changed
+	assert.Equal(t, "app/org.gnome.Calculator/x86_64/stable", specs[0].Ref)
+}
@@ -32,7 +32,7 @@ type AnacondaLiveInstaller struct {
 
 	Filename string
 
-	AdditionalKernelOpts []string
+	InstallerCustomizations
 }
 
 func NewAnacondaLiveInstaller() *AnacondaLiveInstaller {
@@ -45,6 +45,10 @@ func (img *AnacondaLiveInstaller) InstantiateManifest(m *manifest.Manifest,
 	repos []rpmmd.RepoConfig,
 	runner runner.Runner,
 	rng *rand.Rand) (*artifact.Artifact, error) {
+	if err := checkISOBoot(img.Platform); err != nil {
+		return nil, err
+	}
+
 	buildPipeline := manifest.NewBuild(m, runner, repos)
 	buildPipeline.Checkpoint()
 
@@ -62,6 +66,8 @@ func (img *AnacondaLiveInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	livePipeline.Variant = img.Variant
 	livePipeline.Biosdevname = (img.Platform.GetArch() == platform.ARCH_X86_64)
+	livePipeline.AdditionalDracutModules = img.AdditionalDracutModules
+	livePipeline.AdditionalDrivers = img.AdditionalDrivers
 
 	livePipeline.Checkpoint()
 
@@ -102,8 +108,7 @@ func (img *AnacondaLiveInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	bootTreePipeline.KernelOpts = kernelOpts
 
-	// enable ISOLinux on x86_64 only
-	isoLinuxEnabled := img.Platform.GetArch() == platform.ARCH_X86_64
+	isoLinuxEnabled := img.Platform.GetISOBootType() == platform.ISOBOOT_UEFI_ISOLINUX
 
 	isoTreePipeline := manifest.NewAnacondaInstallerISOTree(buildPipeline, livePipeline, rootfsImagePipeline, bootTreePipeline)
 	isoTreePipeline.PartitionTable = rootfsPartitionTable
@@ -115,7 +120,7 @@ func (img *AnacondaLiveInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	isoPipeline := manifest.NewISO(buildPipeline, isoTreePipeline, isoLabel)
 	isoPipeline.SetFilename(img.Filename)
-	isoPipeline.ISOLinux = isoLinuxEnabled
+	isoPipeline.ISOBoot = img.Platform.GetISOBootType()
 
 	artifact := isoPipeline.Export()
 
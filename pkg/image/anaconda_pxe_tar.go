@@ -0,0 +1,126 @@
+package image
+
+import (
+	"math/rand"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/environment"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/internal/workload"
+	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+)
+
+// AnacondaPXETar is a tarball with everything needed to boot a live
+// environment or the anaconda installer over the network.
+type AnacondaPXETar struct {
+	Base
+	Platform         platform.Platform
+	OSCustomizations manifest.OSCustomizations
+	Environment      environment.Environment
+	Workload         workload.Workload
+
+	// Boot a live environment instead of the anaconda installer. The
+	// OSCustomizations, Environment, Users and Groups only apply to the
+	// payload of the installer.
+	Live bool
+
+	ExtraBasePackages rpmmd.PackageSet
+	Users             []users.User
+	Groups            []users.Group
+
+	// URL the extracted tarball is served from. If empty, the boot
+	// configurations contain manifest.PXEBaseURLPlaceholder instead.
+	BaseURL string
+
+	SquashfsCompression string
+
+	Product   string
+	Variant   string
+	OSName    string
+	OSVersion string
+
+	Filename string
+
+	InstallerCustomizations
+}
+
+func NewAnacondaPXETar() *AnacondaPXETar {
+	return &AnacondaPXETar{
+		Base: NewBase("pxe-tar"),
+	}
+}
+
+func (img *AnacondaPXETar) InstantiateManifest(m *manifest.Manifest,
+	repos []rpmmd.RepoConfig,
+	runner runner.Runner,
+	rng *rand.Rand) (*artifact.Artifact, error) {
+	buildPipeline := manifest.NewBuild(m, runner, repos)
+	buildPipeline.Checkpoint()
+
+	installerType := manifest.AnacondaInstallerTypePayload
+	if img.Live {
+		installerType = manifest.AnacondaInstallerTypeLive
+	}
+
+	anacondaPipeline := manifest.NewAnacondaInstaller(m,
+		installerType,
+		buildPipeline,
+		img.Platform,
+		repos,
+		"kernel",
+		img.Product,
+		img.OSVersion)
+
+	anacondaPipeline.ExtraPackages = img.ExtraBasePackages.Include
+	anacondaPipeline.ExcludePackages = img.ExtraBasePackages.Exclude
+	anacondaPipeline.ExtraRepos = img.ExtraBasePackages.Repositories
+	anacondaPipeline.Variant = img.Variant
+	anacondaPipeline.Biosdevname = (img.Platform.GetArch() == platform.ARCH_X86_64)
+	anacondaPipeline.AdditionalDracutModules = img.AdditionalDracutModules
+	anacondaPipeline.AdditionalDrivers = img.AdditionalDrivers
+	if !img.Live {
+		anacondaPipeline.Users = img.Users
+		anacondaPipeline.Groups = img.Groups
+		anacondaPipeline.AdditionalAnacondaModules = img.AdditionalAnacondaModules
+		anacondaPipeline.DisabledAnacondaModules = img.DisabledAnacondaModules
+		anacondaPipeline.HiddenSpokes = img.HiddenSpokes
+	}
+	anacondaPipeline.Checkpoint()
+
+	rootfsImagePipeline := manifest.NewISORootfsImg(buildPipeline, anacondaPipeline)
+	if img.Live {
+		rootfsImagePipeline.Size = 8 * common.GibiByte
+	} else {
+		rootfsImagePipeline.Size = 4 * common.GibiByte
+	}
+
+	var osPipeline *manifest.OS
+	if !img.Live {
+		osPipeline = manifest.NewOS(m, buildPipeline, img.Platform, repos)
+		osPipeline.OSCustomizations = img.OSCustomizations
+		osPipeline.Environment = img.Environment
+		osPipeline.Workload = img.Workload
+	}
+
+	pxeTreePipeline := manifest.NewPXETree(buildPipeline, anacondaPipeline, rootfsImagePipeline)
+	pxeTreePipeline.OSName = img.OSName
+	pxeTreePipeline.BaseURL = img.BaseURL
+	pxeTreePipeline.SquashfsCompression = img.SquashfsCompression
+	pxeTreePipeline.KernelOpts = img.AdditionalKernelOpts
+	if osPipeline != nil {
+		pxeTreePipeline.OSPipeline = osPipeline
+		pxeTreePipeline.Users = img.Users
+		pxeTreePipeline.Groups = img.Groups
+	}
+
+	tarPipeline := manifest.NewTar(buildPipeline, pxeTreePipeline, "pxe-tar")
+	tarPipeline.RootNode = osbuild.TarRootNodeOmit
+	tarPipeline.SetFilename(img.Filename)
+
+	return tarPipeline.Export(), nil
+}
@@ -46,10 +46,7 @@ type AnacondaTarInstaller struct {
 
 	Filename string
 
-	AdditionalKernelOpts      []string
-	AdditionalAnacondaModules []string
-	AdditionalDracutModules   []string
-	AdditionalDrivers         []string
+	InstallerCustomizations
 }
 
 func NewAnacondaTarInstaller() *AnacondaTarInstaller {
@@ -62,6 +59,10 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	repos []rpmmd.RepoConfig,
 	runner runner.Runner,
 	rng *rand.Rand) (*artifact.Artifact, error) {
+	if err := checkISOBoot(img.Platform); err != nil {
+		return nil, err
+	}
+
 	buildPipeline := manifest.NewBuild(m, runner, repos)
 	buildPipeline.Checkpoint()
 
@@ -84,6 +85,8 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	anacondaPipeline.AdditionalAnacondaModules = img.AdditionalAnacondaModules
 	anacondaPipeline.AdditionalDracutModules = img.AdditionalDracutModules
 	anacondaPipeline.AdditionalDrivers = img.AdditionalDrivers
+	anacondaPipeline.DisabledAnacondaModules = img.DisabledAnacondaModules
+	anacondaPipeline.HiddenSpokes = img.HiddenSpokes
 
 	tarPath := "/liveimg.tar.gz"
 
@@ -132,8 +135,7 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 	osPipeline.Environment = img.Environment
 	osPipeline.Workload = img.Workload
 
-	// enable ISOLinux on x86_64 only
-	isoLinuxEnabled := img.Platform.GetArch() == platform.ARCH_X86_64
+	isoLinuxEnabled := img.Platform.GetISOBootType() == platform.ISOBOOT_UEFI_ISOLINUX
 
 	isoTreePipeline := manifest.NewAnacondaInstallerISOTree(buildPipeline, anacondaPipeline, rootfsImagePipeline, bootTreePipeline)
 	isoTreePipeline.PartitionTable = rootfsPartitionTable
@@ -154,7 +156,7 @@ func (img *AnacondaTarInstaller) InstantiateManifest(m *manifest.Manifest,
 
 	isoPipeline := manifest.NewISO(buildPipeline, isoTreePipeline, isoLabel)
 	isoPipeline.SetFilename(img.Filename)
-	isoPipeline.ISOLinux = isoLinuxEnabled
+	isoPipeline.ISOBoot = img.Platform.GetISOBootType()
 
 	artifact := isoPipeline.Export()
 
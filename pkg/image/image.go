@@ -1,10 +1,12 @@
 package image
 
 import (
+	"fmt"
 	"math/rand"
 
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/manifest"
+	"github.com/osbuild/images/pkg/platform"
 	"github.com/osbuild/images/pkg/rpmmd"
 	"github.com/osbuild/images/pkg/runner"
 )
@@ -27,3 +29,30 @@ func NewBase(name string) Base {
 		name: name,
 	}
 }
+
+// checkISOBoot returns an error if installer ISOs cannot be booted on the
+// platform. Installer ISOs are only built for x86_64 and aarch64, see
+// platform.ISOBootType.
+func checkISOBoot(p platform.Platform) error {
+	if p.GetISOBootType() != platform.ISOBOOT_NONE {
+		return nil
+	}
+	switch p.GetArch() {
+	case platform.ARCH_X86_64, platform.ARCH_AARCH64:
+		return fmt.Errorf("installer ISOs are not supported on %s without a UEFI vendor", p.GetArch())
+	default:
+		return fmt.Errorf("installer ISOs are not supported on %s", p.GetArch())
+	}
+}
+
+// InstallerCustomizations configure the anaconda installer environment of the
+// installer image kinds. Live installers ignore the anaconda modules and the
+// hidden spokes.
+type InstallerCustomizations struct {
+	AdditionalKernelOpts      []string
+	AdditionalAnacondaModules []string
+	AdditionalDracutModules   []string
+	AdditionalDrivers         []string
+	DisabledAnacondaModules   []string
+	HiddenSpokes              []string
+}
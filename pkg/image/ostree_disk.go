@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"math/rand"
 
+	"github.com/osbuild/images/internal/firstboot"
 	"github.com/osbuild/images/internal/fsnode"
 	"github.com/osbuild/images/internal/users"
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/artifact"
+	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/disk"
 	"github.com/osbuild/images/pkg/manifest"
 	"github.com/osbuild/images/pkg/ostree"
@@ -26,7 +28,9 @@ type OSTreeDiskImage struct {
 	Users  []users.User
 	Groups []users.Group
 
-	CommitSource ostree.SourceSpec
+	// Exactly one of CommitSource or ContainerSource is set
+	CommitSource    *ostree.SourceSpec
+	ContainerSource *container.SourceSpec
 
 	SysrootReadOnly bool
 
@@ -37,6 +41,9 @@ type OSTreeDiskImage struct {
 	Keyboard            string
 	Locale              string
 
+	// Boot the deployment in FIPS mode
+	FIPS bool
+
 	Filename string
 
 	Ignition         bool
@@ -45,27 +52,48 @@ type OSTreeDiskImage struct {
 
 	Directories []*fsnode.Directory
 	Files       []*fsnode.File
+
+	FirstBoot *firstboot.Options
 }
 
 func NewOSTreeDiskImage(commit ostree.SourceSpec) *OSTreeDiskImage {
 	return &OSTreeDiskImage{
 		Base:         NewBase("ostree-raw-image"),
-		CommitSource: commit,
+		CommitSource: &commit,
+	}
+}
+
+// NewOSTreeDiskImageFromContainer creates a disk image deployed from an
+// OSTree native container image instead of an ostree commit.
+func NewOSTreeDiskImageFromContainer(container container.SourceSpec) *OSTreeDiskImage {
+	return &OSTreeDiskImage{
+		Base:            NewBase("ostree-raw-image"),
+		ContainerSource: &container,
 	}
 }
 
 func baseRawOstreeImage(img *OSTreeDiskImage, m *manifest.Manifest, buildPipeline *manifest.Build) *manifest.RawOSTreeImage {
-	osPipeline := manifest.NewOSTreeDeployment(buildPipeline, m, img.CommitSource, img.OSName, img.Ignition, img.IgnitionPlatform, img.Platform)
+	var osPipeline *manifest.OSTreeDeployment
+	switch {
+	case img.CommitSource != nil:
+		osPipeline = manifest.NewOSTreeDeployment(buildPipeline, m, *img.CommitSource, img.OSName, img.Ignition, img.IgnitionPlatform, img.Platform)
+	case img.ContainerSource != nil:
+		osPipeline = manifest.NewOSTreeContainerDeployment(buildPipeline, m, *img.ContainerSource, img.OSName, img.Ignition, img.IgnitionPlatform, img.Platform)
+	default:
+		panic("no content source defined for ostree image")
+	}
 	osPipeline.PartitionTable = img.PartitionTable
 	osPipeline.Remote = img.Remote
 	osPipeline.KernelOptionsAppend = img.KernelOptionsAppend
 	osPipeline.Keyboard = img.Keyboard
 	osPipeline.Locale = img.Locale
+	osPipeline.FIPS = img.FIPS
 	osPipeline.Users = img.Users
 	osPipeline.Groups = img.Groups
 	osPipeline.SysrootReadOnly = img.SysrootReadOnly
 	osPipeline.Directories = img.Directories
 	osPipeline.Files = img.Files
+	osPipeline.FirstBoot = img.FirstBoot
 
 	// other image types (e.g. live) pass the workload to the pipeline.
 	osPipeline.EnabledServices = img.Workload.GetServices()
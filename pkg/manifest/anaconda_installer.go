@@ -66,6 +66,13 @@ type AnacondaInstaller struct {
 	// Additional anaconda modules to enable
 	AdditionalAnacondaModules []string
 
+	// Anaconda modules to disable, including default ones
+	DisabledAnacondaModules []string
+
+	// Anaconda spokes to hide from the user, the interactive defaults are
+	// used for their settings
+	HiddenSpokes []string
+
 	// Additional dracut modules and drivers to enable
 	AdditionalDracutModules []string
 	AdditionalDrivers       []string
@@ -191,6 +198,10 @@ func (p *AnacondaInstaller) serialize() osbuild.Pipeline {
 		if p.InteractiveDefaults != nil {
 			panic("anaconda installer type payload does not support interactive defaults")
 		}
+
+		if len(p.DisabledAnacondaModules) != 0 || len(p.HiddenSpokes) != 0 {
+			panic("anaconda installer type live does not support anaconda module or spoke customization")
+		}
 	} else if p.Type == AnacondaInstallerTypePayload {
 	} else {
 		panic("invalid anaconda installer type")
@@ -268,7 +279,11 @@ func (p *AnacondaInstaller) serialize() osbuild.Pipeline {
 	}
 
 	if p.Type == AnacondaInstallerTypePayload {
-		pipeline.AddStage(osbuild.NewAnacondaStage(osbuild.NewAnacondaStageOptions(p.AdditionalAnacondaModules)))
+		pipeline.AddStage(osbuild.NewAnacondaStage(osbuild.NewAnacondaStageOptions(p.AdditionalAnacondaModules, p.DisabledAnacondaModules)))
+		if len(p.HiddenSpokes) > 0 {
+			p.Files = []*fsnode.File{anacondaHiddenSpokesConfig(p.HiddenSpokes)}
+			pipeline.AddStages(osbuild.GenFileNodesStages(p.Files)...)
+		}
 		pipeline.AddStage(osbuild.NewLoraxScriptStage(&osbuild.LoraxScriptStageOptions{
 			Path:     "99-generic/runtime-postinstall.tmpl",
 			BaseArch: p.platform.GetArch().String(),
@@ -383,6 +398,21 @@ func dracutStageOptions(kernelVer string, biosdevname bool, additionalModules []
 	}
 }
 
+// anacondaHiddenSpokesConfig returns an anaconda configuration drop-in that
+// hides the given spokes from the user interface.
+func anacondaHiddenSpokesConfig(spokes []string) *fsnode.File {
+	data := "[User Interface]\nhidden_spokes =\n"
+	for _, spoke := range spokes {
+		data += fmt.Sprintf("    %s\n", spoke)
+	}
+
+	file, err := fsnode.NewFile("/etc/anaconda/conf.d/90-osbuild.conf", nil, nil, nil, []byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return file
+}
+
 func (p *AnacondaInstaller) Platform() platform.Platform {
 	return p.platform
 }
@@ -57,6 +57,10 @@ func (p *Build) getPackageSetChain(distro Distro) []rpmmd.PackageSet {
 		packages = append(packages, pipeline.getBuildPackages(distro)...)
 	}
 
+	if p.fipsEnabled() {
+		packages = append(packages, "crypto-policies-scripts")
+	}
+
 	return []rpmmd.PackageSet{
 		{
 			Include:         packages,
@@ -98,9 +102,35 @@ func (p *Build) serialize() osbuild.Pipeline {
 	},
 	))
 
+	if p.fipsEnabled() {
+		// tools in the build root (e.g. ostree) should only use FIPS
+		// approved algorithms when producing a FIPS image
+		pipeline.AddStage(osbuild.NewUpdateCryptoPoliciesStage(&osbuild.UpdateCryptoPoliciesStageOptions{
+			Policy: "FIPS",
+		}))
+	}
+
 	return pipeline
 }
 
+// fipsEnabled returns true if any of the pipelines depending on the build
+// root produces a tree that is set up for FIPS mode.
+func (p *Build) fipsEnabled() bool {
+	for _, pipeline := range p.dependents {
+		switch dep := pipeline.(type) {
+		case *OS:
+			if dep.FIPS {
+				return true
+			}
+		case *OSTreeDeployment:
+			if dep.FIPS {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Returns a map of paths to labels for the SELinux stage based on specific
 // packages found in the pipeline.
 func (p *Build) getSELinuxLabels() map[string]string {
@@ -162,6 +162,17 @@ func (p *CoreOSInstaller) getInline() []string {
 	return inlineData
 }
 
+func (p *CoreOSInstaller) getSecrets() Secrets {
+	secrets := make(Secrets)
+	if p.FDO != nil && p.FDO.DiunPubKeyRootCerts != "" {
+		secrets[p.Name()+".fdo-diun-pub-key-root-certs"] = p.FDO.DiunPubKeyRootCerts
+	}
+	if p.Ignition != nil && p.Ignition.Config != "" {
+		secrets[p.Name()+".ignition-config"] = p.Ignition.Config
+	}
+	return secrets
+}
+
 func (p *CoreOSInstaller) serializeEnd() {
 	if len(p.packageSpecs) == 0 {
 		panic("serializeEnd() call when serialization not in progress")
@@ -1,10 +1,16 @@
 package manifest
 
 import (
+	"fmt"
+
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/platform"
 )
 
+// An EFIBootTree is the content of the El Torito EFI image of an installer
+// ISO. The tree contains the Secure Boot chain of the platform, i.e. shim as
+// the default loader, which loads the signed grub from the vendor directory,
+// and the grub configuration to boot the installer.
 type EFIBootTree struct {
 	Base
 
@@ -30,19 +36,36 @@ func NewEFIBootTree(m *Manifest, buildPipeline *Build, product, version string)
 	return p
 }
 
+// The boot loaders are copied from the build root, so the signed shim and grub
+// binaries of the platform must be installed there.
+func (p *EFIBootTree) getBuildPackages(Distro) []string {
+	switch p.Platform.GetArch() {
+	case platform.ARCH_X86_64:
+		return []string{
+			"grub2-efi-x64",
+			"grub2-efi-x64-cdboot",
+			"shim-x64",
+		}
+	case platform.ARCH_AARCH64:
+		return []string{
+			"grub2-efi-aa64",
+			"grub2-efi-aa64-cdboot",
+			"shim-aa64",
+		}
+	default:
+		return nil
+	}
+}
+
 func (p *EFIBootTree) serialize() osbuild.Pipeline {
 	pipeline := p.Base.serialize()
 
-	arch := p.Platform.GetArch().String()
-	var architectures []string
-	if arch == platform.ARCH_X86_64.String() {
-		architectures = []string{"X64"}
-	} else if arch == platform.ARCH_AARCH64.String() {
-		architectures = []string{"AA64"}
-	} else {
-		panic("unsupported architecture")
+	if p.UEFIVendor == "" {
+		panic("the EFI boot tree requires a UEFI vendor")
 	}
 
+	architectures := efiBootArchitectures(p.Platform.GetArch())
+
 	grubOptions := &osbuild.GrubISOStageOptions{
 		Product: osbuild.Product{
 			Name:    p.product,
@@ -60,3 +83,16 @@ func (p *EFIBootTree) serialize() osbuild.Pipeline {
 	pipeline.AddStage(grub2Stage)
 	return pipeline
 }
+
+// efiBootArchitectures returns the names of the EFI architectures used in the
+// file names of the default loaders, e.g. BOOTAA64.EFI
+func efiBootArchitectures(arch platform.Arch) []string {
+	switch arch {
+	case platform.ARCH_X86_64:
+		return []string{"X64"}
+	case platform.ARCH_AARCH64:
+		return []string{"AA64"}
+	default:
+		panic(fmt.Sprintf("unsupported architecture for an EFI boot tree: %s", arch))
+	}
+}
@@ -0,0 +1,60 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEFIBootTree(p platform.Platform) *EFIBootTree {
+	m := New()
+	build := NewBuild(&m, &runner.Fedora{Version: 39}, []rpmmd.RepoConfig{})
+	tree := NewEFIBootTree(&m, build, "Fedora", "39")
+	tree.Platform = p
+	tree.UEFIVendor = p.GetUEFIVendor()
+	tree.ISOLabel = "Fedora-39-BaseOS"
+	return tree
+}
+
+func TestEFIBootTreeArchitectures(t *testing.T) {
+	testCases := []struct {
+		platform platform.Platform
+		arch     string
+		shim     string
+	}{
+		{&platform.X86{UEFIVendor: "fedora"}, "X64", "shim-x64"},
+		{&platform.Aarch64{UEFIVendor: "fedora"}, "AA64", "shim-aa64"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.platform.GetArch().String(), func(t *testing.T) {
+			tree := newTestEFIBootTree(tc.platform)
+			assert.Contains(t, tree.getBuildPackages(DISTRO_FEDORA), tc.shim)
+
+			pipeline := tree.serialize()
+			require.Len(t, pipeline.Stages, 1)
+			options, ok := pipeline.Stages[0].Options.(*osbuild.GrubISOStageOptions)
+			require.True(t, ok)
+			assert.Equal(t, []string{tc.arch}, options.Architectures)
+			assert.Equal(t, "fedora", options.Vendor)
+		})
+	}
+}
+
+func TestEFIBootTreeRequiresVendor(t *testing.T) {
+	tree := newTestEFIBootTree(&platform.Aarch64{})
+	assert.PanicsWithValue(t, "the EFI boot tree requires a UEFI vendor", func() { tree.serialize() })
+}
+
+func TestEFIBootTreeUnsupportedArch(t *testing.T) {
+	tree := newTestEFIBootTree(&platform.PPC64LE{})
+	tree.UEFIVendor = "fedora"
+	assert.Empty(t, tree.getBuildPackages(DISTRO_FEDORA))
+	assert.Panics(t, func() { tree.serialize() })
+}
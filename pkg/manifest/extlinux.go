@@ -0,0 +1,126 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
+)
+
+const (
+	extlinuxConfigPath = "/boot/extlinux/extlinux.conf"
+	rpiConfigPath      = "/boot/efi/config.txt"
+)
+
+// sbcPlatform returns the single board computer the image is built for, or
+// nil if the platform boots through UEFI, BIOS or zipl.
+func (p *OS) sbcPlatform() *platform.Aarch64_SBC {
+	sbc, _ := p.platform.(*platform.Aarch64_SBC)
+	return sbc
+}
+
+// sbcBootStages returns the stages that set up U-Boot booting on single
+// board computers: the board firmware files are copied to the firmware
+// partition and the kernel is booted from an extlinux configuration instead
+// of grub2.
+func (p *OS) sbcBootStages() []*osbuild.Stage {
+	var stages []*osbuild.Stage
+
+	if bootFiles := p.platform.GetBootFiles(); len(bootFiles) > 0 {
+		options := &osbuild.CopyStageOptions{}
+		for _, file := range bootFiles {
+			options.Paths = append(options.Paths, osbuild.CopyStagePath{
+				From: fmt.Sprintf("tree://%s", file[0]),
+				To:   fmt.Sprintf("tree://%s", file[1]),
+				// firmware packages may ship their own copy
+				RemoveDestination: true,
+			})
+		}
+		stages = append(stages, osbuild.NewCopyStageSimple(options, nil))
+	}
+
+	stages = append(stages, osbuild.GenDirectoryNodesStages(sbcBootDirs())...)
+	stages = append(stages, osbuild.GenFileNodesStages(p.sbcBootFiles())...)
+
+	return stages
+}
+
+func sbcBootDirs() []*fsnode.Directory {
+	dir, err := fsnode.NewDirectory(filepath.Dir(extlinuxConfigPath), nil, nil, nil, true)
+	if err != nil {
+		panic(err)
+	}
+	return []*fsnode.Directory{dir}
+}
+
+// sbcBootFiles returns the boot configuration files for single board
+// computers: an extlinux.conf for U-Boot and, on the Raspberry Pi, the
+// config.txt of the VideoCore firmware that chain loads U-Boot.
+func (p *OS) sbcBootFiles() []*fsnode.File {
+	var files []*fsnode.File
+
+	if p.sbcPlatform().Board == platform.SBC_RPI {
+		files = append(files, newBootFile(rpiConfigPath, rpiConfig()))
+	}
+	files = append(files, newBootFile(extlinuxConfigPath, p.extlinuxConfig()))
+
+	return files
+}
+
+func newBootFile(path, data string) *fsnode.File {
+	file, err := fsnode.NewFile(path, common.ToPtr(os.FileMode(0644)), "root", "root", []byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return file
+}
+
+// rpiConfig returns the config.txt for the Raspberry Pi firmware, which
+// boots the 64-bit U-Boot copied from the platform boot files.
+func rpiConfig() string {
+	return strings.Join([]string{
+		"# Raspberry Pi firmware configuration generated by osbuild",
+		"arm_64bit=1",
+		"enable_uart=1",
+		"kernel=rpi-u-boot.bin",
+		"",
+	}, "\n")
+}
+
+// extlinuxConfig returns the extlinux.conf that U-Boot's distro boot reads to
+// boot the installed kernel. Paths are relative to the filesystem that holds
+// /boot, which is the root filesystem if there is no separate /boot.
+func (p *OS) extlinuxConfig() string {
+	pt := p.PartitionTable
+
+	prefix := "/boot"
+	if pt.FindMountable("/boot") != nil {
+		prefix = ""
+	}
+
+	kernelOptions := []string{"root=UUID=" + pt.FindMountable("/").GetFSSpec().UUID}
+	kernelOptions = append(kernelOptions, p.imageKernelOptions()...)
+
+	label := fmt.Sprintf("%s (%s)", p.OSProduct, p.kernelVer)
+	if p.OSProduct == "" {
+		label = p.kernelVer
+	}
+
+	return strings.Join([]string{
+		"# extlinux configuration generated by osbuild",
+		"timeout 20",
+		fmt.Sprintf("default %s", label),
+		"",
+		fmt.Sprintf("label %s", label),
+		fmt.Sprintf("\tkernel %s/vmlinuz-%s", prefix, p.kernelVer),
+		fmt.Sprintf("\tinitrd %s/initramfs-%s.img", prefix, p.kernelVer),
+		fmt.Sprintf("\tfdtdir %s/dtb-%s", prefix, p.kernelVer),
+		fmt.Sprintf("\tappend %s", strings.Join(kernelOptions, " ")),
+		"",
+	}, "\n")
+}
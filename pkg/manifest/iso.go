@@ -1,15 +1,19 @@
 package manifest
 
 import (
+	"fmt"
+
 	"github.com/osbuild/images/pkg/artifact"
 	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
 )
 
 // An ISO represents a bootable ISO file created from an
 // an existing ISOTreePipeline.
 type ISO struct {
 	Base
-	ISOLinux bool
+	// The El Torito boot records of the ISO, see platform.ISOBootType
+	ISOBoot  platform.ISOBootType
 	filename string
 
 	treePipeline Pipeline
@@ -46,28 +50,37 @@ func (p *ISO) getBuildPackages(Distro) []string {
 func (p *ISO) serialize() osbuild.Pipeline {
 	pipeline := p.Base.serialize()
 
-	pipeline.AddStage(osbuild.NewXorrisofsStage(xorrisofsStageOptions(p.Filename(), p.isoLabel, p.ISOLinux), p.treePipeline.Name()))
+	pipeline.AddStage(osbuild.NewXorrisofsStage(xorrisofsStageOptions(p.Filename(), p.isoLabel, p.ISOBoot), p.treePipeline.Name()))
 	pipeline.AddStage(osbuild.NewImplantisomd5Stage(&osbuild.Implantisomd5StageOptions{Filename: p.Filename()}))
 
 	return pipeline
 }
 
-func xorrisofsStageOptions(filename, isolabel string, isolinux bool) *osbuild.XorrisofsStageOptions {
+func xorrisofsStageOptions(filename, isolabel string, isoBoot platform.ISOBootType) *osbuild.XorrisofsStageOptions {
 	options := &osbuild.XorrisofsStageOptions{
 		Filename: filename,
 		VolID:    isolabel,
 		SysID:    "LINUX",
-		EFI:      "images/efiboot.img",
 		ISOLevel: 3,
 	}
 
-	if isolinux {
+	switch isoBoot {
+	case platform.ISOBOOT_UEFI:
+		// the EFI image is the only El Torito boot record, there is no
+		// BIOS boot image and no MBR
+		options.EFI = "images/efiboot.img"
+	case platform.ISOBOOT_UEFI_ISOLINUX:
+		// isolinux is the default boot record for BIOS, the EFI image
+		// the alternative one, and the isohybrid MBR makes the ISO
+		// bootable from USB sticks
 		options.Boot = &osbuild.XorrisofsBoot{
 			Image:   "isolinux/isolinux.bin",
 			Catalog: "isolinux/boot.cat",
 		}
-
+		options.EFI = "images/efiboot.img"
 		options.IsohybridMBR = "/usr/share/syslinux/isohdpfx.bin"
+	default:
+		panic(fmt.Sprintf("unsupported ISO boot type %q", isoBoot))
 	}
 
 	return options
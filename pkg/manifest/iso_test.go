@@ -0,0 +1,51 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/platform"
+)
+
+func TestXorrisofsStageOptions(t *testing.T) {
+	testCases := []struct {
+		isoBoot  platform.ISOBootType
+		expected *osbuild.XorrisofsStageOptions
+	}{
+		{
+			isoBoot: platform.ISOBOOT_UEFI,
+			expected: &osbuild.XorrisofsStageOptions{
+				Filename: "image.iso",
+				VolID:    "Fedora-39-BaseOS",
+				SysID:    "LINUX",
+				EFI:      "images/efiboot.img",
+				ISOLevel: 3,
+			},
+		},
+		{
+			isoBoot: platform.ISOBOOT_UEFI_ISOLINUX,
+			expected: &osbuild.XorrisofsStageOptions{
+				Filename: "image.iso",
+				VolID:    "Fedora-39-BaseOS",
+				SysID:    "LINUX",
+				Boot: &osbuild.XorrisofsBoot{
+					Image:   "isolinux/isolinux.bin",
+					Catalog: "isolinux/boot.cat",
+				},
+				EFI:          "images/efiboot.img",
+				IsohybridMBR: "/usr/share/syslinux/isohdpfx.bin",
+				ISOLevel:     3,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.isoBoot.String(), func(t *testing.T) {
+			assert.Equal(t, tc.expected, xorrisofsStageOptions("image.iso", "Fedora-39-BaseOS", tc.isoBoot))
+		})
+	}
+
+	assert.Panics(t, func() { xorrisofsStageOptions("image.iso", "Fedora-39-BaseOS", platform.ISOBOOT_NONE) })
+}
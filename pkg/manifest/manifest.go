@@ -38,6 +38,7 @@ const (
 	DISTRO_EL8
 	DISTRO_EL7
 	DISTRO_FEDORA
+	DISTRO_EL10
 )
 
 // An OSBuildManifest is an opaque JSON object, which is a valid input to osbuild
@@ -130,37 +131,72 @@ func (m Manifest) GetOSTreeSourceSpecs() map[string][]ostree.SourceSpec {
 }
 
 func (m Manifest) Serialize(packageSets map[string][]rpmmd.PackageSpec, containerSpecs map[string][]container.Spec, ostreeCommits map[string][]ostree.CommitSpec) (OSBuildManifest, error) {
+	manifest, _, err := m.serialize(packageSets, containerSpecs, ostreeCommits)
+	return manifest, err
+}
+
+// SerializeWithSecrets serializes the manifest like Serialize, but the
+// secrets are replaced with references to their IDs and returned separately.
+// The manifest can be stored or logged and needs to be materialized with
+// MaterializeSecrets before it's built.
+func (m Manifest) SerializeWithSecrets(packageSets map[string][]rpmmd.PackageSpec, containerSpecs map[string][]container.Spec, ostreeCommits map[string][]ostree.CommitSpec) (OSBuildManifest, Secrets, error) {
+	manifest, secrets, err := m.serialize(packageSets, containerSpecs, ostreeCommits)
+	if err != nil {
+		return nil, nil, err
+	}
+	redacted, err := RedactSecrets(manifest, secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	return redacted, secrets, nil
+}
+
+func (m Manifest) serialize(packageSets map[string][]rpmmd.PackageSpec, containerSpecs map[string][]container.Spec, ostreeCommits map[string][]ostree.CommitSpec) (OSBuildManifest, Secrets, error) {
 	pipelines := make([]osbuild.Pipeline, 0)
 	packages := make([]rpmmd.PackageSpec, 0)
 	commits := make([]ostree.CommitSpec, 0)
 	inline := make([]string, 0)
+	remoteFiles := make([]osbuild.RemoteFile, 0)
 	containers := make([]container.Spec, 0)
+	secrets := make(Secrets)
 	for _, pipeline := range m.pipelines {
 		pipeline.serializeStart(packageSets[pipeline.Name()], containerSpecs[pipeline.Name()], ostreeCommits[pipeline.Name()])
 	}
 	for _, pipeline := range m.pipelines {
 		commits = append(commits, pipeline.getOSTreeCommits()...)
-		pipelines = append(pipelines, pipeline.serialize())
+		serialized := pipeline.serialize()
+		pipelines = append(pipelines, serialized)
 		packages = append(packages, packageSets[pipeline.Name()]...)
 		inline = append(inline, pipeline.getInline()...)
+		remoteFiles = append(remoteFiles, pipeline.getRemoteFiles()...)
 		containers = append(containers, pipeline.getContainerSpecs()...)
+		for id, value := range pipeline.getSecrets() {
+			secrets[id] = value
+		}
+		for id, value := range stageSecrets(serialized) {
+			secrets[id] = value
+		}
 	}
 	for _, pipeline := range m.pipelines {
 		pipeline.serializeEnd()
 	}
 
-	sources, err := osbuild.GenSources(packages, commits, inline, containers)
+	sources, err := osbuild.GenSources(packages, commits, inline, containers, remoteFiles)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return json.Marshal(
+	manifest, err := json.Marshal(
 		osbuild.Manifest{
 			Version:   "2",
 			Pipelines: pipelines,
 			Sources:   sources,
 		},
 	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, secrets, nil
 }
 
 func (m Manifest) GetCheckpoints() []string {
@@ -2,17 +2,21 @@ package manifest
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/osbuild/images/internal/common"
 	"github.com/osbuild/images/internal/environment"
+	"github.com/osbuild/images/internal/firstboot"
 	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/selinuxpolicy"
 	"github.com/osbuild/images/internal/shell"
 	"github.com/osbuild/images/internal/users"
 	"github.com/osbuild/images/internal/workload"
 	"github.com/osbuild/images/pkg/container"
 	"github.com/osbuild/images/pkg/disk"
+	"github.com/osbuild/images/pkg/flatpak"
 	"github.com/osbuild/images/pkg/osbuild"
 	"github.com/osbuild/images/pkg/ostree"
 	"github.com/osbuild/images/pkg/platform"
@@ -47,6 +51,11 @@ type OSCustomizations struct {
 	// TODO: move to workload
 	Containers []container.SourceSpec
 
+	// Flatpaks to install into the system installation of the image
+	// (source specification). The flatpaks are installed in /var, so they
+	// can't be embedded in ostree commits.
+	Flatpaks []flatpak.SourceSpec
+
 	// KernelName indicates that a kernel is installed, and names the kernel
 	// package.
 	KernelName string
@@ -93,16 +102,23 @@ type OSCustomizations struct {
 	ShellInit []shell.InitFile
 
 	// TODO: drop osbuild types from the API
-	Firewall             *osbuild.FirewallStageOptions
-	Grub2Config          *osbuild.GRUB2Config
-	Sysconfig            []*osbuild.SysconfigStageOptions
-	SystemdLogind        []*osbuild.SystemdLogindStageOptions
-	CloudInit            []*osbuild.CloudInitStageOptions
-	Modprobe             []*osbuild.ModprobeStageOptions
-	DracutConf           []*osbuild.DracutConfStageOptions
-	SystemdUnit          []*osbuild.SystemdUnitStageOptions
-	Authselect           *osbuild.AuthselectStageOptions
-	SELinuxConfig        *osbuild.SELinuxConfigStageOptions
+	Firewall        *osbuild.FirewallStageOptions
+	Grub2Config     *osbuild.GRUB2Config
+	Sysconfig       []*osbuild.SysconfigStageOptions
+	SystemdLogind   []*osbuild.SystemdLogindStageOptions
+	SystemdJournald []*osbuild.SystemdJournaldStageOptions
+	CloudInit       []*osbuild.CloudInitStageOptions
+	Modprobe        []*osbuild.ModprobeStageOptions
+	DracutConf      []*osbuild.DracutConfStageOptions
+	// Recreate the initramfs after the DracutConf files are written, which
+	// otherwise only apply to kernels installed later on
+	RegenerateInitramfs bool
+	SystemdUnit         []*osbuild.SystemdUnitStageOptions
+	Authselect          *osbuild.AuthselectStageOptions
+	SELinuxConfig       *osbuild.SELinuxConfigStageOptions
+	// Changes to the local SELinux policy, applied before the tree is
+	// labelled
+	SELinuxPolicy        *selinuxpolicy.Options
 	Tuned                *osbuild.TunedStageOptions
 	Tmpfilesd            []*osbuild.TmpfilesdStageOptions
 	PamLimitsConf        []*osbuild.PamLimitsConfStageOptions
@@ -131,8 +147,29 @@ type OSCustomizations struct {
 	// Custom directories and files to create in the image
 	Directories []*fsnode.Directory
 	Files       []*fsnode.File
+
+	// Files to download at build time and copy into the image
+	RemoteFiles []osbuild.RemoteFile
+
+	// Scripts to run once on the first boot of the image
+	FirstBoot *firstboot.Options
+
+	// Seal the boot image for IBM Secure Execution (s390x only)
+	SecureExecution *platform.SecureExecutionOptions
+
+	// Set up the image for FIPS mode: the FIPS crypto policy, the fips
+	// dracut module and the kernel command line options
+	FIPS bool
 }
 
+const (
+	// Location of the IBM Secure Execution boot image and of the host key
+	// documents it was created for, which are kept in the image so the boot
+	// image can be recreated on kernel updates
+	secureExecutionImagePath   = "/boot/secure-image"
+	secureExecutionHostKeysDir = "/etc/se-hostkeys"
+)
+
 // OS represents the filesystem tree of the target image. This roughly
 // corresponds to the root filesystem once an instance of the image is running.
 type OS struct {
@@ -156,6 +193,7 @@ type OS struct {
 	packageSpecs     []rpmmd.PackageSpec
 	containerSpecs   []container.Spec
 	ostreeParentSpec *ostree.CommitSpec
+	flatpakSpecs     []flatpak.Spec
 
 	platform  platform.Platform
 	kernelVer string
@@ -192,7 +230,7 @@ func NewOS(m *Manifest,
 	return p
 }
 
-func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
+func (p *OS) getPackageSetChain(distro Distro) []rpmmd.PackageSet {
 	packages := p.platform.GetPackages()
 
 	if p.KernelName != "" {
@@ -222,6 +260,28 @@ func (p *OS) getPackageSetChain(Distro) []rpmmd.PackageSet {
 		packages = append(packages, "openscap-scanner", "scap-security-guide")
 	}
 
+	// the policy is changed by running semanage in the tree
+	if p.SELinuxPolicy != nil {
+		switch distro {
+		case DISTRO_EL7:
+			packages = append(packages, "policycoreutils-python")
+		default:
+			packages = append(packages, "policycoreutils-python-utils")
+		}
+	}
+
+	if len(p.Flatpaks) > 0 {
+		packages = append(packages, "flatpak")
+	}
+
+	if p.FIPS {
+		packages = append(packages, "crypto-policies-scripts")
+		if distro == DISTRO_EL8 {
+			// the fips dracut module is part of dracut on el9+
+			packages = append(packages, "dracut-fips")
+		}
+	}
+
 	// Make sure the right packages are included for subscriptions
 	// rhc always uses insights, and depends on subscription-manager
 	// non-rhc uses subscription-manager and optionally includes Insights
@@ -302,24 +362,35 @@ func (p *OS) getBuildPackages(distro Distro) []string {
 		packages = append(packages, "openscap-utils")
 	}
 
+	if len(p.Flatpaks) > 0 {
+		packages = append(packages, "flatpak")
+	}
+
 	return packages
 }
 
+// getOSTreeCommitSources returns the parent commit, if any, followed by the
+// commits of the flatpaks in order.
 func (p *OS) getOSTreeCommitSources() []ostree.SourceSpec {
-	if p.OSTreeParent == nil {
-		return nil
+	var sources []ostree.SourceSpec
+	if p.OSTreeParent != nil {
+		sources = append(sources, *p.OSTreeParent)
 	}
-
-	return []ostree.SourceSpec{
-		*p.OSTreeParent,
+	for _, fp := range p.Flatpaks {
+		sources = append(sources, fp.OSTreeSourceSpec())
 	}
+	return sources
 }
 
 func (p *OS) getOSTreeCommits() []ostree.CommitSpec {
-	if p.ostreeParentSpec == nil {
-		return nil
+	var commits []ostree.CommitSpec
+	if p.ostreeParentSpec != nil {
+		commits = append(commits, *p.ostreeParentSpec)
+	}
+	for _, fp := range p.flatpakSpecs {
+		commits = append(commits, fp.CommitSpec())
 	}
-	return []ostree.CommitSpec{*p.ostreeParentSpec}
+	return commits
 }
 
 func (p *OS) getPackageSpecs() []rpmmd.PackageSpec {
@@ -337,11 +408,15 @@ func (p *OS) serializeStart(packages []rpmmd.PackageSpec, containers []container
 
 	p.packageSpecs = packages
 	p.containerSpecs = containers
-	if len(commits) > 0 {
-		if len(commits) > 1 {
-			panic("pipeline supports at most one ostree commit")
-		}
+	if len(commits) != len(p.getOSTreeCommitSources()) {
+		panic(fmt.Sprintf("pipeline expects %d ostree commits, got %d", len(p.getOSTreeCommitSources()), len(commits)))
+	}
+	if p.OSTreeParent != nil {
 		p.ostreeParentSpec = &commits[0]
+		commits = commits[1:]
+	}
+	for idx, fp := range p.Flatpaks {
+		p.flatpakSpecs = append(p.flatpakSpecs, flatpak.NewSpec(fp, commits[idx]))
 	}
 
 	if p.KernelName != "" {
@@ -357,6 +432,7 @@ func (p *OS) serializeEnd() {
 	p.packageSpecs = nil
 	p.containerSpecs = nil
 	p.ostreeParentSpec = nil
+	p.flatpakSpecs = nil
 }
 
 func (p *OS) serialize() osbuild.Pipeline {
@@ -420,6 +496,13 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(skopeo)
 	}
 
+	if len(p.flatpakSpecs) > 0 {
+		if p.OSTreeRef != "" {
+			panic("flatpaks are not supported in ostree commits")
+		}
+		pipeline.AddStages(osbuild.GenFlatpakStages(p.flatpakSpecs)...)
+	}
+
 	pipeline.AddStage(osbuild.NewLocaleStage(&osbuild.LocaleStageOptions{Language: p.Language}))
 
 	if p.Keyboard != nil {
@@ -481,6 +564,10 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewSystemdLogindStage(systemdLogindConfig))
 	}
 
+	for _, systemdJournaldConfig := range p.SystemdJournald {
+		pipeline.AddStage(osbuild.NewSystemdJournaldStage(systemdJournaldConfig))
+	}
+
 	for _, cloudInitConfig := range p.CloudInit {
 		pipeline.AddStage(osbuild.NewCloudInitStage(cloudInitConfig))
 	}
@@ -493,6 +580,21 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewDracutConfStage(dracutConfConfig))
 	}
 
+	if p.FIPS {
+		// the initramfs of ostree commits is created when the tree is
+		// prepared for the commit
+		kernelVer := p.kernelVer
+		if p.OSTreeRef != "" {
+			kernelVer = ""
+		}
+		pipeline.AddStages(osbuild.GenFIPSStages(kernelVer)...)
+	} else if p.RegenerateInitramfs && p.kernelVer != "" && p.OSTreeRef == "" {
+		// FIPS mode recreates the initramfs already
+		pipeline.AddStage(osbuild.NewDracutStage(&osbuild.DracutStageOptions{
+			Kernel: []string{p.kernelVer},
+		}))
+	}
+
 	for _, systemdUnitConfig := range p.SystemdUnit {
 		pipeline.AddStage(osbuild.NewSystemdUnitStage(systemdUnitConfig))
 	}
@@ -553,35 +655,17 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewPwqualityConfStage(p.PwQuality))
 	}
 
-	// If subscription settings are included there are 3 possible setups:
-	// - Register the system with rhc and enable Insights
-	// - Register with subscription-manager, no Insights or rhc
-	// - Register with subscription-manager and enable Insights, no rhc
+	// The secrets and the script registering the system are added with the
+	// other files, the script runs at first boot unless the system is
+	// registered while building the image
 	if p.Subscription != nil {
-		var commands []string
-		if p.Subscription.Rhc {
-			// Use rhc for registration instead of subscription manager
-			commands = []string{fmt.Sprintf("/usr/bin/rhc connect -o=%s -a=%s --server %s", p.Subscription.Organization, p.Subscription.ActivationKey, p.Subscription.ServerUrl)}
-			// insights-client creates the .gnupg directory during boot process, and is labeled incorrectly
-			commands = append(commands, "restorecon -R /root/.gnupg")
-			// execute the rhc post install script as the selinuxenabled check doesn't work in the buildroot container
-			commands = append(commands, "/usr/sbin/semanage permissive --add rhcd_t")
-		} else {
-			commands = []string{fmt.Sprintf("/usr/sbin/subscription-manager register --org=%s --activationkey=%s --serverurl %s --baseurl %s", p.Subscription.Organization, p.Subscription.ActivationKey, p.Subscription.ServerUrl, p.Subscription.BaseUrl)}
-
-			// Insights is optional when using subscription-manager
-			if p.Subscription.Insights {
-				commands = append(commands, "/usr/bin/insights-client --register")
-				// insights-client creates the .gnupg directory during boot process, and is labeled incorrectly
-				commands = append(commands, "restorecon -R /root/.gnupg")
-			}
+		if p.Subscription.RegisterAt != subscription.RegisterAtBuild {
+			pipeline.AddStage(osbuild.NewFirstBootStage(&osbuild.FirstBootStageOptions{
+				Commands:       []string{subscription.RegisterScriptPath},
+				WaitForNetwork: true,
+			}))
 		}
 
-		pipeline.AddStage(osbuild.NewFirstBootStage(&osbuild.FirstBootStageOptions{
-			Commands:       commands,
-			WaitForNetwork: true,
-		}))
-
 		if rhsmConfig, exists := p.RHSMConfig[subscription.RHSMConfigWithSubscription]; exists {
 			pipeline.AddStage(osbuild.NewRHSMStage(rhsmConfig))
 		}
@@ -600,8 +684,7 @@ func (p *OS) serialize() osbuild.Pipeline {
 	}
 
 	if pt := p.PartitionTable; pt != nil {
-		kernelOptions := osbuild.GenImageKernelOptions(p.PartitionTable)
-		kernelOptions = append(kernelOptions, p.KernelOptionsAppend...)
+		kernelOptions := p.imageKernelOptions()
 		if !p.KernelOptionsBootloader {
 			pipeline = prependKernelCmdlineStage(pipeline, strings.Join(kernelOptions, " "), pt)
 		}
@@ -609,9 +692,11 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStage(osbuild.NewFSTabStage(osbuild.NewFSTabStageOptions(pt)))
 
 		var bootloader *osbuild.Stage
-		switch p.platform.GetArch() {
-		case platform.ARCH_S390X:
+		switch {
+		case p.platform.GetArch() == platform.ARCH_S390X:
 			bootloader = osbuild.NewZiplStage(new(osbuild.ZiplStageOptions))
+		case p.sbcPlatform() != nil:
+			// U-Boot reads the extlinux configuration, grub2 is not used
 		default:
 			if p.NoBLS {
 				// BLS entries not supported: use grub2.legacy
@@ -661,7 +746,35 @@ func (p *OS) serialize() osbuild.Pipeline {
 			}
 		}
 
-		pipeline.AddStage(bootloader)
+		if bootloader != nil {
+			pipeline.AddStage(bootloader)
+		} else {
+			pipeline.AddStages(p.sbcBootStages()...)
+		}
+
+		if p.SecureExecution != nil {
+			if p.platform.GetArch() != platform.ARCH_S390X {
+				panic("secure execution is only supported on s390x")
+			}
+			hostKeys := p.secureExecutionHostKeys()
+			hostKeyPaths := make([]string, len(hostKeys))
+			for idx, hostKey := range hostKeys {
+				hostKeyPaths[idx] = hostKey.Path()
+			}
+			pipeline.AddStages(osbuild.GenDirectoryNodesStages(secureExecutionHostKeysDirs())...)
+			pipeline.AddStages(osbuild.GenFileNodesStages(hostKeys)...)
+			pipeline.AddStage(osbuild.NewGenprotimgStage(&osbuild.GenprotimgStageOptions{
+				Kernel:   fmt.Sprintf("/boot/vmlinuz-%s", p.kernelVer),
+				Initrd:   fmt.Sprintf("/boot/initramfs-%s.img", p.kernelVer),
+				Cmdline:  strings.Join(append([]string{"root=UUID=" + pt.FindMountable("/").GetFSSpec().UUID}, kernelOptions...), " "),
+				HostKeys: hostKeyPaths,
+				Output:   secureExecutionImagePath,
+				// the host key documents are provided with the image
+				// options and can't be verified without the IBM signing
+				// keys and revocation lists
+				NoVerify: true,
+			}))
+		}
 	}
 
 	if p.FactAPIType != nil {
@@ -693,9 +806,36 @@ func (p *OS) serialize() osbuild.Pipeline {
 		pipeline.AddStages(osbuild.GenFileNodesStages(p.Files)...)
 	}
 
+	if len(p.RemoteFiles) > 0 {
+		pipeline.AddStages(osbuild.GenRemoteFilesStages(p.RemoteFiles)...)
+	}
+
+	if len(p.flatpakSpecs) > 0 {
+		pipeline.AddStages(osbuild.GenFileNodesStages(flatpak.InstallFiles(p.flatpakSpecs))...)
+	}
+
+	if p.FirstBoot != nil {
+		pipeline.AddStages(osbuild.GenDirectoryNodesStages(p.FirstBoot.Directories())...)
+		pipeline.AddStages(osbuild.GenFileNodesStages(p.FirstBoot.Files())...)
+	}
+
+	if p.Subscription != nil {
+		pipeline.AddStages(osbuild.GenDirectoryNodesStages(p.Subscription.Directories())...)
+		pipeline.AddStages(osbuild.GenFileNodesStages(p.Subscription.Files())...)
+		if p.Subscription.RegisterAt == subscription.RegisterAtBuild {
+			pipeline.AddStage(osbuild.NewScriptStage(osbuild.NewScriptStageOptions(subscription.RegisterScriptPath)))
+		}
+	}
+
 	enabledServices := []string{}
 	disabledServices := []string{}
 	enabledServices = append(enabledServices, p.EnabledServices...)
+	if p.FirstBoot != nil {
+		enabledServices = append(enabledServices, firstboot.ServiceName)
+	}
+	if len(p.flatpakSpecs) > 0 {
+		enabledServices = append(enabledServices, flatpak.ServiceName)
+	}
 	disabledServices = append(disabledServices, p.DisabledServices...)
 	if p.Environment != nil {
 		enabledServices = append(enabledServices, p.Environment.GetServices()...)
@@ -742,6 +882,13 @@ func (p *OS) serialize() osbuild.Pipeline {
 		}))
 	}
 
+	// policy modules and file context rules need to be in place when the
+	// tree is labelled
+	if p.SELinuxPolicy != nil {
+		pipeline.AddStages(osbuild.GenFileNodesStages(p.SELinuxPolicy.Files())...)
+		pipeline.AddStage(osbuild.NewScriptStage(osbuild.NewScriptStageOptions(selinuxpolicy.ScriptPath)))
+	}
+
 	if p.SElinux != "" {
 		pipeline.AddStage(osbuild.NewSELinuxStage(&osbuild.SELinuxStageOptions{
 			FileContexts:     fmt.Sprintf("etc/selinux/%s/contexts/files/file_contexts", p.SElinux),
@@ -761,6 +908,16 @@ func (p *OS) serialize() osbuild.Pipeline {
 	return pipeline
 }
 
+// imageKernelOptions returns the kernel command line options of a bootable
+// image, without the root filesystem.
+func (p *OS) imageKernelOptions() []string {
+	kernelOptions := osbuild.GenImageKernelOptions(p.PartitionTable)
+	if p.FIPS {
+		kernelOptions = append(kernelOptions, osbuild.GenFIPSKernelOptions(p.PartitionTable)...)
+	}
+	return append(kernelOptions, p.KernelOptionsAppend...)
+}
+
 func prependKernelCmdlineStage(pipeline osbuild.Pipeline, kernelOptions string, pt *disk.PartitionTable) osbuild.Pipeline {
 	rootFs := pt.FindMountable("/")
 	if rootFs == nil {
@@ -807,6 +964,29 @@ func usersFirstBootOptions(users []users.User) *osbuild.FirstBootStageOptions {
 	return options
 }
 
+// secureExecutionHostKeys returns the host key documents for IBM Secure
+// Execution as files in the image
+func (p *OS) secureExecutionHostKeys() []*fsnode.File {
+	var files []*fsnode.File
+	for idx, hostKey := range p.SecureExecution.HostKeys {
+		path := filepath.Join(secureExecutionHostKeysDir, fmt.Sprintf("hostkey-%d.crt", idx+1))
+		file, err := fsnode.NewFile(path, common.ToPtr(os.FileMode(0644)), "root", "root", []byte(hostKey))
+		if err != nil {
+			panic(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func secureExecutionHostKeysDirs() []*fsnode.Directory {
+	dir, err := fsnode.NewDirectory(secureExecutionHostKeysDir, nil, nil, nil, true)
+	if err != nil {
+		panic(err)
+	}
+	return []*fsnode.Directory{dir}
+}
+
 func (p *OS) Platform() platform.Platform {
 	return p.platform
 }
@@ -819,5 +999,54 @@ func (p *OS) getInline() []string {
 		inlineData = append(inlineData, string(file.Data()))
 	}
 
+	if p.FirstBoot != nil {
+		for _, file := range p.FirstBoot.Files() {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
+	if p.Subscription != nil {
+		for _, file := range p.Subscription.Files() {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
+	if p.SELinuxPolicy != nil {
+		for _, file := range p.SELinuxPolicy.Files() {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
+	if len(p.flatpakSpecs) > 0 {
+		for _, file := range flatpak.InstallFiles(p.flatpakSpecs) {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
+	if p.PartitionTable != nil && p.sbcPlatform() != nil {
+		for _, file := range p.sbcBootFiles() {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
+	if p.SecureExecution != nil {
+		for _, file := range p.secureExecutionHostKeys() {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
 	return inlineData
 }
+
+func (p *OS) getSecrets() Secrets {
+	if p.Subscription == nil {
+		return nil
+	}
+	return Secrets{
+		p.Name() + ".subscription-activation-key": p.Subscription.ActivationKey,
+	}
+}
+
+func (p *OS) getRemoteFiles() []osbuild.RemoteFile {
+	return p.RemoteFiles
+}
@@ -1,6 +1,8 @@
 package manifest
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/osbuild/images/pkg/osbuild"
@@ -67,6 +69,30 @@ func CheckPkgSetInclude(t *testing.T, pkgSetChain []rpmmd.PackageSet, pkgs []str
 	}
 }
 
+// CheckInlineContains checks that each string is part of an inline file
+func CheckInlineContains(t *testing.T, inline []string, contents []string) {
+	for _, content := range contents {
+		found := false
+		for _, data := range inline {
+			if strings.Contains(data, content) {
+				found = true
+				break
+			}
+		}
+		assert.True(t, found, "%q not found in inline data", content)
+	}
+}
+
+// findStage returns the first stage of the given type
+func findStage(stageType string, stages []*osbuild.Stage) *osbuild.Stage {
+	for _, s := range stages {
+		if s.Type == stageType {
+			return s
+		}
+	}
+	return nil
+}
+
 func TestSubscriptionManagerCommands(t *testing.T) {
 	os := NewTestOS()
 	os.Subscription = &subscription.ImageOptions{
@@ -77,8 +103,13 @@ func TestSubscriptionManagerCommands(t *testing.T) {
 	}
 	pipeline := os.serialize()
 	CheckFirstBootStageOptions(t, pipeline.Stages, []string{
-		"/usr/sbin/subscription-manager register --org=2040324 --activationkey=my-secret-key --serverurl subscription.rhsm.redhat.com --baseurl http://cdn.redhat.com/",
+		"/usr/local/sbin/osbuild-subscription-register",
+	})
+	CheckInlineContains(t, os.getInline(), []string{
+		"ORGANIZATION='2040324'\n",
+		"my-secret-key",
 	})
+	assert.Nil(t, findStage("org.osbuild.script", pipeline.Stages))
 }
 
 func TestSubscriptionManagerInsightsCommands(t *testing.T) {
@@ -92,10 +123,13 @@ func TestSubscriptionManagerInsightsCommands(t *testing.T) {
 	}
 	pipeline := os.serialize()
 	CheckFirstBootStageOptions(t, pipeline.Stages, []string{
-		"/usr/sbin/subscription-manager register --org=2040324 --activationkey=my-secret-key --serverurl subscription.rhsm.redhat.com --baseurl http://cdn.redhat.com/",
-		"/usr/bin/insights-client --register",
-		"restorecon -R /root/.gnupg",
+		"/usr/local/sbin/osbuild-subscription-register",
 	})
+	CheckInlineContains(t, os.getInline(), []string{
+		`/usr/sbin/subscription-manager register --org="$ORGANIZATION" --activationkey="$ACTIVATION_KEY" --serverurl 'subscription.rhsm.redhat.com' --baseurl 'http://cdn.redhat.com/'`,
+		"/usr/bin/insights-client --register\nrestorecon -R /root/.gnupg\n",
+	})
+	assert.Nil(t, findStage("org.osbuild.script", pipeline.Stages))
 }
 
 func TestRhcInsightsCommands(t *testing.T) {
@@ -110,10 +144,36 @@ func TestRhcInsightsCommands(t *testing.T) {
 	}
 	pipeline := os.serialize()
 	CheckFirstBootStageOptions(t, pipeline.Stages, []string{
-		"/usr/bin/rhc connect -o=2040324 -a=my-secret-key --server subscription.rhsm.redhat.com",
-		"restorecon -R /root/.gnupg",
-		"/usr/sbin/semanage permissive --add rhcd_t",
+		"/usr/local/sbin/osbuild-subscription-register",
+	})
+	CheckInlineContains(t, os.getInline(), []string{
+		`/usr/bin/rhc connect -o="$ORGANIZATION" -a="$ACTIVATION_KEY" --server 'subscription.rhsm.redhat.com'` + "\nrestorecon -R /root/.gnupg\n/usr/sbin/semanage permissive --add rhcd_t\n",
 	})
+	assert.Nil(t, findStage("org.osbuild.script", pipeline.Stages))
+}
+
+func TestSubscriptionManagerBuildTimeRegistration(t *testing.T) {
+	os := NewTestOS()
+	os.Subscription = &subscription.ImageOptions{
+		Organization:  "2040324",
+		ActivationKey: "my-secret-key",
+		ServerUrl:     "subscription.rhsm.redhat.com",
+		BaseUrl:       "http://cdn.redhat.com/",
+		RegisterAt:    subscription.RegisterAtBuild,
+	}
+	pipeline := os.serialize()
+	assert.Nil(t, findStage("org.osbuild.first-boot", pipeline.Stages))
+
+	scriptStage := findStage("org.osbuild.script", pipeline.Stages)
+	require.NotNil(t, scriptStage)
+	assert.Equal(t, &osbuild.ScriptStageOptions{Script: "/usr/local/sbin/osbuild-subscription-register"}, scriptStage.Options)
+
+	// the secrets are only part of the inline sources
+	for _, stage := range pipeline.Stages {
+		data, err := json.Marshal(stage)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "my-secret-key")
+	}
 }
 
 func TestSubscriptionManagerPackages(t *testing.T) {
@@ -152,3 +212,28 @@ func TestRhcInsightsPackages(t *testing.T) {
 	}
 	CheckPkgSetInclude(t, os.getPackageSetChain(DISTRO_NULL), []string{"rhc", "subscription-manager", "insights-client"})
 }
+
+func TestRemoteFilesCopyStage(t *testing.T) {
+	os := NewTestOS()
+	os.RemoteFiles = []osbuild.RemoteFile{
+		{
+			URL:      "https://example.com/tailoring.xml",
+			Checksum: "sha256:4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3",
+			Path:     "/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+		},
+	}
+	pipeline := os.serialize()
+
+	var copyStage *osbuild.Stage
+	for _, s := range pipeline.Stages {
+		if s.Type == "org.osbuild.copy" {
+			copyStage = s
+		}
+	}
+	require.NotNil(t, copyStage)
+	options, ok := copyStage.Options.(*osbuild.CopyStageOptions)
+	require.True(t, ok)
+	require.Len(t, options.Paths, 1)
+	assert.Equal(t, "tree:///usr/share/xml/osbuild-openscap-data/tailoring.xml", options.Paths[0].To)
+	assert.Equal(t, os.RemoteFiles, os.getRemoteFiles())
+}
@@ -1,10 +1,12 @@
 package manifest
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
 	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/firstboot"
 	"github.com/osbuild/images/internal/fsnode"
 	"github.com/osbuild/images/internal/users"
 	"github.com/osbuild/images/pkg/container"
@@ -15,18 +17,32 @@ import (
 	"github.com/osbuild/images/pkg/rpmmd"
 )
 
+// containerDeploymentRef is the ref under which ostree pins the first
+// deployment created from a container image (the deployment of bootversion 1,
+// subbootversion 1 with index 0). Container deployments are not created from
+// a named ref, so this is the ref used to mount the deployment.
+const containerDeploymentRef = "ostree/1/1/0"
+
 // OSTreeDeployment represents the filesystem tree of a target image based
-// on a deployed ostree commit.
+// on a deployed ostree commit or OSTree native container image.
 type OSTreeDeployment struct {
 	Base
 
+	// Remote the deployment is updated from. For deployments from a
+	// container, the remote is only used to verify the signatures of the
+	// container image; if it is unset, the container reference is recorded
+	// as an unverified registry origin.
 	Remote ostree.Remote
 
 	OSVersion string
 
-	commitSource ostree.SourceSpec
+	// Exactly one of commitSource or containerSource is set
+	commitSource *ostree.SourceSpec
 	ostreeSpecs  []ostree.CommitSpec
 
+	containerSource *container.SourceSpec
+	containerSpec   *container.Spec
+
 	SysrootReadOnly bool
 
 	osName string
@@ -35,6 +51,10 @@ type OSTreeDeployment struct {
 	Keyboard            string
 	Locale              string
 
+	// Boot the deployment in FIPS mode. The FIPS crypto policy and dracut
+	// module are part of the deployed commit.
+	FIPS bool
+
 	Users  []users.User
 	Groups []users.Group
 
@@ -53,6 +73,9 @@ type OSTreeDeployment struct {
 
 	EnabledServices  []string
 	DisabledServices []string
+
+	// Scripts to run once on the first boot of the deployment
+	FirstBoot *firstboot.Options
 }
 
 // NewOSTreeDeployment creates a pipeline for an ostree deployment from a
@@ -67,7 +90,32 @@ func NewOSTreeDeployment(buildPipeline *Build,
 
 	p := &OSTreeDeployment{
 		Base:             NewBase(m, "ostree-deployment", buildPipeline),
-		commitSource:     commit,
+		commitSource:     &commit,
+		osName:           osName,
+		platform:         platform,
+		ignition:         ignition,
+		ignitionPlatform: ignitionPlatform,
+	}
+	buildPipeline.addDependent(p)
+	m.addPipeline(p)
+	return p
+}
+
+// NewOSTreeContainerDeployment creates a pipeline for an ostree deployment
+// from an OSTree native container image. The container reference is recorded
+// as the origin of the deployment, so that the resulting system is updated
+// from the registry.
+func NewOSTreeContainerDeployment(buildPipeline *Build,
+	m *Manifest,
+	container container.SourceSpec,
+	osName string,
+	ignition bool,
+	ignitionPlatform string,
+	platform platform.Platform) *OSTreeDeployment {
+
+	p := &OSTreeDeployment{
+		Base:             NewBase(m, "ostree-deployment", buildPipeline),
+		containerSource:  &container,
 		osName:           osName,
 		platform:         platform,
 		ignition:         ignition,
@@ -90,49 +138,168 @@ func (p *OSTreeDeployment) getOSTreeCommits() []ostree.CommitSpec {
 }
 
 func (p *OSTreeDeployment) getOSTreeCommitSources() []ostree.SourceSpec {
+	if p.commitSource == nil {
+		return nil
+	}
 	return []ostree.SourceSpec{
-		p.commitSource,
+		*p.commitSource,
+	}
+}
+
+func (p *OSTreeDeployment) getContainerSources() []container.SourceSpec {
+	if p.containerSource == nil {
+		return nil
+	}
+	return []container.SourceSpec{
+		*p.containerSource,
+	}
+}
+
+func (p *OSTreeDeployment) getContainerSpecs() []container.Spec {
+	if p.containerSpec == nil {
+		return nil
 	}
+	return []container.Spec{*p.containerSpec}
 }
 
 func (p *OSTreeDeployment) serializeStart(packages []rpmmd.PackageSpec, containers []container.Spec, commits []ostree.CommitSpec) {
-	if len(p.ostreeSpecs) > 0 {
+	if len(p.ostreeSpecs) > 0 || p.containerSpec != nil {
 		panic("double call to serializeStart()")
 	}
 
-	if len(commits) != 1 {
-		panic("pipeline requires exactly one ostree commit")
+	switch {
+	case p.commitSource != nil:
+		if len(commits) != 1 {
+			panic("pipeline requires exactly one ostree commit")
+		}
+		p.ostreeSpecs = commits
+	case p.containerSource != nil:
+		if len(containers) != 1 {
+			panic("pipeline requires exactly one container")
+		}
+		p.containerSpec = &containers[0]
+	default:
+		panic("pipeline requires either an ostree commit or a container")
 	}
-
-	p.ostreeSpecs = commits
 }
 
 func (p *OSTreeDeployment) serializeEnd() {
-	if len(p.ostreeSpecs) == 0 {
+	if len(p.ostreeSpecs) == 0 && p.containerSpec == nil {
 		panic("serializeEnd() call when serialization not in progress")
 	}
 
 	p.ostreeSpecs = nil
+	p.containerSpec = nil
+}
+
+// doOSTreeSpec adds the stages that deploy the ostree commit and returns the
+// ref of the deployment
+func (p *OSTreeDeployment) doOSTreeSpec(pipeline *osbuild.Pipeline, repoPath string, kernelOpts []string) string {
+	commit := p.ostreeSpecs[0]
+
+	pipeline.AddStage(osbuild.NewOSTreeDeployStage(
+		&osbuild.OSTreeDeployStageOptions{
+			OsName: p.osName,
+			Ref:    commit.Ref,
+			Remote: p.Remote.Name,
+			Mounts: []string{"/boot", "/boot/efi"},
+			Rootfs: osbuild.Rootfs{
+				Label: "root",
+			},
+			KernelOpts: kernelOpts,
+		},
+	))
+
+	remoteURL := p.Remote.URL
+	if remoteURL == "" {
+		// if the remote URL for the image is not specified, use the source commit URL
+		remoteURL = commit.URL
+	}
+	pipeline.AddStage(osbuild.NewOSTreeRemotesStage(
+		&osbuild.OSTreeRemotesStageOptions{
+			Repo: repoPath,
+			Remotes: []osbuild.OSTreeRemote{
+				{
+					Name:        p.Remote.Name,
+					URL:         remoteURL,
+					ContentURL:  p.Remote.ContentURL,
+					GPGKeyPaths: p.Remote.GPGKeyPaths,
+				},
+			},
+		},
+	))
+
+	return commit.Ref
+}
+
+// doOSTreeContainerSpec adds the stages that deploy the container and
+// returns the ref of the deployment
+func (p *OSTreeDeployment) doOSTreeContainerSpec(pipeline *osbuild.Pipeline, repoPath string, kernelOpts []string) string {
+	cont := *p.containerSpec
+
+	// the origin of the deployment is the container in the registry and not
+	// the local copy that is deployed by osbuild
+	var targetImgref string
+	if p.Remote.Name != "" {
+		targetImgref = fmt.Sprintf("ostree-remote-registry:%s:%s", p.Remote.Name, cont.Source)
+	} else {
+		targetImgref = fmt.Sprintf("ostree-unverified-registry:%s", cont.Source)
+	}
+
+	pipeline.AddStage(osbuild.NewOSTreeDeployContainerStage(
+		&osbuild.OSTreeDeployContainerStageOptions{
+			OsName:       p.osName,
+			TargetImgref: targetImgref,
+			Mounts:       []string{"/boot", "/boot/efi"},
+			Rootfs: &osbuild.Rootfs{
+				Label: "root",
+			},
+			KernelOpts: kernelOpts,
+		},
+		osbuild.NewContainersInputForSources([]container.Spec{cont}),
+	))
+
+	// the remote only needs to be configured when it is used to verify the
+	// container signatures
+	if p.Remote.Name != "" {
+		pipeline.AddStage(osbuild.NewOSTreeRemotesStage(
+			&osbuild.OSTreeRemotesStageOptions{
+				Repo: repoPath,
+				Remotes: []osbuild.OSTreeRemote{
+					{
+						Name:        p.Remote.Name,
+						URL:         p.Remote.URL,
+						ContentURL:  p.Remote.ContentURL,
+						GPGKeyPaths: p.Remote.GPGKeyPaths,
+					},
+				},
+			},
+		))
+	}
+
+	return containerDeploymentRef
 }
 
 func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
-	if len(p.ostreeSpecs) == 0 {
+	if len(p.ostreeSpecs) == 0 && p.containerSpec == nil {
 		panic("serialization not started")
 	}
 	if len(p.ostreeSpecs) > 1 {
 		panic("multiple ostree commit specs found; this is a programming error")
 	}
-	commit := p.ostreeSpecs[0]
 
 	const repoPath = "/ostree/repo"
 
 	pipeline := p.Base.serialize()
 
 	pipeline.AddStage(osbuild.OSTreeInitFsStage())
-	pipeline.AddStage(osbuild.NewOSTreePullStage(
-		&osbuild.OSTreePullStageOptions{Repo: repoPath, Remote: p.Remote.Name},
-		osbuild.NewOstreePullStageInputs("org.osbuild.source", commit.Checksum, commit.Ref),
-	))
+	if len(p.ostreeSpecs) > 0 {
+		commit := p.ostreeSpecs[0]
+		pipeline.AddStage(osbuild.NewOSTreePullStage(
+			&osbuild.OSTreePullStageOptions{Repo: repoPath, Remote: p.Remote.Name},
+			osbuild.NewOstreePullStageInputs("org.osbuild.source", commit.Checksum, commit.Ref),
+		))
+	}
 	pipeline.AddStage(osbuild.NewOSTreeOsInitStage(
 		&osbuild.OSTreeOsInitStageOptions{
 			OSName: p.osName,
@@ -147,6 +314,9 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		},
 	}))
 	kernelOpts := osbuild.GenImageKernelOptions(p.PartitionTable)
+	if p.FIPS {
+		kernelOpts = append(kernelOpts, osbuild.GenFIPSKernelOptions(p.PartitionTable)...)
+	}
 	kernelOpts = append(kernelOpts, p.KernelOptionsAppend...)
 
 	if p.ignition {
@@ -160,43 +330,18 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		)
 	}
 
-	pipeline.AddStage(osbuild.NewOSTreeDeployStage(
-		&osbuild.OSTreeDeployStageOptions{
-			OsName: p.osName,
-			Ref:    commit.Ref,
-			Remote: p.Remote.Name,
-			Mounts: []string{"/boot", "/boot/efi"},
-			Rootfs: osbuild.Rootfs{
-				Label: "root",
-			},
-			KernelOpts: kernelOpts,
-		},
-	))
-
-	remoteURL := p.Remote.URL
-	if remoteURL == "" {
-		// if the remote URL for the image is not specified, use the source commit URL
-		remoteURL = commit.URL
+	var ref string
+	if len(p.ostreeSpecs) > 0 {
+		ref = p.doOSTreeSpec(&pipeline, repoPath, kernelOpts)
+	} else {
+		ref = p.doOSTreeContainerSpec(&pipeline, repoPath, kernelOpts)
 	}
-	pipeline.AddStage(osbuild.NewOSTreeRemotesStage(
-		&osbuild.OSTreeRemotesStageOptions{
-			Repo: "/ostree/repo",
-			Remotes: []osbuild.OSTreeRemote{
-				{
-					Name:        p.Remote.Name,
-					URL:         remoteURL,
-					ContentURL:  p.Remote.ContentURL,
-					GPGKeyPaths: p.Remote.GPGKeyPaths,
-				},
-			},
-		},
-	))
 
 	pipeline.AddStage(osbuild.NewOSTreeFillvarStage(
 		&osbuild.OSTreeFillvarStageOptions{
 			Deployment: osbuild.OSTreeDeployment{
 				OSName: p.osName,
-				Ref:    commit.Ref,
+				Ref:    ref,
 			},
 		},
 	))
@@ -212,12 +357,12 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 			},
 		},
 	)
-	configStage.MountOSTree(p.osName, commit.Ref, 0)
+	configStage.MountOSTree(p.osName, ref, 0)
 	pipeline.AddStage(configStage)
 
 	fstabOptions := osbuild.NewFSTabStageOptions(p.PartitionTable)
 	fstabStage := osbuild.NewFSTabStage(fstabOptions)
-	fstabStage.MountOSTree(p.osName, commit.Ref, 0)
+	fstabStage.MountOSTree(p.osName, ref, 0)
 	pipeline.AddStage(fstabStage)
 
 	if len(p.Users) > 0 {
@@ -225,13 +370,13 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		if err != nil {
 			panic("password encryption failed")
 		}
-		usersStage.MountOSTree(p.osName, commit.Ref, 0)
+		usersStage.MountOSTree(p.osName, ref, 0)
 		pipeline.AddStage(usersStage)
 	}
 
 	if len(p.Groups) > 0 {
 		grpStage := osbuild.GenGroupsStage(p.Groups)
-		grpStage.MountOSTree(p.osName, commit.Ref, 0)
+		grpStage.MountOSTree(p.osName, ref, 0)
 		pipeline.AddStage(grpStage)
 	}
 
@@ -254,9 +399,9 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		// only when Ignition is used. To prevent this and to not have a special cases
 		// in the code based on distro version, we enable / disable services also by
 		// creating a preset file.
-		if len(p.EnabledServices) != 0 || len(p.DisabledServices) != 0 {
-			presetsStage := osbuild.GenServicesPresetStage(p.EnabledServices, p.DisabledServices)
-			presetsStage.MountOSTree(p.osName, commit.Ref, 0)
+		if enabledServices := p.enabledServices(); len(enabledServices) != 0 || len(p.DisabledServices) != 0 {
+			presetsStage := osbuild.GenServicesPresetStage(enabledServices, p.DisabledServices)
+			presetsStage.MountOSTree(p.osName, ref, 0)
 			pipeline.AddStage(presetsStage)
 		}
 	}
@@ -279,7 +424,7 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 			},
 		}
 		rootLockStage := osbuild.NewUsersStage(userOptions)
-		rootLockStage.MountOSTree(p.osName, commit.Ref, 0)
+		rootLockStage.MountOSTree(p.osName, ref, 0)
 		pipeline.AddStage(rootLockStage)
 	}
 
@@ -288,7 +433,7 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 			Keymap: p.Keyboard,
 		}
 		keymapStage := osbuild.NewKeymapStage(options)
-		keymapStage.MountOSTree(p.osName, commit.Ref, 0)
+		keymapStage.MountOSTree(p.osName, ref, 0)
 		pipeline.AddStage(keymapStage)
 	}
 
@@ -297,7 +442,7 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 			Language: p.Locale,
 		}
 		localeStage := osbuild.NewLocaleStage(options)
-		localeStage.MountOSTree(p.osName, commit.Ref, 0)
+		localeStage.MountOSTree(p.osName, ref, 0)
 		pipeline.AddStage(localeStage)
 	}
 
@@ -315,14 +460,14 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		TerminalOutput: []string{"console"},
 	}
 	bootloader := osbuild.NewGRUB2Stage(grubOptions)
-	bootloader.MountOSTree(p.osName, commit.Ref, 0)
+	bootloader.MountOSTree(p.osName, ref, 0)
 	pipeline.AddStage(bootloader)
 
 	// First create custom directories, because some of the files may depend on them
 	if len(p.Directories) > 0 {
 		dirStages := osbuild.GenDirectoryNodesStages(p.Directories)
 		for _, stage := range dirStages {
-			stage.MountOSTree(p.osName, commit.Ref, 0)
+			stage.MountOSTree(p.osName, ref, 0)
 		}
 		pipeline.AddStages(dirStages...)
 	}
@@ -330,17 +475,26 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 	if len(p.Files) > 0 {
 		fileStages := osbuild.GenFileNodesStages(p.Files)
 		for _, stage := range fileStages {
-			stage.MountOSTree(p.osName, commit.Ref, 0)
+			stage.MountOSTree(p.osName, ref, 0)
 		}
 		pipeline.AddStages(fileStages...)
 	}
 
-	if len(p.EnabledServices) != 0 || len(p.DisabledServices) != 0 {
+	if p.FirstBoot != nil {
+		firstBootStages := osbuild.GenDirectoryNodesStages(p.FirstBoot.Directories())
+		firstBootStages = append(firstBootStages, osbuild.GenFileNodesStages(p.FirstBoot.Files())...)
+		for _, stage := range firstBootStages {
+			stage.MountOSTree(p.osName, ref, 0)
+		}
+		pipeline.AddStages(firstBootStages...)
+	}
+
+	if enabledServices := p.enabledServices(); len(enabledServices) != 0 || len(p.DisabledServices) != 0 {
 		systemdStage := osbuild.NewSystemdStage(&osbuild.SystemdStageOptions{
-			EnabledServices:  p.EnabledServices,
+			EnabledServices:  enabledServices,
 			DisabledServices: p.DisabledServices,
 		})
-		systemdStage.MountOSTree(p.osName, commit.Ref, 0)
+		systemdStage.MountOSTree(p.osName, ref, 0)
 		pipeline.AddStage(systemdStage)
 	}
 
@@ -348,7 +502,7 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 		&osbuild.OSTreeSelinuxStageOptions{
 			Deployment: osbuild.OSTreeDeployment{
 				OSName: p.osName,
-				Ref:    commit.Ref,
+				Ref:    ref,
 			},
 		},
 	))
@@ -356,6 +510,13 @@ func (p *OSTreeDeployment) serialize() osbuild.Pipeline {
 	return pipeline
 }
 
+func (p *OSTreeDeployment) enabledServices() []string {
+	if p.FirstBoot == nil {
+		return p.EnabledServices
+	}
+	return append(append([]string{}, p.EnabledServices...), firstboot.ServiceName)
+}
+
 func (p *OSTreeDeployment) getInline() []string {
 	inlineData := []string{}
 
@@ -364,5 +525,11 @@ func (p *OSTreeDeployment) getInline() []string {
 		inlineData = append(inlineData, string(file.Data()))
 	}
 
+	if p.FirstBoot != nil {
+		for _, file := range p.FirstBoot.Files() {
+			inlineData = append(inlineData, string(file.Data()))
+		}
+	}
+
 	return inlineData
 }
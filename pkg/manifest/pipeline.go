@@ -67,6 +67,13 @@ type Pipeline interface {
 	// getInline returns the list of inlined data content that will be used to
 	// embed files in the pipeline tree.
 	getInline() []string
+	// getRemoteFiles returns the list of files that will be downloaded at
+	// build time and embedded in the pipeline tree.
+	getRemoteFiles() []osbuild.RemoteFile
+	// getSecrets returns the sensitive values of the pipeline, keyed by
+	// their ID, that are kept out of redacted manifests. The IDs are
+	// prefixed with the name of the pipeline.
+	getSecrets() Secrets
 }
 
 // A Base represents the core functionality shared between each of the pipeline
@@ -142,6 +149,14 @@ func (p Base) getInline() []string {
 	return []string{}
 }
 
+func (p Base) getRemoteFiles() []osbuild.RemoteFile {
+	return nil
+}
+
+func (p Base) getSecrets() Secrets {
+	return nil
+}
+
 // NewBase returns a generic Pipeline object. The name is mandatory, immutable and must
 // be unique among all the pipelines used in a manifest, which is currently not enforced.
 // The build argument is a pipeline representing a build root in which the rest of the
@@ -0,0 +1,260 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/osbuild/images/internal/fsnode"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/pkg/container"
+	"github.com/osbuild/images/pkg/osbuild"
+	"github.com/osbuild/images/pkg/ostree"
+	"github.com/osbuild/images/pkg/rpmmd"
+)
+
+// PXEBaseURLPlaceholder is used in the generated boot configurations if no
+// base URL is set. It must be replaced with the URL the tree is served from
+// before the tree can be booted.
+const PXEBaseURLPlaceholder = "@PXE_BASE_URL@"
+
+// A PXETree represents a tree to boot the anaconda installer or a live
+// environment over the network. It contains the kernel, initramfs and
+// squashfs root file system of an installer pipeline as well as GRUB, iPXE
+// and PXELINUX configurations to boot them.
+type PXETree struct {
+	Base
+
+	OSName string
+	Users  []users.User
+	Groups []users.Group
+
+	// URL the tree is served from, used to reach the root file system and
+	// the payload from the booted kernel.
+	BaseURL string
+
+	SquashfsCompression string
+
+	// Payload of an anaconda installer, installed with an unattended
+	// kickstart
+	OSPipeline *OS
+
+	KernelOpts []string
+
+	anacondaPipeline *AnacondaInstaller
+	rootfsPipeline   *ISORootfsImg
+
+	files []*fsnode.File
+}
+
+const (
+	pxeKickstartPath = "/osbuild.ks"
+	pxePayloadPath   = "/liveimg.tar.gz"
+)
+
+func NewPXETree(buildPipeline *Build, anacondaPipeline *AnacondaInstaller, rootfsPipeline *ISORootfsImg) *PXETree {
+	if anacondaPipeline.Manifest() != rootfsPipeline.Manifest() {
+		panic("pipelines from different manifests")
+	}
+	p := &PXETree{
+		Base:             NewBase(anacondaPipeline.Manifest(), "pxe-tree", buildPipeline),
+		anacondaPipeline: anacondaPipeline,
+		rootfsPipeline:   rootfsPipeline,
+	}
+	buildPipeline.addDependent(p)
+	anacondaPipeline.Manifest().addPipeline(p)
+	return p
+}
+
+func (p *PXETree) getBuildPackages(_ Distro) []string {
+	packages := []string{
+		"squashfs-tools",
+	}
+
+	if p.OSPipeline != nil {
+		packages = append(packages, "tar")
+	}
+
+	return packages
+}
+
+func (p *PXETree) baseURL() string {
+	if p.BaseURL == "" {
+		return PXEBaseURLPlaceholder
+	}
+	return strings.TrimSuffix(p.BaseURL, "/")
+}
+
+// rootfsPath returns the path of the squashfs root file system in the tree
+func (p *PXETree) rootfsPath() string {
+	if p.anacondaPipeline.Type == AnacondaInstallerTypeLive {
+		return "/LiveOS/squashfs.img"
+	}
+	return "/images/install.img"
+}
+
+func (p *PXETree) kernelOpts() []string {
+	var kernelOpts []string
+	switch p.anacondaPipeline.Type {
+	case AnacondaInstallerTypeLive:
+		kernelOpts = append(kernelOpts,
+			fmt.Sprintf("root=live:%s%s", p.baseURL(), p.rootfsPath()),
+			"rd.live.image",
+		)
+	case AnacondaInstallerTypePayload:
+		// anaconda looks for images/install.img below the stage2 URL
+		kernelOpts = append(kernelOpts, fmt.Sprintf("inst.stage2=%s", p.baseURL()))
+		if p.OSPipeline != nil {
+			kernelOpts = append(kernelOpts, fmt.Sprintf("inst.ks=%s%s", p.baseURL(), pxeKickstartPath))
+		}
+	}
+	return append(kernelOpts, p.KernelOpts...)
+}
+
+// bootConfigs returns the GRUB, iPXE and PXELINUX configurations. iPXE and
+// PXELINUX reference the kernel and initramfs relative to the location of
+// their configuration. GRUB loads them from the root of its boot device
+// ($prefix is the GRUB directory, not the tree), so the tree must be served
+// from the root of the TFTP or HTTP server when booting with GRUB.
+func (p *PXETree) bootConfigs() []*fsnode.File {
+	title := fmt.Sprintf("%s %s", p.anacondaPipeline.product, p.anacondaPipeline.version)
+	opts := strings.Join(p.kernelOpts(), " ")
+
+	grub := fmt.Sprintf(`set default=0
+set timeout=5
+
+menuentry '%s' {
+	linux ($root)/vmlinuz %s
+	initrd ($root)/initrd.img
+}
+`, title, opts)
+
+	ipxe := fmt.Sprintf(`#!ipxe
+kernel vmlinuz initrd=initrd.img %s
+initrd initrd.img
+boot
+`, opts)
+
+	pxelinux := fmt.Sprintf(`default %[1]s
+prompt 0
+timeout 50
+
+label %[1]s
+	menu label %[2]s
+	kernel vmlinuz
+	append initrd=initrd.img %[3]s
+`, strings.ToLower(p.anacondaPipeline.product), title, opts)
+
+	configs := []struct {
+		path string
+		data string
+	}{
+		{"/grub.cfg", grub},
+		{"/boot.ipxe", ipxe},
+		{"/pxelinux.cfg/default", pxelinux},
+	}
+
+	var files []*fsnode.File
+	for _, config := range configs {
+		file, err := fsnode.NewFile(config.path, nil, nil, nil, []byte(config.data))
+		if err != nil {
+			panic(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func (p *PXETree) serializeStart(_ []rpmmd.PackageSpec, _ []container.Spec, _ []ostree.CommitSpec) {
+	if len(p.files) > 0 {
+		panic("double call to serializeStart()")
+	}
+	p.files = p.bootConfigs()
+}
+
+func (p *PXETree) serializeEnd() {
+	if len(p.files) == 0 {
+		panic("serializeEnd() call when serialization not in progress")
+	}
+	p.files = nil
+}
+
+func (p *PXETree) serialize() osbuild.Pipeline {
+	if len(p.files) == 0 {
+		panic("serialization not started")
+	}
+	if p.anacondaPipeline.Type == AnacondaInstallerTypeLive && p.OSPipeline != nil {
+		panic("a live PXE tree cannot contain a payload")
+	}
+
+	pipeline := p.Base.serialize()
+
+	pipeline.AddStage(osbuild.NewMkdirStage(&osbuild.MkdirStageOptions{
+		Paths: []osbuild.MkdirStagePath{
+			{
+				Path: "/pxelinux.cfg",
+			},
+			{
+				Path: "/images",
+			},
+			{
+				Path: "/LiveOS",
+			},
+		},
+	}))
+
+	inputName := "tree"
+	copyStageOptions := &osbuild.CopyStageOptions{
+		Paths: []osbuild.CopyStagePath{
+			{
+				From: fmt.Sprintf("input://%s/boot/vmlinuz-%s", inputName, p.anacondaPipeline.kernelVer),
+				To:   "tree:///vmlinuz",
+			},
+			{
+				From: fmt.Sprintf("input://%s/boot/initramfs-%s.img", inputName, p.anacondaPipeline.kernelVer),
+				To:   "tree:///initrd.img",
+			},
+		},
+	}
+	copyStageInputs := osbuild.NewPipelineTreeInputs(inputName, p.anacondaPipeline.Name())
+	pipeline.AddStage(osbuild.NewCopyStageSimple(copyStageOptions, copyStageInputs))
+
+	squashfsOptions := osbuild.SquashfsStageOptions{
+		Filename: strings.TrimPrefix(p.rootfsPath(), "/"),
+	}
+	if p.SquashfsCompression != "" {
+		squashfsOptions.Compression.Method = p.SquashfsCompression
+	} else {
+		// default to xz if not specified
+		squashfsOptions.Compression.Method = "xz"
+	}
+	if squashfsOptions.Compression.Method == "xz" {
+		squashfsOptions.Compression.Options = &osbuild.FSCompressionOptions{
+			BCJ: osbuild.BCJOption(p.anacondaPipeline.platform.GetArch().String()),
+		}
+	}
+	pipeline.AddStage(osbuild.NewSquashfsStage(&squashfsOptions, p.rootfsPipeline.Name()))
+
+	if p.OSPipeline != nil {
+		pipeline.AddStage(osbuild.NewTarStage(&osbuild.TarStageOptions{Filename: pxePayloadPath}, p.OSPipeline.name))
+
+		kickstartOptions, err := osbuild.NewKickstartStageOptions(pxeKickstartPath, p.baseURL()+pxePayloadPath, p.Users, p.Groups, "", "", p.OSName)
+		if err != nil {
+			panic("failed to create kickstartstage options")
+		}
+		pipeline.AddStage(osbuild.NewKickstartStage(kickstartOptions))
+	}
+
+	pipeline.AddStages(osbuild.GenFileNodesStages(p.files)...)
+
+	return pipeline
+}
+
+func (p *PXETree) getInline() []string {
+	inlineData := []string{}
+
+	for _, file := range p.files {
+		inlineData = append(inlineData, string(file.Data()))
+	}
+
+	return inlineData
+}
@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPXETree(installerType AnacondaInstallerType) *PXETree {
+	m := New()
+	pf := &platform.X86{UEFIVendor: "fedora"}
+	build := NewBuild(&m, &runner.Fedora{Version: 39}, []rpmmd.RepoConfig{})
+	anaconda := NewAnacondaInstaller(&m, installerType, build, pf, nil, "kernel", "Fedora", "39")
+	rootfs := NewISORootfsImg(build, anaconda)
+	return NewPXETree(build, anaconda, rootfs)
+}
+
+func pxeTreeConfigs(tree *PXETree) map[string]string {
+	configs := map[string]string{}
+	for _, file := range tree.bootConfigs() {
+		configs[file.Path()] = string(file.Data())
+	}
+	return configs
+}
+
+func TestPXETreeLiveKernelOpts(t *testing.T) {
+	tree := newTestPXETree(AnacondaInstallerTypeLive)
+	tree.BaseURL = "http://example.com/pxe/"
+	tree.KernelOpts = []string{"console=ttyS0"}
+
+	assert.Equal(t, []string{
+		"root=live:http://example.com/pxe/LiveOS/squashfs.img",
+		"rd.live.image",
+		"console=ttyS0",
+	}, tree.kernelOpts())
+}
+
+func TestPXETreeInstallerKernelOpts(t *testing.T) {
+	tree := newTestPXETree(AnacondaInstallerTypePayload)
+	assert.Equal(t, []string{"inst.stage2=" + PXEBaseURLPlaceholder}, tree.kernelOpts())
+
+	tree.OSPipeline = &OS{}
+	assert.Equal(t, []string{
+		"inst.stage2=" + PXEBaseURLPlaceholder,
+		"inst.ks=" + PXEBaseURLPlaceholder + "/osbuild.ks",
+	}, tree.kernelOpts())
+	assert.Contains(t, tree.getBuildPackages(DISTRO_FEDORA), "tar")
+}
+
+func TestPXETreeBootConfigs(t *testing.T) {
+	tree := newTestPXETree(AnacondaInstallerTypeLive)
+	tree.BaseURL = "http://example.com"
+
+	configs := pxeTreeConfigs(tree)
+	require.Len(t, configs, 3)
+
+	opts := "root=live:http://example.com/LiveOS/squashfs.img rd.live.image"
+	assert.Contains(t, configs["/grub.cfg"], "linux ($root)/vmlinuz "+opts)
+	assert.Contains(t, configs["/grub.cfg"], "initrd ($root)/initrd.img")
+	assert.True(t, strings.HasPrefix(configs["/boot.ipxe"], "#!ipxe\n"))
+	assert.Contains(t, configs["/boot.ipxe"], "kernel vmlinuz initrd=initrd.img "+opts)
+	assert.Contains(t, configs["/pxelinux.cfg/default"], "append initrd=initrd.img "+opts)
+}
@@ -67,7 +67,11 @@ func (p *RawImage) serialize() osbuild.Pipeline {
 	switch p.treePipeline.platform.GetArch() {
 	case platform.ARCH_S390X:
 		loopback := osbuild.NewLoopbackDevice(&osbuild.LoopbackDeviceOptions{Filename: p.Filename()})
-		pipeline.AddStage(osbuild.NewZiplInstStage(osbuild.NewZiplInstStageOptions(p.treePipeline.kernelVer, pt), loopback, copyDevices, copyMounts))
+		ziplInstOptions := osbuild.NewZiplInstStageOptions(p.treePipeline.kernelVer, pt)
+		if p.treePipeline.SecureExecution != nil {
+			ziplInstOptions.SecureImage = secureExecutionImagePath
+		}
+		pipeline.AddStage(osbuild.NewZiplInstStage(ziplInstOptions, loopback, copyDevices, copyMounts))
 	default:
 		if grubLegacy := p.treePipeline.platform.GetBIOSPlatform(); grubLegacy != "" {
 			pipeline.AddStage(osbuild.NewGrub2InstStage(osbuild.NewGrub2InstStageOption(p.Filename(), pt, grubLegacy)))
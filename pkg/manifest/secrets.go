@@ -0,0 +1,370 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/osbuild/images/pkg/crypt"
+	"github.com/osbuild/images/pkg/osbuild"
+)
+
+// DefaultSecretEnvPrefix is the prefix of the environment variables holding
+// secrets, see EnvSecretSource
+const DefaultSecretEnvPrefix = "OSBUILD_SECRET_"
+
+// secretRefRegex matches the references to secrets in a manifest
+var secretRefRegex = regexp.MustCompile(`\$\{secret:([A-Za-z0-9._-]+)\}`)
+
+// invalidSecretIDCharsRegex matches the characters that can't be used in the
+// ID of a secret
+var invalidSecretIDCharsRegex = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Secrets maps the IDs of sensitive values in a manifest, like activation
+// keys, password hashes or ignition configs, to the values.
+type Secrets map[string]string
+
+// Lookup returns the value of the secret with the given ID
+func (s Secrets) Lookup(id string) (string, bool) {
+	value, ok := s[id]
+	return value, ok
+}
+
+// A SecretSource provides the values of the secrets when a manifest is
+// materialized for building.
+type SecretSource interface {
+	Lookup(id string) (string, bool)
+}
+
+// EnvSecretSource looks up secrets in the environment. The variable of a
+// secret is the Prefix followed by the ID in upper case, with every character
+// other than a letter or digit replaced by an underscore, e.g.
+// OSBUILD_SECRET_OS_SUBSCRIPTION_ACTIVATION_KEY for the ID
+// "os.subscription-activation-key" and the DefaultSecretEnvPrefix.
+type EnvSecretSource struct {
+	Prefix string
+}
+
+func (s EnvSecretSource) Lookup(id string) (string, bool) {
+	return os.LookupEnv(s.Prefix + SecretEnvName(id))
+}
+
+// SecretEnvName returns the name of the environment variable for the secret
+// with the given ID, without a prefix.
+func SecretEnvName(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// SecretRef returns the reference to the secret with the given ID, which
+// replaces the secret in a redacted manifest.
+func SecretRef(id string) string {
+	return fmt.Sprintf("${secret:%s}", id)
+}
+
+// RedactSecrets replaces the secrets in the manifest with references to
+// their IDs. The result can be logged or stored and needs to be materialized
+// with MaterializeSecrets before it's built.
+//
+// Only whole values are replaced: the string values of the options of the
+// stages, devices and mounts and the data of the inline sources that are
+// equal to a secret. A secret that is only part of a value, or is encoded,
+// e.g. base64, is not found, so that unrelated URLs, paths and checksums are
+// never rewritten. Secrets with the same value are replaced by a reference
+// to the smallest of their IDs. Since the inline sources are addressed by
+// their checksum, the references to them are updated too. The order of the
+// keys of the manifest is kept.
+func RedactSecrets(manifest OSBuildManifest, secrets Secrets) (OSBuildManifest, error) {
+	if len(secrets) == 0 {
+		return manifest, nil
+	}
+
+	byValue := make(map[string]string)
+	for id, value := range secrets {
+		if !secretRefRegex.MatchString(SecretRef(id)) {
+			return nil, fmt.Errorf("invalid secret ID %q", id)
+		}
+		if value == "" {
+			continue
+		}
+		if other, ok := byValue[value]; !ok || id < other {
+			byValue[value] = id
+		}
+	}
+
+	redact := func(s string) (string, error) {
+		if id, ok := byValue[s]; ok {
+			return SecretRef(id), nil
+		}
+		return s, nil
+	}
+	return rewriteManifest(manifest, redact)
+}
+
+// MaterializeSecrets replaces the references to secrets in a manifest
+// redacted by RedactSecrets with the values from the source. Like
+// RedactSecrets, it only replaces whole values. It's an error if the source
+// doesn't have a referenced secret.
+func MaterializeSecrets(manifest OSBuildManifest, source SecretSource) (OSBuildManifest, error) {
+	materialize := func(s string) (string, error) {
+		match := secretRefRegex.FindStringSubmatch(s)
+		if match == nil || match[0] != s {
+			return s, nil
+		}
+		value, ok := source.Lookup(match[1])
+		if !ok {
+			return "", fmt.Errorf("secret %q not found", match[1])
+		}
+		return value, nil
+	}
+	return rewriteManifest(manifest, materialize)
+}
+
+// rewriteManifest applies rewrite to the string values of the options in the
+// pipelines of the manifest and to the data of the inline sources. The
+// references to the inline sources are updated to the checksums of the
+// rewritten data.
+func rewriteManifest(manifest OSBuildManifest, rewrite func(string) (string, error)) (OSBuildManifest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(manifest))
+	decoder.UseNumber()
+	node, err := decodeJSON(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode manifest: %w", err)
+	}
+	doc, ok := node.(*jsonObject)
+	if !ok {
+		return nil, fmt.Errorf("cannot decode manifest: not an object")
+	}
+
+	// old checksum -> new checksum, without the algorithm
+	checksums := make(map[string]string)
+	sources, _ := doc.get("sources").(*jsonObject)
+	inline, _ := sources.get("org.osbuild.inline").(*jsonObject)
+	if items, ok := inline.get("items").(*jsonObject); ok {
+		for idx, name := range items.keys {
+			item, ok := items.values[idx].(*jsonObject)
+			if !ok || item.get("encoding") != "base64" {
+				return nil, fmt.Errorf("unsupported inline source item %q", name)
+			}
+			encoded, _ := item.get("data").(string)
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("cannot decode inline source item %q: %w", name, err)
+			}
+			newData, err := rewrite(string(data))
+			if err != nil {
+				return nil, err
+			}
+			if newData == string(data) {
+				continue
+			}
+			newSum := fmt.Sprintf("%x", sha256.Sum256([]byte(newData)))
+			checksums[strings.TrimPrefix(name, "sha256:")] = newSum
+			items.keys[idx] = "sha256:" + newSum
+			item.set("data", base64.StdEncoding.EncodeToString([]byte(newData)))
+		}
+	}
+
+	updateChecksums := func(s string) string {
+		for oldSum, newSum := range checksums {
+			s = strings.ReplaceAll(s, oldSum, newSum)
+		}
+		return s
+	}
+
+	// walk rewrites the values below an "options" key and updates the
+	// checksums in all keys and values
+	var walk func(node interface{}, inOptions bool) (interface{}, error)
+	walk = func(node interface{}, inOptions bool) (interface{}, error) {
+		switch n := node.(type) {
+		case *jsonObject:
+			for idx, key := range n.keys {
+				value, err := walk(n.values[idx], inOptions || key == "options")
+				if err != nil {
+					return nil, err
+				}
+				n.keys[idx] = updateChecksums(key)
+				n.values[idx] = value
+			}
+		case []interface{}:
+			for idx, value := range n {
+				newValue, err := walk(value, inOptions)
+				if err != nil {
+					return nil, err
+				}
+				n[idx] = newValue
+			}
+		case string:
+			if inOptions {
+				s, err := rewrite(n)
+				if err != nil {
+					return nil, err
+				}
+				n = s
+			}
+			return updateChecksums(n), nil
+		}
+		return node, nil
+	}
+	if _, err := walk(doc.get("pipelines"), false); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonObject is a decoded JSON object that keeps the order of its keys, so
+// that a rewritten manifest only differs from the original in the rewritten
+// values.
+type jsonObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// get returns the value of the key, nil if the object doesn't have the key
+// or is nil itself
+func (o *jsonObject) get(key string) interface{} {
+	if o == nil {
+		return nil
+	}
+	for idx, k := range o.keys {
+		if k == key {
+			return o.values[idx]
+		}
+	}
+	return nil
+}
+
+// set replaces the value of the key or appends the key if it's missing
+func (o *jsonObject) set(key string, value interface{}) {
+	for idx, k := range o.keys {
+		if k == key {
+			o.values[idx] = value
+			return
+		}
+	}
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, value)
+}
+
+// decodeJSON decodes the next value of the decoder. Objects are decoded as
+// *jsonObject, arrays as []interface{} and everything else like
+// json.Decoder.Token returns it.
+func decodeJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := &jsonObject{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.keys = append(object.keys, keyToken.(string))
+			object.values = append(object.values, value)
+		}
+		// the closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return object, nil
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	}
+	return token, nil
+}
+
+// encodeJSON encodes a value decoded by decodeJSON
+func encodeJSON(buf *bytes.Buffer, node interface{}) error {
+	switch n := node.(type) {
+	case *jsonObject:
+		buf.WriteByte('{')
+		for idx, key := range n.keys {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, n.values[idx]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for idx, value := range n {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		data, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
+
+// stageSecrets returns the secrets that are generated when the pipeline is
+// serialized, i.e. the password hashes of the users and kickstart stages.
+func stageSecrets(pipeline osbuild.Pipeline) Secrets {
+	secrets := make(Secrets)
+	for _, stage := range pipeline.Stages {
+		var users map[string]osbuild.UsersStageOptionsUser
+		switch options := stage.Options.(type) {
+		case *osbuild.UsersStageOptions:
+			users = options.Users
+		case *osbuild.KickstartStageOptions:
+			users = options.Users
+		}
+		for name, user := range users {
+			if user.Password != nil && crypt.PasswordIsCrypted(*user.Password) {
+				id := fmt.Sprintf("%s.user-password-%s", pipeline.Name, name)
+				secrets[invalidSecretIDCharsRegex.ReplaceAllString(id, "_")] = *user.Password
+			}
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,142 @@
+package manifest
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/users"
+	"github.com/osbuild/images/pkg/platform"
+	"github.com/osbuild/images/pkg/rpmmd"
+	"github.com/osbuild/images/pkg/runner"
+	"github.com/osbuild/images/pkg/subscription"
+)
+
+// newTestSecretsManifest returns a manifest with an activation key and a user
+// password
+func newTestSecretsManifest() Manifest {
+	repos := []rpmmd.RepoConfig{}
+	m := New()
+	build := NewBuild(&m, &runner.Fedora{Version: 37}, repos)
+	os := NewOS(&m, build, &platform.X86{BIOS: true}, repos)
+	os.Subscription = &subscription.ImageOptions{
+		Organization:  "2040324",
+		ActivationKey: "my-secret-key",
+		ServerUrl:     "subscription.rhsm.redhat.com",
+	}
+	os.Users = []users.User{{Name: "admin", Password: common.ToPtr("my-secret-password")}}
+	return m
+}
+
+func serializeTestSecretsManifest(t *testing.T, m Manifest, withSecrets bool) (OSBuildManifest, Secrets) {
+	packages := []rpmmd.PackageSpec{
+		{Name: "pkg1", Checksum: "sha1:c02524e2bd19490f2a7167958f792262754c5f46"},
+	}
+	packageSets := map[string][]rpmmd.PackageSpec{"build": packages, "os": packages}
+	if withSecrets {
+		manifest, secrets, err := m.SerializeWithSecrets(packageSets, nil, nil)
+		require.NoError(t, err)
+		return manifest, secrets
+	}
+	manifest, err := m.Serialize(packageSets, nil, nil)
+	require.NoError(t, err)
+	return manifest, nil
+}
+
+func TestSerializeWithSecrets(t *testing.T) {
+	m := newTestSecretsManifest()
+	redacted, secrets := serializeTestSecretsManifest(t, m, true)
+
+	require.Len(t, secrets, 2)
+	assert.Equal(t, "my-secret-key", secrets["os.subscription-activation-key"])
+	passwordHash := secrets["os.user-password-admin"]
+	assert.Contains(t, passwordHash, "$6$")
+
+	assert.NotContains(t, string(redacted), "my-secret-key")
+	assert.NotContains(t, string(redacted), passwordHash)
+	assert.Contains(t, string(redacted), `"password":"${secret:os.user-password-admin}"`)
+
+	// the secrets are restored, including the inline sources
+	materialized, err := MaterializeSecrets(redacted, secrets)
+	require.NoError(t, err)
+	assert.Contains(t, string(materialized), passwordHash)
+	assert.Contains(t, string(materialized), base64.StdEncoding.EncodeToString([]byte("my-secret-key")))
+
+	reredacted, err := RedactSecrets(materialized, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, string(redacted), string(reredacted))
+}
+
+func TestRedactSecretsRoundTrip(t *testing.T) {
+	manifest, _ := serializeTestSecretsManifest(t, newTestSecretsManifest(), false)
+	secrets := Secrets{"os.subscription-activation-key": "my-secret-key"}
+	redacted, err := RedactSecrets(manifest, secrets)
+	require.NoError(t, err)
+	assert.NotEqual(t, string(manifest), string(redacted))
+
+	materialized, err := MaterializeSecrets(redacted, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, string(manifest), string(materialized))
+}
+
+func TestMaterializeSecretsFromEnv(t *testing.T) {
+	manifest, _ := serializeTestSecretsManifest(t, newTestSecretsManifest(), false)
+	redacted, err := RedactSecrets(manifest, Secrets{"os.subscription-activation-key": "my-secret-key"})
+	require.NoError(t, err)
+
+	_, err = MaterializeSecrets(redacted, EnvSecretSource{Prefix: DefaultSecretEnvPrefix})
+	assert.EqualError(t, err, `secret "os.subscription-activation-key" not found`)
+
+	t.Setenv("OSBUILD_SECRET_OS_SUBSCRIPTION_ACTIVATION_KEY", "my-secret-key")
+	materialized, err := MaterializeSecrets(redacted, EnvSecretSource{Prefix: DefaultSecretEnvPrefix})
+	require.NoError(t, err)
+	assert.Equal(t, string(manifest), string(materialized))
+}
+
+func TestRedactSecretsWholeValues(t *testing.T) {
+	// a short secret that is also part of unrelated values
+	manifest := OSBuildManifest(`{"version":"2","pipelines":[{"name":"os","stages":[{"type":"org.osbuild.test","options":{"url":"https://example.com/key/abc","path":"/abc","key":"abc","checksum":"sha256:abc123"}}]}],"sources":{"org.osbuild.curl":{"items":{"sha256:abc123":{"url":"https://example.com/abc"}}}}}`)
+	secrets := Secrets{"os.activation-key": "abc"}
+	redacted, err := RedactSecrets(manifest, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":"2","pipelines":[{"name":"os","stages":[{"type":"org.osbuild.test","options":{"url":"https://example.com/key/abc","path":"/abc","key":"${secret:os.activation-key}","checksum":"sha256:abc123"}}]}],"sources":{"org.osbuild.curl":{"items":{"sha256:abc123":{"url":"https://example.com/abc"}}}}}`, string(redacted))
+
+	materialized, err := MaterializeSecrets(redacted, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, string(manifest), string(materialized))
+}
+
+func TestRedactSecretsKeyOrder(t *testing.T) {
+	// the keys are not sorted and must stay in their order
+	manifest := OSBuildManifest(`{"version":"2","pipelines":[{"name":"os","stages":[{"type":"org.osbuild.users","options":{"users":{"zed":{"uid":1000,"password":"hash"},"admin":{"password":"other"}}}}]}],"sources":{}}`)
+	redacted, err := RedactSecrets(manifest, Secrets{"os.user-password-zed": "hash"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":"2","pipelines":[{"name":"os","stages":[{"type":"org.osbuild.users","options":{"users":{"zed":{"uid":1000,"password":"${secret:os.user-password-zed}"},"admin":{"password":"other"}}}}]}],"sources":{}}`, string(redacted))
+}
+
+func TestRedactSecretsDuplicateValues(t *testing.T) {
+	manifest := OSBuildManifest(`{"pipelines":[{"name":"os","stages":[{"type":"org.osbuild.test","options":{"a":"same-secret","b":"same-secret"}}]}]}`)
+	secrets := Secrets{
+		"os.secret-c": "same-secret",
+		"os.secret-a": "same-secret",
+		"os.secret-b": "same-secret",
+	}
+	// the smallest ID is used regardless of the order of the map
+	for i := 0; i < 10; i++ {
+		redacted, err := RedactSecrets(manifest, secrets)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"pipelines":[{"name":"os","stages":[{"type":"org.osbuild.test","options":{"a":"${secret:os.secret-a}","b":"${secret:os.secret-a}"}}]}]}`, string(redacted))
+	}
+}
+
+func TestRedactSecretsInvalidID(t *testing.T) {
+	_, err := RedactSecrets(OSBuildManifest(`{}`), Secrets{"my secret": "value"})
+	assert.EqualError(t, err, `invalid secret ID "my secret"`)
+}
+
+func TestSecretEnvName(t *testing.T) {
+	assert.Equal(t, "OS_USER_PASSWORD_ADMIN", SecretEnvName("os.user-password-admin"))
+}
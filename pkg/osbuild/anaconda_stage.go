@@ -15,15 +15,28 @@ func NewAnacondaStage(options *AnacondaStageOptions) *Stage {
 	}
 }
 
-func NewAnacondaStageOptions(additionalModules []string) *AnacondaStageOptions {
-	modules := []string{
+// NewAnacondaStageOptions returns the options to enable the default kickstart
+// modules and additionalModules, except for the ones in disabledModules.
+func NewAnacondaStageOptions(additionalModules, disabledModules []string) *AnacondaStageOptions {
+	defaultModules := []string{
 		"org.fedoraproject.Anaconda.Modules.Network",
 		"org.fedoraproject.Anaconda.Modules.Payloads",
 		"org.fedoraproject.Anaconda.Modules.Storage",
 	}
 
-	if len(additionalModules) > 0 {
-		modules = append(modules, additionalModules...)
+	// skip disabled and duplicate modules
+	skip := make(map[string]bool)
+	for _, module := range disabledModules {
+		skip[module] = true
+	}
+
+	modules := []string{}
+	for _, module := range append(defaultModules, additionalModules...) {
+		if skip[module] {
+			continue
+		}
+		modules = append(modules, module)
+		skip[module] = true
 	}
 
 	return &AnacondaStageOptions{
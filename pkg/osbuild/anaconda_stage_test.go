@@ -0,0 +1,26 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAnacondaStageOptions(t *testing.T) {
+	options := NewAnacondaStageOptions(
+		[]string{"org.fedoraproject.Anaconda.Modules.Users", "org.fedoraproject.Anaconda.Modules.Storage"},
+		[]string{"org.fedoraproject.Anaconda.Modules.Network"},
+	)
+	assert.Equal(t, []string{
+		"org.fedoraproject.Anaconda.Modules.Payloads",
+		"org.fedoraproject.Anaconda.Modules.Storage",
+		"org.fedoraproject.Anaconda.Modules.Users",
+	}, options.KickstartModules)
+
+	options = NewAnacondaStageOptions(nil, nil)
+	assert.Equal(t, []string{
+		"org.fedoraproject.Anaconda.Modules.Network",
+		"org.fedoraproject.Anaconda.Modules.Payloads",
+		"org.fedoraproject.Anaconda.Modules.Storage",
+	}, options.KickstartModules)
+}
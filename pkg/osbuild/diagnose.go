@@ -0,0 +1,290 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// number of lines at the end of the output of the failed stage that are
+// part of a diagnosis
+const diagnosisLogLines = 20
+
+// FailureClass is the kind of problem that made a build fail
+type FailureClass string
+
+const (
+	FailureUnknown        FailureClass = "unknown"
+	FailureValidation     FailureClass = "validation"
+	FailureDepsolve       FailureClass = "depsolve"
+	FailureGPG            FailureClass = "gpg"
+	FailureChecksum       FailureClass = "checksum"
+	FailureNoSpace        FailureClass = "no-space"
+	FailureSELinux        FailureClass = "selinux"
+	FailureMissingPackage FailureClass = "missing-package"
+)
+
+type failureRule struct {
+	class   FailureClass
+	pattern *regexp.Regexp
+	hint    string
+}
+
+// failureRules are tried in order on the output of the failed stage, the
+// first match classifies the failure. Running out of space is checked first
+// since it makes all kinds of tools fail.
+var failureRules = []failureRule{
+	{
+		FailureNoSpace,
+		regexp.MustCompile(`(?i)no space left on device|ENOSPC|not enough free space|needs \d+(\.\d+)?\s*[KMG]i?B more space|Disk Requirements`),
+		"the build ran out of disk space: check the free space of the osbuild store and the sizes of the filesystems of the image",
+	},
+	{
+		FailureGPG,
+		regexp.MustCompile(`(?i)GPG check FAILED|public key for \S+ is not installed|NOKEY|signature.*(BAD|not OK)|gpg: .*(failed|error)|GPG key .* (is already installed|failed)`),
+		"a package signature could not be verified: check the GPG keys of the repositories",
+	},
+	{
+		FailureChecksum,
+		regexp.MustCompile(`(?i)checksum (mismatch|error|doesn't match|does not match)|does not match (the )?(expected )?checksum|hash mismatch|digest mismatch`),
+		"a downloaded file doesn't match its checksum: the repository metadata may be outdated, depsolve again",
+	},
+	{
+		FailureMissingPackage,
+		regexp.MustCompile(`(?i)No match for argument|Unable to find a match|No package \S+ available|package \S+ (is not available|does not exist)`),
+		"a package is not available in the repositories: check the package names and the enabled repositories",
+	},
+	{
+		FailureDepsolve,
+		regexp.MustCompile(`(?i)depsolve|nothing provides|conflicts with (file from )?package|cannot install both|none of the providers can be installed|Transaction (test|check) error`),
+		"the package dependencies could not be resolved: check the packages and repositories for conflicts",
+	},
+	{
+		FailureSELinux,
+		regexp.MustCompile(`(?i)setfiles|restorecon|avc: +denied|invalid (security )?context|file_contexts?\b|semodule|semanage`),
+		"SELinux labelling or policy configuration failed: check the SELinux customizations and the policy of the image",
+	},
+}
+
+// A Diagnosis pinpoints why a build failed
+type Diagnosis struct {
+	// Failed pipeline, empty if the build failed before running the
+	// pipelines, e.g. when downloading the sources
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// Failed stage, StageNumber counts from 1 and StageCount is the number
+	// of stages of the pipeline in the manifest; both are 0 if unknown
+	StageID     string `json:"stage_id,omitempty"`
+	StageType   string `json:"stage_type,omitempty"`
+	StageNumber int    `json:"stage_number,omitempty"`
+	StageCount  int    `json:"stage_count,omitempty"`
+
+	Class FailureClass `json:"class"`
+	Hint  string       `json:"hint,omitempty"`
+
+	// The line of the output that classified the failure and the end of
+	// the output of the failed stage
+	Match string   `json:"match,omitempty"`
+	Log   []string `json:"log,omitempty"`
+
+	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+}
+
+// diagnosisManifest is the description of a manifest printed by osbuild
+// --inspect, which has the IDs of the stages
+type diagnosisManifest struct {
+	Pipelines []struct {
+		Name   string `json:"name"`
+		Stages []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"stages"`
+	} `json:"pipelines"`
+}
+
+type resultError struct {
+	Type    string `json:"type"`
+	Details struct {
+		Stage *StageResult `json:"stage"`
+	} `json:"details"`
+}
+
+// DiagnoseResult analyzes the result of a failed build. The manifest is
+// optional, it's used to order the pipelines and to locate the failed stage
+// in its pipeline by its ID, so it must be the output of InspectManifest. The
+// log of the result can't be used to locate the stage since it lacks the
+// stages that were skipped because their tree was cached. The diagnosis is
+// nil for successful builds.
+func DiagnoseResult(res *Result, manifest []byte) (*Diagnosis, error) {
+	if res == nil || res.Success {
+		return nil, nil
+	}
+
+	var mf diagnosisManifest
+	if len(manifest) > 0 {
+		if err := json.Unmarshal(manifest, &mf); err != nil {
+			return nil, fmt.Errorf("cannot decode manifest: %w", err)
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		return &Diagnosis{
+			Class:            FailureValidation,
+			Hint:             "osbuild rejected the manifest: " + res.Title,
+			ValidationErrors: res.Errors,
+		}, nil
+	}
+
+	d := &Diagnosis{Class: FailureUnknown}
+
+	// pipelines in the order of the manifest, the ones that are only in the
+	// result are sorted by name
+	var pipelineNames []string
+	seen := make(map[string]bool)
+	for _, pipeline := range mf.Pipelines {
+		pipelineNames = append(pipelineNames, pipeline.Name)
+		seen[pipeline.Name] = true
+	}
+	var extraNames []string
+	for name := range res.Log {
+		if !seen[name] {
+			extraNames = append(extraNames, name)
+		}
+	}
+	sort.Strings(extraNames)
+	pipelineNames = append(pipelineNames, extraNames...)
+
+	var failed *StageResult
+	for _, name := range pipelineNames {
+		for idx := range res.Log[name] {
+			stage := res.Log[name][idx]
+			if stage.Success {
+				continue
+			}
+			failed = &stage
+			d.Pipeline = name
+			break
+		}
+		if failed != nil {
+			break
+		}
+	}
+
+	// the error of the result may describe the failed stage or a failure
+	// outside of the pipelines
+	var resErr resultError
+	if len(res.Error) > 0 {
+		_ = json.Unmarshal(res.Error, &resErr)
+	}
+	if failed == nil && resErr.Details.Stage != nil {
+		failed = resErr.Details.Stage
+	}
+
+	var output string
+	if failed != nil {
+		d.StageID = failed.ID
+		d.StageType = failed.Type
+		output = failed.Output
+	} else if len(res.Error) > 0 {
+		output = string(res.Error)
+	}
+
+	if d.StageID != "" {
+		for _, pipeline := range mf.Pipelines {
+			for idx, stage := range pipeline.Stages {
+				if stage.ID != d.StageID {
+					continue
+				}
+				d.Pipeline = pipeline.Name
+				d.StageNumber = idx + 1
+				d.StageCount = len(pipeline.Stages)
+				if d.StageType == "" {
+					d.StageType = stage.Type
+				}
+			}
+		}
+	}
+
+	d.Log = logTail(output, diagnosisLogLines)
+	classifyFailure(d, output)
+	if d.Class == FailureUnknown && d.StageType == "org.osbuild.selinux" {
+		classifyFailureAs(d, FailureSELinux, "")
+	}
+	if d.Class == FailureUnknown && resErr.Type != "" && failed == nil {
+		d.Hint = fmt.Sprintf("osbuild failed with %s", resErr.Type)
+	}
+
+	return d, nil
+}
+
+func classifyFailure(d *Diagnosis, output string) {
+	lines := strings.Split(output, "\n")
+	for _, rule := range failureRules {
+		for _, line := range lines {
+			if rule.pattern.MatchString(line) {
+				classifyFailureAs(d, rule.class, strings.TrimSpace(line))
+				return
+			}
+		}
+	}
+}
+
+func classifyFailureAs(d *Diagnosis, class FailureClass, match string) {
+	d.Class = class
+	d.Match = match
+	for _, rule := range failureRules {
+		if rule.class == class {
+			d.Hint = rule.hint
+		}
+	}
+}
+
+func logTail(output string, n int) []string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// Write prints a human readable report of the diagnosis.
+func (d *Diagnosis) Write(w io.Writer) error {
+	var b strings.Builder
+
+	switch {
+	case d.Pipeline != "" && d.StageCount > 0:
+		fmt.Fprintf(&b, "Build failed in stage %d of %d (%s) of pipeline %s\n", d.StageNumber, d.StageCount, d.StageType, d.Pipeline)
+	case d.Pipeline != "":
+		fmt.Fprintf(&b, "Build failed in stage %s of pipeline %s\n", d.StageType, d.Pipeline)
+	case d.StageType != "":
+		fmt.Fprintf(&b, "Build failed in stage %s\n", d.StageType)
+	default:
+		b.WriteString("Build failed\n")
+	}
+
+	fmt.Fprintf(&b, "Cause: %s\n", d.Class)
+	if d.Hint != "" {
+		fmt.Fprintf(&b, "Hint: %s\n", d.Hint)
+	}
+	if d.Match != "" {
+		fmt.Fprintf(&b, "Error: %s\n", d.Match)
+	}
+	for _, e := range d.ValidationErrors {
+		fmt.Fprintf(&b, "%s: %s\n", strings.Join(e.Path, "."), e.Message)
+	}
+	if len(d.Log) > 0 {
+		b.WriteString("Log:\n")
+		for _, line := range d.Log {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,184 @@
+package osbuild
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the manifest as described by osbuild --inspect
+const diagnoseTestManifest = `{
+  "version": "2",
+  "pipelines": [
+    {"name": "build", "stages": [
+      {"id": "56a93713050f49c966eda0391dce1340d16f168bcbfd542d9d90be668ecc8268", "type": "org.osbuild.rpm"},
+      {"id": "cac48f998b87f9c9007037f48202bea9ef7966eacdaaf35f8e9da4b543cfa7fb", "type": "org.osbuild.selinux"}
+    ]},
+    {"name": "ostree-tree", "stages": [
+      {"id": "52f9740ad68953831b503edbcdf2c54eb3eab87efa7dacedabe3ab83b2db708a", "type": "org.osbuild.rpm"},
+      {"id": "fb5e7b93a3eba924a02a89043554641b022abcdaf07eb933da24813277a93636", "type": "org.osbuild.locale"},
+      {"id": "52bb78797b3fc3c05d4d538f1b1362648f232b149cf7bf73edda582754167a7f", "type": "org.osbuild.timezone"},
+      {"id": "b63e0b7baa7b0acd794ee3d2f92728f6ef45bde203fbffba3364b17499aab63f", "type": "org.osbuild.systemd"},
+      {"id": "147fe506d915edb9e0eb8fdb88adb43c8603125f455f47d0228bca935bb997f6", "type": "org.osbuild.selinux"},
+      {"id": "0b2ae3fdc2d4a42df0b9a4cc8c1d8b3e7b5fe8a7e0d0f5a4e9e2e8f8d0c3f2b1", "type": "org.osbuild.ostree.preptree"}
+    ]}
+  ]
+}`
+
+func TestDiagnoseResultSuccess(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v2ResultSuccess), &result))
+
+	d, err := DiagnoseResult(&result, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+}
+
+func TestDiagnoseResultV2Failure(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v2ResultFailure), &result))
+
+	d, err := DiagnoseResult(&result, []byte(diagnoseTestManifest))
+	require.NoError(t, err)
+	require.NotNil(t, d)
+
+	assert.Equal(t, "ostree-tree", d.Pipeline)
+	assert.Equal(t, "147fe506d915edb9e0eb8fdb88adb43c8603125f455f47d0228bca935bb997f6", d.StageID)
+	assert.Equal(t, "org.osbuild.selinux", d.StageType)
+	assert.Equal(t, 5, d.StageNumber)
+	assert.Equal(t, 6, d.StageCount)
+	assert.Equal(t, FailureSELinux, d.Class)
+	assert.Equal(t, "/run/osbuild/tree/etc/selinux/targeted/contexts/files/file_context: No such file or directory", d.Match)
+	assert.Len(t, d.Log, 12)
+	assert.Contains(t, d.Log[len(d.Log)-1], "returned non-zero exit status 255.")
+
+	var buf bytes.Buffer
+	require.NoError(t, d.Write(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), `Build failed in stage 5 of 6 (org.osbuild.selinux) of pipeline ostree-tree
+Cause: selinux
+Hint: SELinux labelling`), buf.String())
+
+	// without the manifest the pipelines are searched in order of their
+	// names and the position of the stage is unknown
+	d, err = DiagnoseResult(&result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ostree-tree", d.Pipeline)
+	assert.Equal(t, "org.osbuild.selinux", d.StageType)
+	assert.Equal(t, 0, d.StageNumber)
+	assert.Equal(t, 0, d.StageCount)
+}
+
+func TestDiagnoseResultCachedStages(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(v2ResultFailure), &result))
+	// the first stages of the pipeline were skipped since their tree was
+	// cached, so the failed stage is the second one of the log
+	result.Log["ostree-tree"] = result.Log["ostree-tree"][3:]
+
+	d, err := DiagnoseResult(&result, []byte(diagnoseTestManifest))
+	require.NoError(t, err)
+	assert.Equal(t, "ostree-tree", d.Pipeline)
+	assert.Equal(t, "org.osbuild.selinux", d.StageType)
+	assert.Equal(t, 5, d.StageNumber)
+	assert.Equal(t, 6, d.StageCount)
+}
+
+func TestDiagnoseResultValidation(t *testing.T) {
+	var result Result
+	require.NoError(t, json.Unmarshal([]byte(validationResultFailure), &result))
+
+	d, err := DiagnoseResult(&result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, FailureValidation, d.Class)
+	assert.Equal(t, "osbuild rejected the manifest: JSON Schema validation failed", d.Hint)
+	assert.Len(t, d.ValidationErrors, 2)
+
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"class":"validation"`)
+	assert.Contains(t, string(data), `"validation_errors":[`)
+}
+
+func TestDiagnoseResultSourceError(t *testing.T) {
+	result := Result{
+		Success: false,
+		Error:   json.RawMessage(`{"type": "org.osbuild.error.download", "details": "curl: (22) hash mismatch for sha256:0123"}`),
+	}
+
+	d, err := DiagnoseResult(&result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", d.Pipeline)
+	assert.Equal(t, FailureChecksum, d.Class)
+	assert.Contains(t, d.Match, "hash mismatch")
+
+	var buf bytes.Buffer
+	require.NoError(t, d.Write(&buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "Build failed\nCause: checksum\n"), buf.String())
+}
+
+func TestDiagnoseResultInvalidManifest(t *testing.T) {
+	_, err := DiagnoseResult(&Result{}, []byte("{"))
+	assert.ErrorContains(t, err, "cannot decode manifest")
+}
+
+func TestDiagnoseResultClasses(t *testing.T) {
+	tests := []struct {
+		stageType string
+		output    string
+		class     FailureClass
+		match     string
+	}{
+		{"org.osbuild.rpm", "installing\nerror: unpacking of archive failed: cpio: write failed - No space left on device\n", FailureNoSpace, "error: unpacking of archive failed: cpio: write failed - No space left on device"},
+		{"org.osbuild.rpm", "warning: /tmp/pkg.rpm: Header V4 RSA/SHA256 Signature, key ID fd431d51: NOKEY\n", FailureGPG, "warning: /tmp/pkg.rpm: Header V4 RSA/SHA256 Signature, key ID fd431d51: NOKEY"},
+		{"org.osbuild.rpm", "Public key for pkg1.rpm is not installed\n", FailureGPG, "Public key for pkg1.rpm is not installed"},
+		{"org.osbuild.copy", "checksum mismatch: sha256:0123\n", FailureChecksum, "checksum mismatch: sha256:0123"},
+		{"org.osbuild.dnf", "No match for argument: pkg3\n", FailureMissingPackage, "No match for argument: pkg3"},
+		{"org.osbuild.dnf", "Problem: package pkg1 requires pkg2, but nothing provides pkg2\n", FailureDepsolve, "Problem: package pkg1 requires pkg2, but nothing provides pkg2"},
+		{"org.osbuild.selinux", "Traceback (most recent call last):\n", FailureSELinux, ""},
+		{"org.osbuild.grub2", "Traceback (most recent call last):\n", FailureUnknown, ""},
+	}
+
+	for idx, tt := range tests {
+		t.Run(fmt.Sprintf("%d-%s", idx, tt.class), func(t *testing.T) {
+			result := Result{
+				Log: map[string]PipelineResult{
+					"os": {
+						{ID: "s1", Type: "org.osbuild.locale", Success: true},
+						{ID: "s2", Type: tt.stageType, Output: tt.output, Success: false},
+					},
+				},
+			}
+			d, err := DiagnoseResult(&result, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "os", d.Pipeline)
+			assert.Equal(t, "s2", d.StageID)
+			assert.Equal(t, tt.class, d.Class)
+			assert.Equal(t, tt.match, d.Match)
+			if tt.class == FailureUnknown {
+				assert.Empty(t, d.Hint)
+			} else {
+				assert.NotEmpty(t, d.Hint)
+			}
+		})
+	}
+}
+
+func TestDiagnoseResultLogTail(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	result := Result{
+		Log: map[string]PipelineResult{
+			"os": {{ID: "s1", Type: "org.osbuild.rpm", Output: strings.Join(lines, "\n") + "\n"}},
+		},
+	}
+	d, err := DiagnoseResult(&result, nil)
+	require.NoError(t, err)
+	assert.Equal(t, lines[30:], d.Log)
+}
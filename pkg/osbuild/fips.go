@@ -0,0 +1,53 @@
+package osbuild
+
+import (
+	"github.com/osbuild/images/pkg/disk"
+)
+
+const (
+	// FIPSDracutConfFilename is the dracut configuration file that adds the
+	// fips module to every initramfs created in the image
+	FIPSDracutConfFilename = "40-fips.conf"
+
+	fipsCryptoPolicy = "FIPS"
+)
+
+// GenFIPSKernelOptions returns the kernel command line options that enable
+// FIPS mode. The integrity check of the kernel in the initramfs needs to
+// find the kernel, so the /boot filesystem is passed when it is separate from
+// the root filesystem.
+func GenFIPSKernelOptions(pt *disk.PartitionTable) []string {
+	cmdline := []string{"fips=1"}
+	if pt == nil {
+		return cmdline
+	}
+	if bootMnt := pt.FindMountable("/boot"); bootMnt != nil {
+		cmdline = append(cmdline, "boot=UUID="+bootMnt.GetFSSpec().UUID)
+	}
+	return cmdline
+}
+
+// GenFIPSStages returns the stages that set up a tree for FIPS mode: the FIPS
+// crypto policy and the fips dracut module. If kernelVer is not empty, the
+// initramfs of the kernel, which was created when the kernel was installed,
+// is recreated with the fips module.
+func GenFIPSStages(kernelVer string) []*Stage {
+	stages := []*Stage{
+		NewUpdateCryptoPoliciesStage(&UpdateCryptoPoliciesStageOptions{
+			Policy: fipsCryptoPolicy,
+		}),
+		NewDracutConfStage(&DracutConfStageOptions{
+			Filename: FIPSDracutConfFilename,
+			Config: DracutConfigFile{
+				AddModules: []string{"fips"},
+			},
+		}),
+	}
+	if kernelVer != "" {
+		stages = append(stages, NewDracutStage(&DracutStageOptions{
+			Kernel:     []string{kernelVer},
+			AddModules: []string{"fips"},
+		}))
+	}
+	return stages
+}
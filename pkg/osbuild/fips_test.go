@@ -0,0 +1,47 @@
+package osbuild
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/blueprint"
+	"github.com/osbuild/images/pkg/disk"
+)
+
+func TestGenFIPSKernelOptions(t *testing.T) {
+	// math/rand is good enough in this case
+	/* #nosec G404 */
+	rng := rand.New(rand.NewSource(13))
+
+	plain := testPartitionTables["plain"]
+	pt, err := disk.NewPartitionTable(&plain, []blueprint.FilesystemCustomization{}, 0, disk.RawPartitioningMode, nil, rng)
+	require.NoError(t, err)
+	bootUUID := pt.FindMountable("/boot").GetFSSpec().UUID
+	require.NotEmpty(t, bootUUID)
+
+	assert.Equal(t, []string{"fips=1", "boot=UUID=" + bootUUID}, GenFIPSKernelOptions(pt))
+	assert.Equal(t, []string{"fips=1"}, GenFIPSKernelOptions(nil))
+}
+
+func TestGenFIPSStages(t *testing.T) {
+	stages := GenFIPSStages("")
+	require.Len(t, stages, 2)
+	assert.Equal(t, "org.osbuild.update-crypto-policies", stages[0].Type)
+	assert.Equal(t, &UpdateCryptoPoliciesStageOptions{Policy: "FIPS"}, stages[0].Options)
+	assert.Equal(t, "org.osbuild.dracut.conf", stages[1].Type)
+	assert.Equal(t, &DracutConfStageOptions{
+		Filename: FIPSDracutConfFilename,
+		Config:   DracutConfigFile{AddModules: []string{"fips"}},
+	}, stages[1].Options)
+
+	stages = GenFIPSStages("5.14.0-1.el9")
+	require.Len(t, stages, 3)
+	assert.Equal(t, "org.osbuild.dracut", stages[2].Type)
+	assert.Equal(t, &DracutStageOptions{
+		Kernel:     []string{"5.14.0-1.el9"},
+		AddModules: []string{"fips"},
+	}, stages[2].Options)
+}
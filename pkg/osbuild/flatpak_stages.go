@@ -0,0 +1,53 @@
+package osbuild
+
+import (
+	"github.com/osbuild/images/pkg/flatpak"
+)
+
+// GenFlatpakStages returns the stages that store the commits of the flatpaks
+// in the repository of the system installation and configure their remotes
+// there. The flatpaks are deployed from the repository on first boot, see
+// flatpak.InstallFiles.
+func GenFlatpakStages(flatpaks []flatpak.Spec) []*Stage {
+	remotes := flatpak.Remotes(flatpaks)
+
+	options := &OSTreeRemotesStageOptions{Repo: flatpak.RepoPath}
+	for _, remote := range remotes {
+		r := OSTreeRemote{
+			Name: remote.Name,
+			URL:  remote.URL,
+		}
+		if remote.GPGKey != "" {
+			r.GPGKeys = []string{remote.GPGKey}
+		}
+		options.Remotes = append(options.Remotes, r)
+	}
+
+	stages := []*Stage{
+		NewOSTreeInitStage(&OSTreeInitStageOptions{
+			Mode: ModeBareUserOnly,
+			Path: flatpak.RepoPath,
+		}),
+		NewOSTreeRemotesStage(options),
+	}
+
+	// the pull stage creates the refs of all its commits for a single
+	// remote
+	for _, remote := range remotes {
+		input := new(OSTreePullStageInput)
+		input.Type = "org.osbuild.ostree"
+		input.Origin = "org.osbuild.source"
+		input.References = make(OSTreePullStageReferences)
+		for _, fp := range flatpaks {
+			if fp.Remote.Name == remote.Name {
+				input.References[fp.Checksum] = OSTreePullStageReference{Ref: fp.Ref}
+			}
+		}
+		stages = append(stages, NewOSTreePullStage(
+			&OSTreePullStageOptions{Repo: flatpak.RepoPath, Remote: remote.Name},
+			&OSTreePullStageInputs{Commits: input},
+		))
+	}
+
+	return stages
+}
@@ -0,0 +1,87 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/flatpak"
+)
+
+func TestGenFlatpakStages(t *testing.T) {
+	flathub := flatpak.Remote{
+		Name:   "flathub",
+		URL:    "https://dl.flathub.org/repo/",
+		GPGKey: "-----BEGIN PGP PUBLIC KEY BLOCK-----",
+	}
+	fedora := flatpak.Remote{
+		Name: "fedora",
+		URL:  "https://registry.example.org/flatpak/",
+	}
+	flatpaks := []flatpak.Spec{
+		{
+			Remote:   flathub,
+			Ref:      "app/org.gnome.Calculator/x86_64/stable",
+			Checksum: "8b4e5e2b4a1c4d7b5f0a3c6e9d2b1a0f7e6d5c4b3a2918e7d6c5b4a39281706f",
+		},
+		{
+			Remote:   fedora,
+			Ref:      "app/org.gnome.Maps/x86_64/stable",
+			Checksum: "0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0",
+		},
+		{
+			Remote:   flathub,
+			Ref:      "runtime/org.gnome.Platform/x86_64/45",
+			Checksum: "1f2e3d4c5b6a79887766554433221100ffeeddccbbaa99887766554433221100",
+		},
+	}
+
+	data, err := json.Marshal(GenFlatpakStages(flatpaks))
+	require.NoError(t, err)
+	expected := `[
+		{
+			"type": "org.osbuild.ostree.init",
+			"options": {"mode": "bare-user-only", "path": "/var/lib/flatpak/repo"}
+		},
+		{
+			"type": "org.osbuild.ostree.remotes",
+			"options": {
+				"repo": "/var/lib/flatpak/repo",
+				"remotes": [
+					{"name": "flathub", "url": "https://dl.flathub.org/repo/", "gpgkeys": ["-----BEGIN PGP PUBLIC KEY BLOCK-----"]},
+					{"name": "fedora", "url": "https://registry.example.org/flatpak/"}
+				]
+			}
+		},
+		{
+			"type": "org.osbuild.ostree.pull",
+			"inputs": {
+				"commits": {
+					"type": "org.osbuild.ostree",
+					"origin": "org.osbuild.source",
+					"references": {
+						"8b4e5e2b4a1c4d7b5f0a3c6e9d2b1a0f7e6d5c4b3a2918e7d6c5b4a39281706f": {"ref": "app/org.gnome.Calculator/x86_64/stable"},
+						"1f2e3d4c5b6a79887766554433221100ffeeddccbbaa99887766554433221100": {"ref": "runtime/org.gnome.Platform/x86_64/45"}
+					}
+				}
+			},
+			"options": {"repo": "/var/lib/flatpak/repo", "remote": "flathub"}
+		},
+		{
+			"type": "org.osbuild.ostree.pull",
+			"inputs": {
+				"commits": {
+					"type": "org.osbuild.ostree",
+					"origin": "org.osbuild.source",
+					"references": {
+						"0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0": {"ref": "app/org.gnome.Maps/x86_64/stable"}
+					}
+				}
+			},
+			"options": {"repo": "/var/lib/flatpak/repo", "remote": "fedora"}
+		}
+	]`
+	assert.JSONEq(t, expected, string(data))
+}
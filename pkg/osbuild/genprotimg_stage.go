@@ -0,0 +1,33 @@
+package osbuild
+
+// Create an IBM Secure Execution boot image from a kernel, initrd and kernel
+// command line
+
+// Options for the org.osbuild.genprotimg stage. All paths are relative to the
+// root of the tree.
+type GenprotimgStageOptions struct {
+	Kernel string `json:"kernel"`
+	Initrd string `json:"initrd"`
+
+	// Kernel command line sealed in the image
+	Cmdline string `json:"cmdline"`
+
+	// Host key documents to encrypt the image for
+	HostKeys []string `json:"host-keys"`
+
+	// Path of the Secure Execution image to create
+	Output string `json:"output"`
+
+	// Don't verify the host key documents against the IBM signing keys
+	NoVerify bool `json:"no-verify,omitempty"`
+}
+
+func (GenprotimgStageOptions) isStageOptions() {}
+
+// NewGenprotimgStage creates a new org.osbuild.genprotimg stage
+func NewGenprotimgStage(options *GenprotimgStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.genprotimg",
+		Options: options,
+	}
+}
@@ -0,0 +1,35 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGenprotimgStage(t *testing.T) {
+	stage := NewGenprotimgStage(&GenprotimgStageOptions{
+		Kernel:   "/boot/vmlinuz-5.14.0-362.el9.s390x",
+		Initrd:   "/boot/initramfs-5.14.0-362.el9.s390x.img",
+		Cmdline:  "root=UUID=6e4ff95f-f662-45ee-a82a-bdf44a2d0b75 console=ttysclp0",
+		HostKeys: []string{"/etc/se-hostkeys/hostkey-1.crt"},
+		Output:   "/boot/secure-image",
+		NoVerify: true,
+	})
+
+	data, err := json.Marshal(stage)
+	require.NoError(t, err)
+	expected := `{
+		"type": "org.osbuild.genprotimg",
+		"options": {
+			"kernel": "/boot/vmlinuz-5.14.0-362.el9.s390x",
+			"initrd": "/boot/initramfs-5.14.0-362.el9.s390x.img",
+			"cmdline": "root=UUID=6e4ff95f-f662-45ee-a82a-bdf44a2d0b75 console=ttysclp0",
+			"host-keys": ["/etc/se-hostkeys/hostkey-1.crt"],
+			"output": "/boot/secure-image",
+			"no-verify": true
+		}
+	}`
+	assert.JSONEq(t, expected, string(data))
+}
@@ -0,0 +1,299 @@
+package osbuild
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// monitorRecordSeparator starts every record of the JSON-seq output of
+// osbuild's JSONSeqMonitor (RFC 7464), a line feed ends it
+const monitorRecordSeparator = '\x1e'
+
+// monitorOrigin is the origin of the messages of the monitor itself, as
+// opposed to the output of the stages
+const monitorOrigin = "osbuild.monitor"
+
+type MonitorEventType string
+
+const (
+	MonitorPipelineStarted  MonitorEventType = "pipeline-started"
+	MonitorPipelineFinished MonitorEventType = "pipeline-finished"
+	MonitorStageStarted     MonitorEventType = "stage-started"
+	MonitorStageFinished    MonitorEventType = "stage-finished"
+	MonitorLog              MonitorEventType = "log"
+)
+
+// A MonitorEvent reports the progress of an osbuild run
+type MonitorEvent struct {
+	Type      MonitorEventType
+	Timestamp time.Time
+
+	// Name of the pipeline, empty while the sources are downloaded
+	Pipeline string
+
+	// Type and ID of the stage, empty for pipeline events
+	Stage   string
+	StageID string
+
+	// Duration and outcome of the pipeline or stage, only set for the
+	// finished events
+	Duration time.Duration
+	Success  bool
+
+	// Line of output for log events
+	Message string
+
+	// Overall progress, nil if osbuild didn't report it
+	Progress *MonitorProgress
+}
+
+// MonitorProgress counts the steps of the build, e.g. the pipelines, and of
+// the current step, e.g. the stages of the current pipeline.
+type MonitorProgress struct {
+	Name        string           `json:"name"`
+	Done        int              `json:"done"`
+	Total       int              `json:"total"`
+	SubProgress *MonitorProgress `json:"progress,omitempty"`
+}
+
+// Fraction returns the completed fraction of the build between 0 and 1,
+// including the progress of the current step.
+func (p *MonitorProgress) Fraction() float64 {
+	if p == nil || p.Total <= 0 {
+		return 0
+	}
+	done := float64(p.Done)
+	if sub := p.SubProgress.Fraction(); sub > 0 {
+		done += sub
+	}
+	return math.Min(done/float64(p.Total), 1)
+}
+
+type monitorContext struct {
+	ID       string `json:"id"`
+	Origin   string `json:"origin"`
+	Pipeline *struct {
+		Name  string `json:"name"`
+		ID    string `json:"id"`
+		Stage *struct {
+			Name string `json:"name"`
+			ID   string `json:"id"`
+		} `json:"stage"`
+	} `json:"pipeline"`
+}
+
+type monitorRecord struct {
+	Message   string           `json:"message"`
+	Context   *monitorContext  `json:"context"`
+	Progress  *MonitorProgress `json:"progress"`
+	Timestamp float64          `json:"timestamp"`
+	Result    *struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Success bool   `json:"success"`
+	} `json:"result"`
+}
+
+// monitorParser turns the records of the monitor into events. osbuild only
+// sends the full context when it changes, otherwise only its ID, so the
+// parser keeps track of the contexts and of the current pipeline and stage.
+type monitorParser struct {
+	contexts map[string]*monitorContext
+
+	pipeline        string
+	pipelineStart   time.Time
+	pipelineSuccess bool
+
+	stage      string
+	stageID    string
+	stageStart time.Time
+	stageDone  bool
+
+	last time.Time
+}
+
+func newMonitorParser() *monitorParser {
+	return &monitorParser{
+		contexts: make(map[string]*monitorContext),
+	}
+}
+
+func (p *monitorParser) parse(data []byte) ([]MonitorEvent, error) {
+	var record monitorRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("cannot decode osbuild monitor record: %w", err)
+	}
+
+	ts := p.last
+	if record.Timestamp > 0 {
+		sec, frac := math.Modf(record.Timestamp)
+		ts = time.Unix(int64(sec), int64(frac*1e9))
+	}
+	p.last = ts
+
+	ctx := record.Context
+	if ctx != nil {
+		if ctx.Origin == "" && ctx.Pipeline == nil {
+			// only the ID of a context that was sent before
+			if known, ok := p.contexts[ctx.ID]; ok {
+				ctx = known
+			}
+		} else if ctx.ID != "" {
+			p.contexts[ctx.ID] = ctx
+		}
+	}
+
+	var events []MonitorEvent
+	newEvent := func(eventType MonitorEventType) MonitorEvent {
+		return MonitorEvent{
+			Type:      eventType,
+			Timestamp: ts,
+			Pipeline:  p.pipeline,
+			Stage:     p.stage,
+			StageID:   p.stageID,
+			Progress:  record.Progress,
+		}
+	}
+
+	var pipeline, stage, stageID string
+	if ctx != nil && ctx.Pipeline != nil {
+		pipeline = ctx.Pipeline.Name
+		if ctx.Pipeline.Stage != nil {
+			stage = ctx.Pipeline.Stage.Name
+			stageID = ctx.Pipeline.Stage.ID
+		}
+	}
+
+	if pipeline != "" && pipeline != p.pipeline {
+		events = append(events, p.finishPipeline(ts)...)
+		p.pipeline = pipeline
+		p.pipelineStart = ts
+		p.pipelineSuccess = true
+		events = append(events, newEvent(MonitorPipelineStarted))
+	}
+
+	if stageID != "" && stageID != p.stageID {
+		events = append(events, p.finishStage(ts, true)...)
+		p.stage = stage
+		p.stageID = stageID
+		p.stageStart = ts
+		p.stageDone = false
+		events = append(events, newEvent(MonitorStageStarted))
+	}
+
+	if result := record.Result; result != nil {
+		if result.ID == p.stageID || result.ID == "" {
+			events = append(events, p.finishStage(ts, result.Success)...)
+		}
+		return events, nil
+	}
+
+	if record.Message != "" && (ctx == nil || ctx.Origin != monitorOrigin) {
+		event := newEvent(MonitorLog)
+		event.Message = record.Message
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (p *monitorParser) finishStage(ts time.Time, success bool) []MonitorEvent {
+	if p.stageID == "" || p.stageDone {
+		return nil
+	}
+	p.stageDone = true
+	if !success {
+		p.pipelineSuccess = false
+	}
+	return []MonitorEvent{{
+		Type:      MonitorStageFinished,
+		Timestamp: ts,
+		Pipeline:  p.pipeline,
+		Stage:     p.stage,
+		StageID:   p.stageID,
+		Duration:  ts.Sub(p.stageStart),
+		Success:   success,
+	}}
+}
+
+func (p *monitorParser) finishPipeline(ts time.Time) []MonitorEvent {
+	if p.pipeline == "" {
+		return nil
+	}
+	events := p.finishStage(ts, true)
+	events = append(events, MonitorEvent{
+		Type:      MonitorPipelineFinished,
+		Timestamp: ts,
+		Pipeline:  p.pipeline,
+		Duration:  ts.Sub(p.pipelineStart),
+		Success:   p.pipelineSuccess,
+	})
+	p.pipeline = ""
+	p.stage = ""
+	p.stageID = ""
+	return events
+}
+
+// splitMonitorRecords splits the JSON-seq output into records, which start
+// with a record separator and end with a line feed (RFC 7464). A record is
+// complete as soon as its line feed is read, so it's not held back until the
+// next record starts.
+func splitMonitorRecords(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && (data[start] == monitorRecordSeparator || data[start] == '\n') {
+		start++
+	}
+	if idx := bytes.IndexByte(data[start:], '\n'); idx >= 0 {
+		record := data[start : start+idx]
+		if rs := bytes.IndexByte(record, monitorRecordSeparator); rs >= 0 {
+			// the record before the separator is truncated, skip it
+			return start + rs, nil, nil
+		}
+		return start + idx + 1, bytes.TrimSpace(record), nil
+	}
+	if atEOF {
+		if record := bytes.TrimSpace(data[start:]); len(record) > 0 {
+			return len(data), record, nil
+		}
+		return len(data), nil, nil
+	}
+	return start, nil, nil
+}
+
+// ReadMonitorEvents reads the JSON-seq output of osbuild's JSONSeqMonitor
+// and sends the events to the channel until the reader is exhausted. The
+// events of the last pipeline are finished at the end of the output. The
+// channel is not closed.
+func ReadMonitorEvents(r io.Reader, events chan<- MonitorEvent) error {
+	parser := newMonitorParser()
+	scanner := bufio.NewScanner(r)
+	// stages can log long lines, e.g. the output of dnf
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	scanner.Split(splitMonitorRecords)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		parsed, err := parser.parse(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+		for _, event := range parsed {
+			events <- event
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading osbuild monitor output: %w", err)
+	}
+
+	for _, event := range parser.finishPipeline(parser.last) {
+		events <- event
+	}
+	return nil
+}
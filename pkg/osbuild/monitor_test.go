@@ -0,0 +1,152 @@
+package osbuild
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monitorOutput is the JSON-seq output of a build of two pipelines, the
+// second stage of the second pipeline fails
+const monitorOutput = "\x1e" + `{"message": "Starting pipeline build", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "build", "id": "p1", "stage": {}}, "id": "c1"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 0, "progress": {"name": "pipeline: build", "total": 1, "done": 0}}, "timestamp": 100.0}
+` + "\x1e" + `{"message": "Starting module org.osbuild.rpm", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "build", "id": "p1", "stage": {"name": "org.osbuild.rpm", "id": "s1"}}, "id": "c2"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 0, "progress": {"name": "pipeline: build", "total": 1, "done": 0}}, "timestamp": 100.5}
+` + "\x1e" + `{"message": "installing pkg1", "context": {"origin": "org.osbuild", "pipeline": {"name": "build", "id": "p1", "stage": {"name": "org.osbuild.rpm", "id": "s1"}}, "id": "c3"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 0, "progress": {"name": "pipeline: build", "total": 1, "done": 0}}, "timestamp": 101.0}
+` + "\x1e" + `{"message": "installing pkg2", "context": {"id": "c3"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 0, "progress": {"name": "pipeline: build", "total": 1, "done": 0}}, "timestamp": 102.0}
+` + "\x1e" + `{"message": "Finished module org.osbuild.rpm", "result": {"id": "s1", "name": "org.osbuild.rpm", "success": true, "output": "installing pkg1\ninstalling pkg2\n"}, "context": {"id": "c2"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 0, "progress": {"name": "pipeline: build", "total": 1, "done": 1}}, "timestamp": 103.0}
+` + "\x1e" + `{"message": "Starting pipeline os", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "os", "id": "p2", "stage": {}}, "id": "c4"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 2, "done": 0}}, "timestamp": 104.0}
+` + "\x1e" + `{"message": "Starting module org.osbuild.locale", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "os", "id": "p2", "stage": {"name": "org.osbuild.locale", "id": "s2"}}, "id": "c5"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 2, "done": 0}}, "timestamp": 104.0}
+` + "\x1e" + `{"message": "Finished module org.osbuild.locale", "result": {"id": "s2", "name": "org.osbuild.locale", "success": true, "output": ""}, "context": {"id": "c5"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 2, "done": 1}}, "timestamp": 104.5}
+` + "\x1e" + `{"message": "Starting module org.osbuild.selinux", "context": {"origin": "osbuild.monitor", "pipeline": {"name": "os", "id": "p2", "stage": {"name": "org.osbuild.selinux", "id": "s3"}}, "id": "c6"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 2, "done": 1}}, "timestamp": 105.0}
+` + "\x1e" + `{"message": "setfiles: no space left on device", "context": {"origin": "org.osbuild", "pipeline": {"name": "os", "id": "p2", "stage": {"name": "org.osbuild.selinux", "id": "s3"}}, "id": "c7"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 2, "done": 1}}, "timestamp": 106.0}
+` + "\x1e" + `{"message": "Finished module org.osbuild.selinux", "result": {"id": "s3", "name": "org.osbuild.selinux", "success": false, "output": "setfiles: no space left on device\n"}, "context": {"id": "c6"}, "progress": {"name": "pipelines/sources", "total": 2, "done": 1, "progress": {"name": "pipeline: os", "total": 2, "done": 2}}, "timestamp": 107.0}
+`
+
+func readTestMonitorEvents(t *testing.T, output string) []MonitorEvent {
+	ch := make(chan MonitorEvent, 100)
+	require.NoError(t, ReadMonitorEvents(strings.NewReader(output), ch))
+	close(ch)
+
+	var events []MonitorEvent
+	for event := range ch {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestReadMonitorEvents(t *testing.T) {
+	events := readTestMonitorEvents(t, monitorOutput)
+
+	type summary struct {
+		Type     MonitorEventType
+		Pipeline string
+		Stage    string
+		Message  string
+		Duration time.Duration
+		Success  bool
+	}
+	var summaries []summary
+	for _, event := range events {
+		summaries = append(summaries, summary{event.Type, event.Pipeline, event.Stage, event.Message, event.Duration, event.Success})
+	}
+
+	assert.Equal(t, []summary{
+		{MonitorPipelineStarted, "build", "", "", 0, false},
+		{MonitorStageStarted, "build", "org.osbuild.rpm", "", 0, false},
+		{MonitorLog, "build", "org.osbuild.rpm", "installing pkg1", 0, false},
+		{MonitorLog, "build", "org.osbuild.rpm", "installing pkg2", 0, false},
+		{MonitorStageFinished, "build", "org.osbuild.rpm", "", 2500 * time.Millisecond, true},
+		{MonitorPipelineFinished, "build", "", "", 4 * time.Second, true},
+		{MonitorPipelineStarted, "os", "", "", 0, false},
+		{MonitorStageStarted, "os", "org.osbuild.locale", "", 0, false},
+		{MonitorStageFinished, "os", "org.osbuild.locale", "", 500 * time.Millisecond, true},
+		{MonitorStageStarted, "os", "org.osbuild.selinux", "", 0, false},
+		{MonitorLog, "os", "org.osbuild.selinux", "setfiles: no space left on device", 0, false},
+		{MonitorStageFinished, "os", "org.osbuild.selinux", "", 2 * time.Second, false},
+		{MonitorPipelineFinished, "os", "", "", 3 * time.Second, false},
+	}, summaries)
+
+	assert.Equal(t, time.Unix(101, 0), events[2].Timestamp)
+	assert.Equal(t, "s3", events[11].StageID)
+	assert.Equal(t, &MonitorProgress{
+		Name:  "pipelines/sources",
+		Total: 2,
+		Done:  1,
+		SubProgress: &MonitorProgress{
+			Name:  "pipeline: os",
+			Total: 2,
+			Done:  1,
+		},
+	}, events[10].Progress)
+}
+
+func TestReadMonitorEventsInvalid(t *testing.T) {
+	ch := make(chan MonitorEvent, 10)
+	err := ReadMonitorEvents(strings.NewReader("\x1e{\"message\": \n"), ch)
+	assert.ErrorContains(t, err, "cannot decode osbuild monitor record")
+}
+
+// Every record is parsed as soon as its line feed is read, even if the
+// output arrives byte by byte and the next record is not written yet.
+func TestReadMonitorEventsStreaming(t *testing.T) {
+	records := []string{
+		"\x1e" + `{"message": "installing pkg1", "context": {"origin": "org.osbuild", "pipeline": {"name": "build", "id": "p1", "stage": {"name": "org.osbuild.rpm", "id": "s1"}}, "id": "c1"}, "timestamp": 101.0}` + "\n",
+		"\x1e" + `{"message": "installing pkg2", "context": {"id": "c1"}, "timestamp": 102.0}` + "\n",
+		"\x1e" + `{"message": "installing pkg3", "context": {"id": "c1"}, "timestamp": 103.0}` + "\n",
+	}
+
+	r, w := io.Pipe()
+	ch := make(chan MonitorEvent, 100)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- ReadMonitorEvents(r, ch)
+	}()
+
+	nextLog := func() MonitorEvent {
+		for {
+			select {
+			case event := <-ch:
+				if event.Type == MonitorLog {
+					return event
+				}
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "the record was not parsed before the next one was written")
+			}
+		}
+	}
+
+	for idx, record := range records {
+		for i := 0; i < len(record); i++ {
+			_, err := w.Write([]byte{record[i]})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, fmt.Sprintf("installing pkg%d", idx+1), nextLog().Message)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, <-readErr)
+}
+
+func TestReadMonitorEventsTruncated(t *testing.T) {
+	// the first record is truncated by the separator of the second one
+	events := readTestMonitorEvents(t, "\x1e"+`{"message": "install`+"\x1e"+`{"message": "installing pkg2", "context": {"origin": "org.osbuild"}}`+"\n")
+	require.Len(t, events, 1)
+	assert.Equal(t, "installing pkg2", events[0].Message)
+}
+
+func TestMonitorProgressFraction(t *testing.T) {
+	var progress *MonitorProgress
+	assert.Equal(t, 0.0, progress.Fraction())
+
+	progress = &MonitorProgress{Total: 4, Done: 1}
+	assert.Equal(t, 0.25, progress.Fraction())
+
+	progress.SubProgress = &MonitorProgress{Total: 2, Done: 1}
+	assert.Equal(t, 0.375, progress.Fraction())
+
+	progress.Done = 4
+	assert.Equal(t, 1.0, progress.Fraction())
+}
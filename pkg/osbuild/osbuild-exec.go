@@ -2,6 +2,7 @@ package osbuild
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,10 +17,63 @@ import (
 // does not return an error in this case. Instead, the failure is communicated
 // with its corresponding logs through osbuild.Result.
 func RunOSBuild(manifest []byte, store, outputDirectory string, exports, checkpoints, extraEnv []string, result bool, errorWriter io.Writer) (*Result, error) {
+	return runOSBuild(context.Background(), manifest, store, outputDirectory, exports, checkpoints, extraEnv, result, errorWriter, nil)
+}
+
+// RunOSBuildWithProgress runs an instance of osbuild like RunOSBuild with
+// the result enabled, and sends the progress of the build to the events
+// channel while it runs. The channel is closed when osbuild exits, the
+// events must be consumed or osbuild blocks.
+func RunOSBuildWithProgress(manifest []byte, store, outputDirectory string, exports, checkpoints, extraEnv []string, errorWriter io.Writer, events chan<- MonitorEvent) (*Result, error) {
+	return RunOSBuildContext(context.Background(), manifest, store, outputDirectory, exports, checkpoints, extraEnv, errorWriter, events)
+}
+
+// RunOSBuildContext runs an instance of osbuild with the result enabled
+// until it exits or the context is cancelled, in which case osbuild is
+// killed and the error of the context is returned. The progress is sent to
+// the events channel like for RunOSBuildWithProgress if it isn't nil.
+func RunOSBuildContext(ctx context.Context, manifest []byte, store, outputDirectory string, exports, checkpoints, extraEnv []string, errorWriter io.Writer, events chan<- MonitorEvent) (*Result, error) {
+	if events == nil {
+		return runOSBuild(ctx, manifest, store, outputDirectory, exports, checkpoints, extraEnv, true, errorWriter, nil)
+	}
+	defer close(events)
+
+	monitorReader, monitorWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the osbuild monitor: %v", err)
+	}
+	defer monitorReader.Close()
+
+	readErr := make(chan error, 1)
+	go func() {
+		err := ReadMonitorEvents(monitorReader, events)
+		if err != nil {
+			// keep osbuild from blocking on a full pipe
+			_, _ = io.Copy(io.Discard, monitorReader)
+		}
+		readErr <- err
+	}()
+
+	res, err := runOSBuild(ctx, manifest, store, outputDirectory, exports, checkpoints, extraEnv, true, errorWriter, monitorWriter)
+	// osbuild exited, closing the last write end ends the monitor output
+	monitorWriter.Close()
+	if monitorErr := <-readErr; err == nil && monitorErr != nil {
+		err = monitorErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// runOSBuild runs osbuild, the JSON-seq monitor writes to the monitor file if
+// it's given.
+func runOSBuild(ctx context.Context, manifest []byte, store, outputDirectory string, exports, checkpoints, extraEnv []string, result bool, errorWriter io.Writer, monitor *os.File) (*Result, error) {
 	var stdoutBuffer bytes.Buffer
 	var res Result
 
-	cmd := exec.Command(
+	cmd := exec.CommandContext(
+		ctx,
 		"osbuild",
 		"--store", store,
 		"--output-directory", outputDirectory,
@@ -41,6 +95,12 @@ func RunOSBuild(manifest []byte, store, outputDirectory string, exports, checkpo
 		cmd.Stdout = os.Stdout
 	}
 
+	if monitor != nil {
+		// the first extra file is fd 3 in osbuild
+		cmd.Args = append(cmd.Args, "--monitor", "JSONSeqMonitor", "--monitor-fd", "3")
+		cmd.ExtraFiles = []*os.File{monitor}
+	}
+
 	if len(extraEnv) > 0 {
 		cmd.Env = append(os.Environ(), extraEnv...)
 	}
@@ -67,6 +127,9 @@ func RunOSBuild(manifest []byte, store, outputDirectory string, exports, checkpo
 	}
 
 	err = cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("running osbuild was interrupted: %w", ctxErr)
+	}
 
 	if result {
 		// try to decode the output even though the job could have failed
@@ -103,3 +166,17 @@ func OSBuildVersion() (string, error) {
 	version = strings.TrimSpace(version)
 	return version, nil
 }
+
+// InspectManifest returns the description of the manifest by osbuild
+// --inspect, which amends the manifest with the IDs of the stages.
+func InspectManifest(manifest []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("osbuild", "--inspect", "-")
+	cmd.Stdin = bytes.NewReader(manifest)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("inspecting the manifest failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
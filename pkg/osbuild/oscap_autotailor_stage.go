@@ -1,6 +1,9 @@
 package osbuild
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 type OscapAutotailorStageOptions struct {
 	Filepath string                `json:"filepath"`
@@ -8,9 +11,47 @@ type OscapAutotailorStageOptions struct {
 }
 type OscapAutotailorConfig struct {
 	OscapConfig
-	NewProfile string   `json:"new_profile"`
-	Selected   []string `json:"selected,omitempty"`
-	Unselected []string `json:"unselected,omitempty"`
+	NewProfile string                    `json:"new_profile"`
+	Selected   []string                  `json:"selected,omitempty"`
+	Unselected []string                  `json:"unselected,omitempty"`
+	Overrides  []OscapAutotailorOverride `json:"overrides,omitempty"`
+
+	// Path to a JSON tailoring file in the tree. The JSON tailoring format
+	// describes the base profile, the rule selections and the variable
+	// refinements itself, so it is mutually exclusive with the selected,
+	// unselected and overrides options. NewProfile must match the ID of the
+	// tailored profile defined in the file.
+	JSONTailoring string `json:"-"`
+}
+
+// oscapAutotailorJSONConfig is the config of the stage for a JSON tailoring
+// file, see the "json-profile" definition of the stage schema. It only
+// accepts these three properties.
+type oscapAutotailorJSONConfig struct {
+	Datastream        string `json:"datastream"`
+	TailoredProfileID string `json:"tailored_profile_id"`
+	TailoringFile     string `json:"tailoring_file"`
+}
+
+// Unexported alias for use in OscapAutotailorConfig MarshalJSON() to prevent recursion
+type oscapAutotailorConfig OscapAutotailorConfig
+
+func (c OscapAutotailorConfig) MarshalJSON() ([]byte, error) {
+	if c.JSONTailoring != "" {
+		return json.Marshal(oscapAutotailorJSONConfig{
+			Datastream:        c.Datastream,
+			TailoredProfileID: c.NewProfile,
+			TailoringFile:     c.JSONTailoring,
+		})
+	}
+	return json.Marshal(oscapAutotailorConfig(c))
+}
+
+// OscapAutotailorOverride sets the value of an XCCDF variable in the
+// tailored profile.
+type OscapAutotailorOverride struct {
+	Var   string `json:"var"`
+	Value string `json:"value"`
 }
 
 func (OscapAutotailorStageOptions) isStageOptions() {}
@@ -19,6 +60,14 @@ func (c OscapAutotailorConfig) validate() error {
 	if c.NewProfile == "" {
 		return fmt.Errorf("'new_profile' must be specified")
 	}
+	if c.JSONTailoring != "" && (len(c.Selected) > 0 || len(c.Unselected) > 0 || len(c.Overrides) > 0) {
+		return fmt.Errorf("'json_tailoring' cannot be combined with 'selected', 'unselected' or 'overrides'")
+	}
+	for _, override := range c.Overrides {
+		if override.Var == "" {
+			return fmt.Errorf("'var' must be specified for every override")
+		}
+	}
 	// reuse the oscap validation
 	return c.OscapConfig.validate()
 }
@@ -38,10 +87,12 @@ func NewOscapAutotailorStageOptions(filepath string, oscapOptions OscapConfig, a
 	return &OscapAutotailorStageOptions{
 		Filepath: filepath,
 		Config: OscapAutotailorConfig{
-			OscapConfig: oscapOptions,
-			NewProfile:  autotailorOptions.NewProfile,
-			Selected:    autotailorOptions.Selected,
-			Unselected:  autotailorOptions.Unselected,
+			OscapConfig:   oscapOptions,
+			NewProfile:    autotailorOptions.NewProfile,
+			Selected:      autotailorOptions.Selected,
+			Unselected:    autotailorOptions.Unselected,
+			Overrides:     autotailorOptions.Overrides,
+			JSONTailoring: autotailorOptions.JSONTailoring,
 		},
 	}
 }
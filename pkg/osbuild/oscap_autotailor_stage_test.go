@@ -1,9 +1,11 @@
 package osbuild
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewOscapAutotailorStage(t *testing.T) {
@@ -87,6 +89,67 @@ func TestOscapAutotailorStageOptionsValidate(t *testing.T) {
 			},
 			err: false,
 		},
+		{
+			name: "valid-overrides",
+			options: OscapAutotailorStageOptions{
+				Config: OscapAutotailorConfig{
+					OscapConfig: OscapConfig{
+						ProfileID:  "test-profile",
+						Datastream: "test-datastream",
+					},
+					NewProfile: "test-profile-osbuild-profile",
+					Overrides: []OscapAutotailorOverride{
+						{Var: "var_password_pam_minlen", Value: "16"},
+					},
+				},
+			},
+			err: false,
+		},
+		{
+			name: "empty-override-var",
+			options: OscapAutotailorStageOptions{
+				Config: OscapAutotailorConfig{
+					OscapConfig: OscapConfig{
+						ProfileID:  "test-profile",
+						Datastream: "test-datastream",
+					},
+					NewProfile: "test-profile-osbuild-profile",
+					Overrides: []OscapAutotailorOverride{
+						{Value: "16"},
+					},
+				},
+			},
+			err: true,
+		},
+		{
+			name: "valid-json-tailoring",
+			options: OscapAutotailorStageOptions{
+				Config: OscapAutotailorConfig{
+					OscapConfig: OscapConfig{
+						ProfileID:  "test-profile",
+						Datastream: "test-datastream",
+					},
+					NewProfile:    "test-profile-tailored",
+					JSONTailoring: "/usr/share/xml/osbuild-openscap-data/tailoring.json",
+				},
+			},
+			err: false,
+		},
+		{
+			name: "json-tailoring-with-selected",
+			options: OscapAutotailorStageOptions{
+				Config: OscapAutotailorConfig{
+					OscapConfig: OscapConfig{
+						ProfileID:  "test-profile",
+						Datastream: "test-datastream",
+					},
+					NewProfile:    "test-profile-tailored",
+					Selected:      []string{"fast_rule"},
+					JSONTailoring: "/usr/share/xml/osbuild-openscap-data/tailoring.json",
+				},
+			},
+			err: true,
+		},
 	}
 	for idx := range tests {
 		tt := tests[idx]
@@ -101,3 +164,51 @@ func TestOscapAutotailorStageOptionsValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestOscapAutotailorConfigMarshal(t *testing.T) {
+	oscapConfig := OscapConfig{
+		Datastream: "/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml",
+		ProfileID:  "xccdf_org.ssgproject.content_profile_cis",
+	}
+
+	// the JSON tailoring file only matches the "json-profile" definition of
+	// the stage schema, which doesn't allow any other property
+	options := NewOscapAutotailorStageOptions(
+		"/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+		oscapConfig,
+		OscapAutotailorConfig{
+			NewProfile:    "cis_tailored",
+			JSONTailoring: "/usr/share/xml/osbuild-openscap-data/tailoring.json",
+		},
+	)
+	data, err := json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"filepath": "/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+		"config": {
+			"datastream": "/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml",
+			"tailored_profile_id": "cis_tailored",
+			"tailoring_file": "/usr/share/xml/osbuild-openscap-data/tailoring.json"
+		}
+	}`, string(data))
+
+	options = NewOscapAutotailorStageOptions(
+		"/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+		oscapConfig,
+		OscapAutotailorConfig{
+			NewProfile: "xccdf_org.ssgproject.content_profile_cis_osbuild_tailoring",
+			Selected:   []string{"fast_rule"},
+		},
+	)
+	data, err = json.Marshal(options)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"filepath": "/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+		"config": {
+			"datastream": "/usr/share/xml/scap/ssg/content/ssg-rhel9-ds.xml",
+			"profile_id": "xccdf_org.ssgproject.content_profile_cis",
+			"new_profile": "xccdf_org.ssgproject.content_profile_cis_osbuild_tailoring",
+			"selected": ["fast_rule"]
+		}
+	}`, string(data))
+}
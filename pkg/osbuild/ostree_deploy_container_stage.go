@@ -0,0 +1,64 @@
+package osbuild
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Options for the org.osbuild.ostree.deploy.container stage.
+type OSTreeDeployContainerStageOptions struct {
+	OsName string `json:"osname"`
+
+	// Image reference the deployment is updated from, e.g.
+	// ostree-unverified-registry:quay.io/fedora/fedora-iot:39
+	TargetImgref string `json:"target_imgref"`
+
+	Mounts []string `json:"mounts,omitempty"`
+
+	Rootfs *Rootfs `json:"rootfs,omitempty"`
+
+	KernelOpts []string `json:"kernel_opts,omitempty"`
+}
+
+func (OSTreeDeployContainerStageOptions) isStageOptions() {}
+
+var ostreeImgrefRE = regexp.MustCompile(`^(ostree-remote-registry:[^:]+|ostree-unverified-registry|ostree-image-signed:[^:]+|ostree-unverified-image:[^:]+):.+$`)
+
+func (options OSTreeDeployContainerStageOptions) validate() error {
+	if options.OsName == "" {
+		return fmt.Errorf("osname is required")
+	}
+	if !ostreeImgrefRE.MatchString(options.TargetImgref) {
+		return fmt.Errorf("target imgref %q is not a valid ostree container image reference", options.TargetImgref)
+	}
+	if options.Rootfs != nil {
+		if (options.Rootfs.UUID == "") == (options.Rootfs.Label == "") {
+			return fmt.Errorf("exactly one of UUID or Label must be specified")
+		}
+	}
+	return nil
+}
+
+type OSTreeDeployContainerInputs struct {
+	Images ContainersInput `json:"images"`
+}
+
+func (OSTreeDeployContainerInputs) isStageInputs() {}
+
+// A new org.osbuild.ostree.deploy.container stage to deploy an OSTree native
+// container image. The container must be the only image in the input.
+func NewOSTreeDeployContainerStage(options *OSTreeDeployContainerStageOptions, images ContainersInput) *Stage {
+	if err := options.validate(); err != nil {
+		panic(err)
+	}
+	if refs, ok := images.References.(ContainersInputSourceMap); !ok || len(refs) != 1 {
+		panic("the ostree container deploy stage requires exactly one container image")
+	}
+	return &Stage{
+		Type:    "org.osbuild.ostree.deploy.container",
+		Options: options,
+		Inputs: OSTreeDeployContainerInputs{
+			Images: images,
+		},
+	}
+}
@@ -0,0 +1,144 @@
+package osbuild
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/images/pkg/container"
+)
+
+func TestNewOSTreeDeployContainerStage(t *testing.T) {
+	options := &OSTreeDeployContainerStageOptions{
+		OsName:       "fedora",
+		TargetImgref: "ostree-unverified-registry:quay.io/fedora/fedora-iot:39",
+		Mounts:       []string{"/boot", "/boot/efi"},
+		Rootfs: &Rootfs{
+			Label: "root",
+		},
+		KernelOpts: []string{"rw"},
+	}
+	images := NewContainersInputForSources([]container.Spec{
+		{
+			Source:    "quay.io/fedora/fedora-iot:39",
+			Digest:    "sha256:f29b6cd6bf2eedf86b1a0ab3e4c4e2e9ff79ca36ac1acd9bb43b8cdf2b1e9c3e",
+			ImageID:   "sha256:1a7c8bd0d6e5f08e5a0a5fba51a8b1f3f0c7ea20a4bea7e2a5fe1ee4b8b0c06a",
+			LocalName: "quay.io/fedora/fedora-iot:39",
+		},
+	})
+
+	stage := NewOSTreeDeployContainerStage(options, images)
+	assert.Equal(t, "org.osbuild.ostree.deploy.container", stage.Type)
+
+	data, err := json.Marshal(stage)
+	require.NoError(t, err)
+	expected := `{
+		"type": "org.osbuild.ostree.deploy.container",
+		"inputs": {
+			"images": {
+				"type": "org.osbuild.containers",
+				"origin": "org.osbuild.source",
+				"references": {
+					"sha256:1a7c8bd0d6e5f08e5a0a5fba51a8b1f3f0c7ea20a4bea7e2a5fe1ee4b8b0c06a": {
+						"name": "quay.io/fedora/fedora-iot:39"
+					}
+				}
+			}
+		},
+		"options": {
+			"osname": "fedora",
+			"target_imgref": "ostree-unverified-registry:quay.io/fedora/fedora-iot:39",
+			"mounts": ["/boot", "/boot/efi"],
+			"rootfs": {"label": "root"},
+			"kernel_opts": ["rw"]
+		}
+	}`
+	assert.JSONEq(t, expected, string(data))
+}
+
+func TestOSTreeDeployContainerStageOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options OSTreeDeployContainerStageOptions
+		err     bool
+	}{
+		{
+			name:    "empty-options",
+			options: OSTreeDeployContainerStageOptions{},
+			err:     true,
+		},
+		{
+			name: "no-osname",
+			options: OSTreeDeployContainerStageOptions{
+				TargetImgref: "ostree-unverified-registry:quay.io/fedora/fedora-iot:39",
+			},
+			err: true,
+		},
+		{
+			name: "plain-imgref",
+			options: OSTreeDeployContainerStageOptions{
+				OsName:       "fedora",
+				TargetImgref: "quay.io/fedora/fedora-iot:39",
+			},
+			err: true,
+		},
+		{
+			name: "remote-imgref-without-remote",
+			options: OSTreeDeployContainerStageOptions{
+				OsName:       "fedora",
+				TargetImgref: "ostree-remote-registry::quay.io/fedora/fedora-iot:39",
+			},
+			err: true,
+		},
+		{
+			name: "rootfs-label-and-uuid",
+			options: OSTreeDeployContainerStageOptions{
+				OsName:       "fedora",
+				TargetImgref: "ostree-unverified-registry:quay.io/fedora/fedora-iot:39",
+				Rootfs:       &Rootfs{Label: "root", UUID: "6264D520-3FB9-423F-8AB8-7A0A8E3D3562"},
+			},
+			err: true,
+		},
+		{
+			name: "unverified-registry",
+			options: OSTreeDeployContainerStageOptions{
+				OsName:       "fedora",
+				TargetImgref: "ostree-unverified-registry:quay.io/fedora/fedora-iot:39",
+			},
+			err: false,
+		},
+		{
+			name: "remote-registry",
+			options: OSTreeDeployContainerStageOptions{
+				OsName:       "fedora",
+				TargetImgref: "ostree-remote-registry:fedora:quay.io/fedora/fedora-iot:39",
+				Rootfs:       &Rootfs{Label: "root"},
+			},
+			err: false,
+		},
+	}
+	for idx := range tests {
+		tt := tests[idx]
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err {
+				assert.Errorf(t, tt.options.validate(), "%q didn't return an error [idx: %d]", tt.name, idx)
+			} else {
+				assert.NoErrorf(t, tt.options.validate(), "%q returned an error [idx: %d]", tt.name, idx)
+			}
+		})
+	}
+}
+
+func TestNewOSTreeDeployContainerStageMultipleImages(t *testing.T) {
+	options := &OSTreeDeployContainerStageOptions{
+		OsName:       "fedora",
+		TargetImgref: "ostree-unverified-registry:quay.io/fedora/fedora-iot:39",
+	}
+	images := NewContainersInputForSources([]container.Spec{
+		{ImageID: "sha256:1111", LocalName: "one"},
+		{ImageID: "sha256:2222", LocalName: "two"},
+	})
+	assert.Panics(t, func() { NewOSTreeDeployContainerStage(options, images) })
+}
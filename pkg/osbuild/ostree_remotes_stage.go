@@ -28,7 +28,7 @@ type OSTreeRemote struct {
 	Branches []string `json:"branches,omitempty"`
 
 	// GPG keys to verify the commits
-	GPGKeys []string `json:"secrets,omitempty"`
+	GPGKeys []string `json:"gpgkeys,omitempty"`
 
 	// Paths to ASCII-armored GPG key or directories containing ASCII-armored
 	// GPG keys to import
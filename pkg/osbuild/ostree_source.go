@@ -36,6 +36,7 @@ func NewOSTreeSourceItem(commit ostree.CommitSpec) *OSTreeSourceItem {
 	item := new(OSTreeSourceItem)
 	item.Remote.URL = commit.URL
 	item.Remote.ContentURL = commit.ContentURL
+	item.Remote.GPGKeys = commit.GPGKeys
 	if commit.Secrets == "org.osbuild.rhsm.consumer" {
 		item.Remote.Secrets = &OSTreeSourceRemoteSecrets{
 			Name: "org.osbuild.rhsm.consumer",
@@ -0,0 +1,66 @@
+package osbuild
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteFile is a file that is downloaded by the curl source at build time
+// and copied into a pipeline tree. The checksum is mandatory, it is used
+// both to verify the downloaded content and to reference the file in stage
+// inputs.
+type RemoteFile struct {
+	// URL to download the file from
+	URL string
+	// Checksum of the file content in the form <algorithm>:<hexdigest>
+	Checksum string
+	// Path of the file in the tree
+	Path string
+}
+
+func (f RemoteFile) validate() error {
+	if f.URL == "" {
+		return fmt.Errorf("remote file %q has no URL", f.Path)
+	}
+	if !curlDigestPattern.MatchString(f.Checksum) {
+		return fmt.Errorf("remote file with URL %q has invalid digest %q", f.URL, f.Checksum)
+	}
+	if f.Path == "" {
+		return fmt.Errorf("remote file with URL %q has no path", f.URL)
+	}
+	return nil
+}
+
+// AddRemoteFile adds a remote file to the curl source to download. Will
+// return an error if any of the supplied options are invalid or missing.
+func (source *CurlSource) AddRemoteFile(file RemoteFile) error {
+	if err := file.validate(); err != nil {
+		return err
+	}
+	source.Items[file.Checksum] = URL(file.URL)
+	return nil
+}
+
+// GenRemoteFilesStages generates a copy stage that copies the given remote
+// files from the curl source into the tree.
+func GenRemoteFilesStages(files []RemoteFile) []*Stage {
+	if len(files) == 0 {
+		return nil
+	}
+
+	var copyStagePaths []CopyStagePath
+	copyStageInputs := make(CopyStageFilesInputs)
+	for _, file := range files {
+		copyStageInputKey := fmt.Sprintf("remote-file-%s", strings.SplitN(file.Checksum, ":", 2)[1])
+		copyStagePaths = append(copyStagePaths, CopyStagePath{
+			From:              fmt.Sprintf("input://%s/%s", copyStageInputKey, file.Checksum),
+			To:                fmt.Sprintf("tree://%s", file.Path),
+			RemoveDestination: true,
+		})
+		copyStageInputs[copyStageInputKey] = NewFilesInput(NewFilesInputSourceArrayRef([]FilesInputSourceArrayRefEntry{
+			NewFilesInputSourceArrayRefEntry(file.Checksum, nil),
+		}))
+	}
+
+	return []*Stage{NewCopyStageSimple(&CopyStageOptions{Paths: copyStagePaths}, &copyStageInputs)}
+}
@@ -0,0 +1,94 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurlSourceAddRemoteFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  RemoteFile
+		valid bool
+	}{
+		{
+			name: "valid",
+			file: RemoteFile{
+				URL:      "https://example.com/tailoring.xml",
+				Checksum: "sha256:4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3",
+				Path:     "/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+			},
+			valid: true,
+		},
+		{
+			name: "no-url",
+			file: RemoteFile{
+				Checksum: "sha256:4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3",
+				Path:     "/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+			},
+			valid: false,
+		},
+		{
+			name: "bad-checksum",
+			file: RemoteFile{
+				URL:      "https://example.com/tailoring.xml",
+				Checksum: "4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3",
+				Path:     "/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+			},
+			valid: false,
+		},
+		{
+			name: "no-path",
+			file: RemoteFile{
+				URL:      "https://example.com/tailoring.xml",
+				Checksum: "sha256:4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3",
+			},
+			valid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := NewCurlSource()
+			err := source.AddRemoteFile(tt.file)
+			if tt.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, URL(tt.file.URL), source.Items[tt.file.Checksum])
+			} else {
+				assert.Error(t, err)
+				assert.Empty(t, source.Items)
+			}
+		})
+	}
+}
+
+func TestGenRemoteFilesStages(t *testing.T) {
+	assert.Nil(t, GenRemoteFilesStages(nil))
+
+	checksum := "sha256:4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3"
+	stages := GenRemoteFilesStages([]RemoteFile{
+		{
+			URL:      "https://example.com/tailoring.xml",
+			Checksum: checksum,
+			Path:     "/usr/share/xml/osbuild-openscap-data/tailoring.xml",
+		},
+	})
+
+	expected := []*Stage{
+		NewCopyStageSimple(&CopyStageOptions{
+			Paths: []CopyStagePath{
+				{
+					From:              "input://remote-file-4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3/" + checksum,
+					To:                "tree:///usr/share/xml/osbuild-openscap-data/tailoring.xml",
+					RemoveDestination: true,
+				},
+			},
+		}, &CopyStageFilesInputs{
+			"remote-file-4be41142a5fb2b4cd6d812e126838cffa57b7c84e5a79d65f66bb9cf1d2830a3": NewFilesInput(NewFilesInputSourceArrayRef([]FilesInputSourceArrayRefEntry{
+				NewFilesInputSourceArrayRefEntry(checksum, nil),
+			})),
+		}),
+	}
+	assert.Equal(t, expected, stages)
+}
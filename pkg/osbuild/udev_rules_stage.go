@@ -1,6 +1,8 @@
 package osbuild
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
 )
@@ -255,3 +257,66 @@ func NewUdevRule(ops []UdevKV) UdevRule {
 
 	return res
 }
+
+// UnmarshalJSON decodes the rules in the format of the stage options: a rule
+// is either a comment object or a list of operations, whose key is a string
+// or an object with the name and argument of the key.
+func (r *UdevRules) UnmarshalJSON(data []byte) error {
+	var rawRules []json.RawMessage
+	if err := json.Unmarshal(data, &rawRules); err != nil {
+		return err
+	}
+
+	rules := make(UdevRules, 0, len(rawRules))
+	for idx, rawRule := range rawRules {
+		var rawOps []json.RawMessage
+		if err := json.Unmarshal(rawRule, &rawOps); err != nil {
+			var comment UdevRuleComment
+			if err := jsonUnmarshalStrict(rawRule, &comment); err != nil {
+				return fmt.Errorf("rule %d is neither a comment nor a list of operations: %w", idx, err)
+			}
+			rules = append(rules, comment)
+			continue
+		}
+
+		ops := make(UdevOps, 0, len(rawOps))
+		for _, rawOp := range rawOps {
+			var op struct {
+				Key json.RawMessage `json:"key"`
+			}
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return fmt.Errorf("invalid operation in rule %d: %w", idx, err)
+			}
+
+			var udevOp UdevOp
+			if len(op.Key) > 0 && op.Key[0] == '{' {
+				var argOp UdevOpArg
+				if err := jsonUnmarshalStrict(rawOp, &argOp); err != nil {
+					return fmt.Errorf("invalid operation in rule %d: %w", idx, err)
+				}
+				udevOp = argOp
+			} else {
+				var simpleOp UdevOpSimple
+				if err := jsonUnmarshalStrict(rawOp, &simpleOp); err != nil {
+					return fmt.Errorf("invalid operation in rule %d: %w", idx, err)
+				}
+				udevOp = simpleOp
+			}
+			if err := udevOp.validate(); err != nil {
+				return fmt.Errorf("rule %d: %w", idx, err)
+			}
+			ops = append(ops, udevOp)
+		}
+		rules = append(rules, ops)
+	}
+
+	*r = rules
+	return nil
+}
+
+// jsonUnmarshalStrict decodes data into v and fails on unknown fields.
+func jsonUnmarshalStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
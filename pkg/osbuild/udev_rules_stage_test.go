@@ -1,6 +1,7 @@
 package osbuild
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -182,3 +183,58 @@ func TestUdevRuleValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestUdevRulesStageOptionsUnmarshalJSON(t *testing.T) {
+	expected := &UdevRulesStageOptions{
+		Filename: "/etc/udev/udev.rules",
+		Rules: UdevRules{
+			NewUdevRuleComment([]string{"This is a comment"}),
+			NewUdevRule(
+				[]UdevKV{
+					{K: "ACTION", O: "==", V: "add"},
+					{K: "ENV", A: "OSBUILD", O: "=", V: "1"},
+				},
+			),
+		},
+	}
+
+	// the stage options decode from their own serialization
+	data, err := json.Marshal(expected)
+	assert.NoError(t, err)
+	var options UdevRulesStageOptions
+	assert.NoError(t, json.Unmarshal(data, &options))
+	assert.Equal(t, expected, &options)
+
+	tests := []struct {
+		name  string
+		rules string
+		err   string
+	}{
+		{
+			name:  "not-a-rule",
+			rules: `["ACTION==add"]`,
+			err:   "rule 0 is neither a comment nor a list of operations",
+		},
+		{
+			name:  "unknown-field",
+			rules: `[[{"key": "ACTION", "op": "==", "value": "add"}]]`,
+			err:   `invalid operation in rule 0: json: unknown field "value"`,
+		},
+		{
+			name:  "missing-arg",
+			rules: `[[{"key": "ENV", "op": "=", "val": "1"}]]`,
+			err:   "rule 0: invalid op: arg is required for key 'ENV'",
+		},
+		{
+			name:  "unsupported-op",
+			rules: `[[{"key": {"name": "ENV", "arg": "OSBUILD"}, "op": "~", "val": "1"}]]`,
+			err:   "rule 0: invalid op: '~' operator is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules UdevRules
+			assert.ErrorContains(t, json.Unmarshal([]byte(tt.rules), &rules), tt.err)
+		})
+	}
+}
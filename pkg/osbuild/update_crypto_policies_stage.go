@@ -0,0 +1,19 @@
+package osbuild
+
+// UpdateCryptoPoliciesStageOptions set the system wide crypto policy
+type UpdateCryptoPoliciesStageOptions struct {
+	// Name of the policy, optionally with sub-policies, e.g. "FIPS" or
+	// "DEFAULT:SHA1"
+	Policy string `json:"policy"`
+}
+
+func (UpdateCryptoPoliciesStageOptions) isStageOptions() {}
+
+// NewUpdateCryptoPoliciesStage creates a new update-crypto-policies Stage
+// object.
+func NewUpdateCryptoPoliciesStage(options *UpdateCryptoPoliciesStageOptions) *Stage {
+	return &Stage{
+		Type:    "org.osbuild.update-crypto-policies",
+		Options: options,
+	}
+}
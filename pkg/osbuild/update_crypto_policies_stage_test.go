@@ -0,0 +1,16 @@
+package osbuild
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUpdateCryptoPoliciesStage(t *testing.T) {
+	expectedStage := &Stage{
+		Type:    "org.osbuild.update-crypto-policies",
+		Options: &UpdateCryptoPoliciesStageOptions{Policy: "FIPS"},
+	}
+	actualStage := NewUpdateCryptoPoliciesStage(&UpdateCryptoPoliciesStageOptions{Policy: "FIPS"})
+	assert.Equal(t, expectedStage, actualStage)
+}
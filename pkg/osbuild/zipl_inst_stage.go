@@ -11,6 +11,10 @@ type ZiplInstStageOptions struct {
 	Location uint64 `json:"location"`
 
 	SectorSize *uint64 `json:"sector-size,omitempty"`
+
+	// IBM Secure Execution image to boot instead of the kernel and initrd of
+	// the boot loader entry (optional)
+	SecureImage string `json:"secure-image,omitempty"`
 }
 
 func (ZiplInstStageOptions) isStageOptions() {}
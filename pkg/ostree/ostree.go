@@ -43,6 +43,10 @@ type CommitSpec struct {
 
 	Secrets string
 
+	// ASCII-armored public GPG keys the commit is verified with when it
+	// is fetched (optional)
+	GPGKeys []string
+
 	// Checksum of the commit.
 	Checksum string
 }
@@ -72,6 +76,11 @@ type ImageOptions struct {
 	// Indicate if the 'org.osbuild.rhsm.consumer' secret should be added when pulling from the
 	// remote.
 	RHSM bool `json:"rhsm"`
+
+	// For ostree raw images: An OSTree native container image (e.g.
+	// quay.io/fedora/fedora-iot:39) to deploy instead of a commit. The
+	// container reference becomes the origin the image is updated from.
+	Container string `json:"container,omitempty"`
 }
 
 // Validate the image options. This doesn't verify the existence of any remote
@@ -84,6 +93,7 @@ type ImageOptions struct {
 // - The ParentRef, if specified, must be a valid ref or a checksum.
 // - If the ParentRef is specified, the URL must also be specified.
 // - URLs must be valid.
+// - A Container cannot be combined with a URL or a ParentRef.
 func (options ImageOptions) Validate() error {
 	if ref := options.ImageRef; ref != "" {
 		// image ref must not look like a checksum
@@ -119,6 +129,10 @@ func (options ImageOptions) Validate() error {
 		}
 	}
 
+	if options.Container != "" && (options.URL != "" || options.ParentRef != "") {
+		return NewParameterComboError("ostree container specified together with a URL or parent ref")
+	}
+
 	return nil
 }
 
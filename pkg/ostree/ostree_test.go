@@ -205,6 +205,19 @@ func TestValidate(t *testing.T) {
 			},
 			valid: false,
 		},
+		"container": {
+			options: ImageOptions{
+				Container: "quay.io/fedora/fedora-iot:39",
+			},
+			valid: true,
+		},
+		"container-with-url": {
+			options: ImageOptions{
+				Container: "quay.io/fedora/fedora-iot:39",
+				URL:       "https://repo.example.com",
+			},
+			valid: false,
+		},
 	}
 
 	for name, testCase := range cases {
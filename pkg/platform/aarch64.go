@@ -28,6 +28,13 @@ func (p *Aarch64) GetPackages() []string {
 	return packages
 }
 
+func (p *Aarch64) GetISOBootType() ISOBootType {
+	if p.UEFIVendor == "" {
+		return ISOBOOT_NONE
+	}
+	return ISOBOOT_UEFI
+}
+
 type Aarch64_Fedora struct {
 	BasePlatform
 	UEFIVendor string
@@ -60,3 +67,71 @@ func (p *Aarch64_Fedora) GetPackages() []string {
 func (p *Aarch64_Fedora) GetBootFiles() [][2]string {
 	return p.BootFiles
 }
+
+func (p *Aarch64_Fedora) GetISOBootType() ISOBootType {
+	if p.UEFIVendor == "" {
+		return ISOBOOT_NONE
+	}
+	return ISOBOOT_UEFI
+}
+
+// SBCBoard is a family of single board computers that boot through U-Boot
+// instead of UEFI firmware.
+type SBCBoard uint64
+
+const ( // single board computer enum
+	// Boards that ship U-Boot in their own flash or get it installed
+	// separately and only need an extlinux configuration on the disk
+	SBC_GENERIC SBCBoard = iota
+	// Raspberry Pi 3 and 4, where the VideoCore firmware loads U-Boot from
+	// the first FAT partition as configured in config.txt
+	SBC_RPI
+)
+
+func (b SBCBoard) String() string {
+	switch b {
+	case SBC_GENERIC:
+		return "generic"
+	case SBC_RPI:
+		return "rpi"
+	default:
+		panic("invalid single board computer")
+	}
+}
+
+// Aarch64_SBC is a single board computer that boots the kernel with U-Boot
+// and an extlinux configuration rather than with UEFI and grub2. The firmware
+// partition is the one mounted at /boot/efi.
+type Aarch64_SBC struct {
+	BasePlatform
+	Board SBCBoard
+}
+
+func (p *Aarch64_SBC) GetArch() Arch {
+	return ARCH_AARCH64
+}
+
+func (p *Aarch64_SBC) GetPackages() []string {
+	packages := append(p.BasePlatform.FirmwarePackages, "dracut-config-generic")
+
+	switch p.Board {
+	case SBC_RPI:
+		packages = append(packages,
+			"bcm283x-firmware",
+			"bcm283x-overlays",
+			"uboot-images-armv8")
+	}
+
+	return packages
+}
+
+func (p *Aarch64_SBC) GetBootFiles() [][2]string {
+	switch p.Board {
+	case SBC_RPI:
+		return [][2]string{
+			{"/usr/share/uboot/rpi_arm64/u-boot.bin", "/boot/efi/rpi-u-boot.bin"},
+		}
+	default:
+		return [][2]string{}
+	}
+}
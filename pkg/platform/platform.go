@@ -2,6 +2,7 @@ package platform
 
 type Arch uint64
 type ImageFormat uint64
+type ISOBootType uint64
 
 const ( // architecture enum
 	ARCH_AARCH64 Arch = iota
@@ -21,6 +22,16 @@ const ( // image format enum
 	FORMAT_OVA
 )
 
+const ( // ISO boot type enum
+	// Installer ISOs are not supported on the platform, this is always the
+	// case for ppc64le and s390x
+	ISOBOOT_NONE ISOBootType = iota
+	// UEFI boot from an El Torito EFI image through the shim, grub chain
+	ISOBOOT_UEFI
+	// ISOBOOT_UEFI and legacy BIOS boot through isolinux
+	ISOBOOT_UEFI_ISOLINUX
+)
+
 func (a Arch) String() string {
 	switch a {
 	case ARCH_AARCH64:
@@ -36,6 +47,19 @@ func (a Arch) String() string {
 	}
 }
 
+func (t ISOBootType) String() string {
+	switch t {
+	case ISOBOOT_NONE:
+		return "none"
+	case ISOBOOT_UEFI:
+		return "uefi"
+	case ISOBOOT_UEFI_ISOLINUX:
+		return "uefi-isolinux"
+	default:
+		panic("invalid iso boot type")
+	}
+}
+
 func (f ImageFormat) String() string {
 	switch f {
 	case FORMAT_RAW:
@@ -67,6 +91,7 @@ type Platform interface {
 	GetPackages() []string
 	GetBuildPackages() []string
 	GetBootFiles() [][2]string
+	GetISOBootType() ISOBootType
 }
 
 type BasePlatform struct {
@@ -106,3 +131,7 @@ func (p BasePlatform) GetBuildPackages() []string {
 func (p BasePlatform) GetBootFiles() [][2]string {
 	return [][2]string{}
 }
+
+func (p BasePlatform) GetISOBootType() ISOBootType {
+	return ISOBOOT_NONE
+}
@@ -43,3 +43,9 @@ func (p *PPC64LE) GetBuildPackages() []string {
 
 	return packages
 }
+
+// Installer ISOs are not supported on ppc64le: booting them needs a grub
+// ieee1275 core image and a CHRP boot record that no osbuild stage creates.
+func (p *PPC64LE) GetISOBootType() ISOBootType {
+	return ISOBOOT_NONE
+}
@@ -1,8 +1,39 @@
 package platform
 
+import (
+	"encoding/pem"
+	"fmt"
+)
+
+// S390XDiskType is the kind of disk an s390x image is written to, which
+// determines the partition layouts zipl can boot from.
+type S390XDiskType uint64
+
+const ( // s390x disk type enum
+	// FBA DASDs, SCSI disks and virtio disks of KVM guests, which use a
+	// regular dos or gpt partition table
+	S390X_DISK_FBA S390XDiskType = iota
+	// ECKD DASDs with the compatible disk layout, which has room for at
+	// most three partitions
+	S390X_DISK_ECKD
+)
+
+func (t S390XDiskType) String() string {
+	switch t {
+	case S390X_DISK_FBA:
+		return "fba"
+	case S390X_DISK_ECKD:
+		return "eckd"
+	default:
+		panic("invalid s390x disk type")
+	}
+}
+
 type S390X struct {
 	BasePlatform
 	Zipl bool
+	// Disk the image is written to, defaults to FBA
+	DiskType S390XDiskType
 }
 
 func (p *S390X) GetArch() Arch {
@@ -36,3 +67,34 @@ func (p *S390X) GetBuildPackages() []string {
 
 	return packages
 }
+
+// Installer ISOs are not supported on s390x: booting them needs the cdboot
+// image and the generic.ins file that no osbuild stage creates.
+func (p *S390X) GetISOBootType() ISOBootType {
+	return ISOBOOT_NONE
+}
+
+// SecureExecutionOptions seal the boot image of an s390x guest for IBM Secure
+// Execution. The kernel, initrd and kernel command line are encrypted for the
+// given host keys, so the image can only be booted on the machines the host
+// key documents were issued for.
+type SecureExecutionOptions struct {
+	// PEM-encoded host key documents of the machines that can boot the image
+	HostKeys []string `json:"host_keys"`
+}
+
+// Validate checks that at least one host key document is given and that all
+// of them are PEM-encoded certificates. The host key documents are not
+// verified against the IBM signing keys.
+func (o SecureExecutionOptions) Validate() error {
+	if len(o.HostKeys) == 0 {
+		return fmt.Errorf("secure execution requires at least one host key document")
+	}
+	for idx, hostKey := range o.HostKeys {
+		block, _ := pem.Decode([]byte(hostKey))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return fmt.Errorf("secure execution host key document %d is not a PEM-encoded certificate", idx+1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testHostKey = `-----BEGIN CERTIFICATE-----
+ZmFrZSBob3N0IGtleSBkb2N1bWVudA==
+-----END CERTIFICATE-----
+`
+
+func TestSecureExecutionOptionsValidate(t *testing.T) {
+	assert.NoError(t, SecureExecutionOptions{HostKeys: []string{testHostKey}}.Validate())
+	assert.EqualError(t, SecureExecutionOptions{}.Validate(), "secure execution requires at least one host key document")
+	assert.EqualError(t, SecureExecutionOptions{HostKeys: []string{testHostKey, "not a certificate"}}.Validate(), "secure execution host key document 2 is not a PEM-encoded certificate")
+}
+
+func TestISOBootTypeUnsupportedArches(t *testing.T) {
+	assert.Equal(t, ISOBOOT_NONE, (&PPC64LE{BIOS: true}).GetISOBootType())
+	assert.Equal(t, ISOBOOT_NONE, (&S390X{Zipl: true}).GetISOBootType())
+}
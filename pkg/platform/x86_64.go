@@ -50,3 +50,12 @@ func (p *X86) GetBuildPackages() []string {
 	}
 	return packages
 }
+
+// Installer ISOs for x86_64 always support legacy BIOS boot, independent of
+// the boot mode of the installed system.
+func (p *X86) GetISOBootType() ISOBootType {
+	if p.UEFIVendor == "" {
+		return ISOBOOT_NONE
+	}
+	return ISOBOOT_UEFI_ISOLINUX
+}
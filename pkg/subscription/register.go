@@ -0,0 +1,166 @@
+package subscription
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/osbuild/images/internal/common"
+	"github.com/osbuild/images/internal/fsnode"
+)
+
+const (
+	// RegisterScriptPath is the script registering the system, it's run at
+	// first boot or at build time
+	RegisterScriptPath = "/usr/local/sbin/osbuild-subscription-register"
+
+	// SecretsPath holds the organization, readable only by root. It's
+	// removed when the registration script exits.
+	SecretsPath = "/etc/osbuild/subscription-register.env"
+
+	// ActivationKeyPath holds the activation key and nothing else, so that
+	// the file can be redacted from a manifest as a whole. It's readable only
+	// by root and removed when the registration script exits.
+	ActivationKeyPath = "/etc/osbuild/subscription-activation-key"
+
+	// KatelloCACertPath is where the CA certificate of the Satellite server
+	// is installed
+	KatelloCACertPath = "/etc/rhsm/ca/katello-server-ca.pem"
+
+	// SyspurposePath holds the system purpose attributes that are sent to
+	// the server when registering
+	SyspurposePath = "/etc/rhsm/syspurpose/syspurpose.json"
+)
+
+// Directories returns the directories of the registration files. Existing
+// directories are left untouched.
+func (o *ImageOptions) Directories() []*fsnode.Directory {
+	var dirs []*fsnode.Directory
+	seen := make(map[string]bool)
+	for _, file := range o.Files() {
+		dirPath := path.Dir(file.Path())
+		if seen[dirPath] {
+			continue
+		}
+		seen[dirPath] = true
+
+		dir, err := fsnode.NewDirectory(dirPath, nil, nil, nil, true)
+		if err != nil {
+			panic(err)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// Files returns the registration script, the secrets it reads and the
+// configuration of subscription-manager. The secrets are never part of the
+// script or the commands running it, they are copied into the image like any
+// other file and only readable by root. The options must have been
+// validated.
+func (o *ImageOptions) Files() []*fsnode.File {
+	files := []*fsnode.File{
+		newFile(SecretsPath, 0600, o.secrets()),
+		newFile(ActivationKeyPath, 0600, o.ActivationKey),
+		newFile(RegisterScriptPath, 0700, o.script()),
+	}
+
+	if o.Satellite != nil {
+		files = append(files, newFile(KatelloCACertPath, 0644, o.Satellite.CACert))
+	}
+
+	if sp := o.Syspurpose; sp != nil {
+		// the format of the file written by "subscription-manager syspurpose"
+		data, err := json.MarshalIndent(struct {
+			Role  string `json:"role,omitempty"`
+			SLA   string `json:"service_level_agreement,omitempty"`
+			Usage string `json:"usage,omitempty"`
+		}{sp.Role, sp.SLA, sp.Usage}, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		files = append(files, newFile(SyspurposePath, 0644, string(data)+"\n"))
+	}
+
+	return files
+}
+
+func (o *ImageOptions) secrets() string {
+	return fmt.Sprintf("ORGANIZATION=%s\n", shellQuote(o.Organization))
+}
+
+// script returns the script that registers the system. There are 3 possible
+// setups:
+//   - Register the system with rhc and enable Insights
+//   - Register with subscription-manager, no Insights or rhc
+//   - Register with subscription-manager and enable Insights, no rhc
+func (o *ImageOptions) script() string {
+	var commands []string
+	atBuild := o.RegisterAt == RegisterAtBuild
+
+	if o.Rhc {
+		// Use rhc for registration instead of subscription manager
+		rhc := `/usr/bin/rhc connect -o="$ORGANIZATION" -a="$ACTIVATION_KEY"`
+		if o.ServerUrl != "" {
+			rhc += " --server " + shellQuote(o.ServerUrl)
+		}
+		commands = append(commands, rhc)
+		// insights-client creates the .gnupg directory during boot process, and is labeled incorrectly
+		commands = append(commands, "restorecon -R /root/.gnupg")
+		// execute the rhc post install script as the selinuxenabled check doesn't work in the buildroot container
+		commands = append(commands, "/usr/sbin/semanage permissive --add rhcd_t")
+	} else {
+		if o.Satellite != nil {
+			commands = append(commands, "/usr/sbin/subscription-manager config --rhsm.repo_ca_cert="+shellQuote("%(ca_cert_dir)s"+path.Base(KatelloCACertPath)))
+		}
+
+		register := []string{`/usr/sbin/subscription-manager register --org="$ORGANIZATION" --activationkey="$ACTIVATION_KEY"`}
+		if o.ServerUrl != "" {
+			register = append(register, "--serverurl", shellQuote(o.ServerUrl))
+		}
+		if o.BaseUrl != "" {
+			register = append(register, "--baseurl", shellQuote(o.BaseUrl))
+		}
+		commands = append(commands, strings.Join(register, " "))
+
+		if o.Release != "" {
+			commands = append(commands, "/usr/sbin/subscription-manager release --set="+shellQuote(o.Release))
+		}
+
+		// Insights is optional when using subscription-manager
+		if o.Insights {
+			commands = append(commands, "/usr/bin/insights-client --register")
+			if !atBuild {
+				// insights-client creates the .gnupg directory during boot process, and is labeled incorrectly
+				commands = append(commands, "restorecon -R /root/.gnupg")
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Generated by osbuild, registers the system\n")
+	b.WriteString("set -e\n\n")
+	fmt.Fprintf(&b, ". %s\n", SecretsPath)
+	// the secrets are removed even if the registration fails
+	fmt.Fprintf(&b, "trap %s EXIT\n", shellQuote("rm -f "+SecretsPath+" "+ActivationKeyPath))
+	fmt.Fprintf(&b, "ACTIVATION_KEY=\"$(cat %s)\"\n", ActivationKeyPath)
+	for _, command := range commands {
+		b.WriteString(command + "\n")
+	}
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func newFile(filePath string, mode os.FileMode, data string) *fsnode.File {
+	file, err := fsnode.NewFile(filePath, common.ToPtr(mode), "root", "root", []byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return file
+}
@@ -0,0 +1,171 @@
+package subscription
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIBszCCAVmgAwIBAgIUXq0a2YkFqHqv7sJ0z5c0bVZrQ9MwCgYIKoZIzj0EAwIw
+-----END CERTIFICATE-----
+`
+
+func filesByPath(o *ImageOptions) map[string]string {
+	files := make(map[string]string)
+	for _, file := range o.Files() {
+		files[file.Path()] = string(file.Data())
+	}
+	return files
+}
+
+func TestRegisterScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  ImageOptions
+		commands string
+	}{
+		{
+			name: "subscription-manager",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "my-secret-key",
+				ServerUrl:     "subscription.rhsm.redhat.com",
+				BaseUrl:       "http://cdn.redhat.com/",
+			},
+			commands: `/usr/sbin/subscription-manager register --org="$ORGANIZATION" --activationkey="$ACTIVATION_KEY" --serverurl 'subscription.rhsm.redhat.com' --baseurl 'http://cdn.redhat.com/'
+`,
+		},
+		{
+			name: "insights",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "my-secret-key",
+				ServerUrl:     "subscription.rhsm.redhat.com",
+				BaseUrl:       "http://cdn.redhat.com/",
+				Insights:      true,
+			},
+			commands: `/usr/sbin/subscription-manager register --org="$ORGANIZATION" --activationkey="$ACTIVATION_KEY" --serverurl 'subscription.rhsm.redhat.com' --baseurl 'http://cdn.redhat.com/'
+/usr/bin/insights-client --register
+restorecon -R /root/.gnupg
+`,
+		},
+		{
+			name: "insights-at-build",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "my-secret-key",
+				Insights:      true,
+				RegisterAt:    RegisterAtBuild,
+			},
+			commands: `/usr/sbin/subscription-manager register --org="$ORGANIZATION" --activationkey="$ACTIVATION_KEY"
+/usr/bin/insights-client --register
+`,
+		},
+		{
+			name: "rhc",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "my-secret-key",
+				ServerUrl:     "subscription.rhsm.redhat.com",
+				BaseUrl:       "http://cdn.redhat.com/",
+				Rhc:           true,
+			},
+			commands: `/usr/bin/rhc connect -o="$ORGANIZATION" -a="$ACTIVATION_KEY" --server 'subscription.rhsm.redhat.com'
+restorecon -R /root/.gnupg
+/usr/sbin/semanage permissive --add rhcd_t
+`,
+		},
+		{
+			name: "satellite-release",
+			options: ImageOptions{
+				Organization:  "Default_Organization",
+				ActivationKey: "my-secret-key",
+				ServerUrl:     "https://satellite.example.com/rhsm",
+				Satellite: &SatelliteOptions{
+					CACert: testCACert,
+				},
+				Release: "9.2",
+			},
+			commands: `/usr/sbin/subscription-manager config --rhsm.repo_ca_cert='%(ca_cert_dir)skatello-server-ca.pem'
+/usr/sbin/subscription-manager register --org="$ORGANIZATION" --activationkey="$ACTIVATION_KEY" --serverurl 'https://satellite.example.com/rhsm'
+/usr/sbin/subscription-manager release --set='9.2'
+`,
+		},
+		{
+			name: "satellite-ca-cert",
+			options: ImageOptions{
+				Organization:  "Default_Organization",
+				ActivationKey: "my-secret-key",
+				ServerUrl:     "satellite.example.com",
+				BaseUrl:       "https://satellite.example.com/pulp/content",
+				Satellite: &SatelliteOptions{
+					CACert: testCACert,
+				},
+			},
+			commands: `/usr/sbin/subscription-manager config --rhsm.repo_ca_cert='%(ca_cert_dir)skatello-server-ca.pem'
+/usr/sbin/subscription-manager register --org="$ORGANIZATION" --activationkey="$ACTIVATION_KEY" --serverurl 'satellite.example.com' --baseurl 'https://satellite.example.com/pulp/content'
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.options.Validate())
+			files := filesByPath(&tt.options)
+
+			expected := "#!/bin/sh\n# Generated by osbuild, registers the system\nset -e\n\n" +
+				". /etc/osbuild/subscription-register.env\n" +
+				"trap 'rm -f /etc/osbuild/subscription-register.env /etc/osbuild/subscription-activation-key' EXIT\n" +
+				"ACTIVATION_KEY=\"$(cat /etc/osbuild/subscription-activation-key)\"\n" +
+				tt.commands
+			assert.Equal(t, expected, files[RegisterScriptPath])
+			assert.NotContains(t, files[RegisterScriptPath], tt.options.ActivationKey)
+		})
+	}
+}
+
+func TestRegisterFiles(t *testing.T) {
+	options := &ImageOptions{
+		Organization:  "it's",
+		ActivationKey: "my-secret-key",
+		ServerUrl:     "satellite.example.com",
+		Satellite: &SatelliteOptions{
+			CACert: testCACert,
+		},
+		Syspurpose: &SyspurposeOptions{
+			Role:  "Red Hat Enterprise Linux Server",
+			Usage: "Production",
+		},
+	}
+	require.NoError(t, options.Validate())
+
+	files := make(map[string]uint32)
+	for _, file := range options.Files() {
+		files[file.Path()] = uint32(*file.Mode())
+	}
+	assert.Equal(t, map[string]uint32{
+		SecretsPath:        0600,
+		ActivationKeyPath:  0600,
+		RegisterScriptPath: 0700,
+		KatelloCACertPath:  0644,
+		SyspurposePath:     0644,
+	}, files)
+
+	data := filesByPath(options)
+	assert.Equal(t, "ORGANIZATION='it'\\''s'\n", data[SecretsPath])
+	assert.Equal(t, "my-secret-key", data[ActivationKeyPath])
+	assert.Equal(t, testCACert, data[KatelloCACertPath])
+	assert.Equal(t, `{
+  "role": "Red Hat Enterprise Linux Server",
+  "usage": "Production"
+}
+`, data[SyspurposePath])
+
+	var dirs []string
+	for _, dir := range options.Directories() {
+		dirs = append(dirs, dir.Path())
+	}
+	assert.Equal(t, []string{"/etc/osbuild", "/usr/local/sbin", "/etc/rhsm/ca", "/etc/rhsm/syspurpose"}, dirs)
+}
@@ -1,5 +1,25 @@
 package subscription
 
+import (
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RegistrationTime selects when the system is registered
+type RegistrationTime string
+
+const (
+	// RegisterAtFirstBoot registers the system when it boots for the first
+	// time, this is the default
+	RegisterAtFirstBoot RegistrationTime = "first-boot"
+
+	// RegisterAtBuild registers the system while the image is built, so that
+	// every instance of the image shares the same identity
+	RegisterAtBuild RegistrationTime = "build"
+)
+
 // The ImageOptions specify subscription-specific image options
 // ServerUrl denotes the host to register the system with
 // BaseUrl specifies the repository URL for DNF
@@ -10,6 +30,106 @@ type ImageOptions struct {
 	BaseUrl       string
 	Insights      bool
 	Rhc           bool
+
+	// Satellite or Capsule server to register with instead of the Red Hat
+	// CDN, the ServerUrl is the server's host
+	Satellite *SatelliteOptions
+
+	// Release version the system is locked to, e.g. "9.2"
+	Release string
+
+	// System purpose attributes, used to select the subscriptions of the
+	// system
+	Syspurpose *SyspurposeOptions
+
+	// When to register the system, defaults to RegisterAtFirstBoot
+	RegisterAt RegistrationTime
+}
+
+// SatelliteOptions configures the registration with a Satellite or Capsule
+// server
+type SatelliteOptions struct {
+	// PEM-encoded CA certificate of the server, required. The
+	// katello-ca-consumer package of the server is not used to bootstrap
+	// the trust, it is only served over plain HTTP.
+	//
+	// There are no options for the lifecycle environment and content view,
+	// the activation key selects them and subscription-manager doesn't
+	// accept them together with an activation key.
+	CACert string
+}
+
+// SyspurposeOptions holds the system purpose attributes of the system
+type SyspurposeOptions struct {
+	Role  string
+	SLA   string
+	Usage string
+}
+
+// Validate checks that the options can be used for registration:
+//   - An organization and activation key are required.
+//   - The registration time must be known; rhc can only register at first
+//     boot and doesn't support Satellite.
+//   - Satellite registration requires an HTTPS server URL and the
+//     PEM-encoded CA certificate of the server.
+//   - The system purpose must set at least one attribute.
+func (o *ImageOptions) Validate() error {
+	if o.Organization == "" || o.ActivationKey == "" {
+		return fmt.Errorf("subscription requires an organization and an activation key")
+	}
+
+	switch o.RegisterAt {
+	case "", RegisterAtFirstBoot, RegisterAtBuild:
+	default:
+		return fmt.Errorf("invalid subscription registration time %q: must be one of %s, %s", o.RegisterAt, RegisterAtFirstBoot, RegisterAtBuild)
+	}
+
+	if o.Rhc {
+		if o.RegisterAt == RegisterAtBuild {
+			return fmt.Errorf("rhc cannot register the system at build time")
+		}
+		if o.Satellite != nil {
+			return fmt.Errorf("rhc cannot register the system with Satellite")
+		}
+	}
+
+	if sat := o.Satellite; sat != nil {
+		if o.ServerUrl == "" {
+			return fmt.Errorf("Satellite registration requires a server URL")
+		}
+		if err := checkSatelliteURL(o.ServerUrl); err != nil {
+			return err
+		}
+		if sat.CACert == "" {
+			return fmt.Errorf("Satellite registration requires the CA certificate of the server")
+		}
+		if block, _ := pem.Decode([]byte(sat.CACert)); block == nil || block.Type != "CERTIFICATE" {
+			return fmt.Errorf("Satellite CA certificate is not a PEM-encoded certificate")
+		}
+	}
+
+	if sp := o.Syspurpose; sp != nil && *sp == (SyspurposeOptions{}) {
+		return fmt.Errorf("system purpose requires at least one attribute")
+	}
+
+	return nil
+}
+
+// checkSatelliteURL checks the server URL, which may be given without a
+// scheme. Only HTTPS is supported.
+func checkSatelliteURL(serverUrl string) error {
+	rawUrl := serverUrl
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "https://" + rawUrl
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid Satellite server URL %q", serverUrl)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("invalid Satellite server URL %q: must use https", serverUrl)
+	}
+	return nil
 }
 
 type RHSMStatus string
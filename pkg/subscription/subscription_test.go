@@ -0,0 +1,105 @@
+package subscription
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ImageOptions
+		wantErr string
+	}{
+		{
+			name: "valid",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "my-secret-key",
+				ServerUrl:     "satellite.example.com",
+				Satellite:     &SatelliteOptions{CACert: testCACert},
+				Release:       "9.2",
+				Syspurpose:    &SyspurposeOptions{SLA: "Premium"},
+				RegisterAt:    RegisterAtBuild,
+			},
+		},
+		{
+			name:    "no-activation-key",
+			options: ImageOptions{Organization: "2040324"},
+			wantErr: "subscription requires an organization and an activation key",
+		},
+		{
+			name:    "register-at",
+			options: ImageOptions{Organization: "2040324", ActivationKey: "key", RegisterAt: "install"},
+			wantErr: `invalid subscription registration time "install": must be one of first-boot, build`,
+		},
+		{
+			name:    "rhc-at-build",
+			options: ImageOptions{Organization: "2040324", ActivationKey: "key", Rhc: true, RegisterAt: RegisterAtBuild},
+			wantErr: "rhc cannot register the system at build time",
+		},
+		{
+			name: "rhc-satellite",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "key",
+				ServerUrl:     "satellite.example.com",
+				Rhc:           true,
+				Satellite:     &SatelliteOptions{},
+			},
+			wantErr: "rhc cannot register the system with Satellite",
+		},
+		{
+			name:    "satellite-no-server",
+			options: ImageOptions{Organization: "2040324", ActivationKey: "key", Satellite: &SatelliteOptions{}},
+			wantErr: "Satellite registration requires a server URL",
+		},
+		{
+			name: "satellite-http",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "key",
+				ServerUrl:     "http://satellite.example.com",
+				Satellite:     &SatelliteOptions{CACert: testCACert},
+			},
+			wantErr: `invalid Satellite server URL "http://satellite.example.com": must use https`,
+		},
+		{
+			name: "satellite-no-ca-cert",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "key",
+				ServerUrl:     "satellite.example.com",
+				Satellite:     &SatelliteOptions{},
+			},
+			wantErr: "Satellite registration requires the CA certificate of the server",
+		},
+		{
+			name: "satellite-ca-cert",
+			options: ImageOptions{
+				Organization:  "2040324",
+				ActivationKey: "key",
+				ServerUrl:     "satellite.example.com",
+				Satellite:     &SatelliteOptions{CACert: "not a certificate"},
+			},
+			wantErr: "Satellite CA certificate is not a PEM-encoded certificate",
+		},
+		{
+			name:    "syspurpose-empty",
+			options: ImageOptions{Organization: "2040324", ActivationKey: "key", Syspurpose: &SyspurposeOptions{}},
+			wantErr: "system purpose requires at least one attribute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}